@@ -1,10 +1,16 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -16,6 +22,7 @@ import (
 	"github.com/zjy-dev/de-fuzz/internal/fuzz"
 	"github.com/zjy-dev/de-fuzz/internal/llm"
 	"github.com/zjy-dev/de-fuzz/internal/logger"
+	"github.com/zjy-dev/de-fuzz/internal/metrics"
 	"github.com/zjy-dev/de-fuzz/internal/oracle"
 	"github.com/zjy-dev/de-fuzz/internal/prompt"
 	"github.com/zjy-dev/de-fuzz/internal/prompt/mechanism"
@@ -26,11 +33,15 @@ import (
 // NewFuzzCommand creates the "fuzz" subcommand.
 func NewFuzzCommand() *cobra.Command {
 	var (
-		output  string
-		logDir  string
-		limit   int
-		timeout int
-		useQEMU bool
+		output        string
+		logDir        string
+		limit         int
+		maxDuration   time.Duration
+		timeout       int
+		useQEMU       bool
+		noLLMCache    bool
+		validateSeeds bool
+		resume        bool
 	)
 
 	cmd := &cobra.Command{
@@ -58,8 +69,9 @@ Configuration:
   Command line flags override the config file values.
 
 Constraints:
-  --limit and --timeout work independently:
+  --limit, --max-duration and --timeout work independently:
     --limit: Maximum number of target BBs to attempt (0 = unlimited)
+    --max-duration: Wall-clock budget; the current target finishes, then fuzzing stops
     --timeout: Maximum execution time per seed in seconds
 
 Examples:
@@ -72,17 +84,32 @@ Examples:
   # Limit to 50 target basic blocks for constraint solving
   defuzz fuzz --limit 50
 
+  # Run for 12 hours or 1000 targets, whichever comes first
+  defuzz fuzz --max-duration 12h --limit 1000
+
   # Use QEMU for cross-architecture fuzzing
   defuzz fuzz --use-qemu
 
+  # Resume a hand-edited corpus, compiling every pending seed first
+  defuzz fuzz --validate-seeds
+
+  # Continue an interrupted campaign's iteration count, bugs and BB weights
+  defuzz fuzz --resume
+
   # Limit to 30 targets with 60s timeout each
   defuzz fuzz --limit 30 --timeout 60`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Load config first to get defaults
+			loadStart := time.Now()
 			cfg, err := config.LoadConfig()
 			if err != nil {
 				return fmt.Errorf("failed to load config: %w", err)
 			}
+			var dryRun *fuzz.DryRunReport
+			if cfg.Compiler.Fuzz.DryRun {
+				dryRun = &fuzz.DryRunReport{}
+				dryRun.Time("load config", loadStart, nil)
+			}
 
 			// Use config values as defaults, command line flags override
 			if !cmd.Flags().Changed("output") {
@@ -94,17 +121,26 @@ Examples:
 			if !cmd.Flags().Changed("limit") {
 				limit = cfg.Compiler.Fuzz.MaxIterations
 			}
+			if cmd.Flags().Changed("max-duration") {
+				cfg.Compiler.Fuzz.MaxDuration = maxDuration
+			}
 			if !cmd.Flags().Changed("timeout") {
 				timeout = cfg.Compiler.Fuzz.Timeout
 			}
 			if !cmd.Flags().Changed("use-qemu") {
 				useQEMU = cfg.Compiler.Fuzz.UseQEMU
 			}
+			if noLLMCache {
+				cfg.LLM.CacheDir = ""
+			}
+			if validateSeeds {
+				cfg.Compiler.Fuzz.ValidateSeeds = true
+			}
 
 			// Build the actual output directory: {output}/{isa}/{strategy}
 			outputDir := filepath.Join(output, cfg.ISA, cfg.Strategy)
 
-			return runFuzz(cfg, outputDir, logDir, limit, timeout, useQEMU)
+			return runFuzz(cmd.Context(), cfg, outputDir, logDir, limit, timeout, useQEMU, resume, dryRun)
 		},
 	}
 
@@ -112,13 +148,20 @@ Examples:
 	cmd.Flags().StringVar(&output, "output", "fuzz_out", "Output directory (actual output at {output}/{isa}/{strategy})")
 	cmd.Flags().StringVar(&logDir, "log-dir", "", "Log file directory (timestamped log files, empty = console only)")
 	cmd.Flags().IntVar(&limit, "limit", -1, "Max number of target BBs for constraint solving (-1 = unlimited, 0 = initial seeds only)")
+	cmd.Flags().DurationVar(&maxDuration, "max-duration", 0, "Wall-clock budget of the campaign, e.g. 12h (0 = unlimited; fuzz.max_duration)")
 	cmd.Flags().IntVar(&timeout, "timeout", 30, "Execution timeout in seconds")
 	cmd.Flags().BoolVar(&useQEMU, "use-qemu", false, "Use QEMU for cross-architecture execution")
+	cmd.Flags().BoolVar(&noLLMCache, "no-llm-cache", false, "Bypass the on-disk LLM completion cache (llm.cache_dir)")
+	cmd.Flags().BoolVar(&validateSeeds, "validate-seeds", false, "Compile every pending seed before fuzzing and skip those that fail (fuzz.validate_seeds)")
+	cmd.Flags().BoolVar(&resume, "resume", false, "Restore the engine checkpoint (counters, known bugs, RNG, BB weights) from the state directory")
 
 	return cmd
 }
 
-func runFuzz(cfg *config.Config, outputDir string, logDir string, limit, timeout int, useQEMU bool) error {
+// runFuzz runs the campaign, or checks the pipeline once when dryRun is not
+// nil: the stages are recorded in it and written to
+// {output}/dry_run_report.json.
+func runFuzz(ctx context.Context, cfg *config.Config, outputDir string, logDir string, limit, timeout int, useQEMU, resume bool, dryRun *fuzz.DryRunReport) error {
 	// Initialize logger with configured level
 	logLevel := cfg.LogLevel
 	if logLevel == "" {
@@ -139,6 +182,17 @@ func runFuzz(cfg *config.Config, outputDir string, logDir string, limit, timeout
 	logger.Info("Output directory: %s", outputDir)
 	logger.Debug("Log level: %s", logLevel)
 
+	if dryRun != nil {
+		logger.Info("Dry run: checking the pipeline once without LLM calls")
+		defer func() {
+			reportPath := filepath.Join(outputDir, "dry_run_report.json")
+			if err := dryRun.Write(reportPath); err != nil {
+				logger.Warn("%v", err)
+			}
+			fmt.Printf("[Fuzz] %s\n[Fuzz] Dry run report: %s\n", dryRun.Summary(), reportPath)
+		}()
+	}
+
 	// Create state directory (used for resume capability)
 	stateDir := filepath.Join(outputDir, "state")
 	if err := os.MkdirAll(stateDir, 0755); err != nil {
@@ -147,6 +201,12 @@ func runFuzz(cfg *config.Config, outputDir string, logDir string, limit, timeout
 
 	// 2. Create corpus manager
 	corpusManager := corpus.NewFileManager(outputDir)
+	if cfg.Compiler.Fuzz.Dedup != "off" {
+		corpusManager.SetDedup(seed.HashStrictness(cfg.Compiler.Fuzz.Dedup))
+	}
+	corpusManager.SetCompress(cfg.Compiler.Fuzz.CompressSeeds)
+	corpusManager.SetSchedule(corpus.Schedule(cfg.Compiler.Fuzz.Schedule), nil)
+	corpusManager.SetFavoredWeight(cfg.Compiler.Fuzz.FavoredWeight)
 
 	// Build deterministic flag scheduler before wiring compiler and engine.
 	flagScheduler, err := fuzz.NewFlagScheduler(cfg.ISA, cfg.Compiler.Fuzz.FlagStrategy)
@@ -177,6 +237,7 @@ func runFuzz(cfg *config.Config, outputDir string, logDir string, limit, timeout
 
 	gccCompiler := compiler.NewGCCCompiler(compiler.GCCCompilerConfig{
 		GCCPath:          cfg.Compiler.Path,
+		CXXPath:          cfg.Compiler.CXXPath,
 		WorkDir:          filepath.Join(outputDir, "build"),
 		PrefixPath:       compilerDir,
 		CFlags:           cflags,
@@ -230,11 +291,77 @@ func runFuzz(cfg *config.Config, outputDir string, logDir string, limit, timeout
 		filterConfigPath,
 	)
 
+	// Optional GCOV_PREFIX redirection: seed executions write runtime .gcda
+	// files under gcovPrefix (see executor.GcovEnv), and gcovr searches it too.
+	gcovPrefix := cfg.Compiler.GcovPrefix
+	if gcovPrefix != "" {
+		if !filepath.IsAbs(gcovPrefix) {
+			gcovPrefix = filepath.Join(outputDir, gcovPrefix)
+		}
+		if abs, err := filepath.Abs(gcovPrefix); err == nil {
+			gcovPrefix = abs
+		}
+		if err := os.MkdirAll(gcovPrefix, 0755); err != nil {
+			return fmt.Errorf("failed to create gcov prefix directory: %w", err)
+		}
+		coverageTracker.SetGcovPrefix(gcovPrefix)
+		logger.Info("Redirecting runtime .gcda files to %s (strip=%d)", gcovPrefix, cfg.Compiler.GcovPrefixStrip)
+	}
+	gcovEnv := executor.GcovEnv(gcovPrefix, cfg.Compiler.GcovPrefixStrip)
+
+	// 5. Verify the coverage backend before spending any LLM calls
+	selfTestStart := time.Now()
+	err = fuzz.ConfigError("compiler.gcovr_exec_path, compiler.gcovr_command", coverageTracker.SelfTest())
+	if dryRun != nil {
+		err = dryRun.Time("coverage self-test", selfTestStart, err)
+	}
+	if err != nil {
+		return fmt.Errorf("coverage self-test failed, fix the compiler config and retry:\n%w", err)
+	}
+
 	// 6. Create LLM client
-	llmClient, err := llm.New(cfg.RemixerConfigPath, cfg.DefaultTemperature)
+	llmOpts := append(llmSamplingOptions(cfg),
+		llm.WithRequestTimeout(time.Duration(cfg.LLM.RequestTimeoutSeconds)*time.Second),
+		llm.WithBatchConcurrency(cfg.LLM.BatchConcurrency))
+	if cfg.LLM.CacheDir != "" {
+		ttl := time.Duration(cfg.LLM.CacheTTLHours) * time.Hour
+		llmOpts = append(llmOpts, llm.WithDiskCache(cfg.LLM.CacheDir, ttl))
+		logger.Info("LLM completion cache: %s", cfg.LLM.CacheDir)
+	}
+	if auditPath := cfg.LLM.AuditLog; auditPath != "" {
+		if !filepath.IsAbs(auditPath) {
+			auditPath = filepath.Join(outputDir, auditPath)
+		}
+		maxBytes := int64(cfg.LLM.AuditMaxMB) << 20
+		llmOpts = append(llmOpts, llm.WithAuditLog(auditPath, maxBytes, cfg.LLM.AuditFullPrompts))
+		logger.Info("LLM audit log: %s", auditPath)
+	}
+	var campaignMetrics *metrics.Metrics
+	if cfg.Compiler.Fuzz.MetricsAddr != "" {
+		campaignMetrics = metrics.New()
+		llmOpts = append(llmOpts, llm.WithCallObserver(campaignMetrics.ObserveLLMCall))
+	}
+	llmClient, err := llm.New(cfg.RemixerConfigPath, cfg.DefaultTemperature, llmOpts...)
 	if err != nil {
 		return fmt.Errorf("failed to create LLM client: %w", err)
 	}
+	if closer, ok := llmClient.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	// 7. Create LLM response cache (in memory, plus JSONL if configured)
+	var responseCache *llm.ResponseCache
+	if cfg.LLM.CacheOnHit != "off" {
+		cachePath := cfg.LLM.CacheFile
+		if cachePath != "" && !filepath.IsAbs(cachePath) {
+			cachePath = filepath.Join(stateDir, cachePath)
+		}
+		responseCache, err = llm.NewResponseCache(cachePath)
+		if err != nil {
+			return fmt.Errorf("failed to create LLM response cache: %w", err)
+		}
+		defer responseCache.Close()
+	}
 
 	// 8. Create prompt service
 	basePath := filepath.Join("initial_seeds", cfg.ISA, cfg.Strategy)
@@ -251,7 +378,8 @@ func runFuzz(cfg *config.Config, outputDir string, logDir string, limit, timeout
 	if !ok {
 		return fmt.Errorf("no mechanism contract registered for strategy %q; register it in internal/prompt/mechanism/", cfg.Strategy)
 	}
-	if mechanismContract.OracleType() != cfg.Compiler.Oracle.Type {
+	// The diff oracle compares builds and fits every strategy.
+	if mechanismContract.OracleType() != cfg.Compiler.Oracle.Type && cfg.Compiler.Oracle.Type != "diff" {
 		return fmt.Errorf(
 			"strategy/oracle mismatch: strategy %q declares oracle type %q but cfg.Compiler.Oracle.Type is %q",
 			cfg.Strategy, mechanismContract.OracleType(), cfg.Compiler.Oracle.Type,
@@ -260,7 +388,10 @@ func runFuzz(cfg *config.Config, outputDir string, logDir string, limit, timeout
 
 	// Create prompt builder: template path is derived from the contract.
 	functionTemplate := mechanismContract.FunctionTemplatePath(cfg.ISA)
-	promptBuilder := prompt.NewBuilder(cfg.Compiler.Fuzz.MaxTestCases, functionTemplate, mechanismContract)
+	promptBuilder := newPromptBuilder(cfg, mechanismContract, functionTemplate, compilerContext(cfg, gccCompiler, cflags))
+	if !cfg.Prompt.DisableDiversityHints {
+		promptBuilder.ConstructStats = corpusManager.ConstructStats
+	}
 
 	// Create prompt service with configuration
 	basePromptDir := cfg.Compiler.Fuzz.BasePromptDir
@@ -272,6 +403,10 @@ func runFuzz(cfg *config.Config, outputDir string, logDir string, limit, timeout
 	if err != nil {
 		return fmt.Errorf("failed to create prompt service: %w", err)
 	}
+	if limiter, ok := llmClient.(llm.ContextLimiter); ok && limiter.PromptTokenLimit() > 0 {
+		promptService.SetContextLimit(limiter.PromptTokenLimit())
+		logger.Info("LLM prompts limited to ~%d tokens by the models' context windows", limiter.PromptTokenLimit())
+	}
 
 	// For oracle creation, we still need understanding content directly
 	understanding, _ := seed.LoadUnderstanding(basePath)
@@ -289,36 +424,29 @@ func runFuzz(cfg *config.Config, outputDir string, logDir string, limit, timeout
 	}
 
 	// 9. Initialize corpus and load initial seeds if needed
-	if err := corpusManager.Initialize(); err != nil {
-		return fmt.Errorf("failed to initialize corpus: %w", err)
+	corpusStart := time.Now()
+	err = loadCorpus(corpusManager, basePath)
+	if dryRun != nil {
+		err = dryRun.Time("load corpus", corpusStart, fuzz.ConfigError("fuzz.output_root_dir", err))
 	}
-
-	if err := corpusManager.Recover(); err != nil {
-		return fmt.Errorf("failed to recover corpus: %w", err)
+	if err != nil {
+		return err
 	}
 
-	// If corpus is empty, load initial seeds
-	if corpusManager.Len() == 0 {
-		logger.Info("Corpus is empty, loading initial seeds from %s...", basePath)
-		initialSeeds, err := seed.LoadSeedsWithMetadata(basePath, seed.NewDefaultNamingStrategy())
-		if err != nil {
-			return fmt.Errorf("failed to load initial seeds: %w", err)
-		}
-		if len(initialSeeds) == 0 {
-			return fmt.Errorf("no initial seeds found in %s, please run 'defuzz generate' first", basePath)
-		}
-		for _, s := range initialSeeds {
-			// Reset ID to 0 so corpus manager assigns a new unique ID
-			s.Meta.ID = 0
-			if err := corpusManager.Add(s); err != nil {
-				return fmt.Errorf("failed to add initial seed to corpus: %w", err)
-			}
+	if cfg.Compiler.Fuzz.ValidateSeeds {
+		pending := corpusManager.Len()
+		failures := corpusManager.ValidatePending(compileFunc, cfg.Compiler.Fuzz.ValidateWorkers)
+		for _, id := range slices.Sorted(maps.Keys(failures)) {
+			reason, _, _ := strings.Cut(failures[id].Error(), "\n")
+			logger.Warn("Seed %d is invalid: %s", id, reason)
 		}
-		logger.Info("Loaded %d initial seeds", len(initialSeeds))
+		logger.Info("Validated %d pending seeds: %d compiled, %d invalid", pending, pending-len(failures), len(failures))
 	}
 
 	// 10. Create analyzer if configured
+	cfgStart := time.Now()
 	var analyzer *coverage.Analyzer
+	var analyzerErr error
 	// Merge cfg_file_path (single, backward compat) and cfg_file_paths (multi)
 	var cfgPaths []string
 	if cfg.Compiler.Fuzz.CFGFilePath != "" {
@@ -371,14 +499,53 @@ func runFuzz(cfg *config.Config, outputDir string, logDir string, limit, timeout
 				cfg.Compiler.Fuzz.WeightDecayFactor,
 			)
 			if err != nil {
-				logger.Warn("Failed to create analyzer: %v (continuing without target function tracking)", err)
 				analyzer = nil
+				analyzerErr = fmt.Errorf("failed to create analyzer: %w", err)
 			} else {
+				// Refuse to hand out seed IDs the mapping already credits
+				if err := corpusManager.CheckMapping(analyzer.GetMapping()); err != nil {
+					return err
+				}
+				analyzer.SetMinTargetSuccessors(cfg.Compiler.Fuzz.MinTargetSuccessors)
+				analyzer.GetMapping().SetFavoredWeight(cfg.Compiler.Fuzz.FavoredWeight)
 				logger.Info("Analyzer initialized, total target lines: %d", analyzer.GetTotalTargetLines())
 			}
 		}
 	}
 
+	// Only an empty cfg_file_path falls back to coverage-guided fuzzing; a
+	// configured CFG the analyzer cannot use is an error
+	if len(cfgPaths) == 0 {
+		logger.Info("No fuzz.cfg_file_path: falling back to coverage-guided fuzzing")
+		if dryRun != nil {
+			dryRun.Skip("parse CFG", "no fuzz.cfg_file_path, coverage-guided mode")
+		}
+	} else {
+		if analyzer == nil && analyzerErr == nil {
+			analyzerErr = errors.New("no target function of compiler.targets is in a CFG dump")
+		}
+		err = fuzz.ConfigError("fuzz.cfg_file_path", analyzerErr)
+		if dryRun != nil {
+			err = dryRun.Time("parse CFG", cfgStart, err)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// Cap the corpus; evicted seeds leave the coverage mapping too
+	if limits := cfg.Compiler.Fuzz.Corpus; limits.MaxSeeds > 0 || limits.MaxDiskMB > 0 {
+		var mapping *coverage.CoverageMapping
+		if analyzer != nil {
+			mapping = analyzer.GetMapping()
+		}
+		corpusManager.SetLimits(corpus.Limits{
+			MaxSeeds:     limits.MaxSeeds,
+			MaxDiskBytes: int64(limits.MaxDiskMB) << 20,
+			HardEvict:    limits.HardEvict,
+		}, mapping)
+	}
+
 	// 12. Create and run fuzzing engine
 	// Use Engine for constraint solving based fuzzing
 	fmt.Println("[Fuzz] Starting fuzzing engine...")
@@ -387,33 +554,190 @@ func runFuzz(cfg *config.Config, outputDir string, logDir string, limit, timeout
 	// Create oracle executor: QEMU for cross-architecture, local for native
 	var oracleExecutor oracle.Executor
 	if useQEMU {
-		oracleExecutor = executor.NewQEMUOracleExecutorAdapter(
+		qemuExecutor := executor.NewQEMUOracleExecutorAdapter(
 			cfg.Compiler.Fuzz.QEMUPath,
 			cfg.Compiler.Fuzz.QEMUSysroot,
 			timeout,
 		)
+		qemuExecutor.SetEnv(gcovEnv)
+		oracleExecutor = qemuExecutor
 		logger.Info("Oracle using QEMU executor: %s", cfg.Compiler.Fuzz.QEMUPath)
 	} else {
-		oracleExecutor = executor.NewOracleExecutorAdapter(timeout)
+		localExecutor := executor.NewOracleExecutorAdapter(timeout)
+		localExecutor.SetEnv(gcovEnv)
+		oracleExecutor = localExecutor
 		logger.Info("Oracle using local executor")
 	}
 
+	// Differential mode: build every seed with the reference compiler too
+	var referenceCompiler compiler.Compiler
+	var referenceExecutor oracle.Executor
+	if ref := cfg.Compiler.Reference; ref.Path != "" {
+		refCFlags := ref.CFlags
+		if len(refCFlags) == 0 {
+			refCFlags = cflags
+		}
+		referenceCompiler = compiler.NewGCCCompiler(compiler.GCCCompilerConfig{
+			GCCPath:          ref.Path,
+			CXXPath:          ref.CXXPath,
+			WorkDir:          filepath.Join(outputDir, "build_reference"),
+			PrefixPath:       filepath.Dir(ref.Path),
+			CFlags:           refCFlags,
+			DisableLLMCFlags: !allowLLMCFlags,
+		})
+		if ref.UseQEMU {
+			referenceExecutor = executor.NewQEMUOracleExecutorAdapter(ref.QEMUPath, ref.QEMUSysroot, timeout)
+		} else {
+			referenceExecutor = executor.NewOracleExecutorAdapter(timeout)
+		}
+		logger.Info("Reference compiler: %s (QEMU: %v)", ref.Path, ref.UseQEMU)
+	}
+
+	// Target settings copied into every bug's reproduction bundle
+	bundle := seed.BundleMeta{Oracle: cfg.Compiler.Oracle.Type}
+	if useQEMU {
+		bundle.QEMUPath = cfg.Compiler.Fuzz.QEMUPath
+		bundle.QEMUSysroot = cfg.Compiler.Fuzz.QEMUSysroot
+	}
+
+	// Serve Prometheus metrics while fuzzing
+	var engineMetrics fuzz.Metrics
+	if campaignMetrics != nil {
+		campaignMetrics.WatchCorpus(corpusManager)
+		if analyzer != nil {
+			campaignMetrics.WatchCoverage(analyzer)
+		}
+		server, err := metrics.Serve(cfg.Compiler.Fuzz.MetricsAddr, campaignMetrics)
+		if err != nil {
+			return err
+		}
+		defer server.Close()
+		engineMetrics = campaignMetrics
+		logger.Info("Serving Prometheus metrics at http://%s/metrics", server.Addr())
+	}
+
+	// Log engine events
+	var eventSinks []fuzz.EventSink
+	if eventPath := cfg.Compiler.Fuzz.EventLog; eventPath != "" {
+		if !filepath.IsAbs(eventPath) {
+			eventPath = filepath.Join(outputDir, eventPath)
+		}
+		eventLog, err := fuzz.NewEventLog(eventPath)
+		if err != nil {
+			return err
+		}
+		defer eventLog.Close()
+		eventSinks = append(eventSinks, eventLog)
+		logger.Info("Engine event log: %s", eventPath)
+	}
+	if iterationPath := cfg.Compiler.Fuzz.IterationLog; iterationPath != "" {
+		if !filepath.IsAbs(iterationPath) {
+			iterationPath = filepath.Join(outputDir, iterationPath)
+		}
+		iterationLog, err := fuzz.NewIterationLog(iterationPath)
+		if err != nil {
+			return err
+		}
+		defer iterationLog.Close()
+		eventSinks = append(eventSinks, iterationLog)
+		logger.Info("Iteration log: %s", iterationPath)
+	}
+
 	cfgEngine := fuzz.NewEngine(fuzz.Config{
-		Corpus:         corpusManager,
-		Compiler:       gccCompiler,
-		Coverage:       coverageTracker,
-		Oracle:         oracleInstance,
-		OracleType:     cfg.Compiler.Oracle.Type,
-		OracleExecutor: oracleExecutor,
-		LLM:            llmClient,
-		Flags:          flagScheduler,
-		Analyzer:       analyzer,
-		PromptService:  promptService,
-		MaxIterations:  limit,
-		MaxRetries:     cfg.Compiler.Fuzz.MaxConstraintRetries,
-		MappingPath:    filepath.Join(stateDir, "coverage_mapping.json"),
+		Corpus:               corpusManager,
+		Compiler:             gccCompiler,
+		Coverage:             coverageTracker,
+		Oracle:               oracleInstance,
+		OracleType:           cfg.Compiler.Oracle.Type,
+		OracleExecutor:       oracleExecutor,
+		ReferenceCompiler:    referenceCompiler,
+		ReferenceExecutor:    referenceExecutor,
+		LLM:                  llmClient,
+		Flags:                flagScheduler,
+		Analyzer:             analyzer,
+		PromptService:        promptService,
+		MaxIterations:        limit,
+		MaxDuration:          cfg.Compiler.Fuzz.MaxDuration,
+		MaxRetries:           cfg.Compiler.Fuzz.MaxConstraintRetries,
+		AdaptiveRetries:      cfg.Compiler.Fuzz.AdaptiveRetries,
+		TargetCooldown:       cfg.Compiler.Fuzz.TargetCooldown,
+		TargetSchedule:       fuzz.TargetSchedule(cfg.Compiler.Fuzz.TargetSchedule),
+		MaxCompileFixRetries: cfg.Compiler.Fuzz.MaxCompileFixRetries,
+		Conversation:         cfg.LLM.Conversation,
+		Stream:               cfg.LLM.Stream,
+		StreamMaxTokens:      cfg.LLM.StreamMaxTokens,
+		ResponseCache:        responseCache,
+		CacheOnHit:           fuzz.CacheHitPolicy(cfg.LLM.CacheOnHit),
+		ExecuteSeeds:         fuzz.ExecuteMode(cfg.Compiler.Fuzz.ExecuteSeeds),
+		DryRunPrompts:        cfg.Compiler.Fuzz.DryRunPrompts,
+		DryRunDir:            filepath.Join(outputDir, "dry_run_prompts"),
+		MappingPath:          filepath.Join(stateDir, "coverage_mapping.json"),
+		UsagePath:            filepath.Join(stateDir, "llm_usage.json"),
+		CheckpointPath:       filepath.Join(stateDir, "engine_checkpoint.json"),
+		LineagePath:          filepath.Join(outputDir, "lineage.dot"),
+		TrimEvery:            cfg.Compiler.Fuzz.TrimEvery,
+		ProgressInterval:     time.Duration(cfg.Compiler.Fuzz.ProgressIntervalSeconds) * time.Second,
+		StatusPath:           filepath.Join(outputDir, "status.json"),
+		Metrics:              engineMetrics,
+		EventSinks:           eventSinks,
+
+		MinimizeBugs:      cfg.Compiler.Fuzz.MinimizeBugs,
+		MinimizeMaxChecks: cfg.Compiler.Fuzz.MinimizeMaxChecks,
+		MinimizeTimeout:   time.Duration(cfg.Compiler.Fuzz.MinimizeTimeoutSeconds) * time.Second,
+
+		UnderstandingMaxTokens:    cfg.Prompt.UnderstandingMaxTokens,
+		UnderstandingTargetTokens: cfg.Prompt.UnderstandingTargetTokens,
+		UnderstandingDir:          basePath,
+		UnderstandingVersion:      fuzz.UnderstandingVersion(cfg.Prompt.UnderstandingVersion),
+
+		BugsDir: filepath.Join(outputDir, "bugs"),
+		Bundle:  bundle,
 	})
-	return cfgEngine.Run()
+	if dryRun != nil {
+		return cfgEngine.DryRun(ctx, dryRun)
+	}
+	if resume {
+		if err := cfgEngine.Resume(filepath.Join(stateDir, "engine_checkpoint.json")); err != nil {
+			return err
+		}
+	}
+	return cfgEngine.Run(ctx)
+}
+
+// loadCorpus initializes and recovers the corpus, and loads the initial seeds
+// in basePath into it if it is empty.
+func loadCorpus(corpusManager corpus.Manager, basePath string) error {
+	if err := corpusManager.Initialize(); err != nil {
+		return fmt.Errorf("failed to initialize corpus: %w", err)
+	}
+
+	if err := corpusManager.Recover(); err != nil {
+		return fmt.Errorf("failed to recover corpus: %w", err)
+	}
+
+	// If corpus is empty, load initial seeds
+	if corpusManager.Len() == 0 {
+		logger.Info("Corpus is empty, loading initial seeds from %s...", basePath)
+		initialSeeds, err := seed.LoadSeedsWithMetadata(basePath, seed.NewDefaultNamingStrategy())
+		if err != nil {
+			return fmt.Errorf("failed to load initial seeds: %w", err)
+		}
+		if len(initialSeeds) == 0 {
+			return fmt.Errorf("no initial seeds found in %s, please run 'defuzz generate' first", basePath)
+		}
+		for _, s := range initialSeeds {
+			// Reset ID to 0 so corpus manager assigns a new unique ID
+			s.Meta.ID = 0
+			if err := corpusManager.Add(s); errors.Is(err, corpus.ErrDuplicate) {
+				logger.Info("Skipping initial seed %s: %v", s.Meta.FilePath, err)
+				continue
+			} else if err != nil {
+				return fmt.Errorf("failed to add initial seed to corpus: %w", err)
+			}
+		}
+		logger.Info("Loaded %d initial seeds", corpusManager.Len())
+	}
+	return nil
 }
 
 func inferCFGSourceBase(cfgPath string) string {
@@ -426,3 +750,77 @@ func inferCFGSourceBase(cfgPath string) string {
 	}
 	return base
 }
+
+// newPromptBuilder creates the prompt builder for the mechanism contract
+// and configures it from the fuzz and prompt settings in cfg.
+func newPromptBuilder(cfg *config.Config, contract mechanism.Contract, functionTemplate string, compilerCtx *prompt.CompilerContext) *prompt.Builder {
+	b := prompt.NewBuilder(cfg.Compiler.Fuzz.MaxTestCases, functionTemplate, contract)
+	b.TemplateDir = cfg.Prompt.TemplateDir
+	b.TokenBudget = cfg.Prompt.TokenBudget
+	b.Language = seed.Language(cfg.Compiler.Fuzz.SeedLanguage)
+	b.StructuredOutput = cfg.LLM.StructuredOutput
+	b.StrictTestCases = cfg.Compiler.Fuzz.StrictTestCases
+	b.AllowShellTestCommands = cfg.Compiler.Fuzz.AllowShellTestCommands
+	b.RequestTags = cfg.Compiler.Fuzz.RequestTags
+	b.AuxContextFiles = cfg.Compiler.Fuzz.AuxContextFiles
+	b.Compiler = compilerCtx
+	if cfg.Compiler.Fuzz.AuxContextMaxBytes > 0 {
+		b.AuxContextMaxBytes = cfg.Compiler.Fuzz.AuxContextMaxBytes
+	}
+	if cfg.Prompt.TestCaseSeparator != "" {
+		b.TestCaseSeparator = cfg.Prompt.TestCaseSeparator
+	}
+	if cfg.Prompt.MaxPriorAttempts > 0 {
+		b.MaxPriorAttempts = cfg.Prompt.MaxPriorAttempts
+	}
+	if cfg.Prompt.PriorAttemptMaxChars > 0 {
+		b.PriorAttemptMaxChars = cfg.Prompt.PriorAttemptMaxChars
+	}
+	b.CandidatesPerCall = cfg.Prompt.CandidatesPerCall
+	b.LineageDepth = cfg.Prompt.LineageDepth
+	b.DisableSystemOverrides = cfg.Prompt.DisableSystemOverrides
+	b.DivergencePrefixEntries = cfg.Prompt.DivergencePrefixEntries
+	b.DivergencePathEntries = cfg.Prompt.DivergencePathEntries
+	return b
+}
+
+// llmSamplingOptions maps the llm.generation/mutation/refinement/
+// understanding blocks onto the call types the engine tags requests with.
+func llmSamplingOptions(cfg *config.Config) []llm.Option {
+	blocks := []struct {
+		sampling  config.SamplingConfig
+		callTypes []string
+	}{
+		{cfg.LLM.Generation, []string{llm.CallGenerate}},
+		{cfg.LLM.Mutation, []string{llm.CallMutate}},
+		{cfg.LLM.Refinement, []string{llm.CallRefine, llm.CallRepair}},
+		{cfg.LLM.Understanding, []string{llm.CallUnderstand}},
+	}
+	var opts []llm.Option
+	for _, b := range blocks {
+		s := llm.Sampling{Temperature: b.sampling.Temperature, TopP: b.sampling.TopP, MaxTokens: b.sampling.MaxTokens}
+		if s == (llm.Sampling{}) {
+			continue
+		}
+		for _, callType := range b.callTypes {
+			opts = append(opts, llm.WithSampling(callType, s))
+		}
+	}
+	return opts
+}
+
+// compilerContext describes the configured compiler for prompts. The target
+// triple comes from the compiler itself, falling back to the ISA name.
+func compilerContext(cfg *config.Config, gccCompiler *compiler.GCCCompiler, cflags []string) *prompt.CompilerContext {
+	target, err := gccCompiler.TargetTriple()
+	if err != nil {
+		logger.Warn("Could not determine compiler target triple, using ISA %q: %v", cfg.ISA, err)
+		target = cfg.ISA
+	}
+	return &prompt.CompilerContext{
+		Name:    cfg.Compiler.Name,
+		Version: cfg.Compiler.Version,
+		Target:  target,
+		CFlags:  cflags,
+	}
+}
@@ -1,14 +1,18 @@
 package app
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
+	"slices"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/zjy-dev/de-fuzz/internal/compiler"
 	"github.com/zjy-dev/de-fuzz/internal/config"
+	"github.com/zjy-dev/de-fuzz/internal/corpus"
 	"github.com/zjy-dev/de-fuzz/internal/llm"
-	"github.com/zjy-dev/de-fuzz/internal/prompt"
 	"github.com/zjy-dev/de-fuzz/internal/prompt/mechanism"
 	"github.com/zjy-dev/de-fuzz/internal/seed"
 )
@@ -30,7 +34,7 @@ It initializes the LLM's understanding of the target and creates a set of starti
 The ISA and strategy are read from the config.yaml file under the 'config' section.
 
 Each seed consists of:
-  - C source code (source.c)
+  - Source code (source.c, or source.cpp / source.rs per fuzz.seed_language)
   - Optional test cases (testcases.json)
 
 The seeds are saved in a directory-based format with metadata encoded in the directory name.
@@ -73,7 +77,8 @@ Examples:
 			if !ok {
 				return fmt.Errorf("no mechanism contract registered for strategy %q; register it in internal/prompt/mechanism/", strategy)
 			}
-			if mechanismContract.OracleType() != cfg.Compiler.Oracle.Type {
+			// The diff oracle compares builds and fits every strategy.
+			if mechanismContract.OracleType() != cfg.Compiler.Oracle.Type && cfg.Compiler.Oracle.Type != "diff" {
 				return fmt.Errorf(
 					"strategy/oracle mismatch: strategy %q declares oracle type %q but cfg.Compiler.Oracle.Type is %q",
 					strategy, mechanismContract.OracleType(), cfg.Compiler.Oracle.Type,
@@ -81,14 +86,19 @@ Examples:
 			}
 
 			// 3. Create LLM client
-			llmClient, err := llm.New(cfg.RemixerConfigPath, cfg.DefaultTemperature)
+			llmOpts := append(llmSamplingOptions(cfg),
+				llm.WithRequestTimeout(time.Duration(cfg.LLM.RequestTimeoutSeconds)*time.Second),
+				llm.WithBatchConcurrency(cfg.LLM.BatchConcurrency))
+			llmClient, err := llm.New(cfg.RemixerConfigPath, cfg.DefaultTemperature, llmOpts...)
 			if err != nil {
 				return fmt.Errorf("failed to create LLM client: %w", err)
 			}
 
 			// 4. Create prompt builder: template path is derived from the contract.
 			functionTemplate := mechanismContract.FunctionTemplatePath(isa)
-			promptBuilder := prompt.NewBuilder(cfg.Compiler.Fuzz.MaxTestCases, functionTemplate, mechanismContract)
+			promptBuilder := newPromptBuilder(cfg, mechanismContract, functionTemplate, compilerContext(cfg,
+				compiler.NewGCCCompiler(compiler.GCCCompilerConfig{GCCPath: cfg.Compiler.Path}),
+				cfg.Compiler.CFlags))
 
 			// Log mode
 			if promptBuilder.IsFunctionTemplateMode() {
@@ -106,11 +116,17 @@ Examples:
 			// 5. Load understanding if exists (optional)
 			// If user provides understanding.md, it will be used as system prompt.
 			// Otherwise, the default SystemPromptGenerate will be used.
+			// A compressed version saved by the fuzz command is preferred
+			// unless prompt.understanding_version is "full".
 			understanding, _ := seed.LoadUnderstanding(basePath)
 			// systemPrompt := prompt.GetSystemPrompt("generate", understanding)
 			// TODO: Update to use PromptService when integrating with fuzz command
 			systemPrompt := understanding
-			if understanding != "" {
+			compressed, compressedErr := seed.LoadCompressedUnderstanding(basePath, understanding)
+			if understanding != "" && compressedErr == nil && cfg.Prompt.UnderstandingVersion != "full" {
+				systemPrompt = compressed
+				fmt.Printf("[Generate] Using compressed understanding from %s\n", seed.GetCompressedUnderstandingPath(basePath))
+			} else if understanding != "" {
 				fmt.Printf("[Generate] Using custom understanding from %s\n", seed.GetUnderstandingPath(basePath))
 			} else {
 				fmt.Printf("[Generate] Using default system prompt for generation\n")
@@ -119,66 +135,113 @@ Examples:
 			// 6. Create naming strategy for seeds
 			namer := seed.NewDefaultNamingStrategy()
 
-			// 7. Generate seeds with retry logic
+			// Steer each new seed away from constructs the seeds generated
+			// so far already cover.
+			if !cfg.Prompt.DisableDiversityHints {
+				promptBuilder.ConstructStats = func() corpus.ConstructStats {
+					seeds, err := seed.LoadSeedsWithMetadata(basePath, namer)
+					if err != nil {
+						return corpus.ConstructStats{}
+					}
+					return corpus.ProfileConstructs(seeds)
+				}
+			}
+
+			// 7. Generate seeds in rounds: every seed still missing is
+			// requested in one concurrent batch, and the failures are
+			// retried in the next round.
 			fmt.Printf("[Generate] Generating %d seeds (max %d retries per seed)...\n", count, maxRetries)
 			successCount := 0
+			ctx := llm.WithCallInfo(cmd.Context(), llm.CallInfo{Type: llm.CallGenerate})
+			pending := make([]int, count) // Seed indexes not generated yet
+			for i := range pending {
+				pending[i] = i
+			}
+			lastErrs := make([]error, count)
 
-			for i := 0; i < count; i++ {
-				var newSeed *seed.Seed
-				var lastErr error
-
-				// Retry loop for each seed
-				for attempt := 0; attempt <= maxRetries; attempt++ {
-					if attempt > 0 {
-						fmt.Printf("  [%d/%d] Retry %d/%d...\n", i+1, count, attempt, maxRetries)
-					}
+			for attempt := 0; attempt <= maxRetries && len(pending) > 0; attempt++ {
+				if attempt > 0 {
+					fmt.Printf("  Retry %d/%d for %d seed(s)...\n", attempt, maxRetries, len(pending))
+				}
 
+				// Providers that cannot enforce the seed schema fail with
+				// ErrUnsupportedFormat; the next round then uses the
+				// delimiter-based contract.
+				var format llm.ResponseFormat
+				if promptBuilder.StructuredOutput {
+					format = llm.ResponseFormat{Type: llm.FormatJSONSchema, Name: "seed", Schema: promptBuilder.ResponseSchema()}
+				}
+				var batch []int
+				var requests []llm.Request
+				for _, i := range pending {
 					generatePrompt, promptErr := promptBuilder.BuildGeneratePrompt(basePath)
 					if promptErr != nil {
-						lastErr = fmt.Errorf("failed to build generate prompt: %w", promptErr)
+						lastErrs[i] = fmt.Errorf("failed to build generate prompt: %w", promptErr)
 						continue
 					}
-
-					// Get raw LLM response
-					response, llmErr := llmClient.GetCompletionWithSystem(systemPrompt, generatePrompt)
-					if llmErr != nil {
-						fmt.Printf("  [%d/%d] LLM request failed: %v\n", i+1, count, llmErr)
-						lastErr = llmErr
+					batch = append(batch, i)
+					requests = append(requests, llm.Request{
+						SystemPrompt: systemPrompt,
+						UserPrompt:   generatePrompt,
+						Format:       format,
+					})
+				}
+				responses, batchErr := llm.GetCompletions(ctx, llmClient, requests, cfg.LLM.BatchConcurrency)
+
+				var failed []int
+				var unsupported error
+				for j, i := range batch {
+					if err := responses[j].Err; err != nil {
+						fmt.Printf("  [%d/%d] LLM request failed: %v\n", i+1, count, err)
+						lastErrs[i] = err
+						failed = append(failed, i)
+						if errors.Is(err, llm.ErrUnsupportedFormat) {
+							unsupported = err
+						}
 						continue
 					}
 
 					// Parse response using prompt builder (handles different modes)
-					newSeed, lastErr = promptBuilder.ParseLLMResponse(response)
-					if lastErr != nil {
-						fmt.Printf("  [%d/%d] Parse failed: %v\n", i+1, count, lastErr)
+					newSeed, parseErr := promptBuilder.ParseLLMResponse(responses[j].Text)
+					if parseErr != nil {
+						fmt.Printf("  [%d/%d] Parse failed: %v\n", i+1, count, parseErr)
+						lastErrs[i] = parseErr
+						failed = append(failed, i)
 						continue
 					}
 
-					// Success - break retry loop
-					lastErr = nil
-					break
-				}
+					// Set metadata for the seed
+					newSeed.Meta.ID = uint64(i + 1)
+					newSeed.Meta.ParentID = 0 // Initial seeds have no parent
+					newSeed.Meta.Depth = 0
+					newSeed.Meta.State = seed.SeedStatePending
 
-				if lastErr != nil {
-					fmt.Printf("  [%d/%d] Failed after %d retries: %v\n", i+1, count, maxRetries, lastErr)
-					continue
-				}
+					// Save using the new metadata-based format
+					filename, saveErr := seed.SaveSeedWithMetadata(basePath, newSeed, namer)
+					if saveErr != nil {
+						fmt.Printf("  [%d/%d] Failed to save: %v\n", i+1, count, saveErr)
+						continue
+					}
 
-				// Set metadata for the seed
-				newSeed.Meta.ID = uint64(i + 1)
-				newSeed.Meta.ParentID = 0 // Initial seeds have no parent
-				newSeed.Meta.Depth = 0
-				newSeed.Meta.State = seed.SeedStatePending
-
-				// Save using the new metadata-based format
-				filename, saveErr := seed.SaveSeedWithMetadata(basePath, newSeed, namer)
-				if saveErr != nil {
-					fmt.Printf("  [%d/%d] Failed to save: %v\n", i+1, count, saveErr)
-					continue
+					successCount++
+					fmt.Printf("  [%d/%d] Generated seed: %s\n", i+1, count, filename)
 				}
-
-				successCount++
-				fmt.Printf("  [%d/%d] Generated seed: %s\n", i+1, count, filename)
+				for _, i := range pending {
+					if lastErrs[i] != nil && !slices.Contains(batch, i) {
+						failed = append(failed, i)
+					}
+				}
+				pending = failed
+				if unsupported != nil && promptBuilder.StructuredOutput {
+					fmt.Printf("[Generate] %v; switching to the delimiter-based output contract\n", unsupported)
+					promptBuilder.StructuredOutput = false
+				}
+				if batchErr != nil {
+					break
+				}
+			}
+			for _, i := range pending {
+				fmt.Printf("  [%d/%d] Failed after %d retries: %v\n", i+1, count, maxRetries, lastErrs[i])
 			}
 
 			if successCount == 0 {
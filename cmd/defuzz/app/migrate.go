@@ -0,0 +1,72 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/zjy-dev/de-fuzz/internal/config"
+	"github.com/zjy-dev/de-fuzz/internal/corpus"
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// NewMigrateCommand creates the "migrate" subcommand.
+func NewMigrateCommand() *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Move a flat corpus into the sharded on-disk layout.",
+		Long: `Move the seed directories of a fuzzing corpus into shards.
+
+A flat corpus keeps every seed directory directly under corpus/, which gets
+slow to list past tens of thousands of seeds. The sharded layout keeps each
+seed in corpus/<ab>/, where ab are the first two hex digits of its content
+hash; corpus/layout.json records it, and new seeds follow. Seed metadata is
+updated to the new paths. Both layouts are read transparently, so an
+interrupted migration is safe to run again.
+
+Run it between fuzzing sessions.
+
+Examples:
+  # Shard the corpus in the configured output directory
+  defuzz migrate
+
+  # Shard a corpus elsewhere
+  defuzz migrate --output my_fuzz_out`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			if !cmd.Flags().Changed("output") {
+				output = cfg.Compiler.Fuzz.OutputRootDir
+			}
+			return runMigrate(cfg, filepath.Join(output, cfg.ISA, cfg.Strategy))
+		},
+	}
+
+	cmd.Flags().StringVar(&output, "output", "fuzz_out", "Output directory (corpus at {output}/{isa}/{strategy})")
+
+	return cmd
+}
+
+func runMigrate(cfg *config.Config, outputDir string) error {
+	corpusManager := corpus.NewFileManager(outputDir)
+	if cfg.Compiler.Fuzz.Dedup != "off" {
+		corpusManager.SetDedup(seed.HashStrictness(cfg.Compiler.Fuzz.Dedup))
+	}
+	if err := corpusManager.Recover(); err != nil {
+		return fmt.Errorf("failed to open corpus: %w", err)
+	}
+
+	moved, err := corpusManager.Migrate()
+	if err != nil {
+		return fmt.Errorf("failed to migrate corpus: %w", err)
+	}
+
+	fmt.Printf("[Migrate] Moved %d seeds into shards of %s\n",
+		moved, filepath.Join(outputDir, corpus.CorpusDir))
+	return nil
+}
@@ -14,6 +14,8 @@ func NewDefuzzCommand() *cobra.Command {
 
 	cmd.AddCommand(NewGenerateCommand())
 	cmd.AddCommand(NewFuzzCommand())
+	cmd.AddCommand(NewTrimCommand())
+	cmd.AddCommand(NewMigrateCommand())
 
 	return cmd
 }
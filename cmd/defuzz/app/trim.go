@@ -0,0 +1,91 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/zjy-dev/de-fuzz/internal/config"
+	"github.com/zjy-dev/de-fuzz/internal/corpus"
+	"github.com/zjy-dev/de-fuzz/internal/coverage"
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// NewTrimCommand creates the "trim" subcommand.
+func NewTrimCommand() *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "trim",
+		Short: "Archive corpus seeds whose coverage other seeds subsume.",
+		Long: `Archive the seeds of a fuzzing corpus that cover no line on their own.
+
+A seed is trimmed when every line it covers in the coverage mapping is also
+covered by another seed that stays; newer seeds win. Bug-triggering seeds and
+initial seeds are always kept. Trimmed seeds move to {output}/{isa}/{strategy}/archive/
+and leave the coverage mapping; their metadata is kept with state ARCHIVED.
+
+Run it between fuzzing sessions, or set fuzz.trim_every to trim during a run.
+
+Examples:
+  # Trim the corpus in the configured output directory
+  defuzz trim
+
+  # Trim a corpus elsewhere
+  defuzz trim --output my_fuzz_out`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			if !cmd.Flags().Changed("output") {
+				output = cfg.Compiler.Fuzz.OutputRootDir
+			}
+			return runTrim(cfg, filepath.Join(output, cfg.ISA, cfg.Strategy))
+		},
+	}
+
+	cmd.Flags().StringVar(&output, "output", "fuzz_out", "Output directory (corpus at {output}/{isa}/{strategy})")
+
+	return cmd
+}
+
+func runTrim(cfg *config.Config, outputDir string) error {
+	corpusManager := corpus.NewFileManager(outputDir)
+	if cfg.Compiler.Fuzz.Dedup != "off" {
+		corpusManager.SetDedup(seed.HashStrictness(cfg.Compiler.Fuzz.Dedup))
+	}
+	if err := corpusManager.Recover(); err != nil {
+		return fmt.Errorf("failed to open corpus: %w", err)
+	}
+
+	mappingPath := cfg.Compiler.Fuzz.MappingPath
+	if mappingPath == "" {
+		mappingPath = filepath.Join(outputDir, corpus.StateDir, "coverage_mapping.json")
+	}
+	mapping, err := coverage.NewCoverageMapping(mappingPath)
+	if err != nil {
+		return fmt.Errorf("failed to load coverage mapping: %w", err)
+	}
+	if err := corpusManager.CheckMapping(mapping); err != nil {
+		return err
+	}
+
+	removed, err := corpusManager.Trim(mapping, nil)
+	if removed > 0 {
+		if err := mapping.Save(mappingPath); err != nil {
+			return fmt.Errorf("failed to save coverage mapping: %w", err)
+		}
+		if err := corpusManager.Save(); err != nil {
+			return fmt.Errorf("failed to save corpus state: %w", err)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to trim corpus: %w", err)
+	}
+
+	fmt.Printf("[Trim] Archived %d seeds to %s; %d seeds remain\n",
+		removed, filepath.Join(outputDir, corpus.ArchiveDir), corpusManager.Len())
+	return nil
+}
@@ -46,6 +46,7 @@ type Compiler interface {
 type GCCCompiler struct {
 	executor   exec.Executor
 	gccPath    string   // Path to gcc executable (e.g., "gcc" or "/usr/bin/aarch64-linux-gnu-gcc")
+	cxxPath    string   // Path to the C++ driver used for C++ seeds
 	workDir    string   // Working directory for compilation
 	prefixPath string   // -B prefix path for compiler components (cc1, as, ld, etc.)
 	cflags     []string // Additional compiler flags as a slice
@@ -55,6 +56,7 @@ type GCCCompiler struct {
 // GCCCompilerConfig holds the configuration for GCCCompiler.
 type GCCCompilerConfig struct {
 	GCCPath          string   // Path to GCC executable
+	CXXPath          string   // C++ driver for C++ seeds (optional, derived from GCCPath)
 	WorkDir          string   // Working directory
 	PrefixPath       string   // -B prefix path for finding compiler components (cc1, as, ld)
 	CFlags           []string // Additional compiler flags as a slice
@@ -66,6 +68,7 @@ func NewGCCCompiler(cfg GCCCompilerConfig) *GCCCompiler {
 	return &GCCCompiler{
 		executor:   exec.NewCommandExecutor(),
 		gccPath:    cfg.GCCPath,
+		cxxPath:    cfg.CXXPath,
 		workDir:    cfg.WorkDir,
 		prefixPath: cfg.PrefixPath,
 		cflags:     cfg.CFlags,
@@ -83,6 +86,19 @@ func (c *GCCCompiler) GetWorkDir() string {
 	return c.workDir
 }
 
+// TargetTriple asks the compiler for its target triple (gcc -dumpmachine).
+func (c *GCCCompiler) TargetTriple() (string, error) {
+	result, err := c.executor.Run(c.gccPath, "-dumpmachine")
+	if err != nil {
+		return "", fmt.Errorf("failed to run %s -dumpmachine: %w", c.gccPath, err)
+	}
+	triple := strings.TrimSpace(result.Stdout)
+	if result.ExitCode != 0 || triple == "" {
+		return "", fmt.Errorf("%s -dumpmachine failed (exit %d): %s", c.gccPath, result.ExitCode, strings.TrimSpace(result.Stderr))
+	}
+	return triple, nil
+}
+
 func (c *GCCCompiler) compile(s *seed.Seed) (*CompileResult, error) {
 	// Ensure work directory exists
 	if err := os.MkdirAll(c.workDir, 0755); err != nil {
@@ -90,7 +106,7 @@ func (c *GCCCompiler) compile(s *seed.Seed) (*CompileResult, error) {
 	}
 
 	// Write source file
-	sourceFile := filepath.Join(c.workDir, fmt.Sprintf("seed_%d.c", s.Meta.ID))
+	sourceFile := filepath.Join(c.workDir, fmt.Sprintf("seed_%d%s", s.Meta.ID, s.Language.Extension()))
 	if err := os.WriteFile(sourceFile, []byte(s.Content), 0644); err != nil {
 		return nil, fmt.Errorf("failed to write source file: %w", err)
 	}
@@ -193,7 +209,54 @@ func (c *GCCCompiler) buildCompileCommand(s *seed.Seed, sourceFile, binaryPath s
 	args = append(args, effectiveFlags...)
 	args = append(args, sourceFile, "-o", binaryPath)
 
-	return c.gccPath, args, prefixFlags, effectiveFlags, appliedLLMCFlags, droppedLLMCFlags
+	return c.driver(s.Language), args, prefixFlags, effectiveFlags, appliedLLMCFlags, droppedLLMCFlags
+}
+
+// driver returns the compiler driver for a seed language. C++ seeds use
+// CXXPath, or else the C++ driver next to a C driver (gcc -> g++,
+// xgcc -> xg++, aarch64-linux-gnu-gcc-12 -> aarch64-linux-gnu-g++-12,
+// clang -> clang++) so libstdc++ is linked; other paths are used as is.
+func (c *GCCCompiler) driver(lang seed.Language) string {
+	if lang.OrDefault() != seed.LanguageCPP {
+		return c.gccPath
+	}
+	if c.cxxPath != "" {
+		return c.cxxPath
+	}
+	return CXXDriver(c.gccPath)
+}
+
+// CXXDriver maps the path of a C driver to its C++ counterpart in the same
+// directory. Paths that name no known C driver are returned unchanged.
+func CXXDriver(gccPath string) string {
+	dir, base := filepath.Split(gccPath)
+	version := ""
+	if i := strings.LastIndexByte(base, '-'); i >= 0 && isVersion(base[i+1:]) {
+		base, version = base[:i], base[i:]
+	}
+	switch {
+	case strings.HasSuffix(base, "gcc"):
+		base = strings.TrimSuffix(base, "gcc") + "g++"
+	case strings.HasSuffix(base, "clang"):
+		base += "++"
+	default:
+		return gccPath
+	}
+	return dir + base + version
+}
+
+// isVersion reports whether s looks like a driver version suffix ("12",
+// "14.2").
+func isVersion(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if (r < '0' || r > '9') && r != '.' {
+			return false
+		}
+	}
+	return true
 }
 
 // ToCompilationRecord converts a compile result into a seed-level record for persistence.
@@ -5,6 +5,7 @@ package compiler
 import (
 	"os"
 	"os/exec"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -52,6 +53,48 @@ int main() {
 	assert.Contains(t, string(output), "Hello, World!")
 }
 
+// TestGCCCompiler_Integration_CompileCppSeed compiles a C++ seed using
+// templates and exceptions through the g++ driver derived from "gcc".
+func TestGCCCompiler_Integration_CompileCppSeed(t *testing.T) {
+	if _, err := exec.LookPath("g++"); err != nil {
+		t.Skip("g++ not found, skipping integration test")
+	}
+
+	compiler := NewGCCCompiler(GCCCompilerConfig{
+		GCCPath: "gcc",
+		WorkDir: t.TempDir(),
+		CFlags:  []string{"-O0"},
+	})
+
+	testSeed := &seed.Seed{
+		Meta:     seed.Metadata{ID: 3},
+		Language: seed.LanguageCPP,
+		Content: `
+#include <iostream>
+#include <stdexcept>
+template <typename T> T twice(T x) { return x + x; }
+int main() {
+    try {
+        throw std::runtime_error("caught");
+    } catch (const std::exception &e) {
+        std::cout << e.what() << " " << twice(21) << std::endl;
+    }
+    return 0;
+}
+`,
+	}
+
+	result, err := compiler.Compile(testSeed)
+	require.NoError(t, err)
+	require.True(t, result.Success, "Compilation should succeed: %s", result.Stderr)
+	assert.Equal(t, "g++", result.CompilerPath)
+	assert.FileExists(t, filepath.Join(compiler.GetWorkDir(), "seed_3.cpp"))
+
+	output, err := exec.Command(result.BinaryPath).Output()
+	require.NoError(t, err)
+	assert.Contains(t, string(output), "caught 42")
+}
+
 // TestGCCCompiler_Integration_CompileWithWarnings tests that warnings don't cause failure.
 func TestGCCCompiler_Integration_CompileWithWarnings(t *testing.T) {
 	_, err := exec.LookPath("gcc")
@@ -3,6 +3,7 @@ package compiler
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -49,6 +50,29 @@ func TestGCCCompiler_GetWorkDir(t *testing.T) {
 	assert.Equal(t, "/custom/work/dir", compiler.GetWorkDir())
 }
 
+func TestGCCCompiler_TargetTriple(t *testing.T) {
+	compiler := NewGCCCompiler(GCCCompilerConfig{GCCPath: "/opt/gcc/bin/gcc"})
+	compiler.executor = &MockExecutor{
+		RunFunc: func(command string, args ...string) (*exec.ExecutionResult, error) {
+			assert.Equal(t, "/opt/gcc/bin/gcc", command)
+			assert.Equal(t, []string{"-dumpmachine"}, args)
+			return &exec.ExecutionResult{Stdout: "aarch64-linux-gnu\n"}, nil
+		},
+	}
+
+	triple, err := compiler.TargetTriple()
+	require.NoError(t, err)
+	assert.Equal(t, "aarch64-linux-gnu", triple)
+
+	compiler.executor = &MockExecutor{
+		RunFunc: func(command string, args ...string) (*exec.ExecutionResult, error) {
+			return &exec.ExecutionResult{ExitCode: 1, Stderr: "unrecognized option"}, nil
+		},
+	}
+	_, err = compiler.TargetTriple()
+	assert.Error(t, err)
+}
+
 func TestGCCCompiler_Compile_Success(t *testing.T) {
 	workDir, err := os.MkdirTemp("", "compiler_test_")
 	require.NoError(t, err)
@@ -216,6 +240,73 @@ func TestGCCCompiler_SourceFileWritten(t *testing.T) {
 	assert.Equal(t, sourceCode, string(content))
 }
 
+func TestGCCCompiler_SourceFileExtensionFollowsLanguage(t *testing.T) {
+	workDir := t.TempDir()
+	compiler := NewGCCCompiler(GCCCompilerConfig{GCCPath: "g++", WorkDir: workDir})
+
+	var compiledSource string
+	compiler.executor = &MockExecutor{
+		RunFunc: func(command string, args ...string) (*exec.ExecutionResult, error) {
+			for _, arg := range args {
+				if strings.HasPrefix(filepath.Base(arg), "seed_") && filepath.Ext(arg) != "" {
+					compiledSource = arg
+				}
+			}
+			return &exec.ExecutionResult{ExitCode: 0}, nil
+		},
+	}
+
+	for lang, ext := range map[seed.Language]string{seed.LanguageCPP: ".cpp", seed.LanguageRust: ".rs", "": ".c"} {
+		testSeed := &seed.Seed{Meta: seed.Metadata{ID: 7}, Content: "int main() {}", Language: lang}
+		_, err := compiler.Compile(testSeed)
+		require.NoError(t, err)
+
+		want := filepath.Join(workDir, "seed_7"+ext)
+		assert.FileExists(t, want)
+		assert.Equal(t, want, compiledSource)
+	}
+}
+
+func TestCXXDriver(t *testing.T) {
+	for path, want := range map[string]string{
+		"gcc":                            "g++",
+		"/build/gcc/xgcc":                "/build/gcc/xg++",
+		"/usr/bin/aarch64-linux-gnu-gcc": "/usr/bin/aarch64-linux-gnu-g++",
+		"gcc-12":                         "g++-12",
+		"clang-18":                       "clang++-18",
+		"g++":                            "g++",
+		"rustc":                          "rustc",
+	} {
+		assert.Equal(t, want, CXXDriver(path), path)
+	}
+}
+
+func TestGCCCompiler_CppSeedsUseCXXDriver(t *testing.T) {
+	var command string
+	mock := &MockExecutor{
+		RunFunc: func(cmd string, args ...string) (*exec.ExecutionResult, error) {
+			command = cmd
+			return &exec.ExecutionResult{ExitCode: 0}, nil
+		},
+	}
+
+	compiler := NewGCCCompiler(GCCCompilerConfig{GCCPath: "/build/gcc/xgcc", WorkDir: t.TempDir(), CFlags: []string{"-O0"}})
+	compiler.executor = mock
+	for lang, want := range map[seed.Language]string{"": "/build/gcc/xgcc", seed.LanguageCPP: "/build/gcc/xg++"} {
+		result, err := compiler.Compile(&seed.Seed{Meta: seed.Metadata{ID: 3}, Content: "int main() {}", Language: lang})
+		require.NoError(t, err)
+		assert.Equal(t, want, command)
+		assert.Equal(t, want, result.CompilerPath)
+		assert.Contains(t, result.EffectiveFlags, "-O0")
+	}
+
+	compiler = NewGCCCompiler(GCCCompilerConfig{GCCPath: "gcc", CXXPath: "/opt/g++", WorkDir: t.TempDir()})
+	compiler.executor = mock
+	_, err := compiler.Compile(&seed.Seed{Meta: seed.Metadata{ID: 4}, Content: "int main() {}", Language: seed.LanguageCPP})
+	require.NoError(t, err)
+	assert.Equal(t, "/opt/g++", command)
+}
+
 func TestCompileResult_ToCompilationRecord(t *testing.T) {
 	result := &CompileResult{
 		BinaryPath:       "/tmp/seed_1",
@@ -350,3 +441,60 @@ func TestNewCrossGCCCompiler(t *testing.T) {
 	assert.Equal(t, "aarch64", compiler.GetTargetArch())
 	assert.Equal(t, "/usr/aarch64-linux-gnu", compiler.sysroot)
 }
+
+func TestCompileResult_InternalError(t *testing.T) {
+	tests := []struct {
+		name   string
+		result *CompileResult
+		want   string
+	}{
+		{
+			name: "ice",
+			result: &CompileResult{Stderr: "seed.c: In function 'seed':\n" +
+				"seed.c:4:1: internal compiler error: in expand_expr_real_1, at expr.cc:10788\n0x8e1f2a expand_expr_real_1\n"},
+			want: "internal compiler error: in expand_expr_real_1, at expr.cc:10788",
+		},
+		{
+			name:   "crashed cc1",
+			result: &CompileResult{Stderr: "gcc: fatal error: Segmentation fault signal terminated program cc1\ncompilation terminated.\n"},
+			want:   "Segmentation fault in cc1",
+		},
+		{
+			name:   "bug report banner",
+			result: &CompileResult{Stderr: "xgcc: fatal error: Killed signal terminated program cc1\nPlease submit a full bug report, with preprocessed source.\n"},
+			want:   "compiler failure (Please submit a full bug report)",
+		},
+		{
+			name:   "seed error",
+			result: &CompileResult{Stderr: "seed.c:3:5: error: 'x' undeclared (first use in this function)\n"},
+		},
+		{
+			name:   "success",
+			result: &CompileResult{Success: true, Stderr: "warning: internal compiler error text in a string\n"},
+		},
+		{name: "nil"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.result.InternalError())
+		})
+	}
+}
+
+func TestCompileResult_ReportArtifacts(t *testing.T) {
+	dir := t.TempDir()
+	reportFile := filepath.Join(dir, "ccAbc123.out")
+	dumpFile := filepath.Join(dir, "ice.c.005t.original")
+	for _, path := range []string{reportFile, dumpFile} {
+		require.NoError(t, os.WriteFile(path, []byte("x"), 0644))
+	}
+
+	result := &CompileResult{
+		BinaryPath: filepath.Join(dir, "seed_1"),
+		Args:       []string{"-dumpbase", "ice.c", "seed_1.c", "-o", filepath.Join(dir, "seed_1")},
+		Stderr: "Preprocessed source stored into " + reportFile + " file, please attach this to your bugreport.\n" +
+			"Preprocessed source stored into /nonexistent/ccGone.out file, please attach this to your bugreport.\n",
+	}
+	assert.Equal(t, []string{reportFile, dumpFile}, result.ReportArtifacts())
+}
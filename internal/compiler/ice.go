@@ -0,0 +1,71 @@
+package compiler
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	// icePattern matches GCC's "internal compiler error: <what>" line.
+	icePattern = regexp.MustCompile(`internal compiler error:? *([^\n]*)`)
+	// crashedPassPattern matches a compiler proper killed by SIGSEGV, e.g.
+	// "gcc: fatal error: Segmentation fault signal terminated program cc1".
+	crashedPassPattern = regexp.MustCompile(`Segmentation fault[^\n]*\b(cc1plus|cc1|lto1)\b|\b(cc1plus|cc1|lto1)\b[^\n]*Segmentation fault`)
+	// reportBugPattern matches the file -freport-bug stores the
+	// preprocessed source and command line into.
+	reportBugPattern = regexp.MustCompile(`Preprocessed source stored into (\S+) file`)
+)
+
+// bugReportBanner is printed by GCC when it fails in a way it wants reported.
+const bugReportBanner = "Please submit a full bug report"
+
+// InternalError describes the internal compiler error a failed compilation
+// ended with, or returns "" when it failed on the seed (or succeeded). It
+// recognizes GCC's "internal compiler error" message, a segfaulting cc1,
+// cc1plus or lto1, and the bug report banner.
+func (r *CompileResult) InternalError() string {
+	if r == nil || r.Success {
+		return ""
+	}
+	if m := icePattern.FindStringSubmatch(r.Stderr); m != nil {
+		return strings.TrimSpace("internal compiler error: " + m[1])
+	}
+	if m := crashedPassPattern.FindStringSubmatch(r.Stderr); m != nil {
+		return "Segmentation fault in " + m[1] + m[2]
+	}
+	if strings.Contains(r.Stderr, bugReportBanner) {
+		return "compiler failure (" + bugReportBanner + ")"
+	}
+	return ""
+}
+
+// ReportArtifacts returns the files a crashed compilation left for a bug
+// report that still exist: the preprocessed source -freport-bug stored, and
+// the files named after the -dumpbase of the command, which is relative to
+// the output's directory.
+func (r *CompileResult) ReportArtifacts() []string {
+	var files []string
+	for _, m := range reportBugPattern.FindAllStringSubmatch(r.Stderr, -1) {
+		if _, err := os.Stat(m[1]); err == nil {
+			files = append(files, m[1])
+		}
+	}
+	for i, arg := range r.Args {
+		if arg != "-dumpbase" || i+1 >= len(r.Args) {
+			continue
+		}
+		base := r.Args[i+1]
+		if !filepath.IsAbs(base) {
+			base = filepath.Join(filepath.Dir(r.BinaryPath), base)
+		}
+		matches, _ := filepath.Glob(base + "*")
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && info.Mode().IsRegular() {
+				files = append(files, match)
+			}
+		}
+	}
+	return files
+}
@@ -2,10 +2,12 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
@@ -19,9 +21,171 @@ type Config struct {
 	Strategy           string         `mapstructure:"strategy"`
 	LogLevel           string         `mapstructure:"log_level"`
 	LogDir             string         `mapstructure:"log_dir"`
+	Prompt             PromptConfig   `mapstructure:"prompt"`
+	LLM                LLMConfig      `mapstructure:"llm"`
 	Compiler           CompilerConfig `mapstructure:"compiler"`
 }
 
+// PromptConfig holds prompt rendering settings.
+type PromptConfig struct {
+	// TemplateDir is a directory of text/template prompt overrides (optional).
+	// Files named like the embedded defaults (understand.tmpl, generate.tmpl,
+	// mutate.tmpl, constraint.tmpl, refined.tmpl) replace them; others keep
+	// the built-in wording.
+	TemplateDir string `mapstructure:"template_dir"`
+
+	// TokenBudget caps the estimated token count of constraint-solving prompts.
+	// Oversized prompts have their function context and base seed trimmed. 0 = unlimited.
+	TokenBudget int `mapstructure:"token_budget"`
+
+	// MaxPriorAttempts caps how many earlier failed seeds the refined prompt
+	// lists as "already tried"; PriorAttemptMaxChars clips each snippet.
+	// 0 keeps the prompt builder defaults.
+	MaxPriorAttempts     int `mapstructure:"max_prior_attempts"`
+	PriorAttemptMaxChars int `mapstructure:"prior_attempt_max_chars"`
+
+	// TestCaseSeparator overrides the marker between code and JSON test cases
+	// in LLM responses. Empty keeps "// ||||| JSON_TESTCASES_START |||||".
+	TestCaseSeparator string `mapstructure:"test_case_separator"`
+
+	// CandidatesPerCall asks each constraint-solving request for up to this
+	// many alternative seeds, all tried before the divergence retries. Extra
+	// candidates count against fuzz.max_constraint_retries. 0 or 1 = one seed
+	// per request.
+	CandidatesPerCall int `mapstructure:"candidates_per_call"`
+
+	// LineageDepth is how many ancestors of a mutated seed the mutation
+	// prompt summarizes (what each one changed). 0 = no lineage section.
+	LineageDepth int `mapstructure:"lineage_depth"`
+
+	// DivergencePrefixEntries and DivergencePathEntries bound the uftrace
+	// divergence shown to the LLM: the last common-prefix calls before the
+	// divergence point and the first calls of each divergent path.
+	// 0 keeps the prompt builder defaults (15 and 10).
+	DivergencePrefixEntries int `mapstructure:"divergence_prefix_entries"`
+	DivergencePathEntries   int `mapstructure:"divergence_path_entries"`
+
+	// DisableSystemOverrides makes divergence retries and compile repairs
+	// use their phase system prompt (base prompt + understanding) instead of
+	// a terse task-specific one.
+	DisableSystemOverrides bool `mapstructure:"disable_system_overrides"`
+
+	// DisableDiversityHints drops the generate prompt's "the corpus already
+	// has many X, prefer exploring Y" section computed from corpus construct
+	// statistics.
+	DisableDiversityHints bool `mapstructure:"disable_diversity_hints"`
+
+	// UnderstandingMaxTokens: an understanding.md estimated over this many
+	// tokens is compressed by the LLM to about UnderstandingTargetTokens
+	// (0 = half the threshold), keeping its attack-vector bullet points. The
+	// compressed version is saved as understanding.compressed.md. 0 = never.
+	UnderstandingMaxTokens    int `mapstructure:"understanding_max_tokens"`
+	UnderstandingTargetTokens int `mapstructure:"understanding_target_tokens"`
+
+	// UnderstandingVersion selects the understanding sent as the system
+	// prompt: "compressed" (once there is one) or "full".
+	// Default: "compressed"
+	UnderstandingVersion string `mapstructure:"understanding_version"`
+}
+
+// LLMConfig holds settings for how seeds are requested from the LLM.
+type LLMConfig struct {
+	// StructuredOutput asks for a single JSON object {"source", "test_cases",
+	// "cflags"} instead of code followed by the test-case separator, and asks
+	// the provider to enforce its JSON schema (a JSON object on DeepSeek).
+	// Providers that cannot enforce it switch the run back to the separator.
+	StructuredOutput bool `mapstructure:"structured_output"`
+
+	// Conversation keeps one chat session per constraint target: retries send
+	// only the failure feedback instead of rebuilding the full prompt. Clients
+	// without chat support fall back to stateless prompts.
+	Conversation bool `mapstructure:"conversation"`
+
+	// CacheOnHit decides what happens when an opening constraint prompt is
+	// byte-identical to an earlier one (same target, same base seed):
+	// "perturb" re-asks with a "produce a different approach" note,
+	// "reuse" reuses the cached completion, "off" disables the cache.
+	// Default: "perturb"
+	CacheOnHit string `mapstructure:"cache_on_hit"`
+
+	// CacheFile is an optional JSONL file that persists the response cache
+	// across runs; relative paths are resolved against the state directory.
+	CacheFile string `mapstructure:"cache_file"`
+
+	// CacheDir enables the on-disk completion cache: a request identical to
+	// an earlier one (provider, model, temperature, prompts) is answered from
+	// a file under this directory without calling the provider. Empty
+	// disables it; the fuzz command's --no-llm-cache flag also bypasses it.
+	CacheDir string `mapstructure:"cache_dir"`
+
+	// CacheTTLHours expires on-disk cache entries older than this many
+	// hours (0 = never).
+	CacheTTLHours int `mapstructure:"cache_ttl_hours"`
+
+	// RequestTimeoutSeconds bounds each LLM call, retries included, to this
+	// many seconds (0 = no limit beyond the providers' own timeouts).
+	RequestTimeoutSeconds int `mapstructure:"request_timeout_seconds"`
+
+	// Stream reads seed completions as they are generated and stops once
+	// the code (and test cases, when requested) have arrived, so trailing
+	// commentary is never waited for. Providers that cannot stream are
+	// asked as usual.
+	Stream bool `mapstructure:"stream"`
+
+	// StreamMaxTokens abandons a streamed completion once it runs past about
+	// this many tokens (0 = no cap). Only applies when Stream is set.
+	StreamMaxTokens int `mapstructure:"stream_max_tokens"`
+
+	// AuditLog is an optional JSONL file recording every LLM request: call
+	// type, target, seed, prompt, completion, usage, latency and error.
+	// Relative paths are resolved against the output directory. Empty
+	// disables it.
+	AuditLog string `mapstructure:"audit_log"`
+
+	// AuditFullPrompts logs prompts in full; by default each message is
+	// clipped to its first 2000 characters.
+	AuditFullPrompts bool `mapstructure:"audit_full_prompts"`
+
+	// AuditMaxMB rotates the audit log once it reaches this many megabytes,
+	// keeping the previous files as audit_log.1, .2, ... (0 = never rotate).
+	AuditMaxMB int `mapstructure:"audit_max_mb"`
+
+	// BatchConcurrency bounds how many LLM requests of a batch (e.g. the
+	// seeds of `defuzz generate`) are in flight at once (0 = 4). The
+	// remixer's rate_limit still applies to each of them.
+	BatchConcurrency int `mapstructure:"batch_concurrency"`
+
+	// Sampling overrides by call type. Generation applies to new seeds
+	// (constraint solving and `defuzz generate`), Mutation to random-phase
+	// mutations, Refinement to divergence retries and compile fixes, and
+	// Understanding to understanding summaries. Unset fields fall back to
+	// default_temperature and the providers' own settings.
+	Generation    SamplingConfig `mapstructure:"generation"`
+	Mutation      SamplingConfig `mapstructure:"mutation"`
+	Refinement    SamplingConfig `mapstructure:"refinement"`
+	Understanding SamplingConfig `mapstructure:"understanding"`
+}
+
+// SamplingConfig overrides the sampling parameters of one LLM call type.
+type SamplingConfig struct {
+	Temperature *float64 `mapstructure:"temperature"` // 0-2
+	TopP        *float64 `mapstructure:"top_p"`       // (0, 1]
+	MaxTokens   int      `mapstructure:"max_tokens"`  // Reply cap, 0 = provider setting
+}
+
+func (s SamplingConfig) validate(name string) error {
+	if s.Temperature != nil && (*s.Temperature < 0 || *s.Temperature > 2) {
+		return fmt.Errorf("invalid llm.%s.temperature %g: must be in [0, 2]", name, *s.Temperature)
+	}
+	if s.TopP != nil && (*s.TopP <= 0 || *s.TopP > 1) {
+		return fmt.Errorf("invalid llm.%s.top_p %g: must be in (0, 1]", name, *s.TopP)
+	}
+	if s.MaxTokens < 0 {
+		return fmt.Errorf("invalid llm.%s.max_tokens %d: must be >= 0", name, s.MaxTokens)
+	}
+	return nil
+}
+
 // FuzzConfig holds the configuration for the fuzzing process.
 // These values serve as defaults and can be overridden by command line flags.
 type FuzzConfig struct {
@@ -32,6 +196,12 @@ type FuzzConfig struct {
 	// MaxIterations is the maximum number of fuzzing iterations (0 = unlimited)
 	MaxIterations int `mapstructure:"max_iterations"`
 
+	// MaxDuration is the wall-clock budget of the campaign, as a Go
+	// duration ("12h", "90m"). Once it is spent the current iteration
+	// finishes and fuzzing stops; with MaxIterations, whichever limit is
+	// reached first applies. Default: 0 (no limit)
+	MaxDuration time.Duration `mapstructure:"max_duration"`
+
 	// MaxNewSeeds is the maximum new seeds to generate per interesting seed
 	MaxNewSeeds int `mapstructure:"max_new_seeds"`
 
@@ -39,6 +209,19 @@ type FuzzConfig struct {
 	// If 0, test cases will not be generated (useful for oracles like canary that don't need test cases)
 	MaxTestCases int `mapstructure:"max_test_cases"`
 
+	// StrictTestCases rejects LLM responses with more than MaxTestCases test
+	// cases so the engine retries; by default the extras are dropped.
+	StrictTestCases bool `mapstructure:"strict_test_cases"`
+
+	// AllowShellTestCommands permits pipes, ";", "&&" and command substitution
+	// in test case running commands. Default: false
+	AllowShellTestCommands bool `mapstructure:"allow_shell_test_commands"`
+
+	// RequestTags asks the generate and mutate prompts to label each seed
+	// with the vulnerability patterns it exercises (vla, alloca, longjmp,
+	// fmt-string, ...), stored as the seed's tags. Default: false
+	RequestTags bool `mapstructure:"request_tags"`
+
 	// FunctionTemplate is the path to a C code template file (optional)
 	// If provided, LLM will only generate the function body, and the result will be merged with the template
 	// This is useful for strategies like canary where we need specific program structure
@@ -80,14 +263,166 @@ type FuzzConfig struct {
 	// per target basic block when constraint solving fails (default: 3)
 	MaxConstraintRetries int `mapstructure:"max_constraint_retries"`
 
+	// AdaptiveRetries scales max_constraint_retries per target: by the share
+	// of its weight the target kept through weight decay, and by the hit
+	// rate of the last 20 targets, between 1 and twice the setting. The
+	// budget is logged with the target. Default: false
+	AdaptiveRetries bool `mapstructure:"adaptive_retries"`
+
+	// MaxCompileFixRetries is how many times a mutated seed that fails to
+	// compile is sent back to the LLM for a minimal fix before it is given
+	// up on. 0 disables compile fixes. Default: 2
+	MaxCompileFixRetries int `mapstructure:"max_compile_fix_retries"`
+
+	// TargetCooldown is how many iterations a target BB that exhausted its
+	// constraint retries is passed over in, so its decayed weight does not
+	// make it the target again right away. 0 disables it. Default: 5
+	TargetCooldown int `mapstructure:"target_cooldown"`
+
+	// TargetSchedule shares iterations between target functions: "weighted"
+	// targets the best BB of any function; "round_robin" cycles through the
+	// functions; "stride" gives each function iterations in proportion to
+	// its uncovered BBs. A function with nothing left to target yields its
+	// turn to "weighted" selection. Default: "weighted"
+	TargetSchedule string `mapstructure:"target_schedule"`
+
 	// WeightDecayFactor is the multiplier applied to BB weight after failed iteration
 	// Valid range: (0, 1], default: 0.8
 	WeightDecayFactor float64 `mapstructure:"weight_decay_factor"`
 
+	// MinTargetSuccessors is the minimum successor count a BB needs to be
+	// preferred as a target. Straight-line BBs below this are only selected
+	// once no other uncovered candidates remain. Default: 0 (no filtering)
+	MinTargetSuccessors int `mapstructure:"min_target_successors"`
+
+	// ExecuteSeeds controls whether compiled seed binaries are executed.
+	// Compiler coverage (.gcda) is produced by compilation alone, so execution
+	// only matters for oracles that inspect runtime behavior.
+	// "auto" executes only when the configured oracle needs it,
+	// "always" executes whenever an oracle runs, "never" never executes.
+	// Default: "auto"
+	ExecuteSeeds string `mapstructure:"execute_seeds"`
+
+	// DryRunPrompts writes each target's system and user prompts to
+	// {output}/dry_run_prompts instead of calling the LLM, to inspect what a
+	// run would send. Iterations are counted as skipped. Default: false
+	DryRunPrompts bool `mapstructure:"dry_run_prompts"`
+
+	// DryRun checks the pipeline once instead of fuzzing, without LLM calls:
+	// the coverage self-test, CFG parsing, compiling and measuring one corpus
+	// seed and the oracle on it. Stage timings go to
+	// {output}/dry_run_report.json; any failed stage fails the run.
+	// Default: false
+	DryRun bool `mapstructure:"dry_run"`
+
+	// SeedLanguage is the source language of generated seeds: "c" (default),
+	// "cpp" or "rust". It selects prompt wording and seed file extensions.
+	// C++ seeds are compiled with compiler.cxx_path (derived from
+	// compiler.path by default); for rust, compiler.path must be rustc.
+	SeedLanguage string `mapstructure:"seed_language"`
+
+	// Dedup sets how the corpus recognizes a seed it already holds, which
+	// is then not added again: "whitespace" ignores whitespace between
+	// tokens, "comments" also ignores comments, "exact" compares bytes and
+	// "off" disables deduplication. CFlags always count.
+	// Default: "whitespace"
+	Dedup string `mapstructure:"dedup"`
+
+	// CompressSeeds saves the sources of new corpus seeds gzip-compressed
+	// (source.c.gz) to keep large corpora small. Corpora mixing both forms
+	// load transparently. Default: false
+	CompressSeeds bool `mapstructure:"compress_seeds"`
+
+	// Schedule sets how the corpus picks the next queued seed: "fifo" takes
+	// them in order; "explore", "exploit" and "fast" sample by an energy
+	// score favoring seeds, and children of seeds, that added coverage or
+	// found bugs, AFL-style. Default: "fast"
+	Schedule string `mapstructure:"schedule"`
+
+	// FavoredWeight is how much likelier seeds in the favored set (a small
+	// set covering every line the coverage mapping has) are picked: as the
+	// next corpus seed under the energy schedules, and as the base seed for
+	// a target line. 1 disables the bias. Default: 4
+	FavoredWeight float64 `mapstructure:"favored_weight"`
+
+	// TrimEvery archives the corpus seeds whose coverage other seeds
+	// subsume every this many iterations; bug and initial seeds are kept.
+	// "defuzz trim" does the same offline. Default: 0 (never)
+	TrimEvery int `mapstructure:"trim_every"`
+
+	// ProgressIntervalSeconds is how often a running campaign logs its
+	// progress (iterations/hour, LLM calls, compile failure rate, coverage,
+	// targets hit, bugs, time since the last coverage gain, current target)
+	// and writes it to {output}/status.json. 0 disables it. Default: 60
+	ProgressIntervalSeconds int `mapstructure:"progress_interval_seconds"`
+
+	// MetricsAddr is the address (e.g. ":9090") of an HTTP listener serving
+	// Prometheus metrics at /metrics while fuzzing. Default: "" (off)
+	MetricsAddr string `mapstructure:"metrics_addr"`
+
+	// EventLog is a JSONL file, relative to the output directory unless
+	// absolute, the engine appends its loop events to (iterations, targets,
+	// seeds measured, coverage gains, bugs, state saves). Default: "" (off)
+	EventLog string `mapstructure:"event_log"`
+
+	// IterationLog is a JSONL file, relative to the output directory unless
+	// absolute, that gets one record per iteration: the target, retries used,
+	// the seeds tried with their compile class, coverage before and after,
+	// oracle verdicts, time per stage and LLM tokens. "" disables it.
+	// Default: "events.jsonl"
+	IterationLog string `mapstructure:"iteration_log"`
+
+	// Corpus caps the corpus size; seeds past the caps are evicted as they
+	// are added.
+	Corpus CorpusLimitsConfig `mapstructure:"corpus"`
+
+	// MinimizeBugs shrinks every bug-triggering seed by delta debugging
+	// before the bug is recorded; the minimized source is saved as
+	// minimized.c (or .cpp / .rs) in the seed directory. Every candidate is
+	// compiled and run through the oracle, so an LLM oracle makes one LLM
+	// call per candidate. MinimizeMaxChecks caps candidates per seed
+	// (0 = 200) and MinimizeTimeoutSeconds the time per seed (0 = no limit).
+	MinimizeBugs           bool `mapstructure:"minimize_bugs"`
+	MinimizeMaxChecks      int  `mapstructure:"minimize_max_checks"`
+	MinimizeTimeoutSeconds int  `mapstructure:"minimize_timeout_seconds"`
+
+	// ValidateSeeds compiles every pending corpus seed with the configured
+	// compiler and flags before fuzzing starts, without running it. Seeds
+	// that fail are marked INVALID and skipped. ValidateWorkers caps the
+	// parallel compilations (0 = number of CPUs). Default: false
+	ValidateSeeds   bool `mapstructure:"validate_seeds"`
+	ValidateWorkers int  `mapstructure:"validate_workers"`
+
+	// AuxContextFiles lists extra context files (paths relative to the strategy
+	// base directory, e.g. initial_seeds/{isa}/{strategy}) rendered under their
+	// own headings in the understand and generate prompts.
+	// Default: ["stack_layout.md"]
+	AuxContextFiles []string `mapstructure:"aux_context_files"`
+
+	// AuxContextMaxBytes caps the size of each auxiliary context file.
+	// 0 keeps the prompt builder default (16 KiB).
+	AuxContextMaxBytes int `mapstructure:"aux_context_max_bytes"`
+
 	// FlagStrategy controls rule-driven compiler flag scheduling during fuzzing.
 	FlagStrategy FlagStrategyConfig `mapstructure:"flag_strategy"`
 }
 
+// CorpusLimitsConfig caps the corpus. When Add takes it past a cap, the
+// seeds with the lowest energy are evicted first; bug, initial and favored
+// seeds never are.
+type CorpusLimitsConfig struct {
+	// MaxSeeds caps the seeds in the corpus. Default: 0 (no cap)
+	MaxSeeds int `mapstructure:"max_seeds"`
+
+	// MaxDiskMB caps the size of the corpus directory in MiB.
+	// Default: 0 (no cap)
+	MaxDiskMB int `mapstructure:"max_disk_mb"`
+
+	// HardEvict deletes evicted seeds instead of moving them to the archive
+	// directory next to the corpus. Default: false
+	HardEvict bool `mapstructure:"hard_evict"`
+}
+
 // CompilerInfo holds basic compiler identification from the main config.
 type CompilerInfo struct {
 	Name    string `mapstructure:"name"`
@@ -103,6 +438,26 @@ type OracleConfig struct {
 	Options map[string]interface{} `mapstructure:"options"`
 }
 
+// ReferenceCompilerConfig configures the reference compiler of differential
+// mode. Coverage is only ever measured on the primary compiler's builds.
+type ReferenceCompilerConfig struct {
+	// Path is the reference compiler executable; empty disables differential mode
+	Path string `mapstructure:"path"`
+
+	// CXXPath is the reference C++ driver, derived from Path when empty
+	CXXPath string `mapstructure:"cxx_path"`
+
+	// CFlags replace compiler.cflags for the reference build when set
+	CFlags []string `mapstructure:"cflags"`
+
+	// UseQEMU runs the reference binaries under QEMU, independently of
+	// fuzz.use_qemu. QEMUPath and QEMUSysroot default to fuzz.qemu_path and
+	// fuzz.qemu_sysroot.
+	UseQEMU     bool   `mapstructure:"use_qemu"`
+	QEMUPath    string `mapstructure:"qemu_path"`
+	QEMUSysroot string `mapstructure:"qemu_sysroot"`
+}
+
 // TargetFunction specifies a source file and the functions within it to track for coverage.
 // This is used for fine-grained coverage analysis and CFG-based fuzzing.
 type TargetFunction struct {
@@ -126,6 +481,10 @@ type CompilerConfig struct {
 	// Path is the path to the compiler executable (e.g., /path/to/gcc)
 	Path string `mapstructure:"path"`
 
+	// CXXPath is the C++ driver used for fuzz.seed_language "cpp" (optional).
+	// Empty derives it from Path: gcc -> g++, xgcc -> xg++.
+	CXXPath string `mapstructure:"cxx_path"`
+
 	// GcovrExecPath is the path to gcovr executable for coverage analysis
 	GcovrExecPath string `mapstructure:"gcovr_exec_path"`
 
@@ -146,12 +505,26 @@ type CompilerConfig struct {
 	// that allows the fuzzer to resume from where it left off after interruption
 	TotalReportPath string `mapstructure:"total_report_path"`
 
+	// GcovPrefix redirects .gcda files written by instrumented target libraries
+	// when seed binaries run (optional, mainly for cross compilers under QEMU).
+	// It is exported as GCOV_PREFIX to the seed and added as a gcovr search path.
+	// Relative paths are resolved against the output directory.
+	GcovPrefix string `mapstructure:"gcov_prefix"`
+
+	// GcovPrefixStrip is the number of leading path components libgcov strips
+	// from the compiled-in object path before prepending GcovPrefix (GCOV_PREFIX_STRIP)
+	GcovPrefixStrip int `mapstructure:"gcov_prefix_strip"`
+
 	// Fuzz holds the fuzzing configuration for this compiler/ISA/strategy combination
 	Fuzz FuzzConfig `mapstructure:"fuzz"`
 
 	// Oracle holds the oracle configuration for this compiler/ISA/strategy combination
 	Oracle OracleConfig `mapstructure:"oracle"`
 
+	// Reference is a second compiler every seed is also built with, so the
+	// "diff" oracle can compare how both builds behave (optional).
+	Reference ReferenceCompilerConfig `mapstructure:"reference"`
+
 	// Targets specifies the source files and functions to focus on for coverage-guided fuzzing.
 	// This enables fine-grained control over which code paths the fuzzer should explore.
 	Targets []TargetFunction `mapstructure:"targets"`
@@ -280,6 +653,15 @@ func LoadEnvFromDotEnvRecursive(startDir string) error {
 	return nil
 }
 
+// resolveOutputPath is path as the fuzz command opens it: relative paths
+// are taken from outputDir.
+func resolveOutputPath(outputDir, path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(outputDir, path)
+}
+
 // strictDecodeOption returns a viper DecoderConfigOption that causes UnmarshalKey/Unmarshal
 // to error on any YAML key that does not map to a known struct field.
 func strictDecodeOption() viper.DecoderConfigOption {
@@ -458,6 +840,20 @@ func LoadConfig() (*Config, error) {
 		cfg.DefaultTemperature = 0.1
 	}
 
+	// Parse prompt settings (optional section)
+	if v.IsSet("config.prompt") {
+		if err := v.UnmarshalKey("config.prompt", &cfg.Prompt, strictDecodeOption()); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal prompt config: %w", err)
+		}
+	}
+
+	// Parse LLM settings (optional section)
+	if v.IsSet("config.llm") {
+		if err := v.UnmarshalKey("config.llm", &cfg.LLM, strictDecodeOption()); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal llm config: %w", err)
+		}
+	}
+
 	// Parse compiler name and version from config.yaml
 	var compilerInfo CompilerInfo
 	if err := v.UnmarshalKey("config.compiler", &compilerInfo); err != nil {
@@ -508,6 +904,9 @@ func LoadConfig() (*Config, error) {
 	if cfg.Compiler.Fuzz.MaxIterations == 0 {
 		cfg.Compiler.Fuzz.MaxIterations = 0 // 0 means unlimited
 	}
+	if cfg.Compiler.Fuzz.MaxDuration < 0 {
+		return nil, fmt.Errorf("invalid fuzz.max_duration %v: must be >= 0", cfg.Compiler.Fuzz.MaxDuration)
+	}
 	if cfg.Compiler.Fuzz.MaxNewSeeds == 0 {
 		cfg.Compiler.Fuzz.MaxNewSeeds = 3
 	}
@@ -520,9 +919,169 @@ func LoadConfig() (*Config, error) {
 	if cfg.Compiler.Fuzz.MaxConstraintRetries == 0 {
 		cfg.Compiler.Fuzz.MaxConstraintRetries = 32
 	}
+	if !compilerViper.IsSet("compiler.fuzz.max_compile_fix_retries") {
+		cfg.Compiler.Fuzz.MaxCompileFixRetries = 2
+	} else if cfg.Compiler.Fuzz.MaxCompileFixRetries < 0 {
+		return nil, fmt.Errorf("invalid fuzz.max_compile_fix_retries %d: must be >= 0", cfg.Compiler.Fuzz.MaxCompileFixRetries)
+	}
+	if !compilerViper.IsSet("compiler.fuzz.target_cooldown") {
+		cfg.Compiler.Fuzz.TargetCooldown = 5
+	} else if cfg.Compiler.Fuzz.TargetCooldown < 0 {
+		return nil, fmt.Errorf("invalid fuzz.target_cooldown %d: must be >= 0", cfg.Compiler.Fuzz.TargetCooldown)
+	}
+	if !compilerViper.IsSet("compiler.fuzz.iteration_log") {
+		cfg.Compiler.Fuzz.IterationLog = "events.jsonl"
+	}
+	// Both logs would interleave their records in one file
+	if eventLog, iterationLog := cfg.Compiler.Fuzz.EventLog, cfg.Compiler.Fuzz.IterationLog; eventLog != "" && iterationLog != "" {
+		outputDir := filepath.Join(cfg.Compiler.Fuzz.OutputRootDir, cfg.ISA, cfg.Strategy)
+		if resolveOutputPath(outputDir, eventLog) == resolveOutputPath(outputDir, iterationLog) {
+			return nil, fmt.Errorf("invalid fuzz.iteration_log %q: same file as fuzz.event_log %q", iterationLog, eventLog)
+		}
+	}
+	switch cfg.Compiler.Fuzz.TargetSchedule {
+	case "":
+		cfg.Compiler.Fuzz.TargetSchedule = "weighted"
+	case "weighted", "round_robin", "stride":
+	default:
+		return nil, fmt.Errorf("invalid fuzz.target_schedule %q: must be one of weighted, round_robin, stride",
+			cfg.Compiler.Fuzz.TargetSchedule)
+	}
 	if cfg.Compiler.Fuzz.WeightDecayFactor <= 0 || cfg.Compiler.Fuzz.WeightDecayFactor > 1 {
 		cfg.Compiler.Fuzz.WeightDecayFactor = 0.8
 	}
+	switch cfg.Compiler.Fuzz.ExecuteSeeds {
+	case "":
+		cfg.Compiler.Fuzz.ExecuteSeeds = "auto"
+	case "auto", "always", "never":
+	default:
+		return nil, fmt.Errorf("invalid fuzz.execute_seeds %q: must be one of auto, always, never",
+			cfg.Compiler.Fuzz.ExecuteSeeds)
+	}
+	if cfg.Compiler.GcovPrefix != "" && cfg.Compiler.Fuzz.ExecuteSeeds == "never" {
+		return nil, fmt.Errorf("invalid gcov_prefix %q: runtime coverage needs seed execution, but fuzz.execute_seeds is never",
+			cfg.Compiler.GcovPrefix)
+	}
+	if cfg.Compiler.Fuzz.MinimizeMaxChecks < 0 {
+		return nil, fmt.Errorf("invalid fuzz.minimize_max_checks %d: must be >= 0", cfg.Compiler.Fuzz.MinimizeMaxChecks)
+	}
+	if cfg.Compiler.Fuzz.MinimizeTimeoutSeconds < 0 {
+		return nil, fmt.Errorf("invalid fuzz.minimize_timeout_seconds %d: must be >= 0", cfg.Compiler.Fuzz.MinimizeTimeoutSeconds)
+	}
+	if cfg.Compiler.Fuzz.TrimEvery < 0 {
+		return nil, fmt.Errorf("invalid fuzz.trim_every %d: must be >= 0", cfg.Compiler.Fuzz.TrimEvery)
+	}
+	if !compilerViper.IsSet("compiler.fuzz.progress_interval_seconds") {
+		cfg.Compiler.Fuzz.ProgressIntervalSeconds = 60
+	} else if cfg.Compiler.Fuzz.ProgressIntervalSeconds < 0 {
+		return nil, fmt.Errorf("invalid fuzz.progress_interval_seconds %d: must be >= 0", cfg.Compiler.Fuzz.ProgressIntervalSeconds)
+	}
+	if addr := cfg.Compiler.Fuzz.MetricsAddr; addr != "" {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return nil, fmt.Errorf("invalid fuzz.metrics_addr %q: %w", addr, err)
+		}
+	}
+	if cfg.Compiler.Fuzz.Corpus.MaxSeeds < 0 {
+		return nil, fmt.Errorf("invalid fuzz.corpus.max_seeds %d: must be >= 0", cfg.Compiler.Fuzz.Corpus.MaxSeeds)
+	}
+	if cfg.Compiler.Fuzz.Corpus.MaxDiskMB < 0 {
+		return nil, fmt.Errorf("invalid fuzz.corpus.max_disk_mb %d: must be >= 0", cfg.Compiler.Fuzz.Corpus.MaxDiskMB)
+	}
+	if cfg.Compiler.Fuzz.ValidateWorkers < 0 {
+		return nil, fmt.Errorf("invalid fuzz.validate_workers %d: must be >= 0", cfg.Compiler.Fuzz.ValidateWorkers)
+	}
+	switch cfg.Compiler.Fuzz.Dedup {
+	case "":
+		cfg.Compiler.Fuzz.Dedup = "whitespace"
+	case "off", "exact", "whitespace", "comments":
+	default:
+		return nil, fmt.Errorf("invalid fuzz.dedup %q: must be one of off, exact, whitespace, comments",
+			cfg.Compiler.Fuzz.Dedup)
+	}
+	switch cfg.Compiler.Fuzz.Schedule {
+	case "":
+		cfg.Compiler.Fuzz.Schedule = "fast"
+	case "fifo", "explore", "exploit", "fast":
+	default:
+		return nil, fmt.Errorf("invalid fuzz.schedule %q: must be one of fifo, explore, exploit, fast",
+			cfg.Compiler.Fuzz.Schedule)
+	}
+	if cfg.Compiler.Fuzz.FavoredWeight == 0 {
+		cfg.Compiler.Fuzz.FavoredWeight = 4
+	} else if cfg.Compiler.Fuzz.FavoredWeight < 1 {
+		return nil, fmt.Errorf("invalid fuzz.favored_weight %v: must be >= 1", cfg.Compiler.Fuzz.FavoredWeight)
+	}
+	switch cfg.Compiler.Fuzz.SeedLanguage {
+	case "":
+		cfg.Compiler.Fuzz.SeedLanguage = "c"
+	case "c", "cpp", "rust":
+	default:
+		return nil, fmt.Errorf("invalid fuzz.seed_language %q: must be one of c, cpp, rust",
+			cfg.Compiler.Fuzz.SeedLanguage)
+	}
+	if cfg.Compiler.Fuzz.AuxContextMaxBytes < 0 {
+		return nil, fmt.Errorf("invalid fuzz.aux_context_max_bytes %d: must be >= 0", cfg.Compiler.Fuzz.AuxContextMaxBytes)
+	}
+	switch cfg.LLM.CacheOnHit {
+	case "":
+		cfg.LLM.CacheOnHit = "perturb"
+	case "perturb", "reuse", "off":
+	default:
+		return nil, fmt.Errorf("invalid llm.cache_on_hit %q: must be one of perturb, reuse, off", cfg.LLM.CacheOnHit)
+	}
+	if cfg.LLM.CacheTTLHours < 0 {
+		return nil, fmt.Errorf("invalid llm.cache_ttl_hours %d: must be >= 0", cfg.LLM.CacheTTLHours)
+	}
+	if cfg.LLM.RequestTimeoutSeconds < 0 {
+		return nil, fmt.Errorf("invalid llm.request_timeout_seconds %d: must be >= 0", cfg.LLM.RequestTimeoutSeconds)
+	}
+	if cfg.LLM.StreamMaxTokens < 0 {
+		return nil, fmt.Errorf("invalid llm.stream_max_tokens %d: must be >= 0", cfg.LLM.StreamMaxTokens)
+	}
+	if cfg.LLM.AuditMaxMB < 0 {
+		return nil, fmt.Errorf("invalid llm.audit_max_mb %d: must be >= 0", cfg.LLM.AuditMaxMB)
+	}
+	if cfg.LLM.BatchConcurrency < 0 {
+		return nil, fmt.Errorf("invalid llm.batch_concurrency %d: must be >= 0", cfg.LLM.BatchConcurrency)
+	}
+	for name, sampling := range map[string]SamplingConfig{
+		"generation":    cfg.LLM.Generation,
+		"mutation":      cfg.LLM.Mutation,
+		"refinement":    cfg.LLM.Refinement,
+		"understanding": cfg.LLM.Understanding,
+	} {
+		if err := sampling.validate(name); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Prompt.TokenBudget < 0 {
+		return nil, fmt.Errorf("invalid prompt.token_budget %d: must be >= 0", cfg.Prompt.TokenBudget)
+	}
+	if cfg.Prompt.CandidatesPerCall < 0 {
+		return nil, fmt.Errorf("invalid prompt.candidates_per_call %d: must be >= 0", cfg.Prompt.CandidatesPerCall)
+	}
+	if cfg.Prompt.LineageDepth < 0 {
+		return nil, fmt.Errorf("invalid prompt.lineage_depth %d: must be >= 0", cfg.Prompt.LineageDepth)
+	}
+	if cfg.Prompt.DivergencePrefixEntries < 0 {
+		return nil, fmt.Errorf("invalid prompt.divergence_prefix_entries %d: must be >= 0", cfg.Prompt.DivergencePrefixEntries)
+	}
+	if cfg.Prompt.DivergencePathEntries < 0 {
+		return nil, fmt.Errorf("invalid prompt.divergence_path_entries %d: must be >= 0", cfg.Prompt.DivergencePathEntries)
+	}
+	if cfg.Prompt.UnderstandingMaxTokens < 0 {
+		return nil, fmt.Errorf("invalid prompt.understanding_max_tokens %d: must be >= 0", cfg.Prompt.UnderstandingMaxTokens)
+	}
+	if cfg.Prompt.UnderstandingTargetTokens < 0 {
+		return nil, fmt.Errorf("invalid prompt.understanding_target_tokens %d: must be >= 0", cfg.Prompt.UnderstandingTargetTokens)
+	}
+	switch cfg.Prompt.UnderstandingVersion {
+	case "":
+		cfg.Prompt.UnderstandingVersion = "compressed"
+	case "compressed", "full":
+	default:
+		return nil, fmt.Errorf("invalid prompt.understanding_version %q: must be one of compressed, full", cfg.Prompt.UnderstandingVersion)
+	}
 	if cfg.Compiler.Fuzz.FlagStrategy.Enabled {
 		if cfg.Compiler.Fuzz.FlagStrategy.Mode == "" {
 			cfg.Compiler.Fuzz.FlagStrategy.Mode = "matrix"
@@ -539,6 +1098,15 @@ func LoadConfig() (*Config, error) {
 	if cfg.Compiler.Oracle.Options == nil {
 		cfg.Compiler.Oracle.Options = make(map[string]interface{})
 	}
+	if cfg.Compiler.Oracle.Type == "diff" && cfg.Compiler.Reference.Path == "" {
+		return nil, fmt.Errorf("compiler.oracle.type \"diff\" requires compiler.reference.path")
+	}
+	if cfg.Compiler.Reference.QEMUPath == "" {
+		cfg.Compiler.Reference.QEMUPath = cfg.Compiler.Fuzz.QEMUPath
+	}
+	if cfg.Compiler.Reference.QEMUSysroot == "" {
+		cfg.Compiler.Reference.QEMUSysroot = cfg.Compiler.Fuzz.QEMUSysroot
+	}
 
 	return &cfg, nil
 } // GetCompilerConfigName returns the compiler config filename based on the pattern:
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
@@ -252,6 +253,7 @@ func TestLoad_CompilerConfig_WithSourceParentPath(t *testing.T) {
 	compilerConfigContent := `
 compiler:
   path: "/root/fuzz-coverage/gcc-build-selective/gcc/xgcc"
+  cxx_path: "/root/fuzz-coverage/gcc-build-selective/gcc/xg++"
   gcovr_exec_path: "/root/fuzz-coverage/gcc-build-selective"
   source_parent_path: "/root/fuzz-coverage"
   gcovr_command: 'gcovr --exclude ".*\.(h|hpp|hxx)$" --gcov-executable "gcov-14" -r ..'
@@ -268,6 +270,7 @@ compiler:
 
 	// Verify all fields are loaded correctly
 	assert.Equal(t, "/root/fuzz-coverage/gcc-build-selective/gcc/xgcc", compilerCfg.Path)
+	assert.Equal(t, "/root/fuzz-coverage/gcc-build-selective/gcc/xg++", compilerCfg.CXXPath)
 	assert.Equal(t, "/root/fuzz-coverage/gcc-build-selective", compilerCfg.GcovrExecPath)
 	assert.Equal(t, "/root/fuzz-coverage", compilerCfg.SourceParentPath)
 	assert.Equal(t, `gcovr --exclude ".*\.(h|hpp|hxx)$" --gcov-executable "gcov-14" -r ..`, compilerCfg.GcovrCommand)
@@ -297,6 +300,7 @@ compiler:
 	assert.Equal(t, "/path/to/gcc", compilerCfg.Path)
 	assert.Equal(t, "/path/to/build", compilerCfg.GcovrExecPath)
 	// Optional fields should be empty string when not provided
+	assert.Equal(t, "", compilerCfg.CXXPath)
 	assert.Equal(t, "", compilerCfg.SourceParentPath)
 	assert.Equal(t, "", compilerCfg.GcovrCommand)
 	assert.Equal(t, "", compilerCfg.TotalReportPath)
@@ -319,12 +323,41 @@ config:
   fuzz:
     output_root_dir: "my_fuzz_out"
     max_iterations: 100
+    max_duration: "12h30m"
     max_new_seeds: 5
     max_constraint_retries: 5
+    min_target_successors: 2
     timeout: 60
     use_qemu: true
     qemu_path: "qemu-x86_64"
     qemu_sysroot: "/usr/x86_64-linux-gnu"
+    aux_context_files:
+      - "stack_layout.md"
+      - "calling_convention.md"
+    strict_test_cases: true
+    request_tags: true
+    dedup: "comments"
+    compress_seeds: true
+    schedule: "explore"
+    favored_weight: 8
+    trim_every: 50
+    progress_interval_seconds: 15
+    metrics_addr: ":9090"
+    event_log: "engine_events.jsonl"
+    iteration_log: ""
+    target_cooldown: 8
+    target_schedule: "stride"
+    adaptive_retries: true
+    dry_run: true
+    corpus:
+      max_seeds: 5000
+      max_disk_mb: 512
+      hard_evict: true
+    minimize_bugs: true
+    minimize_max_checks: 300
+    minimize_timeout_seconds: 120
+    validate_seeds: true
+    validate_workers: 4
 `
 	configFile := filepath.Join(actualConfigPath, "config.yaml")
 	err := os.WriteFile(configFile, []byte(configContent), 0644)
@@ -346,12 +379,37 @@ config:
 	// Verify fuzz config fields
 	assert.Equal(t, "my_fuzz_out", fuzzCfg.OutputRootDir)
 	assert.Equal(t, 100, fuzzCfg.MaxIterations)
+	assert.Equal(t, 12*time.Hour+30*time.Minute, fuzzCfg.MaxDuration)
 	assert.Equal(t, 5, fuzzCfg.MaxNewSeeds)
 	assert.Equal(t, 5, fuzzCfg.MaxConstraintRetries)
+	assert.Equal(t, 2, fuzzCfg.MinTargetSuccessors)
 	assert.Equal(t, 60, fuzzCfg.Timeout)
 	assert.True(t, fuzzCfg.UseQEMU)
 	assert.Equal(t, "qemu-x86_64", fuzzCfg.QEMUPath)
 	assert.Equal(t, "/usr/x86_64-linux-gnu", fuzzCfg.QEMUSysroot)
+	assert.Equal(t, []string{"stack_layout.md", "calling_convention.md"}, fuzzCfg.AuxContextFiles)
+	assert.True(t, fuzzCfg.StrictTestCases)
+	assert.False(t, fuzzCfg.AllowShellTestCommands)
+	assert.True(t, fuzzCfg.RequestTags)
+	assert.Equal(t, "comments", fuzzCfg.Dedup)
+	assert.True(t, fuzzCfg.CompressSeeds)
+	assert.Equal(t, "explore", fuzzCfg.Schedule)
+	assert.Equal(t, 8.0, fuzzCfg.FavoredWeight)
+	assert.Equal(t, 50, fuzzCfg.TrimEvery)
+	assert.Equal(t, 15, fuzzCfg.ProgressIntervalSeconds)
+	assert.Equal(t, ":9090", fuzzCfg.MetricsAddr)
+	assert.Equal(t, "engine_events.jsonl", fuzzCfg.EventLog)
+	assert.Empty(t, fuzzCfg.IterationLog, "set empty to disable")
+	assert.Equal(t, 8, fuzzCfg.TargetCooldown)
+	assert.Equal(t, "stride", fuzzCfg.TargetSchedule)
+	assert.True(t, fuzzCfg.AdaptiveRetries)
+	assert.True(t, fuzzCfg.DryRun)
+	assert.Equal(t, CorpusLimitsConfig{MaxSeeds: 5000, MaxDiskMB: 512, HardEvict: true}, fuzzCfg.Corpus)
+	assert.True(t, fuzzCfg.MinimizeBugs)
+	assert.Equal(t, 300, fuzzCfg.MinimizeMaxChecks)
+	assert.Equal(t, 120, fuzzCfg.MinimizeTimeoutSeconds)
+	assert.True(t, fuzzCfg.ValidateSeeds)
+	assert.Equal(t, 4, fuzzCfg.ValidateWorkers)
 }
 
 func TestLoad_FuzzConfig_Defaults(t *testing.T) {
@@ -612,3 +670,214 @@ config:
 	assert.Equal(t, 0, fuzzCfg.MaxNewSeeds)
 	assert.False(t, fuzzCfg.UseQEMU)
 }
+
+func TestLoadConfig_PromptSection(t *testing.T) {
+	actualConfigPath, cleanup := setupTestConfigs(t)
+	defer cleanup()
+
+	configContent := `
+config:
+  isa: "x64"
+  strategy: "canary"
+  compiler:
+    name: "gcc"
+    version: "12.2.0"
+  prompt:
+    template_dir: "prompts/custom"
+    token_budget: 16000
+    max_prior_attempts: 3
+    prior_attempt_max_chars: 400
+    test_case_separator: "/* TESTS */"
+    candidates_per_call: 3
+    lineage_depth: 4
+    divergence_prefix_entries: 20
+    divergence_path_entries: 6
+    understanding_max_tokens: 3000
+    understanding_target_tokens: 1200
+    understanding_version: "full"
+`
+	assert.NoError(t, os.WriteFile(filepath.Join(actualConfigPath, "config.yaml"), []byte(configContent), 0644))
+	compilerContent := `
+compiler:
+  path: "/usr/bin/gcc"
+`
+	assert.NoError(t, os.WriteFile(filepath.Join(actualConfigPath, "gcc-v12.2.0-x64-canary.yaml"), []byte(compilerContent), 0644))
+
+	cfg, err := LoadConfig()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "prompts/custom", cfg.Prompt.TemplateDir)
+	assert.Equal(t, 16000, cfg.Prompt.TokenBudget)
+	assert.Equal(t, 3, cfg.Prompt.MaxPriorAttempts)
+	assert.Equal(t, 400, cfg.Prompt.PriorAttemptMaxChars)
+	assert.Equal(t, "/* TESTS */", cfg.Prompt.TestCaseSeparator)
+	assert.Equal(t, 3, cfg.Prompt.CandidatesPerCall)
+	assert.Equal(t, 4, cfg.Prompt.LineageDepth)
+	assert.Equal(t, 20, cfg.Prompt.DivergencePrefixEntries)
+	assert.Equal(t, 6, cfg.Prompt.DivergencePathEntries)
+	assert.Equal(t, 3000, cfg.Prompt.UnderstandingMaxTokens)
+	assert.Equal(t, 1200, cfg.Prompt.UnderstandingTargetTokens)
+	assert.Equal(t, "full", cfg.Prompt.UnderstandingVersion)
+}
+
+func TestLoadConfig_LLMSection(t *testing.T) {
+	actualConfigPath, cleanup := setupTestConfigs(t)
+	defer cleanup()
+
+	configContent := `
+config:
+  isa: "x64"
+  strategy: "canary"
+  compiler:
+    name: "gcc"
+    version: "12.2.0"
+  llm:
+    structured_output: true
+    conversation: true
+    cache_on_hit: "reuse"
+    cache_file: "llm_cache.jsonl"
+    cache_dir: "llm_cache"
+    cache_ttl_hours: 24
+    request_timeout_seconds: 120
+    stream: true
+    stream_max_tokens: 4000
+    audit_log: "llm_audit.jsonl"
+    audit_full_prompts: true
+    audit_max_mb: 64
+    batch_concurrency: 8
+    generation:
+      temperature: 0.9
+      top_p: 0.95
+    refinement:
+      temperature: 0.2
+      max_tokens: 2048
+`
+	assert.NoError(t, os.WriteFile(filepath.Join(actualConfigPath, "config.yaml"), []byte(configContent), 0644))
+	compilerContent := `
+compiler:
+  path: "/usr/bin/gcc"
+`
+	assert.NoError(t, os.WriteFile(filepath.Join(actualConfigPath, "gcc-v12.2.0-x64-canary.yaml"), []byte(compilerContent), 0644))
+
+	cfg, err := LoadConfig()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, cfg.LLM.StructuredOutput)
+	assert.True(t, cfg.LLM.Conversation)
+	assert.Equal(t, "reuse", cfg.LLM.CacheOnHit)
+	assert.Equal(t, "llm_cache.jsonl", cfg.LLM.CacheFile)
+	assert.Equal(t, "llm_cache", cfg.LLM.CacheDir)
+	assert.Equal(t, 24, cfg.LLM.CacheTTLHours)
+	assert.Equal(t, 120, cfg.LLM.RequestTimeoutSeconds)
+	assert.True(t, cfg.LLM.Stream)
+	assert.Equal(t, 4000, cfg.LLM.StreamMaxTokens)
+	assert.Equal(t, "llm_audit.jsonl", cfg.LLM.AuditLog)
+	assert.True(t, cfg.LLM.AuditFullPrompts)
+	assert.Equal(t, 64, cfg.LLM.AuditMaxMB)
+	assert.Equal(t, 8, cfg.LLM.BatchConcurrency)
+	if assert.NotNil(t, cfg.LLM.Generation.Temperature) && assert.NotNil(t, cfg.LLM.Generation.TopP) {
+		assert.Equal(t, 0.9, *cfg.LLM.Generation.Temperature)
+		assert.Equal(t, 0.95, *cfg.LLM.Generation.TopP)
+	}
+	if assert.NotNil(t, cfg.LLM.Refinement.Temperature) {
+		assert.Equal(t, 0.2, *cfg.LLM.Refinement.Temperature)
+	}
+	assert.Equal(t, 2048, cfg.LLM.Refinement.MaxTokens)
+	assert.Nil(t, cfg.LLM.Mutation.Temperature)
+}
+
+func TestLoadConfig_MaxCompileFixRetries(t *testing.T) {
+	actualConfigPath, cleanup := setupTestConfigs(t)
+	defer cleanup()
+
+	configContent := `
+config:
+  isa: "x64"
+  strategy: "canary"
+  compiler:
+    name: "gcc"
+    version: "12.2.0"
+`
+	assert.NoError(t, os.WriteFile(filepath.Join(actualConfigPath, "config.yaml"), []byte(configContent), 0644))
+	compilerPath := filepath.Join(actualConfigPath, "gcc-v12.2.0-x64-canary.yaml")
+
+	assert.NoError(t, os.WriteFile(compilerPath, []byte("compiler:\n  path: \"/usr/bin/gcc\"\n"), 0644))
+	cfg, err := LoadConfig()
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, cfg.Compiler.Fuzz.MaxCompileFixRetries, "default")
+		assert.Equal(t, 5, cfg.Compiler.Fuzz.TargetCooldown, "default")
+		assert.Equal(t, "weighted", cfg.Compiler.Fuzz.TargetSchedule, "default")
+		assert.Equal(t, "events.jsonl", cfg.Compiler.Fuzz.IterationLog, "default")
+	}
+
+	assert.NoError(t, os.WriteFile(compilerPath, []byte("compiler:\n  path: \"/usr/bin/gcc\"\n  fuzz:\n    max_compile_fix_retries: 0\n"), 0644))
+	cfg, err = LoadConfig()
+	if assert.NoError(t, err) {
+		assert.Equal(t, 0, cfg.Compiler.Fuzz.MaxCompileFixRetries, "explicit 0 disables fixes")
+	}
+
+	assert.NoError(t, os.WriteFile(compilerPath, []byte("compiler:\n  path: \"/usr/bin/gcc\"\n  fuzz:\n    max_compile_fix_retries: -1\n"), 0644))
+	_, err = LoadConfig()
+	assert.Error(t, err)
+
+	// The event log may not take the iteration log's default file
+	assert.NoError(t, os.WriteFile(compilerPath, []byte("compiler:\n  path: \"/usr/bin/gcc\"\n  fuzz:\n    event_log: \"./events.jsonl\"\n"), 0644))
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "fuzz.event_log")
+
+	assert.NoError(t, os.WriteFile(compilerPath, []byte("compiler:\n  path: \"/usr/bin/gcc\"\n  fuzz:\n    event_log: \"engine.jsonl\"\n"), 0644))
+	_, err = LoadConfig()
+	assert.NoError(t, err)
+
+	// Runtime coverage is written by seed runs
+	assert.NoError(t, os.WriteFile(compilerPath, []byte("compiler:\n  path: \"/usr/bin/gcc\"\n  gcov_prefix: \"gcov\"\n  fuzz:\n    execute_seeds: never\n"), 0644))
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "gcov_prefix")
+}
+
+func TestLoadConfig_ReferenceCompiler(t *testing.T) {
+	actualConfigPath, cleanup := setupTestConfigs(t)
+	defer cleanup()
+
+	configContent := `
+config:
+  isa: "x64"
+  strategy: "canary"
+  compiler:
+    name: "gcc"
+    version: "12.2.0"
+`
+	assert.NoError(t, os.WriteFile(filepath.Join(actualConfigPath, "config.yaml"), []byte(configContent), 0644))
+	compilerPath := filepath.Join(actualConfigPath, "gcc-v12.2.0-x64-canary.yaml")
+
+	compilerContent := `
+compiler:
+  path: "/usr/bin/gcc"
+  fuzz:
+    qemu_path: "qemu-x86_64"
+  oracle:
+    type: "diff"
+  reference:
+    path: "/opt/gcc-14/bin/gcc"
+    cflags: ["-O2"]
+    use_qemu: true
+    qemu_sysroot: "/opt/sysroot"
+`
+	assert.NoError(t, os.WriteFile(compilerPath, []byte(compilerContent), 0644))
+	cfg, err := LoadConfig()
+	if assert.NoError(t, err) {
+		assert.Equal(t, ReferenceCompilerConfig{
+			Path:        "/opt/gcc-14/bin/gcc",
+			CFlags:      []string{"-O2"},
+			UseQEMU:     true,
+			QEMUPath:    "qemu-x86_64",
+			QEMUSysroot: "/opt/sysroot",
+		}, cfg.Compiler.Reference)
+	}
+
+	assert.NoError(t, os.WriteFile(compilerPath, []byte("compiler:\n  path: \"/usr/bin/gcc\"\n  oracle:\n    type: \"diff\"\n"), 0644))
+	_, err = LoadConfig()
+	assert.Error(t, err, "the diff oracle needs a reference compiler")
+}
@@ -0,0 +1,136 @@
+package corpus
+
+import (
+	"regexp"
+	"sort"
+
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// construct is a source-level feature the profiler looks for in seeds.
+type construct struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// constructs are matched with regexes over comment-stripped source. They are
+// rough by design: the counts only steer the generate prompt towards
+// features the corpus has not tried yet.
+var constructs = []construct{
+	{"unbounded string copies (strcpy/strcat/sprintf/gets)", regexp.MustCompile(`\b(?:strcpy|strcat|sprintf|gets)\s*\(`)},
+	{"memcpy/memmove/memset", regexp.MustCompile(`\b(?:memcpy|memmove|memset)\s*\(`)},
+	{"heap allocation", regexp.MustCompile(`\b(?:malloc|calloc|realloc)\s*\(`)},
+	{"variable-length arrays", regexp.MustCompile(`\b(?:char|short|int|long|float|double|unsigned|signed|size_t|u?int\d+_t)\s+\w+\s*\[\s*[a-z_][a-z0-9_]*(?:\s*[-+*]\s*\w+)?\s*\]\s*[;=]`)},
+	{"alloca", regexp.MustCompile(`\b(?:__builtin_)?alloca\s*\(`)},
+	{"nested structs", regexp.MustCompile(`\bstruct\s*\w*\s*\{[^{}]*\bstruct\s*\w*\s*\{`)},
+	{"unions", regexp.MustCompile(`\bunion\s*\w*\s*\{`)},
+	{"bit-fields", regexp.MustCompile(`\b\w+\s+\w+\s*:\s*\d+\s*;`)},
+	{"function pointers", regexp.MustCompile(`\(\s*\*\s*\w*\s*\)\s*\(`)},
+	{"variadic functions", regexp.MustCompile(`\.\.\.\s*\)`)},
+	{"setjmp/longjmp", regexp.MustCompile(`\b(?:sig)?(?:setjmp|longjmp)\s*\(`)},
+	{"inline asm", regexp.MustCompile(`\b(?:__asm__|__asm|asm)\b\s*(?:volatile|__volatile__)?\s*[({]`)},
+	{"goto", regexp.MustCompile(`\bgoto\s+\*?\w+`)},
+	{"signal handlers", regexp.MustCompile(`\b(?:signal|sigaction)\s*\(`)},
+	{"threads", regexp.MustCompile(`\bpthread_create\s*\(`)},
+}
+
+var reComment = regexp.MustCompile(`(?s)/\*.*?\*/|//[^\n]*`)
+
+// ConstructCount is how many seeds use one construct.
+type ConstructCount struct {
+	Name  string
+	Seeds int
+}
+
+// ConstructStats summarizes which constructs a set of seeds uses.
+type ConstructStats struct {
+	Seeds  int              // Number of seeds profiled
+	Counts []ConstructCount // One entry per known construct, in a fixed order
+
+	// Tags counts the seeds labeled with each Metadata tag, most common
+	// first (ties by name). Untagged seeds are not counted.
+	Tags []ConstructCount
+}
+
+// ProfileConstructs counts, for each known construct, the seeds whose source
+// uses it at least once, and the seeds carrying each tag.
+func ProfileConstructs(seeds []*seed.Seed) ConstructStats {
+	profiler := newConstructProfiler()
+	for _, s := range seeds {
+		profiler.add(s)
+	}
+	return profiler.stats()
+}
+
+// constructProfiler accumulates ConstructStats one seed at a time.
+type constructProfiler struct {
+	result ConstructStats
+	tags   map[string]int
+}
+
+func newConstructProfiler() *constructProfiler {
+	p := &constructProfiler{
+		result: ConstructStats{Counts: make([]ConstructCount, len(constructs))},
+		tags:   make(map[string]int),
+	}
+	for i, c := range constructs {
+		p.result.Counts[i].Name = c.name
+	}
+	return p
+}
+
+func (p *constructProfiler) add(s *seed.Seed) {
+	if s == nil || s.Content == "" {
+		return
+	}
+	p.result.Seeds++
+	for _, tag := range seed.NormalizeTags(s.Meta.Tags) {
+		p.tags[tag]++
+	}
+	code := reComment.ReplaceAllString(s.Content, " ")
+	for i, c := range constructs {
+		if c.pattern.MatchString(code) {
+			p.result.Counts[i].Seeds++
+		}
+	}
+}
+
+func (p *constructProfiler) stats() ConstructStats {
+	stats := p.result
+	stats.Counts = append([]ConstructCount(nil), p.result.Counts...)
+	stats.Tags = nil
+	for name, n := range p.tags {
+		stats.Tags = append(stats.Tags, ConstructCount{Name: name, Seeds: n})
+	}
+	sort.Slice(stats.Tags, func(i, j int) bool {
+		if stats.Tags[i].Seeds != stats.Tags[j].Seeds {
+			return stats.Tags[i].Seeds > stats.Tags[j].Seeds
+		}
+		return stats.Tags[i].Name < stats.Tags[j].Name
+	})
+	return stats
+}
+
+// MostUsed returns up to n constructs used by at least atLeast seeds, most
+// common first. Ties keep the fixed construct order.
+func (s ConstructStats) MostUsed(n, atLeast int) []ConstructCount {
+	sorted := append([]ConstructCount(nil), s.Counts...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Seeds > sorted[j].Seeds })
+
+	var common []ConstructCount
+	for _, c := range sorted {
+		if c.Seeds < atLeast || len(common) == n {
+			break
+		}
+		common = append(common, c)
+	}
+	return common
+}
+
+// LeastUsed returns the n least used constructs, rarest first. Ties keep
+// the fixed construct order.
+func (s ConstructStats) LeastUsed(n int) []ConstructCount {
+	sorted := append([]ConstructCount(nil), s.Counts...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Seeds < sorted[j].Seeds })
+	return sorted[:min(n, len(sorted))]
+}
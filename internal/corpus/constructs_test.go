@@ -0,0 +1,123 @@
+package corpus
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+func countOf(t *testing.T, stats ConstructStats, name string) int {
+	t.Helper()
+	for _, c := range stats.Counts {
+		if c.Name == name {
+			return c.Seeds
+		}
+	}
+	t.Fatalf("unknown construct %q", name)
+	return 0
+}
+
+func TestProfileConstructs(t *testing.T) {
+	seeds := []*seed.Seed{
+		{Content: `#include <string.h>
+int main(int argc, char **argv) {
+    char buf[16];
+    strcpy(buf, argv[1]);
+    return 0;
+}`},
+		{Content: `#include <string.h>
+void f(int n, const char *s) {
+    char vla[n];
+    strcpy(vla, s); /* strcpy again */
+}`},
+		{Content: `#include <setjmp.h>
+#include <stdarg.h>
+static jmp_buf env;
+int sum(int count, ...) { return count; }
+struct outer { int a; struct inner { int b : 3; } in; };
+int main(void) {
+    if (setjmp(env)) return 1;
+    char *p = __builtin_alloca(32);
+    __asm__ volatile("" ::: "memory");
+    // alloca( in a comment does not count
+    longjmp(env, 1);
+}`},
+		{Content: ""},
+	}
+
+	stats := ProfileConstructs(seeds)
+	if stats.Seeds != 3 {
+		t.Errorf("Seeds = %d, want 3 (empty seeds are skipped)", stats.Seeds)
+	}
+
+	tests := []struct {
+		name string
+		want int
+	}{
+		{"unbounded string copies (strcpy/strcat/sprintf/gets)", 2},
+		{"variable-length arrays", 1},
+		{"alloca", 1},
+		{"setjmp/longjmp", 1},
+		{"variadic functions", 1},
+		{"nested structs", 1},
+		{"bit-fields", 1},
+		{"inline asm", 1},
+		{"threads", 0},
+		{"heap allocation", 0},
+	}
+	for _, tt := range tests {
+		if got := countOf(t, stats, tt.name); got != tt.want {
+			t.Errorf("%s: %d seeds, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	common := stats.MostUsed(3, 2)
+	if len(common) != 1 || common[0].Name != "unbounded string copies (strcpy/strcat/sprintf/gets)" {
+		t.Errorf("MostUsed() = %+v", common)
+	}
+
+	rare := stats.LeastUsed(2)
+	if len(rare) != 2 || rare[0].Seeds != 0 || rare[1].Seeds != 0 {
+		t.Errorf("LeastUsed() = %+v, want two unused constructs", rare)
+	}
+	if rare[0].Name != "memcpy/memmove/memset" {
+		t.Errorf("ties should keep the construct order, got %q first", rare[0].Name)
+	}
+}
+
+func TestFileManager_ConstructStats(t *testing.T) {
+	manager := NewFileManager(t.TempDir())
+	if err := manager.Initialize(); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+	for _, content := range []string{"int main() { char *p = alloca(8); }", "int main() { goto out; out: return 0; }"} {
+		if err := manager.Add(&seed.Seed{Content: content}); err != nil {
+			t.Fatalf("failed to add seed: %v", err)
+		}
+	}
+	manager.Next() // one processed, one still queued
+
+	stats := manager.ConstructStats()
+	if stats.Seeds != 2 || countOf(t, stats, "alloca") != 1 || countOf(t, stats, "goto") != 1 {
+		t.Errorf("ConstructStats() = %+v", stats)
+	}
+	if len(stats.Tags) != 0 {
+		t.Errorf("Tags = %+v, want none for untagged seeds", stats.Tags)
+	}
+}
+
+func TestProfileConstructs_Tags(t *testing.T) {
+	seeds := []*seed.Seed{
+		{Content: "int a;", Meta: seed.Metadata{Tags: []string{"vla", "alloca"}}},
+		{Content: "int b;", Meta: seed.Metadata{Tags: []string{"alloca"}}},
+		{Content: "int c;", Meta: seed.Metadata{Tags: []string{"longjmp"}}},
+		{Content: "int d;"},
+	}
+
+	got := ProfileConstructs(seeds).Tags
+	want := []ConstructCount{{"alloca", 2}, {"longjmp", 1}, {"vla", 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tags = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,182 @@
+package corpus
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/zjy-dev/de-fuzz/internal/coverage"
+	"github.com/zjy-dev/de-fuzz/internal/logger"
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// Limits caps the corpus; see SetLimits. Zero values mean no cap.
+type Limits struct {
+	MaxSeeds     int   // Seeds in the corpus, processed and queued
+	MaxDiskBytes int64 // Size of CorpusDir
+	// HardEvict deletes evicted seeds, directory and metadata, instead of
+	// moving them to ArchiveDir.
+	HardEvict bool
+}
+
+// SetLimits makes Add evict seeds while the corpus is over limits, lowest
+// energy first (see evictionOrder). Bug seeds, initial seeds, favored seeds
+// and the seed being added are never evicted, so the corpus can stay over
+// a cap when only those are left. Evicted seeds are archived like trimmed
+// ones, or deleted under HardEvict, and their IDs removed from mapping
+// (nil for none).
+func (m *FileManager) SetLimits(limits Limits, mapping *coverage.CoverageMapping) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.limits = limits
+	m.evictMapping = mapping
+}
+
+// rankEnergy is the energy evict and SeedsCovering rank seeds by: their
+// energy under the schedule, or under ScheduleExploit for ScheduleFIFO,
+// where every seed scores the same. Callers hold m.mu.
+func (m *FileManager) rankEnergy(meta *seed.Metadata) float64 {
+	schedule := m.schedule
+	if _, ok := scheduleTable[schedule]; !ok {
+		schedule = ScheduleExploit
+	}
+	return Energy(meta, schedule)
+}
+
+// overLimits reports which cap, if any, the corpus exceeds. Callers hold
+// m.mu.
+func (m *FileManager) overLimits() string {
+	if m.limits.MaxSeeds > 0 && len(m.processed)+len(m.queue) > m.limits.MaxSeeds {
+		return fmt.Sprintf("%d seeds", m.limits.MaxSeeds)
+	}
+	if m.limits.MaxDiskBytes > 0 && m.diskBytes > m.limits.MaxDiskBytes {
+		return fmt.Sprintf("%d bytes", m.limits.MaxDiskBytes)
+	}
+	return ""
+}
+
+// evict brings the corpus back within its limits after Add saved added.
+// The size of CorpusDir is measured the first time, then kept up to date
+// as seeds come and go; files written into seed directories later are only
+// counted after the next Recover. Callers hold m.mu.
+func (m *FileManager) evict(added *seed.Seed) error {
+	if m.limits.MaxDiskBytes > 0 {
+		if m.diskBytes < 0 {
+			size, err := dirSize(m.corpusDir)
+			if err != nil {
+				return fmt.Errorf("failed to measure %s: %w", m.corpusDir, err)
+			}
+			m.diskBytes = size
+		} else {
+			m.diskBytes += m.seedSize(added)
+		}
+	}
+	limit := m.overLimits()
+	if limit == "" {
+		return nil
+	}
+
+	var candidates []*seed.Seed
+	for _, s := range m.queue {
+		candidates = append(candidates, s)
+	}
+	for _, s := range m.processed {
+		candidates = append(candidates, s)
+	}
+	candidates = evictionOrder(candidates, added, func(s *seed.Seed) float64 { return m.rankEnergy(&s.Meta) })
+
+	var removed []int64
+	var err error
+	for _, s := range candidates {
+		if m.overLimits() == "" {
+			break
+		}
+		size := m.seedSize(s)
+		energy := m.rankEnergy(&s.Meta)
+		if m.limits.HardEvict {
+			err = m.remove(s)
+		} else {
+			err = m.archive(s)
+		}
+		if err != nil {
+			break
+		}
+		if m.diskBytes >= 0 {
+			m.diskBytes -= size
+		}
+		removed = append(removed, int64(s.Meta.ID))
+		logger.Info("Evicted seed %d (energy %.2f, %d bytes)", s.Meta.ID, energy, size)
+	}
+	if m.evictMapping != nil {
+		m.evictMapping.RemoveSeeds(removed)
+	}
+	m.evicted += len(removed)
+	m.stateManager.UpdatePoolSize(len(m.queue))
+
+	action := "archived"
+	if m.limits.HardEvict {
+		action = "deleted"
+	}
+	if len(removed) > 0 {
+		logger.Info("Corpus over %s: %s %d seeds (%d evicted so far)", limit, action, len(removed), m.evicted)
+	}
+	if err == nil && m.overLimits() != "" {
+		logger.Warn("Corpus still over %s: the seeds left cannot be evicted", limit)
+	}
+	return err
+}
+
+// evictionOrder returns the seeds evict may remove, lowest energy first and
+// oldest first among equals: every seed but bug seeds, initial seeds,
+// favored seeds and added.
+func evictionOrder(seeds []*seed.Seed, added *seed.Seed, energy func(*seed.Seed) float64) []*seed.Seed {
+	candidates := seeds[:0]
+	for _, s := range seeds {
+		switch {
+		case s == added, s.Meta.Favored, s.Meta.ParentID == 0,
+			s.Meta.OracleVerdict == seed.OracleVerdictBug:
+			continue
+		}
+		candidates = append(candidates, s)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		ei, ej := energy(candidates[i]), energy(candidates[j])
+		if ei != ej {
+			return ei < ej
+		}
+		return candidates[i].Meta.ID < candidates[j].Meta.ID
+	})
+	return candidates
+}
+
+// seedSize returns the size of s's directory in CorpusDir, or 0 if it
+// cannot be measured. Callers hold m.mu.
+func (m *FileManager) seedSize(s *seed.Seed) int64 {
+	if s.Meta.FilePath == "" {
+		return 0
+	}
+	size, err := dirSize(filepath.Join(m.corpusDir, s.Meta.FilePath))
+	if err != nil {
+		return 0
+	}
+	return size
+}
+
+// remove deletes s, its directory and metadata file, and forgets it. Like
+// archive, it keeps its hash indexed. Callers hold m.mu.
+func (m *FileManager) remove(s *seed.Seed) error {
+	if s.Meta.FilePath != "" {
+		if err := os.RemoveAll(filepath.Join(m.corpusDir, s.Meta.FilePath)); err != nil {
+			return fmt.Errorf("failed to delete seed %d: %w", s.Meta.ID, err)
+		}
+	}
+	if err := os.Remove(filepath.Join(m.metadataDir, fmt.Sprintf("id-%06d.json", s.Meta.ID))); err != nil && !os.IsNotExist(err) {
+		logger.Warn("Failed to delete metadata of seed %d: %v", s.Meta.ID, err)
+	}
+	if err := m.journal.append(journalRecord{Op: journalArchive, ID: s.Meta.ID}); err != nil {
+		logger.Warn("Failed to journal deleted seed %d: %v", s.Meta.ID, err)
+	}
+	m.forget(s)
+	return nil
+}
@@ -0,0 +1,143 @@
+package corpus
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zjy-dev/de-fuzz/internal/coverage"
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+func seedIDs(t *testing.T, manager *FileManager) string {
+	t.Helper()
+	var ids []uint64
+	it := manager.Seeds(nil)
+	for s, ok := it.Next(); ok; s, ok = it.Next() {
+		ids = append(ids, s.Meta.ID)
+	}
+	return fmt.Sprint(ids)
+}
+
+func TestFileManager_EvictMaxSeeds(t *testing.T) {
+	manager := NewFileManager(t.TempDir())
+	if err := manager.Initialize(); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+
+	// Seed 1 is initial; 2-5 mutants of 1, processed: 2 found a bug, 3
+	// added the most coverage and 4 none, but is favored.
+	results := map[uint64]FuzzResult{
+		2: {State: seed.SeedStateProcessed, OracleVerdict: seed.OracleVerdictBug},
+		3: {State: seed.SeedStateProcessed, NewCoverage: 500},
+		4: {State: seed.SeedStateProcessed},
+		5: {State: seed.SeedStateProcessed, NewCoverage: 100},
+	}
+	add := func(i int) {
+		t.Helper()
+		s := &seed.Seed{Content: fmt.Sprintf("int main() { return %d; }", i)}
+		if i > 1 {
+			s.Meta.ParentID = 1
+		}
+		if err := manager.Add(s); err != nil {
+			t.Fatalf("failed to add seed %d: %v", i, err)
+		}
+	}
+	for i := 1; i <= 5; i++ {
+		add(i)
+	}
+	for i := 1; i <= 5; i++ {
+		next, _ := manager.Next()
+		result, ok := results[next.Meta.ID]
+		if !ok {
+			result = FuzzResult{State: seed.SeedStateProcessed}
+		}
+		if err := manager.ReportResult(next.Meta.ID, result); err != nil {
+			t.Fatalf("ReportResult() failed: %v", err)
+		}
+	}
+	manager.SetFavored([]uint64{4})
+
+	mapping, _ := coverage.NewCoverageMapping("")
+	for id := int64(1); id <= 5; id++ {
+		mapping.RecordLine(coverage.LineID{File: "cc.c", Line: int(id)}, id)
+	}
+	manager.SetLimits(Limits{MaxSeeds: 5}, mapping)
+
+	// Seed 5 has the least energy; after it, each new seed evicts the one
+	// before, still pending with no results
+	for i := 6; i <= 8; i++ {
+		add(i)
+	}
+	if got := seedIDs(t, manager); got != "[1 2 3 4 8]" {
+		t.Errorf("seeds left = %s, want [1 2 3 4 8]", got)
+	}
+	if _, err := os.Stat(filepath.Join(manager.baseDir, ArchiveDir)); err != nil {
+		t.Errorf("evicted seeds not archived: %v", err)
+	}
+	if seeds := mapping.GetSeedsForLine(coverage.LineID{File: "cc.c", Line: 5}); len(seeds) != 0 {
+		t.Errorf("mapping still lists evicted seed: %v", seeds)
+	}
+	stats := corpusStats(t, manager)
+	if stats.Evicted != 3 || stats.Total != 5 {
+		t.Errorf("stats: %d evicted, %d total; want 3, 5", stats.Evicted, stats.Total)
+	}
+
+	// Once only protected seeds are left, the corpus stays over the cap
+	manager.SetLimits(Limits{MaxSeeds: 2}, mapping)
+	add(9)
+	if got := seedIDs(t, manager); got != "[1 2 4 9]" {
+		t.Errorf("seeds left = %s, want [1 2 4 9]", got)
+	}
+}
+
+func TestFileManager_EvictHardMaxDisk(t *testing.T) {
+	dir := t.TempDir()
+	manager := NewFileManager(dir)
+	if err := manager.Initialize(); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+	add := func(i int) *seed.Seed {
+		t.Helper()
+		s := &seed.Seed{Content: fmt.Sprintf("int main() { return %d; }", i)}
+		if i > 1 {
+			s.Meta.ParentID = 1
+		}
+		if err := manager.Add(s); err != nil {
+			t.Fatalf("failed to add seed %d: %v", i, err)
+		}
+		return s
+	}
+	add(1)
+	second := add(2)
+	size, err := dirSize(manager.corpusDir)
+	if err != nil {
+		t.Fatalf("dirSize() failed: %v", err)
+	}
+
+	// Room for two seeds: each new one deletes the oldest mutant
+	manager.SetLimits(Limits{MaxDiskBytes: size + size/4, HardEvict: true}, nil)
+	add(3)
+	add(4)
+	if got := seedIDs(t, manager); got != "[1 4]" {
+		t.Fatalf("seeds left = %s, want [1 4]", got)
+	}
+	if _, err := os.Stat(filepath.Join(manager.corpusDir, second.Meta.FilePath)); !os.IsNotExist(err) {
+		t.Errorf("evicted seed directory still there: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(manager.metadataDir, "id-000002.json")); !os.IsNotExist(err) {
+		t.Errorf("evicted seed metadata still there: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ArchiveDir)); !os.IsNotExist(err) {
+		t.Errorf("hard eviction archived seeds: %v", err)
+	}
+
+	resumed := NewFileManager(dir)
+	if err := resumed.Recover(); err != nil {
+		t.Fatalf("Recover() failed: %v", err)
+	}
+	if got := seedIDs(t, resumed); got != "[1 4]" {
+		t.Errorf("seeds after Recover = %s, want [1 4]", got)
+	}
+}
@@ -0,0 +1,364 @@
+package corpus
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zjy-dev/de-fuzz/internal/logger"
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// ManifestFile is the first entry of an Export archive.
+const ManifestFile = "manifest.json"
+
+// MergeMode selects how Import treats the seeds already in the corpus.
+type MergeMode string
+
+const (
+	// ImportReplace drops the corpus and takes the archive's seeds with
+	// their IDs.
+	ImportReplace MergeMode = "replace"
+	// ImportMerge adds the archive's seeds to the corpus. Seeds whose ID is
+	// taken get a new one, and the ParentIDs pointing at them follow.
+	ImportMerge MergeMode = "merge"
+)
+
+// ArchiveManifest describes an Export archive.
+type ArchiveManifest struct {
+	SchemaVersion int                    `json:"schema_version"` // seed.MetadataSchemaVersion of the records
+	NextID        uint64                 `json:"next_id"`        // First ID the exporting corpus had not handed out
+	Seeds         int                    `json:"seeds"`
+	ByState       map[seed.SeedState]int `json:"by_state"`
+	CreatedAt     time.Time              `json:"created_at"`
+}
+
+// Export writes the corpus to w as a tar.gz: ManifestFile, then every seed
+// directory under CorpusDir/ and its metadata record under MetadataDir/.
+// Writers wait until it is done, so the archive is a consistent snapshot.
+func (m *FileManager) Export(w io.Writer) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	seeds := make([]*seed.Seed, 0, len(m.processed)+len(m.queue))
+	for _, s := range m.processed {
+		seeds = append(seeds, s)
+	}
+	seeds = append(seeds, m.queue...)
+	sort.Slice(seeds, func(i, j int) bool { return seeds[i].Meta.ID < seeds[j].Meta.ID })
+
+	manifest := ArchiveManifest{
+		SchemaVersion: seed.MetadataSchemaVersion,
+		NextID:        m.lastID.Load() + 1,
+		ByState:       make(map[seed.SeedState]int),
+		CreatedAt:     time.Now(),
+	}
+	for _, s := range seeds {
+		if s.Meta.FilePath != "" {
+			manifest.Seeds++
+			manifest.ByState[s.Meta.State]++
+		}
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	data, err := json.MarshalIndent(&manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err := writeTarFile(tw, ManifestFile, data, manifest.CreatedAt); err != nil {
+		return err
+	}
+	for _, s := range seeds {
+		if s.Meta.FilePath == "" {
+			continue // Never saved
+		}
+		if err := m.exportSeed(tw, s, manifest.CreatedAt); err != nil {
+			return fmt.Errorf("failed to export seed %d: %w", s.Meta.ID, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// exportSeed writes the files of s's directory and its metadata record.
+// Callers hold m.mu.
+func (m *FileManager) exportSeed(tw *tar.Writer, s *seed.Seed, modTime time.Time) error {
+	seedDir := filepath.Join(m.corpusDir, s.Meta.FilePath)
+	entries, err := os.ReadDir(seedDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(seedDir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		if err := writeTarFile(tw, path.Join(CorpusDir, s.Meta.FilePath, entry.Name()), data, modTime); err != nil {
+			return err
+		}
+	}
+
+	stamped := s.Meta
+	stamped.SchemaVersion = seed.MetadataSchemaVersion
+	data, err := json.MarshalIndent(&stamped, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+	return writeTarFile(tw, path.Join(MetadataDir, fmt.Sprintf("id-%06d.json", s.Meta.ID)), data, modTime)
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: modTime}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// Import reads an archive written by Export into the corpus and returns
+// how many seeds it added. Under ImportReplace the corpus is first emptied
+// and the seeds keep their IDs and parents. Under ImportMerge a seed whose
+// ID the corpus has handed out gets a newly allocated one, and ParentIDs
+// are rewritten to match; a parent missing from the archive leaves its
+// child an initial seed (ParentID 0), since that ID may mean another seed
+// here.
+// With deduplication on, merged seeds the corpus already holds are skipped
+// and their children point at the corpus's copy.
+//
+// Seeds keep their state and metadata, so processed seeds are not fuzzed
+// again. Archives from a newer metadata schema are rejected.
+func (m *FileManager) Import(r io.Reader, mode MergeMode) (int, error) {
+	if m.readOnly {
+		return 0, m.readOnlyError("Import")
+	}
+	if mode != ImportReplace && mode != ImportMerge {
+		return 0, fmt.Errorf("unknown merge mode %q", mode)
+	}
+
+	// Unpack next to the corpus, so nothing is touched until the whole
+	// archive has been read
+	tmp, err := os.MkdirTemp(m.baseDir, "import-")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create import directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+	manifest, err := unpackArchive(r, tmp)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read corpus archive: %w", err)
+	}
+	if manifest.SchemaVersion > seed.MetadataSchemaVersion {
+		return 0, fmt.Errorf("corpus archive has metadata schema version %d, newer than %d: %w",
+			manifest.SchemaVersion, seed.MetadataSchemaVersion, seed.ErrMetadataTooNew)
+	}
+	seeds, err := seed.LoadSeedsWithMetadata(filepath.Join(tmp, CorpusDir), m.namer)
+	if err != nil {
+		return 0, err
+	}
+	metas, _, err := seed.LoadAllMetadataJSON(filepath.Join(tmp, MetadataDir))
+	if err != nil {
+		return 0, err
+	}
+	byID := make(map[uint64]*seed.Metadata, len(metas))
+	for _, meta := range metas {
+		byID[meta.ID] = meta
+	}
+	for _, s := range seeds {
+		// The record holds everything; the files only where they are
+		if meta, ok := byID[s.Meta.ID]; ok {
+			loaded := s.Meta
+			s.Meta = *meta
+			s.Meta.Tags = loaded.Tags
+			s.Meta.Compressed = loaded.Compressed
+		}
+	}
+	sort.Slice(seeds, func(i, j int) bool { return seeds[i].Meta.ID < seeds[j].Meta.ID })
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	remap := make(map[uint64]uint64, len(seeds)) // Archive ID -> corpus ID
+	if mode == ImportReplace {
+		if err := m.clear(); err != nil {
+			return 0, err
+		}
+		if manifest.NextID > 0 {
+			m.reserveID(manifest.NextID - 1)
+		}
+		for _, s := range seeds {
+			remap[s.Meta.ID] = s.Meta.ID
+		}
+	} else {
+		// An ID is taken once handed out, even if its seed was trimmed or
+		// has not been added yet. New IDs come after every archived one,
+		// so they cannot collide with an archived seed that keeps its ID.
+		taken := m.lastID.Load()
+		if len(seeds) > 0 {
+			m.reserveID(seeds[len(seeds)-1].Meta.ID)
+		}
+		for _, s := range seeds {
+			if m.dedup != "" {
+				if id, ok := m.hashes[s.Hash(m.dedup)]; ok {
+					remap[s.Meta.ID] = id
+					continue
+				}
+			}
+			if s.Meta.ID <= taken {
+				remap[s.Meta.ID] = m.AllocateID()
+			} else {
+				remap[s.Meta.ID] = s.Meta.ID
+			}
+		}
+	}
+
+	imported := 0
+	for _, s := range seeds {
+		id := remap[s.Meta.ID]
+		if m.lookup(id) != nil {
+			continue // A duplicate merged into the corpus's copy
+		}
+		s.Meta.ID = id
+		m.reserveID(id)
+		if mode == ImportMerge && s.Meta.ParentID != 0 {
+			s.Meta.ParentID = remap[s.Meta.ParentID]
+		}
+		if err := m.importSeed(s); err != nil {
+			return imported, err
+		}
+		imported++
+	}
+
+	sort.Slice(m.queue, func(i, j int) bool { return m.queue[i].Meta.ID < m.queue[j].Meta.ID })
+	m.stateManager.UpdatePoolSize(len(m.queue))
+	logger.Info("Imported %d of %d seeds from corpus archive (%s)", imported, len(seeds), mode)
+	return imported, nil
+}
+
+// importSeed saves s, an archived seed given its corpus ID, and queues it
+// or files it as processed by its state. Callers hold m.mu.
+func (m *FileManager) importSeed(s *seed.Seed) error {
+	if _, err := seed.SaveSeedWithMetadata(m.corpusDir, s, m.namer); err != nil {
+		return fmt.Errorf("failed to save seed %d: %w", s.Meta.ID, err)
+	}
+	if err := seed.SaveMetadataJSON(m.metadataDir, &s.Meta); err != nil {
+		logger.Warn("Failed to save metadata for seed %d: %v", s.Meta.ID, err)
+	}
+	if m.dedup != "" {
+		s.Meta.Hash = s.Hash(m.dedup)
+		if _, ok := m.hashes[s.Meta.Hash]; !ok {
+			m.hashes[s.Meta.Hash] = s.Meta.ID
+		}
+	}
+	if err := m.journal.append(journalRecord{Op: journalAdd, ID: s.Meta.ID, Hash: s.Meta.Hash}); err != nil {
+		logger.Warn("Failed to journal seed %d: %v", s.Meta.ID, err)
+	}
+	if s.Meta.State == seed.SeedStatePending {
+		m.queue = append(m.queue, s)
+	} else {
+		m.processed[s.Meta.ID] = s
+	}
+	return nil
+}
+
+// clear removes every seed from the corpus, on disk and in memory, with
+// the hash index and journal. Archived seeds and the global state stay.
+// Callers hold m.mu.
+func (m *FileManager) clear() error {
+	if err := m.journal.reset(); err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(m.stateDir, HashIndexFile)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove seed hash index: %w", err)
+	}
+	for _, dir := range []string{m.corpusDir, m.metadataDir} {
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", dir, err)
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", dir, err)
+		}
+	}
+	if _, ok := m.namer.(*seed.ShardedNamingStrategy); ok {
+		if err := seed.WriteLayout(m.corpusDir, seed.LayoutSharded); err != nil {
+			return err
+		}
+	}
+	m.queue = make([]*seed.Seed, 0)
+	m.processed = make(map[uint64]*seed.Seed)
+	m.hashes = make(map[string]uint64)
+	m.taken = make(map[uint64]int)
+	m.diskBytes = -1
+	return nil
+}
+
+// unpackArchive extracts the corpus and metadata files of an Export
+// archive into dir and returns its manifest. Entries that would land
+// outside dir are an error.
+func unpackArchive(r io.Reader, dir string) (*ArchiveManifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	var manifest *ArchiveManifest
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean(hdr.Name)
+		if name == ManifestFile {
+			manifest = &ArchiveManifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", ManifestFile, err)
+			}
+			continue
+		}
+		if !fs.ValidPath(name) || !strings.HasPrefix(name, CorpusDir+"/") && !strings.HasPrefix(name, MetadataDir+"/") {
+			return nil, fmt.Errorf("unexpected archive entry %q", hdr.Name)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, err
+		}
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			return nil, err
+		}
+		_, err = io.Copy(f, tr)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("missing %s", ManifestFile)
+	}
+	return manifest, nil
+}
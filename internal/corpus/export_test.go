@@ -0,0 +1,234 @@
+package corpus
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// newExportCorpus builds a corpus of four seeds, 1 <- 2 <- 3 and 4, with
+// 1-3 processed and seed 3 a bug.
+func newExportCorpus(t *testing.T) *FileManager {
+	t.Helper()
+	manager := NewFileManager(t.TempDir())
+	if err := manager.Initialize(); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+	for i, parent := range []uint64{0, 1, 2, 0} {
+		s := &seed.Seed{
+			Content:   fmt.Sprintf("int main() { return %d; }", i),
+			TestCases: []seed.TestCase{{RunningCommand: "./prog", ExpectedResult: "ok"}},
+			Meta:      seed.Metadata{ParentID: parent, Depth: i % 3, MutationNote: fmt.Sprintf("note %d", i)},
+		}
+		if err := manager.Add(s); err != nil {
+			t.Fatalf("failed to add seed: %v", err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		s, _ := manager.Next()
+		result := FuzzResult{State: seed.SeedStateProcessed, NewCoverage: uint64(100 * (i + 1))}
+		if s.Meta.ID == 3 {
+			result.OracleVerdict = seed.OracleVerdictBug
+		}
+		if err := manager.ReportResult(s.Meta.ID, result); err != nil {
+			t.Fatalf("ReportResult() failed: %v", err)
+		}
+	}
+	return manager
+}
+
+func corpusStats(t *testing.T, manager *FileManager) CorpusStats {
+	t.Helper()
+	stats, err := manager.Stats()
+	if err != nil {
+		t.Fatalf("Stats() failed: %v", err)
+	}
+	stats.DiskBytes = 0 // Metadata paths differ between corpora
+	return *stats
+}
+
+func TestFileManager_ExportImportRoundTrip(t *testing.T) {
+	source := newExportCorpus(t)
+	var archive bytes.Buffer
+	if err := source.Export(&archive); err != nil {
+		t.Fatalf("Export() failed: %v", err)
+	}
+
+	// The manifest comes first
+	gz, err := gzip.NewReader(bytes.NewReader(archive.Bytes()))
+	if err != nil {
+		t.Fatalf("archive is not gzip: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	if hdr, err := tr.Next(); err != nil || hdr.Name != ManifestFile {
+		t.Fatalf("first entry = %v, %v; want %s", hdr, err, ManifestFile)
+	}
+	var manifest ArchiveManifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		t.Fatalf("failed to decode manifest: %v", err)
+	}
+	if manifest.SchemaVersion != seed.MetadataSchemaVersion || manifest.NextID != 5 || manifest.Seeds != 4 {
+		t.Errorf("manifest = %+v", manifest)
+	}
+	if manifest.ByState[seed.SeedStateProcessed] != 3 || manifest.ByState[seed.SeedStatePending] != 1 {
+		t.Errorf("manifest ByState = %v", manifest.ByState)
+	}
+
+	target := NewFileManager(t.TempDir())
+	if err := target.Initialize(); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+	if err := target.Add(&seed.Seed{Content: "int main() { return 99; }"}); err != nil {
+		t.Fatalf("failed to add seed: %v", err)
+	}
+	n, err := target.Import(bytes.NewReader(archive.Bytes()), ImportReplace)
+	if err != nil {
+		t.Fatalf("Import() failed: %v", err)
+	}
+	if n != 4 {
+		t.Errorf("Import() = %d, want 4", n)
+	}
+	if want, got := corpusStats(t, source), corpusStats(t, target); !reflect.DeepEqual(want, got) {
+		t.Errorf("stats after import = %+v, want %+v", got, want)
+	}
+	if id := target.AllocateID(); id != 5 {
+		t.Errorf("AllocateID() after import = %d, want 5", id)
+	}
+
+	// The imported corpus survives a restart, contents and records intact
+	recovered := NewFileManager(target.baseDir)
+	if err := recovered.Recover(); err != nil {
+		t.Fatalf("Recover() failed: %v", err)
+	}
+	s, err := recovered.Get(2)
+	if err != nil || s == nil {
+		t.Fatalf("Get(2) = %v, %v", s, err)
+	}
+	if err := s.Load(); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if s.Content != "int main() { return 1; }" || len(s.TestCases) != 1 || s.Meta.MutationNote != "note 1" || s.Meta.ParentID != 1 {
+		t.Errorf("recovered seed 2 = %+v", s)
+	}
+}
+
+func TestFileManager_ImportMerge(t *testing.T) {
+	source := newExportCorpus(t)
+	var archive bytes.Buffer
+	if err := source.Export(&archive); err != nil {
+		t.Fatalf("Export() failed: %v", err)
+	}
+
+	target := NewFileManager(t.TempDir())
+	if err := target.Initialize(); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := target.Add(&seed.Seed{Content: fmt.Sprintf("int main() { return %d; }", 10+i)}); err != nil {
+			t.Fatalf("failed to add seed: %v", err)
+		}
+	}
+	n, err := target.Import(bytes.NewReader(archive.Bytes()), ImportMerge)
+	if err != nil {
+		t.Fatalf("Import() failed: %v", err)
+	}
+	if n != 4 {
+		t.Errorf("Import() = %d, want 4", n)
+	}
+
+	// Seeds 1 and 2 collide and are renumbered after the archive's IDs;
+	// 3 and 4 keep theirs, and every ParentID follows
+	byNote := make(map[string]*seed.Seed)
+	it := target.Seeds(nil)
+	for s, ok := it.Next(); ok; s, ok = it.Next() {
+		byNote[s.Meta.MutationNote] = s
+	}
+	wantIDs := map[string][2]uint64{"note 0": {5, 0}, "note 1": {6, 5}, "note 2": {3, 6}, "note 3": {4, 0}}
+	for note, want := range wantIDs {
+		s := byNote[note]
+		if s == nil {
+			t.Fatalf("seed with %q missing after merge", note)
+		}
+		if s.Meta.ID != want[0] || s.Meta.ParentID != want[1] {
+			t.Errorf("seed %q: ID, ParentID = %d, %d; want %d, %d", note, s.Meta.ID, s.Meta.ParentID, want[0], want[1])
+		}
+	}
+	stats := corpusStats(t, target)
+	if stats.Total != 6 || stats.Bugs != 1 || stats.ByState[seed.SeedStatePending] != 3 {
+		t.Errorf("stats after merge = %+v", stats)
+	}
+	if id := target.AllocateID(); id != 7 {
+		t.Errorf("AllocateID() after merge = %d, want 7", id)
+	}
+}
+
+func TestFileManager_ImportMergeDedup(t *testing.T) {
+	source := newExportCorpus(t)
+	var archive bytes.Buffer
+	if err := source.Export(&archive); err != nil {
+		t.Fatalf("Export() failed: %v", err)
+	}
+
+	target := NewFileManager(t.TempDir())
+	target.SetDedup(seed.HashExact)
+	if err := target.Initialize(); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+	// The same program as the archive's seed 1
+	if err := target.Add(&seed.Seed{Content: "int main() { return 0; }"}); err != nil {
+		t.Fatalf("failed to add seed: %v", err)
+	}
+	n, err := target.Import(bytes.NewReader(archive.Bytes()), ImportMerge)
+	if err != nil {
+		t.Fatalf("Import() failed: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("Import() = %d, want 3", n)
+	}
+	it := target.Seeds(nil)
+	for s, ok := it.Next(); ok; s, ok = it.Next() {
+		if s.Meta.MutationNote == "note 1" && s.Meta.ParentID != 1 {
+			t.Errorf("child of the duplicate has ParentID %d, want 1", s.Meta.ParentID)
+		}
+	}
+}
+
+func TestFileManager_ImportRejectsBadArchives(t *testing.T) {
+	manager := NewFileManager(t.TempDir())
+	if err := manager.Initialize(); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+
+	archive := func(entries map[string]string) *bytes.Buffer {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+		for name, data := range entries {
+			tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data))})
+			tw.Write([]byte(data))
+		}
+		tw.Close()
+		gz.Close()
+		return &buf
+	}
+	cases := map[string]*bytes.Buffer{
+		"not gzip":     bytes.NewBufferString("plain text"),
+		"no manifest":  archive(map[string]string{"corpus/id-000001/source.c": "int main() {}"}),
+		"escape":       archive(map[string]string{ManifestFile: `{"schema_version":2}`, "../evil": "x"}),
+		"newer schema": archive(map[string]string{ManifestFile: `{"schema_version":99}`}),
+	}
+	for name, r := range cases {
+		if _, err := manager.Import(r, ImportReplace); err == nil {
+			t.Errorf("%s: Import() succeeded", name)
+		}
+	}
+	if _, err := manager.Import(archive(map[string]string{ManifestFile: `{}`}), "overwrite"); err == nil {
+		t.Error("Import() accepted an unknown merge mode")
+	}
+}
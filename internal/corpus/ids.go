@@ -0,0 +1,86 @@
+package corpus
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/zjy-dev/de-fuzz/internal/coverage"
+	"github.com/zjy-dev/de-fuzz/internal/fsutil"
+	"github.com/zjy-dev/de-fuzz/internal/logger"
+)
+
+// IDMarkFile is the file in StateDir holding the ID high-water mark: no
+// seed ID above it has been handed out.
+const IDMarkFile = "id_high_water.json"
+
+// idMark is the on-disk form of the ID high-water mark.
+type idMark struct {
+	HighWater uint64 `json:"high_water"`
+}
+
+// ensureHighWater persists a high-water mark of at least id before id is
+// used. Concurrent callers are serialized, and one whose ID an earlier
+// write already covers returns at once. A failed write is logged: the run
+// goes on, but a restart may then reuse IDs. A read-only corpus only
+// tracks the mark in memory.
+func (m *FileManager) ensureHighWater(id uint64) {
+	if id <= m.highWater.Load() {
+		return
+	}
+	m.markMu.Lock()
+	defer m.markMu.Unlock()
+	if id <= m.highWater.Load() {
+		return
+	}
+
+	if !m.readOnly {
+		data, err := json.Marshal(idMark{HighWater: id})
+		if err == nil {
+			if err = os.MkdirAll(m.stateDir, 0755); err == nil {
+				err = fsutil.WriteFileAtomic(filepath.Join(m.stateDir, IDMarkFile), data)
+			}
+		}
+		if err != nil {
+			logger.Warn("Failed to persist seed ID high-water mark %d: %v", id, err)
+		}
+	}
+	m.highWater.Store(id)
+}
+
+// loadHighWater starts the ID counter after the persisted high-water mark,
+// so IDs handed out before a restart are never handed out again, even if
+// their seeds were trimmed or never saved. It runs before anything else
+// reserves an ID, which would overwrite the mark.
+func (m *FileManager) loadHighWater() error {
+	data, err := os.ReadFile(filepath.Join(m.stateDir, IDMarkFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read seed ID high-water mark: %w", err)
+	}
+	var mark idMark
+	if err := json.Unmarshal(data, &mark); err != nil {
+		return fmt.Errorf("failed to parse seed ID high-water mark %s: %w", IDMarkFile, err)
+	}
+	for {
+		current := m.highWater.Load()
+		if current >= mark.HighWater || m.highWater.CompareAndSwap(current, mark.HighWater) {
+			break
+		}
+	}
+	m.reserveID(mark.HighWater)
+	return nil
+}
+
+// CheckMapping verifies that mapping references no seed ID the corpus has
+// not handed out. Such a reference means the ID state was lost, and new
+// seeds would take IDs the mapping already credits with coverage.
+func (m *FileManager) CheckMapping(mapping *coverage.CoverageMapping) error {
+	if id, last := mapping.MaxSeedID(), m.lastID.Load(); id > 0 && uint64(id) > last {
+		return fmt.Errorf("coverage mapping references seed %d, but the corpus has only allocated IDs up to %d; the corpus state in %s is stale or missing", id, last, m.stateDir)
+	}
+	return nil
+}
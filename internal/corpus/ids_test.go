@@ -0,0 +1,77 @@
+package corpus
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zjy-dev/de-fuzz/internal/coverage"
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+func TestFileManager_IDsSurviveRestartAfterTrim(t *testing.T) {
+	tmpDir := t.TempDir()
+	manager := NewFileManager(tmpDir)
+	if err := manager.Initialize(); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+
+	// Seed 1 is initial; 2 and 3 mutants of 1.
+	for i := 1; i <= 3; i++ {
+		s := &seed.Seed{Content: fmt.Sprintf("int main() { return %d; }", i)}
+		if i > 1 {
+			s.Meta.ParentID = 1
+		}
+		if err := manager.Add(s); err != nil {
+			t.Fatalf("failed to add seed: %v", err)
+		}
+		next, _ := manager.Next()
+		if err := manager.ReportResult(next.Meta.ID, FuzzResult{State: seed.SeedStateProcessed}); err != nil {
+			t.Fatalf("ReportResult() failed: %v", err)
+		}
+	}
+	if err := manager.Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	// Seed 4 records coverage but is never added, as when its test cases
+	// fail after the mapping was updated; then seed 3 is trimmed.
+	if id := manager.AllocateID(); id != 4 {
+		t.Fatalf("AllocateID() = %d, want 4", id)
+	}
+	line := func(n int) coverage.LineID { return coverage.LineID{File: "cc.c", Line: n} }
+	mapping, _ := coverage.NewCoverageMapping("")
+	mapping.RecordLine(line(1), 1)
+	mapping.RecordLine(line(2), 2)
+	mapping.RecordLine(line(3), 2)
+	mapping.RecordLine(line(2), 3)
+	mapping.RecordLine(line(4), 4)
+	if removed, err := manager.Trim(mapping, nil); err != nil || removed != 1 {
+		t.Fatalf("Trim() = %d, %v; want 1 seed removed", removed, err)
+	}
+
+	// Crash: the last snapshot predates both; IDs still are not reused.
+	resumed := NewFileManager(tmpDir)
+	if err := resumed.Recover(); err != nil {
+		t.Fatalf("Recover() failed: %v", err)
+	}
+	if err := resumed.CheckMapping(mapping); err != nil {
+		t.Errorf("CheckMapping() failed: %v", err)
+	}
+	if id := resumed.AllocateID(); id != 5 {
+		t.Errorf("AllocateID() after restart = %d, want 5", id)
+	}
+
+	// Without the high-water mark, the mapping shows the ID state was lost.
+	if err := os.Remove(filepath.Join(tmpDir, StateDir, IDMarkFile)); err != nil {
+		t.Fatalf("failed to remove %s: %v", IDMarkFile, err)
+	}
+	lost := NewFileManager(tmpDir)
+	if err := lost.Recover(); err != nil {
+		t.Fatalf("Recover() failed: %v", err)
+	}
+	if err := lost.CheckMapping(mapping); err == nil {
+		t.Error("CheckMapping() accepted a mapping that references unallocated seed 4")
+	}
+}
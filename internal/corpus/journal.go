@@ -0,0 +1,306 @@
+package corpus
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/zjy-dev/de-fuzz/internal/logger"
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+const (
+	// JournalFile is the append-only log in StateDir of the corpus changes
+	// made since the last snapshot (the global state and HashIndexFile).
+	JournalFile = "journal.jsonl"
+
+	// journalCompactRecords is how long Save lets the journal grow before
+	// it folds the journal into a new snapshot.
+	journalCompactRecords = 4096
+)
+
+// journalOp names the change a journal record describes.
+type journalOp string
+
+const (
+	journalAdd     journalOp = "add"     // A seed was saved to the corpus
+	journalResult  journalOp = "result"  // ReportResult recorded a seed's result
+	journalArchive journalOp = "archive" // Trim or eviction removed a seed
+)
+
+// journalRecord is one line of the journal. Every field holds the value
+// after the change, not a delta, so replaying a record twice (a crash
+// between writing a snapshot and dropping the journal it covers) is
+// harmless.
+type journalRecord struct {
+	Op   journalOp `json:"op"`
+	ID   uint64    `json:"id"`
+	Hash string    `json:"hash,omitempty"` // journalAdd, with deduplication on
+
+	// journalResult
+	Result    *seedResult `json:"result,omitempty"`
+	Processed int         `json:"processed,omitempty"` // Processed count after the result
+	Coverage  uint64      `json:"coverage,omitempty"`  // Total coverage after the result
+}
+
+// seedResult is what ReportResult changes in a seed's metadata.
+type seedResult struct {
+	State          seed.SeedState     `json:"state"`
+	OldCoverage    uint64             `json:"old_cov,omitempty"`
+	NewCoverage    uint64             `json:"new_cov,omitempty"`
+	CovIncrease    uint64             `json:"cov_incr,omitempty"`
+	OracleVerdict  seed.OracleVerdict `json:"oracle_verdict,omitempty"`
+	BugType        string             `json:"bug_type,omitempty"`
+	BugDescription string             `json:"bug_description,omitempty"`
+	ExecTimeUs     int64              `json:"exec_time_us,omitempty"`
+	Energy         float64            `json:"energy,omitempty"`
+	// CompileFailed is the negated CompileOK, so records journaled before
+	// outcomes were kept, all of seeds that compiled, replay as such.
+	CompileFailed bool     `json:"compile_failed,omitempty"`
+	CompileStderr string   `json:"compile_stderr,omitempty"`
+	ExecSignals   []string `json:"exec_signals,omitempty"`
+	DurationMs    int64    `json:"duration_ms,omitempty"`
+}
+
+func resultOf(meta *seed.Metadata) *seedResult {
+	return &seedResult{
+		State:          meta.State,
+		OldCoverage:    meta.OldCoverage,
+		NewCoverage:    meta.NewCoverage,
+		CovIncrease:    meta.CovIncrease,
+		OracleVerdict:  meta.OracleVerdict,
+		BugType:        meta.BugType,
+		BugDescription: meta.BugDescription,
+		ExecTimeUs:     meta.ExecTimeUs,
+		Energy:         meta.Energy,
+		CompileFailed:  !meta.CompileOK,
+		CompileStderr:  meta.CompileStderr,
+		ExecSignals:    meta.ExecSignals,
+		DurationMs:     meta.DurationMs,
+	}
+}
+
+func (r *seedResult) apply(meta *seed.Metadata) {
+	meta.State = r.State
+	meta.OldCoverage = r.OldCoverage
+	meta.NewCoverage = r.NewCoverage
+	meta.CovIncrease = r.CovIncrease
+	meta.OracleVerdict = r.OracleVerdict
+	meta.BugType = r.BugType
+	meta.BugDescription = r.BugDescription
+	meta.ExecTimeUs = r.ExecTimeUs
+	meta.Energy = r.Energy
+	meta.CompileOK = !r.CompileFailed
+	meta.CompileStderr = r.CompileStderr
+	meta.ExecSignals = r.ExecSignals
+	meta.DurationMs = r.DurationMs
+}
+
+// journal appends records to JournalFile. Compaction rotates the file to
+// JournalFile.old, which is replayed first and removed once the snapshot
+// covering it is written. Callers serialize append, rotate and replay (the
+// corpus holds m.mu); sync and dropRotated may run alongside them.
+type journal struct {
+	path     string
+	file     *os.File
+	records  int  // Records appended or replayed since the last rotation
+	readOnly bool // Replay leaves the files as they are; see OpenReadOnly
+}
+
+func newJournal(path string) *journal {
+	return &journal{path: path}
+}
+
+// rotatedPath is where rotate moves the journal until it is compacted.
+func (j *journal) rotatedPath() string {
+	return j.path + ".old"
+}
+
+// append writes rec as one line, opening the journal on first use. The
+// line reaches the disk on the next sync.
+func (j *journal) append(rec journalRecord) error {
+	if j.file == nil {
+		if err := os.MkdirAll(filepath.Dir(j.path), 0755); err != nil {
+			return fmt.Errorf("failed to create journal directory: %w", err)
+		}
+		f, err := os.OpenFile(j.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open journal: %w", err)
+		}
+		j.file = f
+	}
+	data, err := json.Marshal(&rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal journal record: %w", err)
+	}
+	if _, err := j.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	j.records++
+	return nil
+}
+
+// sync flushes the records appended so far to disk.
+func (j *journal) sync() error {
+	if f := j.file; f != nil {
+		return f.Sync()
+	}
+	return nil
+}
+
+// rotate moves the journal aside for compaction; appends after it start a
+// new file.
+func (j *journal) rotate() error {
+	if err := j.close(); err != nil {
+		return err
+	}
+	if _, err := os.Stat(j.rotatedPath()); err == nil {
+		// The last compaction failed; its records are not in a snapshot
+		// yet, so keep them ahead of the current ones.
+		if err := appendFile(j.rotatedPath(), j.path); err != nil {
+			return err
+		}
+		if err := os.Remove(j.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	} else if err := os.Rename(j.path, j.rotatedPath()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to rotate journal: %w", err)
+	}
+	j.records = 0
+	return nil
+}
+
+// dropRotated removes the journal rotate moved aside, once a snapshot
+// covers it.
+func (j *journal) dropRotated() error {
+	if err := os.Remove(j.rotatedPath()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove compacted journal: %w", err)
+	}
+	return nil
+}
+
+// reset drops every record, for a corpus replaced as a whole.
+func (j *journal) reset() error {
+	if err := j.close(); err != nil {
+		return err
+	}
+	for _, path := range []string{j.rotatedPath(), j.path} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove journal: %w", err)
+		}
+	}
+	j.records = 0
+	return nil
+}
+
+// close syncs and closes the journal; the next append reopens it.
+func (j *journal) close() error {
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Sync()
+	if closeErr := j.file.Close(); err == nil {
+		err = closeErr
+	}
+	j.file = nil
+	return err
+}
+
+// replay calls apply for every record in the rotated journal, then the
+// current one. A record cut short by a crash ends its file: it and
+// anything after it are dropped, and the file is truncated so later
+// appends follow the last whole record. A read-only journal is not
+// truncated: the record may still be being written.
+func (j *journal) replay(apply func(journalRecord)) error {
+	if err := j.close(); err != nil {
+		return err
+	}
+	j.records = 0
+	for _, path := range []string{j.rotatedPath(), j.path} {
+		n, err := replayFile(path, apply, j.readOnly)
+		if err != nil {
+			return err
+		}
+		j.records += n
+	}
+	return nil
+}
+
+// replayFile replays the whole records of one journal file and returns
+// how many there were.
+func replayFile(path string, apply func(journalRecord), readOnly bool) (int, error) {
+	flag := os.O_RDWR
+	if readOnly {
+		flag = os.O_RDONLY
+	}
+	f, err := os.OpenFile(path, flag, 0)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var good int64 // Offset just past the last whole record
+	n := 0
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(line) > 0 && !readOnly {
+				logger.Warn("Dropping torn record at the end of %s", path)
+			}
+			break
+		}
+		if err != nil {
+			return n, fmt.Errorf("failed to read journal: %w", err)
+		}
+		var rec journalRecord
+		if err := json.Unmarshal(bytes.TrimSpace(line), &rec); err != nil || rec.Op == "" {
+			logger.Warn("Dropping unreadable journal records from offset %d of %s", good, path)
+			break
+		}
+		apply(rec)
+		good += int64(len(line))
+		n++
+	}
+	if readOnly {
+		return n, nil
+	}
+	if info, err := f.Stat(); err == nil && info.Size() > good {
+		if err := f.Truncate(good); err != nil {
+			return n, fmt.Errorf("failed to truncate journal: %w", err)
+		}
+	}
+	return n, nil
+}
+
+// appendFile appends the contents of src to dst.
+func appendFile(dst, src string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,185 @@
+package corpus
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+	"github.com/zjy-dev/de-fuzz/internal/state"
+)
+
+// newJournaledCorpus adds three seeds and reports a result for each,
+// without ever saving, and returns the corpus directory.
+func newJournaledCorpus(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	manager := NewFileManager(dir)
+	manager.SetDedup(seed.HashExact)
+	if err := manager.Initialize(); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := manager.Add(&seed.Seed{Content: fmt.Sprintf("int main() { return %d; }", i)}); err != nil {
+			t.Fatalf("failed to add seed: %v", err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		s, _ := manager.Next()
+		if err := manager.ReportResult(s.Meta.ID, FuzzResult{State: seed.SeedStateProcessed, NewCoverage: uint64(100 * (i + 1))}); err != nil {
+			t.Fatalf("ReportResult() failed: %v", err)
+		}
+	}
+	return dir
+}
+
+// copyCorpus copies the corpus directory src to a new directory, as a
+// crash would leave it.
+func copyCorpus(t *testing.T, src string) string {
+	t.Helper()
+	dst := t.TempDir()
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, path)
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, 0644)
+	})
+	if err != nil {
+		t.Fatalf("failed to copy corpus: %v", err)
+	}
+	return dst
+}
+
+func recoverCorpus(t *testing.T, dir string) *FileManager {
+	t.Helper()
+	manager := NewFileManager(dir)
+	manager.SetDedup(seed.HashExact)
+	if err := manager.Recover(); err != nil {
+		t.Fatalf("Recover() failed: %v", err)
+	}
+	return manager
+}
+
+func TestFileManager_RecoverReplaysJournal(t *testing.T) {
+	dir := newJournaledCorpus(t)
+	if _, err := os.Stat(filepath.Join(dir, StateDir, state.StateFileName)); err == nil {
+		t.Fatal("the global state was saved; the test needs it missing")
+	}
+
+	manager := recoverCorpus(t, dir)
+	progress := manager.GetStateManager().GetState()
+	if progress.Stats.ProcessedCount != 3 || progress.TotalCoverage != 300 || progress.LastAllocatedID != 3 {
+		t.Errorf("state after replay = %+v", progress)
+	}
+	if manager.Len() != 0 {
+		t.Errorf("Len() = %d, want 0: processed seeds were queued again", manager.Len())
+	}
+	if err := manager.Add(&seed.Seed{Content: "int main() { return 2; }"}); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Add() of a journaled duplicate = %v, want ErrDuplicate", err)
+	}
+	if id := manager.AllocateID(); id != 4 {
+		t.Errorf("AllocateID() = %d, want 4", id)
+	}
+}
+
+func TestFileManager_RecoverTornJournal(t *testing.T) {
+	dir := newJournaledCorpus(t)
+	data, err := os.ReadFile(filepath.Join(dir, StateDir, JournalFile))
+	if err != nil {
+		t.Fatalf("failed to read journal: %v", err)
+	}
+	if n := bytes.Count(data, []byte("\n")); n != 6 {
+		t.Fatalf("journal has %d records, want 6 (3 adds, 3 results)", n)
+	}
+
+	// Cut the journal at every byte, as a crash mid-append would
+	for cut := 0; cut <= len(data); cut++ {
+		crashed := copyCorpus(t, dir)
+		path := filepath.Join(crashed, StateDir, JournalFile)
+		if err := os.Truncate(path, int64(cut)); err != nil {
+			t.Fatalf("failed to truncate journal: %v", err)
+		}
+		whole := data[:bytes.LastIndexByte(data[:cut], '\n')+1]
+		results := bytes.Count(whole, []byte(`"op":"result"`))
+
+		manager := recoverCorpus(t, crashed)
+		if got := manager.GetStateManager().GetState().Stats.ProcessedCount; got != results {
+			t.Fatalf("cut at %d: ProcessedCount = %d, want %d", cut, got, results)
+		}
+		if info, err := os.Stat(path); err != nil || info.Size() != int64(len(whole)) {
+			t.Fatalf("cut at %d: torn record not truncated: %v, %v", cut, info, err)
+		}
+
+		// Records appended after recovery follow the last whole one
+		if err := manager.Add(&seed.Seed{Content: "int main() { return 9; }"}); err != nil {
+			t.Fatalf("cut at %d: failed to add seed: %v", cut, err)
+		}
+		again := recoverCorpus(t, crashed)
+		if again.journal.records != bytes.Count(whole, []byte("\n"))+1 {
+			t.Fatalf("cut at %d: replayed %d records after appending", cut, again.journal.records)
+		}
+	}
+}
+
+func TestFileManager_SaveCompactsJournal(t *testing.T) {
+	dir := newJournaledCorpus(t)
+	stale, err := os.ReadFile(filepath.Join(dir, StateDir, JournalFile))
+	if err != nil {
+		t.Fatalf("failed to read journal: %v", err)
+	}
+
+	manager := recoverCorpus(t, dir)
+	manager.compactEvery = 8
+	if err := manager.Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, StateDir, HashIndexFile)); err == nil {
+		t.Error("Save() below compactEvery wrote the hash index")
+	}
+	for i := 0; i < 2; i++ {
+		if err := manager.Add(&seed.Seed{Content: fmt.Sprintf("int main() { return %d; }", 10+i)}); err != nil {
+			t.Fatalf("failed to add seed: %v", err)
+		}
+	}
+	if err := manager.Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, StateDir, HashIndexFile)); err != nil {
+		t.Errorf("compaction did not write the hash index: %v", err)
+	}
+	for _, name := range []string{JournalFile, JournalFile + ".old"} {
+		if _, err := os.Stat(filepath.Join(dir, StateDir, name)); err == nil {
+			t.Errorf("%s left behind by compaction", name)
+		}
+	}
+
+	// A crash before the compacted journal was dropped replays it again,
+	// which changes nothing
+	if err := os.WriteFile(filepath.Join(dir, StateDir, JournalFile+".old"), stale, 0644); err != nil {
+		t.Fatalf("failed to restore journal: %v", err)
+	}
+	recovered := recoverCorpus(t, dir)
+	progress := recovered.GetStateManager().GetState()
+	if progress.Stats.ProcessedCount != 3 || progress.LastAllocatedID != 5 {
+		t.Errorf("state after compaction = %+v", progress)
+	}
+	if recovered.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", recovered.Len())
+	}
+	if err := recovered.Add(&seed.Seed{Content: "int main() { return 11; }"}); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Add() of a compacted duplicate = %v, want ErrDuplicate", err)
+	}
+}
@@ -0,0 +1,82 @@
+package corpus
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/zjy-dev/de-fuzz/internal/logger"
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// Layout returns how CorpusDir stores seed directories. Corpora start flat;
+// Migrate shards them.
+func (m *FileManager) Layout() seed.Layout {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, ok := m.namer.(*seed.ShardedNamingStrategy); ok {
+		return seed.LayoutSharded
+	}
+	return seed.LayoutFlat
+}
+
+// loadLayout names new seed directories for the layout CorpusDir records.
+// Seeds are read in either layout regardless. Callers hold m.mu.
+func (m *FileManager) loadLayout() error {
+	layout, err := seed.DetectLayout(m.corpusDir)
+	if err != nil {
+		return fmt.Errorf("failed to detect corpus layout: %w", err)
+	}
+	sharded, ok := m.namer.(*seed.ShardedNamingStrategy)
+	switch {
+	case layout == seed.LayoutSharded && !ok:
+		m.namer = seed.NewShardedNamingStrategy(m.namer)
+	case layout != seed.LayoutSharded && ok:
+		m.namer = sharded.NamingStrategy
+	}
+	return nil
+}
+
+// Migrate moves a flat corpus into the sharded layout (see
+// seed.LayoutSharded), for corpora large enough that one directory of seed
+// directories is slow to list; new seeds then go into shards too. The
+// FilePath and ContentPath of the seeds moved are updated, in memory and in
+// their metadata files, so call it after Recover. An interrupted migration
+// leaves a usable corpus, and calling Migrate again finishes it. It returns
+// how many seeds were moved.
+func (m *FileManager) Migrate() (int, error) {
+	if m.readOnly {
+		return 0, m.readOnlyError("Migrate")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	moved, err := seed.MigrateToSharded(m.corpusDir, m.namer)
+	if loadErr := m.loadLayout(); err == nil {
+		err = loadErr
+	}
+	if len(moved) == 0 {
+		return 0, err
+	}
+
+	update := func(s *seed.Seed) {
+		target, ok := moved[s.Meta.FilePath]
+		if !ok {
+			return
+		}
+		s.Meta.FilePath = target
+		if s.Meta.ContentPath != "" {
+			s.Meta.ContentPath = filepath.Join(m.corpusDir, target, filepath.Base(s.Meta.ContentPath))
+		}
+		if err := seed.SaveMetadataJSON(m.metadataDir, &s.Meta); err != nil {
+			logger.Warn("Failed to save metadata for seed %d: %v", s.Meta.ID, err)
+		}
+	}
+	for _, s := range m.queue {
+		update(s)
+	}
+	for _, s := range m.processed {
+		update(s)
+	}
+	logger.Info("Moved %d seeds into shards of %s", len(moved), m.corpusDir)
+	return len(moved), err
+}
@@ -0,0 +1,132 @@
+package corpus
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+func TestFileManager_Migrate(t *testing.T) {
+	manager := newExportCorpus(t)
+	if err := manager.Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	before := corpusStats(t, manager)
+
+	// Migrate a recovered corpus, as after a restart
+	migrated := NewFileManager(manager.baseDir)
+	if err := migrated.Recover(); err != nil {
+		t.Fatalf("Recover() failed: %v", err)
+	}
+	n, err := migrated.Migrate()
+	if err != nil {
+		t.Fatalf("Migrate() failed: %v", err)
+	}
+	if n != 4 || migrated.Layout() != seed.LayoutSharded {
+		t.Fatalf("Migrate() = %d, layout %s; want 4, sharded", n, migrated.Layout())
+	}
+
+	entries, err := os.ReadDir(migrated.corpusDir)
+	if err != nil {
+		t.Fatalf("failed to read corpus: %v", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && len(entry.Name()) != 2 {
+			t.Errorf("seed directory %s left unsharded", entry.Name())
+		}
+	}
+
+	// Paths follow the seeds, in memory and in the metadata files
+	s, err := migrated.Get(2)
+	if err != nil || s == nil {
+		t.Fatalf("Get(2) = %v, %v", s, err)
+	}
+	if s.Meta.ContentPath != filepath.Join(migrated.corpusDir, s.Meta.FilePath, "source.c") || filepath.Dir(s.Meta.FilePath) == "." {
+		t.Errorf("seed 2 paths after Migrate: FilePath %q, ContentPath %q", s.Meta.FilePath, s.Meta.ContentPath)
+	}
+	if err := s.Load(); err != nil || s.Content != "int main() { return 1; }" {
+		t.Errorf("Load() after Migrate = %v, content %q", err, s.Content)
+	}
+	meta, err := seed.LoadMetadataJSON(filepath.Join(migrated.metadataDir, "id-000002.json"))
+	if err != nil || meta.ContentPath != s.Meta.ContentPath {
+		t.Errorf("metadata of seed 2 = %+v, %v; want ContentPath %q", meta, err, s.Meta.ContentPath)
+	}
+
+	// A restart reads the sharded corpus and keeps adding seeds to shards
+	resumed := NewFileManager(manager.baseDir)
+	if err := resumed.Recover(); err != nil {
+		t.Fatalf("Recover() failed: %v", err)
+	}
+	if after := corpusStats(t, resumed); !reflect.DeepEqual(before, after) {
+		t.Errorf("stats after migration = %+v, want %+v", after, before)
+	}
+	added := &seed.Seed{Content: "int main() { return 9; }"}
+	if err := resumed.Add(added); err != nil {
+		t.Fatalf("failed to add seed: %v", err)
+	}
+	shard := seed.GenerateContentHash(added.Content)[:2]
+	if filepath.Dir(added.Meta.FilePath) != shard {
+		t.Errorf("added seed FilePath = %q, want it in shard %s", added.Meta.FilePath, shard)
+	}
+
+	// ReportResult renames the directory within its shard
+	next, _ := resumed.Next()
+	if err := resumed.ReportResult(next.Meta.ID, FuzzResult{State: seed.SeedStateProcessed, NewCoverage: 700}); err != nil {
+		t.Fatalf("ReportResult() failed: %v", err)
+	}
+	shard = seed.GenerateContentHash(next.Content)[:2]
+	if _, err := os.Stat(next.Meta.ContentPath); err != nil || filepath.Dir(next.Meta.FilePath) != shard || next.Meta.CovIncrease != 700 {
+		t.Errorf("renamed seed: FilePath %q, %v", next.Meta.FilePath, err)
+	}
+}
+
+func TestFileManager_MigrateInterrupted(t *testing.T) {
+	dir := t.TempDir()
+	manager := NewFileManager(dir)
+	if err := manager.Initialize(); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+	var seeds []*seed.Seed
+	for i := 0; i < 4; i++ {
+		s := &seed.Seed{Content: fmt.Sprintf("int main() { return %d; }", i)}
+		if err := manager.Add(s); err != nil {
+			t.Fatalf("failed to add seed: %v", err)
+		}
+		seeds = append(seeds, s)
+	}
+
+	// A crash after the layout marker and the first seed were written
+	if err := seed.WriteLayout(manager.corpusDir, seed.LayoutSharded); err != nil {
+		t.Fatalf("WriteLayout() failed: %v", err)
+	}
+	first := seeds[0].Meta.FilePath
+	shard := filepath.Join(manager.corpusDir, seed.GenerateContentHash(seeds[0].Content)[:2])
+	if err := os.MkdirAll(shard, 0755); err != nil {
+		t.Fatalf("failed to create shard: %v", err)
+	}
+	if err := os.Rename(filepath.Join(manager.corpusDir, first), filepath.Join(shard, first)); err != nil {
+		t.Fatalf("failed to move seed: %v", err)
+	}
+
+	resumed := NewFileManager(dir)
+	if err := resumed.Recover(); err != nil {
+		t.Fatalf("Recover() failed: %v", err)
+	}
+	if resumed.Len() != 4 {
+		t.Fatalf("Len() after an interrupted migration = %d, want 4", resumed.Len())
+	}
+	n, err := resumed.Migrate()
+	if err != nil || n != 3 {
+		t.Errorf("Migrate() = %d, %v; want the 3 seeds left", n, err)
+	}
+	for i := 0; i < 4; i++ {
+		s, _ := resumed.Next()
+		if err := s.Load(); err != nil || s.Content != seeds[i].Content {
+			t.Errorf("seed %d after Migrate: %v, content %q", s.Meta.ID, err, s.Content)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package corpus
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// Lineage export formats accepted by ExportLineage.
+const (
+	LineageDOT  = "dot"
+	LineageJSON = "json"
+)
+
+// LineageNode is a seed in the lineage tree, with the seeds mutated from it
+// as children.
+type LineageNode struct {
+	ID          uint64         `json:"id"`
+	CovIncrease uint64         `json:"cov_incr"`
+	Bug         bool           `json:"bug,omitempty"`
+	Children    []*LineageNode `json:"children,omitempty"`
+}
+
+// lineageIndex links metadata records by ParentID, so lineages can be
+// walked without reading any seed's content.
+type lineageIndex struct {
+	metas    map[uint64]*seed.Metadata
+	children map[uint64][]uint64 // Parent ID -> child IDs, ascending
+}
+
+func newLineageIndex(metas []*seed.Metadata) *lineageIndex {
+	x := &lineageIndex{
+		metas:    make(map[uint64]*seed.Metadata, len(metas)),
+		children: make(map[uint64][]uint64),
+	}
+	for _, meta := range metas {
+		x.metas[meta.ID] = meta
+	}
+	for _, meta := range metas {
+		if _, ok := x.metas[meta.ParentID]; ok && meta.ParentID != meta.ID {
+			x.children[meta.ParentID] = append(x.children[meta.ParentID], meta.ID)
+		}
+	}
+	for _, ids := range x.children {
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	}
+	return x
+}
+
+// isRoot reports whether meta starts a tree: its parent is not indexed.
+func (x *lineageIndex) isRoot(meta *seed.Metadata) bool {
+	_, ok := x.metas[meta.ParentID]
+	return !ok || meta.ParentID == meta.ID
+}
+
+// ancestors walks ParentID links up from id and returns up to limit
+// ancestors (no limit when limit <= 0), nearest first. The walk stops at
+// an initial seed, a parent that is not indexed, or a cycle.
+func (x *lineageIndex) ancestors(id uint64, limit int) []seed.Metadata {
+	var ancestors []seed.Metadata
+	visited := map[uint64]bool{id: true}
+	current, ok := x.metas[id]
+	for ok && (limit <= 0 || len(ancestors) < limit) {
+		parentID := current.ParentID
+		if parentID == 0 || visited[parentID] {
+			break
+		}
+		visited[parentID] = true
+		if current, ok = x.metas[parentID]; ok {
+			ancestors = append(ancestors, *current)
+		}
+	}
+	return ancestors
+}
+
+// descendants returns the seeds mutated from id, directly or not, down to
+// depth generations (all when depth <= 0): breadth first, each generation
+// in ID order. Seeds are visited once, so cycles end the walk.
+func (x *lineageIndex) descendants(id uint64, depth int) []seed.Metadata {
+	var descendants []seed.Metadata
+	visited := map[uint64]bool{id: true}
+	generation := []uint64{id}
+	for d := 0; len(generation) > 0 && (depth <= 0 || d < depth); d++ {
+		var next []uint64
+		for _, parent := range generation {
+			for _, child := range x.children[parent] {
+				if visited[child] {
+					continue
+				}
+				visited[child] = true
+				descendants = append(descendants, *x.metas[child])
+				next = append(next, child)
+			}
+		}
+		generation = next
+	}
+	return descendants
+}
+
+// BuildLineage links metadata records into trees by ParentID and returns
+// the roots in ID order. A seed whose parent is not among metas is a root.
+func BuildLineage(metas []*seed.Metadata) []*LineageNode {
+	x := newLineageIndex(metas)
+	nodes := make(map[uint64]*LineageNode, len(metas))
+	for _, meta := range metas {
+		nodes[meta.ID] = &LineageNode{
+			ID:          meta.ID,
+			CovIncrease: meta.CovIncrease,
+			Bug:         meta.OracleVerdict == seed.OracleVerdictBug,
+		}
+	}
+
+	var roots []*LineageNode
+	for id, node := range nodes {
+		for _, child := range x.children[id] {
+			node.Children = append(node.Children, nodes[child])
+		}
+		if x.isRoot(x.metas[id]) {
+			roots = append(roots, node)
+		}
+	}
+	sort.Slice(roots, func(i, j int) bool { return roots[i].ID < roots[j].ID })
+	return roots
+}
+
+// WriteLineage writes the trees under roots to w as Graphviz DOT (one node
+// per seed labeled with its ID and coverage increase, bug seeds filled red)
+// or as nested JSON.
+func WriteLineage(roots []*LineageNode, format string, w io.Writer) error {
+	switch format {
+	case LineageJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if roots == nil {
+			roots = []*LineageNode{}
+		}
+		return enc.Encode(roots)
+	case LineageDOT:
+		if _, err := fmt.Fprintln(w, "digraph lineage {\n  node [shape=box];"); err != nil {
+			return err
+		}
+		var walk func(node *LineageNode) error
+		walk = func(node *LineageNode) error {
+			attrs := fmt.Sprintf(`label="%d\n+%d"`, node.ID, node.CovIncrease)
+			if node.Bug {
+				attrs += `, style=filled, fillcolor="#f4a6a6"`
+			}
+			if _, err := fmt.Fprintf(w, "  s%d [%s];\n", node.ID, attrs); err != nil {
+				return err
+			}
+			for _, child := range node.Children {
+				if _, err := fmt.Fprintf(w, "  s%d -> s%d;\n", node.ID, child.ID); err != nil {
+					return err
+				}
+				if err := walk(child); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		for _, root := range roots {
+			if err := walk(root); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintln(w, "}")
+		return err
+	default:
+		return fmt.Errorf("unknown lineage format %q: must be %s or %s", format, LineageDOT, LineageJSON)
+	}
+}
+
+// LineageSummary picks out the notable lineages of a corpus.
+type LineageSummary struct {
+	// DeepestSeed ends the longest chain of mutations, Depth mutations
+	// below its root DeepestRoot.
+	DeepestSeed uint64
+	DeepestRoot uint64
+	Depth       int
+
+	// TopRoot is the root whose descendants added the most coverage
+	// (TopRootCoverage basis points), ties going to more bugs.
+	TopRoot            uint64
+	TopRootDescendants int
+	TopRootCoverage    uint64
+	TopRootBugs        int
+}
+
+// SummarizeLineage finds the deepest lineage and the most productive root
+// under roots. Roots without descendants do not count as productive.
+func SummarizeLineage(roots []*LineageNode) LineageSummary {
+	var summary LineageSummary
+	for _, root := range roots {
+		descendants, coverage, bugs := 0, uint64(0), 0
+		var walk func(node *LineageNode, depth int)
+		walk = func(node *LineageNode, depth int) {
+			if depth > summary.Depth {
+				summary.DeepestSeed, summary.DeepestRoot, summary.Depth = node.ID, root.ID, depth
+			}
+			for _, child := range node.Children {
+				descendants++
+				coverage += child.CovIncrease
+				if child.Bug {
+					bugs++
+				}
+				walk(child, depth+1)
+			}
+		}
+		walk(root, 0)
+
+		if descendants == 0 {
+			continue
+		}
+		if summary.TopRoot == 0 || coverage > summary.TopRootCoverage ||
+			coverage == summary.TopRootCoverage && bugs > summary.TopRootBugs {
+			summary.TopRoot = root.ID
+			summary.TopRootDescendants = descendants
+			summary.TopRootCoverage = coverage
+			summary.TopRootBugs = bugs
+		}
+	}
+	return summary
+}
+
+// lineageIndex indexes the metadata of the seeds in the corpus, copied
+// under the read lock. Seed contents are never loaded.
+func (m *FileManager) lineageIndex() *lineageIndex {
+	m.mu.RLock()
+	metas := make([]*seed.Metadata, 0, len(m.processed)+len(m.queue))
+	for _, s := range m.processed {
+		meta := s.Meta
+		metas = append(metas, &meta)
+	}
+	for _, s := range m.queue {
+		meta := s.Meta
+		metas = append(metas, &meta)
+	}
+	m.mu.RUnlock()
+	return newLineageIndex(metas)
+}
+
+// Ancestors walks ParentID links up from seed id and returns the metadata
+// of up to limit ancestors (no limit when limit <= 0), nearest first. The
+// walk stops at an initial seed, at a parent that is not in the corpus, or
+// at a cycle.
+func (m *FileManager) Ancestors(id uint64, limit int) []seed.Metadata {
+	return m.lineageIndex().ancestors(id, limit)
+}
+
+// Descendants returns the metadata of the seeds mutated from seed id,
+// directly or not, down to depth generations (all when depth <= 0):
+// children first, each generation in ID order.
+func (m *FileManager) Descendants(id uint64, depth int) []seed.Metadata {
+	return m.lineageIndex().descendants(id, depth)
+}
+
+// Roots returns the metadata of the initial seeds (ParentID 0) in ID order.
+func (m *FileManager) Roots() []seed.Metadata {
+	x := m.lineageIndex()
+	var roots []seed.Metadata
+	for _, meta := range x.metas {
+		if meta.ParentID == 0 {
+			roots = append(roots, *meta)
+		}
+	}
+	sort.Slice(roots, func(i, j int) bool { return roots[i].ID < roots[j].ID })
+	return roots
+}
+
+// AncestorSeeds is Ancestors with the seeds themselves, loaded, for
+// callers that need their content. Ancestors that fail to load are left
+// out.
+func (m *FileManager) AncestorSeeds(id uint64, n int) []*seed.Seed {
+	if n <= 0 {
+		return nil
+	}
+	ancestors := m.Ancestors(id, n)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	seeds := make([]*seed.Seed, 0, len(ancestors))
+	for _, meta := range ancestors {
+		if s := m.lookup(meta.ID); s != nil && s.Load() == nil {
+			seeds = append(seeds, s)
+		}
+	}
+	return seeds
+}
+
+// Lineage builds the lineage trees of the corpus from its metadata files.
+func (m *FileManager) Lineage() ([]*LineageNode, error) {
+	metas, _, err := seed.LoadAllMetadataJSON(m.metadataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load seed metadata: %w", err)
+	}
+	return BuildLineage(metas), nil
+}
+
+// ExportLineage writes the lineage of the corpus to w in format (LineageDOT
+// or LineageJSON). Only the metadata files are read, not the seeds.
+func (m *FileManager) ExportLineage(format string, w io.Writer) error {
+	roots, err := m.Lineage()
+	if err != nil {
+		return err
+	}
+	return WriteLineage(roots, format, w)
+}
@@ -0,0 +1,175 @@
+package corpus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// newLineageCorpus writes metadata for two roots: 1 -> 3 -> 5 (a bug)
+// with 1 -> 4 beside it, and 2 -> 6.
+func newLineageCorpus(t *testing.T) *FileManager {
+	t.Helper()
+	baseDir := t.TempDir()
+	manager := NewFileManager(baseDir)
+	if err := manager.Initialize(); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+	for _, meta := range []seed.Metadata{
+		{ID: 1, CovIncrease: 120},
+		{ID: 2, CovIncrease: 80},
+		{ID: 3, ParentID: 1, Depth: 1, CovIncrease: 15},
+		{ID: 4, ParentID: 1, Depth: 1, CovIncrease: 5},
+		{ID: 5, ParentID: 3, Depth: 2, CovIncrease: 2, OracleVerdict: seed.OracleVerdictBug},
+		{ID: 6, ParentID: 2, Depth: 1, CovIncrease: 30},
+	} {
+		if err := seed.SaveMetadataJSON(filepath.Join(baseDir, MetadataDir), &meta); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return manager
+}
+
+func TestFileManager_ExportLineage(t *testing.T) {
+	manager := newLineageCorpus(t)
+
+	var dot bytes.Buffer
+	if err := manager.ExportLineage(LineageDOT, &dot); err != nil {
+		t.Fatalf("ExportLineage(dot) error = %v", err)
+	}
+	for _, want := range []string{
+		"digraph lineage {",
+		`s1 [label="1\n+120"];`,
+		"s1 -> s3;",
+		"s3 -> s5;",
+		"s2 -> s6;",
+		`s5 [label="5\n+2", style=filled, fillcolor="#f4a6a6"];`,
+	} {
+		if !strings.Contains(dot.String(), want) {
+			t.Errorf("DOT output missing %q:\n%s", want, dot.String())
+		}
+	}
+	if strings.Count(dot.String(), "fillcolor") != 1 {
+		t.Errorf("only the bug seed should be highlighted:\n%s", dot.String())
+	}
+
+	var out bytes.Buffer
+	if err := manager.ExportLineage(LineageJSON, &out); err != nil {
+		t.Fatalf("ExportLineage(json) error = %v", err)
+	}
+	var roots []*LineageNode
+	if err := json.Unmarshal(out.Bytes(), &roots); err != nil {
+		t.Fatalf("JSON output does not parse: %v", err)
+	}
+	if len(roots) != 2 || roots[0].ID != 1 || roots[1].ID != 2 {
+		t.Fatalf("roots = %+v, want seeds 1 and 2", roots)
+	}
+	if len(roots[0].Children) != 2 || roots[0].Children[0].ID != 3 {
+		t.Fatalf("root 1 children = %+v, want seeds 3 and 4", roots[0].Children)
+	}
+	if leaf := roots[0].Children[0].Children[0]; leaf.ID != 5 || !leaf.Bug {
+		t.Errorf("leaf = %+v, want bug seed 5", leaf)
+	}
+
+	if err := manager.ExportLineage("svg", &out); err == nil {
+		t.Error("an unknown format should fail")
+	}
+}
+
+func TestSummarizeLineage(t *testing.T) {
+	roots, err := newLineageCorpus(t).Lineage()
+	if err != nil {
+		t.Fatalf("Lineage() error = %v", err)
+	}
+	got := SummarizeLineage(roots)
+	want := LineageSummary{
+		DeepestSeed: 5, DeepestRoot: 1, Depth: 2,
+		// Root 2's one child added 30 bp, more than the 22 bp below root 1.
+		TopRoot: 2, TopRootDescendants: 1, TopRootCoverage: 30,
+	}
+	if got != want {
+		t.Errorf("SummarizeLineage() = %+v, want %+v", got, want)
+	}
+
+	if got := SummarizeLineage(BuildLineage([]*seed.Metadata{{ID: 1}})); got != (LineageSummary{}) {
+		t.Errorf("a lone root should give an empty summary, got %+v", got)
+	}
+}
+
+func TestBuildLineage_OrphansAreRoots(t *testing.T) {
+	roots := BuildLineage([]*seed.Metadata{{ID: 9, ParentID: 7}, {ID: 8}})
+	if len(roots) != 2 || roots[0].ID != 8 || roots[1].ID != 9 {
+		t.Errorf("roots = %+v, want seeds 8 and 9", roots)
+	}
+}
+
+func TestFileManager_AncestryQueries(t *testing.T) {
+	// Three generations: 1 -> 3 -> 5 and 1 -> 4, 2 -> 6
+	baseDir := t.TempDir()
+	manager := NewFileManager(baseDir)
+	if err := manager.Initialize(); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+	for i, parent := range []uint64{0, 0, 1, 1, 3, 2} {
+		s := &seed.Seed{Content: fmt.Sprintf("int main() { return %d; }", i), Meta: seed.Metadata{ParentID: parent}}
+		if err := manager.Add(s); err != nil {
+			t.Fatalf("failed to add seed: %v", err)
+		}
+	}
+
+	// Queries run on the lazily opened corpus without loading any seed
+	lazy := NewFileManager(baseDir)
+	if err := lazy.Recover(); err != nil {
+		t.Fatalf("Recover() failed: %v", err)
+	}
+	ids := func(metas []seed.Metadata) string {
+		var out []string
+		for _, meta := range metas {
+			out = append(out, fmt.Sprint(meta.ID))
+		}
+		return strings.Join(out, " ")
+	}
+	for _, tc := range []struct {
+		name string
+		got  []seed.Metadata
+		want string
+	}{
+		{"Ancestors(5, 0)", lazy.Ancestors(5, 0), "3 1"},
+		{"Ancestors(5, 1)", lazy.Ancestors(5, 1), "3"},
+		{"Ancestors(1, 0)", lazy.Ancestors(1, 0), ""},
+		{"Ancestors(99, 0)", lazy.Ancestors(99, 0), ""},
+		{"Descendants(1, 0)", lazy.Descendants(1, 0), "3 4 5"},
+		{"Descendants(1, 1)", lazy.Descendants(1, 1), "3 4"},
+		{"Descendants(5, 0)", lazy.Descendants(5, 0), ""},
+		{"Roots()", lazy.Roots(), "1 2"},
+	} {
+		if got := ids(tc.got); got != tc.want {
+			t.Errorf("%s = [%s], want [%s]", tc.name, got, tc.want)
+		}
+	}
+	if got := lazy.Ancestors(5, 0); got[0].Depth != 1 || got[0].ParentID != 1 {
+		t.Errorf("Ancestors(5, 0)[0] = %+v, want seed 3 at depth 1", got[0])
+	}
+	it := lazy.Seeds(nil)
+	for s, ok := it.Next(); ok; s, ok = it.Next() {
+		if s.Loaded() {
+			t.Errorf("seed %d was loaded by a lineage query", s.Meta.ID)
+		}
+	}
+}
+
+func TestLineageIndex_Cycles(t *testing.T) {
+	// 1 -> 2 -> 3 -> 1, with 4 hanging off 3
+	x := newLineageIndex([]*seed.Metadata{{ID: 1, ParentID: 3}, {ID: 2, ParentID: 1}, {ID: 3, ParentID: 2}, {ID: 4, ParentID: 3}})
+	if got := x.ancestors(4, 0); len(got) != 3 || got[0].ID != 3 || got[2].ID != 1 {
+		t.Errorf("ancestors(4) = %+v, want 3 2 1", got)
+	}
+	if got := x.descendants(1, 0); len(got) != 3 || got[0].ID != 2 || got[1].ID != 3 || got[2].ID != 4 {
+		t.Errorf("descendants(1) = %+v, want 2 3 4", got)
+	}
+}
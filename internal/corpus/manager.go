@@ -1,13 +1,22 @@
 package corpus
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"math/rand"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/zjy-dev/de-fuzz/internal/coverage"
+	"github.com/zjy-dev/de-fuzz/internal/fsutil"
 	"github.com/zjy-dev/de-fuzz/internal/logger"
 	"github.com/zjy-dev/de-fuzz/internal/seed"
 	"github.com/zjy-dev/de-fuzz/internal/state"
@@ -20,21 +29,57 @@ const (
 	MetadataDir = "metadata"
 	// StateDir is the subdirectory for global state.
 	StateDir = "state"
+	// HashIndexFile is the file in StateDir mapping seed hashes to IDs.
+	HashIndexFile = "seed_hashes.json"
 )
 
+// ErrDuplicate is matched (errors.Is) by the *DuplicateError Add returns for
+// a seed the corpus already holds.
+var ErrDuplicate = errors.New("duplicate seed")
+
+// DuplicateError reports a seed rejected by Add because seed ExistingID has
+// the same hash.
+type DuplicateError struct {
+	Hash       string
+	ExistingID uint64
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("duplicate seed: same hash as seed %d (%.12s)", e.ExistingID, e.Hash)
+}
+
+// Is makes errors.Is(err, ErrDuplicate) match.
+func (e *DuplicateError) Is(target error) bool {
+	return target == ErrDuplicate
+}
+
 // FuzzResult contains the outcome of a fuzzing iteration.
 type FuzzResult struct {
 	State       seed.SeedState
 	OldCoverage uint64 // BB coverage before (basis points)
 	NewCoverage uint64 // BB coverage after (basis points)
+	ExecTimeUs  int64  // Time running the seed's test cases (0 if not run)
 
 	// Oracle Results
 	OracleVerdict  seed.OracleVerdict // Verdict from oracle analysis
 	BugType        string             // Type of bug if detected
 	BugDescription string             // Description of bug
+
+	// Outcome, kept in the seed's metadata for postmortems
+	CompileOK            bool     // The seed compiled
+	CompileStderrSnippet string   // Compiler stderr; stored as seed.StderrSnippet
+	ExecSignals          []string // How test runs ended other than by exiting
+	DurationMs           int64    // Time spent compiling, measuring and running
 }
 
 // Manager manages the lifecycle of seeds on disk and in memory.
+//
+// A Manager is safe for concurrent use by several workers. AllocateID
+// never blocks and never returns the same ID twice. Len and Seeds only wait
+// for writers; every other call may wait for one in progress, and Add,
+// Next, Get and ReportResult read or write the seed on disk while holding
+// the corpus. A seed handed out by Next belongs to its caller until it
+// reports the result.
 type Manager interface {
 	// Initialize prepares the directory structure.
 	Initialize() error
@@ -44,9 +89,17 @@ type Manager interface {
 	Recover() error
 
 	// Add persists a new seed to disk and adds it to the processing queue.
-	// It handles ID allocation via the State Manager.
+	// It handles ID allocation via the State Manager. With deduplication on,
+	// a seed whose hash the corpus already holds is not added and Add
+	// returns a *DuplicateError. A corpus over its Limits then evicts
+	// seeds to get back within them.
 	Add(s *seed.Seed) error
 
+	// AddAll adds seeds in bulk, e.g. from seed.ImportDirectory, and
+	// returns how many were added. Duplicates, of the corpus or of earlier
+	// seeds in the batch, are skipped rather than failing the batch.
+	AddAll(seeds []*seed.Seed) (int, error)
+
 	// AllocateID allocates and returns the next unique seed ID without persisting.
 	// Use this to pre-assign an ID to a seed before compilation.
 	AllocateID() uint64
@@ -55,10 +108,64 @@ type Manager interface {
 	// Returns nil if the seed is not found.
 	Get(id uint64) (*seed.Seed, error)
 
-	// Next retrieves the next seed to process from the queue.
+	// Ancestors walks ParentID links up from seed id and returns the
+	// metadata of up to limit ancestors (0 for all), nearest first. The
+	// walk stops at an initial seed, at a parent that is not in the corpus,
+	// or at a cycle. Descendants walks down, breadth first, up to depth
+	// generations (0 for all), and Roots lists the initial seeds. None of
+	// them reads seed contents.
+	Ancestors(id uint64, limit int) []seed.Metadata
+	Descendants(id uint64, depth int) []seed.Metadata
+	Roots() []seed.Metadata
+
+	// AncestorSeeds is Ancestors with up to n ancestor seeds, loaded.
+	AncestorSeeds(id uint64, n int) []*seed.Seed
+
+	// Lineage links the seeds into trees by ParentID, from the metadata
+	// files alone; ExportLineage writes them as DOT or JSON.
+	Lineage() ([]*LineageNode, error)
+	ExportLineage(format string, w io.Writer) error
+
+	// Trim archives the seeds whose every covered line in mapping another
+	// seed also covers, and removes them from mapping. Bug seeds, initial
+	// seeds and seeds keep selects stay.
+	Trim(mapping *coverage.CoverageMapping, keep func(*seed.Seed) bool) (int, error)
+
+	// SeedsCovering returns the metadata of the corpus seeds mapping
+	// credits with covering line, newest or most energetic first.
+	// SeedsCoveringFunction does the same for every line of a function in
+	// analyzer's CFG, against its mapping.
+	SeedsCovering(mapping *coverage.CoverageMapping, line coverage.LineID, order SeedOrder) ([]*seed.Metadata, error)
+	SeedsCoveringFunction(analyzer *coverage.Analyzer, name string, order SeedOrder) ([]*seed.Metadata, error)
+
+	// Stats summarizes the corpus (counts, depths, coverage, bugs, disk
+	// usage, top seeds) from metadata alone.
+	Stats() (*CorpusStats, error)
+
+	// Export writes the corpus as a tar.gz with a manifest; Import reads
+	// one back, replacing the corpus or merging into it with fresh IDs
+	// for colliding seeds.
+	Export(w io.Writer) error
+	Import(r io.Reader, mode MergeMode) (int, error)
+
+	// ConstructStats profiles which source constructs (VLAs, alloca,
+	// setjmp/longjmp, inline asm, ...) the seeds in the corpus use.
+	ConstructStats() ConstructStats
+
+	// Next retrieves the next seed to process from the queue: the oldest,
+	// or one sampled by energy under an energy Schedule.
 	Next() (*seed.Seed, bool)
 
-	// ReportResult updates a seed's metadata after fuzzing.
+	// NextWhere is Next among the queued seeds that filter selects; the
+	// others stay queued in order.
+	NextWhere(filter seed.Filter) (*seed.Seed, bool)
+
+	// Seeds returns an iterator over a snapshot of every seed in the corpus,
+	// processed and queued, in ID order, that filter selects (nil for all).
+	Seeds(filter seed.Filter) *seed.Iterator
+
+	// ReportResult updates a seed's metadata, energy included, after
+	// fuzzing.
 	ReportResult(id uint64, result FuzzResult) error
 
 	// Len returns the number of seeds in the queue.
@@ -72,11 +179,23 @@ type Manager interface {
 
 	// UpdateTotalCoverage updates the total coverage in global state.
 	UpdateTotalCoverage(coverageBasisPoints uint64)
+
+	// SetFavored marks the seeds in ids favored, and every other seed not,
+	// including seeds added later; see seed.Metadata.Favored.
+	SetFavored(ids []uint64)
+
+	// CheckMapping fails if mapping references a seed ID the corpus has
+	// not handed out, which means the corpus state is stale.
+	CheckMapping(mapping *coverage.CoverageMapping) error
 }
 
 // FileManager is a file-backed implementation of the corpus Manager.
 type FileManager struct {
-	mu           sync.Mutex
+	mu           sync.RWMutex
+	saveMu       sync.Mutex    // Orders Save and Finalize snapshots
+	lastID       atomic.Uint64 // Last ID handed out by AllocateID or Add
+	highWater    atomic.Uint64 // Persisted bound on handed out IDs
+	markMu       sync.Mutex    // Orders writes of IDMarkFile
 	baseDir      string
 	corpusDir    string
 	metadataDir  string
@@ -85,6 +204,24 @@ type FileManager struct {
 	namer        seed.NamingStrategy
 	queue        []*seed.Seed          // Seeds waiting to be processed
 	processed    map[uint64]*seed.Seed // Seeds that have been processed
+	dedup        seed.HashStrictness   // "" = no deduplication
+	hashes       map[string]uint64     // Seed.Hash(dedup) -> seed ID
+	compress     bool                  // gzip the sources of added seeds
+	schedule     Schedule              // "" = ScheduleFIFO
+	rng          *rand.Rand            // Samples seeds under energy schedules
+	taken        map[uint64]int        // Parent ID -> children taken by Next
+	favored      map[uint64]bool       // IDs last passed to SetFavored
+	favorWeight  float64               // 0 = DefaultFavoredWeight
+	journal      *journal              // Changes since the last snapshot
+	compactEvery int                   // Journal records that make Save compact
+
+	// Eviction; see SetLimits
+	limits       Limits
+	evictMapping *coverage.CoverageMapping // IDs of evicted seeds are removed from it
+	diskBytes    int64                     // Size of corpusDir as evict tracks it; -1 = unmeasured
+	evicted      int                       // Seeds evicted since the manager was created
+
+	readOnly bool // Opened by OpenReadOnly; set once, read without m.mu
 }
 
 // NewFileManager creates a new corpus FileManager.
@@ -99,11 +236,115 @@ func NewFileManager(baseDir string) *FileManager {
 		namer:        seed.NewDefaultNamingStrategy(),
 		queue:        make([]*seed.Seed, 0),
 		processed:    make(map[uint64]*seed.Seed),
+		hashes:       make(map[string]uint64),
+		taken:        make(map[uint64]int),
+		journal:      newJournal(filepath.Join(stateDir, JournalFile)),
+		compactEvery: journalCompactRecords,
+		diskBytes:    -1,
+	}
+}
+
+// SetDedup makes Add reject seeds whose Seed.Hash(strictness) is already in
+// the corpus. Call it before Recover, which rebuilds the hash index.
+func (m *FileManager) SetDedup(strictness seed.HashStrictness) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dedup = strictness
+}
+
+// SetCompress makes Add save seed sources gzip-compressed (source.c.gz).
+// Seeds already on disk keep their form; Recover loads both.
+func (m *FileManager) SetCompress(compress bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.compress = compress
+}
+
+// SetSchedule selects how Next picks queued seeds. rng drives the energy
+// schedules; nil seeds one from the clock.
+func (m *FileManager) SetSchedule(schedule Schedule, rng *rand.Rand) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if rng == nil {
+		rng = rand.New(rand.NewSource(time.Now().UnixNano()))
 	}
+	m.schedule = schedule
+	m.rng = rng
+}
+
+// hashIndex is the on-disk form of the hash index.
+type hashIndex struct {
+	Strictness seed.HashStrictness `json:"strictness"`
+	Hashes     map[string]uint64   `json:"hashes"`
+}
+
+// loadHashIndex restores the hash index saved for the same strictness,
+// adds the journaled hashes, and computes those of seeds still missing
+// from it. Callers hold m.mu.
+func (m *FileManager) loadHashIndex(seeds []*seed.Seed, journaled map[string]uint64) {
+	m.hashes = make(map[string]uint64)
+	if m.dedup == "" {
+		return
+	}
+	path := filepath.Join(m.stateDir, HashIndexFile)
+	if data, err := os.ReadFile(path); err == nil {
+		var index hashIndex
+		if err := json.Unmarshal(data, &index); err != nil {
+			logger.Warn("Ignoring unreadable seed hash index %s: %v", path, err)
+		} else if index.Strictness == m.dedup {
+			for hash, id := range index.Hashes {
+				m.hashes[hash] = id
+			}
+		}
+	}
+
+	for hash, id := range journaled {
+		if _, ok := m.hashes[hash]; !ok {
+			m.hashes[hash] = id
+		}
+	}
+
+	indexed := make(map[uint64]bool, len(m.hashes))
+	for _, id := range m.hashes {
+		indexed[id] = true
+	}
+	for _, s := range seeds {
+		if indexed[s.Meta.ID] {
+			continue
+		}
+		loaded := s.Loaded()
+		if err := s.Load(); err != nil {
+			continue
+		}
+		hash := s.Hash(m.dedup)
+		if !loaded {
+			s.Release()
+		}
+		if _, ok := m.hashes[hash]; !ok {
+			m.hashes[hash] = s.Meta.ID
+		}
+	}
+}
+
+// hashIndexData encodes the hash index saved next to the global state, or
+// returns nil when deduplication is off. Callers hold m.mu, at least for
+// reading.
+func (m *FileManager) hashIndexData() ([]byte, error) {
+	if m.dedup == "" {
+		return nil, nil
+	}
+	data, err := json.MarshalIndent(hashIndex{Strictness: m.dedup, Hashes: m.hashes}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal seed hash index: %w", err)
+	}
+	return data, nil
 }
 
 // Initialize prepares the directory structure.
 func (m *FileManager) Initialize() error {
+	if m.readOnly {
+		return m.readOnlyError("Initialize")
+	}
 	dirs := []string{m.corpusDir, m.metadataDir, m.stateDir}
 	for _, dir := range dirs {
 		if err := os.MkdirAll(dir, 0755); err != nil {
@@ -111,10 +352,23 @@ func (m *FileManager) Initialize() error {
 		}
 	}
 
-	// Load or initialize state
+	// Load or initialize state, and catch up with the journal
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if err := m.stateManager.Load(); err != nil {
 		return fmt.Errorf("failed to load state: %w", err)
 	}
+	if err := m.loadHighWater(); err != nil {
+		return err
+	}
+	m.syncLastID()
+	if err := m.loadLayout(); err != nil {
+		return err
+	}
+	m.diskBytes = -1
+	if _, err := m.replayJournal(nil); err != nil {
+		return fmt.Errorf("failed to replay corpus journal: %w", err)
+	}
 
 	return nil
 }
@@ -123,17 +377,87 @@ func (m *FileManager) Initialize() error {
 func (m *FileManager) Recover() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if err := m.recover(); err != nil {
+		return err
+	}
+
+	// Log recovery status for checkpoint/resume visibility
+	pendingCount := len(m.queue)
+	processedCount := len(m.processed)
+	totalSeeds := pendingCount + processedCount
+
+	if totalSeeds == 0 {
+		logger.Info("[FRESH START] No seeds found in corpus, starting fresh")
+	} else if processedCount == 0 && pendingCount > 0 {
+		logger.Info("[FRESH START] Found %d initial seeds, no previous run detected", pendingCount)
+	} else if pendingCount > 0 {
+		logger.Info("[RESUME] Resuming from checkpoint: %d seeds in corpus (%d processed, %d pending)",
+			totalSeeds, processedCount, pendingCount)
+	} else {
+		logger.Info("[RESUME] All %d seeds already processed, ready for constraint solving", totalSeeds)
+	}
 
+	return nil
+}
+
+// recover rebuilds the queue, the processed seeds, the hash index and the
+// global state from the corpus directory. Callers hold m.mu.
+func (m *FileManager) recover() error {
 	// Load state first
 	if err := m.stateManager.Load(); err != nil {
 		return fmt.Errorf("failed to load state: %w", err)
 	}
+	if err := m.loadHighWater(); err != nil {
+		return err
+	}
+	m.syncLastID()
+	if err := m.loadLayout(); err != nil {
+		return err
+	}
+	m.diskBytes = -1
 
-	// Load all seeds from corpus
-	seeds, err := seed.LoadSeedsWithMetadata(m.corpusDir, m.namer)
+	// Open the corpus lazily: seed sources are read when a seed is taken
+	// from the queue (or looked up), not all up front.
+	open := seed.OpenPool
+	if m.readOnly {
+		open = seed.OpenPoolReadOnly
+	}
+	pool, err := open(m.corpusDir, m.namer)
 	if err != nil {
 		return fmt.Errorf("failed to load seeds: %w", err)
 	}
+	seeds := pool.Seeds()
+
+	// Restore what the directory names do not encode from the metadata JSON,
+	// upgrading records written by older versions
+	metas, migrated, err := seed.LoadAllMetadataJSON(m.metadataDir)
+	if err != nil {
+		return fmt.Errorf("failed to load seed metadata: %w", err)
+	}
+	restorePersistedMetadata(seeds, metas)
+	if migrated > 0 && !m.readOnly {
+		logger.Info("Migrated %d seed metadata files to schema version %d", migrated, seed.MetadataSchemaVersion)
+	}
+
+	// Replay the changes made since the last snapshot
+	byID := make(map[uint64]*seed.Seed, len(seeds))
+	for _, s := range seeds {
+		byID[s.Meta.ID] = s
+		m.reserveID(s.Meta.ID)
+	}
+	journaled, err := m.replayJournal(byID)
+	if err != nil {
+		return fmt.Errorf("failed to replay corpus journal: %w", err)
+	}
+	if len(byID) < len(seeds) {
+		kept := seeds[:0]
+		for _, s := range seeds {
+			if byID[s.Meta.ID] == s {
+				kept = append(kept, s)
+			}
+		}
+		seeds = kept
+	}
 
 	// Separate pending and processed seeds
 	m.queue = make([]*seed.Seed, 0)
@@ -153,40 +477,139 @@ func (m *FileManager) Recover() error {
 		return m.queue[i].Meta.ID < m.queue[j].Meta.ID
 	})
 
+	m.loadHashIndex(seeds, journaled)
+
 	// Update pool size in state
 	m.stateManager.UpdatePoolSize(len(m.queue))
+	return nil
+}
 
-	// Log recovery status for checkpoint/resume visibility
-	totalSeeds := len(seeds)
-	pendingCount := len(m.queue)
-	processedCount := len(m.processed)
+// restorePersistedMetadata copies lineage, provenance and results from the
+// metadata JSON records onto the seeds loaded from the corpus directory,
+// so processed seeds stay processed. Fields the directory itself provides
+// (ID, parent, coverage increase, paths) are kept.
+func restorePersistedMetadata(seeds []*seed.Seed, metas []*seed.Metadata) {
+	byID := make(map[uint64]*seed.Metadata, len(metas))
+	for _, meta := range metas {
+		byID[meta.ID] = meta
+	}
+	for _, s := range seeds {
+		meta, ok := byID[s.Meta.ID]
+		if !ok {
+			continue
+		}
+		s.Meta.CreatedAt = meta.CreatedAt
+		s.Meta.Depth = meta.Depth
+		s.Meta.MutationNote = meta.MutationNote
+		s.Meta.Provenance = meta.Provenance
+		s.Meta.PromptTokens = meta.PromptTokens
+		s.Meta.ContextTokens = meta.ContextTokens
+		s.Meta.Hash = meta.Hash
+		s.Meta.Energy = meta.Energy
+		s.Meta.SchemaVersion = meta.SchemaVersion
+		if meta.State != "" && meta.State != seed.SeedStateArchived {
+			s.Meta.State = meta.State
+			s.Meta.OldCoverage = meta.OldCoverage
+			s.Meta.NewCoverage = meta.NewCoverage
+			s.Meta.OracleVerdict = meta.OracleVerdict
+			s.Meta.BugType = meta.BugType
+			s.Meta.BugDescription = meta.BugDescription
+			s.Meta.ExecTimeUs = meta.ExecTimeUs
+			s.Meta.CompileOK = meta.CompileOK
+			s.Meta.CompileStderr = meta.CompileStderr
+			s.Meta.ExecSignals = meta.ExecSignals
+			s.Meta.DurationMs = meta.DurationMs
+		}
+	}
+}
 
-	if totalSeeds == 0 {
-		logger.Info("[FRESH START] No seeds found in corpus, starting fresh")
-	} else if processedCount == 0 && pendingCount > 0 {
-		logger.Info("[FRESH START] Found %d initial seeds, no previous run detected", pendingCount)
-	} else if pendingCount > 0 {
-		logger.Info("[RESUME] Resuming from checkpoint: %d seeds in corpus (%d processed, %d pending)",
-			totalSeeds, processedCount, pendingCount)
-	} else {
-		logger.Info("[RESUME] All %d seeds already processed, ready for constraint solving", totalSeeds)
+// replayJournal applies the journal over the snapshot just loaded: the
+// global state catches up, the seeds (by ID; nil when only the state is
+// wanted) get their recorded results, archived seeds are deleted from
+// seeds, and the hashes of added seeds are returned. Callers hold m.mu.
+func (m *FileManager) replayJournal(seeds map[uint64]*seed.Seed) (map[string]uint64, error) {
+	hashes := make(map[string]uint64)
+	err := m.journal.replay(func(rec journalRecord) {
+		m.reserveID(rec.ID)
+		switch rec.Op {
+		case journalAdd:
+			if _, ok := hashes[rec.Hash]; rec.Hash != "" && !ok {
+				hashes[rec.Hash] = rec.ID
+			}
+		case journalResult:
+			m.stateManager.RestoreProgress(rec.Processed, rec.Coverage)
+			if s, ok := seeds[rec.ID]; ok && rec.Result != nil {
+				rec.Result.apply(&s.Meta)
+			}
+		case journalArchive:
+			delete(seeds, rec.ID)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if m.journal.records > 0 && !m.readOnly {
+		logger.Info("Replayed %d corpus journal records", m.journal.records)
 	}
+	return hashes, nil
+}
 
-	return nil
+// ValidatePending compiles every queued seed with compileFn, at most workers
+// at once (see seed.ValidateAll), and marks the failures
+// seed.SeedStateInvalid, recording the state in their metadata. The seeds
+// stay in the queue; the engine skips them. It returns the failures by ID.
+func (m *FileManager) ValidatePending(compileFn func(*seed.Seed) error, workers int) map[uint64]error {
+	m.mu.Lock()
+	pending := append([]*seed.Seed(nil), m.queue...)
+	m.mu.Unlock()
+
+	failures := seed.ValidateAll(pending, compileFn, workers)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range pending {
+		err, ok := failures[s.Meta.ID]
+		if !ok {
+			continue
+		}
+		s.Meta.CompileOK = false
+		s.Meta.CompileStderr = seed.StderrSnippet(err.Error())
+		if m.readOnly {
+			continue
+		}
+		if err := seed.SaveMetadataJSON(m.metadataDir, &s.Meta); err != nil {
+			logger.Warn("Failed to save metadata for seed %d: %v", s.Meta.ID, err)
+		}
+	}
+	return failures
 }
 
 // Add persists a new seed to disk and adds it to the processing queue.
 func (m *FileManager) Add(s *seed.Seed) error {
+	if m.readOnly {
+		return m.readOnlyError("Add")
+	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	// Reject duplicates before allocating an ID for them
+	if m.dedup != "" {
+		s.Meta.Hash = s.Hash(m.dedup)
+		if id, ok := m.hashes[s.Meta.Hash]; ok {
+			return &DuplicateError{Hash: s.Meta.Hash, ExistingID: id}
+		}
+	}
+
 	// Allocate new ID if not set
 	if s.Meta.ID == 0 {
-		s.Meta.ID = m.stateManager.NextID()
+		s.Meta.ID = m.AllocateID()
+	} else {
+		m.reserveID(s.Meta.ID)
 	}
 
 	// Ensure state is pending
 	s.Meta.State = seed.SeedStatePending
+	s.Meta.Favored = m.favored[s.Meta.ID]
 
 	// Set depth from parent if not already set
 	if s.Meta.Depth == 0 && s.Meta.ParentID > 0 {
@@ -198,6 +621,7 @@ func (m *FileManager) Add(s *seed.Seed) error {
 	}
 
 	// Save to disk
+	s.Meta.Compressed = m.compress
 	_, err := seed.SaveSeedWithMetadata(m.corpusDir, s, m.namer)
 	if err != nil {
 		return fmt.Errorf("failed to save seed: %w", err)
@@ -207,67 +631,212 @@ func (m *FileManager) Add(s *seed.Seed) error {
 	if err := seed.SaveMetadataJSON(m.metadataDir, &s.Meta); err != nil {
 		// Log warning but don't fail
 	}
+	if err := m.journal.append(journalRecord{Op: journalAdd, ID: s.Meta.ID, Hash: s.Meta.Hash}); err != nil {
+		logger.Warn("Failed to journal seed %d: %v", s.Meta.ID, err)
+	}
 
 	// Add to queue
 	m.queue = append(m.queue, s)
 	m.stateManager.UpdatePoolSize(len(m.queue))
+	if m.dedup != "" {
+		m.hashes[s.Meta.Hash] = s.Meta.ID
+	}
 
-	return nil
+	return m.evict(s)
 }
 
-// AllocateID allocates and returns the next unique seed ID without persisting.
-// This allows pre-assigning an ID to a seed before compilation.
+// AddAll adds seeds one by one, skipping duplicates. It stops at the first
+// other error.
+func (m *FileManager) AddAll(seeds []*seed.Seed) (int, error) {
+	added := 0
+	for _, s := range seeds {
+		if err := m.Add(s); err != nil {
+			if errors.Is(err, ErrDuplicate) {
+				continue
+			}
+			return added, fmt.Errorf("failed to add seed %d: %w", s.Meta.ID, err)
+		}
+		added++
+	}
+	return added, nil
+}
+
+// AllocateID allocates and returns the next unique seed ID. IDs increase
+// across restarts, even past seeds that were trimmed or never saved: the
+// ID is covered by the persisted high-water mark (see IDMarkFile) before
+// it is returned. It does not take the corpus lock.
 func (m *FileManager) AllocateID() uint64 {
-	return m.stateManager.NextID()
+	id := m.lastID.Add(1)
+	m.ensureHighWater(id)
+	m.stateManager.ReserveID(id)
+	return id
+}
+
+// reserveID makes AllocateID skip IDs up to id, for seeds added with an ID
+// of their own.
+func (m *FileManager) reserveID(id uint64) {
+	for {
+		last := m.lastID.Load()
+		if last >= id || m.lastID.CompareAndSwap(last, id) {
+			break
+		}
+	}
+	m.ensureHighWater(id)
+	m.stateManager.ReserveID(id)
+}
+
+// syncLastID reconciles the ID counter with the state just loaded, which
+// may be ahead of it (a resumed run) or behind it (IDs allocated before
+// the load).
+func (m *FileManager) syncLastID() {
+	m.reserveID(m.stateManager.GetState().LastAllocatedID)
+	m.stateManager.ReserveID(m.lastID.Load())
 }
 
-// Next retrieves the next seed to process from the queue.
+// Next retrieves the next seed to process from the queue, loaded: the
+// oldest under ScheduleFIFO, else one sampled by energy (see SetSchedule).
 // Returns false if the queue is empty.
 func (m *FileManager) Next() (*seed.Seed, bool) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	return m.NextWhere(nil)
+}
 
-	if len(m.queue) == 0 {
-		return nil, false
+// take loads a seed just removed from the queue and moves it to the
+// processed map, so ReportResult can find it. A seed that fails to load
+// has been quarantined and is dropped. Callers hold m.mu.
+func (m *FileManager) take(s *seed.Seed) bool {
+	m.stateManager.UpdatePoolSize(len(m.queue))
+	if err := s.Load(); err != nil {
+		logger.Warn("Dropping seed %d: %v", s.Meta.ID, err)
+		return false
 	}
+	m.stateManager.UpdateCurrentID(s.Meta.ID)
+	m.processed[s.Meta.ID] = s
+	if s.Meta.ParentID != 0 {
+		m.taken[s.Meta.ParentID]++
+	}
+	return true
+}
 
-	// Pop from front (FIFO)
-	s := m.queue[0]
-	m.queue = m.queue[1:]
+// NextWhere is Next among the queued seeds that filter selects (nil for
+// all), leaving the others queued in order.
+func (m *FileManager) NextWhere(filter seed.Filter) (*seed.Seed, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	// Update state
-	m.stateManager.UpdateCurrentID(s.Meta.ID)
-	m.stateManager.UpdatePoolSize(len(m.queue))
+	for {
+		i := m.pick(filter)
+		if i < 0 {
+			return nil, false
+		}
+		s := m.queue[i]
+		m.queue = append(m.queue[:i:i], m.queue[i+1:]...)
+		if m.take(s) {
+			return s, true
+		}
+	}
+}
 
-	// Store in processed map so ReportResult can find it
-	m.processed[s.Meta.ID] = s
+// Seeds returns an iterator over a snapshot of the processed and queued
+// seeds, in ID order, that filter selects. Seeds recovered from disk may be
+// unloaded; call Load before reading their content.
+func (m *FileManager) Seeds(filter seed.Filter) *seed.Iterator {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 
-	return s, true
+	seeds := make([]*seed.Seed, 0, len(m.processed)+len(m.queue))
+	for _, s := range m.processed {
+		seeds = append(seeds, s)
+	}
+	seeds = append(seeds, m.queue...)
+	sort.Slice(seeds, func(i, j int) bool {
+		return seeds[i].Meta.ID < seeds[j].Meta.ID
+	})
+	return seed.NewIterator(seeds, filter)
 }
 
 // Get retrieves a seed by ID from the processed seeds or queue.
-// Returns nil if the seed is not found.
+// Returns nil if the seed is not found. A read-only corpus refreshes once
+// when the seed is missing, from memory or from disk, and looks again.
 func (m *FileManager) Get(id uint64) (*seed.Seed, error) {
+	s, err := m.get(id)
+	if m.readOnly && (s == nil && err == nil || errors.Is(err, fs.ErrNotExist)) {
+		// Added, renamed or removed by the writer since the last refresh
+		if err := m.Refresh(); err != nil {
+			return nil, err
+		}
+		s, err = m.get(id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, fmt.Errorf("seed %d not found in corpus", id)
+	}
+	return s, nil
+}
+
+// get finds seed id, processed or queued, and loads it; it returns nil
+// and no error if there is no such seed.
+func (m *FileManager) get(id uint64) (*seed.Seed, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// First check processed map
-	if s, ok := m.processed[id]; ok {
-		return s, nil
+	// Processed seeds first, then the queue (seeds added but not yet processed)
+	s := m.lookup(id)
+	if s == nil {
+		return nil, nil
+	}
+	if err := s.Load(); err != nil {
+		return nil, err
 	}
+	return s, nil
+}
 
-	// Also check queue (for seeds added but not yet processed)
+// ConstructStats profiles the constructs used by every seed in the corpus,
+// processed or still queued. Unloaded seeds are loaded one at a time and
+// released again.
+func (m *FileManager) ConstructStats() ConstructStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	profiler := newConstructProfiler()
+	profile := func(s *seed.Seed) {
+		loaded := s.Loaded()
+		if err := s.Load(); err != nil {
+			return
+		}
+		profiler.add(s)
+		if !loaded {
+			s.Release()
+		}
+	}
+	for _, s := range m.processed {
+		profile(s)
+	}
+	for _, s := range m.queue {
+		profile(s)
+	}
+	return profiler.stats()
+}
+
+// lookup finds a seed in the processed map or the queue. Callers hold m.mu.
+func (m *FileManager) lookup(id uint64) *seed.Seed {
+	if s, ok := m.processed[id]; ok {
+		return s
+	}
 	for _, s := range m.queue {
 		if s.Meta.ID == id {
-			return s, nil
+			return s
 		}
 	}
-
-	return nil, fmt.Errorf("seed %d not found in corpus", id)
+	return nil
 }
 
 // ReportResult updates a seed's metadata after fuzzing.
 func (m *FileManager) ReportResult(id uint64, result FuzzResult) error {
+	if m.readOnly {
+		return m.readOnlyError("ReportResult")
+	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -290,6 +859,7 @@ func (m *FileManager) ReportResult(id uint64, result FuzzResult) error {
 	s.Meta.State = result.State
 	s.Meta.OldCoverage = result.OldCoverage
 	s.Meta.NewCoverage = result.NewCoverage
+	s.Meta.ExecTimeUs = result.ExecTimeUs
 
 	// Calculate coverage increase
 	if result.NewCoverage > result.OldCoverage {
@@ -302,13 +872,18 @@ func (m *FileManager) ReportResult(id uint64, result FuzzResult) error {
 	s.Meta.OracleVerdict = result.OracleVerdict
 	s.Meta.BugType = result.BugType
 	s.Meta.BugDescription = result.BugDescription
+	s.Meta.CompileOK = result.CompileOK
+	s.Meta.CompileStderr = seed.StderrSnippet(result.CompileStderrSnippet)
+	s.Meta.ExecSignals = result.ExecSignals
+	s.Meta.DurationMs = result.DurationMs
+	s.Meta.Energy = Energy(&s.Meta, m.schedule)
 
 	// Debug: Log the oracle verdict being saved
 	logger.Debug("ReportResult: seed %d oracle_verdict=%q", id, s.Meta.OracleVerdict)
 
 	// Rename seed directory if CovIncrease changed (fixes cov-00000 naming issue)
 	// oldPath is the path to source.c, we need to rename the parent directory
-	if s.Meta.CovIncrease != oldCovIncrease && oldPath != "" && s.Content != "" {
+	if s.Meta.CovIncrease != oldCovIncrease && oldPath != "" && s.Load() == nil && s.Content != "" {
 		oldDir := filepath.Dir(oldPath) // Get the seed directory (parent of source.c)
 		newFilename := m.namer.GenerateFilename(&s.Meta, s.Content)
 		// Remove .seed extension to get directory name
@@ -318,8 +893,8 @@ func (m *FileManager) ReportResult(id uint64, result FuzzResult) error {
 			if err := os.Rename(oldDir, newDir); err != nil {
 				logger.Warn("Failed to rename seed directory from %s to %s: %v", oldDir, newDir, err)
 			} else {
-				// Update ContentPath to point to source.c in the new directory
-				s.Meta.ContentPath = filepath.Join(newDir, "source.c")
+				// Update ContentPath to point to the source file in the new directory
+				s.Meta.ContentPath = filepath.Join(newDir, filepath.Base(oldPath))
 				s.Meta.FilePath = newDirName
 				logger.Debug("Renamed seed %d directory: %s -> %s", id, filepath.Base(oldDir), newDirName)
 			}
@@ -340,30 +915,99 @@ func (m *FileManager) ReportResult(id uint64, result FuzzResult) error {
 		m.stateManager.UpdateCoverage(result.NewCoverage)
 	}
 
+	// Journal the result, so the global state need not be saved to keep it
+	progress := m.stateManager.GetState()
+	rec := journalRecord{
+		Op:        journalResult,
+		ID:        id,
+		Result:    resultOf(&s.Meta),
+		Processed: progress.Stats.ProcessedCount,
+		Coverage:  progress.TotalCoverage,
+	}
+	if err := m.journal.append(rec); err != nil {
+		logger.Warn("Failed to journal result of seed %d: %v", id, err)
+	}
+
 	return nil
 }
 
 // Len returns the number of seeds in the queue.
 func (m *FileManager) Len() int {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	return len(m.queue)
 }
 
-// Save persists the current state to disk.
+// Save makes the changes so far durable: it syncs the journal and writes
+// the global state, which is small. Once the journal reaches compactEvery
+// records, Save also compacts it: the seed hash index is written and the
+// journal it covers dropped. Seeds and their metadata are written when
+// they change, so Save never rewrites them.
 func (m *FileManager) Save() error {
-	return m.stateManager.Save()
+	if m.readOnly {
+		return m.readOnlyError("Save")
+	}
+	m.saveMu.Lock()
+	defer m.saveMu.Unlock()
+	return m.save(false)
 }
 
-// Finalize updates the global state when fuzzing completes.
-// It sets pool_size to 0 and current_fuzzing_id to 0.
+// Finalize updates the global state when fuzzing completes and compacts
+// the journal. It sets pool_size to 0 and current_fuzzing_id to 0.
 func (m *FileManager) Finalize() error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	if m.readOnly {
+		return m.readOnlyError("Finalize")
+	}
+	m.saveMu.Lock()
+	defer m.saveMu.Unlock()
 
+	m.mu.Lock()
 	m.stateManager.UpdatePoolSize(0)
 	m.stateManager.UpdateCurrentID(0)
-	return m.stateManager.Save()
+	m.mu.Unlock()
+	return m.save(true)
+}
+
+// save syncs the journal, or rotates it aside when compacting, and copies
+// the hash index and global state together under the lock; the files are
+// written after it is released. Callers hold m.saveMu.
+func (m *FileManager) save(compact bool) error {
+	m.mu.Lock()
+	compact = compact || m.journal.records >= m.compactEvery
+	var index []byte
+	var err error
+	if compact {
+		if index, err = m.hashIndexData(); err == nil {
+			err = m.journal.rotate()
+		}
+	} else {
+		err = m.journal.sync()
+	}
+	snapshot := m.stateManager.GetState()
+	m.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to save corpus journal: %w", err)
+	}
+
+	if err := m.writeSnapshot(index, snapshot); err != nil {
+		return err
+	}
+	if compact {
+		return m.journal.dropRotated()
+	}
+	return nil
+}
+
+// writeSnapshot writes a hash index (nil for none) and global state copied
+// together, each atomically. Callers hold m.saveMu, not m.mu.
+func (m *FileManager) writeSnapshot(index []byte, snapshot state.GlobalState) error {
+	if index != nil {
+		path := filepath.Join(m.stateDir, HashIndexFile)
+		if err := fsutil.WriteFileAtomic(path, index); err != nil {
+			return fmt.Errorf("failed to write seed hash index %s: %w", path, err)
+		}
+	}
+	return m.stateManager.SaveSnapshot(snapshot)
 }
 
 // UpdateTotalCoverage updates the total coverage in global state.
@@ -1,6 +1,12 @@
 package corpus
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/zjy-dev/de-fuzz/internal/seed"
@@ -138,3 +144,442 @@ func TestFileManager(t *testing.T) {
 		}
 	})
 }
+
+func TestFileManager_AncestorSeeds(t *testing.T) {
+	manager := NewFileManager(t.TempDir())
+	if err := manager.Initialize(); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+
+	// 1 <- 2 <- 3 <- 4, with 2 and 3 processed and 4 still queued.
+	var parent uint64
+	for i := 0; i < 4; i++ {
+		s := &seed.Seed{Meta: seed.Metadata{ParentID: parent}, Content: "int main() { return 0; }"}
+		if err := manager.Add(s); err != nil {
+			t.Fatalf("failed to add seed: %v", err)
+		}
+		if i < 3 {
+			manager.Next()
+		}
+		parent = s.Meta.ID
+	}
+
+	ids := func(seeds []*seed.Seed) []uint64 {
+		var out []uint64
+		for _, s := range seeds {
+			out = append(out, s.Meta.ID)
+		}
+		return out
+	}
+
+	if got := ids(manager.AncestorSeeds(4, 10)); len(got) != 3 || got[0] != 3 || got[1] != 2 || got[2] != 1 {
+		t.Errorf("AncestorSeeds(4, 10) = %v, want [3 2 1]", got)
+	}
+	if got := ids(manager.AncestorSeeds(4, 2)); len(got) != 2 || got[0] != 3 || got[1] != 2 {
+		t.Errorf("AncestorSeeds(4, 2) = %v, want [3 2]", got)
+	}
+	if got := manager.AncestorSeeds(1, 5); len(got) != 0 {
+		t.Errorf("initial seed should have no ancestors, got %v", ids(got))
+	}
+	if got := manager.AncestorSeeds(99, 5); len(got) != 0 {
+		t.Errorf("unknown seed should have no ancestors, got %v", ids(got))
+	}
+}
+
+func TestFileManager_Dedup(t *testing.T) {
+	tmpDir := t.TempDir()
+	manager := NewFileManager(tmpDir)
+	manager.SetDedup(seed.HashWhitespace)
+	if err := manager.Initialize(); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+
+	original := &seed.Seed{Content: "int main() { return 0; }"}
+	if err := manager.Add(original); err != nil {
+		t.Fatalf("failed to add seed: %v", err)
+	}
+	if original.Meta.Hash != original.Hash(seed.HashWhitespace) {
+		t.Errorf("Meta.Hash = %q, want the seed hash", original.Meta.Hash)
+	}
+
+	for name, content := range map[string]string{
+		"exact duplicate":      "int main() { return 0; }",
+		"whitespace duplicate": "int main(){\n\treturn 0;\n}\n",
+	} {
+		err := manager.Add(&seed.Seed{Content: content})
+		var dup *DuplicateError
+		if !errors.As(err, &dup) || !errors.Is(err, ErrDuplicate) {
+			t.Fatalf("%s: Add() error = %v, want a DuplicateError", name, err)
+		}
+		if dup.ExistingID != original.Meta.ID {
+			t.Errorf("%s: ExistingID = %d, want %d", name, dup.ExistingID, original.Meta.ID)
+		}
+	}
+	if err := manager.Add(&seed.Seed{Content: "int main() { return 1; }"}); err != nil {
+		t.Fatalf("a different seed should be added: %v", err)
+	}
+	if manager.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", manager.Len())
+	}
+	// Save leaves the hashes in the journal; Finalize compacts them into
+	// the index.
+	if err := manager.Finalize(); err != nil {
+		t.Fatalf("failed to finalize: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, StateDir, HashIndexFile)); err != nil {
+		t.Errorf("hash index not saved: %v", err)
+	}
+
+	// A resumed manager keeps rejecting the duplicates.
+	resumed := NewFileManager(tmpDir)
+	resumed.SetDedup(seed.HashWhitespace)
+	if err := resumed.Recover(); err != nil {
+		t.Fatalf("failed to recover: %v", err)
+	}
+	if err := resumed.Add(&seed.Seed{Content: "int main()  {  return 1;  }"}); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Add() after resume error = %v, want ErrDuplicate", err)
+	}
+
+	// Without dedup, duplicates are added.
+	plain := NewFileManager(t.TempDir())
+	_ = plain.Initialize()
+	for i := 0; i < 2; i++ {
+		if err := plain.Add(&seed.Seed{Content: original.Content}); err != nil {
+			t.Fatalf("Add() without dedup failed: %v", err)
+		}
+	}
+}
+
+func TestFileManager_RecoverMigratesMetadata(t *testing.T) {
+	tmpDir := t.TempDir()
+	manager := NewFileManager(tmpDir)
+	if err := manager.Initialize(); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+	s := &seed.Seed{Content: "int main() { return 0; }"}
+	if err := manager.Add(s); err != nil {
+		t.Fatalf("failed to add seed: %v", err)
+	}
+
+	// Replace the seed's metadata with a version 1 record.
+	legacy := fmt.Sprintf(`{"id": %d, "depth": 3, "mutation_note": "legacy note", "hash": "abc", "schema_version": 1}`, s.Meta.ID)
+	metaPath := filepath.Join(tmpDir, MetadataDir, fmt.Sprintf("id-%06d.json", s.Meta.ID))
+	if err := os.WriteFile(metaPath, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	resumed := NewFileManager(tmpDir)
+	if err := resumed.Recover(); err != nil {
+		t.Fatalf("failed to recover: %v", err)
+	}
+	got, ok := resumed.Next()
+	if !ok {
+		t.Fatal("recovered corpus is empty")
+	}
+	if got.Meta.Depth != 3 || got.Meta.MutationNote != "legacy note" || got.Meta.Hash != "abc" {
+		t.Errorf("metadata not restored: %+v", got.Meta)
+	}
+	if got.Meta.SchemaVersion != seed.MetadataSchemaVersion {
+		t.Errorf("SchemaVersion = %d, want %d", got.Meta.SchemaVersion, seed.MetadataSchemaVersion)
+	}
+
+	// Metadata from a newer binary stops the resume.
+	future := fmt.Sprintf(`{"id": %d, "schema_version": %d}`, s.Meta.ID, seed.MetadataSchemaVersion+1)
+	if err := os.WriteFile(metaPath, []byte(future), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewFileManager(tmpDir).Recover(); !errors.Is(err, seed.ErrMetadataTooNew) {
+		t.Errorf("Recover() error = %v, want ErrMetadataTooNew", err)
+	}
+}
+
+func TestFileManager_NextWhere(t *testing.T) {
+	manager := NewFileManager(t.TempDir())
+	if err := manager.Initialize(); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+	for i, lang := range []seed.Language{seed.LanguageCPP, seed.LanguageC, seed.LanguageCPP, seed.LanguageC} {
+		s := &seed.Seed{Content: fmt.Sprintf("int main() { return %d; }", i), Language: lang}
+		if err := manager.Add(s); err != nil {
+			t.Fatalf("failed to add seed: %v", err)
+		}
+	}
+
+	var got []uint64
+	for s, ok := manager.NextWhere(seed.ByLanguage(seed.LanguageC)); ok; s, ok = manager.NextWhere(seed.ByLanguage(seed.LanguageC)) {
+		got = append(got, s.Meta.ID)
+	}
+	if fmt.Sprint(got) != "[2 4]" {
+		t.Errorf("NextWhere() yielded %v, want [2 4]", got)
+	}
+	if manager.Len() != 2 {
+		t.Errorf("Len() = %d, want the 2 C++ seeds still queued", manager.Len())
+	}
+	// Next keeps its order over the seeds left.
+	if s, ok := manager.Next(); !ok || s.Meta.ID != 1 {
+		t.Errorf("Next() = %v, want seed 1", s)
+	}
+
+	// Seeds covers processed and queued seeds in ID order.
+	it := manager.Seeds(seed.ByLanguage(seed.LanguageCPP))
+	got = nil
+	for s, ok := it.Next(); ok; s, ok = it.Next() {
+		got = append(got, s.Meta.ID)
+	}
+	if fmt.Sprint(got) != "[1 3]" {
+		t.Errorf("Seeds() yielded %v, want [1 3]", got)
+	}
+	it.Reset()
+	if s, ok := it.Next(); !ok || s.Meta.ID != 1 {
+		t.Errorf("Next() after Reset = %v, want seed 1", s)
+	}
+	if _, ok := manager.Seeds(nil).Next(); !ok {
+		t.Error("Seeds(nil) should select every seed")
+	}
+}
+
+func TestFileManager_Tags(t *testing.T) {
+	tmpDir := t.TempDir()
+	manager := NewFileManager(tmpDir)
+	if err := manager.Initialize(); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+	for i, tags := range [][]string{{"vla"}, nil, {"alloca", "vla"}} {
+		s := &seed.Seed{Content: fmt.Sprintf("int main() { return %d; }", i), Meta: seed.Metadata{Tags: tags}}
+		if err := manager.Add(s); err != nil {
+			t.Fatalf("failed to add seed: %v", err)
+		}
+	}
+
+	// The tags survive a restart.
+	recovered := NewFileManager(tmpDir)
+	if err := recovered.Recover(); err != nil {
+		t.Fatalf("Recover() failed: %v", err)
+	}
+	var got []uint64
+	it := recovered.Seeds(seed.ByTag("vla"))
+	for s, ok := it.Next(); ok; s, ok = it.Next() {
+		got = append(got, s.Meta.ID)
+	}
+	if fmt.Sprint(got) != "[1 3]" {
+		t.Errorf("Seeds(ByTag(vla)) yielded %v, want [1 3]", got)
+	}
+	if s, _ := recovered.Get(3); s == nil || fmt.Sprint(s.Meta.Tags) != "[alloca vla]" {
+		t.Errorf("seed 3 tags = %v, want [alloca vla]", s)
+	}
+	if tags := recovered.ConstructStats().Tags; fmt.Sprint(tags) != "[{vla 2} {alloca 1}]" {
+		t.Errorf("ConstructStats().Tags = %v", tags)
+	}
+}
+
+func TestFileManager_RecoverLazily(t *testing.T) {
+	tmpDir := t.TempDir()
+	manager := NewFileManager(tmpDir)
+	if err := manager.Initialize(); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+	s := &seed.Seed{
+		Content:   "int main() { return 0; }",
+		TestCases: []seed.TestCase{{RunningCommand: "./a.out", ExpectedResult: "0"}},
+	}
+	if err := manager.Add(s); err != nil {
+		t.Fatalf("failed to add seed: %v", err)
+	}
+
+	recovered := NewFileManager(tmpDir)
+	if err := recovered.Recover(); err != nil {
+		t.Fatalf("Recover() failed: %v", err)
+	}
+	it := recovered.Seeds(nil)
+	pending, _ := it.Next()
+	if pending == nil || pending.Loaded() {
+		t.Fatalf("recovered seed should not be loaded before it is taken: %+v", pending)
+	}
+
+	next, ok := recovered.Next()
+	if !ok || !next.Loaded() || next.Content != s.Content || len(next.TestCases) != 1 {
+		t.Errorf("Next() = %+v, want the loaded seed", next)
+	}
+}
+
+func TestFileManager_Compress(t *testing.T) {
+	tmpDir := t.TempDir()
+	manager := NewFileManager(tmpDir)
+	if err := manager.Initialize(); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+	if err := manager.Add(&seed.Seed{Content: "int main() { return 0; }"}); err != nil {
+		t.Fatalf("failed to add seed: %v", err)
+	}
+	manager.SetCompress(true)
+	compressed := &seed.Seed{Content: "int main() { return 1; }"}
+	if err := manager.Add(compressed); err != nil {
+		t.Fatalf("failed to add seed: %v", err)
+	}
+	if !strings.HasSuffix(compressed.Meta.ContentPath, "source.c.gz") {
+		t.Errorf("ContentPath = %s, want the compressed source", compressed.Meta.ContentPath)
+	}
+
+	resumed := NewFileManager(tmpDir)
+	if err := resumed.Recover(); err != nil {
+		t.Fatalf("failed to recover: %v", err)
+	}
+	for id, want := range map[uint64]string{1: "int main() { return 0; }", 2: "int main() { return 1; }"} {
+		s, err := resumed.Get(id)
+		if err != nil {
+			t.Fatalf("Get(%d) error = %v", id, err)
+		}
+		if s.Content != want || s.Meta.Compressed != (id == 2) {
+			t.Errorf("seed %d: content %q, compressed %v", id, s.Content, s.Meta.Compressed)
+		}
+	}
+}
+
+func TestFileManager_AddAll(t *testing.T) {
+	srcDir := t.TempDir()
+	for name, content := range map[string]string{
+		"a.c":      "int main() { return 0; }",
+		"b.c":      "int main() {\n    return 0;\n}\n", // a.c modulo whitespace
+		"c.cpp":    "int main() { return 1; }",
+		"junk.c":   "int main() {",
+		"notes.md": "# not a seed",
+	} {
+		if err := os.WriteFile(filepath.Join(srcDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manager := NewFileManager(t.TempDir())
+	manager.SetDedup(seed.HashWhitespace)
+	if err := manager.Initialize(); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+	if err := manager.Add(&seed.Seed{Content: "int main() { return 1; }", Language: seed.LanguageCPP}); err != nil {
+		t.Fatalf("failed to add seed: %v", err)
+	}
+
+	seeds, err := seed.ImportDirectory(srcDir, seed.ImportOptions{NextID: manager.AllocateID})
+	if err != nil {
+		t.Fatalf("ImportDirectory() error = %v", err)
+	}
+	if len(seeds) != 3 {
+		t.Fatalf("imported %d seeds, want 3", len(seeds))
+	}
+	added, err := manager.AddAll(seeds)
+	if err != nil {
+		t.Fatalf("AddAll() error = %v", err)
+	}
+	// b.c duplicates a.c and c.cpp duplicates the seed already in the corpus.
+	if added != 1 {
+		t.Errorf("AddAll() added %d seeds, want 1", added)
+	}
+	if manager.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", manager.Len())
+	}
+	if seeds[0].Meta.Provenance == "" {
+		t.Error("imported seed lost its provenance")
+	}
+}
+
+// TestFileManager_ConcurrentStress is meant for go test -race: 16 workers
+// add, take, report and allocate at once, with saves in between.
+func TestFileManager_ConcurrentStress(t *testing.T) {
+	const workers, perWorker = 16, 12
+	tmpDir := t.TempDir()
+	manager := NewFileManager(tmpDir)
+	if err := manager.Initialize(); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+	manager.SetDedup(seed.HashWhitespace)
+	manager.SetSchedule(ScheduleFast, nil)
+
+	var mu sync.Mutex
+	ids := make(map[uint64]string) // ID -> who got it
+	claim := func(id uint64, by string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if prev, ok := ids[id]; ok {
+			t.Errorf("ID %d handed out twice (%s, %s)", id, prev, by)
+		}
+		ids[id] = by
+	}
+	var added, reported sync.Map
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				s := &seed.Seed{Content: fmt.Sprintf("int main() { return %d; }", w*perWorker+i)}
+				if err := manager.Add(s); err != nil {
+					t.Errorf("Add() failed: %v", err)
+					continue
+				}
+				claim(s.Meta.ID, "Add")
+				added.Store(s.Meta.ID, true)
+				claim(manager.AllocateID(), "AllocateID")
+
+				if next, ok := manager.Next(); ok {
+					if err := manager.ReportResult(next.Meta.ID, FuzzResult{State: seed.SeedStateProcessed, NewCoverage: uint64(i)}); err != nil {
+						t.Errorf("ReportResult() failed: %v", err)
+					}
+					if _, dup := reported.LoadOrStore(next.Meta.ID, true); dup {
+						t.Errorf("seed %d taken twice", next.Meta.ID)
+					}
+				}
+				_ = manager.Len()
+				manager.Seeds(nil)
+				if i%4 == 0 {
+					if err := manager.Save(); err != nil {
+						t.Errorf("Save() failed: %v", err)
+					}
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	for next, ok := manager.Next(); ok; next, ok = manager.Next() {
+		if _, dup := reported.LoadOrStore(next.Meta.ID, true); dup {
+			t.Errorf("seed %d taken twice", next.Meta.ID)
+		}
+		manager.ReportResult(next.Meta.ID, FuzzResult{State: seed.SeedStateProcessed})
+	}
+
+	total := workers * perWorker
+	count := 0
+	added.Range(func(id, _ any) bool {
+		count++
+		if _, ok := reported.Load(id); !ok {
+			t.Errorf("seed %v was added but never taken", id)
+		}
+		return true
+	})
+	if count != total {
+		t.Errorf("added %d seeds, want %d", count, total)
+	}
+	if got := manager.GetStateManager().GetState().Stats.ProcessedCount; got != total {
+		t.Errorf("ProcessedCount = %d, want %d", got, total)
+	}
+
+	// Nothing is lost across a restart either.
+	if err := manager.Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	resumed := NewFileManager(tmpDir)
+	if err := resumed.Recover(); err != nil {
+		t.Fatalf("Recover() failed: %v", err)
+	}
+	n := 0
+	it := resumed.Seeds(nil)
+	for _, ok := it.Next(); ok; _, ok = it.Next() {
+		n++
+	}
+	if n != total {
+		t.Errorf("recovered %d seeds, want %d", n, total)
+	}
+	if next := resumed.AllocateID(); ids[next] != "" {
+		t.Errorf("AllocateID() after Recover reused ID %d", next)
+	}
+}
@@ -0,0 +1,82 @@
+package corpus
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/zjy-dev/de-fuzz/internal/coverage"
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// SeedOrder is how SeedsCovering sorts the seeds it returns.
+type SeedOrder string
+
+const (
+	// OrderRecency lists the newest seeds (highest IDs) first.
+	OrderRecency SeedOrder = "recency"
+	// OrderEnergy lists the seeds with the most energy first (see
+	// rankEnergy), newest first among equals.
+	OrderEnergy SeedOrder = "energy"
+)
+
+// SeedsCovering returns the metadata of the corpus seeds mapping credits
+// with covering line, sorted by order ("" for OrderRecency). Seeds the
+// mapping names that are no longer in the corpus, trimmed or evicted, are
+// left out. The metadata is copied; no seed content is read.
+func (m *FileManager) SeedsCovering(mapping *coverage.CoverageMapping, line coverage.LineID, order SeedOrder) ([]*seed.Metadata, error) {
+	if mapping == nil {
+		return nil, errors.New("no coverage mapping to query")
+	}
+	return m.seedsCovering(mapping, []coverage.LineID{line}, order)
+}
+
+// SeedsCoveringFunction is SeedsCovering for every line of the function
+// name, as analyzer's CFG maps it to basic blocks, against analyzer's
+// mapping. Each seed is listed once, however many of the lines it covers.
+func (m *FileManager) SeedsCoveringFunction(analyzer *coverage.Analyzer, name string, order SeedOrder) ([]*seed.Metadata, error) {
+	if analyzer == nil {
+		return nil, errors.New("no analyzer to look up functions in")
+	}
+	lines := analyzer.GetFunctionLines(name)
+	if lines == nil {
+		return nil, fmt.Errorf("function %q not found in the CFG", name)
+	}
+	return m.seedsCovering(analyzer.GetMapping(), lines, order)
+}
+
+// seedsCovering gathers the corpus seeds covering any of lines and sorts
+// them by order.
+func (m *FileManager) seedsCovering(mapping *coverage.CoverageMapping, lines []coverage.LineID, order SeedOrder) ([]*seed.Metadata, error) {
+	if order != "" && order != OrderRecency && order != OrderEnergy {
+		return nil, fmt.Errorf("unknown seed order %q (want %s or %s)", order, OrderRecency, OrderEnergy)
+	}
+
+	m.mu.RLock()
+	metas := []*seed.Metadata{}
+	energy := make(map[uint64]float64)
+	for _, line := range lines {
+		for _, id := range mapping.GetSeedsForLine(line) {
+			if _, ok := energy[uint64(id)]; ok {
+				continue
+			}
+			s := m.lookup(uint64(id))
+			if s == nil {
+				continue
+			}
+			meta := s.Meta
+			metas = append(metas, &meta)
+			energy[meta.ID] = m.rankEnergy(&meta)
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(metas, func(i, j int) bool {
+		a, b := metas[i], metas[j]
+		if order == OrderEnergy && energy[a.ID] != energy[b.ID] {
+			return energy[a.ID] > energy[b.ID]
+		}
+		return a.ID > b.ID
+	})
+	return metas, nil
+}
@@ -0,0 +1,159 @@
+package corpus
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zjy-dev/de-fuzz/internal/coverage"
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// newQueryFixture returns a corpus and an analyzer whose mapping credits
+// its seeds. Seed 1 is initial; 2-5 are mutants of 1, of which 2 added
+// coverage, 3 none and 4 found a bug; 5 is still queued. The CFG has
+// function first on test.cc lines 10-11 and second on line 20.
+func newQueryFixture(t *testing.T) (*FileManager, *coverage.Analyzer) {
+	t.Helper()
+	dir := t.TempDir()
+	manager := NewFileManager(filepath.Join(dir, "out"))
+	if err := manager.Initialize(); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+	for i := 1; i <= 5; i++ {
+		s := &seed.Seed{Content: fmt.Sprintf("int main() { return %d; }", i)}
+		if i > 1 {
+			s.Meta.ParentID = 1
+		}
+		if err := manager.Add(s); err != nil {
+			t.Fatalf("failed to add seed: %v", err)
+		}
+	}
+	results := map[uint64]FuzzResult{
+		2: {State: seed.SeedStateProcessed, NewCoverage: 500},
+		4: {State: seed.SeedStateProcessed, OracleVerdict: seed.OracleVerdictBug},
+	}
+	for i := 1; i <= 4; i++ {
+		next, _ := manager.Next()
+		result, ok := results[next.Meta.ID]
+		if !ok {
+			result = FuzzResult{State: seed.SeedStateProcessed}
+		}
+		if err := manager.ReportResult(next.Meta.ID, result); err != nil {
+			t.Fatalf("ReportResult() failed: %v", err)
+		}
+	}
+
+	cfgContent := `;; Function first (_Z5firsti, funcdef_no=1, decl_uid=100, cgraph_uid=1, symbol_order=1)
+;; 2 succs { 3 }
+;; 3 succs { 1 }
+int first (int a)
+{
+  <bb 2> :
+  [/path/to/test.cc:10:3] if (a > 0)
+
+  <bb 3> :
+  [/path/to/test.cc:11:3] return a;
+}
+
+;; Function second (_Z6secondi, funcdef_no=2, decl_uid=101, cgraph_uid=2, symbol_order=2)
+;; 2 succs { 1 }
+int second (int b)
+{
+  <bb 2> :
+  [/path/to/test.cc:20:3] return b;
+}
+`
+	cfgPath := filepath.Join(dir, "test.cc.015t.cfg")
+	if err := os.WriteFile(cfgPath, []byte(cfgContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	analyzer, err := coverage.NewAnalyzer([]string{cfgPath}, nil, "", filepath.Join(dir, "mapping.json"), 0.8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for line, ids := range map[int][]int64{
+		10: {1, 2, 5},
+		11: {3, 99}, // Seed 99 is not in the corpus
+		20: {4},
+	} {
+		for _, id := range ids {
+			analyzer.GetMapping().RecordLine(testLine(line), id)
+		}
+	}
+	return manager, analyzer
+}
+
+func testLine(n int) coverage.LineID {
+	return coverage.LineID{File: "/path/to/test.cc", Line: n}
+}
+
+func metadataIDs(metas []*seed.Metadata) string {
+	ids := make([]uint64, len(metas))
+	for i, meta := range metas {
+		ids[i] = meta.ID
+	}
+	return fmt.Sprint(ids)
+}
+
+func TestFileManager_SeedsCovering(t *testing.T) {
+	manager, analyzer := newQueryFixture(t)
+	mapping := analyzer.GetMapping()
+
+	for _, tt := range []struct {
+		line  int
+		order SeedOrder
+		want  string
+	}{
+		{10, "", "[5 2 1]"},
+		{10, OrderRecency, "[5 2 1]"},
+		{10, OrderEnergy, "[2 1 5]"}, // Coverage first; queued 5 has no results
+		{11, OrderRecency, "[3]"},
+		{12, OrderRecency, "[]"},
+	} {
+		metas, err := manager.SeedsCovering(mapping, testLine(tt.line), tt.order)
+		if err != nil || metadataIDs(metas) != tt.want {
+			t.Errorf("SeedsCovering(line %d, %q) = %s, %v; want %s", tt.line, tt.order, metadataIDs(metas), err, tt.want)
+		}
+	}
+
+	metas, _ := manager.SeedsCovering(mapping, testLine(20), OrderRecency)
+	if len(metas) != 1 || metas[0].OracleVerdict != seed.OracleVerdictBug || metas[0].ParentID != 1 {
+		t.Errorf("SeedsCovering(line 20) = %+v, want the metadata of bug seed 4", metas)
+	}
+	metas[0].State = seed.SeedStateArchived
+	if s, _ := manager.Get(4); s.Meta.State != seed.SeedStateProcessed {
+		t.Error("SeedsCovering() returned the corpus' own metadata, not a copy")
+	}
+
+	if _, err := manager.SeedsCovering(nil, testLine(10), OrderRecency); err == nil {
+		t.Error("SeedsCovering() without a mapping succeeded")
+	}
+	if _, err := manager.SeedsCovering(mapping, testLine(10), "oldest"); err == nil {
+		t.Error("SeedsCovering() with an unknown order succeeded")
+	}
+}
+
+func TestFileManager_SeedsCoveringFunction(t *testing.T) {
+	manager, analyzer := newQueryFixture(t)
+
+	for _, tt := range []struct {
+		name  string
+		order SeedOrder
+		want  string
+	}{
+		{"first", OrderRecency, "[5 3 2 1]"},
+		{"first", OrderEnergy, "[2 1 5 3]"}, // 3 and 5 tie; newer first
+		{"second", OrderEnergy, "[4]"},
+	} {
+		metas, err := manager.SeedsCoveringFunction(analyzer, tt.name, tt.order)
+		if err != nil || metadataIDs(metas) != tt.want {
+			t.Errorf("SeedsCoveringFunction(%s, %q) = %s, %v; want %s", tt.name, tt.order, metadataIDs(metas), err, tt.want)
+		}
+	}
+
+	if _, err := manager.SeedsCoveringFunction(analyzer, "missing", OrderRecency); err == nil {
+		t.Error("SeedsCoveringFunction() of an unknown function succeeded")
+	}
+}
@@ -0,0 +1,143 @@
+package corpus
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/zjy-dev/de-fuzz/internal/logger"
+	"github.com/zjy-dev/de-fuzz/internal/state"
+)
+
+// ErrReadOnly is matched (errors.Is) by the *ReadOnlyError a corpus opened
+// with OpenReadOnly returns from the calls that would change it.
+var ErrReadOnly = errors.New("read-only corpus")
+
+// ReadOnlyError reports a call, Op, refused because the corpus in Dir was
+// opened with OpenReadOnly.
+type ReadOnlyError struct {
+	Op  string
+	Dir string
+}
+
+func (e *ReadOnlyError) Error() string {
+	return fmt.Sprintf("%s: corpus %s is open read-only", e.Op, e.Dir)
+}
+
+// Is makes errors.Is(err, ErrReadOnly) match.
+func (e *ReadOnlyError) Is(target error) bool {
+	return target == ErrReadOnly
+}
+
+const (
+	// refreshAttempts bounds how many times Refresh reads the corpus while
+	// the writer keeps changing it.
+	refreshAttempts = 5
+	// refreshBackoff is the wait before the second read; it doubles after.
+	refreshBackoff = 10 * time.Millisecond
+)
+
+// OpenReadOnly opens the corpus in dir for analysis (stats, queries,
+// exports) while a campaign may be writing it. It loads the latest
+// snapshot and replays the journal like Recover, but writes nothing: a torn
+// journal record is not truncated, corrupt seeds are not quarantined and
+// the ID mark is not advanced. Initialize, Add, ReportResult, Save,
+// Finalize, Trim, Migrate and Import fail with a *ReadOnlyError.
+//
+// The view is an eventually consistent snapshot of the corpus. It lags the
+// writer until Refresh rereads it; Get refreshes by itself when the seed it
+// looks for is missing. One refresh may still miss seeds written or
+// renamed while it read, or the journal records of a compaction it raced
+// with, if retrying did not help; the next refresh sees them. Changes
+// made in memory, by Next or SetFavored, last until the next refresh.
+func OpenReadOnly(dir string) (*FileManager, error) {
+	if _, err := os.Stat(filepath.Join(dir, CorpusDir)); err != nil {
+		return nil, fmt.Errorf("failed to open corpus: %w", err)
+	}
+	m := newReadOnly(dir)
+	if err := m.Refresh(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func newReadOnly(dir string) *FileManager {
+	m := NewFileManager(dir)
+	m.readOnly = true
+	m.journal.readOnly = true
+	return m
+}
+
+// readOnlyError is the error for op on a read-only corpus.
+func (m *FileManager) readOnlyError(op string) error {
+	return &ReadOnlyError{Op: op, Dir: m.baseDir}
+}
+
+// Refresh rereads a corpus opened with OpenReadOnly. The files are read
+// into a new view while the current one keeps serving calls, and it
+// replaces the current one once whole. A read that fails, as when a file
+// vanishes under it, or that a compaction by the writer overlaps, is
+// retried with backoff; when the writer keeps compacting, the last whole
+// read is kept.
+func (m *FileManager) Refresh() error {
+	if !m.readOnly {
+		return errors.New("refresh needs a corpus opened with OpenReadOnly; use Recover")
+	}
+	m.mu.RLock()
+	dedup := m.dedup
+	m.mu.RUnlock()
+
+	var fresh *FileManager
+	var err error
+	for attempt := 0; attempt < refreshAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(refreshBackoff << (attempt - 1))
+		}
+		before := m.snapshotVersion()
+		next := newReadOnly(m.baseDir)
+		next.dedup = dedup
+		next.mu.Lock()
+		err = next.recover()
+		next.mu.Unlock()
+		if err != nil {
+			logger.Debug("Rereading corpus %s: %v", m.baseDir, err)
+			continue
+		}
+		fresh = next
+		if m.snapshotVersion() == before {
+			break
+		}
+	}
+	if fresh == nil {
+		return fmt.Errorf("failed to read corpus %s: %w", m.baseDir, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.namer = fresh.namer
+	m.queue = fresh.queue
+	m.processed = fresh.processed
+	m.hashes = fresh.hashes
+	m.taken = make(map[uint64]int)
+	m.diskBytes = -1
+	m.stateManager.SetState(fresh.stateManager.GetState())
+	m.reserveID(fresh.lastID.Load())
+	return nil
+}
+
+// snapshotVersion identifies the snapshot on disk by the size and
+// modification time of the files a compaction rewrites. The journal is
+// left out: appends to it do not invalidate a read.
+func (m *FileManager) snapshotVersion() string {
+	version := ""
+	for _, path := range []string{filepath.Join(m.stateDir, state.StateFileName), m.journal.rotatedPath()} {
+		if info, err := os.Stat(path); err == nil {
+			version += fmt.Sprintf("%d@%d;", info.Size(), info.ModTime().UnixNano())
+		} else {
+			version += "-;"
+		}
+	}
+	return version
+}
@@ -0,0 +1,167 @@
+package corpus
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+func TestOpenReadOnly(t *testing.T) {
+	dir := t.TempDir()
+	writer := NewFileManager(dir)
+	if err := writer.Initialize(); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+	for i := 1; i <= 3; i++ {
+		if err := writer.Add(&seed.Seed{Content: fmt.Sprintf("int main() { return %d; }", i)}); err != nil {
+			t.Fatalf("failed to add seed: %v", err)
+		}
+	}
+	next, _ := writer.Next()
+	if err := writer.ReportResult(next.Meta.ID, FuzzResult{State: seed.SeedStateProcessed, NewCoverage: 100}); err != nil {
+		t.Fatalf("ReportResult() failed: %v", err)
+	}
+
+	// A record the writer is still appending
+	journalPath := filepath.Join(dir, StateDir, JournalFile)
+	f, err := os.OpenFile(journalPath, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	torn := `{"op":"add","id":4`
+	f.WriteString(torn)
+	f.Close()
+	journal, _ := os.ReadFile(journalPath)
+
+	reader, err := OpenReadOnly(dir)
+	if err != nil {
+		t.Fatalf("OpenReadOnly() failed: %v", err)
+	}
+	stats := corpusStats(t, reader)
+	if stats.Total != 3 || stats.ByState[seed.SeedStateProcessed] != 1 {
+		t.Errorf("stats: %d seeds, %d processed; want 3, 1", stats.Total, stats.ByState[seed.SeedStateProcessed])
+	}
+	if after, _ := os.ReadFile(journalPath); string(after) != string(journal) {
+		t.Error("OpenReadOnly() changed the journal")
+	}
+	if err := os.WriteFile(journalPath, journal[:len(journal)-len(torn)], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, addErr := reader.AddAll([]*seed.Seed{{Content: "int main() { return 4; }"}})
+	_, trimErr := reader.Trim(nil, nil)
+	for op, err := range map[string]error{
+		"Add":          addErr,
+		"ReportResult": reader.ReportResult(1, FuzzResult{State: seed.SeedStateProcessed}),
+		"Save":         reader.Save(),
+		"Finalize":     reader.Finalize(),
+		"Trim":         trimErr,
+	} {
+		var readOnly *ReadOnlyError
+		if !errors.Is(err, ErrReadOnly) || !errors.As(err, &readOnly) || readOnly.Op != op {
+			t.Errorf("%s() on a read-only corpus = %v, want a *ReadOnlyError", op, err)
+		}
+	}
+	if got := seedIDs(t, reader); got != "[1 2 3]" {
+		t.Errorf("seeds = %s, want [1 2 3]", got)
+	}
+
+	// Get finds seeds added and renamed since, by refreshing
+	if err := writer.Add(&seed.Seed{Content: "int main() { return 4; }"}); err != nil {
+		t.Fatalf("failed to add seed: %v", err)
+	}
+	if s, err := reader.Get(4); err != nil || s.Content != "int main() { return 4; }" {
+		t.Errorf("Get(4) = %v, %v; want the seed added after opening", s, err)
+	}
+	next, _ = writer.Next()
+	if err := writer.ReportResult(next.Meta.ID, FuzzResult{State: seed.SeedStateProcessed, NewCoverage: 300}); err != nil {
+		t.Fatalf("ReportResult() failed: %v", err)
+	}
+	if s, err := reader.Get(next.Meta.ID); err != nil || s.Meta.CovIncrease != 300 {
+		t.Errorf("Get(%d) after its directory was renamed = %v, %v", next.Meta.ID, s, err)
+	}
+}
+
+func TestOpenReadOnly_ConcurrentWriter(t *testing.T) {
+	dir := t.TempDir()
+	writer := NewFileManager(dir)
+	if err := writer.Initialize(); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+	writer.compactEvery = 8
+	if err := writer.Add(&seed.Seed{Content: "int main() { return 0; }"}); err != nil {
+		t.Fatalf("failed to add seed: %v", err)
+	}
+	reader, err := OpenReadOnly(dir)
+	if err != nil {
+		t.Fatalf("OpenReadOnly() failed: %v", err)
+	}
+
+	const seeds = 60
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	// A Fatalf below must not end the test while the writer still logs
+	defer wg.Wait()
+	go func() {
+		defer wg.Done()
+		defer close(done)
+		for i := 1; i <= seeds; i++ {
+			s := &seed.Seed{Content: fmt.Sprintf("int main() { return %d; }", i)}
+			s.Meta.ParentID = 1
+			if err := writer.Add(s); err != nil {
+				t.Errorf("failed to add seed: %v", err)
+				return
+			}
+			next, _ := writer.Next()
+			if err := writer.ReportResult(next.Meta.ID, FuzzResult{State: seed.SeedStateProcessed, NewCoverage: uint64(i)}); err != nil {
+				t.Errorf("ReportResult() failed: %v", err)
+				return
+			}
+			if err := writer.Save(); err != nil {
+				t.Errorf("Save() failed: %v", err)
+				return
+			}
+		}
+	}()
+
+	// The view may lag, and one refresh may miss a seed renamed while it
+	// read, but it never shows a seed half written
+	for reading := true; reading; {
+		select {
+		case <-done:
+			reading = false
+		default:
+		}
+		if err := reader.Refresh(); err != nil {
+			t.Fatalf("Refresh() failed: %v", err)
+		}
+		it := reader.Seeds(nil)
+		for s, ok := it.Next(); ok; s, ok = it.Next() {
+			if s.Meta.ID == 0 || s.Meta.State == "" {
+				t.Fatalf("seed with incomplete metadata in view: %+v", s.Meta)
+			}
+		}
+	}
+	wg.Wait()
+
+	if err := reader.Refresh(); err != nil {
+		t.Fatalf("Refresh() failed: %v", err)
+	}
+	// Seed 1 and the first seeds-1 mutants were processed; the last is queued
+	stats := corpusStats(t, reader)
+	if stats.Total != seeds+1 || stats.ByState[seed.SeedStateProcessed] != seeds {
+		t.Errorf("final view: %d seeds, %d processed; want %d, %d", stats.Total, stats.ByState[seed.SeedStateProcessed], seeds+1, seeds)
+	}
+	if got, want := reader.GetStateManager().GetState().Stats.ProcessedCount, seeds; got != want {
+		t.Errorf("processed count = %d, want %d", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, CorpusDir, seed.QuarantineDir)); !os.IsNotExist(err) {
+		t.Errorf("read-only reader quarantined seeds: %v", err)
+	}
+}
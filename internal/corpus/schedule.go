@@ -0,0 +1,161 @@
+package corpus
+
+import (
+	"math"
+
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// Schedule selects how Next picks the next queued seed.
+type Schedule string
+
+const (
+	// ScheduleFIFO takes seeds in queue order (the default).
+	ScheduleFIFO Schedule = "fifo"
+	// ScheduleExplore samples by energy but flattens it: coverage counts
+	// sublinearly and depth barely, so most seeds get picked now and then.
+	ScheduleExplore Schedule = "explore"
+	// ScheduleExploit samples by energy, favoring seeds (and children of
+	// seeds) that added coverage or found bugs.
+	ScheduleExploit Schedule = "exploit"
+	// ScheduleFast is ScheduleExploit with AFL's "fast" decay: a seed's
+	// energy halves for every sibling already taken from the same parent.
+	ScheduleFast Schedule = "fast"
+)
+
+// DefaultFavoredWeight is how many times the energy of a favored seed is
+// multiplied, unless SetFavoredWeight says otherwise.
+const DefaultFavoredWeight = 4
+
+// Schedules lists the accepted schedules, the default first.
+var Schedules = []Schedule{ScheduleFIFO, ScheduleExplore, ScheduleExploit, ScheduleFast}
+
+type scheduleParams struct {
+	sqrtCoverage bool    // Coverage counts as its square root
+	depthPenalty float64 // Energy is divided by 1 + depthPenalty*Depth
+	bugBonus     float64 // Energy is multiplied by this for bug seeds
+}
+
+var scheduleTable = map[Schedule]scheduleParams{
+	ScheduleExplore: {sqrtCoverage: true, depthPenalty: 0.05, bugBonus: 2},
+	ScheduleExploit: {depthPenalty: 0.2, bugBonus: 8},
+	ScheduleFast:    {depthPenalty: 0.2, bugBonus: 8},
+}
+
+// Energy scores meta under schedule: it grows with the coverage increase
+// (in percentage points) and a bug verdict, and shrinks with execution
+// time (in seconds) and mutation depth. A seed with no results scores 1 at
+// depth 0. Under ScheduleFIFO every seed scores 1.
+func Energy(meta *seed.Metadata, schedule Schedule) float64 {
+	params, ok := scheduleTable[schedule]
+	if !ok {
+		return 1
+	}
+	coverage := float64(meta.CovIncrease) / 100
+	if params.sqrtCoverage {
+		coverage = math.Sqrt(coverage)
+	}
+	energy := 1 + coverage
+	energy /= 1 + float64(meta.ExecTimeUs)/1e6
+	energy /= 1 + params.depthPenalty*float64(meta.Depth)
+	if meta.OracleVerdict == seed.OracleVerdictBug {
+		energy *= params.bugBonus
+	}
+	return energy
+}
+
+// SetFavoredWeight sets how many times the energy schedules multiply the
+// weight of a favored seed; 1 disables the bias. ScheduleFIFO ignores it.
+func (m *FileManager) SetFavoredWeight(weight float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.favorWeight = weight
+}
+
+// SetFavored marks the seeds in ids favored and every other seed not. The
+// set is kept, so seeds added later are marked too.
+func (m *FileManager) SetFavored(ids []uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.favored = make(map[uint64]bool, len(ids))
+	for _, id := range ids {
+		m.favored[id] = true
+	}
+	for _, s := range m.queue {
+		s.Meta.Favored = m.favored[s.Meta.ID]
+	}
+	for id, s := range m.processed {
+		s.Meta.Favored = m.favored[id]
+	}
+}
+
+// weight is the energy Next samples queued seed s by: its own, or its
+// parent's when the parent scored higher, so the children of productive
+// seeds are picked sooner. Under ScheduleFast it halves for every sibling
+// already taken. A favored seed's is multiplied by the favored weight.
+// Callers hold m.mu.
+func (m *FileManager) weight(s *seed.Seed) float64 {
+	energy := Energy(&s.Meta, m.schedule)
+	if s.Meta.Favored {
+		energy *= m.favoredWeight()
+	}
+	if s.Meta.ParentID == 0 {
+		return energy
+	}
+	if parent, ok := m.processed[s.Meta.ParentID]; ok {
+		energy = max(energy, parent.Meta.Energy)
+	}
+	if m.schedule == ScheduleFast {
+		energy = math.Ldexp(energy, -min(m.taken[s.Meta.ParentID], 32))
+	}
+	return energy
+}
+
+// favoredWeight returns the weight set by SetFavoredWeight, or the default.
+// Callers hold m.mu.
+func (m *FileManager) favoredWeight() float64 {
+	if m.favorWeight == 0 {
+		return DefaultFavoredWeight
+	}
+	return m.favorWeight
+}
+
+// pick returns the index in m.queue of the seed to take next among those
+// filter selects (nil for all), or -1 if there is none. FIFO takes the
+// first; the energy schedules sample in proportion to weight. Callers hold
+// m.mu.
+func (m *FileManager) pick(filter seed.Filter) int {
+	if m.schedule == "" || m.schedule == ScheduleFIFO {
+		for i, s := range m.queue {
+			if filter == nil || filter(s) {
+				return i
+			}
+		}
+		return -1
+	}
+
+	var candidates []int
+	var weights []float64
+	total := 0.0
+	for i, s := range m.queue {
+		if filter != nil && !filter(s) {
+			continue
+		}
+		w := m.weight(s)
+		candidates = append(candidates, i)
+		weights = append(weights, w)
+		total += w
+	}
+	if len(candidates) == 0 {
+		return -1
+	}
+	r := m.rng.Float64() * total
+	for j, w := range weights {
+		if r < w {
+			return candidates[j]
+		}
+		r -= w
+	}
+	return candidates[len(candidates)-1]
+}
@@ -0,0 +1,178 @@
+package corpus
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+func TestEnergy(t *testing.T) {
+	base := seed.Metadata{CovIncrease: 500}
+	for _, schedule := range []Schedule{ScheduleExplore, ScheduleExploit, ScheduleFast} {
+		e := Energy(&base, schedule)
+		for name, meta := range map[string]seed.Metadata{
+			"no coverage": {},
+			"slow":        {CovIncrease: 500, ExecTimeUs: 2_000_000},
+			"deep":        {CovIncrease: 500, Depth: 10},
+		} {
+			if got := Energy(&meta, schedule); got >= e {
+				t.Errorf("%s: Energy(%s) = %v, want below %v", schedule, name, got, e)
+			}
+		}
+		bug := seed.Metadata{CovIncrease: 500, OracleVerdict: seed.OracleVerdictBug}
+		if got := Energy(&bug, schedule); got <= e {
+			t.Errorf("%s: Energy(bug) = %v, want above %v", schedule, got, e)
+		}
+	}
+	if got := Energy(&base, ScheduleFIFO); got != 1 {
+		t.Errorf("Energy under fifo = %v, want 1", got)
+	}
+	if got := Energy(&seed.Metadata{}, ScheduleExploit); got != 1 {
+		t.Errorf("Energy of a seed without results = %v, want 1", got)
+	}
+}
+
+// syntheticQueue fills m's queue with seeds whose exploit energies are 1, 3
+// and 6.
+func syntheticQueue(m *FileManager) {
+	for i, cov := range []uint64{0, 200, 500} {
+		m.queue = append(m.queue, &seed.Seed{Meta: seed.Metadata{ID: uint64(i + 1), CovIncrease: cov}})
+	}
+}
+
+func TestFileManager_PickSamplesByEnergy(t *testing.T) {
+	m := NewFileManager(t.TempDir())
+	m.SetSchedule(ScheduleExploit, rand.New(rand.NewSource(1)))
+	syntheticQueue(m)
+
+	const draws = 30000
+	counts := make([]int, len(m.queue))
+	for i := 0; i < draws; i++ {
+		counts[m.pick(nil)]++
+	}
+	for i, want := range []float64{1.0 / 10, 3.0 / 10, 6.0 / 10} {
+		got := float64(counts[i]) / draws
+		if math.Abs(got-want) > 0.02 {
+			t.Errorf("seed %d picked %.3f of the time, want %.3f", i+1, got, want)
+		}
+	}
+
+	// A filter restricts the sample.
+	for i := 0; i < 100; i++ {
+		if got := m.pick(func(s *seed.Seed) bool { return s.Meta.ID != 3 }); got == 2 {
+			t.Fatalf("pick returned a seed the filter rejects")
+		}
+	}
+}
+
+func TestFileManager_PickFavorsFavoredSeeds(t *testing.T) {
+	m := NewFileManager(t.TempDir())
+	m.SetSchedule(ScheduleExploit, rand.New(rand.NewSource(1)))
+	syntheticQueue(m)
+	m.SetFavored([]uint64{1})
+	if !m.queue[0].Meta.Favored || m.queue[1].Meta.Favored {
+		t.Fatalf("SetFavored() marked %v, %v; want only seed 1", m.queue[0].Meta.Favored, m.queue[1].Meta.Favored)
+	}
+
+	const draws = 30000
+	counts := make([]int, len(m.queue))
+	for i := 0; i < draws; i++ {
+		counts[m.pick(nil)]++
+	}
+	// Seed 1's energy of 1 counts DefaultFavoredWeight times
+	for i, want := range []float64{4.0 / 13, 3.0 / 13, 6.0 / 13} {
+		got := float64(counts[i]) / draws
+		if math.Abs(got-want) > 0.02 {
+			t.Errorf("seed %d picked %.3f of the time, want %.3f", i+1, got, want)
+		}
+	}
+
+	// The set applies to seeds added later
+	m.SetFavored([]uint64{2, 4})
+	s := &seed.Seed{Content: "int main() { return 4; }", Meta: seed.Metadata{ID: 4}}
+	if err := m.Add(s); err != nil {
+		t.Fatalf("failed to add seed: %v", err)
+	}
+	if !s.Meta.Favored || m.queue[0].Meta.Favored {
+		t.Errorf("after SetFavored([2 4]): seed 4 favored %v, seed 1 favored %v", s.Meta.Favored, m.queue[0].Meta.Favored)
+	}
+}
+
+func TestFileManager_PickFIFO(t *testing.T) {
+	m := NewFileManager(t.TempDir())
+	syntheticQueue(m)
+	for i := 0; i < 10; i++ {
+		if got := m.pick(nil); got != 0 {
+			t.Fatalf("fifo pick = %d, want 0", got)
+		}
+	}
+	if got := m.pick(func(s *seed.Seed) bool { return s.Meta.ID == 3 }); got != 2 {
+		t.Errorf("fifo pick with filter = %d, want 2", got)
+	}
+}
+
+func TestFileManager_ScheduleInheritsParentEnergy(t *testing.T) {
+	tmpDir := t.TempDir()
+	m := NewFileManager(tmpDir)
+	if err := m.Initialize(); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+	m.SetSchedule(ScheduleFast, rand.New(rand.NewSource(1)))
+
+	parent := &seed.Seed{Content: "int main() { return 0; }"}
+	if err := m.Add(parent); err != nil {
+		t.Fatalf("failed to add seed: %v", err)
+	}
+	if _, ok := m.Next(); !ok {
+		t.Fatal("Next() returned nothing")
+	}
+	if err := m.ReportResult(parent.Meta.ID, FuzzResult{
+		State: seed.SeedStateProcessed, NewCoverage: 900, OracleVerdict: seed.OracleVerdictBug,
+	}); err != nil {
+		t.Fatalf("ReportResult() failed: %v", err)
+	}
+	want := Energy(&parent.Meta, ScheduleFast)
+	if parent.Meta.Energy != want || want <= 1 {
+		t.Fatalf("parent energy = %v, want %v", parent.Meta.Energy, want)
+	}
+
+	var children []*seed.Seed
+	for i := 0; i < 2; i++ {
+		child := &seed.Seed{Content: fmt.Sprintf("int main() { return %d; }", i+1), Meta: seed.Metadata{ParentID: parent.Meta.ID}}
+		if err := m.Add(child); err != nil {
+			t.Fatalf("failed to add seed: %v", err)
+		}
+		children = append(children, child)
+	}
+	orphan := &seed.Seed{Content: "int main() { return 9; }"}
+	if err := m.Add(orphan); err != nil {
+		t.Fatalf("failed to add seed: %v", err)
+	}
+
+	if got := m.weight(children[0]); got != want {
+		t.Errorf("child weight = %v, want the parent's %v", got, want)
+	}
+	if got := m.weight(orphan); got != 1 {
+		t.Errorf("orphan weight = %v, want 1", got)
+	}
+
+	// Under fast, taking one child halves the weight of its sibling.
+	m.mu.Lock()
+	m.taken[parent.Meta.ID]++
+	if got := m.weight(children[1]); got != want/2 {
+		t.Errorf("sibling weight = %v, want %v", got, want/2)
+	}
+	m.mu.Unlock()
+
+	// The energy survives a restart.
+	resumed := NewFileManager(tmpDir)
+	if err := resumed.Recover(); err != nil {
+		t.Fatalf("Recover() failed: %v", err)
+	}
+	if s, _ := resumed.Get(parent.Meta.ID); s == nil || s.Meta.Energy != want {
+		t.Errorf("recovered parent = %+v, want energy %v", s, want)
+	}
+}
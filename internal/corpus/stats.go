@@ -0,0 +1,185 @@
+package corpus
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// statsTopSeeds is how many seeds CorpusStats.TopCoverage lists.
+const statsTopSeeds = 10
+
+// CorpusStats summarizes a corpus. It marshals to JSON for tools.
+type CorpusStats struct {
+	Total      int                    `json:"total"`
+	ByState    map[seed.SeedState]int `json:"by_state"`
+	ByLanguage map[seed.Language]int  `json:"by_language"`
+	Initial    int                    `json:"initial"` // Seeds without a parent
+	// DepthHistogram counts seeds by mutation depth: DepthHistogram[d] seeds
+	// are d mutations below their root.
+	DepthHistogram []int `json:"depth_histogram"`
+	// AvgCovIncrease is the mean coverage increase in basis points.
+	AvgCovIncrease float64 `json:"avg_cov_incr"`
+	Bugs           int     `json:"bugs"`
+	Favored        int     `json:"favored"` // Seeds marked by SetFavored
+	Evicted        int     `json:"evicted"` // Seeds Add evicted (see SetLimits) this run
+	// Rejections counts the seeds that were run but did not pay off, by
+	// why; see Rejection.
+	Rejections map[RejectionReason]int `json:"rejections"`
+	// DiskBytes is the size of the corpus and metadata directories.
+	DiskBytes   int64         `json:"disk_bytes"`
+	TopCoverage []SeedSummary `json:"top_coverage"` // Most coverage first
+}
+
+// RejectionReason is why a seed that was run did not pay off.
+type RejectionReason string
+
+const (
+	// RejectedCompile seeds did not compile, or failed validation.
+	RejectedCompile RejectionReason = "compile_failed"
+	// RejectedTimeout seeds had a test run time out.
+	RejectedTimeout RejectionReason = "timeout"
+	// RejectedSignal seeds had a test run killed by a signal that the
+	// oracle did not report as a bug.
+	RejectedSignal RejectionReason = "signal"
+	// RejectedUninteresting seeds compiled and ran cleanly but added no
+	// coverage and found no bug.
+	RejectedUninteresting RejectionReason = "uninteresting"
+)
+
+// Rejection returns why the seed meta describes did not pay off, from the
+// outcome ReportResult recorded, or "" for seeds that added coverage or
+// found a bug and for seeds not run yet.
+func Rejection(meta *seed.Metadata) RejectionReason {
+	switch {
+	case meta.State == seed.SeedStatePending, meta.OracleVerdict == seed.OracleVerdictBug:
+		return ""
+	case !meta.CompileOK || meta.State == seed.SeedStateInvalid:
+		return RejectedCompile
+	case meta.State == seed.SeedStateTimeout || slices.Contains(meta.ExecSignals, seed.ExecSignalTimeout):
+		return RejectedTimeout
+	case meta.State == seed.SeedStateCrash || len(meta.ExecSignals) > 0:
+		return RejectedSignal
+	case meta.CovIncrease == 0:
+		return RejectedUninteresting
+	}
+	return ""
+}
+
+// SeedSummary identifies a seed in CorpusStats.
+type SeedSummary struct {
+	ID          uint64 `json:"id"`
+	ParentID    uint64 `json:"parent_id"`
+	Depth       int    `json:"depth"`
+	CovIncrease uint64 `json:"cov_incr"`
+	Bug         bool   `json:"bug,omitempty"`
+}
+
+// Stats summarizes the corpus from the seeds' metadata, without loading
+// their contents. The metadata is copied under a read lock, so Stats can
+// run while workers modify the corpus; DiskBytes is measured afterwards
+// and skips files removed meanwhile.
+func (m *FileManager) Stats() (*CorpusStats, error) {
+	type entry struct {
+		meta     seed.Metadata
+		language seed.Language
+	}
+	m.mu.RLock()
+	entries := make([]entry, 0, len(m.processed)+len(m.queue))
+	for _, s := range m.processed {
+		entries = append(entries, entry{s.Meta, s.Language})
+	}
+	for _, s := range m.queue {
+		entries = append(entries, entry{s.Meta, s.Language})
+	}
+	evicted := m.evicted
+	m.mu.RUnlock()
+
+	stats := &CorpusStats{
+		Total:          len(entries),
+		Evicted:        evicted,
+		ByState:        make(map[seed.SeedState]int),
+		ByLanguage:     make(map[seed.Language]int),
+		Rejections:     make(map[RejectionReason]int),
+		DepthHistogram: []int{},
+		TopCoverage:    []SeedSummary{},
+	}
+	var covSum uint64
+	top := make([]SeedSummary, 0, len(entries))
+	for _, e := range entries {
+		meta := &e.meta
+		stats.ByState[meta.State]++
+		stats.ByLanguage[e.language.OrDefault()]++
+		if meta.ParentID == 0 {
+			stats.Initial++
+		}
+		depth := max(meta.Depth, 0)
+		for len(stats.DepthHistogram) <= depth {
+			stats.DepthHistogram = append(stats.DepthHistogram, 0)
+		}
+		stats.DepthHistogram[depth]++
+		covSum += meta.CovIncrease
+		bug := meta.OracleVerdict == seed.OracleVerdictBug
+		if bug {
+			stats.Bugs++
+		}
+		if meta.Favored {
+			stats.Favored++
+		}
+		if reason := Rejection(meta); reason != "" {
+			stats.Rejections[reason]++
+		}
+		top = append(top, SeedSummary{ID: meta.ID, ParentID: meta.ParentID, Depth: meta.Depth, CovIncrease: meta.CovIncrease, Bug: bug})
+	}
+	if len(entries) > 0 {
+		stats.AvgCovIncrease = float64(covSum) / float64(len(entries))
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].CovIncrease != top[j].CovIncrease {
+			return top[i].CovIncrease > top[j].CovIncrease
+		}
+		return top[i].ID < top[j].ID
+	})
+	stats.TopCoverage = append(stats.TopCoverage, top[:min(len(top), statsTopSeeds)]...)
+
+	for _, dir := range []string{m.corpusDir, m.metadataDir} {
+		size, err := dirSize(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to measure %s: %w", dir, err)
+		}
+		stats.DiskBytes += size
+	}
+	return stats, nil
+}
+
+// dirSize sums the sizes of the regular files under dir. Files that vanish
+// during the walk, and a missing dir, count as empty.
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,162 @@
+package corpus
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+func TestFileManager_Stats(t *testing.T) {
+	manager := NewFileManager(t.TempDir())
+	if err := manager.Initialize(); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+
+	empty, err := manager.Stats()
+	if err != nil {
+		t.Fatalf("Stats() failed: %v", err)
+	}
+	if data, _ := json.Marshal(empty); string(data) != `{"total":0,"by_state":{},"by_language":{},"initial":0,"depth_histogram":[],"avg_cov_incr":0,"bugs":0,"favored":0,"evicted":0,"rejections":{},"disk_bytes":0,"top_coverage":[]}` {
+		t.Errorf("empty stats JSON = %s", data)
+	}
+
+	depths := []int{0, 0, 1, 2, 2}
+	for i, parent := range []uint64{0, 0, 1, 3, 3} {
+		s := &seed.Seed{Content: fmt.Sprintf("int main() { return %d; }", i), Meta: seed.Metadata{ParentID: parent, Depth: depths[i]}}
+		if i == 4 {
+			s.Language = seed.LanguageCPP
+		}
+		if err := manager.Add(s); err != nil {
+			t.Fatalf("failed to add seed: %v", err)
+		}
+	}
+	for i := 0; i < 4; i++ {
+		s, _ := manager.Next()
+		result := FuzzResult{State: seed.SeedStateProcessed, NewCoverage: uint64(i * 100)}
+		if s.Meta.ID == 4 {
+			result.OracleVerdict = seed.OracleVerdictBug
+		}
+		if err := manager.ReportResult(s.Meta.ID, result); err != nil {
+			t.Fatalf("ReportResult() failed: %v", err)
+		}
+	}
+
+	manager.SetFavored([]uint64{1, 4})
+
+	stats, err := manager.Stats()
+	if err != nil {
+		t.Fatalf("Stats() failed: %v", err)
+	}
+	if stats.Total != 5 || stats.Initial != 2 || stats.Bugs != 1 || stats.Favored != 2 {
+		t.Errorf("Total, Initial, Bugs, Favored = %d, %d, %d, %d; want 5, 2, 1, 2", stats.Total, stats.Initial, stats.Bugs, stats.Favored)
+	}
+	if stats.ByState[seed.SeedStateProcessed] != 4 || stats.ByState[seed.SeedStatePending] != 1 {
+		t.Errorf("ByState = %v", stats.ByState)
+	}
+	if stats.ByLanguage[seed.LanguageC] != 4 || stats.ByLanguage[seed.LanguageCPP] != 1 {
+		t.Errorf("ByLanguage = %v", stats.ByLanguage)
+	}
+	if fmt.Sprint(stats.DepthHistogram) != "[2 1 2]" {
+		t.Errorf("DepthHistogram = %v, want [2 1 2]", stats.DepthHistogram)
+	}
+	if stats.AvgCovIncrease != 120 {
+		t.Errorf("AvgCovIncrease = %v, want 120", stats.AvgCovIncrease)
+	}
+	if len(stats.TopCoverage) != 5 || stats.TopCoverage[0].ID != 4 || !stats.TopCoverage[0].Bug || stats.TopCoverage[0].CovIncrease != 300 {
+		t.Errorf("TopCoverage = %+v", stats.TopCoverage)
+	}
+	if stats.DiskBytes == 0 {
+		t.Error("DiskBytes = 0")
+	}
+
+	var decoded CorpusStats
+	data, err := json.Marshal(stats)
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil || fmt.Sprint(decoded) != fmt.Sprint(*stats) {
+		t.Errorf("JSON round trip = %+v, %v", decoded, err)
+	}
+}
+
+func TestFileManager_StatsWhileModified(t *testing.T) {
+	manager := NewFileManager(t.TempDir())
+	if err := manager.Initialize(); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 10; i++ {
+				manager.Add(&seed.Seed{Content: fmt.Sprintf("int main() { return %d; }", w*10+i)})
+				if s, ok := manager.Next(); ok {
+					manager.ReportResult(s.Meta.ID, FuzzResult{State: seed.SeedStateProcessed})
+				}
+				if _, err := manager.Stats(); err != nil {
+					t.Errorf("Stats() failed: %v", err)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	if stats, _ := manager.Stats(); stats.Total != 40 {
+		t.Errorf("Total = %d, want 40", stats.Total)
+	}
+}
+
+func TestFileManager_StatsRejections(t *testing.T) {
+	dir := t.TempDir()
+	manager := NewFileManager(dir)
+	if err := manager.Initialize(); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+	results := []FuzzResult{
+		{State: seed.SeedStateProcessed, CompileStderrSnippet: "error: expected ';'", DurationMs: 40},
+		{State: seed.SeedStateProcessed, CompileOK: true, ExecSignals: []string{seed.ExecSignalTimeout}},
+		{State: seed.SeedStateProcessed, CompileOK: true, ExecSignals: []string{"SIGSEGV"}},
+		{State: seed.SeedStateProcessed, CompileOK: true, ExecSignals: []string{"SIGSEGV"}, OracleVerdict: seed.OracleVerdictBug},
+		{State: seed.SeedStateProcessed, CompileOK: true},
+		{State: seed.SeedStateProcessed, CompileOK: true, NewCoverage: 100},
+	}
+	for i := range results {
+		if err := manager.Add(&seed.Seed{Content: fmt.Sprintf("int main() { return %d; }", i)}); err != nil {
+			t.Fatalf("failed to add seed: %v", err)
+		}
+	}
+	if err := manager.Add(&seed.Seed{Content: "int main() { return 9; }"}); err != nil {
+		t.Fatalf("failed to add seed: %v", err)
+	}
+	for _, result := range results {
+		s, _ := manager.Next()
+		if err := manager.ReportResult(s.Meta.ID, result); err != nil {
+			t.Fatalf("ReportResult() failed: %v", err)
+		}
+	}
+
+	want := map[RejectionReason]int{RejectedCompile: 1, RejectedTimeout: 1, RejectedSignal: 1, RejectedUninteresting: 1}
+	if stats := corpusStats(t, manager); fmt.Sprint(stats.Rejections) != fmt.Sprint(want) {
+		t.Errorf("Rejections = %v, want %v", stats.Rejections, want)
+	}
+
+	// The outcome survives a restart, from the journal or the metadata
+	bare := copyCorpus(t, dir)
+	if err := os.Remove(filepath.Join(bare, StateDir, JournalFile)); err != nil {
+		t.Fatalf("failed to remove the journal: %v", err)
+	}
+	for _, resumed := range []*FileManager{recoverCorpus(t, dir), recoverCorpus(t, bare)} {
+		if stats := corpusStats(t, resumed); fmt.Sprint(stats.Rejections) != fmt.Sprint(want) {
+			t.Errorf("Rejections after Recover = %v, want %v", stats.Rejections, want)
+		}
+		s, err := resumed.Get(1)
+		if err != nil || s.Meta.CompileOK || s.Meta.CompileStderr != "error: expected ';'" || s.Meta.DurationMs != 40 {
+			t.Errorf("seed 1 after Recover = %+v (%v)", s.Meta, err)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package corpus
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/zjy-dev/de-fuzz/internal/coverage"
+	"github.com/zjy-dev/de-fuzz/internal/logger"
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// ArchiveDir is the subdirectory Trim moves trimmed seeds to, next to
+// CorpusDir so Recover does not load them again.
+const ArchiveDir = "archive"
+
+// Trim archives the seeds whose coverage other seeds subsume: a seed is
+// trimmed when every line it covers in mapping (see GetSeedsForLine) is
+// still covered by another seed left in the corpus. Older seeds go first,
+// so newer seeds win ties. Bug seeds, initial seeds, seeds keep selects
+// (nil keeps none; their content may be unloaded) and queued seeds the
+// mapping does not know yet are always kept.
+//
+// Trimmed seeds move to ArchiveDir with their metadata marked
+// seed.SeedStateArchived, and their IDs are removed from mapping. Trim
+// returns how many seeds it archived.
+func (m *FileManager) Trim(mapping *coverage.CoverageMapping, keep func(*seed.Seed) bool) (int, error) {
+	if m.readOnly {
+		return 0, m.readOnlyError("Trim")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	seeds := make(map[uint64]*seed.Seed, len(m.processed)+len(m.queue))
+	for id, s := range m.processed {
+		seeds[id] = s
+	}
+	for _, s := range m.queue {
+		seeds[s.Meta.ID] = s
+	}
+
+	// How many corpus seeds cover each line, and which lines each covers
+	coverers := make(map[coverage.LineID]int)
+	seedLines := make(map[uint64][]coverage.LineID)
+	for line := range mapping.GetCoveredLines() {
+		for _, id := range mapping.GetSeedsForLine(line) {
+			if _, ok := seeds[uint64(id)]; !ok {
+				continue
+			}
+			coverers[line]++
+			seedLines[uint64(id)] = append(seedLines[uint64(id)], line)
+		}
+	}
+
+	ids := make([]uint64, 0, len(seeds))
+	for id := range seeds {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var trimmed []*seed.Seed
+	for _, id := range ids {
+		s := seeds[id]
+		lines, known := seedLines[id]
+		switch {
+		case s.Meta.OracleVerdict == seed.OracleVerdictBug, s.Meta.ParentID == 0:
+			continue
+		case s.Meta.State == seed.SeedStatePending && !known:
+			continue
+		case keep != nil && keep(s):
+			continue
+		}
+		sole := false
+		for _, line := range lines {
+			if coverers[line] == 1 {
+				sole = true
+				break
+			}
+		}
+		if sole {
+			continue
+		}
+		for _, line := range lines {
+			coverers[line]--
+		}
+		trimmed = append(trimmed, s)
+	}
+
+	removed := make([]int64, 0, len(trimmed))
+	var err error
+	for _, s := range trimmed {
+		if err = m.archive(s); err != nil {
+			break
+		}
+		removed = append(removed, int64(s.Meta.ID))
+	}
+	mapping.RemoveSeeds(removed)
+	m.stateManager.UpdatePoolSize(len(m.queue))
+	if len(removed) > 0 {
+		logger.Info("Trimmed %d coverage-subsumed seeds into %s", len(removed), filepath.Join(m.baseDir, ArchiveDir))
+	}
+	return len(removed), err
+}
+
+// archive moves s from the corpus to ArchiveDir and forgets it. Its hash
+// stays indexed, so the program is not added again. Callers hold m.mu.
+func (m *FileManager) archive(s *seed.Seed) error {
+	if s.Meta.FilePath != "" {
+		from := filepath.Join(m.corpusDir, s.Meta.FilePath)
+		to := filepath.Join(m.baseDir, ArchiveDir, s.Meta.FilePath)
+		if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+			return fmt.Errorf("failed to create archive directory: %w", err)
+		}
+		if err := os.Rename(from, to); err != nil {
+			return fmt.Errorf("failed to archive seed %d: %w", s.Meta.ID, err)
+		}
+		if s.Meta.ContentPath != "" {
+			s.Meta.ContentPath = filepath.Join(to, filepath.Base(s.Meta.ContentPath))
+		}
+	}
+
+	s.Meta.State = seed.SeedStateArchived
+	if err := seed.SaveMetadataJSON(m.metadataDir, &s.Meta); err != nil {
+		logger.Warn("Failed to save metadata for seed %d: %v", s.Meta.ID, err)
+	}
+	if err := m.journal.append(journalRecord{Op: journalArchive, ID: s.Meta.ID}); err != nil {
+		logger.Warn("Failed to journal archived seed %d: %v", s.Meta.ID, err)
+	}
+	m.forget(s)
+	return nil
+}
+
+// forget drops s from the processed seeds and the queue. Callers hold m.mu.
+func (m *FileManager) forget(s *seed.Seed) {
+	delete(m.processed, s.Meta.ID)
+	for i, queued := range m.queue {
+		if queued == s {
+			m.queue = append(m.queue[:i:i], m.queue[i+1:]...)
+			break
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package corpus
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zjy-dev/de-fuzz/internal/coverage"
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+func TestFileManager_Trim(t *testing.T) {
+	tmpDir := t.TempDir()
+	manager := NewFileManager(tmpDir)
+	if err := manager.Initialize(); err != nil {
+		t.Fatalf("failed to initialize: %v", err)
+	}
+
+	// Seeds 1 and 2 are initial; 3-7 mutants of 1.
+	var seeds []*seed.Seed
+	for i := 1; i <= 7; i++ {
+		s := &seed.Seed{Content: fmt.Sprintf("int main() { return %d; }", i)}
+		if i > 2 {
+			s.Meta.ParentID = 1
+		}
+		if err := manager.Add(s); err != nil {
+			t.Fatalf("failed to add seed: %v", err)
+		}
+		seeds = append(seeds, s)
+	}
+	for range seeds[:6] {
+		next, _ := manager.Next()
+		result := FuzzResult{State: seed.SeedStateProcessed}
+		if next.Meta.ID == 5 {
+			result.OracleVerdict = seed.OracleVerdictBug
+		}
+		if err := manager.ReportResult(next.Meta.ID, result); err != nil {
+			t.Fatalf("ReportResult() failed: %v", err)
+		}
+	}
+
+	line := func(n int) coverage.LineID { return coverage.LineID{File: "cc.c", Line: n} }
+	mapping, _ := coverage.NewCoverageMapping("")
+	for id, lines := range map[int64][]int{
+		1:  {1, 2},    // initial: kept
+		3:  {2, 3},    // line 3 is also covered by 4, and 4 is newer: trimmed
+		4:  {3, 4, 5}, // sole coverer of 4: kept
+		5:  {5},       // bug seed: kept
+		6:  {4, 5},    // subsumed by 4 and 5: trimmed
+		99: {1},       // not in the corpus: does not count as a coverer
+	} {
+		for _, n := range lines {
+			mapping.RecordLine(line(n), id)
+		}
+	}
+	// Seed 2 covers nothing in the mapping but is initial; seed 7 is still
+	// queued and unknown to the mapping.
+
+	// keep protects seeds from trimming.
+	if removed, _ := manager.Trim(mapping, func(*seed.Seed) bool { return true }); removed != 0 {
+		t.Fatalf("Trim() with keep-all removed %d seeds", removed)
+	}
+
+	removed, err := manager.Trim(mapping, nil)
+	if err != nil {
+		t.Fatalf("Trim() failed: %v", err)
+	}
+	if removed != 2 {
+		t.Fatalf("Trim() removed %d seeds, want 2", removed)
+	}
+
+	var left []uint64
+	it := manager.Seeds(nil)
+	for s, ok := it.Next(); ok; s, ok = it.Next() {
+		left = append(left, s.Meta.ID)
+	}
+	if fmt.Sprint(left) != "[1 2 4 5 7]" {
+		t.Errorf("seeds left = %v, want [1 2 4 5 7]", left)
+	}
+	for n := 1; n <= 5; n++ {
+		if !mapping.IsCovered(line(n)) {
+			t.Errorf("line %d lost its coverage", n)
+		}
+	}
+	if got := mapping.GetSeedsForLine(line(2)); fmt.Sprint(got) != "[1]" {
+		t.Errorf("line 2 seeds = %v, want [1]", got)
+	}
+
+	// Trimmed seeds are archived, not deleted, and stay archived.
+	trimmed := seeds[2]
+	if _, err := os.Stat(filepath.Join(tmpDir, ArchiveDir, trimmed.Meta.FilePath, "source.c")); err != nil {
+		t.Errorf("seed 3 not in the archive: %v", err)
+	}
+	meta, err := seed.LoadMetadataJSON(filepath.Join(tmpDir, MetadataDir, "id-000003.json"))
+	if err != nil || meta.State != seed.SeedStateArchived {
+		t.Errorf("seed 3 metadata = %+v, %v; want state ARCHIVED", meta, err)
+	}
+	resumed := NewFileManager(tmpDir)
+	if err := resumed.Recover(); err != nil {
+		t.Fatalf("Recover() failed: %v", err)
+	}
+	if s, _ := resumed.Get(3); s != nil {
+		t.Errorf("Recover() brought back archived seed 3")
+	}
+
+}
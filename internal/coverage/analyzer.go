@@ -57,8 +57,8 @@ type CFGFunction struct {
 
 // BBWeightInfo tracks attempts and weight for a basic block.
 type BBWeightInfo struct {
-	Attempts int     // Number of fuzz attempts
-	Weight   float64 // Current weight (starts as successor count, decays after failures)
+	Attempts int     `json:"attempts"` // Number of fuzz attempts
+	Weight   float64 `json:"weight"`   // Current weight (starts as successor count, decays after failures)
 }
 
 // Analyzer parses and analyzes GCC CFG dump files for fuzzing guidance.
@@ -74,6 +74,39 @@ type Analyzer struct {
 	targetFunctions   []string         // Functions to focus on
 	sourceDir         string           // Directory containing source files
 	weightDecayFactor float64          // Decay factor for BB weights after failed iterations
+
+	// minTargetSuccessors excludes BBs with fewer successors from target selection
+	// while higher-successor candidates remain. 0 disables the filter.
+	minTargetSuccessors int
+
+	rng *rand.Rand // Breaks ties between targets; nil = the global source
+}
+
+// SetRand makes target selection break ties between equally weighted BBs
+// with rng instead of the global source, so a campaign can checkpoint and
+// restore it. Target selection is not safe for concurrent use either way.
+func (c *Analyzer) SetRand(rng *rand.Rand) {
+	c.rng = rng
+}
+
+// intn returns a random int in [0, n) from the analyzer's rng.
+func (c *Analyzer) intn(n int) int {
+	if c.rng == nil || n <= 1 {
+		return randIntn(n)
+	}
+	return c.rng.Intn(n)
+}
+
+// SetMinTargetSuccessors sets the minimum successor count a BB needs to be
+// preferred as a target. Straight-line BBs (one successor) are usually covered
+// for free once their predecessor branch is taken, so filtering them saves LLM
+// calls. If every remaining candidate is below the threshold, they are still
+// selected. Negative values are treated as 0.
+func (c *Analyzer) SetMinTargetSuccessors(n int) {
+	if n < 0 {
+		n = 0
+	}
+	c.minTargetSuccessors = n
 }
 
 func (c *Analyzer) normalizeFilePath(filePath string) string {
@@ -354,6 +387,64 @@ func (c *Analyzer) GetBasicBlocksForLine(file string, line int) []int {
 	return c.lineToBB[lid]
 }
 
+// GetFunctionLineBBs returns the source line -> BB IDs map for one function
+// in file, with IDs sorted. It is the per-function slice of lineToBB: BB IDs
+// are only unique within a function, so callers annotating a function's code
+// should not mix in blocks from its neighbours.
+func (c *Analyzer) GetFunctionLineBBs(funcName, file string) map[int][]int {
+	fn, ok := c.functions[funcName]
+	if !ok {
+		return nil
+	}
+
+	file = c.normalizeFilePath(file)
+	lineBBs := make(map[int][]int)
+	for bbID, bb := range fn.Blocks {
+		if bb.File != file {
+			continue
+		}
+		for _, lineNum := range bb.Lines {
+			lineBBs[lineNum] = append(lineBBs[lineNum], bbID)
+		}
+	}
+	for _, ids := range lineBBs {
+		sort.Ints(ids)
+	}
+	return lineBBs
+}
+
+// GetFunctionLines returns the source lines of a function's basic blocks,
+// as the line IDs lineToBB and the coverage mapping key them by, sorted by
+// file and line. It returns nil for an unknown function.
+func (c *Analyzer) GetFunctionLines(funcName string) []LineID {
+	fn, ok := c.functions[funcName]
+	if !ok {
+		return nil
+	}
+
+	seen := make(map[LineID]bool)
+	lines := []LineID{}
+	for bbID, bb := range fn.Blocks {
+		if bbID <= 1 {
+			continue
+		}
+		for _, lineNum := range bb.Lines {
+			lid := c.makeLineID(bb.File, lineNum)
+			if _, indexed := c.lineToBB[lid]; indexed && !seen[lid] {
+				seen[lid] = true
+				lines = append(lines, lid)
+			}
+		}
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		if lines[i].File != lines[j].File {
+			return lines[i].File < lines[j].File
+		}
+		return lines[i].Line < lines[j].Line
+	})
+	return lines
+}
+
 // GetSuccessorCount returns the number of successors for a basic block.
 func (c *Analyzer) GetSuccessorCount(funcName string, bbID int) int {
 	key := fmt.Sprintf("%s:%d", funcName, bbID)
@@ -385,17 +476,73 @@ type BBCandidate struct {
 
 // SelectTarget selects the best uncovered basic block to target.
 func (c *Analyzer) SelectTarget() *TargetInfo {
+	return c.SelectTargetExcluding(nil)
+}
+
+// SelectTargetExcluding is SelectTarget passing over the BBs exclude reports,
+// e.g. targets cooling down after failing. When it excludes every candidate,
+// the best of them is selected anyway. A nil exclude excludes nothing.
+func (c *Analyzer) SelectTargetExcluding(exclude func(funcName string, bbID int) bool) *TargetInfo {
+	return c.selectTarget(c.targetFunctions, exclude)
+}
+
+// SelectTargetIn is SelectTargetExcluding among the BBs of funcName alone.
+// It returns nil when funcName has no uncovered BB left to target.
+func (c *Analyzer) SelectTargetIn(funcName string, exclude func(funcName string, bbID int) bool) *TargetInfo {
+	return c.selectTarget([]string{funcName}, exclude)
+}
+
+// TargetFunctions returns the functions targets are selected in.
+func (c *Analyzer) TargetFunctions() []string {
+	return c.targetFunctions
+}
+
+func (c *Analyzer) selectTarget(targetFunctions []string, exclude func(string, int) bool) *TargetInfo {
 	coveredLines := c.mapping.GetCoveredLines()
 
-	candidate := c.selectTargetBB(c.targetFunctions, coveredLines)
+	candidate := c.selectTargetBB(targetFunctions, coveredLines, exclude)
 	if candidate == nil {
-		logger.Debug("[Analyzer] No uncovered BBs found - all covered!")
+		logger.Debug("[Analyzer] No uncovered BBs found in %v - all covered!", targetFunctions)
 		return nil
 	}
 
 	logger.Debug("[Analyzer] Selected candidate: %s:BB%d (weight=%.2f, succs=%d, preds=%v)",
 		candidate.Function, candidate.BBID, candidate.Weight, candidate.SuccessorCount, candidate.Predecessors)
+	return c.targetInfo(candidate, coveredLines)
+}
 
+// TargetFor returns the target info SelectTarget would for BB bbID of
+// funcName, e.g. to retarget a BB an interrupted run was working on, or
+// nil if there is no such BB or all its lines are covered.
+func (c *Analyzer) TargetFor(funcName string, bbID int) *TargetInfo {
+	fn, ok := c.functions[funcName]
+	if !ok {
+		return nil
+	}
+	bb, ok := fn.Blocks[bbID]
+	if !ok || len(bb.Lines) == 0 {
+		return nil
+	}
+	coveredLines := c.mapping.GetCoveredLines()
+	for _, lineNum := range bb.Lines {
+		if !coveredLines[c.makeLineID(bb.File, lineNum)] {
+			return c.targetInfo(&BBCandidate{
+				Function:       funcName,
+				BBID:           bbID,
+				SuccessorCount: len(bb.Successors),
+				Lines:          bb.Lines,
+				File:           bb.File,
+				Weight:         c.GetBBWeight(funcName, bbID),
+				Predecessors:   bb.Predecessors,
+			}, coveredLines)
+		}
+	}
+	return nil
+}
+
+// targetInfo describes candidate as a target, with a base seed covering a
+// predecessor (or, for a function entry, another line of the function).
+func (c *Analyzer) targetInfo(candidate *BBCandidate, coveredLines map[LineID]bool) *TargetInfo {
 	info := &TargetInfo{
 		Function:       candidate.Function,
 		BBID:           candidate.BBID,
@@ -439,7 +586,7 @@ func (c *Analyzer) SelectTarget() *TargetInfo {
 	return info
 }
 
-func (c *Analyzer) selectTargetBB(targetFunctions []string, coveredLines map[LineID]bool) *BBCandidate {
+func (c *Analyzer) selectTargetBB(targetFunctions []string, coveredLines map[LineID]bool, exclude func(string, int) bool) *BBCandidate {
 	var candidates []BBCandidate
 
 	for _, funcName := range targetFunctions {
@@ -509,6 +656,9 @@ func (c *Analyzer) selectTargetBB(targetFunctions []string, coveredLines map[Lin
 		return nil
 	}
 
+	candidates = filterExcluded(candidates, exclude)
+	candidates = c.filterBySuccessorCount(candidates)
+
 	// Sort by weight descending
 	sort.Slice(candidates, func(i, j int) bool {
 		return candidates[i].Weight > candidates[j].Weight
@@ -526,10 +676,57 @@ func (c *Analyzer) selectTargetBB(targetFunctions []string, coveredLines map[Lin
 	}
 
 	// Randomly select from top candidates
-	idx := randIntn(len(topCandidates))
+	idx := c.intn(len(topCandidates))
 	return &topCandidates[idx]
 }
 
+// filterExcluded drops the candidates exclude reports, unless that would
+// drop all of them.
+func filterExcluded(candidates []BBCandidate, exclude func(string, int) bool) []BBCandidate {
+	if exclude == nil {
+		return candidates
+	}
+	var kept []BBCandidate
+	for _, cand := range candidates {
+		if !exclude(cand.Function, cand.BBID) {
+			kept = append(kept, cand)
+		}
+	}
+	if len(kept) == 0 {
+		logger.Debug("[Analyzer] All %d candidates are excluded, selecting among them anyway", len(candidates))
+		return candidates
+	}
+	return kept
+}
+
+// filterBySuccessorCount drops candidates below minTargetSuccessors.
+// When all candidates fall below the threshold, the unfiltered list is returned
+// so that the remaining uncovered BBs are still targeted.
+func (c *Analyzer) filterBySuccessorCount(candidates []BBCandidate) []BBCandidate {
+	if c.minTargetSuccessors <= 0 {
+		return candidates
+	}
+
+	var kept []BBCandidate
+	for _, cand := range candidates {
+		if cand.SuccessorCount >= c.minTargetSuccessors {
+			kept = append(kept, cand)
+		}
+	}
+
+	filtered := len(candidates) - len(kept)
+	if len(kept) == 0 {
+		logger.Info("[Analyzer] All %d candidates have fewer than %d successors, selecting among them anyway",
+			len(candidates), c.minTargetSuccessors)
+		return candidates
+	}
+	if filtered > 0 {
+		logger.Info("[Analyzer] Filtered %d/%d candidates with fewer than %d successors",
+			filtered, len(candidates), c.minTargetSuccessors)
+	}
+	return kept
+}
+
 func (c *Analyzer) findCoveredPredecessorSeed(candidate *BBCandidate, coveredLines map[LineID]bool) (int64, LineID, bool) {
 	coveredPreds := c.GetCoveredPredecessors(candidate.Function, candidate.BBID, coveredLines)
 	if len(coveredPreds) == 0 {
@@ -614,6 +811,23 @@ func (c *Analyzer) CheckNewCoverage(coveredLines []string) bool {
 	return false
 }
 
+// CoversTarget reports whether coveredLines, "file:line" strings, include a
+// line of target. Both sides are normalized like the lines of the coverage
+// mapping, so a relative and an absolute path to the same file under the
+// source directory match.
+func (c *Analyzer) CoversTarget(target *TargetInfo, coveredLines []string) bool {
+	targetLines := make(map[LineID]bool, len(target.Lines))
+	for _, line := range target.Lines {
+		targetLines[c.makeLineID(target.File, line)] = true
+	}
+	for _, lid := range c.parseLinesToIDs(coveredLines) {
+		if targetLines[lid] {
+			return true
+		}
+	}
+	return false
+}
+
 // parseLinesToIDs converts "file:line" strings to LineID structs.
 func (c *Analyzer) parseLinesToIDs(coveredLines []string) []LineID {
 	lineIDs := make([]LineID, 0, len(coveredLines))
@@ -833,6 +1047,26 @@ func (c *Analyzer) GetBBWeight(funcName string, bbID int) float64 {
 	return float64(c.bbToSuccCount[key])
 }
 
+// BBWeights returns a copy of the weights of the BBs targeted so far, by
+// "FuncName:BBID". BBs never targeted keep their successor count.
+func (c *Analyzer) BBWeights() map[string]BBWeightInfo {
+	weights := make(map[string]BBWeightInfo, len(c.bbWeights))
+	for key, wi := range c.bbWeights {
+		weights[key] = *wi
+	}
+	return weights
+}
+
+// RestoreBBWeights replaces the BB weights with ones BBWeights returned,
+// e.g. from an earlier run of the same campaign.
+func (c *Analyzer) RestoreBBWeights(weights map[string]BBWeightInfo) {
+	c.bbWeights = make(map[string]*BBWeightInfo, len(weights))
+	for key, wi := range weights {
+		wi := wi
+		c.bbWeights[key] = &wi
+	}
+}
+
 func (c *Analyzer) GetBBAttempts(funcName string, bbID int) int {
 	key := fmt.Sprintf("%s:%d", funcName, bbID)
 	if wi, ok := c.bbWeights[key]; ok {
@@ -929,6 +1163,12 @@ type CoverageMapping struct {
 	mu          sync.RWMutex
 	LineToSeeds map[string][]int64 `json:"line_to_seeds"`
 	path        string
+
+	// The favored seeds (see favored.go), rebuilt on Load
+	owner         map[string]int64 // Line key -> favored seed owning it
+	owned         map[int64]int    // Favored seed -> lines it owns
+	favoredGen    uint64           // Bumped when the favored set changes
+	favoredWeight float64          // 0 = DefaultFavoredWeight
 }
 
 // NewCoverageMapping creates a new CoverageMapping instance.
@@ -966,6 +1206,7 @@ func (cm *CoverageMapping) RecordLine(line LineID, seedID int64) bool {
 	}
 
 	cm.LineToSeeds[key] = append(seeds, seedID)
+	cm.cover([]string{key})
 	return true
 }
 
@@ -976,6 +1217,7 @@ func (cm *CoverageMapping) RecordLines(lines []LineID, seedID int64) int {
 	defer cm.mu.Unlock()
 
 	newCount := 0
+	var added []string
 	for _, line := range lines {
 		key := line.String()
 		seeds := cm.LineToSeeds[key]
@@ -991,16 +1233,19 @@ func (cm *CoverageMapping) RecordLines(lines []LineID, seedID int64) int {
 
 		if !found {
 			cm.LineToSeeds[key] = append(seeds, seedID)
+			added = append(added, key)
 			if len(seeds) == 0 {
 				// This is a newly covered line
 				newCount++
 			}
 		}
 	}
+	cm.cover(added)
 	return newCount
 }
 
-// GetSeedForLine returns a randomly selected seed from the seeds that covered this line.
+// GetSeedForLine returns a randomly selected seed from the seeds that covered this line,
+// favoring the favored seeds (see SetFavoredWeight).
 func (cm *CoverageMapping) GetSeedForLine(line LineID) (int64, bool) {
 	cm.mu.RLock()
 	defer cm.mu.RUnlock()
@@ -1010,9 +1255,21 @@ func (cm *CoverageMapping) GetSeedForLine(line LineID) (int64, bool) {
 		return 0, false
 	}
 
-	// Random selection from available seeds
-	idx := randIntn(len(seeds))
-	return seeds[idx], true
+	return cm.pickSeed(seeds), true
+}
+
+// SeedIDs returns the set of seeds any line is credited to.
+func (cm *CoverageMapping) SeedIDs() map[int64]bool {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	ids := make(map[int64]bool)
+	for _, seeds := range cm.LineToSeeds {
+		for _, id := range seeds {
+			ids[id] = true
+		}
+	}
+	return ids
 }
 
 // GetSeedsForLine returns all seeds that covered this line.
@@ -1031,6 +1288,54 @@ func (cm *CoverageMapping) GetSeedsForLine(line LineID) []int64 {
 	return result
 }
 
+// RemoveSeeds removes ids from every line's seed list. Lines left without
+// seeds are dropped. It returns how many line entries were removed.
+func (cm *CoverageMapping) RemoveSeeds(ids []int64) int {
+	if len(ids) == 0 {
+		return 0
+	}
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	drop := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		drop[id] = true
+	}
+	removed := 0
+	for key, seeds := range cm.LineToSeeds {
+		kept := seeds[:0]
+		for _, s := range seeds {
+			if drop[s] {
+				removed++
+			} else {
+				kept = append(kept, s)
+			}
+		}
+		if len(kept) == 0 {
+			delete(cm.LineToSeeds, key)
+		} else {
+			cm.LineToSeeds[key] = kept
+		}
+	}
+	cm.cover(cm.uncover(drop))
+	return removed
+}
+
+// MaxSeedID returns the highest seed ID any line references, or 0 when
+// the mapping is empty.
+func (cm *CoverageMapping) MaxSeedID() int64 {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	var maxID int64
+	for _, seeds := range cm.LineToSeeds {
+		for _, id := range seeds {
+			maxID = max(maxID, id)
+		}
+	}
+	return maxID
+}
+
 func (cm *CoverageMapping) IsCovered(line LineID) bool {
 	cm.mu.RLock()
 	defer cm.mu.RUnlock()
@@ -1138,6 +1443,7 @@ func (cm *CoverageMapping) Load(path string) error {
 	}
 
 	cm.path = path
+	cm.recomputeFavored()
 	return nil
 }
 
@@ -1165,7 +1471,5 @@ func (cm *CoverageMapping) FindClosestCoveredLine(file string, targetLine int) (
 		return LineID{}, 0, false
 	}
 
-	// Random selection from available seeds
-	idx := randIntn(len(closestSeeds))
-	return LineID{File: file, Line: closestLine}, closestSeeds[idx], true
+	return LineID{File: file, Line: closestLine}, cm.pickSeed(closestSeeds), true
 }
@@ -3,6 +3,7 @@ package coverage
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -262,6 +263,32 @@ func TestCoverageMapping_FindClosestCoveredLine(t *testing.T) {
 	assert.False(t, found)
 }
 
+func TestCoverageMapping_RemoveSeeds(t *testing.T) {
+	cm, err := NewCoverageMapping("")
+	require.NoError(t, err)
+	cm.RecordLine(LineID{File: "test.c", Line: 10}, 1)
+	cm.RecordLine(LineID{File: "test.c", Line: 10}, 2)
+	cm.RecordLine(LineID{File: "test.c", Line: 20}, 2)
+	cm.RecordLine(LineID{File: "test.c", Line: 30}, 3)
+
+	assert.Equal(t, 0, cm.RemoveSeeds(nil))
+	assert.Equal(t, 2, cm.RemoveSeeds([]int64{2}))
+	assert.Equal(t, []int64{1}, cm.GetSeedsForLine(LineID{File: "test.c", Line: 10}))
+	assert.False(t, cm.IsCovered(LineID{File: "test.c", Line: 20}))
+	assert.Equal(t, 2, cm.TotalCoveredLines())
+}
+
+func TestCoverageMapping_MaxSeedID(t *testing.T) {
+	cm, err := NewCoverageMapping("")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), cm.MaxSeedID())
+
+	cm.RecordLine(LineID{File: "test.c", Line: 10}, 7)
+	cm.RecordLine(LineID{File: "test.c", Line: 10}, 3)
+	cm.RecordLine(LineID{File: "test.c", Line: 20}, 5)
+	assert.Equal(t, int64(7), cm.MaxSeedID())
+}
+
 func TestCoverageMapping_TotalCoveredLines(t *testing.T) {
 	cm, err := NewCoverageMapping("")
 	require.NoError(t, err)
@@ -339,3 +366,248 @@ func TestCoverageMapping_RecordLinesMultipleSeeds(t *testing.T) {
 	seeds2 := cm.GetSeedsForLine(lines[1])
 	assert.Len(t, seeds2, 2)
 }
+
+func TestAnalyzer_SelectTarget_MinTargetSuccessors(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfgContent := `;; Function test_func (_Z9test_funcii, funcdef_no=1, decl_uid=100, cgraph_uid=1, symbol_order=1)
+;; 2 succs { 3 4 }
+;; 3 succs { 5 }
+;; 4 succs { 5 6 }
+;; 5 succs { 1 }
+;; 6 succs { 1 }
+int test_func (int a, int b)
+{
+  <bb 2> :
+  [/path/to/test.cc:10:3] if (a > b)
+
+  <bb 3> :
+  [/path/to/test.cc:11:5] result = a;
+
+  <bb 4> :
+  [/path/to/test.cc:13:3] if (b > 0)
+
+  <bb 5> :
+  [/path/to/test.cc:14:5] return result;
+
+  <bb 6> :
+  [/path/to/test.cc:16:3] return b;
+}
+`
+	cfgPath := filepath.Join(tmpDir, "test.cc.015t.cfg")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(cfgContent), 0644))
+
+	analyzer, err := NewAnalyzer([]string{cfgPath}, []string{"test_func"}, "", filepath.Join(tmpDir, "mapping.json"), 0.8)
+	require.NoError(t, err)
+	analyzer.RecordCoverage(1, []string{"/path/to/test.cc:10"})
+
+	// Decay BB4 below BB3's weight so the unfiltered selector would pick BB3.
+	for i := 0; i < 4; i++ {
+		analyzer.DecayBBWeight("test_func", 4)
+	}
+	require.Less(t, analyzer.GetBBWeight("test_func", 4), analyzer.GetBBWeight("test_func", 3))
+
+	target := analyzer.SelectTarget()
+	require.NotNil(t, target)
+	assert.Equal(t, 3, target.BBID)
+
+	analyzer.SetMinTargetSuccessors(2)
+	target = analyzer.SelectTarget()
+	require.NotNil(t, target)
+	assert.Equal(t, 4, target.BBID)
+
+	// Once only single-successor BBs remain, they are still selected.
+	analyzer.RecordCoverage(2, []string{"/path/to/test.cc:13"})
+	target = analyzer.SelectTarget()
+	require.NotNil(t, target)
+	assert.Equal(t, 1, target.SuccessorCount)
+}
+
+func TestAnalyzer_SelectTargetExcluding(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfgContent := `;; Function test_func (_Z9test_funcii, funcdef_no=1, decl_uid=100, cgraph_uid=1, symbol_order=1)
+;; 2 succs { 3 4 }
+;; 3 succs { 5 }
+;; 4 succs { 5 6 }
+;; 5 succs { 1 }
+;; 6 succs { 1 }
+int test_func (int a, int b)
+{
+  <bb 2> :
+  [/path/to/test.cc:10:3] if (a > b)
+
+  <bb 3> :
+  [/path/to/test.cc:11:5] result = a;
+
+  <bb 4> :
+  [/path/to/test.cc:13:3] if (b > 0)
+
+  <bb 5> :
+  [/path/to/test.cc:14:5] return result;
+
+  <bb 6> :
+  [/path/to/test.cc:16:3] return b;
+}
+`
+	cfgPath := filepath.Join(tmpDir, "test.cc.015t.cfg")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(cfgContent), 0644))
+
+	analyzer, err := NewAnalyzer([]string{cfgPath}, []string{"test_func"}, "", filepath.Join(tmpDir, "mapping.json"), 0.8)
+	require.NoError(t, err)
+	analyzer.RecordCoverage(1, []string{"/path/to/test.cc:10"})
+
+	target := analyzer.SelectTargetExcluding(nil)
+	require.NotNil(t, target)
+	assert.Equal(t, 4, target.BBID, "BB4 has the most successors")
+
+	target = analyzer.SelectTargetExcluding(func(funcName string, bbID int) bool { return bbID == 4 })
+	require.NotNil(t, target)
+	assert.Equal(t, 3, target.BBID)
+
+	// Excluding every candidate falls back to all of them.
+	target = analyzer.SelectTargetExcluding(func(string, int) bool { return true })
+	require.NotNil(t, target)
+	assert.Equal(t, 4, target.BBID)
+}
+
+func TestAnalyzer_TargetForAndBBWeights(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfgContent := `;; Function test_func (_Z9test_funcii, funcdef_no=1, decl_uid=100, cgraph_uid=1, symbol_order=1)
+;; 2 succs { 3 4 }
+;; 3 succs { 4 }
+;; 4 succs { 1 }
+int test_func (int a, int b)
+{
+  <bb 2> :
+  [/path/to/test.cc:10:3] if (a > b)
+
+  <bb 3> :
+  [/path/to/test.cc:11:5] result = a;
+
+  <bb 4> :
+  [/path/to/test.cc:13:3] return result;
+}
+`
+	cfgPath := filepath.Join(tmpDir, "test.cc.015t.cfg")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(cfgContent), 0644))
+	analyzer, err := NewAnalyzer([]string{cfgPath}, []string{"test_func"}, "", "", 0.5)
+	require.NoError(t, err)
+	analyzer.RecordCoverage(1, []string{"/path/to/test.cc:10"})
+
+	target := analyzer.TargetFor("test_func", 3)
+	require.NotNil(t, target)
+	assert.Equal(t, []int{11}, target.Lines)
+	assert.Equal(t, "1", target.BaseSeed, "the seed covering the predecessor")
+	assert.Nil(t, analyzer.TargetFor("test_func", 2), "covered")
+	assert.Nil(t, analyzer.TargetFor("test_func", 9))
+	assert.Nil(t, analyzer.TargetFor("missing", 3))
+	assert.Equal(t, map[int64]bool{1: true}, analyzer.GetMapping().SeedIDs())
+
+	analyzer.DecayBBWeight("test_func", 3)
+	weights := analyzer.BBWeights()
+	assert.Equal(t, BBWeightInfo{Attempts: 1, Weight: 0.5}, weights["test_func:3"])
+
+	restored, err := NewAnalyzer([]string{cfgPath}, []string{"test_func"}, "", "", 0.5)
+	require.NoError(t, err)
+	restored.RestoreBBWeights(weights)
+	assert.Equal(t, 0.5, restored.GetBBWeight("test_func", 3))
+	assert.Equal(t, 1, restored.GetBBAttempts("test_func", 3))
+	assert.Equal(t, float64(1), restored.GetBBWeight("test_func", 4))
+}
+
+func TestAnalyzer_GetFunctionLineBBs(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfgContent := `;; Function first (_Z5firsti, funcdef_no=1, decl_uid=100, cgraph_uid=1, symbol_order=1)
+;; 2 succs { 3 }
+;; 3 succs { 1 }
+int first (int a)
+{
+  <bb 2> :
+  [/path/to/test.cc:10:3] if (a > 0)
+
+  <bb 3> :
+  [/path/to/test.cc:10:7] a = 1;
+  [/path/to/test.cc:11:3] return a;
+}
+
+;; Function second (_Z6secondi, funcdef_no=2, decl_uid=101, cgraph_uid=2, symbol_order=2)
+;; 2 succs { 1 }
+int second (int b)
+{
+  <bb 2> :
+  [/path/to/test.cc:20:3] return b;
+}
+`
+	cfgPath := filepath.Join(tmpDir, "test.cc.015t.cfg")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(cfgContent), 0644))
+
+	analyzer, err := NewAnalyzer([]string{cfgPath}, nil, "", filepath.Join(tmpDir, "mapping.json"), 0.8)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[int][]int{10: {2, 3}, 11: {3}}, analyzer.GetFunctionLineBBs("first", "/path/to/test.cc"))
+	assert.Empty(t, analyzer.GetFunctionLineBBs("first", "/path/to/other.cc"))
+	assert.Nil(t, analyzer.GetFunctionLineBBs("missing", "/path/to/test.cc"))
+
+	assert.Equal(t, []LineID{{File: "/path/to/test.cc", Line: 10}, {File: "/path/to/test.cc", Line: 11}}, analyzer.GetFunctionLines("first"))
+	assert.Nil(t, analyzer.GetFunctionLines("missing"))
+}
+
+func TestAnalyzer_SelectTargetIn(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfgContent := `;; Function hot (_Z3hotv, funcdef_no=1, decl_uid=100, cgraph_uid=1, symbol_order=1)
+;; 2 succs { 3 4 }
+int hot ()
+{
+  <bb 2> :
+  [/path/to/test.cc:10:3] if (a > b)
+}
+
+;; Function cold (_Z4coldv, funcdef_no=2, decl_uid=200, cgraph_uid=2, symbol_order=2)
+;; 2 succs { 3 }
+int cold ()
+{
+  <bb 2> :
+  [/path/to/test.cc:20:3] return 0;
+}
+`
+	cfgPath := filepath.Join(tmpDir, "test.cc.015t.cfg")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(cfgContent), 0644))
+
+	analyzer, err := NewAnalyzer([]string{cfgPath}, []string{"hot", "cold"}, "", filepath.Join(tmpDir, "mapping.json"), 0.8)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hot", "cold"}, analyzer.TargetFunctions())
+	assert.Equal(t, "hot", analyzer.SelectTargetExcluding(nil).Function, "hot:BB2 has more successors")
+
+	target := analyzer.SelectTargetIn("cold", nil)
+	require.NotNil(t, target)
+	assert.Equal(t, "cold", target.Function)
+
+	analyzer.RecordCoverage(1, []string{"/path/to/test.cc:20"})
+	assert.Nil(t, analyzer.SelectTargetIn("cold", nil), "cold is covered")
+	assert.Nil(t, analyzer.SelectTargetIn("missing", nil))
+}
+
+func TestAnalyzer_CoversTarget(t *testing.T) {
+	relSourceDir := filepath.Join("target_compilers", "gcc-v15.2.0-aarch64-cross-compile")
+	absRelSourceFile := filepath.ToSlash(filepath.Join(t.TempDir(), relSourceDir, "gcc/gcc/cfgexpand.cc"))
+
+	tests := []struct {
+		name       string
+		sourceDir  string
+		targetFile string
+		covered    string
+	}{
+		{"absolute CFG path, relative gcovr path", relSourceDir, absRelSourceFile, "gcc/gcc/cfgexpand.cc:2203"},
+		{"relative CFG path, absolute gcovr path", relSourceDir, "gcc/gcc/cfgexpand.cc", absRelSourceFile + ":2203"},
+		{"absolute source dir", "/src/gcc", "/src/gcc/gcc/cfgexpand.cc", "gcc/./cfgexpand.cc:2203"},
+		{"absolute source dir, relative CFG path", "/src/gcc", "gcc/cfgexpand.cc", "/src/gcc/gcc/../gcc/cfgexpand.cc:2203"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer := &Analyzer{sourceDir: tt.sourceDir}
+			target := &TargetInfo{Function: "stack_protect_classify_type", BBID: 2, File: tt.targetFile, Lines: []int{2203, 2205}}
+
+			assert.True(t, analyzer.CoversTarget(target, []string{"gcc/gcc/expr.cc:2203", tt.covered}))
+			assert.False(t, analyzer.CoversTarget(target, []string{strings.Replace(tt.covered, ":2203", ":2204", 1)}))
+		})
+	}
+}
@@ -21,6 +21,9 @@ type CoverageStats struct {
 	// Function coverage (optional, may be 0 if not available)
 	TotalFunctions        int
 	TotalCoveredFunctions int
+
+	// Target functions that have never been executed at all (optional)
+	ZeroCoverageFunctions int
 }
 
 // CoverageIncrease holds information about what coverage was newly increased.
@@ -62,6 +65,10 @@ type Coverage interface {
 
 	// GetStats returns the current total coverage statistics.
 	GetStats() (*CoverageStats, error)
+
+	// SelfTest verifies that the coverage backend is usable before fuzzing
+	// starts. It returns an error describing every failed check, or nil.
+	SelfTest() error
 }
 
 // PreCompileCoverage is an optional interface for coverage implementations that
@@ -70,6 +77,14 @@ type PreCompileCoverage interface {
 	Prepare() error
 }
 
+// RuntimeCoverage is an optional interface for coverage implementations
+// that also read data the seed binary writes when it runs. While
+// CollectsRuntime is true, the engine runs the compiled seed before
+// MeasureCompiled.
+type RuntimeCoverage interface {
+	CollectsRuntime() bool
+}
+
 // PostCompileCoverage is an optional interface for coverage implementations that
 // can generate a report after the caller has already compiled the seed.
 type PostCompileCoverage interface {
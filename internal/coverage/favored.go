@@ -0,0 +1,183 @@
+package coverage
+
+import (
+	"math/rand"
+	"slices"
+)
+
+// The favored seeds are a small set that together cover every covered line,
+// AFL-style: a greedy set cover, where each covered line is owned by one
+// favored seed that covers it. The cover is kept up to date as the mapping
+// changes: a newly covered line, or one whose owner was removed, is handed
+// to a favored seed covering it if there is one, and otherwise the lines
+// left are covered greedily, the seed covering most of them first. Load
+// recomputes the cover from scratch.
+
+// DefaultFavoredWeight is how many times likelier than others GetSeedForLine
+// picks a favored seed, unless SetFavoredWeight says otherwise.
+const DefaultFavoredWeight = 4
+
+// SetFavoredWeight sets how many times likelier than others GetSeedForLine
+// picks a favored seed; 1 disables the bias.
+func (cm *CoverageMapping) SetFavoredWeight(weight float64) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.favoredWeight = weight
+}
+
+// Favored returns the IDs of the favored seeds in ascending order.
+func (cm *CoverageMapping) Favored() []int64 {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	ids := make([]int64, 0, len(cm.owned))
+	for id := range cm.owned {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// IsFavored reports whether seedID is a favored seed.
+func (cm *CoverageMapping) IsFavored(seedID int64) bool {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return cm.owned[seedID] > 0
+}
+
+// FavoredGeneration counts the changes to the favored set, so callers can
+// tell whether Favored has changed since they last read it.
+func (cm *CoverageMapping) FavoredGeneration() uint64 {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return cm.favoredGen
+}
+
+// recomputeFavored rebuilds the cover from scratch. Callers hold cm.mu.
+func (cm *CoverageMapping) recomputeFavored() {
+	cm.owner = make(map[string]int64, len(cm.LineToSeeds))
+	cm.owned = make(map[int64]int)
+	keys := make([]string, 0, len(cm.LineToSeeds))
+	for key := range cm.LineToSeeds {
+		keys = append(keys, key)
+	}
+	cm.cover(keys)
+	cm.favoredGen++
+}
+
+// cover gives each line in keys that has seeds but no owner one. Callers
+// hold cm.mu.
+func (cm *CoverageMapping) cover(keys []string) {
+	if cm.owner == nil {
+		cm.owner = make(map[string]int64)
+		cm.owned = make(map[int64]int)
+	}
+
+	uncovered := make(map[string]bool)
+	for _, key := range keys {
+		if _, ok := cm.owner[key]; ok || len(cm.LineToSeeds[key]) == 0 {
+			continue
+		}
+		// A seed already favored covers the line at no cost
+		if id, ok := cm.favoredAmong(cm.LineToSeeds[key]); ok {
+			cm.own(key, id)
+			continue
+		}
+		uncovered[key] = true
+	}
+	if len(uncovered) == 0 {
+		return
+	}
+
+	for len(uncovered) > 0 {
+		counts := make(map[int64]int)
+		for key := range uncovered {
+			for _, id := range cm.LineToSeeds[key] {
+				counts[id]++
+			}
+		}
+		var best int64
+		bestCount := 0
+		for id, n := range counts {
+			if n > bestCount || n == bestCount && id < best {
+				best, bestCount = id, n
+			}
+		}
+		for key := range uncovered {
+			if slices.Contains(cm.LineToSeeds[key], best) {
+				cm.own(key, best)
+				delete(uncovered, key)
+			}
+		}
+	}
+	cm.favoredGen++
+}
+
+// uncover drops the removed seeds from the cover and returns the lines
+// they owned, for cover to hand out again. Callers hold cm.mu.
+func (cm *CoverageMapping) uncover(drop map[int64]bool) []string {
+	orphaned := make([]string, 0)
+	for key, id := range cm.owner {
+		if drop[id] {
+			delete(cm.owner, key)
+			orphaned = append(orphaned, key)
+		}
+	}
+	removed := false
+	for id := range drop {
+		if cm.owned[id] > 0 {
+			delete(cm.owned, id)
+			removed = true
+		}
+	}
+	if removed {
+		cm.favoredGen++
+	}
+	return orphaned
+}
+
+func (cm *CoverageMapping) own(key string, id int64) {
+	cm.owner[key] = id
+	cm.owned[id]++
+}
+
+// favoredAmong returns the first favored seed in seeds. Callers hold cm.mu.
+func (cm *CoverageMapping) favoredAmong(seeds []int64) (int64, bool) {
+	for _, id := range seeds {
+		if cm.owned[id] > 0 {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// pickSeed picks one of seeds at random, favored seeds favoredWeight times
+// likelier than the others. Callers hold cm.mu.
+func (cm *CoverageMapping) pickSeed(seeds []int64) int64 {
+	weight := cm.favoredWeight
+	if weight == 0 {
+		weight = DefaultFavoredWeight
+	}
+	favored := 0
+	for _, id := range seeds {
+		if cm.owned[id] > 0 {
+			favored++
+		}
+	}
+	if weight <= 1 || favored == 0 || favored == len(seeds) {
+		return seeds[randIntn(len(seeds))]
+	}
+
+	r := rand.Float64() * (float64(len(seeds)-favored) + weight*float64(favored))
+	for _, id := range seeds {
+		w := 1.0
+		if cm.owned[id] > 0 {
+			w = weight
+		}
+		if r < w {
+			return id
+		}
+		r -= w
+	}
+	return seeds[len(seeds)-1]
+}
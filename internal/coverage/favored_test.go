@@ -0,0 +1,96 @@
+package coverage
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// favoredFixture maps seeds to the lines of test.c they cover: seed 1
+// covers 1-4, seed 2 covers 1-2, seed 3 covers 3-5 and seed 4 covers 5-6.
+var favoredFixture = []struct {
+	seed  int64
+	lines []int
+}{
+	{2, []int{1, 2}},
+	{3, []int{3, 4, 5}},
+	{4, []int{5, 6}},
+	{1, []int{1, 2, 3, 4}},
+}
+
+func newFavoredMapping(t *testing.T) *CoverageMapping {
+	t.Helper()
+	cm, err := NewCoverageMapping("")
+	require.NoError(t, err)
+	for _, f := range favoredFixture {
+		lines := make([]LineID, len(f.lines))
+		for i, n := range f.lines {
+			lines[i] = LineID{File: "test.c", Line: n}
+		}
+		cm.RecordLines(lines, f.seed)
+	}
+	return cm
+}
+
+// assertCovered checks that every covered line has a favored seed.
+func assertCovered(t *testing.T, cm *CoverageMapping) {
+	t.Helper()
+	for key, seeds := range cm.LineToSeeds {
+		_, ok := cm.favoredAmong(seeds)
+		assert.True(t, ok, "line %s has no favored seed", key)
+	}
+}
+
+func TestCoverageMapping_FavoredGreedyCover(t *testing.T) {
+	cm := newFavoredMapping(t)
+	path := filepath.Join(t.TempDir(), "mapping.json")
+	require.NoError(t, cm.Save(path))
+
+	// From scratch, seed 1 covers the most lines, then seed 4 the rest
+	loaded, err := NewCoverageMapping(path)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 4}, loaded.Favored())
+	assert.True(t, loaded.IsFavored(1))
+	assert.False(t, loaded.IsFavored(3))
+	assertCovered(t, loaded)
+}
+
+func TestCoverageMapping_FavoredIncremental(t *testing.T) {
+	// Recorded one seed at a time, each seed covering a new line joins;
+	// seed 1 covers nothing new and stays out
+	cm := newFavoredMapping(t)
+	assert.Equal(t, []int64{2, 3, 4}, cm.Favored())
+	assertCovered(t, cm)
+
+	gen := cm.FavoredGeneration()
+	cm.RecordLine(LineID{File: "test.c", Line: 6}, 1)
+	assert.Equal(t, gen, cm.FavoredGeneration(), "a line already covered changed the favored set")
+
+	// Removing seed 3 hands line 5 to seed 4, already favored, and lines
+	// 3-4 to seed 1
+	cm.RemoveSeeds([]int64{3})
+	assert.Equal(t, []int64{1, 2, 4}, cm.Favored())
+	assert.Greater(t, cm.FavoredGeneration(), gen)
+	assertCovered(t, cm)
+}
+
+func TestCoverageMapping_GetSeedForLineFavorsFavored(t *testing.T) {
+	cm := newFavoredMapping(t)
+	line := LineID{File: "test.c", Line: 3} // Seeds 3 (favored) and 1
+
+	share := func() float64 {
+		const draws = 20000
+		hits := 0
+		for i := 0; i < draws; i++ {
+			if id, _ := cm.GetSeedForLine(line); id == 3 {
+				hits++
+			}
+		}
+		return float64(hits) / draws
+	}
+	assert.InDelta(t, 4.0/5, share(), 0.02)
+	cm.SetFavoredWeight(1)
+	assert.InDelta(t, 1.0/2, share(), 0.02)
+}
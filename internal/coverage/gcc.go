@@ -2,12 +2,16 @@ package coverage
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/zjy-dev/de-fuzz/internal/exec"
+	"github.com/zjy-dev/de-fuzz/internal/fsutil"
+	"github.com/zjy-dev/de-fuzz/internal/logger"
 	"github.com/zjy-dev/de-fuzz/internal/seed"
 
 	"github.com/zjy-dev/gcovr-json-util/v2/pkg/gcovr"
@@ -33,7 +37,17 @@ func (r *GcovrReport) ToBytes() ([]byte, error) {
 	return data, nil
 }
 
+// FunctionRef identifies a target function by source file and name,
+// as listed in the filter config.
+type FunctionRef struct {
+	File     string
+	Function string
+}
+
 // GCCCoverage implements the Coverage interface using GCC's gcov/gcovr toolchain.
+// The target is the instrumented compiler itself: .gcda files are written while
+// the seed is compiled, so seed binaries never need to be executed to measure
+// coverage (see fuzz.ExecuteMode).
 type GCCCoverage struct {
 	executor         exec.Executor
 	compileFunc      func(*seed.Seed) error // Function to compile a seed
@@ -42,6 +56,7 @@ type GCCCoverage struct {
 	totalReportPath  string                 // Path to total.json
 	filterConfigPath string                 // Path to filter config YAML (from compiler-isa-strategy.yaml)
 	seedReportDir    string                 // Directory to store individual seed reports
+	gcovPrefixDir    string                 // Extra .gcda root from GCOV_PREFIX redirection (optional)
 
 	// Cached filter config (loaded once)
 	filterConfig *gcovr.FilterConfig
@@ -200,22 +215,154 @@ func (g *GCCCoverage) applyTargetFilter(report *gcovr.GcovrReport) *gcovr.GcovrR
 	return filteredReport
 }
 
+// SetGcovPrefix registers the directory that seed executions redirect .gcda
+// files to via GCOV_PREFIX. Data flow for cross builds:
+//
+//	seed binary (QEMU) --GCOV_PREFIX--> dir/<stripped object path>/*.gcda
+//	gcovr (cwd gcovrExecPath) searches both its root and dir
+//
+// Clean also removes stale .gcda files under dir before each measurement,
+// so the seed has to run between compiling and MeasureCompiled: with a
+// prefix set, CollectsRuntime tells the engine to run it there.
+func (g *GCCCoverage) SetGcovPrefix(dir string) {
+	g.gcovPrefixDir = dir
+}
+
+// CollectsRuntime reports whether a GCOV_PREFIX directory is set, whose
+// .gcda files only the seed binary's runs write.
+func (g *GCCCoverage) CollectsRuntime() bool {
+	return g.gcovPrefixDir != ""
+}
+
+// gcovrRootArg returns the --root/-r value of a gcovr command, or "." (gcovr's
+// default) if none is set.
+func gcovrRootArg(command string) string {
+	fields := strings.Fields(command)
+	for i, f := range fields {
+		switch {
+		case (f == "-r" || f == "--root") && i+1 < len(fields):
+			return fields[i+1]
+		case strings.HasPrefix(f, "--root="):
+			return strings.TrimPrefix(f, "--root=")
+		}
+	}
+	return "."
+}
+
+// SelfTest checks the gcovr setup so that misconfiguration is reported before
+// any LLM call is made, instead of as a failure on the first Measure.
+// All checks are run; the returned error joins every failure found.
+func (g *GCCCoverage) SelfTest() error {
+	var errs []error
+
+	if err := g.checkGcovrBinary(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := g.checkGcovrExecPath(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := g.checkReportDirWritable(); err != nil {
+		errs = append(errs, err)
+	}
+	if g.filterConfigPath != "" {
+		if _, err := gcovr.ParseFilterConfig(g.filterConfigPath); err != nil {
+			errs = append(errs, fmt.Errorf("filter config is invalid: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkGcovrBinary runs the gcovr binary from gcovrCommand with --version.
+func (g *GCCCoverage) checkGcovrBinary() error {
+	fields := strings.Fields(g.gcovrCommand)
+	if len(fields) == 0 {
+		return fmt.Errorf("gcovr command is empty")
+	}
+
+	result, err := g.executor.Run(fields[0], "--version")
+	if err != nil {
+		return fmt.Errorf("failed to run %s --version: %w", fields[0], err)
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("%s --version exited with code %d: %s",
+			fields[0], result.ExitCode, strings.TrimSpace(result.Stderr))
+	}
+	return nil
+}
+
+// checkGcovrExecPath verifies that gcovrExecPath is a directory containing at
+// least one .gcno file, i.e. that it points into an instrumented build tree.
+func (g *GCCCoverage) checkGcovrExecPath() error {
+	info, err := os.Stat(g.gcovrExecPath)
+	if err != nil {
+		return fmt.Errorf("gcovr exec path %s is not accessible: %w", g.gcovrExecPath, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("gcovr exec path %s is not a directory", g.gcovrExecPath)
+	}
+
+	found := false
+	err = filepath.WalkDir(g.gcovrExecPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Skip unreadable entries
+		}
+		if !d.IsDir() && strings.HasSuffix(d.Name(), ".gcno") {
+			found = true
+			return fs.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scan gcovr exec path %s: %w", g.gcovrExecPath, err)
+	}
+	if !found {
+		return fmt.Errorf("no .gcno files found under %s (is the compiler built with --coverage?)", g.gcovrExecPath)
+	}
+	return nil
+}
+
+// checkReportDirWritable verifies that the directory holding total.json can be
+// created and written to.
+func (g *GCCCoverage) checkReportDirWritable() error {
+	dir := filepath.Dir(g.totalReportPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create report directory %s: %w", dir, err)
+	}
+
+	f, err := os.CreateTemp(dir, ".selftest-*")
+	if err != nil {
+		return fmt.Errorf("report directory %s is not writable: %w", dir, err)
+	}
+	name := f.Name()
+	f.Close()
+	os.Remove(name)
+	return nil
+}
+
 // Clean removes all .gcda files from the gcovr execution path.
 // Note: .gcno files (compile-time coverage notes) are NOT deleted because they
 // contain structural information about the source code and are reused across runs.
 // Only .gcda files (runtime coverage data) need to be cleaned before each measurement.
 func (g *GCCCoverage) Clean() error {
 	// Remove .gcda files (runtime coverage data)
-	cleanGcdaCmd := fmt.Sprintf("find %s -name '*.gcda' -delete", g.gcovrExecPath)
+	cleanGcdaCmd := fmt.Sprintf("find %s -name '*.gcda' -delete", exec.ShellQuote(g.gcovrExecPath))
 	if _, err := g.executor.Run("sh", "-c", cleanGcdaCmd); err != nil {
 		return fmt.Errorf("failed to clean .gcda files: %w", err)
 	}
 
-	cleanGcdaCmd = fmt.Sprintf("find %s -name '*.gcov' -delete", g.gcovrExecPath)
+	cleanGcdaCmd = fmt.Sprintf("find %s -name '*.gcov' -delete", exec.ShellQuote(g.gcovrExecPath))
 	if _, err := g.executor.Run("sh", "-c", cleanGcdaCmd); err != nil {
 		return fmt.Errorf("failed to clean .gcov files: %w", err)
 	}
 
+	if g.gcovPrefixDir != "" {
+		cleanGcdaCmd = fmt.Sprintf("find %s -name '*.gcda' -delete", exec.ShellQuote(g.gcovPrefixDir))
+		if _, err := g.executor.Run("sh", "-c", cleanGcdaCmd); err != nil {
+			return fmt.Errorf("failed to clean redirected .gcda files: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -267,10 +414,16 @@ func (g *GCCCoverage) MeasureCompiled(s *seed.Seed) (Report, error) {
 
 	// Build the full gcovr command
 	// Example: cd /build/gcc && gcovr --exclude '.*\.(h|hpp|hxx)$' --gcov-executable "gcov-14 --demangled-names" -r .. --json-pretty --json /path/to/<seed>.json
+	gcovrCommand := g.gcovrCommand
+	if g.gcovPrefixDir != "" {
+		// gcovr only searches --root when no search paths are given, so list
+		// the root explicitly alongside the prefix dir.
+		gcovrCommand = fmt.Sprintf("%s %s %s", gcovrCommand, gcovrRootArg(gcovrCommand), exec.ShellQuote(g.gcovPrefixDir))
+	}
 	fullCommand := fmt.Sprintf("cd %s && %s --json-pretty --json %s",
-		g.gcovrExecPath,
-		g.gcovrCommand,
-		seedReportPath,
+		exec.ShellQuote(g.gcovrExecPath),
+		gcovrCommand,
+		exec.ShellQuote(seedReportPath),
 	)
 
 	result, err := g.executor.Run("sh", "-c", fullCommand)
@@ -305,6 +458,10 @@ func (g *GCCCoverage) HasIncreased(newReport Report) (bool, error) {
 		return false, fmt.Errorf("expected GcovrReport, got %T", newReport)
 	}
 
+	if err := g.ensureTotalReportValid(); err != nil {
+		return false, err
+	}
+
 	// If total report doesn't exist, this is the first seed
 	if _, err := os.Stat(g.totalReportPath); os.IsNotExist(err) {
 		return true, nil
@@ -340,7 +497,9 @@ func (g *GCCCoverage) HasIncreased(newReport Report) (bool, error) {
 
 // Merge merges the new coverage report into the total report.
 // If total.json doesn't exist, copies the new report as total.json.
-// Otherwise, uses gcovr to merge: mv total.json tmp.json && gcovr -a tmp.json -a <seed>.json -o total.json && rm tmp.json
+// Otherwise, gcovr merges total.json and <seed>.json into a temp file which
+// then atomically replaces total.json, so an interrupted merge never leaves a
+// truncated total.json behind. The previous total is kept as total.json.bak.
 func (g *GCCCoverage) Merge(newReport Report) error {
 	// Get the path to the new report
 	gcovrRep, ok := newReport.(*GcovrReport)
@@ -348,6 +507,10 @@ func (g *GCCCoverage) Merge(newReport Report) error {
 		return fmt.Errorf("expected GcovrReport, got %T", newReport)
 	}
 
+	if err := g.ensureTotalReportValid(); err != nil {
+		return err
+	}
+
 	// If total report doesn't exist, just copy the new report as total
 	if _, err := os.Stat(g.totalReportPath); os.IsNotExist(err) {
 		// Ensure the directory exists
@@ -361,44 +524,117 @@ func (g *GCCCoverage) Merge(newReport Report) error {
 			return fmt.Errorf("failed to read new report: %w", err)
 		}
 
-		if err := os.WriteFile(g.totalReportPath, data, 0644); err != nil {
+		if err := fsutil.WriteFileAtomic(g.totalReportPath, data); err != nil {
 			return fmt.Errorf("failed to write total report: %w", err)
 		}
 		return nil
 	}
 
-	// Merge using gcovr command as described in README:
-	// mv total.json tmp.json && gcovr --json-pretty --json total.json -a tmp.json -a <seed>.json && rm tmp.json
-	tmpReportPath := g.totalReportPath + ".tmp.json"
-
-	// Rename current total to tmp
-	if err := os.Rename(g.totalReportPath, tmpReportPath); err != nil {
-		return fmt.Errorf("failed to rename total report to tmp: %w", err)
+	// Keep the last good total before replacing it
+	if err := g.backupTotalReport(); err != nil {
+		return err
 	}
 
-	// Run gcovr merge command
+	mergedPath := g.totalReportPath + ".merge.tmp"
+	defer os.Remove(mergedPath)
+
 	mergeCmd := fmt.Sprintf("gcovr -a %s -a %s --json-pretty --json %s",
-		tmpReportPath,
-		gcovrRep.path,
 		g.totalReportPath,
+		gcovrRep.path,
+		mergedPath,
 	)
 
 	result, err := g.executor.Run("sh", "-c", mergeCmd)
 	if err != nil {
-		// Try to restore the original total.json if merge fails
-		os.Rename(tmpReportPath, g.totalReportPath)
 		return fmt.Errorf("failed to merge reports: %w (stdout: %s, stderr: %s)",
 			err, result.Stdout, result.Stderr)
 	}
 
-	// Remove tmp file
-	os.Remove(tmpReportPath)
+	if err := validateJSONFile(mergedPath); err != nil {
+		return fmt.Errorf("merged report is invalid, keeping previous total: %w", err)
+	}
+
+	if err := os.Rename(mergedPath, g.totalReportPath); err != nil {
+		return fmt.Errorf("failed to replace total report: %w", err)
+	}
 
 	return nil
 }
 
+// backupPath returns the path of the last-good copy of total.json.
+func (g *GCCCoverage) backupPath() string {
+	return g.totalReportPath + ".bak"
+}
+
+// backupTotalReport atomically copies total.json to total.json.bak.
+func (g *GCCCoverage) backupTotalReport() error {
+	data, err := os.ReadFile(g.totalReportPath)
+	if err != nil {
+		return fmt.Errorf("failed to read total report for backup: %w", err)
+	}
+	if err := fsutil.WriteFileAtomic(g.backupPath(), data); err != nil {
+		return fmt.Errorf("failed to back up total report: %w", err)
+	}
+	return nil
+}
+
+// ensureTotalReportValid checks that total.json (if present) is valid JSON.
+// A corrupted total.json is moved aside to total.json.corrupt and restored from
+// total.json.bak. If no valid backup exists, an error is returned rather than
+// silently starting from empty coverage.
+func (g *GCCCoverage) ensureTotalReportValid() error {
+	if _, err := os.Stat(g.totalReportPath); os.IsNotExist(err) {
+		return nil
+	}
+
+	verr := validateJSONFile(g.totalReportPath)
+	if verr == nil {
+		return nil
+	}
+
+	logger.Error("[Coverage] Total report %s is corrupted: %v", g.totalReportPath, verr)
+
+	backup := g.backupPath()
+	if err := validateJSONFile(backup); err != nil {
+		return fmt.Errorf("total report %s is corrupted and no valid backup is available: %w", g.totalReportPath, verr)
+	}
+
+	corruptPath := g.totalReportPath + ".corrupt"
+	if err := os.Rename(g.totalReportPath, corruptPath); err != nil {
+		return fmt.Errorf("failed to move corrupted total report aside: %w", err)
+	}
+
+	data, err := os.ReadFile(backup)
+	if err != nil {
+		return fmt.Errorf("failed to read total report backup: %w", err)
+	}
+	if err := fsutil.WriteFileAtomic(g.totalReportPath, data); err != nil {
+		return fmt.Errorf("failed to restore total report from backup: %w", err)
+	}
+
+	logger.Warn("[Coverage] Restored total report from %s (corrupted copy kept at %s); coverage merged since the backup is lost",
+		backup, corruptPath)
+	return nil
+}
+
+// validateJSONFile returns an error if path cannot be read or is not valid JSON.
+func validateJSONFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if !json.Valid(data) {
+		return fmt.Errorf("%s is not valid JSON (%d bytes)", path, len(data))
+	}
+	return nil
+}
+
 // GetTotalReport returns the current total accumulated coverage report.
 func (g *GCCCoverage) GetTotalReport() (Report, error) {
+	if err := g.ensureTotalReportValid(); err != nil {
+		return nil, err
+	}
+
 	// Check if total report exists
 	if _, err := os.Stat(g.totalReportPath); os.IsNotExist(err) {
 		return nil, fmt.Errorf("total report does not exist: %s", g.totalReportPath)
@@ -472,9 +708,14 @@ func (g *GCCCoverage) GetIncrease(newReport Report) (*CoverageIncrease, error) {
 
 // GetStats returns the current total coverage statistics.
 func (g *GCCCoverage) GetStats() (*CoverageStats, error) {
+	if err := g.ensureTotalReportValid(); err != nil {
+		return nil, err
+	}
+
 	// Check if total report exists
 	if _, err := os.Stat(g.totalReportPath); os.IsNotExist(err) {
-		return &CoverageStats{}, nil // Return zero stats if no coverage yet
+		// Return zero stats if no coverage yet; every target is untouched
+		return &CoverageStats{ZeroCoverageFunctions: len(g.zeroCoverageFunctions(nil))}, nil
 	}
 
 	// Parse the total report
@@ -483,6 +724,7 @@ func (g *GCCCoverage) GetStats() (*CoverageStats, error) {
 		return nil, fmt.Errorf("failed to parse total report: %w", err)
 	}
 
+	zeroFuncs := g.zeroCoverageFunctions(totalReport)
 	totalReport = g.applyTargetFilter(totalReport)
 
 	// Calculate coverage statistics using gcovr-json-util
@@ -497,9 +739,77 @@ func (g *GCCCoverage) GetStats() (*CoverageStats, error) {
 		TotalCoveredLines:     coverageReport.TotalCoveredLines,
 		TotalFunctions:        len(coverageReport.Functions),
 		TotalCoveredFunctions: countCoveredFunctions(coverageReport.Functions),
+		ZeroCoverageFunctions: len(zeroFuncs),
 	}, nil
 }
 
+// GetZeroCoverageFunctions returns the target functions from the filter config
+// that have never been executed: they are missing from total.json or have no
+// executed line. Returns nil if no filter config is loaded.
+func (g *GCCCoverage) GetZeroCoverageFunctions() ([]FunctionRef, error) {
+	if err := g.ensureTotalReportValid(); err != nil {
+		return nil, err
+	}
+
+	var totalReport *gcovr.GcovrReport
+	if _, err := os.Stat(g.totalReportPath); err == nil {
+		report, err := gcovr.ParseReport(g.totalReportPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse total report: %w", err)
+		}
+		totalReport = report
+	}
+
+	return g.zeroCoverageFunctions(totalReport), nil
+}
+
+// zeroCoverageFunctions lists filter-config targets with no execution in report.
+// A nil report means no coverage has been recorded yet.
+func (g *GCCCoverage) zeroCoverageFunctions(report *gcovr.GcovrReport) []FunctionRef {
+	if g.filterConfig == nil {
+		return nil
+	}
+
+	// Executed functions per report file, keyed by full path and base name
+	executed := make(map[string]*targetFunctionMatcher)
+	if report != nil {
+		for _, file := range report.Files {
+			matcher := newTargetFunctionMatcher()
+			for _, fn := range file.Functions {
+				if fn.ExecutionCount > 0 {
+					matcher.add(fn.DemangledName)
+					matcher.add(fn.Name)
+				}
+			}
+			for _, line := range file.Lines {
+				if line.Count > 0 {
+					matcher.add(line.FunctionName)
+				}
+			}
+			normalized := normalizeCoveragePath(file.FilePath)
+			executed[normalized] = matcher
+			if _, ok := executed[filepath.Base(normalized)]; !ok {
+				executed[filepath.Base(normalized)] = matcher
+			}
+		}
+	}
+
+	var zero []FunctionRef
+	for _, target := range g.filterConfig.Targets {
+		normalized := normalizeCoveragePath(target.File)
+		matcher, ok := executed[normalized]
+		if !ok {
+			matcher = executed[filepath.Base(normalized)]
+		}
+		for _, fn := range target.Functions {
+			if !matcher.matches(fn) {
+				zero = append(zero, FunctionRef{File: target.File, Function: fn})
+			}
+		}
+	}
+	return zero
+}
+
 // countCoveredFunctions counts functions with at least one covered line.
 func countCoveredFunctions(functions []gcovr.FunctionCoverage) int {
 	count := 0
@@ -2,8 +2,10 @@ package coverage
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/zjy-dev/de-fuzz/internal/exec"
@@ -331,3 +333,282 @@ func TestGCCCoverage_ExtractCoveredLinesFiltered_MatchesDemangledLineNames(t *te
 		t.Fatalf("Missing filtered lines: %v", want)
 	}
 }
+
+// fakeExecutor is a stub exec.Executor that records invocations.
+type fakeExecutor struct {
+	calls   []string
+	runFunc func(command string, args ...string) (*exec.ExecutionResult, error)
+}
+
+func (f *fakeExecutor) Run(command string, args ...string) (*exec.ExecutionResult, error) {
+	f.calls = append(f.calls, strings.TrimSpace(command+" "+strings.Join(args, " ")))
+	if f.runFunc != nil {
+		return f.runFunc(command, args...)
+	}
+	return &exec.ExecutionResult{ExitCode: 0}, nil
+}
+
+func TestGCCCoverage_SelfTest_Success(t *testing.T) {
+	tmpDir := t.TempDir()
+	buildDir := filepath.Join(tmpDir, "build", "gcc")
+	if err := os.MkdirAll(buildDir, 0755); err != nil {
+		t.Fatalf("Failed to create build dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(buildDir, "cfgexpand.gcno"), []byte("gcno"), 0644); err != nil {
+		t.Fatalf("Failed to create .gcno file: %v", err)
+	}
+	filterPath := filepath.Join(tmpDir, "filter.yaml")
+	if err := os.WriteFile(filterPath, []byte("targets:\n  - file: gcc/cfgexpand.cc\n    functions: [expand_used_vars]\n"), 0644); err != nil {
+		t.Fatalf("Failed to write filter config: %v", err)
+	}
+
+	executor := &fakeExecutor{}
+	gcc := NewGCCCoverage(executor, nil, filepath.Join(tmpDir, "build"),
+		"gcovr -r ..", filepath.Join(tmpDir, "state", "total.json"), filterPath)
+
+	if err := gcc.SelfTest(); err != nil {
+		t.Fatalf("SelfTest() error = %v", err)
+	}
+	if len(executor.calls) != 1 || executor.calls[0] != "gcovr --version" {
+		t.Errorf("expected a single 'gcovr --version' call, got %v", executor.calls)
+	}
+}
+
+func TestGCCCoverage_SelfTest_ReportsAllFailures(t *testing.T) {
+	tmpDir := t.TempDir()
+	filterPath := filepath.Join(tmpDir, "filter.yaml")
+	if err := os.WriteFile(filterPath, []byte("targets: [unterminated"), 0644); err != nil {
+		t.Fatalf("Failed to write filter config: %v", err)
+	}
+
+	executor := &fakeExecutor{
+		runFunc: func(command string, args ...string) (*exec.ExecutionResult, error) {
+			return nil, fmt.Errorf("executable file not found")
+		},
+	}
+	// The build dir exists but has no .gcno files.
+	gcc := NewGCCCoverage(executor, nil, tmpDir, "gcovr", filepath.Join(tmpDir, "total.json"), filterPath)
+
+	err := gcc.SelfTest()
+	if err == nil {
+		t.Fatal("SelfTest() expected error, got nil")
+	}
+	msg := err.Error()
+	for _, want := range []string{"gcovr --version", "no .gcno files", "filter config is invalid"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("SelfTest() error missing %q:\n%s", want, msg)
+		}
+	}
+	if strings.Contains(msg, "not writable") {
+		t.Errorf("SelfTest() unexpectedly reported report dir as not writable:\n%s", msg)
+	}
+}
+
+func TestGCCCoverage_SelfTest_MissingExecPath(t *testing.T) {
+	tmpDir := t.TempDir()
+	gcc := NewGCCCoverage(&fakeExecutor{}, nil, filepath.Join(tmpDir, "missing"),
+		"gcovr", filepath.Join(tmpDir, "total.json"), "")
+
+	err := gcc.SelfTest()
+	if err == nil || !strings.Contains(err.Error(), "is not accessible") {
+		t.Errorf("SelfTest() error = %v, want exec path error", err)
+	}
+}
+
+func TestGCCCoverage_GcovPrefix_AddsSearchPathsAndCleans(t *testing.T) {
+	tmpDir := t.TempDir()
+	prefixDir := filepath.Join(tmpDir, "gcov prefix")
+
+	executor := &fakeExecutor{}
+	gcc := NewGCCCoverage(executor, nil, tmpDir, `gcovr --exclude ".*\.h$" -r ..`,
+		filepath.Join(tmpDir, "total.json"), "")
+	gcc.SetGcovPrefix(prefixDir)
+
+	if err := gcc.Clean(); err != nil {
+		t.Fatalf("Clean() error = %v", err)
+	}
+	wantClean := "sh -c find '" + prefixDir + "' -name '*.gcda' -delete"
+	if executor.calls[len(executor.calls)-1] != wantClean {
+		t.Errorf("Clean() last call = %q, want %q", executor.calls[len(executor.calls)-1], wantClean)
+	}
+
+	// The fake executor does not create the report, so MeasureCompiled fails
+	// after issuing the command; only the command line is checked here.
+	s := &seed.Seed{Meta: seed.Metadata{ID: 7}}
+	_, _ = gcc.MeasureCompiled(s)
+	last := executor.calls[len(executor.calls)-1]
+	if !strings.Contains(last, "-r .. .. '"+prefixDir+"' --json-pretty") {
+		t.Errorf("gcovr command does not search root and prefix dir: %s", last)
+	}
+}
+
+func TestGcovrRootArg(t *testing.T) {
+	tests := map[string]string{
+		"gcovr -r ..":              "..",
+		"gcovr --root /src --txt":  "/src",
+		"gcovr --root=/src":        "/src",
+		`gcovr --exclude ".*\.h$"`: ".",
+	}
+	for cmd, want := range tests {
+		if got := gcovrRootArg(cmd); got != want {
+			t.Errorf("gcovrRootArg(%q) = %q, want %q", cmd, got, want)
+		}
+	}
+}
+
+// writeTestReport writes a single-function gcovr report with the given line count.
+func writeTestReport(t *testing.T, path string, count int) {
+	t.Helper()
+	report := &gcovr.GcovrReport{
+		FormatVersion: "0.14",
+		Files: []gcovr.File{
+			{
+				FilePath: "gcc/gcc/cfgexpand.cc",
+				Lines: []gcovr.Line{
+					{LineNumber: 2203, FunctionName: "stack_protect_classify_type(tree_node*)", Count: count},
+				},
+				Functions: []gcovr.Function{
+					{Name: "_Z27stack_protect_classify_typeP9tree_node", DemangledName: "stack_protect_classify_type(tree_node*)", LineNo: 2203, ExecutionCount: count},
+				},
+			},
+		},
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("Failed to marshal report: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to write report: %v", err)
+	}
+}
+
+// fakeGcovrMerge emulates "gcovr -a <total> -a <seed> ... --json <out>" by
+// copying the seed report to the output path.
+func fakeGcovrMerge(command string, args ...string) (*exec.ExecutionResult, error) {
+	fields := strings.Fields(args[len(args)-1])
+	out := fields[len(fields)-1]
+	data, err := os.ReadFile(fields[4])
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(out, data, 0644); err != nil {
+		return nil, err
+	}
+	return &exec.ExecutionResult{}, nil
+}
+
+func TestGCCCoverage_Merge_RecoversFromTruncatedTotal(t *testing.T) {
+	tmpDir := t.TempDir()
+	totalPath := filepath.Join(tmpDir, "total.json")
+	gcc := NewGCCCoverage(&fakeExecutor{runFunc: fakeGcovrMerge}, nil, tmpDir, "gcovr", totalPath, "")
+
+	for i := 1; i <= 2; i++ {
+		reportPath := filepath.Join(tmpDir, fmt.Sprintf("%d.json", i))
+		writeTestReport(t, reportPath, i)
+		if err := gcc.Merge(&GcovrReport{path: reportPath}); err != nil {
+			t.Fatalf("Merge(%d) error = %v", i, err)
+		}
+	}
+	backup, err := os.ReadFile(totalPath + ".bak")
+	if err != nil {
+		t.Fatalf("expected total.json.bak after second merge: %v", err)
+	}
+
+	// Simulate a kill mid-write leaving a truncated total.json
+	data, _ := os.ReadFile(totalPath)
+	if err := os.WriteFile(totalPath, data[:len(data)/2], 0644); err != nil {
+		t.Fatalf("Failed to truncate total report: %v", err)
+	}
+
+	newReportPath := filepath.Join(tmpDir, "3.json")
+	writeTestReport(t, newReportPath, 3)
+	if _, err := gcc.HasIncreased(&GcovrReport{path: newReportPath}); err != nil {
+		t.Fatalf("HasIncreased() should recover from truncated total, got %v", err)
+	}
+
+	restored, _ := os.ReadFile(totalPath)
+	if string(restored) != string(backup) {
+		t.Error("total.json was not restored from backup")
+	}
+	if _, err := os.Stat(totalPath + ".corrupt"); err != nil {
+		t.Errorf("corrupted total.json should be kept aside: %v", err)
+	}
+
+	// Merging continues normally after recovery
+	if err := gcc.Merge(&GcovrReport{path: newReportPath}); err != nil {
+		t.Fatalf("Merge() after recovery error = %v", err)
+	}
+	if _, err := gcc.GetStats(); err != nil {
+		t.Errorf("GetStats() after recovery error = %v", err)
+	}
+}
+
+func TestGCCCoverage_GetStats_CorruptedTotalWithoutBackup(t *testing.T) {
+	tmpDir := t.TempDir()
+	totalPath := filepath.Join(tmpDir, "total.json")
+	if err := os.WriteFile(totalPath, []byte(`{"gcovr/format_version": "0.`), 0644); err != nil {
+		t.Fatalf("Failed to write total report: %v", err)
+	}
+	gcc := NewGCCCoverage(&fakeExecutor{}, nil, tmpDir, "gcovr", totalPath, "")
+
+	if _, err := gcc.GetStats(); err == nil || !strings.Contains(err.Error(), "no valid backup") {
+		t.Errorf("GetStats() error = %v, want corruption error", err)
+	}
+}
+
+func TestGCCCoverage_GetZeroCoverageFunctions(t *testing.T) {
+	tmpDir := t.TempDir()
+	filterPath := filepath.Join(tmpDir, "filter.yaml")
+	filterContent := `targets:
+  - file: "gcc/gcc/cfgexpand.cc"
+    functions:
+      - "stack_protect_classify_type"
+      - "stack_protect_prologue"
+  - file: "gcc/gcc/function.cc"
+    functions:
+      - "stack_protect_epilogue"
+`
+	if err := os.WriteFile(filterPath, []byte(filterContent), 0644); err != nil {
+		t.Fatalf("Failed to write filter config: %v", err)
+	}
+
+	totalPath := filepath.Join(tmpDir, "total.json")
+	gcc := NewGCCCoverage(&fakeExecutor{}, nil, tmpDir, "gcovr", totalPath, filterPath)
+
+	// No total.json yet: every target function is untouched
+	zero, err := gcc.GetZeroCoverageFunctions()
+	if err != nil {
+		t.Fatalf("GetZeroCoverageFunctions() error = %v", err)
+	}
+	if len(zero) != 3 {
+		t.Fatalf("GetZeroCoverageFunctions() = %v, want all 3 targets", zero)
+	}
+
+	// total.json covers only stack_protect_classify_type
+	writeTestReport(t, totalPath, 2)
+
+	zero, err = gcc.GetZeroCoverageFunctions()
+	if err != nil {
+		t.Fatalf("GetZeroCoverageFunctions() error = %v", err)
+	}
+	want := []FunctionRef{
+		{File: "gcc/gcc/cfgexpand.cc", Function: "stack_protect_prologue"},
+		{File: "gcc/gcc/function.cc", Function: "stack_protect_epilogue"},
+	}
+	if len(zero) != len(want) {
+		t.Fatalf("GetZeroCoverageFunctions() = %v, want %v", zero, want)
+	}
+	for i := range want {
+		if zero[i] != want[i] {
+			t.Errorf("GetZeroCoverageFunctions()[%d] = %v, want %v", i, zero[i], want[i])
+		}
+	}
+
+	stats, err := gcc.GetStats()
+	if err != nil {
+		t.Fatalf("GetStats() error = %v", err)
+	}
+	if stats.ZeroCoverageFunctions != 2 {
+		t.Errorf("GetStats().ZeroCoverageFunctions = %d, want 2", stats.ZeroCoverageFunctions)
+	}
+}
@@ -3,6 +3,7 @@ package exec
 import (
 	"bytes"
 	"os/exec"
+	"strings"
 )
 
 // ExecutionResult holds the outcome of a command execution.
@@ -53,3 +54,15 @@ func (e *CommandExecutor) Run(command string, args ...string) (*ExecutionResult,
 
 	return result, nil
 }
+
+// ShellQuote quotes s for a POSIX shell unless it is made of safe
+// characters only.
+func ShellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	if strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789/._-:=+,@%") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
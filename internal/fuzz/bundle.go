@@ -0,0 +1,85 @@
+package fuzz
+
+import (
+	"path/filepath"
+	"strconv"
+
+	"github.com/zjy-dev/de-fuzz/internal/compiler"
+	"github.com/zjy-dev/de-fuzz/internal/logger"
+	"github.com/zjy-dev/de-fuzz/internal/oracle"
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// bundleLineageDepth caps how many ancestors a bug bundle lists.
+const bundleLineageDepth = 32
+
+// bugBundle is an exported bundle, kept to list later occurrences of its
+// bug in it.
+type bugBundle struct {
+	seed *seed.Seed
+	meta seed.BundleMeta
+	path string
+}
+
+// exportBundle writes the reproduction bundle of a bug-triggering corpus
+// seed under BugsDir, reproducing the minimized seed when there is one.
+// The seed's ancestors in the corpus make up the bundle's lineage. A bug
+// that duplicates an earlier one gets no bundle of its own; the first
+// bug's bundle lists its seed instead (see updateBundleOccurrences).
+func (e *Engine) exportBundle(s *seed.Seed, bug *oracle.Bug, compileResult *compiler.CompileResult) {
+	if e.cfg.BugsDir == "" || bug == nil || compileResult == nil || bug.DuplicateOf != 0 {
+		return
+	}
+	target := s
+	if bug.Seed != nil {
+		target = bug.Seed
+	}
+	meta := e.cfg.Bundle
+	meta.Env = append([]string(nil), meta.Env...)
+	meta.CompilerPath = compileResult.CompilerPath
+	meta.CFlags = append([]string(nil), compileResult.EffectiveFlags...)
+	meta.CompileCommand = compileResult.Command
+	meta.Verdict = bug.Description
+	meta.UnderstandingVersion = e.understandingVersion
+	meta.Signature = bug.Signature
+	meta.AlsoTriggeredBy = append([]uint64(nil), bug.Occurrences...)
+	if bug.Type != "" {
+		meta.Oracle = bug.Type
+	}
+	if meta.Oracle == "" {
+		meta.Oracle = e.cfg.OracleType
+	}
+	if e.cfg.Corpus != nil {
+		for _, ancestor := range e.cfg.Corpus.Ancestors(s.Meta.ID, bundleLineageDepth) {
+			meta.Lineage = append(meta.Lineage, seed.BundleAncestor{
+				ID:           ancestor.ID,
+				Depth:        ancestor.Depth,
+				CovIncrease:  ancestor.CovIncrease,
+				MutationNote: ancestor.MutationNote,
+			})
+		}
+	}
+
+	path := filepath.Join(e.cfg.BugsDir, strconv.FormatUint(s.Meta.ID, 10), "bundle.tar.gz")
+	if err := seed.ExportBundle(target, meta, path); err != nil {
+		logger.Warn("Failed to export reproduction bundle of seed %d: %v", s.Meta.ID, err)
+		return
+	}
+	logger.Info("Reproduction bundle of seed %d: %s", s.Meta.ID, path)
+	if bug.Signature != "" {
+		e.bugBundles[bug.Signature] = &bugBundle{seed: target, meta: meta, path: path}
+	}
+}
+
+// updateBundleOccurrences rewrites the bundle of bug, if this run exported
+// one, to list all of its occurrences.
+func (e *Engine) updateBundleOccurrences(bug *oracle.Bug) {
+	b := e.bugBundles[bug.Signature]
+	if b == nil {
+		return
+	}
+	b.meta.AlsoTriggeredBy = append([]uint64(nil), bug.Occurrences...)
+	if err := seed.ExportBundle(b.seed, b.meta, b.path); err != nil {
+		logger.Warn("Failed to update reproduction bundle %s: %v", b.path, err)
+	}
+}
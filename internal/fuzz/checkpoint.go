@@ -0,0 +1,309 @@
+package fuzz
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/zjy-dev/de-fuzz/internal/coverage"
+	"github.com/zjy-dev/de-fuzz/internal/fsutil"
+	"github.com/zjy-dev/de-fuzz/internal/logger"
+	"github.com/zjy-dev/de-fuzz/internal/oracle"
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// CheckpointVersion is the version of the engine checkpoint format; Resume
+// rejects checkpoints from a newer one.
+const CheckpointVersion = 1
+
+// Checkpoint is the engine's own progress, written to CheckpointPath
+// whenever the corpus and mapping are saved, so Resume can continue a
+// campaign where it stopped.
+type Checkpoint struct {
+	Version int       `json:"version"`
+	SavedAt time.Time `json:"saved_at"`
+
+	// Limits of the run that wrote the checkpoint and how much of them the
+	// campaign consumed. Elapsed carries over into a resumed run's budget.
+	MaxIterations      int     `json:"max_iterations"`
+	MaxDurationSeconds float64 `json:"max_duration_seconds,omitempty"`
+	ElapsedSeconds     float64 `json:"elapsed_seconds"`
+	StopReason         string  `json:"stop_reason,omitempty"`
+
+	// Counters, as in the run summary
+	Iterations          int            `json:"iterations"`
+	TargetHits          int            `json:"target_hits"`
+	SkippedIterations   int            `json:"skipped_iterations,omitempty"`
+	DuplicateSeeds      int            `json:"duplicate_seeds,omitempty"`
+	TrimmedSeeds        int            `json:"trimmed_seeds,omitempty"`
+	CompileFixAttempted int            `json:"compile_fix_attempted,omitempty"`
+	CompileFixRepaired  int            `json:"compile_fix_repaired,omitempty"`
+	FallbackSeeds       int            `json:"fallback_seeds,omitempty"`
+	ICEs                int            `json:"ices,omitempty"`
+	CoverageGains       int            `json:"coverage_gains,omitempty"`
+	ProfileCoverage     map[string]int `json:"profile_coverage,omitempty"`
+	ProfileBugs         map[string]int `json:"profile_bugs,omitempty"`
+
+	Bugs []BugSummary `json:"bugs"`
+
+	// Interrupted is the target being solved when the checkpoint was
+	// written, e.g. when the run was canceled; its iteration is not in
+	// Iterations, and a resumed run targets it first.
+	Interrupted *TargetRef `json:"interrupted,omitempty"`
+
+	RNG       RNGState                         `json:"rng"`
+	BBWeights map[string]coverage.BBWeightInfo `json:"bb_weights,omitempty"` // By "FuncName:BBID"
+}
+
+// BugSummary is a bug found by an earlier run, by the seed that triggered it.
+type BugSummary struct {
+	SeedID      uint64   `json:"seed_id"`
+	Description string   `json:"description"`
+	Type        string   `json:"type,omitempty"` // oracle.BugTypeICE, or empty for oracle verdicts
+	Signature   string   `json:"signature,omitempty"`
+	Occurrences []uint64 `json:"occurrences,omitempty"` // Other seeds that triggered it
+}
+
+// TargetRef names a target basic block.
+type TargetRef struct {
+	Function string `json:"function"`
+	BBID     int    `json:"bb_id"`
+}
+
+// RNGState is the state of the engine's random source: its seed and how
+// many values were drawn from it since.
+type RNGState struct {
+	Seed  int64  `json:"seed"`
+	Draws uint64 `json:"draws"`
+}
+
+// countingSource is a math/rand source that counts its draws, so its whole
+// state is an RNGState: seeding it again and drawing as many values
+// restores it. It is not safe for concurrent use.
+type countingSource struct {
+	src   rand.Source64
+	state RNGState
+}
+
+func newCountingSource(seed int64) *countingSource {
+	return &countingSource{
+		src:   rand.NewSource(seed).(rand.Source64),
+		state: RNGState{Seed: seed},
+	}
+}
+
+func (s *countingSource) Int63() int64 {
+	s.state.Draws++
+	return s.src.Int63()
+}
+
+func (s *countingSource) Uint64() uint64 {
+	s.state.Draws++
+	return s.src.Uint64()
+}
+
+func (s *countingSource) Seed(seed int64) {
+	s.src.Seed(seed)
+	s.state = RNGState{Seed: seed}
+}
+
+// restore brings the source back to state.
+func (s *countingSource) restore(state RNGState) {
+	s.Seed(state.Seed)
+	for s.state.Draws < state.Draws {
+		s.Int63()
+	}
+}
+
+// checkpoint captures the engine's progress.
+func (e *Engine) checkpoint() *Checkpoint {
+	cp := &Checkpoint{
+		Version:             CheckpointVersion,
+		SavedAt:             time.Now(),
+		MaxIterations:       e.cfg.MaxIterations,
+		MaxDurationSeconds:  e.cfg.MaxDuration.Seconds(),
+		ElapsedSeconds:      e.elapsed().Seconds(),
+		StopReason:          e.stopReason,
+		Iterations:          e.iterationCount,
+		TargetHits:          e.targetHits,
+		SkippedIterations:   e.skippedIterations,
+		DuplicateSeeds:      e.duplicateSeeds,
+		TrimmedSeeds:        e.trimmedSeeds,
+		CompileFixAttempted: e.compileFixAttempted,
+		CompileFixRepaired:  e.compileFixRepaired,
+		FallbackSeeds:       e.fallbackSeeds,
+		ICEs:                e.ices,
+		CoverageGains:       e.coverageGains,
+		ProfileCoverage:     e.profileCoverage,
+		ProfileBugs:         e.profileBugs,
+		Bugs:                make([]BugSummary, 0, len(e.bugsFound)),
+		RNG:                 e.rngSource.state,
+	}
+	for _, bug := range e.bugsFound {
+		cp.Bugs = append(cp.Bugs, BugSummary{
+			SeedID:      bug.Seed.Meta.ID,
+			Description: bug.Description,
+			Type:        bug.Type,
+			Signature:   bug.Signature,
+			Occurrences: bug.Occurrences,
+		})
+	}
+	if e.inFlight != nil {
+		cp.Iterations--
+		cp.Interrupted = &TargetRef{Function: e.inFlight.Function, BBID: e.inFlight.BBID}
+	}
+	if e.cfg.Analyzer != nil {
+		cp.BBWeights = e.cfg.Analyzer.BBWeights()
+	}
+	return cp
+}
+
+// saveCheckpoint writes the checkpoint to CheckpointPath, through a
+// temporary file so a crash never leaves half of one.
+func (e *Engine) saveCheckpoint() error {
+	if e.cfg.CheckpointPath == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(e.checkpoint(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode engine checkpoint: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(e.cfg.CheckpointPath), 0755); err != nil {
+		return fmt.Errorf("failed to write engine checkpoint: %w", err)
+	}
+	if err := fsutil.WriteFileAtomic(e.cfg.CheckpointPath, data); err != nil {
+		return fmt.Errorf("failed to write engine checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Resume restores the checkpoint at path, written by an earlier run of the
+// campaign: counters continue instead of restarting from zero, known bugs
+// are not reported again, the random source and BB weights pick up where
+// they were, and the time already spent counts against MaxDuration. Run
+// then skips the initial phase for queued seeds the coverage mapping
+// already credits, and targets the BB the earlier run was interrupted on
+// first. Call it after the corpus and mapping are recovered, before Run.
+// A missing checkpoint is not an error; the campaign's statistics then
+// start fresh.
+func (e *Engine) Resume(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		logger.Info("No engine checkpoint at %s, starting statistics fresh", path)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read engine checkpoint: %w", err)
+	}
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return fmt.Errorf("failed to parse engine checkpoint %s: %w", path, err)
+	}
+	if cp.Version > CheckpointVersion {
+		return fmt.Errorf("engine checkpoint %s has version %d, newer than %d", path, cp.Version, CheckpointVersion)
+	}
+
+	e.iterationCount = cp.Iterations
+	e.priorElapsed = time.Duration(cp.ElapsedSeconds * float64(time.Second))
+	e.targetHits = cp.TargetHits
+	e.skippedIterations = cp.SkippedIterations
+	e.duplicateSeeds = cp.DuplicateSeeds
+	e.trimmedSeeds = cp.TrimmedSeeds
+	e.compileFixAttempted = cp.CompileFixAttempted
+	e.compileFixRepaired = cp.CompileFixRepaired
+	e.fallbackSeeds = cp.FallbackSeeds
+	e.ices = cp.ICEs
+	e.coverageGains = cp.CoverageGains
+	if cp.ProfileCoverage != nil {
+		e.profileCoverage = cp.ProfileCoverage
+	}
+	if cp.ProfileBugs != nil {
+		e.profileBugs = cp.ProfileBugs
+	}
+	e.bugsFound = make([]*oracle.Bug, 0, len(cp.Bugs))
+	e.bugSignatures = make(map[string]*oracle.Bug)
+	for _, b := range cp.Bugs {
+		bug := &oracle.Bug{
+			Seed:        &seed.Seed{Meta: seed.Metadata{ID: b.SeedID}},
+			Description: b.Description,
+			Type:        b.Type,
+			Signature:   b.Signature,
+			Occurrences: b.Occurrences,
+		}
+		e.bugsFound = append(e.bugsFound, bug)
+		if bug.Signature != "" {
+			e.bugSignatures[bug.Signature] = bug
+		}
+	}
+	e.rngSource.restore(cp.RNG)
+	if e.cfg.Analyzer != nil && cp.BBWeights != nil {
+		e.cfg.Analyzer.RestoreBBWeights(cp.BBWeights)
+	}
+	e.retarget = cp.Interrupted
+	e.resumed = true
+
+	logger.Info("Resumed from %s (saved %s): %d iterations, %v elapsed, %d targets hit, %d bugs",
+		path, cp.SavedAt.Format(time.RFC3339), cp.Iterations, e.priorElapsed.Round(time.Second), cp.TargetHits, len(cp.Bugs))
+	return nil
+}
+
+// knownBug reports whether a bug was already recorded for the seed id, as
+// a new bug or an occurrence of one, by this run or, after Resume, an
+// earlier one.
+func (e *Engine) knownBug(id uint64) bool {
+	for _, bug := range e.bugsFound {
+		if bug.Seed != nil && bug.Seed.Meta.ID == id || slices.Contains(bug.Occurrences, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectTarget picks the next target: the one an interrupted run was
+// working on, if still uncovered, else the one the TargetSchedule selects,
+// passing over the targets cooling down. A dry run passes over the targets
+// it rendered, too, and gets nil once it rendered every uncovered one.
+func (e *Engine) selectTarget() *coverage.TargetInfo {
+	if ref := e.retarget; ref != nil {
+		e.retarget = nil
+		if target := e.cfg.Analyzer.TargetFor(ref.Function, ref.BBID); target != nil {
+			logger.Info("Retargeting %s:BB%d, interrupted in the last run", ref.Function, ref.BBID)
+			return target
+		}
+	}
+	var exclude func(string, int) bool
+	if e.expireCooldowns() > 0 {
+		exclude = e.coolingDown
+	}
+	if !e.cfg.DryRunPrompts {
+		return e.scheduler.selectTarget(e.cfg.Analyzer, exclude)
+	}
+
+	coolingDown := exclude
+	exclude = func(funcName string, bbID int) bool {
+		return e.rendered[cooldownKey(funcName, bbID)] ||
+			(coolingDown != nil && coolingDown(funcName, bbID))
+	}
+	target := e.scheduler.selectTarget(e.cfg.Analyzer, exclude)
+	if target != nil && e.rendered[cooldownKey(target.Function, target.BBID)] {
+		// The scheduled function is done: the analyzer selects an excluded
+		// BB only when it excludes them all, so look among all functions.
+		target = e.cfg.Analyzer.SelectTargetExcluding(exclude)
+		if target != nil && e.rendered[cooldownKey(target.Function, target.BBID)] {
+			return nil
+		}
+	}
+	return target
+}
+
+// markRendered records that a dry run rendered the prompts for target.
+func (e *Engine) markRendered(target *coverage.TargetInfo) {
+	if e.rendered == nil {
+		e.rendered = make(map[string]bool)
+	}
+	e.rendered[cooldownKey(target.Function, target.BBID)] = true
+}
@@ -0,0 +1,164 @@
+package fuzz
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zjy-dev/de-fuzz/internal/coverage"
+	"github.com/zjy-dev/de-fuzz/internal/oracle"
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// newCheckpointAnalyzer returns an analyzer over test_func, whose BBs 2-4
+// are all uncovered.
+func newCheckpointAnalyzer(t *testing.T) *coverage.Analyzer {
+	t.Helper()
+	tmpDir := t.TempDir()
+	cfgContent := `;; Function test_func (_Z9test_funcii, funcdef_no=1, decl_uid=100, cgraph_uid=1, symbol_order=1)
+;; 2 succs { 3 4 }
+;; 3 succs { 4 }
+;; 4 succs { 1 }
+int test_func (int a, int b)
+{
+  <bb 2> :
+  [/path/to/test.cc:10:3] if (a > b)
+
+  <bb 3> :
+  [/path/to/test.cc:11:5] result = a;
+
+  <bb 4> :
+  [/path/to/test.cc:13:3] return result;
+}
+`
+	cfgPath := filepath.Join(tmpDir, "test.cc.015t.cfg")
+	if err := os.WriteFile(cfgPath, []byte(cfgContent), 0644); err != nil {
+		t.Fatalf("Failed to write CFG file: %v", err)
+	}
+	analyzer, err := coverage.NewAnalyzer([]string{cfgPath}, []string{"test_func"}, "", filepath.Join(tmpDir, "mapping.json"), 0.8)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	return analyzer
+}
+
+func TestEngine_CheckpointRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "engine_checkpoint.json")
+	analyzer := newCheckpointAnalyzer(t)
+	engine := NewEngine(Config{Analyzer: analyzer, CheckpointPath: path})
+	engine.iterationCount = 7
+	engine.targetHits = 3
+	engine.duplicateSeeds = 2
+	engine.fallbackSeeds = 4
+	engine.ices = 2
+	engine.coverageGains = 5
+	engine.profileCoverage["O2"] = 40
+	engine.bugsFound = append(engine.bugsFound, &oracle.Bug{
+		Seed:        &seed.Seed{Meta: seed.Metadata{ID: 12}},
+		Description: "canary overwritten",
+	}, &oracle.Bug{
+		Seed:        &seed.Seed{Meta: seed.Metadata{ID: 15}},
+		Description: "Internal compiler error on seed 15",
+		Type:        oracle.BugTypeICE,
+		Occurrences: []uint64{16},
+	})
+	for range 5 {
+		engine.rng.Intn(100)
+	}
+	analyzer.DecayBBWeight("test_func", 2)
+	engine.inFlight = analyzer.TargetFor("test_func", 3)
+	if err := engine.saveCheckpoint(); err != nil {
+		t.Fatalf("saveCheckpoint() failed: %v", err)
+	}
+
+	resumedAnalyzer := newCheckpointAnalyzer(t)
+	resumed := NewEngine(Config{Analyzer: resumedAnalyzer})
+	if err := resumed.Resume(path); err != nil {
+		t.Fatalf("Resume() failed: %v", err)
+	}
+
+	// The interrupted iteration is not counted; it is run again
+	if resumed.iterationCount != 6 || resumed.targetHits != 3 || resumed.duplicateSeeds != 2 {
+		t.Errorf("counters = %d iterations, %d hits, %d duplicates; want 6, 3, 2",
+			resumed.iterationCount, resumed.targetHits, resumed.duplicateSeeds)
+	}
+	if resumed.fallbackSeeds != 4 || resumed.ices != 2 || resumed.coverageGains != 5 {
+		t.Errorf("counters = %d fallback seeds, %d ICEs, %d coverage gains; want 4, 2, 5",
+			resumed.fallbackSeeds, resumed.ices, resumed.coverageGains)
+	}
+	if resumed.profileCoverage["O2"] != 40 {
+		t.Errorf("profileCoverage = %v, want O2: 40", resumed.profileCoverage)
+	}
+	if len(resumed.bugsFound) != 2 || resumed.bugsFound[0].Seed.Meta.ID != 12 || !resumed.knownBug(12) {
+		t.Errorf("bugsFound = %v, want the bugs of seeds 12 and 15", resumed.bugsFound)
+	}
+	// The ICE stays one, so the summary and MinimizeBugs still tell it apart
+	if resumed.uniqueICEs() != 1 || resumed.bugsFound[0].Type != "" {
+		t.Errorf("%d unique ICEs after Resume, want the bug of seed 15 only", resumed.uniqueICEs())
+	}
+	if got, want := resumed.rng.Int63(), engine.rng.Int63(); got != want {
+		t.Errorf("resumed RNG drew %d, want %d", got, want)
+	}
+	if got, want := resumedAnalyzer.GetBBWeight("test_func", 2), analyzer.GetBBWeight("test_func", 2); got != want || got == 1.0 {
+		t.Errorf("BB2 weight = %v, want the decayed %v", got, want)
+	}
+	if target := resumed.selectTarget(); target == nil || target.BBID != 3 {
+		t.Errorf("first target after Resume = %+v, want the interrupted BB3", target)
+	}
+
+	if err := NewEngine(Config{}).Resume(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Errorf("Resume() of a missing checkpoint failed: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(fmt.Sprintf(`{"version": %d}`, CheckpointVersion+1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewEngine(Config{}).Resume(path); err == nil {
+		t.Error("Resume() of a newer checkpoint version succeeded")
+	}
+}
+
+func TestEngine_ResumeContinuesCounters(t *testing.T) {
+	checkpointPath := filepath.Join(t.TempDir(), "engine_checkpoint.json")
+	run := func(maxIterations int, resume bool, seeds ...*seed.Seed) (*Engine, *fixableCompiler) {
+		_, corpusManager := newInitialPhase(t, seeds...)
+		comp := &fixableCompiler{want: "return"}
+		cfg := newDryRunConfig(t)
+		cfg.Corpus = corpusManager
+		cfg.Compiler = comp
+		cfg.MaxIterations = maxIterations
+		cfg.CheckpointPath = checkpointPath
+		engine := NewEngine(cfg)
+		engine.cfg.Analyzer.GetMapping().RecordLine(coverage.LineID{File: "cc.c", Line: 1}, 1)
+		if resume {
+			if err := engine.Resume(checkpointPath); err != nil {
+				t.Fatalf("Resume() failed: %v", err)
+			}
+		}
+		if err := engine.Run(context.Background()); err != nil {
+			t.Fatalf("Run() failed: %v", err)
+		}
+		return engine, comp
+	}
+
+	first, _ := run(2, false)
+	if first.iterationCount != 2 || first.skippedIterations != 2 {
+		t.Fatalf("first run = %d iterations, %d skipped; want 2, 2", first.iterationCount, first.skippedIterations)
+	}
+
+	// Seed 1 is credited by the mapping, so only seed 2 is measured again
+	second, comp := run(3, true,
+		&seed.Seed{Content: "int main() { return 1; }"},
+		&seed.Seed{Content: "int main() { return 2; }"})
+	if second.iterationCount != 3 || second.skippedIterations != 3 {
+		t.Errorf("resumed run = %d iterations, %d skipped; want 3, 3 (one more each)",
+			second.iterationCount, second.skippedIterations)
+	}
+	if comp.calls != 1 {
+		t.Errorf("compiled %d initial seeds, want only the one the mapping does not credit", comp.calls)
+	}
+	if second.priorElapsed <= 0 {
+		t.Error("resumed run did not carry over the time spent")
+	}
+}
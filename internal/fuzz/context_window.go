@@ -0,0 +1,142 @@
+package fuzz
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/zjy-dev/de-fuzz/internal/logger"
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// summaryShareOfLimit: a summarized understanding may take up to 1/4 of the
+// context limit.
+const summaryShareOfLimit = 4
+
+// fitContextWindow makes a (system, user) prompt pair fit the prompt
+// service's context limit. Constraint-solving and refined prompts are
+// already trimmed by the builder; when the pair is still too large, the
+// understanding in the system prompt is replaced by a summary. Prompts are
+// returned unchanged when no limit is known or the summary fails.
+func (e *Engine) fitContextWindow(systemPrompt, userPrompt string) (string, string) {
+	ps := e.cfg.PromptService
+	limit := ps.ContextLimit()
+	if limit <= 0 {
+		return systemPrompt, userPrompt
+	}
+	before := ps.EstimateTokens(systemPrompt) + ps.EstimateTokens(userPrompt)
+	if before <= limit {
+		return systemPrompt, userPrompt
+	}
+
+	understanding := ps.Understanding()
+	if understanding == "" || !strings.Contains(systemPrompt, understanding) {
+		logger.Warn("[Context] prompt is ~%d tokens, over the model limit of %d, and has no understanding left to summarize", before, limit)
+		return systemPrompt, userPrompt
+	}
+	summary, err := e.summarizeUnderstanding(limit/summaryShareOfLimit, limit/summaryShareOfLimit)
+	if err != nil {
+		logger.Warn("[Context] prompt is ~%d tokens, over the model limit of %d, and the understanding could not be summarized: %v", before, limit, err)
+		return systemPrompt, userPrompt
+	}
+	systemPrompt = strings.Replace(systemPrompt, understanding, summary, 1)
+
+	after := ps.EstimateTokens(systemPrompt) + ps.EstimateTokens(userPrompt)
+	logger.Info("[Context] prompt ~%d tokens exceeded the model limit of %d: understanding summarized from ~%d to ~%d tokens, prompt now ~%d",
+		before, limit, ps.EstimateTokens(understanding), ps.EstimateTokens(summary), after)
+	if after > limit {
+		logger.Warn("[Context] prompt is still ~%d tokens, over the model limit of %d", after, limit)
+	}
+	return systemPrompt, userPrompt
+}
+
+// summarizeUnderstanding returns the understanding in use compressed to
+// about targetTokens tokens, and makes system prompts built from now on
+// carry it. The LLM is asked only when no summary of at most maxTokens is
+// known for this understanding: one made earlier in the run, or one saved
+// in UnderstandingDir, where a new summary is saved for resumed runs and
+// the generate command.
+func (e *Engine) summarizeUnderstanding(targetTokens, maxTokens int) (string, error) {
+	ps := e.cfg.PromptService
+	full := ps.Understanding()
+	summary := e.understandingSummary
+	if summary == "" && e.cfg.UnderstandingDir != "" {
+		if saved, err := seed.LoadCompressedUnderstanding(e.cfg.UnderstandingDir, full); err == nil {
+			summary = strings.TrimSpace(saved)
+		}
+	}
+	if summary != "" && ps.EstimateTokens(summary) > maxTokens {
+		summary = ""
+	}
+
+	if summary == "" {
+		systemPrompt, userPrompt := ps.GetUnderstandingSummaryPrompt(targetTokens)
+		var completion string
+		err := e.trackLLM(callSummary, func(ctx context.Context) error {
+			var err error
+			completion, err = e.cfg.LLM.GetCompletionWithSystem(ctx, systemPrompt, userPrompt)
+			return err
+		})
+		if err != nil {
+			return "", err
+		}
+		if summary = strings.TrimSpace(completion); summary == "" {
+			return "", fmt.Errorf("empty summary")
+		}
+		if e.cfg.UnderstandingDir != "" {
+			if err := seed.SaveCompressedUnderstanding(e.cfg.UnderstandingDir, full, summary+"\n"); err != nil {
+				logger.Warn("[Understanding] failed to save the compressed understanding: %v", err)
+			}
+		}
+	}
+
+	e.understandingSummary = summary
+	ps.UseUnderstandingSummary(summary)
+	return summary, nil
+}
+
+// recordUnderstanding adds the understanding this run starts with to the
+// history in UnderstandingDir, so bug bundles can name its version.
+func (e *Engine) recordUnderstanding() {
+	ps := e.cfg.PromptService
+	if e.cfg.UnderstandingDir == "" || ps == nil {
+		return
+	}
+	full := ps.Understanding()
+	if full == "" {
+		return
+	}
+	record, err := seed.RecordUnderstanding(e.cfg.UnderstandingDir, full, "", ps.EstimateTokens(full))
+	if err != nil {
+		logger.Warn("[Understanding] failed to record it in the history: %v", err)
+		return
+	}
+	e.understandingVersion = record.Timestamp
+}
+
+// compressUnderstanding makes system prompts carry a compressed
+// understanding when the full one is over UnderstandingMaxTokens; the full
+// text stays available from the prompt service. The full understanding is
+// kept when compression fails.
+func (e *Engine) compressUnderstanding() {
+	limit := e.cfg.UnderstandingMaxTokens
+	if limit <= 0 || e.cfg.UnderstandingVersion == UnderstandingFull {
+		return
+	}
+	ps := e.cfg.PromptService
+	tokens := ps.EstimateTokens(ps.Understanding())
+	if tokens <= limit {
+		return
+	}
+	target := e.cfg.UnderstandingTargetTokens
+	if target <= 0 {
+		target = limit / 2
+	}
+
+	compressed, err := e.summarizeUnderstanding(target, limit)
+	if err != nil {
+		logger.Warn("[Understanding] ~%d tokens, over %d, but compressing it failed; using the full text: %v", tokens, limit, err)
+		return
+	}
+	logger.Info("[Understanding] compressed from ~%d to ~%d tokens for the system prompt", tokens, ps.EstimateTokens(compressed))
+}
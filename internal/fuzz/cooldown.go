@@ -0,0 +1,72 @@
+package fuzz
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/zjy-dev/de-fuzz/internal/coverage"
+	"github.com/zjy-dev/de-fuzz/internal/logger"
+)
+
+// coolDown passes over target in the next TargetCooldown iterations, after
+// it exhausted its retries: decaying its weight alone may leave it on top.
+func (e *Engine) coolDown(target *coverage.TargetInfo) {
+	if e.cfg.TargetCooldown <= 0 {
+		return
+	}
+	if e.cooldowns == nil {
+		e.cooldowns = make(map[string]int)
+	}
+	e.cooldowns[cooldownKey(target.Function, target.BBID)] = e.iterationCount + e.cfg.TargetCooldown
+	logger.Info("Target %s:BB%d cools down for %d iterations", target.Function, target.BBID, e.cfg.TargetCooldown)
+}
+
+// coolingDown reports whether BB bbID of funcName is passed over in the
+// current iteration.
+func (e *Engine) coolingDown(funcName string, bbID int) bool {
+	return e.iterationCount <= e.cooldowns[cooldownKey(funcName, bbID)]
+}
+
+// expireCooldowns forgets the targets whose cooldown is over and returns
+// how many still cool down.
+func (e *Engine) expireCooldowns() int {
+	for key, last := range e.cooldowns {
+		if last < e.iterationCount {
+			delete(e.cooldowns, key)
+		}
+	}
+	return len(e.cooldowns)
+}
+
+// cooldownsLeft maps the targets cooling down to the iterations they are
+// still passed over in after the current one.
+func (e *Engine) cooldownsLeft() map[string]int {
+	var left map[string]int
+	for key, last := range e.cooldowns {
+		if last > e.iterationCount {
+			if left == nil {
+				left = make(map[string]int)
+			}
+			left[key] = last - e.iterationCount
+		}
+	}
+	return left
+}
+
+func cooldownKey(funcName string, bbID int) string {
+	return fmt.Sprintf("%s:BB%d", funcName, bbID)
+}
+
+// formatCooldowns lists cooldowns as "f:BB3 (2 left), ..." sorted by target.
+func formatCooldowns(cooldowns map[string]int) string {
+	keys := make([]string, 0, len(cooldowns))
+	for key := range cooldowns {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for i, key := range keys {
+		keys[i] = fmt.Sprintf("%s (%d left)", key, cooldowns[key])
+	}
+	return strings.Join(keys, ", ")
+}
@@ -0,0 +1,115 @@
+package fuzz
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/zjy-dev/de-fuzz/internal/coverage"
+	"github.com/zjy-dev/de-fuzz/internal/prompt"
+)
+
+// newTwoTargetAnalyzer returns an analyzer with two entry BBs: hot:BB2 with
+// two successors, which a decay or two leaves on top, and cold:BB2 with one.
+func newTwoTargetAnalyzer(t *testing.T) *coverage.Analyzer {
+	t.Helper()
+	tmpDir := t.TempDir()
+	cfgContent := `;; Function hot (_Z3hotv, funcdef_no=1, decl_uid=100, cgraph_uid=1, symbol_order=1)
+;; 2 succs { 3 4 }
+int hot ()
+{
+  <bb 2> :
+  [/path/to/test.cc:10:3] if (a > b)
+}
+
+;; Function cold (_Z4coldv, funcdef_no=2, decl_uid=200, cgraph_uid=2, symbol_order=2)
+;; 2 succs { 3 }
+int cold ()
+{
+  <bb 2> :
+  [/path/to/test.cc:20:3] return 0;
+}
+`
+	cfgPath := filepath.Join(tmpDir, "test.cc.015t.cfg")
+	if err := os.WriteFile(cfgPath, []byte(cfgContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	analyzer, err := coverage.NewAnalyzer([]string{cfgPath}, []string{"hot", "cold"}, "", filepath.Join(tmpDir, "mapping.json"), 0.8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return analyzer
+}
+
+func TestEngine_TargetCooldown(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		cooldown int
+		want     []string
+	}{
+		{"off", 0, []string{"hot:BB2", "hot:BB2", "hot:BB2", "hot:BB2"}},
+		{"one iteration", 1, []string{"hot:BB2", "cold:BB2", "hot:BB2", "cold:BB2"}},
+		// Both targets cool down from iteration 3 on: the best is chosen anyway
+		{"all cooling down", 2, []string{"hot:BB2", "cold:BB2", "hot:BB2", "hot:BB2"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			baseDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(baseDir, "constraint.md"), []byte("system"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			promptService, err := prompt.NewPromptService(baseDir, "", prompt.NewBuilder(0, "", nil))
+			if err != nil {
+				t.Fatalf("NewPromptService() failed: %v", err)
+			}
+			_, corpusManager := newInitialPhase(t)
+			sink := &targetSink{}
+			// Without an LLM or a base seed to mutate, every target misses
+			engine := NewEngine(Config{
+				Corpus:         corpusManager,
+				Compiler:       &fixableCompiler{want: "main"},
+				Analyzer:       newTwoTargetAnalyzer(t),
+				LLM:            &unavailableLLM{},
+				PromptService:  promptService,
+				MaxIterations:  4,
+				MaxRetries:     1,
+				TargetCooldown: tt.cooldown,
+				EventSinks:     []EventSink{sink},
+			})
+			if err := engine.Run(context.Background()); err != nil {
+				t.Fatalf("Run() failed: %v", err)
+			}
+			if !reflect.DeepEqual(sink.targets, tt.want) {
+				t.Errorf("targets = %q, want %q", sink.targets, tt.want)
+			}
+		})
+	}
+}
+
+func TestEngine_CooldownsLeft(t *testing.T) {
+	engine := NewEngine(Config{TargetCooldown: 3})
+	engine.iterationCount = 5
+	engine.coolDown(&coverage.TargetInfo{Function: "f", BBID: 4})
+	engine.iterationCount = 7
+	if got := engine.cooldownsLeft(); !reflect.DeepEqual(got, map[string]int{"f:BB4": 1}) {
+		t.Errorf("cooldownsLeft() = %v, want f:BB4 passed over once more", got)
+	}
+	if got := formatCooldowns(engine.cooldownsLeft()); got != "f:BB4 (1 left)" {
+		t.Errorf("formatCooldowns() = %q", got)
+	}
+	engine.iterationCount = 9
+	if n := engine.expireCooldowns(); n != 0 || engine.coolingDown("f", 4) {
+		t.Errorf("f:BB4 still cools down in iteration 9")
+	}
+}
+
+// targetSink records the targets selected.
+type targetSink struct {
+	recordingSink
+	targets []string
+}
+
+func (s *targetSink) OnTargetSelected(ev TargetEvent) {
+	s.targets = append(s.targets, cooldownKey(ev.Function, ev.BBID))
+}
@@ -0,0 +1,312 @@
+package fuzz
+
+import (
+	"errors"
+	"time"
+
+	"github.com/zjy-dev/de-fuzz/internal/coverage"
+	"github.com/zjy-dev/de-fuzz/internal/llm"
+	"github.com/zjy-dev/de-fuzz/internal/logger"
+	"github.com/zjy-dev/de-fuzz/internal/oracle"
+	"github.com/zjy-dev/de-fuzz/internal/prompt"
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// Outcomes of coverage-guided iterations, in IterationEvent.Outcome.
+const (
+	OutcomeNewCoverage = "new coverage" // The mutated seed raised coverage
+	OutcomeNoGain      = "no gain"      // It did not, or did not compile
+)
+
+// coverageGuided reports whether the engine runs without a CFG: with no
+// Analyzer there are no target BBs, so Run falls back to plain
+// coverage-guided fuzzing (see runCoverageGuided).
+func (e *Engine) coverageGuided() bool {
+	return e.cfg.Analyzer == nil
+}
+
+// coverageBasisPoints returns the coverage so far in basis points: BB
+// coverage of the target functions, or without a CFG the line coverage
+// Coverage reports.
+func (e *Engine) coverageBasisPoints() uint64 {
+	if e.cfg.Analyzer != nil {
+		return e.cfg.Analyzer.GetBBCoverageBasisPoints()
+	}
+	if e.cfg.Coverage == nil {
+		return 0
+	}
+	stats, err := e.cfg.Coverage.GetStats()
+	if err != nil {
+		return 0
+	}
+	return uint64(stats.CoveragePercentage * 100)
+}
+
+// coverageStats returns the total coverage statistics of Coverage.
+func (e *Engine) coverageStats() (*coverage.CoverageStats, error) {
+	if e.cfg.Coverage == nil {
+		return nil, errors.New("no coverage backend")
+	}
+	return e.cfg.Coverage.GetStats()
+}
+
+// recordInitialCoverage records the coverage of the initial seed s: in the
+// Analyzer's mapping, or without a CFG in the total coverage if
+// Coverage.HasIncreased.
+func (e *Engine) recordInitialCoverage(s *seed.Seed, report coverage.Report) {
+	if e.cfg.Analyzer != nil {
+		e.cfg.Analyzer.RecordCoverage(int64(s.Meta.ID), e.extractCoveredLines(report))
+		e.syncFavored()
+		return
+	}
+	increased, err := e.cfg.Coverage.HasIncreased(report)
+	if err != nil {
+		logger.Warn("Failed to compare coverage of initial seed %d: %v", s.Meta.ID, err)
+		return
+	}
+	if !increased {
+		return
+	}
+	if inc, err := e.cfg.Coverage.GetIncrease(report); err == nil {
+		e.lastIncrease = inc
+	}
+	if err := e.cfg.Coverage.Merge(report); err != nil {
+		logger.Warn("Failed to merge coverage of initial seed %d: %v", s.Meta.ID, err)
+	}
+}
+
+// runCoverageGuided is the loop of Run without a CFG: each iteration
+// mutates a corpus seed picked by energy with the mutation prompt and keeps
+// the result if Coverage.HasIncreased says it covered new lines, or if it
+// triggered a bug.
+func (e *Engine) runCoverageGuided() {
+	logger.Info("No CFG: coverage-guided fuzzing without target BBs")
+	if e.cfg.DryRunPrompts {
+		logger.Warn("Dry run renders constraint prompts, which need a CFG; nothing to render")
+		e.stopReason = "dry run without CFG"
+		return
+	}
+	for {
+		if err := e.ctx.Err(); err != nil {
+			logger.Info("Fuzzing interrupted: %v", err)
+			e.stopReason = "interrupted"
+			return
+		}
+		if e.cfg.MaxIterations > 0 && e.iterationCount >= e.cfg.MaxIterations {
+			logger.Info("Reached max iterations (%d), stopping", e.cfg.MaxIterations)
+			e.stopReason = "max iterations reached"
+			return
+		}
+		if e.budgetSpent() {
+			logger.Info("Time budget (%v) spent, stopping", e.cfg.MaxDuration)
+			e.stopReason = "time budget spent"
+			return
+		}
+
+		baseSeed := e.energySeed()
+		if baseSeed == nil {
+			logger.Warn("No corpus seed to mutate, stopping")
+			e.stopReason = "no seed to mutate"
+			return
+		}
+
+		e.iterationCount++
+		iterationStart := time.Now()
+		startEvent := IterationEvent{Iteration: e.iterationCount}
+		e.emit(func(sink EventSink) { sink.OnIterationStart(startEvent) })
+		e.retries = 0
+		e.beginIteration()
+		e.publishProgress("coverage")
+
+		logger.Info("Iteration %d: Mutating seed %d (energy %.2f)", e.iterationCount, baseSeed.Meta.ID, baseSeed.Meta.Energy)
+		outcome := OutcomeNoGain
+		result, err := e.mutateForCoverage(baseSeed)
+		baseSeed.Release()
+		switch {
+		case err != nil:
+			logger.Warn("Mutating seed %d failed: %v", baseSeed.Meta.ID, err)
+		case result.CoveredNew:
+			outcome = OutcomeNewCoverage
+			e.coverageGains++
+		}
+		if e.ctx.Err() != nil && err != nil {
+			e.stopReason = "interrupted"
+			e.endIteration(iterationStart, OutcomeInterrupted)
+			return
+		}
+
+		if e.iterationCount%10 == 0 {
+			e.saveState()
+		}
+		e.cfg.Metrics.IterationDone(time.Since(iterationStart))
+		e.endIteration(iterationStart, outcome)
+		e.publishProgress("coverage")
+	}
+}
+
+// energySeed returns a corpus seed not marked invalid, sampled by energy
+// and loaded, or nil when there is none. Seeds with no energy yet, like
+// the ones this mode adds, weigh 1, as fresh seeds score under every
+// schedule.
+func (e *Engine) energySeed() *seed.Seed {
+	var candidates []*seed.Seed
+	total := 0.0
+	seeds := e.cfg.Corpus.Seeds(func(s *seed.Seed) bool { return s.Meta.State != seed.SeedStateInvalid })
+	for s, ok := seeds.Next(); ok; s, ok = seeds.Next() {
+		candidates = append(candidates, s)
+		total += seedEnergy(s)
+	}
+	for len(candidates) > 0 {
+		pick := e.rng.Float64() * total
+		i := 0
+		for ; i < len(candidates)-1; i++ {
+			if pick -= seedEnergy(candidates[i]); pick < 0 {
+				break
+			}
+		}
+		s := candidates[i]
+		err := s.Load()
+		if err == nil {
+			return s
+		}
+		logger.Warn("Skipping seed %d: %v", s.Meta.ID, err)
+		total -= seedEnergy(s)
+		candidates = append(candidates[:i], candidates[i+1:]...)
+	}
+	return nil
+}
+
+// seedEnergy is the weight energySeed samples s with.
+func seedEnergy(s *seed.Seed) float64 {
+	if s.Meta.Energy > 0 {
+		return s.Meta.Energy
+	}
+	return 1
+}
+
+// mutateForCoverage asks the LLM to mutate baseSeed, with the coverage so
+// far and the last increase as context, and tries the mutated seed.
+func (e *Engine) mutateForCoverage(baseSeed *seed.Seed) (*seedTryResult, error) {
+	mutationCtx := &prompt.MutationContext{
+		TotalCoveragePercentage: float64(e.coverageBasisPoints()) / 100.0,
+	}
+	if stats, err := e.coverageStats(); err == nil {
+		mutationCtx.TotalCoveredLines = stats.TotalCoveredLines
+		mutationCtx.TotalLines = stats.TotalLines
+	}
+	if e.lastIncrease != nil {
+		mutationCtx.CoverageIncreaseSummary = e.lastIncrease.Summary
+		mutationCtx.CoverageIncreaseDetails = e.lastIncrease.FormattedReport
+	}
+	if depth := e.cfg.PromptService.LineageDepth(); depth > 0 {
+		mutationCtx.Ancestors = e.cfg.Corpus.AncestorSeeds(baseSeed.Meta.ID, depth)
+	}
+	systemPrompt, userPrompt, err := e.cfg.PromptService.GetMutatePrompt(baseSeed, mutationCtx)
+	if err != nil {
+		return nil, err
+	}
+	e.logPromptDebug("mutate", systemPrompt, userPrompt)
+
+	e.llmScope = llm.CallInfo{SeedID: baseSeed.Meta.ID}
+	defer func() { e.llmScope = llm.CallInfo{} }()
+	completion, err := e.completeSeed(callMutate, systemPrompt, userPrompt)
+	if err != nil {
+		return nil, err
+	}
+	mutated, err := e.cfg.PromptService.ParseLLMResponse(completion)
+	if err != nil {
+		return nil, err
+	}
+	mutated.Meta.ID = e.cfg.Corpus.AllocateID()
+	mutated.Meta.ParentID = baseSeed.Meta.ID
+	mutated.Meta.CreatedAt = time.Now()
+	mutated.Meta.MutationNote = seed.SummarizeDiff(baseSeed.Content, mutated.Content, mutated.Language)
+	return e.tryCoverageSeed(mutated)
+}
+
+// tryCoverageSeed is tryMutatedSeed without a target: it compiles and
+// measures s, merges its coverage when Coverage.HasIncreased, runs the
+// oracle and adds s to the corpus if it raised coverage or found a bug.
+func (e *Engine) tryCoverageSeed(s *seed.Seed) (*seedTryResult, error) {
+	start := time.Now()
+	result := &seedTryResult{SeedCode: s.Content}
+	e.assignDefaultProfile(s)
+	defer e.noteSeedTried(s, result)
+	if e.rejectDefenseDisabling(s, result) {
+		return result, nil
+	}
+
+	report, compileResult, err := e.measureSeed(s, e.compileWithFixes)
+	result.SeedCode = s.Content
+	result.Compile = compileClass(compileResult, err)
+	if errors.Is(err, errCompile) {
+		result.CompileFailed = true
+		result.CompileError = err.Error()
+		return result, nil
+	}
+	if err != nil {
+		return result, err
+	}
+	if !compileResult.Success {
+		result.CompileFailed = true
+		result.CompileError = compileResult.Stderr
+		return result, nil
+	}
+	if report == nil {
+		return result, nil
+	}
+
+	oldBasisPoints := e.coverageBasisPoints()
+	increased, err := e.cfg.Coverage.HasIncreased(report)
+	if err != nil {
+		return result, err
+	}
+	if increased {
+		if inc, err := e.cfg.Coverage.GetIncrease(report); err == nil {
+			e.lastIncrease = inc
+		}
+		if err := e.cfg.Coverage.Merge(report); err != nil {
+			return result, err
+		}
+	}
+	result.CoveredNew = increased
+
+	var bug *oracle.Bug
+	result.OracleVerdict = seed.OracleVerdictSkipped
+	if e.oracleEnabled() {
+		result.OracleVerdict = seed.OracleVerdictNormal
+		if bug = e.runOracle(s, compileResult); bug != nil {
+			result.OracleVerdict = seed.OracleVerdictBug
+			result.BugDescription = bug.Description
+			logger.Info("Seed %d triggered bug: %s", s.Meta.ID, bug.Description)
+		}
+	}
+
+	newBasisPoints := e.coverageBasisPoints()
+	s.Meta.OracleVerdict = result.OracleVerdict
+	s.Meta.CompileOK = true
+	s.Meta.DurationMs = time.Since(start).Milliseconds()
+	s.Meta.OldCoverage = oldBasisPoints
+	s.Meta.NewCoverage = newBasisPoints
+	if newBasisPoints > oldBasisPoints {
+		s.Meta.CovIncrease = newBasisPoints - oldBasisPoints
+		e.noteCoverageGain(s, oldBasisPoints, newBasisPoints)
+	}
+
+	if !increased && bug == nil {
+		return result, nil
+	}
+	if added, err := e.addToCorpus(s); err != nil {
+		logger.Warn("Failed to add seed to corpus: %v", err)
+	} else if added {
+		e.persistCompilationRecord(s, compileResult)
+		e.saveMinimizedSource(s, bug)
+		e.exportBundle(s, bug, compileResult)
+		reason := "coverage"
+		if bug != nil {
+			reason = "bug"
+		}
+		logger.Info("Added seed %d to corpus (reason: %s, cov: %d -> %d bp)", s.Meta.ID, reason, oldBasisPoints, newBasisPoints)
+	}
+	return result, nil
+}
@@ -0,0 +1,159 @@
+package fuzz
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/zjy-dev/de-fuzz/internal/coverage"
+	"github.com/zjy-dev/de-fuzz/internal/llm"
+	"github.com/zjy-dev/de-fuzz/internal/prompt"
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+func TestEngine_CoverageGuidedWithoutCFG(t *testing.T) {
+	baseDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(baseDir, "mutate.md"), []byte("system"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	promptService, err := prompt.NewPromptService(baseDir, "", prompt.NewBuilder(0, "", nil))
+	if err != nil {
+		t.Fatalf("NewPromptService() failed: %v", err)
+	}
+	_, corpusManager := newInitialPhase(t, &seed.Seed{Content: "int main() { return 0; } /* L1 */"})
+	client := &replayLLM{replies: []string{
+		"```c\nint main() { return 1; } /* L1 */ /* L2 */\n```",
+		"```c\nint main() { return 2; } /* L1 */\n```",
+		"```c\nint f() { return x; } /* L3 */\n```",
+	}}
+	sink := &recordingSink{}
+	engine := NewEngine(Config{
+		Corpus:        corpusManager,
+		Compiler:      &fixableCompiler{want: "main"},
+		Coverage:      &lineCoverage{total: 4, covered: map[int]bool{}},
+		LLM:           client,
+		PromptService: promptService,
+		MaxIterations: 3,
+		MaxRetries:    4,
+		EventSinks:    []EventSink{sink},
+	})
+	if err := engine.Run(context.Background()); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	var outcomes []string
+	for _, ev := range sink.recorded() {
+		if strings.HasPrefix(ev, "iteration_end") || strings.HasPrefix(ev, "target_selected") {
+			outcomes = append(outcomes, ev)
+		}
+	}
+	want := []string{"iteration_end 1 new coverage", "iteration_end 2 no gain", "iteration_end 3 no gain"}
+	if !reflect.DeepEqual(outcomes, want) {
+		t.Errorf("iterations = %q, want %q and no target selected", outcomes, want)
+	}
+	if engine.coverageGains != 1 || engine.coverageBasisPoints() != 5000 {
+		t.Errorf("%d coverage gains, coverage %d bp; want 1 gain and 5000 bp", engine.coverageGains, engine.coverageBasisPoints())
+	}
+
+	// Only the seed that covered line 2 joined the corpus, as a child of
+	// the initial seed
+	var added []*seed.Seed
+	seeds := corpusManager.Seeds(func(s *seed.Seed) bool { return s.Meta.ParentID != 0 })
+	for s, ok := seeds.Next(); ok; s, ok = seeds.Next() {
+		added = append(added, s)
+	}
+	if len(added) != 1 || added[0].Meta.ParentID != 1 || added[0].Meta.CovIncrease != 2500 {
+		t.Fatalf("added seeds = %+v, want one child of seed 1 gaining 2500 bp", added)
+	}
+
+	// The mutation prompts carry the coverage so far and the last increase,
+	// the initial seed's at first
+	if len(client.prompts) != 3 {
+		t.Fatalf("%d prompts, want one per iteration", len(client.prompts))
+	}
+	for i, want := range []string{"25.0% (1/4 lines)", "50.0% (2/4 lines)"} {
+		if !strings.Contains(client.prompts[i], "Current coverage: "+want) ||
+			!strings.Contains(client.prompts[i], "1 line(s) newly covered") {
+			t.Errorf("prompt %d = %q, want coverage %s and the last increase", i+1, client.prompts[i], want)
+		}
+	}
+}
+
+// replayLLM answers each request with the next of replies and records the
+// user prompts.
+type replayLLM struct {
+	llm.LLM
+	replies []string
+	prompts []string
+}
+
+func (m *replayLLM) GetCompletionWithSystem(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	m.prompts = append(m.prompts, userPrompt)
+	if len(m.prompts) > len(m.replies) {
+		return "", fmt.Errorf("no reply left for request %d", len(m.prompts))
+	}
+	return m.replies[len(m.prompts)-1], nil
+}
+
+// lineCoverage covers the lines a seed names in /* Ln */ comments, out of
+// total.
+type lineCoverage struct {
+	coverage.Coverage
+	total   int
+	covered map[int]bool
+}
+
+type linesReport []int
+
+func (r linesReport) ToBytes() ([]byte, error) { return []byte(fmt.Sprint([]int(r))), nil }
+
+var lineMarker = regexp.MustCompile(`/\* L(\d+) \*/`)
+
+func (c *lineCoverage) MeasureCompiled(s *seed.Seed) (coverage.Report, error) {
+	var lines linesReport
+	for _, m := range lineMarker.FindAllStringSubmatch(s.Content, -1) {
+		n, _ := strconv.Atoi(m[1])
+		lines = append(lines, n)
+	}
+	return lines, nil
+}
+
+func (c *lineCoverage) newLines(report coverage.Report) int {
+	n := 0
+	for _, line := range report.(linesReport) {
+		if !c.covered[line] {
+			n++
+		}
+	}
+	return n
+}
+
+func (c *lineCoverage) HasIncreased(report coverage.Report) (bool, error) {
+	return c.newLines(report) > 0, nil
+}
+
+func (c *lineCoverage) GetIncrease(report coverage.Report) (*coverage.CoverageIncrease, error) {
+	n := c.newLines(report)
+	return &coverage.CoverageIncrease{Summary: fmt.Sprintf("%d line(s) newly covered", n), NewlyCoveredLines: n}, nil
+}
+
+func (c *lineCoverage) Merge(report coverage.Report) error {
+	for _, line := range report.(linesReport) {
+		c.covered[line] = true
+	}
+	return nil
+}
+
+func (c *lineCoverage) GetStats() (*coverage.CoverageStats, error) {
+	return &coverage.CoverageStats{
+		CoveragePercentage: float64(len(c.covered)) / float64(c.total) * 100,
+		TotalLines:         c.total,
+		TotalCoveredLines:  len(c.covered),
+	}, nil
+}
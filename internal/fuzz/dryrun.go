@@ -0,0 +1,204 @@
+package fuzz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/zjy-dev/de-fuzz/internal/compiler"
+	"github.com/zjy-dev/de-fuzz/internal/logger"
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// DryRunReport records the stages of a dry run (fuzz.dry_run): how long
+// each took and why the one that failed did. A dry run stops at the first
+// failed stage.
+type DryRunReport struct {
+	Stages       []DryRunStage `json:"stages"`
+	SeedID       uint64        `json:"seed_id,omitempty"`
+	CoveredLines int           `json:"covered_lines,omitempty"`
+	Bug          string        `json:"bug,omitempty"`
+	Failure      string        `json:"failure,omitempty"`
+}
+
+// DryRunStage is one stage of a dry run. Field is the config key to check
+// when the stage failed.
+type DryRunStage struct {
+	Name       string  `json:"name"`
+	DurationMs float64 `json:"duration_ms"`
+	Skipped    string  `json:"skipped,omitempty"`
+	Error      string  `json:"error,omitempty"`
+	Field      string  `json:"field,omitempty"`
+}
+
+// configError is an error a config field is the place to fix.
+type configError struct {
+	field string
+	err   error
+}
+
+func (e *configError) Error() string { return fmt.Sprintf("%v (check %s)", e.err, e.field) }
+func (e *configError) Unwrap() error { return e.err }
+
+// ConfigError blames err on the config field, which its message names, so
+// the dry run report points there. A nil err stays nil.
+func ConfigError(field string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &configError{field: field, err: err}
+}
+
+// Time records the stage name that started at start and ended with err,
+// and returns err naming the stage.
+func (r *DryRunReport) Time(name string, start time.Time, err error) error {
+	stage := DryRunStage{
+		Name:       name,
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err == nil {
+		r.Stages = append(r.Stages, stage)
+		return nil
+	}
+	stage.Error = err.Error()
+	var cfgErr *configError
+	if errors.As(err, &cfgErr) {
+		stage.Field = cfgErr.field
+	}
+	r.Stages = append(r.Stages, stage)
+	r.Failure = name
+	return fmt.Errorf("dry run failed at %s: %w", name, err)
+}
+
+// Skip records that stage name did not run, and why.
+func (r *DryRunReport) Skip(name, why string) {
+	r.Stages = append(r.Stages, DryRunStage{Name: name, Skipped: why})
+}
+
+// Passed reports whether no stage failed.
+func (r *DryRunReport) Passed() bool {
+	return r.Failure == ""
+}
+
+// Summary lists the stages and their timings, one per line, for the log.
+func (r *DryRunReport) Summary() string {
+	var b strings.Builder
+	if r.Passed() {
+		b.WriteString("Dry run passed\n")
+	} else {
+		fmt.Fprintf(&b, "Dry run FAILED at %s\n", r.Failure)
+	}
+	for _, stage := range r.Stages {
+		switch {
+		case stage.Skipped != "":
+			fmt.Fprintf(&b, "  %-20s skipped: %s\n", stage.Name, stage.Skipped)
+		case stage.Error != "":
+			fmt.Fprintf(&b, "  %-20s %10.1fms  FAILED\n", stage.Name, stage.DurationMs)
+		default:
+			fmt.Fprintf(&b, "  %-20s %10.1fms  ok\n", stage.Name, stage.DurationMs)
+		}
+	}
+	if r.SeedID != 0 {
+		fmt.Fprintf(&b, "  seed %d: %d target lines covered", r.SeedID, r.CoveredLines)
+		if r.Bug != "" {
+			fmt.Fprintf(&b, ", oracle reported: %s", r.Bug)
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// Write saves the report to path as JSON.
+func (r *DryRunReport) Write(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode dry run report: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to write dry run report: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write dry run report: %w", err)
+	}
+	return nil
+}
+
+// DryRun exercises the pipeline once instead of Run, without LLM calls: it
+// compiles and measures the first valid corpus seed, runs the oracle on its
+// binary and records the stages in report. The error names the failed stage
+// and the config field to check.
+func (e *Engine) DryRun(ctx context.Context, report *DryRunReport) error {
+	e.ctx = ctx
+	e.startTime = time.Now()
+
+	start := time.Now()
+	s, err := e.dryRunSeed()
+	if err := report.Time("select seed", start, err); err != nil {
+		return err
+	}
+	defer s.Release()
+	report.SeedID = s.Meta.ID
+
+	start = time.Now()
+	compileResult, err := e.dryRunMeasure(s, report)
+	if err := report.Time("compile and measure", start, err); err != nil {
+		return err
+	}
+
+	switch {
+	case !e.oracleEnabled():
+		report.Skip("oracle", "no oracle runs on seeds (compiler.oracle.type, fuzz.execute_seeds)")
+	case e.cfg.OracleType == "llm":
+		report.Skip("oracle", "the llm oracle calls the LLM")
+	default:
+		start = time.Now()
+		bug, err := e.checkOracle(s, compileResult)
+		if bug != nil {
+			report.Bug = bug.Description
+		}
+		if err := report.Time("oracle", start, ConfigError("compiler.oracle, fuzz.use_qemu", err)); err != nil {
+			return err
+		}
+	}
+	logger.Info("Dry run of seed %d finished in %v", s.Meta.ID, e.elapsed())
+	return nil
+}
+
+// dryRunSeed returns the first corpus seed not marked invalid.
+func (e *Engine) dryRunSeed() (*seed.Seed, error) {
+	if e.cfg.Corpus != nil {
+		seeds := e.cfg.Corpus.Seeds(nil)
+		for s, ok := seeds.Next(); ok; s, ok = seeds.Next() {
+			if s.Meta.State != seed.SeedStateInvalid {
+				return s, nil
+			}
+		}
+	}
+	return nil, ConfigError("fuzz.output_root_dir", errors.New("the corpus holds no valid seed, run 'defuzz generate' first"))
+}
+
+// dryRunMeasure compiles s, without compile fixes, and measures its coverage
+// with measureSeed.
+func (e *Engine) dryRunMeasure(s *seed.Seed, report *DryRunReport) (*compiler.CompileResult, error) {
+	cov, compileResult, err := e.measureSeed(s, e.cfg.Compiler.Compile)
+	switch {
+	case errors.Is(err, errCompile):
+		return nil, ConfigError("compiler.path", err)
+	case err != nil:
+		return nil, ConfigError("compiler.gcovr_exec_path, compiler.gcovr_command", err)
+	case compileResult.InternalError() != "":
+		return nil, ConfigError("compiler.path", fmt.Errorf("seed %d crashed the compiler: %s", s.Meta.ID, compileResult.InternalError()))
+	case !compileResult.Success:
+		reason, _, _ := strings.Cut(strings.TrimSpace(compileResult.Stderr), "\n")
+		return nil, ConfigError("compiler.cflags", fmt.Errorf("seed %d does not compile: %s", s.Meta.ID, reason))
+	case e.cfg.Coverage != nil && cov == nil:
+		return nil, ConfigError("compiler.gcovr_command", fmt.Errorf("no coverage report for seed %d", s.Meta.ID))
+	}
+	report.CoveredLines = len(e.extractCoveredLines(cov))
+	return compileResult, nil
+}
@@ -0,0 +1,100 @@
+package fuzz
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+func TestEngine_DryRun(t *testing.T) {
+	_, corpusManager := newInitialPhase(t,
+		&seed.Seed{Content: "int main(int argc, char **argv) { char buf[8]; strcpy(buf, argv[1]); }"})
+	cov := &seedRecordingCoverage{}
+	client := &unavailableLLM{}
+	engine := NewEngine(Config{
+		Corpus:     corpusManager,
+		Compiler:   &fixableCompiler{want: "main"},
+		Coverage:   cov,
+		Oracle:     overflowOracle{},
+		OracleType: "canary",
+		LLM:        client,
+	})
+
+	report := &DryRunReport{}
+	if err := engine.DryRun(context.Background(), report); err != nil {
+		t.Fatalf("DryRun() failed: %v", err)
+	}
+	var stages []string
+	for _, stage := range report.Stages {
+		stages = append(stages, stage.Name)
+	}
+	if got := strings.Join(stages, ", "); got != "select seed, compile and measure, oracle" {
+		t.Errorf("stages = %s", got)
+	}
+	if !report.Passed() || report.SeedID != 1 || report.Bug != "overflow" {
+		t.Errorf("report = %+v, want seed 1 passing with the overflow found", report)
+	}
+	if len(cov.measured) != 1 || client.calls != 0 {
+		t.Errorf("measured %d seeds with %d LLM calls, want 1 seed and no calls", len(cov.measured), client.calls)
+	}
+
+	path := filepath.Join(t.TempDir(), "dry_run_report.json")
+	if err := report.Write(path); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written DryRunReport
+	if err := json.Unmarshal(data, &written); err != nil || len(written.Stages) != 3 {
+		t.Errorf("written report = %s (%v)", data, err)
+	}
+}
+
+func TestEngine_DryRunFailures(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		seeds  []*seed.Seed
+		stage  string
+		field  string
+		reason string
+	}{
+		{"empty corpus", nil, "select seed", "fuzz.output_root_dir", "defuzz generate"},
+		{"seed does not compile", []*seed.Seed{{Content: "int f() { return x; }"}},
+			"compile and measure", "compiler.cflags", "'x' undeclared"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, corpusManager := newInitialPhase(t, tt.seeds...)
+			engine := NewEngine(Config{
+				Corpus:   corpusManager,
+				Compiler: &fixableCompiler{want: "main"},
+				Coverage: &seedRecordingCoverage{},
+				LLM:      &unavailableLLM{},
+			})
+
+			report := &DryRunReport{}
+			err := engine.DryRun(context.Background(), report)
+			if err == nil {
+				t.Fatal("DryRun() passed")
+			}
+			for _, want := range []string{tt.stage, "check " + tt.field, tt.reason} {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not name %q", err, want)
+				}
+			}
+			last := report.Stages[len(report.Stages)-1]
+			if report.Failure != tt.stage || last.Field != tt.field {
+				t.Errorf("report failed at %q blaming %q, want %q and %q", report.Failure, last.Field, tt.stage, tt.field)
+			}
+			if !strings.Contains(report.Summary(), "FAILED at "+tt.stage) {
+				t.Errorf("Summary() = %q", report.Summary())
+			}
+		})
+	}
+}
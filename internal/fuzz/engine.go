@@ -2,8 +2,15 @@
 package fuzz
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"math/rand"
+	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/zjy-dev/de-fuzz/internal/compiler"
@@ -40,40 +47,159 @@ type Config struct {
 	// Prompt service for unified prompt management
 	PromptService *prompt.PromptService
 
+	// ResponseCache remembers completions of opening prompts so a target that
+	// is re-selected with the same base seed does not repeat an identical
+	// request (optional). CacheOnHit decides what happens on a hit.
+	ResponseCache *llm.ResponseCache
+	CacheOnHit    CacheHitPolicy
+
 	// Fuzzing parameters
-	MaxIterations   int           // Maximum iterations (0 = unlimited)
-	MaxRetries      int           // Max retries per target BB with divergence analysis
-	SaveInterval    time.Duration // State save interval
-	CoverageTimeout int           // Coverage measurement timeout in seconds
-	MappingPath     string        // Path to save/load coverage mapping
+	MaxIterations        int           // Maximum iterations (0 = unlimited)
+	MaxDuration          time.Duration // Wall-clock budget of the campaign (0 = unlimited)
+	MaxRetries           int           // Max retries per target BB with divergence analysis
+	TargetCooldown       int           // Iterations a target that exhausted its retries is passed over (0 = off)
+	MaxCompileFixRetries int           // Max repair prompts for a seed that fails to compile (0 = off)
+	Conversation         bool          // Keep one chat session per target; retries send only what changed
+	Stream               bool          // Stream seed completions and stop reading once they are complete
+	StreamMaxTokens      int           // Abandon a streamed completion past about this many tokens (0 = no cap)
+	SaveInterval         time.Duration // State save interval
+	CoverageTimeout      int           // Coverage measurement timeout in seconds
+	MappingPath          string        // Path to save/load coverage mapping
+	UsagePath            string        // Path to save/load LLM token usage totals (optional)
+	CheckpointPath       string        // Path to save the engine checkpoint Resume reads (optional)
+	LineagePath          string        // Path to write the seed lineage as Graphviz DOT when fuzzing ends (optional)
+	TrimEvery            int           // Archive coverage-subsumed seeds every this many iterations (0 = never)
+	ProgressInterval     time.Duration // Log progress and write StatusPath this often (0 = never)
+	StatusPath           string        // Path of the JSON status file progress reports write (optional)
+
+	// TargetSchedule shares the iterations between the target functions
+	// ("" = ScheduleWeighted).
+	TargetSchedule TargetSchedule
+
+	// AdaptiveRetries scales MaxRetries per target by its weight and the
+	// rolling hit rate; see retriesFor.
+	AdaptiveRetries bool
+
+	// An understanding over UnderstandingMaxTokens (0 = never) is compressed
+	// to about UnderstandingTargetTokens (0 = half of it) before the loop
+	// starts, and both versions are saved in UnderstandingDir (optional).
+	// UnderstandingVersion picks the one sent as the per-call system prompt.
+	UnderstandingMaxTokens    int
+	UnderstandingTargetTokens int
+	UnderstandingDir          string
+	UnderstandingVersion      UnderstandingVersion
+
+	// MinimizeBugs shrinks every bug-triggering seed with seed.Minimize
+	// before the bug is recorded: each candidate is compiled and the oracle
+	// must still report a bug. The corpus keeps the original seed; the
+	// minimized source is saved next to it.
+	MinimizeBugs      bool
+	MinimizeMaxChecks int           // Candidates checked per bug seed (0 = seed.DefaultMinimizeMaxChecks)
+	MinimizeTimeout   time.Duration // Time spent minimizing one bug seed (0 = no limit)
+
+	// BugsDir receives a reproduction bundle, <BugsDir>/<seedID>/bundle.tar.gz,
+	// for every bug-triggering seed added to the corpus (optional). Bundle
+	// holds the target settings (QEMU, run environment, oracle) copied into
+	// each bundle; the compiler and flags come from the seed's compilation.
+	BugsDir string
+	Bundle  seed.BundleMeta
 
 	// OracleType is the oracle type name (e.g. "canary", "ibt") used to select
 	// the defense-flag denylist when checking LLM-emitted CFlags.
 	OracleType string
 
+	// Metrics receives iteration, compile and bug measurements as they are
+	// made, e.g. for a metrics endpoint (optional).
+	Metrics Metrics
+
+	// EventSinks observe the loop (optional); each call to a sink is
+	// abandoned after EventSinkTimeout (0 = DefaultEventSinkTimeout).
+	EventSinks       []EventSink
+	EventSinkTimeout time.Duration
+
 	// Oracle executor for cross-architecture execution (e.g., QEMU)
 	// If nil, uses OracleExecutorAdapter with local execution
 	OracleExecutor oracle.Executor
 
+	// ReferenceCompiler builds every seed the oracle analyzes a second time
+	// for differential testing (optional); the oracle gets the build as
+	// AnalyzeContext.Reference. Its binaries run on ReferenceExecutor, or
+	// locally when that is nil. Coverage is measured on Compiler's builds only.
+	ReferenceCompiler compiler.Compiler
+	ReferenceExecutor oracle.Executor
+
+	// ExecuteSeeds controls whether seed binaries are executed (default: auto).
+	// Coverage never depends on it since .gcda files come from compilation.
+	ExecuteSeeds ExecuteMode
+
+	// DryRunPrompts renders each target's prompts into DryRunDir instead of
+	// calling the LLM; the iteration is counted as skipped.
+	DryRunPrompts bool
+	DryRunDir     string
+
+	// InitialSeedFilter restricts the initial phase to the queued seeds it
+	// selects, e.g. seed.ByLanguage(seed.LanguageC) when the compiler
+	// cannot build the other languages (optional). The rest stay queued.
+	InitialSeedFilter seed.Filter
+
 	// Random Mutation Phase (activated when coverage is saturated)
 	EnableRandomPhase   bool // Enable random mutation phase after coverage saturation
 	MaxRandomIterations int  // Maximum iterations in random phase (0 = unlimited)
 }
 
+// ExecuteMode selects when compiled seed binaries are executed.
+type ExecuteMode string
+
+const (
+	// ExecuteAuto executes seeds only when the oracle needs runtime results.
+	ExecuteAuto ExecuteMode = "auto"
+	// ExecuteAlways hands the oracle an executor whenever it runs.
+	ExecuteAlways ExecuteMode = "always"
+	// ExecuteNever never executes seeds; oracles that need execution are skipped.
+	ExecuteNever ExecuteMode = "never"
+)
+
+// CacheHitPolicy selects how a ResponseCache hit is handled.
+type CacheHitPolicy string
+
+const (
+	// CachePerturb re-asks with a note requesting a different approach.
+	CachePerturb CacheHitPolicy = "perturb"
+	// CacheReuse reuses the cached completion without calling the LLM.
+	CacheReuse CacheHitPolicy = "reuse"
+)
+
+// UnderstandingVersion selects which understanding system prompts carry.
+type UnderstandingVersion string
+
+const (
+	// UnderstandingCompressed uses the compressed understanding once the
+	// full one is over UnderstandingMaxTokens (the default).
+	UnderstandingCompressed UnderstandingVersion = "compressed"
+	// UnderstandingFull always uses the full understanding.
+	UnderstandingFull UnderstandingVersion = "full"
+)
+
 // Maximum number of debug log calls per prompt type
 const maxPromptDebugLogs = 3
 
 // Engine implements constraint solving based fuzzing.
 type Engine struct {
 	cfg            Config
+	ctx            context.Context // Bounds every LLM request; set by Run
 	iterationCount int
-	targetHits     int // Number of times we successfully hit a target
-	bugsFound      []*oracle.Bug
+	targetHits     int           // Number of times we successfully hit a target
+	bugsFound      []*oracle.Bug // Unique bugs; see recordBug
+	bugSignatures  map[string]*oracle.Bug
+	bugBundles     map[string]*bugBundle // Exported bundles of bugsFound, by signature
+	sinks          []*sinkRunner
 	startTime      time.Time
+	priorElapsed   time.Duration // Campaign time spent by earlier runs, from the checkpoint
+	stopReason     string        // Why the loop ended, for the summary and checkpoint
 
-	// Paths for divergence analysis
-	currentBaseSeedPath    string
-	currentMutatedSeedPath string
+	// Scratch directory of the current iteration, holding the seed sources
+	// handed to the divergence analyzer; see scratchDir.
+	scratch string
 
 	// Prompt debug log counters (to limit verbose output)
 	promptDebugCount map[string]int
@@ -81,6 +207,81 @@ type Engine struct {
 	// Lightweight profile aggregation for run summaries.
 	profileCoverage map[string]int
 	profileBugs     map[string]int
+
+	skippedIterations int // Iterations that only rendered prompts (dry run)
+	duplicateSeeds    int // Seeds the corpus rejected as duplicates
+	trimmedSeeds      int // Seeds archived by corpus trimming
+	fallbackSeeds     int // Seeds made by LLM-free mutations
+	ices              int // Seeds the compiler crashed on
+
+	// cooldowns maps the targets that exhausted their retries, as
+	// "func:BBn", to the last iteration they are passed over in.
+	cooldowns map[string]int
+
+	// rendered holds the targets a dry run rendered prompts for, as
+	// "func:BBn": each is rendered once, then the run moves on.
+	rendered map[string]bool
+
+	// scheduler picks the target function of each iteration.
+	scheduler targetScheduler
+
+	// retries is the retry budget of the current iteration's target, and
+	// recentHits whether the last hitRateWindow targets were hit.
+	retries    int
+	recentHits []bool
+
+	// Coverage-guided mode (no CFG): seeds that raised coverage, and the
+	// last increase, for the next mutation prompt.
+	coverageGains int
+	lastIncrease  *coverage.CoverageIncrease
+
+	// iteration records the current iteration for IterationSinks (nil
+	// outside of one); iterationUsage is the LLM usage when it started.
+	iteration      *IterationRecord
+	iterationUsage llm.Usage
+
+	// Compile-fix counters: seeds sent for repair, and how many compiled after it.
+	compileFixAttempted int
+	compileFixRepaired  int
+
+	// LLM usage by call type, carried over from UsagePath on resume.
+	llmUsage map[string]llm.Usage
+
+	// What the current LLM requests are for (target and base seed); the
+	// call type is filled in by trackLLM.
+	llmScope llm.CallInfo
+
+	// Compressed understanding, once it was over UnderstandingMaxTokens or a
+	// prompt did not fit the context window.
+	understandingSummary string
+
+	// Understanding history timestamp of the understanding in use, for bug
+	// bundles.
+	understandingVersion string
+
+	// Coverage mapping favored-set generation last passed to the corpus.
+	favoredGen uint64
+
+	// Random source for target tie-breaks and the random phase, kept in
+	// checkpoints.
+	rng       *rand.Rand
+	rngSource *countingSource
+
+	// Checkpoint and resume: the target being solved, the interrupted
+	// target a resumed run starts with, and whether Resume ran.
+	inFlight *coverage.TargetInfo
+	retarget *TargetRef
+	resumed  bool
+
+	// Selects the queued seeds of the initial phase; see Run.
+	initialFilter seed.Filter
+
+	// Progress reporting: the reporter while Run is active, seeds compiled
+	// and failed to compile, and when coverage last grew.
+	progress         *progressReporter
+	compiles         int
+	compileFailures  int
+	lastCoverageGain time.Time
 }
 
 // seedTryResult holds the result of trying a mutated seed.
@@ -90,6 +291,7 @@ type seedTryResult struct {
 	CoveredNew    bool   // Whether any new coverage was achieved
 	CompileFailed bool   // Whether compilation failed
 	CompileError  string // Compiler error output (if compile failed)
+	Compile       string // Compile result class, CompileClassOK etc.
 	SeedCode      string // The seed code that was tried
 
 	// Oracle results
@@ -103,12 +305,40 @@ func NewEngine(cfg Config) *Engine {
 	if cfg.MaxRetries <= 0 {
 		cfg.MaxRetries = 3
 	}
+	if cfg.ExecuteSeeds == "" {
+		cfg.ExecuteSeeds = ExecuteAuto
+	}
+	if cfg.CacheOnHit == "" {
+		cfg.CacheOnHit = CachePerturb
+	}
+	if cfg.Metrics == nil {
+		cfg.Metrics = nopMetrics{}
+	}
+	if cfg.ExecuteSeeds == ExecuteNever && oracle.NeedsExecution(cfg.Oracle) {
+		logger.Warn("execute_seeds=never: oracle %q needs seed execution and will be skipped", cfg.OracleType)
+	}
+	if rc, ok := cfg.Coverage.(coverage.RuntimeCoverage); ok && rc.CollectsRuntime() && cfg.ExecuteSeeds == ExecuteNever {
+		logger.Warn("execute_seeds=never: seeds do not run, so no runtime coverage reaches the report")
+	}
+	source := newCountingSource(time.Now().UnixNano())
+	sinks := make([]*sinkRunner, 0, len(cfg.EventSinks))
+	for _, sink := range cfg.EventSinks {
+		sinks = append(sinks, &sinkRunner{sink: sink})
+	}
 	return &Engine{
+		sinks:            sinks,
 		cfg:              cfg,
+		ctx:              context.Background(),
 		bugsFound:        make([]*oracle.Bug, 0),
+		bugSignatures:    make(map[string]*oracle.Bug),
+		bugBundles:       make(map[string]*bugBundle),
 		promptDebugCount: make(map[string]int),
 		profileCoverage:  make(map[string]int),
 		profileBugs:      make(map[string]int),
+		llmUsage:         make(map[string]llm.Usage),
+		rng:              rand.New(source),
+		rngSource:        source,
+		scheduler:        targetScheduler{schedule: cfg.TargetSchedule},
 	}
 }
 
@@ -126,12 +356,77 @@ func (e *Engine) logPromptDebug(promptType, systemPrompt, userPrompt string) boo
 	return true
 }
 
-// Run starts the fuzzing loop.
-func (e *Engine) Run() error {
+// completeSeed requests a seed from the LLM, asking the provider to enforce
+// the seed JSON schema when prompts use the structured output contract.
+// With Stream set the reply is read as it arrives and cut off once complete
+// or past StreamMaxTokens. Usage is charged to callType.
+//
+// When the provider cannot enforce the schema, later prompts switch to the
+// delimiter-based contract and this request fails, so the caller's next
+// attempt uses it.
+func (e *Engine) completeSeed(callType, systemPrompt, userPrompt string) (string, error) {
+	systemPrompt, userPrompt = e.fitContextWindow(systemPrompt, userPrompt)
+	var format llm.ResponseFormat
+	if e.cfg.PromptService.StructuredOutput() {
+		format = llm.ResponseFormat{Type: llm.FormatJSONSchema, Name: "seed", Schema: e.cfg.PromptService.ResponseSchema()}
+	}
+	var completion string
+	err := e.trackLLM(callType, func(ctx context.Context) error {
+		var err error
+		if !e.cfg.Stream {
+			completion, err = llm.CompleteSeed(ctx, e.cfg.LLM, format, systemPrompt, userPrompt)
+			return err
+		}
+		watcher := e.cfg.PromptService.NewStreamWatcher(e.cfg.StreamMaxTokens)
+		completion, err = llm.StreamSeed(ctx, e.cfg.LLM, format, systemPrompt, userPrompt, watcher.Feed)
+		if reason := watcher.StopReason(); err == nil && reason != "" {
+			logger.Debug("[LLM] stopped %s stream early: %s", callType, reason)
+		}
+		return err
+	})
+	if errors.Is(err, llm.ErrUnsupportedFormat) && e.cfg.PromptService.StructuredOutput() {
+		logger.Warn("[LLM] %v; switching to the delimiter-based output contract", err)
+		e.cfg.PromptService.DisableStructuredOutput()
+	}
+	return completion, err
+}
+
+// sendTurn is conv.Send with usage charged to callType.
+func (e *Engine) sendTurn(callType string, conv *llm.Conversation, userPrompt string) (string, error) {
+	var reply string
+	err := e.trackLLM(callType, func(ctx context.Context) error {
+		var err error
+		reply, err = conv.Send(ctx, userPrompt)
+		return err
+	})
+	return reply, err
+}
+
+// Run starts the fuzzing loop. Canceling ctx aborts the LLM request in
+// flight and ends the loop after the current iteration.
+func (e *Engine) Run(ctx context.Context) error {
+	e.ctx = ctx
 	e.startTime = time.Now()
 	logger.Info("Starting fuzzing loop...")
+	defer e.startProgress()()
+
+	if err := e.loadLLMUsage(); err != nil {
+		logger.Warn("%v", err)
+	}
+	e.recordUnderstanding()
+	e.compressUnderstanding()
+	e.syncFavored()
+	if e.cfg.Analyzer != nil {
+		e.cfg.Analyzer.SetRand(e.rng)
+	}
 
-	// Process initial seeds to build coverage mapping
+	// Process initial seeds to build coverage mapping. A resumed campaign
+	// leaves out the queued seeds the mapping already credits: they were
+	// measured when they were added.
+	e.initialFilter = e.cfg.InitialSeedFilter
+	if e.resumed && e.cfg.Analyzer != nil {
+		e.initialFilter = unmappedSeeds(e.cfg.Analyzer.GetMapping(), e.initialFilter)
+	}
 	if err := e.processInitialSeeds(); err != nil {
 		return fmt.Errorf("failed to process initial seeds: %w", err)
 	}
@@ -139,6 +434,14 @@ func (e *Engine) Run() error {
 	// Special case: limit=0 means only run initial seeds, skip constraint solving
 	if e.cfg.MaxIterations == 0 {
 		logger.Info("Limit=0: skipping constraint solving loop")
+		e.stopReason = "initial seeds only"
+		e.finalizeState()
+		e.printSummary()
+		return nil
+	}
+
+	if e.coverageGuided() {
+		e.runCoverageGuided()
 		e.finalizeState()
 		e.printSummary()
 		return nil
@@ -146,21 +449,47 @@ func (e *Engine) Run() error {
 
 	// Main fuzzing loop
 	for {
-		// Check iteration limit (-1 = unlimited)
+		if err := ctx.Err(); err != nil {
+			logger.Info("Fuzzing interrupted: %v", err)
+			e.stopReason = "interrupted"
+			break
+		}
+
+		// Check iteration limit (-1 = unlimited) and time budget; the
+		// iteration in progress always finishes
 		if e.cfg.MaxIterations > 0 && e.iterationCount >= e.cfg.MaxIterations {
 			logger.Info("Reached max iterations (%d), stopping", e.cfg.MaxIterations)
+			e.stopReason = "max iterations reached"
+			break
+		}
+		if e.budgetSpent() {
+			logger.Info("Time budget (%v) spent, stopping", e.cfg.MaxDuration)
+			e.stopReason = "time budget spent"
 			break
 		}
 
 		e.iterationCount++
+		iterationStart := time.Now()
+		startEvent := IterationEvent{Iteration: e.iterationCount}
+		e.emit(func(sink EventSink) { sink.OnIterationStart(startEvent) })
+		e.retries = 0
+		e.beginIteration()
 
 		// Step 1: Select target BB (one with most successors among uncovered)
-		target := e.cfg.Analyzer.SelectTarget()
+		target := e.selectTarget()
+		if target == nil && e.cfg.DryRunPrompts && len(e.rendered) > 0 {
+			logger.Info("Prompts rendered for all %d uncovered targets", len(e.rendered))
+			e.stopReason = "all targets rendered"
+			e.endIteration(iterationStart, OutcomeNoTarget)
+			break
+		}
 		if target == nil {
 			logger.Info("All target basic blocks covered! Fuzzing complete.")
+			e.stopReason = "all targets covered"
+			e.endIteration(iterationStart, OutcomeNoTarget)
 
 			// Enter random mutation phase if enabled
-			if e.cfg.EnableRandomPhase {
+			if e.cfg.EnableRandomPhase && !e.cfg.DryRunPrompts {
 				logger.Info("Entering random mutation phase...")
 				randomPhase := NewRandomMutationPhase(e, e.cfg.MaxRandomIterations)
 				if err := randomPhase.Run(); err != nil {
@@ -170,27 +499,71 @@ func (e *Engine) Run() error {
 			break
 		}
 
-		logger.Info("Iteration %d: Targeting %s:BB%d (succs=%d, lines=%v)",
-			e.iterationCount, target.Function, target.BBID, target.SuccessorCount, target.Lines)
+		e.retries = e.retriesFor(target)
+		logger.Info("Iteration %d: Targeting %s:BB%d (succs=%d, lines=%v, retries=%d)",
+			e.iterationCount, target.Function, target.BBID, target.SuccessorCount, target.Lines, e.retries)
+		targetEvent := TargetEvent{
+			Iteration:      e.iterationCount,
+			Function:       target.Function,
+			BBID:           target.BBID,
+			SuccessorCount: target.SuccessorCount,
+			Weight:         e.cfg.Analyzer.GetBBWeight(target.Function, target.BBID),
+		}
+		e.emit(func(sink EventSink) { sink.OnTargetSelected(targetEvent) })
+		e.iteration.Target = &targetEvent
 
 		// Step 2: Try to cover the target with constraint solving
-		hit, actualRetries, err := e.solveConstraint(target)
+		e.inFlight = target
+		e.publishProgress("constraint")
+		hit, actualRetries, err := e.solveConstraint(target, e.retries)
+		e.iteration.Retries = actualRetries
+		if ctx.Err() != nil && !hit {
+			// Cut short: the checkpoint records the target as interrupted
+			logger.Info("Target %s:BB%d interrupted", target.Function, target.BBID)
+			e.stopReason = "interrupted"
+			e.endIteration(iterationStart, OutcomeInterrupted)
+			break
+		}
+		e.inFlight = nil
 		if err != nil {
 			logger.Error("Error solving constraint for %s:BB%d: %v", target.Function, target.BBID, err)
 		}
 
-		if hit {
+		// A target that was not hit loses weight, so SelectTarget moves on
+		// to other BBs instead of retrying it every iteration
+		outcome := OutcomeMissed
+		if e.cfg.DryRunPrompts {
+			// Move on to another target next time, as after a failed attempt.
+			outcome = OutcomeSkipped
+			e.skippedIterations++
+			e.markRendered(target)
+			e.cfg.Analyzer.DecayBBWeight(target.Function, target.BBID)
+			logger.Info("Iteration %d skipped (dry run)", e.iterationCount)
+		} else if hit {
+			outcome = OutcomeHit
 			e.targetHits++
+			e.noteTargetOutcome(true)
+			e.cfg.Analyzer.RecordSuccess(target.Function, target.BBID)
 			logger.Info("Successfully covered target %s:BB%d!", target.Function, target.BBID)
 		} else {
-			logger.Warn("Failed to cover target %s:BB%d after %d retries",
-				target.Function, target.BBID, actualRetries)
+			e.noteTargetOutcome(false)
+			e.cfg.Analyzer.DecayBBWeight(target.Function, target.BBID)
+			logger.Warn("Failed to cover target %s:BB%d after %d retries (weight now %.2f)",
+				target.Function, target.BBID, actualRetries, e.cfg.Analyzer.GetBBWeight(target.Function, target.BBID))
+			e.coolDown(target)
+		}
+
+		if e.cfg.TrimEvery > 0 && e.iterationCount%e.cfg.TrimEvery == 0 {
+			e.trimCorpus()
 		}
 
 		// Save state periodically
 		if e.iterationCount%10 == 0 {
 			e.saveState()
 		}
+		e.cfg.Metrics.IterationDone(time.Since(iterationStart))
+		e.endIteration(iterationStart, outcome)
+		e.publishProgress("constraint")
 	}
 
 	// Final save with correct global state
@@ -199,6 +572,34 @@ func (e *Engine) Run() error {
 	return nil
 }
 
+// endIteration raises OnIterationEnd for the current iteration.
+func (e *Engine) endIteration(start time.Time, outcome string) {
+	ev := IterationEvent{Iteration: e.iterationCount, Outcome: outcome, Duration: time.Since(start), RetryBudget: e.retries}
+	e.emit(func(sink EventSink) { sink.OnIterationEnd(ev) })
+	e.finishIteration(start, outcome)
+}
+
+// noteCoverageGain records that s raised BB coverage from oldBP to newBP.
+func (e *Engine) noteCoverageGain(s *seed.Seed, oldBP, newBP uint64) {
+	e.lastCoverageGain = time.Now()
+	ev := CoverageEvent{Iteration: e.iterationCount, SeedID: s.Meta.ID, OldBasisPoints: oldBP, NewBasisPoints: newBP}
+	e.emit(func(sink EventSink) { sink.OnNewCoverage(ev) })
+}
+
+// elapsed returns the wall-clock time the campaign has run, this run and
+// the earlier ones Resume restored.
+func (e *Engine) elapsed() time.Duration {
+	if e.startTime.IsZero() {
+		return e.priorElapsed
+	}
+	return e.priorElapsed + time.Since(e.startTime)
+}
+
+// budgetSpent reports whether the campaign has used up MaxDuration.
+func (e *Engine) budgetSpent() bool {
+	return e.cfg.MaxDuration > 0 && e.elapsed() >= e.cfg.MaxDuration
+}
+
 // processInitialSeeds runs all initial seeds to build the coverage mapping.
 func (e *Engine) processInitialSeeds() error {
 	logger.Info("Processing initial seeds to build coverage mapping...")
@@ -206,10 +607,15 @@ func (e *Engine) processInitialSeeds() error {
 	totalStart := time.Now()
 
 	for {
-		s, ok := e.cfg.Corpus.Next()
+		s, ok := e.nextInitialSeed()
 		if !ok {
 			break
 		}
+		if s.Meta.State == seed.SeedStateInvalid {
+			logger.Info("Skipping invalid initial seed %d", s.Meta.ID)
+			s.Release()
+			continue
+		}
 		seedCount++
 
 		logger.Debug("Processing initial seed %d...", s.Meta.ID)
@@ -218,56 +624,79 @@ func (e *Engine) processInitialSeeds() error {
 		e.assignDefaultProfile(s)
 
 		// Get coverage before processing this seed
-		oldBasisPoints := e.cfg.Analyzer.GetBBCoverageBasisPoints()
+		oldBasisPoints := e.coverageBasisPoints()
 
 		// Compile and measure coverage
 		compileStart := time.Now()
-		report, compileResult, err := e.measureSeed(s)
+		report, compileResult, err := e.measureSeed(s, e.cfg.Compiler.Compile)
 		logger.Debug("[TIMING] Seed %d: compile+coverage took %v", s.Meta.ID, time.Since(compileStart))
 		if compileResult != nil {
 			e.persistCompilationRecord(s, compileResult)
 		}
 		if err != nil {
 			logger.Warn("Failed to measure initial seed %d: %v", s.Meta.ID, err)
+			s.Release()
 			continue
 		}
 
-		// Record coverage in mapping
+		// Record coverage in mapping, or without a CFG in the total coverage
 		if report != nil {
 			recordStart := time.Now()
-			coveredLines := e.extractCoveredLines(report)
-			e.cfg.Analyzer.RecordCoverage(int64(s.Meta.ID), coveredLines)
+			e.recordInitialCoverage(s, report)
 			logger.Debug("[TIMING] Seed %d: record coverage took %v", s.Meta.ID, time.Since(recordStart))
 		}
 
 		// Get coverage after processing
-		newBasisPoints := e.cfg.Analyzer.GetBBCoverageBasisPoints()
+		newBasisPoints := e.coverageBasisPoints()
 
 		// Run oracle on initial seed if configured
 		oracleVerdict := seed.OracleVerdictSkipped
-		if e.cfg.Oracle != nil && compileResult != nil && compileResult.BinaryPath != "" {
+		var execTime time.Duration
+		if e.oracleEnabled() && compileResult != nil && compileResult.BinaryPath != "" {
 			oracleStart := time.Now()
-			bug := e.runOracle(s, compileResult.BinaryPath)
-			logger.Debug("[TIMING] Seed %d: oracle took %v", s.Meta.ID, time.Since(oracleStart))
+			bug := e.runOracle(s, compileResult)
+			execTime = time.Since(oracleStart)
+			logger.Debug("[TIMING] Seed %d: oracle took %v", s.Meta.ID, execTime)
 			if bug != nil {
 				oracleVerdict = seed.OracleVerdictBug
 				logger.Info("Initial seed %d triggered oracle bug: %s", s.Meta.ID, bug.Description)
+				e.saveMinimizedSource(s, bug)
+				e.exportBundle(s, bug, compileResult)
 			} else {
 				oracleVerdict = seed.OracleVerdictNormal
 			}
 		}
 
-		// Mark as processed with coverage and oracle info
-		e.cfg.Corpus.ReportResult(s.Meta.ID, corpus.FuzzResult{
+		// Mark as processed with coverage, oracle info and the outcome
+		result := corpus.FuzzResult{
 			State:         seed.SeedStateProcessed,
 			OldCoverage:   oldBasisPoints,
 			NewCoverage:   newBasisPoints,
+			ExecTimeUs:    execTime.Microseconds(),
 			OracleVerdict: oracleVerdict,
-		})
+			CompileOK:     compileResult != nil && compileResult.Success,
+			ExecSignals:   s.Meta.ExecSignals,
+			DurationMs:    time.Since(seedStart).Milliseconds(),
+		}
+		if compileResult != nil && !compileResult.Success {
+			result.CompileStderrSnippet = compileResult.Stderr
+		}
+		e.cfg.Corpus.ReportResult(s.Meta.ID, result)
+		// Large corpora are opened lazily; do not keep every initial seed's
+		// source in memory. The corpus reloads it if the seed is needed again.
+		s.Release()
+		if newBasisPoints > oldBasisPoints {
+			e.noteCoverageGain(s, oldBasisPoints, newBasisPoints)
+		}
+		e.publishProgress("initial")
 
 		logger.Debug("[TIMING] Seed %d: total processing took %v", s.Meta.ID, time.Since(seedStart))
 	}
 
+	if e.initialFilter != nil && e.cfg.Corpus.Len() > 0 {
+		logger.Info("Left %d queued seeds out of the initial phase", e.cfg.Corpus.Len())
+	}
+
 	// Log total timing for initial seeds
 	totalElapsed := time.Since(totalStart)
 	if seedCount > 0 {
@@ -276,9 +705,13 @@ func (e *Engine) processInitialSeeds() error {
 	}
 
 	// Print initial coverage stats
-	funcCov := e.cfg.Analyzer.GetFunctionCoverage()
-	for name, stats := range funcCov {
-		logger.Info("Initial coverage for %s: %d/%d BBs", name, stats.Covered, stats.Total)
+	if e.cfg.Analyzer != nil {
+		funcCov := e.cfg.Analyzer.GetFunctionCoverage()
+		for name, stats := range funcCov {
+			logger.Info("Initial coverage for %s: %d/%d BBs", name, stats.Covered, stats.Total)
+		}
+	} else {
+		logger.Info("Initial coverage: %.2f%%", float64(e.coverageBasisPoints())/100)
 	}
 
 	// Save state immediately after processing initial seeds
@@ -289,9 +722,64 @@ func (e *Engine) processInitialSeeds() error {
 	return nil
 }
 
-// solveConstraint tries to generate a seed that covers the target BB.
+// printLineage adds the deepest lineage and the most productive root seed
+// to the summary and writes the lineage graph to LineagePath.
+func (e *Engine) printLineage() {
+	if e.cfg.Corpus == nil {
+		return
+	}
+	roots, err := e.cfg.Corpus.Lineage()
+	if err != nil {
+		logger.Warn("Failed to build seed lineage: %v", err)
+		return
+	}
+	lineage := corpus.SummarizeLineage(roots)
+	if lineage.Depth > 0 {
+		logger.Info("Deepest lineage: seed %d, %d mutations from root %d", lineage.DeepestSeed, lineage.Depth, lineage.DeepestRoot)
+	}
+	if lineage.TopRoot != 0 {
+		logger.Info("Top root seed:  %d (%d descendants, +%d bp coverage, %d bugs)",
+			lineage.TopRoot, lineage.TopRootDescendants, lineage.TopRootCoverage, lineage.TopRootBugs)
+	}
+	if e.cfg.LineagePath == "" {
+		return
+	}
+	var buf bytes.Buffer
+	if err := corpus.WriteLineage(roots, corpus.LineageDOT, &buf); err != nil {
+		logger.Warn("Failed to render seed lineage: %v", err)
+		return
+	}
+	if err := os.WriteFile(e.cfg.LineagePath, buf.Bytes(), 0644); err != nil {
+		logger.Warn("Failed to write seed lineage to %s: %v", e.cfg.LineagePath, err)
+	}
+}
+
+// nextInitialSeed takes the next queued seed for the initial phase,
+// honoring InitialSeedFilter (and, on resume, the coverage mapping).
+func (e *Engine) nextInitialSeed() (*seed.Seed, bool) {
+	filter := e.initialFilter
+	if filter == nil {
+		filter = e.cfg.InitialSeedFilter
+	}
+	if filter != nil {
+		return e.cfg.Corpus.NextWhere(filter)
+	}
+	return e.cfg.Corpus.Next()
+}
+
+// unmappedSeeds narrows filter (nil for all) to the seeds mapping does not
+// credit with any line.
+func unmappedSeeds(mapping *coverage.CoverageMapping, filter seed.Filter) seed.Filter {
+	mapped := mapping.SeedIDs()
+	return func(s *seed.Seed) bool {
+		return !mapped[int64(s.Meta.ID)] && (filter == nil || filter(s))
+	}
+}
+
+// solveConstraint tries to generate a seed that covers the target BB within
+// retries retries.
 // Returns (hit bool, actualRetries int, err error)
-func (e *Engine) solveConstraint(target *coverage.TargetInfo) (bool, int, error) {
+func (e *Engine) solveConstraint(target *coverage.TargetInfo, retries int) (bool, int, error) {
 	if e.cfg.Flags != nil {
 		e.cfg.Flags.BeginTarget(target)
 	}
@@ -313,6 +801,13 @@ func (e *Engine) solveConstraint(target *coverage.TargetInfo) (bool, int, error)
 		}
 	}
 
+	e.llmScope = llm.CallInfo{Target: fmt.Sprintf("%s:BB%d", target.Function, target.BBID)}
+	if baseSeed != nil {
+		e.llmScope.SeedID = baseSeed.Meta.ID
+	}
+	defer func() { e.llmScope = llm.CallInfo{} }()
+	defer e.removeScratch()
+
 	// Build target context for prompt
 	ctx, err := prompt.BuildTargetContextFromCFG(target, baseSeed, e.cfg.Analyzer)
 	if err != nil {
@@ -323,22 +818,49 @@ func (e *Engine) solveConstraint(target *coverage.TargetInfo) (bool, int, error)
 		ctx.BaseSeedCode = baseSeedCode
 	}
 
+	if e.cfg.DryRunPrompts {
+		e.attachPromptProfile(target, ctx, ctx.BaseSeedCode)
+		return false, 0, e.writeDryRunPrompts(target, ctx)
+	}
+
+	// In conversation mode retries continue this session instead of
+	// re-sending the full prompt (nil = stateless prompts).
+	conv := e.newConversation()
+
 	// First attempt: direct constraint solving
 	e.attachPromptProfile(target, ctx, ctx.BaseSeedCode)
-	mutatedSeed, err := e.generateMutatedSeed(ctx)
+	// Every candidate after the first uses up one retry.
+	candidates, err := e.generateCandidateSeeds(ctx, conv, retries+1)
+	if errors.Is(err, errLLMCall) && e.llmUnavailable(err) {
+		logger.Warn("%v; falling back to LLM-free mutations", err)
+		return e.solveWithFallback(target, baseSeed, ctx, 0, retries)
+	}
 	if err != nil {
 		logger.Warn("Failed to generate mutated seed: %v", err)
 		return false, 0, nil
 	}
 
-	// Try the mutated seed
-	result, err := e.tryMutatedSeed(mutatedSeed, target)
-	if err != nil {
-		return false, 0, err
-	}
-
-	if result.HitTarget {
-		return true, 0, nil // Hit on first try, 0 retries needed
+	// Every failed seed for this target, oldest first. All but the newest are
+	// fed back to the refined prompt as negative examples.
+	var attempts []prompt.FailedAttempt
+	var mutatedSeed *seed.Seed
+	for i, candidate := range candidates {
+		if len(candidates) > 1 {
+			logger.Debug("Trying candidate %d/%d", i+1, len(candidates))
+		}
+		result, err := e.tryMutatedSeed(candidate, target)
+		if err != nil {
+			return false, i, err
+		}
+		if result.HitTarget {
+			return true, i, nil // 0 retries when the first candidate hits
+		}
+		mutatedSeed = candidate
+		attempts = append(attempts, prompt.FailedAttempt{
+			Attempt: len(attempts) + 1,
+			Code:    candidate.Content,
+			Reason:  failedAttemptReason(result),
+		})
 	}
 
 	// If first attempt failed, try with divergence analysis
@@ -348,8 +870,8 @@ func (e *Engine) solveConstraint(target *coverage.TargetInfo) (bool, int, error)
 	// Try multiple retries with divergence analysis
 	var refinedPrompt string
 	var systemPrompt string // Declare systemPrompt at broader scope
-	for retry := 0; retry < e.cfg.MaxRetries; retry++ {
-		logger.Debug("Retry %d/%d with divergence analysis...", retry+1, e.cfg.MaxRetries)
+	for retry := len(candidates) - 1; retry < retries; retry++ {
+		logger.Debug("Retry %d/%d with divergence analysis...", retry+1, retries)
 		e.attachPromptProfile(target, ctx, mutatedSeed.Content)
 
 		// Check if previous attempt had compile error
@@ -360,10 +882,16 @@ func (e *Engine) solveConstraint(target *coverage.TargetInfo) (bool, int, error)
 				CompilerOutput: lastResult.CompileError,
 				ExitCode:       1, // Generic failure
 				RetryAttempt:   retry + 1,
-				MaxRetries:     e.cfg.MaxRetries,
+				MaxRetries:     retries,
+
+				SystemPromptOverride: e.cfg.PromptService.CompileRepairSystemPrompt(),
 			}
 			var userPrompt string
-			systemPrompt, userPrompt, err = e.cfg.PromptService.GetCompileErrorPrompt(ctx, compileErrInfo)
+			if conversing(conv) {
+				userPrompt, err = e.cfg.PromptService.GetFollowUpPrompt(ctx, nil, compileErrInfo)
+			} else {
+				systemPrompt, userPrompt, err = e.cfg.PromptService.GetCompileErrorPrompt(ctx, compileErrInfo)
+			}
 			if err != nil {
 				logger.Warn("Failed to build compile error prompt: %v", err)
 				continue
@@ -378,14 +906,14 @@ func (e *Engine) solveConstraint(target *coverage.TargetInfo) (bool, int, error)
 
 			if e.cfg.DivergenceAnalyzer != nil && e.cfg.CompilerPath != "" {
 				// Run uftrace divergence analysis
-				divPoint, err := e.cfg.DivergenceAnalyzer.Analyze(
-					e.currentBaseSeedPath, e.currentMutatedSeedPath, e.cfg.CompilerPath)
+				divPoint, err := e.analyzeDivergence(baseSeed, mutatedSeed)
 				if err != nil {
 					logger.Warn("Divergence analysis failed: %v", err)
 				} else if divPoint != nil {
 					logger.Info("Divergence found at index %d: %s vs %s",
 						divPoint.Index, divPoint.Function1, divPoint.Function2)
 					divergentFunc = divPoint.Function2
+					attempts[len(attempts)-1].Reason = "diverged at " + divergentFunc
 				}
 			}
 
@@ -420,11 +948,18 @@ func (e *Engine) solveConstraint(target *coverage.TargetInfo) (bool, int, error)
 				DivergentFunctionCode: divergentFuncCode,
 				MutatedSeedCode:       mutatedSeed.Content,
 				BaseSeedCode:          baseSeedCode,
+				PriorAttempts:         attempts[:len(attempts)-1],
+
+				SystemPromptOverride: e.cfg.PromptService.RefinementSystemPrompt(ctx),
 			}
 
 			// Generate refined prompt
 			var userPrompt string
-			systemPrompt, userPrompt, err = e.cfg.PromptService.GetRefinedPrompt(ctx, divInfo)
+			if conversing(conv) {
+				userPrompt, err = e.cfg.PromptService.GetFollowUpPrompt(ctx, divInfo, nil)
+			} else {
+				systemPrompt, userPrompt, err = e.cfg.PromptService.GetRefinedPrompt(ctx, divInfo)
+			}
 			refinedPrompt = userPrompt
 			if err != nil {
 				logger.Warn("Failed to build refined prompt: %v", err)
@@ -436,7 +971,11 @@ func (e *Engine) solveConstraint(target *coverage.TargetInfo) (bool, int, error)
 		}
 
 		// Call LLM with refined prompt
-		completion, err := e.cfg.LLM.GetCompletionWithSystem(systemPrompt, refinedPrompt)
+		completion, usage, err := e.askForSeed(callRefine, conv, systemPrompt, refinedPrompt)
+		if err != nil && e.llmUnavailable(err) {
+			logger.Warn("LLM call failed: %v; falling back to LLM-free mutations", err)
+			return e.solveWithFallback(target, baseSeed, ctx, retry+1, retries)
+		}
 		if err != nil {
 			logger.Warn("LLM call failed: %v", err)
 			continue
@@ -456,6 +995,7 @@ func (e *Engine) solveConstraint(target *coverage.TargetInfo) (bool, int, error)
 			newSeed.Meta.ParentID = uint64(ctx.BaseSeedID)
 		}
 		newSeed.FlagProfile = clonePromptProfile(ctx)
+		usage.apply(newSeed)
 
 		// Try the new seed with V2 to capture compile errors
 		lastResult, err = e.tryMutatedSeed(newSeed, target)
@@ -469,6 +1009,11 @@ func (e *Engine) solveConstraint(target *coverage.TargetInfo) (bool, int, error)
 
 		// Update mutated seed for next iteration
 		mutatedSeed = newSeed
+		attempts = append(attempts, prompt.FailedAttempt{
+			Attempt: len(attempts) + 1,
+			Code:    newSeed.Content,
+			Reason:  failedAttemptReason(lastResult),
+		})
 
 		// If we covered something new (even if not the target), that's progress
 		if lastResult.CoveredNew {
@@ -477,14 +1022,237 @@ func (e *Engine) solveConstraint(target *coverage.TargetInfo) (bool, int, error)
 		}
 	}
 
-	// Failed to cover target after all retries - decay its weight
-	e.cfg.Analyzer.DecayBBWeight(target.Function, target.BBID)
+	// Failed to cover target after all retries; Run decays its weight
+	return false, retries, nil
+}
+
+// errLLMCall wraps the LLM errors generateCandidateSeeds returns, as
+// opposed to prompt and parse failures.
+var errLLMCall = errors.New("LLM call failed")
+
+// llmUnavailable reports whether err from an LLM request, made after the
+// client's own retries, means the model cannot be used for now (provider
+// down, quota exhausted) rather than that the run was canceled or the
+// request needs another output contract.
+func (e *Engine) llmUnavailable(err error) bool {
+	if e.ctx != nil && e.ctx.Err() != nil {
+		return false
+	}
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, llm.ErrUnsupportedFormat)
+}
 
-	return false, e.cfg.MaxRetries, nil
+// analyzeDivergence runs the divergence analyzer on the sources of the base
+// seed and the mutated seed that missed the target.
+func (e *Engine) analyzeDivergence(base, mutated *seed.Seed) (*coverage.DivergencePoint, error) {
+	if base == nil {
+		return nil, errors.New("no base seed to compare against")
+	}
+	basePath, err := e.seedSourcePath(base)
+	if err != nil {
+		return nil, err
+	}
+	mutatedPath, err := e.seedSourcePath(mutated)
+	if err != nil {
+		return nil, err
+	}
+	return e.cfg.DivergenceAnalyzer.Analyze(basePath, mutatedPath, e.cfg.CompilerPath)
+}
+
+// seedSourcePath returns the path of a file holding s's source, for tools
+// that compile it themselves. A corpus seed's own source file is used,
+// uncompressed into the scratch directory if need be. Any other seed is
+// written to the scratch directory, and s.Meta.ContentPath set to the file
+// until the corpus saves the seed.
+func (e *Engine) seedSourcePath(s *seed.Seed) (string, error) {
+	if s.Meta.ContentPath != "" && !seed.IsCompressedSource(s.Meta.ContentPath) {
+		return s.Meta.ContentPath, nil
+	}
+	dir, err := e.scratchDir()
+	if err != nil {
+		return "", err
+	}
+	if s.Meta.ContentPath != "" {
+		plain, err := seed.PlainSourcePath(s.Meta.ContentPath, dir)
+		if err != nil {
+			return "", fmt.Errorf("failed to decompress seed %d: %w", s.Meta.ID, err)
+		}
+		return plain, nil
+	}
+	path := filepath.Join(dir, fmt.Sprintf("seed_%d%s", s.Meta.ID, s.Language.Extension()))
+	if err := os.WriteFile(path, []byte(s.Content), 0644); err != nil {
+		return "", fmt.Errorf("failed to write source of seed %d: %w", s.Meta.ID, err)
+	}
+	s.Meta.ContentPath = path
+	return path, nil
+}
+
+// scratchDir returns the current iteration's scratch directory,
+// {state}/scratch/iter-NNNN (a temporary directory without MappingPath),
+// creating it on first use.
+func (e *Engine) scratchDir() (string, error) {
+	if e.scratch != "" {
+		return e.scratch, nil
+	}
+	var dir string
+	var err error
+	if e.cfg.MappingPath != "" {
+		dir = filepath.Join(filepath.Dir(e.cfg.MappingPath), "scratch", fmt.Sprintf("iter-%04d", e.iterationCount))
+		err = os.MkdirAll(dir, 0755)
+	} else {
+		dir, err = os.MkdirTemp("", "defuzz-scratch-")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to create scratch directory: %w", err)
+	}
+	e.scratch = dir
+	return dir, nil
+}
+
+// removeScratch deletes the scratch directory once a target is done with.
+// The seeds worth keeping are in the corpus by then, with their own files.
+func (e *Engine) removeScratch() {
+	if e.scratch == "" {
+		return
+	}
+	if err := os.RemoveAll(e.scratch); err != nil {
+		logger.Warn("Failed to remove scratch directory: %v", err)
+	}
+	e.scratch = ""
 }
 
-// generateMutatedSeed generates a new seed using LLM with constraint solving prompt.
-func (e *Engine) generateMutatedSeed(ctx *prompt.TargetContext) (*seed.Seed, error) {
+// writeDryRunPrompts renders the prompts for target with
+// PromptService.RenderOnly and writes each system and user prompt to its own
+// file under the dry-run directory.
+func (e *Engine) writeDryRunPrompts(target *coverage.TargetInfo, ctx *prompt.TargetContext) error {
+	rendered, err := e.cfg.PromptService.RenderOnly(ctx, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to render prompts: %w", err)
+	}
+
+	dir := e.dryRunDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create dry-run directory: %w", err)
+	}
+	prefix := fmt.Sprintf("iter-%04d_%s_bb%d", e.iterationCount, target.Function, target.BBID)
+	for _, p := range rendered.All() {
+		files := []struct{ part, content string }{{"system", p.System}, {"user", p.User}}
+		for _, f := range files {
+			path := filepath.Join(dir, fmt.Sprintf("%s_%s.%s.md", prefix, p.Phase, f.part))
+			if err := os.WriteFile(path, []byte(f.content), 0644); err != nil {
+				return fmt.Errorf("failed to write %s: %w", path, err)
+			}
+		}
+	}
+	logger.Debug("Dry run: wrote prompts for %s:BB%d to %s", target.Function, target.BBID, dir)
+	return nil
+}
+
+// dryRunDir returns DryRunDir, defaulting to dry_run_prompts next to the
+// state directory.
+func (e *Engine) dryRunDir() string {
+	if e.cfg.DryRunDir != "" {
+		return e.cfg.DryRunDir
+	}
+	if e.cfg.MappingPath != "" {
+		return filepath.Join(filepath.Dir(filepath.Dir(e.cfg.MappingPath)), "dry_run_prompts")
+	}
+	return "dry_run_prompts"
+}
+
+// failedAttemptReason gives a one-line summary of why a tried seed missed its target.
+func failedAttemptReason(r *seedTryResult) string {
+	if r.CompileFailed {
+		return "did not compile"
+	}
+	return "compiled but did not reach the target lines"
+}
+
+// promptUsage is the estimated token cost of one seed request.
+type promptUsage struct {
+	promptTokens  int // new prompt text sent with this request
+	contextTokens int // earlier conversation turns resent with it
+}
+
+func (u promptUsage) apply(s *seed.Seed) {
+	s.Meta.PromptTokens = u.promptTokens
+	s.Meta.ContextTokens = u.contextTokens
+}
+
+// newConversation starts a chat session for one target, or returns nil when
+// conversation mode is off or the LLM client cannot hold a session.
+func (e *Engine) newConversation() *llm.Conversation {
+	if !e.cfg.Conversation {
+		return nil
+	}
+	conv := llm.NewConversation(e.cfg.LLM, e.cfg.PromptService.StructuredOutput())
+	if conv == nil {
+		logger.Debug("LLM client does not support chat sessions, using stateless prompts")
+	}
+	return conv
+}
+
+// conversing reports whether conv has a completed turn to build on.
+func conversing(conv *llm.Conversation) bool {
+	return conv != nil && conv.Turns() > 0
+}
+
+// askForSeed sends a seed request, continuing conv when it already has a
+// turn and starting it with systemPrompt otherwise. Without a conversation
+// the request is stateless. Opening requests go through the ResponseCache.
+// LLM usage is charged to callType.
+func (e *Engine) askForSeed(callType string, conv *llm.Conversation, systemPrompt, userPrompt string) (string, promptUsage, error) {
+	if conversing(conv) {
+		var usage promptUsage
+		for _, m := range conv.Messages() {
+			usage.contextTokens += e.cfg.PromptService.EstimateTokens(m.Content)
+		}
+		usage.promptTokens = e.cfg.PromptService.EstimateTokens(userPrompt)
+		completion, err := e.sendTurn(callType, conv, userPrompt)
+		return completion, usage, err
+	}
+
+	if cache := e.cfg.ResponseCache; cache != nil {
+		if cached, hits, ok := cache.Get(systemPrompt, userPrompt); ok {
+			if e.cfg.CacheOnHit == CacheReuse {
+				logger.Debug("[Cache] reusing cached completion for an identical prompt")
+				if conv != nil {
+					conv.SetSystemPrompt(systemPrompt)
+					conv.Record(userPrompt, cached)
+				}
+				return cached, promptUsage{}, nil
+			}
+			logger.Debug("[Cache] identical prompt seen %d time(s) before, asking for a different approach", hits+1)
+			userPrompt = prompt.PerturbPrompt(userPrompt, hits+1)
+		}
+	}
+
+	usage := promptUsage{promptTokens: e.cfg.PromptService.EstimateTokens(systemPrompt + userPrompt)}
+	var completion string
+	var err error
+	if conv != nil {
+		systemPrompt, userPrompt := e.fitContextWindow(systemPrompt, userPrompt)
+		conv.SetSystemPrompt(systemPrompt)
+		completion, err = e.sendTurn(callType, conv, userPrompt)
+	} else {
+		completion, err = e.completeSeed(callType, systemPrompt, userPrompt)
+	}
+	if err != nil {
+		return "", usage, err
+	}
+
+	if cache := e.cfg.ResponseCache; cache != nil {
+		if err := cache.Put(systemPrompt, userPrompt, completion); err != nil {
+			logger.Warn("[Cache] %v", err)
+		}
+	}
+	return completion, usage, nil
+}
+
+// generateCandidateSeeds asks the LLM for seeds with the constraint solving
+// prompt: one seed, or up to CandidatesPerCall alternatives from a single
+// response, of which the first limit are kept. When conv is non-nil the
+// request opens that conversation.
+func (e *Engine) generateCandidateSeeds(ctx *prompt.TargetContext, conv *llm.Conversation, limit int) ([]*seed.Seed, error) {
 	// Build constraint solving prompt
 	systemPrompt, userPrompt, err := e.cfg.PromptService.GetConstraintPrompt(ctx)
 	if err != nil {
@@ -495,99 +1263,74 @@ func (e *Engine) generateMutatedSeed(ctx *prompt.TargetContext) (*seed.Seed, err
 	e.logPromptDebug("generateMutatedSeed", systemPrompt, userPrompt)
 
 	// Call LLM
-	completion, err := e.cfg.LLM.GetCompletionWithSystem(systemPrompt, userPrompt)
+	completion, usage, err := e.askForSeed(callGenerate, conv, systemPrompt, userPrompt)
 	if err != nil {
-		return nil, fmt.Errorf("LLM call failed: %w", err)
+		return nil, fmt.Errorf("%w: %w", errLLMCall, err)
 	}
 
 	// Parse response
-	newSeed, err := e.cfg.PromptService.ParseLLMResponse(completion)
+	seeds, err := e.cfg.PromptService.ParseMultiCandidateResponse(completion)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
+	if want := e.cfg.PromptService.CandidatesPerCall(); want > 1 {
+		logger.Debug("Got %d of %d requested candidates", len(seeds), want)
+	}
+	// Only the candidates kept take corpus IDs.
+	if len(seeds) > limit {
+		seeds = seeds[:limit]
+	}
 
-	// Pre-allocate ID for the new seed before compilation
-	// This ensures the seed has a valid ID when being compiled
-	newSeed.Meta.ID = e.cfg.Corpus.AllocateID()
-	newSeed.Meta.CreatedAt = time.Now()
-	newSeed.FlagProfile = clonePromptProfile(ctx)
+	for _, newSeed := range seeds {
+		// Pre-allocate ID for the new seed before compilation
+		// This ensures the seed has a valid ID when being compiled
+		newSeed.Meta.ID = e.cfg.Corpus.AllocateID()
+		newSeed.Meta.CreatedAt = time.Now()
+		newSeed.FlagProfile = clonePromptProfile(ctx)
+		usage.apply(newSeed)
 
-	// Set lineage information from context
-	if ctx.BaseSeedID > 0 {
-		newSeed.Meta.ParentID = uint64(ctx.BaseSeedID)
-		// Depth will be properly set in tryMutatedSeed when we have parent info
+		// Set lineage information from context
+		if ctx.BaseSeedID > 0 {
+			newSeed.Meta.ParentID = uint64(ctx.BaseSeedID)
+			// Depth will be properly set in tryMutatedSeed when we have parent info
+		}
 	}
 
-	return newSeed, nil
+	return seeds, nil
 }
 
 // tryMutatedSeed compiles and runs a mutated seed, checking if it covers the target.
 // Returns detailed result including compile errors for LLM feedback.
 func (e *Engine) tryMutatedSeed(s *seed.Seed, target *coverage.TargetInfo) (*seedTryResult, error) {
+	start := time.Now()
 	result := &seedTryResult{
 		SeedCode: s.Content,
 	}
 
 	e.assignTargetProfile(target, s)
-
-	// Reject seeds that explicitly disable the active defense mechanism.
-	if violating := seed.FindDefenseDisablingFlags(e.cfg.OracleType, s.CFlags); len(violating) > 0 {
-		result.CompileFailed = true
-		result.CompileError = fmt.Sprintf(
-			"seed violated rule: defense-disabling flag(s) %v were emitted; "+
-				"you MUST keep the defense enabled — do not emit %v or similar flags",
-			violating, violating)
-		logger.Debug("Seed %d rejected: defense-disabling flags %v", s.Meta.ID, violating)
+	defer e.noteSeedTried(s, result)
+	if e.rejectDefenseDisabling(s, result) {
 		return result, nil
 	}
 
-	// Save seed path for divergence analysis
-	stateDir := ""
-	if e.cfg.MappingPath != "" {
-		stateDir = filepath.Dir(e.cfg.MappingPath)
-	}
-
-	if s.Meta.ContentPath != "" {
-		e.currentMutatedSeedPath = s.Meta.ContentPath
-	} else if stateDir != "" {
-		e.currentMutatedSeedPath = filepath.Join(stateDir, fmt.Sprintf("seed_%d.c", s.Meta.ID))
-	}
-
-	if target.BaseSeed != "" && e.currentBaseSeedPath == "" && stateDir != "" {
-		e.currentBaseSeedPath = filepath.Join(stateDir, fmt.Sprintf("seed_%s.c", target.BaseSeed))
-	}
-
-	// Compile first to detect compile errors
-	if preparer, ok := e.cfg.Coverage.(coverage.PreCompileCoverage); ok {
-		if err := preparer.Prepare(); err != nil {
-			return result, fmt.Errorf("coverage preparation failed: %w", err)
-		}
-	}
-
-	compileResult, err := e.cfg.Compiler.Compile(s)
-	if err != nil {
+	// Compile (repairing compile errors) and measure coverage; the oracle
+	// below works on this compilation's binary
+	report, compileResult, err := e.measureSeed(s, e.compileWithFixes)
+	result.SeedCode = s.Content
+	result.Compile = compileClass(compileResult, err)
+	if errors.Is(err, errCompile) {
 		result.CompileFailed = true
-		result.CompileError = fmt.Sprintf("compilation error: %v", err)
+		result.CompileError = err.Error()
 		return result, nil
 	}
-
+	if err != nil {
+		return result, err
+	}
 	if !compileResult.Success {
 		result.CompileFailed = true
 		result.CompileError = compileResult.Stderr
-		logger.Debug("Seed failed to compile: %s", compileResult.Stderr)
 		return result, nil
 	}
-
-	// Measure coverage (generated by instrumented compiler during compilation)
-	if e.cfg.Coverage == nil {
-		return result, nil
-	}
-
-	report, err := measureCoverage(e.cfg.Coverage, s)
-	if err != nil {
-		return result, fmt.Errorf("coverage measurement failed: %w", err)
-	}
-
 	if report == nil {
 		return result, nil
 	}
@@ -596,19 +1339,7 @@ func (e *Engine) tryMutatedSeed(s *seed.Seed, target *coverage.TargetInfo) (*see
 	coveredLines := e.extractCoveredLines(report)
 
 	// Check if target was hit
-	if target != nil {
-		for _, line := range coveredLines {
-			for _, targetLine := range target.Lines {
-				if line == fmt.Sprintf("%s:%d", target.File, targetLine) {
-					result.HitTarget = true
-					break
-				}
-			}
-			if result.HitTarget {
-				break
-			}
-		}
-	}
+	result.HitTarget = target != nil && e.cfg.Analyzer.CoversTarget(target, coveredLines)
 
 	// Get coverage before any recording
 	oldBasisPoints := e.cfg.Analyzer.GetBBCoverageBasisPoints()
@@ -618,8 +1349,9 @@ func (e *Engine) tryMutatedSeed(s *seed.Seed, target *coverage.TargetInfo) (*see
 
 	// Run oracle for ALL mutated seeds (need to know bug status before deciding to record)
 	foundBug := false
-	if e.cfg.Oracle != nil {
-		bug := e.runOracle(s, compileResult.BinaryPath)
+	var bug *oracle.Bug
+	if e.oracleEnabled() {
+		bug = e.runOracle(s, compileResult)
 		if bug != nil {
 			result.OracleVerdict = seed.OracleVerdictBug
 			result.BugDescription = bug.Description
@@ -632,8 +1364,10 @@ func (e *Engine) tryMutatedSeed(s *seed.Seed, target *coverage.TargetInfo) (*see
 		result.OracleVerdict = seed.OracleVerdictSkipped
 	}
 
-	// Persist oracle verdict to seed metadata
+	// Persist oracle verdict and the outcome to seed metadata
 	s.Meta.OracleVerdict = result.OracleVerdict
+	s.Meta.CompileOK = true
+	s.Meta.DurationMs = time.Since(start).Milliseconds()
 
 	// Only record coverage for "qualified" seeds:
 	// - Seeds with new coverage
@@ -643,6 +1377,7 @@ func (e *Engine) tryMutatedSeed(s *seed.Seed, target *coverage.TargetInfo) (*see
 	result.CoveredNew = hasNewCoverage
 	if hasNewCoverage || result.HitTarget || foundBug {
 		e.cfg.Analyzer.RecordCoverage(int64(s.Meta.ID), coveredLines)
+		e.syncFavored()
 		if s.FlagProfile != nil && s.FlagProfile.Name != "" {
 			e.profileCoverage[s.FlagProfile.Name]++
 		}
@@ -656,15 +1391,21 @@ func (e *Engine) tryMutatedSeed(s *seed.Seed, target *coverage.TargetInfo) (*see
 	s.Meta.NewCoverage = newBasisPoints
 	if newBasisPoints > oldBasisPoints {
 		s.Meta.CovIncrease = newBasisPoints - oldBasisPoints
+		e.noteCoverageGain(s, oldBasisPoints, newBasisPoints)
 	}
 
 	// Add to corpus if: covered new lines, hit target, OR found bug
 	if result.CoveredNew || result.HitTarget || foundBug {
-		s.Meta.Depth = 1
-		if err := e.cfg.Corpus.Add(s); err != nil {
+		if s.Meta.ParentID == 0 {
+			s.Meta.Depth = 1
+		} // otherwise Corpus.Add derives it from the parent
+		e.noteMutation(s)
+		if added, err := e.addToCorpus(s); err != nil {
 			logger.Warn("Failed to add seed to corpus: %v", err)
-		} else {
+		} else if added {
 			e.persistCompilationRecord(s, compileResult)
+			e.saveMinimizedSource(s, bug)
+			e.exportBundle(s, bug, compileResult)
 			reason := "coverage"
 			if foundBug {
 				reason = "bug"
@@ -688,6 +1429,101 @@ func (e *Engine) tryMutatedSeed(s *seed.Seed, target *coverage.TargetInfo) (*see
 	return result, nil
 }
 
+// rejectDefenseDisabling rejects s, recording why in result, if it
+// explicitly disables the active defense mechanism.
+func (e *Engine) rejectDefenseDisabling(s *seed.Seed, result *seedTryResult) bool {
+	violating := seed.FindDefenseDisablingFlags(e.cfg.OracleType, s.CFlags)
+	if len(violating) == 0 {
+		return false
+	}
+	result.Compile = CompileClassRejected
+	result.CompileFailed = true
+	result.CompileError = fmt.Sprintf(
+		"seed violated rule: defense-disabling flag(s) %v were emitted; "+
+			"you MUST keep the defense enabled — do not emit %v or similar flags",
+		violating, violating)
+	logger.Debug("Seed %d rejected: defense-disabling flags %v", s.Meta.ID, violating)
+	return true
+}
+
+// compileWithFixes compiles s and, while it fails to compile, asks the LLM
+// for a minimal fix up to MaxCompileFixRetries times. Fixes are applied to s in
+// place, so the seed keeps its allocated ID and lineage. The last compile
+// result is returned; Success=false means every repair failed.
+func (e *Engine) compileWithFixes(s *seed.Seed) (*compiler.CompileResult, error) {
+	compileResult, err := e.cfg.Compiler.Compile(s)
+	if err != nil || compileResult.Success || e.cfg.MaxCompileFixRetries <= 0 || e.cfg.LLM == nil {
+		return compileResult, err
+	}
+	if compileResult.InternalError() != "" {
+		// The compiler is at fault, not the seed: keep it as it is
+		return compileResult, nil
+	}
+
+	e.compileFixAttempted++
+	for attempt := 1; attempt <= e.cfg.MaxCompileFixRetries; attempt++ {
+		systemPrompt, userPrompt, err := e.cfg.PromptService.GetCompileFixPrompt(s, compileResult.Stderr)
+		if err != nil {
+			logger.Warn("Failed to build compile-fix prompt: %v", err)
+			return compileResult, nil
+		}
+		e.logPromptDebug("compileFix", systemPrompt, userPrompt)
+
+		completion, err := e.completeSeed(callRepair, systemPrompt, userPrompt)
+		if err != nil {
+			logger.Warn("LLM compile-fix call failed: %v", err)
+			return compileResult, nil
+		}
+		fixed, err := e.cfg.PromptService.ParseLLMResponse(completion)
+		if err != nil {
+			logger.Debug("Compile fix %d/%d for seed %d could not be parsed: %v", attempt, e.cfg.MaxCompileFixRetries, s.Meta.ID, err)
+			continue
+		}
+		e.applyCompileFix(s, fixed)
+
+		compileResult, err = e.cfg.Compiler.Compile(s)
+		if err != nil {
+			return compileResult, err
+		}
+		if compileResult.Success {
+			e.compileFixRepaired++
+			logger.Debug("Seed %d compiles after %d fix attempt(s)", s.Meta.ID, attempt)
+			return compileResult, nil
+		}
+		if compileResult.InternalError() != "" {
+			return compileResult, nil
+		}
+		logger.Debug("Compile fix %d/%d for seed %d still fails: %s", attempt, e.cfg.MaxCompileFixRetries, s.Meta.ID, compileResult.Stderr)
+	}
+	return compileResult, nil
+}
+
+// applyCompileFix copies the repaired code into s. Test cases and CFlags are
+// replaced only when the fix provides them, and CFlags that disable the
+// active defense are ignored.
+func (e *Engine) applyCompileFix(s, fixed *seed.Seed) {
+	s.Content = fixed.Content
+	if len(fixed.TestCases) > 0 {
+		s.TestCases = fixed.TestCases
+	}
+	if len(fixed.CFlags) > 0 && len(seed.FindDefenseDisablingFlags(e.cfg.OracleType, fixed.CFlags)) == 0 {
+		s.CFlags = fixed.CFlags
+	}
+}
+
+// noteMutation fills in an empty s.Meta.MutationNote from a diff against
+// the seed's parent.
+func (e *Engine) noteMutation(s *seed.Seed) {
+	if s.Meta.MutationNote != "" || s.Meta.ParentID == 0 {
+		return
+	}
+	parent, err := e.cfg.Corpus.Get(s.Meta.ParentID)
+	if err != nil || parent == nil {
+		return
+	}
+	s.Meta.MutationNote = seed.SummarizeDiff(parent.Content, s.Content, s.Language)
+}
+
 func (e *Engine) assignDefaultProfile(s *seed.Seed) {
 	if e.cfg.Flags == nil || s == nil || s.FlagProfile != nil {
 		return
@@ -747,9 +1583,37 @@ func cloneProfileAxes(axes map[string]string) map[string]string {
 	return cloned
 }
 
-// measureSeed compiles and measures coverage for a seed.
-// Returns the coverage report, compile result, and any error.
-func (e *Engine) measureSeed(s *seed.Seed) (coverage.Report, *compiler.CompileResult, error) {
+// errCompile wraps the errors measureSeed's compile function returns, as
+// opposed to failures of the coverage backend.
+var errCompile = errors.New("compilation failed")
+
+// measureSeed compiles a seed with compile and measures coverage for it.
+// Returns the coverage report, compile result, and any error. The compile
+// result is the only compilation of the seed: runOracle runs the oracle on
+// its binary rather than compiling again.
+func (e *Engine) measureSeed(s *seed.Seed, compile func(*seed.Seed) (*compiler.CompileResult, error)) (coverage.Report, *compiler.CompileResult, error) {
+	start := time.Now()
+	report, compileResult, err := e.compileAndMeasure(s, compile)
+	e.timeStage("compile", start)
+	if err == nil && compileResult.InternalError() != "" {
+		e.recordICE(s, compileResult)
+	}
+	ev := SeedEvent{
+		Iteration: e.iterationCount,
+		SeedID:    s.Meta.ID,
+		ParentID:  s.Meta.ParentID,
+		Compiled:  compileResult != nil && compileResult.Success,
+		Measured:  report != nil,
+	}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+	e.emit(func(sink EventSink) { sink.OnSeedMeasured(ev) })
+	return report, compileResult, err
+}
+
+// compileAndMeasure is measureSeed without the event.
+func (e *Engine) compileAndMeasure(s *seed.Seed, compile func(*seed.Seed) (*compiler.CompileResult, error)) (coverage.Report, *compiler.CompileResult, error) {
 	if preparer, ok := e.cfg.Coverage.(coverage.PreCompileCoverage); ok {
 		if err := preparer.Prepare(); err != nil {
 			return nil, nil, fmt.Errorf("coverage preparation failed: %w", err)
@@ -757,13 +1621,19 @@ func (e *Engine) measureSeed(s *seed.Seed) (coverage.Report, *compiler.CompileRe
 	}
 
 	// Compile
-	compileResult, err := e.cfg.Compiler.Compile(s)
+	compileResult, err := compile(s)
+	e.compiles++
+	e.cfg.Metrics.Compiled(err == nil && compileResult.Success)
 	if err != nil {
-		return nil, compileResult, fmt.Errorf("compilation failed: %w", err)
+		e.compileFailures++
+		return nil, compileResult, fmt.Errorf("%w: %w", errCompile, err)
 	}
 
 	if !compileResult.Success {
+		e.compileFailures++
 		logger.Debug("Seed failed to compile: %s", compileResult.Stderr)
+		// An ICE is recorded by measureSeed; coverage of a crashed
+		// compiler is not measured either
 		return nil, compileResult, nil
 	}
 
@@ -772,6 +1642,12 @@ func (e *Engine) measureSeed(s *seed.Seed) (coverage.Report, *compiler.CompileRe
 		return nil, compileResult, nil
 	}
 
+	// Data the seed binary writes when it runs (GCOV_PREFIX) is only there
+	// if it runs before measuring; the next Prepare deletes it
+	if rc, ok := e.cfg.Coverage.(coverage.RuntimeCoverage); ok && rc.CollectsRuntime() {
+		e.runForCoverage(s, compileResult.BinaryPath)
+	}
+
 	report, err := measureCoverage(e.cfg.Coverage, s)
 	if err != nil {
 		return nil, compileResult, fmt.Errorf("coverage measurement failed: %w", err)
@@ -817,37 +1693,162 @@ func (e *Engine) extractCoveredLines(report coverage.Report) []string {
 	return lines
 }
 
-// runOracle runs bug detection oracle on a seed.
-// binaryPath is the path to the already-compiled binary.
-// Returns the detected bug (if any) for persistence.
-func (e *Engine) runOracle(s *seed.Seed, binaryPath string) *oracle.Bug {
-	if binaryPath == "" {
-		return nil
+// executesSeeds reports whether the oracle is given an executor to run seed
+// binaries, according to the ExecuteSeeds mode.
+func (e *Engine) executesSeeds() bool {
+	switch e.cfg.ExecuteSeeds {
+	case ExecuteNever:
+		return false
+	case ExecuteAlways:
+		return e.cfg.Oracle != nil
+	default:
+		return oracle.NeedsExecution(e.cfg.Oracle)
 	}
+}
 
-	ctx := &oracle.AnalyzeContext{
-		BinaryPath: binaryPath,
-		Executor:   e.cfg.OracleExecutor,
+// runForCoverage runs the compiled seed for the runtime coverage it
+// writes: each test case, or the bare binary when it has none. A failing
+// run is only logged, as the coverage up to it counts. In never mode the
+// seed does not run.
+func (e *Engine) runForCoverage(s *seed.Seed, binaryPath string) {
+	if e.cfg.ExecuteSeeds == ExecuteNever || binaryPath == "" {
+		return
 	}
+	runner := e.cfg.OracleExecutor
+	if runner == nil {
+		runner = executor.NewOracleExecutorAdapter(e.cfg.CoverageTimeout)
+	}
+	testCases := s.TestCases
+	if len(testCases) == 0 {
+		testCases = []seed.TestCase{{RunningCommand: binaryPath}}
+	}
+	for _, tc := range testCases {
+		if _, err := oracle.RunTestCase(runner, binaryPath, tc); err != nil {
+			logger.Debug("Running seed %d for runtime coverage failed: %v", s.Meta.ID, err)
+		}
+	}
+}
 
-	// Fall back to local executor if OracleExecutor not configured
-	if ctx.Executor == nil {
-		ctx.Executor = executor.NewOracleExecutorAdapter(e.cfg.CoverageTimeout)
+// oracleEnabled reports whether the oracle should run on compiled seeds.
+// In never mode only passive oracles run, since they need no execution.
+func (e *Engine) oracleEnabled() bool {
+	if e.cfg.Oracle == nil {
+		return false
 	}
+	return e.cfg.ExecuteSeeds != ExecuteNever || !oracle.NeedsExecution(e.cfg.Oracle)
+}
 
-	// Oracle handles all execution internally (e.g., CanaryOracle does binary search)
-	bug, err := e.cfg.Oracle.Analyze(s, ctx, nil)
+// runOracle runs bug detection oracle on a seed.
+// compileResult is the compilation measureSeed already did; the seed is not
+// compiled again, and the binary only runs if the oracle executes it
+// through its AnalyzeContext.
+// Returns the detected bug (if any) for persistence.
+func (e *Engine) runOracle(s *seed.Seed, compileResult *compiler.CompileResult) *oracle.Bug {
+	bug, err := e.checkOracle(s, compileResult)
 	if err != nil {
 		logger.Error("Oracle analysis failed: %v", err)
 		return nil
 	}
+	return bug
+}
 
-	if bug != nil {
-		logger.Error("BUG FOUND in seed %d: %s", s.Meta.ID, bug.Description)
-		e.bugsFound = append(e.bugsFound, bug)
+// checkOracle is runOracle returning the oracle's error.
+func (e *Engine) checkOracle(s *seed.Seed, compileResult *compiler.CompileResult) (*oracle.Bug, error) {
+	if compileResult == nil || !compileResult.Success || compileResult.BinaryPath == "" {
+		return nil, nil
 	}
 
-	return bug
+	start := time.Now()
+	bug, err := e.analyze(s, compileResult.BinaryPath)
+	e.timeStage("oracle", start)
+	if err != nil {
+		return nil, err
+	}
+
+	if bug != nil && e.knownBug(s.Meta.ID) {
+		logger.Debug("Seed %d triggered its known bug again: %s", s.Meta.ID, bug.Description)
+	} else if bug != nil {
+		e.recordBug(s, bug)
+	}
+
+	return bug, nil
+}
+
+// recordBug adds a new bug to bugsFound, unless a bug with the same
+// signature is there already: then bug is recorded as another occurrence
+// of that first one and marked DuplicateOf it, and the first bug's bundle
+// is updated to list s.
+func (e *Engine) recordBug(s *seed.Seed, bug *oracle.Bug) {
+	bug.Signature = oracle.Signature(e.cfg.OracleType, bug)
+	defer func() {
+		ev := BugEvent{
+			Iteration:   e.iterationCount,
+			SeedID:      s.Meta.ID,
+			Signature:   bug.Signature,
+			DuplicateOf: bug.DuplicateOf,
+			Description: bug.Description,
+		}
+		e.emit(func(sink EventSink) { sink.OnBug(ev) })
+	}()
+	if first := e.bugSignatures[bug.Signature]; first != nil {
+		first.Occurrences = append(first.Occurrences, s.Meta.ID)
+		bug.DuplicateOf = first.Seed.Meta.ID
+		logger.Info("Seed %d triggered the bug of seed %d again (%d hits): %s",
+			s.Meta.ID, bug.DuplicateOf, len(first.Occurrences)+1, bug.Description)
+		e.updateBundleOccurrences(first)
+		return
+	}
+
+	logger.Error("BUG FOUND in seed %d: %s", s.Meta.ID, bug.Description)
+	if e.cfg.MinimizeBugs && bug.Type != oracle.BugTypeICE {
+		e.minimizeBug(s, bug)
+	}
+	e.bugsFound = append(e.bugsFound, bug)
+	e.bugSignatures[bug.Signature] = bug
+	e.cfg.Metrics.BugFound()
+}
+
+// analyze runs the oracle on a compiled seed. The signals and timeouts its
+// runs end with replace s.Meta.ExecSignals.
+func (e *Engine) analyze(s *seed.Seed, binaryPath string) (*oracle.Bug, error) {
+	ctx := &oracle.AnalyzeContext{
+		BinaryPath: binaryPath,
+	}
+
+	s.Meta.ExecSignals = nil
+	if e.executesSeeds() {
+		ctx.Executor = e.cfg.OracleExecutor
+		// Fall back to local executor if OracleExecutor not configured
+		if ctx.Executor == nil {
+			ctx.Executor = executor.NewOracleExecutorAdapter(e.cfg.CoverageTimeout)
+		}
+		ctx.Executor = &signalRecorder{Executor: ctx.Executor, meta: &s.Meta}
+	}
+	if e.cfg.ReferenceCompiler != nil {
+		ctx.Reference = e.buildReference(s)
+	}
+
+	// Oracle handles all execution internally (e.g., CanaryOracle does binary search)
+	return e.cfg.Oracle.Analyze(s, ctx, nil)
+}
+
+// buildReference compiles s with the reference compiler. A seed it rejects
+// gets a ReferenceBuild without a binary.
+func (e *Engine) buildReference(s *seed.Seed) *oracle.ReferenceBuild {
+	ref := &oracle.ReferenceBuild{Executor: e.cfg.ReferenceExecutor}
+	if ref.Executor == nil {
+		ref.Executor = executor.NewOracleExecutorAdapter(e.cfg.CoverageTimeout)
+	}
+	result, err := e.cfg.ReferenceCompiler.Compile(s)
+	switch {
+	case err != nil:
+		logger.Warn("Reference compiler failed on seed %d: %v", s.Meta.ID, err)
+	case !result.Success:
+		logger.Debug("Reference compiler rejected seed %d: %s", s.Meta.ID, result.Stderr)
+	default:
+		ref.BinaryPath = result.BinaryPath
+	}
+	return ref
 }
 
 func (e *Engine) persistCompilationRecord(s *seed.Seed, compileResult *compiler.CompileResult) {
@@ -866,14 +1867,53 @@ func (e *Engine) persistCompilationRecord(s *seed.Seed, compileResult *compiler.
 	}
 }
 
+// trimCorpus archives the corpus seeds whose coverage newer seeds subsume
+// and saves the trimmed mapping.
+func (e *Engine) trimCorpus() {
+	if e.cfg.Analyzer == nil {
+		return
+	}
+	removed, err := e.cfg.Corpus.Trim(e.cfg.Analyzer.GetMapping(), nil)
+	if err != nil {
+		logger.Warn("Failed to trim corpus: %v", err)
+	}
+	if removed == 0 {
+		return
+	}
+	e.trimmedSeeds += removed
+	e.syncFavored()
+	e.saveState()
+}
+
+// syncFavored marks the coverage mapping's favored seeds in the corpus if
+// they changed since the last call. A seed recorded before it is added is
+// marked when added.
+func (e *Engine) syncFavored() {
+	if e.cfg.Analyzer == nil {
+		return
+	}
+	mapping := e.cfg.Analyzer.GetMapping()
+	gen := mapping.FavoredGeneration()
+	if gen == e.favoredGen {
+		return
+	}
+	e.favoredGen = gen
+	ids := mapping.Favored()
+	favored := make([]uint64, len(ids))
+	for i, id := range ids {
+		favored[i] = uint64(id)
+	}
+	e.cfg.Corpus.SetFavored(favored)
+}
+
 // saveState saves the current state.
 func (e *Engine) saveState() {
 	// Update total coverage in global state
-	coverageBP := e.cfg.Analyzer.GetBBCoverageBasisPoints()
+	coverageBP := e.coverageBasisPoints()
 	e.cfg.Corpus.UpdateTotalCoverage(coverageBP)
 
 	// Save coverage mapping
-	if e.cfg.MappingPath != "" {
+	if e.cfg.MappingPath != "" && e.cfg.Analyzer != nil {
 		if err := e.cfg.Analyzer.SaveMapping(e.cfg.MappingPath); err != nil {
 			logger.Warn("Failed to save mapping: %v", err)
 		}
@@ -883,16 +1923,25 @@ func (e *Engine) saveState() {
 	if err := e.cfg.Corpus.Save(); err != nil {
 		logger.Warn("Failed to save corpus: %v", err)
 	}
+
+	if err := e.saveLLMUsage(); err != nil {
+		logger.Warn("%v", err)
+	}
+	if err := e.saveCheckpoint(); err != nil {
+		logger.Warn("%v", err)
+	}
+	ev := StateEvent{Iteration: e.iterationCount}
+	e.emit(func(sink EventSink) { sink.OnStateSaved(ev) })
 }
 
 // finalizeState saves state and finalizes global state when fuzzing completes.
 func (e *Engine) finalizeState() {
 	// Update total coverage
-	coverageBP := e.cfg.Analyzer.GetBBCoverageBasisPoints()
+	coverageBP := e.coverageBasisPoints()
 	e.cfg.Corpus.UpdateTotalCoverage(coverageBP)
 
 	// Save coverage mapping
-	if e.cfg.MappingPath != "" {
+	if e.cfg.MappingPath != "" && e.cfg.Analyzer != nil {
 		if err := e.cfg.Analyzer.SaveMapping(e.cfg.MappingPath); err != nil {
 			logger.Warn("Failed to save mapping: %v", err)
 		}
@@ -902,22 +1951,100 @@ func (e *Engine) finalizeState() {
 	if err := e.cfg.Corpus.Finalize(); err != nil {
 		logger.Warn("Failed to finalize corpus: %v", err)
 	}
+
+	if err := e.saveLLMUsage(); err != nil {
+		logger.Warn("%v", err)
+	}
+	if err := e.saveCheckpoint(); err != nil {
+		logger.Warn("%v", err)
+	}
+	ev := StateEvent{Iteration: e.iterationCount, Final: true}
+	e.emit(func(sink EventSink) { sink.OnStateSaved(ev) })
+}
+
+// printCorpusStats logs a condensed corpus.Stats.
+func (e *Engine) printCorpusStats() {
+	stats, err := e.cfg.Corpus.Stats()
+	if err != nil {
+		logger.Warn("Failed to compute corpus stats: %v", err)
+		return
+	}
+	logger.Info("Corpus:         %d seeds (%d initial, %d favored), max depth %d, avg +%.1f bp, %d bugs, %.1f MB",
+		stats.Total, stats.Initial, stats.Favored, max(len(stats.DepthHistogram)-1, 0), stats.AvgCovIncrease,
+		stats.Bugs, float64(stats.DiskBytes)/(1<<20))
+	if len(stats.TopCoverage) > 0 {
+		top := make([]string, 0, 3)
+		for _, s := range stats.TopCoverage[:min(len(stats.TopCoverage), 3)] {
+			top = append(top, fmt.Sprintf("%d (+%d bp)", s.ID, s.CovIncrease))
+		}
+		logger.Info("Top seeds:      %s", strings.Join(top, ", "))
+	}
+	if len(stats.Rejections) > 0 {
+		reasons := make([]string, 0, len(stats.Rejections))
+		for reason, n := range stats.Rejections {
+			reasons = append(reasons, fmt.Sprintf("%d %s", n, reason))
+		}
+		sort.Strings(reasons)
+		logger.Info("Rejected:       %s", strings.Join(reasons, ", "))
+	}
+	if stats.Evicted > 0 {
+		logger.Info("Evicted:        %d seeds over the corpus limits", stats.Evicted)
+	}
 }
 
 // printSummary prints a summary of the fuzzing session.
 func (e *Engine) printSummary() {
 	elapsed := time.Since(e.startTime)
 
-	// Get final coverage stats
-	funcCov := e.cfg.Analyzer.GetFunctionCoverage()
-
 	logger.Info("=========================================")
 	logger.Info("      FUZZING SUMMARY")
 	logger.Info("=========================================")
 	logger.Info("Duration:       %v", elapsed)
-	logger.Info("Iterations:     %d", e.iterationCount)
-	logger.Info("Targets hit:    %d", e.targetHits)
-	logger.Info("Bugs found:     %d", len(e.bugsFound))
+	if e.stopReason != "" {
+		logger.Info("Stopped:        %s", e.stopReason)
+	}
+	if e.cfg.MaxIterations > 0 {
+		logger.Info("Iterations:     %d of %d", e.iterationCount, e.cfg.MaxIterations)
+	} else {
+		logger.Info("Iterations:     %d", e.iterationCount)
+	}
+	if e.cfg.MaxDuration > 0 {
+		logger.Info("Time budget:    %v of %v", e.elapsed().Round(time.Second), e.cfg.MaxDuration)
+	}
+	if e.coverageGuided() {
+		logger.Info("Mode:           coverage-guided (no CFG)")
+		logger.Info("Coverage gains: %d seeds", e.coverageGains)
+	} else {
+		logger.Info("Targets hit:    %d", e.targetHits)
+	}
+	if occurrences := e.bugOccurrences(); occurrences > len(e.bugsFound) {
+		logger.Info("Bugs found:     %d unique (%d occurrences)", len(e.bugsFound), occurrences)
+	} else {
+		logger.Info("Bugs found:     %d", len(e.bugsFound))
+	}
+	if e.ices > 0 {
+		logger.Info("ICEs:           %d unique (%d seeds)", e.uniqueICEs(), e.ices)
+	}
+	e.printLLMUsage()
+	if e.cfg.ResponseCache != nil {
+		hits, misses := e.cfg.ResponseCache.Stats()
+		logger.Info("LLM cache:      %d hits, %d misses", hits, misses)
+	}
+	if e.skippedIterations > 0 {
+		logger.Info("Dry run:        %d iterations skipped, prompts in %s", e.skippedIterations, e.dryRunDir())
+	}
+	if e.compileFixAttempted > 0 {
+		logger.Info("Compile fixes:  %d/%d repaired", e.compileFixRepaired, e.compileFixAttempted)
+	}
+	if e.duplicateSeeds > 0 {
+		logger.Info("Duplicates:     %d seeds rejected by the corpus", e.duplicateSeeds)
+	}
+	if e.trimmedSeeds > 0 {
+		logger.Info("Trimmed:        %d coverage-subsumed seeds archived", e.trimmedSeeds)
+	}
+	if e.fallbackSeeds > 0 {
+		logger.Info("Fallback:       %d seeds mutated without the LLM", e.fallbackSeeds)
+	}
 	if len(e.profileCoverage) > 0 {
 		logger.Info("Profile coverage hits:")
 		for name, count := range e.profileCoverage {
@@ -930,26 +2057,75 @@ func (e *Engine) printSummary() {
 			logger.Info("  %s => %d", name, count)
 		}
 	}
+	if e.cfg.Corpus != nil {
+		e.printCorpusStats()
+		if tags := e.cfg.Corpus.ConstructStats().Tags; len(tags) > 0 {
+			logger.Info("Seed tags:")
+			for _, tag := range tags {
+				logger.Info("  %s => %d", tag.Name, tag.Seeds)
+			}
+		}
+	}
+	e.printLineage()
+	if e.cfg.Coverage != nil {
+		if stats, err := e.cfg.Coverage.GetStats(); err == nil && stats.ZeroCoverageFunctions > 0 {
+			logger.Info("Untouched target functions: %d", stats.ZeroCoverageFunctions)
+		}
+	}
 	logger.Info("-----------------------------------------")
-	logger.Info("Final BB Coverage:")
-	for name, stats := range funcCov {
-		pct := float64(0)
-		if stats.Total > 0 {
-			pct = float64(stats.Covered) / float64(stats.Total) * 100
+	if e.coverageGuided() {
+		if stats, err := e.coverageStats(); err == nil {
+			logger.Info("Final Coverage: %d/%d lines (%.1f%%)", stats.TotalCoveredLines, stats.TotalLines, stats.CoveragePercentage)
+		}
+	} else {
+		logger.Info("Final BB Coverage:")
+		for name, stats := range e.cfg.Analyzer.GetFunctionCoverage() {
+			pct := float64(0)
+			if stats.Total > 0 {
+				pct = float64(stats.Covered) / float64(stats.Total) * 100
+			}
+			logger.Info("  %s: %d/%d BBs (%.1f%%)", name, stats.Covered, stats.Total, pct)
 		}
-		logger.Info("  %s: %d/%d BBs (%.1f%%)", name, stats.Covered, stats.Total, pct)
 	}
 	logger.Info("=========================================")
 
 	if len(e.bugsFound) > 0 {
 		logger.Info("Bugs:")
 		for i, bug := range e.bugsFound {
+			if len(bug.Occurrences) > 0 {
+				logger.Info("  [%d] Seed %d (%d hits, also seeds %v): %s",
+					i+1, bug.Seed.Meta.ID, len(bug.Occurrences)+1, bug.Occurrences, bug.Description)
+				continue
+			}
 			logger.Info("  [%d] Seed %d: %s", i+1, bug.Seed.Meta.ID, bug.Description)
 		}
 	}
 }
 
-// GetBugs returns all bugs found during fuzzing.
+// addToCorpus adds s to the corpus. A seed the corpus already holds is
+// counted as a duplicate and reported as not added, without an error.
+func (e *Engine) addToCorpus(s *seed.Seed) (bool, error) {
+	err := e.cfg.Corpus.Add(s)
+	var dup *corpus.DuplicateError
+	if errors.As(err, &dup) {
+		e.duplicateSeeds++
+		logger.Info("Seed %d duplicates seed %d, not added to corpus", s.Meta.ID, dup.ExistingID)
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// bugOccurrences counts every seed that triggered one of bugsFound.
+func (e *Engine) bugOccurrences() int {
+	n := 0
+	for _, bug := range e.bugsFound {
+		n += 1 + len(bug.Occurrences)
+	}
+	return n
+}
+
+// GetBugs returns the unique bugs found during fuzzing; other seeds that
+// triggered each are in its Occurrences.
 func (e *Engine) GetBugs() []*oracle.Bug {
 	return e.bugsFound
 }
@@ -4,6 +4,7 @@
 package fuzz
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -154,29 +155,29 @@ type mockLLM struct {
 	callCount int
 }
 
-func (m *mockLLM) GetCompletion(prompt string) (string, error) {
+func (m *mockLLM) GetCompletion(ctx context.Context, prompt string) (string, error) {
 	m.callCount++
 	return m.response, nil
 }
 
-func (m *mockLLM) GetCompletionWithSystem(system, prompt string) (string, error) {
+func (m *mockLLM) GetCompletionWithSystem(ctx context.Context, system, prompt string) (string, error) {
 	m.callCount++
 	return m.response, nil
 }
 
-func (m *mockLLM) Analyze(understanding string, query string, s *seed.Seed, diff string) (string, error) {
+func (m *mockLLM) Analyze(ctx context.Context, understanding string, query string, s *seed.Seed, diff string) (string, error) {
 	return "mock analysis", nil
 }
 
-func (m *mockLLM) Understand(prompt string) (string, error) {
+func (m *mockLLM) Understand(ctx context.Context, prompt string) (string, error) {
 	return "mock understanding", nil
 }
 
-func (m *mockLLM) Generate(understanding, prompt string) (*seed.Seed, error) {
+func (m *mockLLM) Generate(ctx context.Context, understanding, prompt string) (*seed.Seed, error) {
 	return &seed.Seed{Content: m.response}, nil
 }
 
-func (m *mockLLM) Mutate(understanding, prompt string, s *seed.Seed) (*seed.Seed, error) {
+func (m *mockLLM) Mutate(ctx context.Context, understanding, prompt string, s *seed.Seed) (*seed.Seed, error) {
 	return &seed.Seed{Content: m.response}, nil
 }
 
@@ -1,13 +1,29 @@
 package fuzz
 
 import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/zjy-dev/de-fuzz/internal/compiler"
+	"github.com/zjy-dev/de-fuzz/internal/corpus"
 	"github.com/zjy-dev/de-fuzz/internal/coverage"
+	"github.com/zjy-dev/de-fuzz/internal/exec"
+	"github.com/zjy-dev/de-fuzz/internal/llm"
+	"github.com/zjy-dev/de-fuzz/internal/oracle"
+	"github.com/zjy-dev/de-fuzz/internal/prompt"
 	"github.com/zjy-dev/de-fuzz/internal/seed"
+	"github.com/zjy-dev/gcovr-json-util/v2/pkg/gcovr"
 )
 
 func TestEngine_NewEngine(t *testing.T) {
@@ -177,3 +193,1668 @@ func TestEngine_PersistCompilationRecord(t *testing.T) {
 		t.Fatalf("Expected source path %q, got %q", s.Meta.ContentPath, record.SourcePath)
 	}
 }
+
+// countingOracleExecutor counts how often a seed binary is executed. Every
+// run ends with exitCode.
+type countingOracleExecutor struct {
+	calls    int
+	exitCode int
+}
+
+func (c *countingOracleExecutor) ExecuteWithInput(binaryPath string, stdin string) (int, string, string, error) {
+	c.calls++
+	return c.exitCode, "", "", nil
+}
+
+func (c *countingOracleExecutor) ExecuteWithArgs(binaryPath string, args ...string) (int, string, string, error) {
+	c.calls++
+	return c.exitCode, "", "", nil
+}
+
+// executingOracle runs the binary through ctx.Executor when one is provided.
+type executingOracle struct {
+	analyzed int
+	passive  bool
+}
+
+func (o *executingOracle) Analyze(s *seed.Seed, ctx *oracle.AnalyzeContext, results []oracle.Result) (*oracle.Bug, error) {
+	o.analyzed++
+	if ctx.Executor != nil {
+		ctx.Executor.ExecuteWithInput(ctx.BinaryPath, "")
+	}
+	return nil, nil
+}
+
+func (o *executingOracle) Passive() bool {
+	return o.passive
+}
+
+func TestEngine_ExecuteSeedsMode(t *testing.T) {
+	tests := []struct {
+		name         string
+		mode         ExecuteMode
+		passive      bool
+		wantEnabled  bool
+		wantAnalyzed int
+		wantExecuted int
+	}{
+		{name: "never skips active oracle", mode: ExecuteNever, wantEnabled: false, wantAnalyzed: 0, wantExecuted: 0},
+		{name: "never runs passive oracle without executing", mode: ExecuteNever, passive: true, wantEnabled: true, wantAnalyzed: 1, wantExecuted: 0},
+		{name: "auto executes for active oracle", mode: ExecuteAuto, wantEnabled: true, wantAnalyzed: 1, wantExecuted: 1},
+		{name: "auto does not execute for passive oracle", mode: ExecuteAuto, passive: true, wantEnabled: true, wantAnalyzed: 1, wantExecuted: 0},
+		{name: "always executes for passive oracle", mode: ExecuteAlways, passive: true, wantEnabled: true, wantAnalyzed: 1, wantExecuted: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orc := &executingOracle{passive: tt.passive}
+			exec := &countingOracleExecutor{}
+			engine := NewEngine(Config{
+				Oracle:         orc,
+				OracleExecutor: exec,
+				ExecuteSeeds:   tt.mode,
+			})
+
+			if got := engine.oracleEnabled(); got != tt.wantEnabled {
+				t.Fatalf("oracleEnabled() = %v, want %v", got, tt.wantEnabled)
+			}
+			if engine.oracleEnabled() {
+				engine.runOracle(&seed.Seed{Meta: seed.Metadata{ID: 1}}, &compiler.CompileResult{Success: true, BinaryPath: "/tmp/seed.bin"})
+			}
+
+			if orc.analyzed != tt.wantAnalyzed {
+				t.Errorf("oracle analyzed %d times, want %d", orc.analyzed, tt.wantAnalyzed)
+			}
+			if exec.calls != tt.wantExecuted {
+				t.Errorf("executor called %d times, want %d", exec.calls, tt.wantExecuted)
+			}
+		})
+	}
+}
+
+// compiledCoverage measures seeds already compiled by the engine and counts
+// the calls that would compile them again.
+type compiledCoverage struct {
+	coverage.Coverage
+	measured   int
+	recompiled int
+}
+
+func (c *compiledCoverage) MeasureCompiled(s *seed.Seed) (coverage.Report, error) {
+	c.measured++
+	return &stubReport{}, nil
+}
+
+func (c *compiledCoverage) Measure(s *seed.Seed) (coverage.Report, error) {
+	c.recompiled++
+	return &stubReport{}, nil
+}
+
+type stubReport struct{}
+
+func (r *stubReport) ToBytes() ([]byte, error) { return nil, nil }
+
+func TestEngine_TryMutatedSeedCompilesOnce(t *testing.T) {
+	analyzer, corpusManager := newInitialPhase(t)
+	comp := &fixableCompiler{want: "return 0"}
+	cov := &compiledCoverage{}
+	orc := &executingOracle{}
+	exec := &countingOracleExecutor{}
+	engine := NewEngine(Config{
+		Corpus:         corpusManager,
+		Compiler:       comp,
+		Coverage:       cov,
+		Analyzer:       analyzer,
+		Oracle:         orc,
+		OracleExecutor: exec,
+	})
+
+	target := &coverage.TargetInfo{Function: "test_func", BBID: 2, File: "/path/to/test.cc", Lines: []int{10}}
+	result, err := engine.tryMutatedSeed(&seed.Seed{Meta: seed.Metadata{ID: 7}, Content: "int main() { return 0; }"}, target)
+	if err != nil || result.CompileFailed {
+		t.Fatalf("tryMutatedSeed() = %+v, %v; want a compiled seed", result, err)
+	}
+	if comp.calls != 1 || cov.measured != 1 || cov.recompiled != 0 {
+		t.Errorf("compiled %d times, measured %d, recompiled %d; want 1, 1, 0", comp.calls, cov.measured, cov.recompiled)
+	}
+	if orc.analyzed != 1 || exec.calls != 1 {
+		t.Errorf("oracle analyzed %d times, executed %d; want 1 each, on the measured binary", orc.analyzed, exec.calls)
+	}
+}
+
+// statelessLLM only supports single-shot completions.
+type statelessLLM struct {
+	llm.LLM
+	calls int
+}
+
+func (s *statelessLLM) GetCompletionWithSystem(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	s.calls++
+	return "stateless reply", nil
+}
+
+// chattyLLM also accepts a message history.
+type chattyLLM struct {
+	statelessLLM
+	histories [][]llm.Message
+}
+
+func (c *chattyLLM) Chat(ctx context.Context, messages []llm.Message) (string, error) {
+	c.histories = append(c.histories, messages)
+	return "chat reply", nil
+}
+
+func TestEngine_ConversationMode(t *testing.T) {
+	promptService, err := prompt.NewPromptService(t.TempDir(), "", prompt.NewBuilder(1, "", nil))
+	if err != nil {
+		t.Fatalf("NewPromptService() failed: %v", err)
+	}
+	system := "system prompt " + strings.Repeat("s", 400)
+	full := "full constraint prompt " + strings.Repeat("f", 4000)
+
+	t.Run("follow-ups send only the new message", func(t *testing.T) {
+		client := &chattyLLM{}
+		engine := NewEngine(Config{LLM: client, PromptService: promptService, Conversation: true})
+
+		conv := engine.newConversation()
+		if conv == nil {
+			t.Fatal("expected a conversation for a chat-capable client")
+		}
+		_, first, err := engine.askForSeed(callGenerate, conv, system, full)
+		if err != nil {
+			t.Fatalf("askForSeed() failed: %v", err)
+		}
+		if !conversing(conv) {
+			t.Fatal("conversation should have a completed turn")
+		}
+		_, second, err := engine.askForSeed(callGenerate, conv, "", "attempt failed")
+		if err != nil {
+			t.Fatalf("askForSeed() failed: %v", err)
+		}
+
+		if first.contextTokens != 0 || first.promptTokens != promptService.EstimateTokens(system+full) {
+			t.Errorf("first turn usage = %+v", first)
+		}
+		if second.promptTokens != promptService.EstimateTokens("attempt failed") {
+			t.Errorf("follow-up should only count the new message, got %+v", second)
+		}
+		if second.contextTokens < first.promptTokens {
+			t.Errorf("follow-up context (%d) should include the first turn (%d)", second.contextTokens, first.promptTokens)
+		}
+		if len(client.histories) != 2 || len(client.histories[1]) != 4 || client.calls != 0 {
+			t.Errorf("expected two chat calls replaying the history, got %d (stateless calls: %d)", len(client.histories), client.calls)
+		}
+	})
+
+	t.Run("falls back to stateless prompts", func(t *testing.T) {
+		client := &statelessLLM{}
+		engine := NewEngine(Config{LLM: client, PromptService: promptService, Conversation: true})
+
+		conv := engine.newConversation()
+		if conv != nil {
+			t.Fatal("expected no conversation for a client without Chat")
+		}
+		reply, usage, err := engine.askForSeed(callGenerate, conv, system, full)
+		if err != nil || reply != "stateless reply" || client.calls != 1 {
+			t.Fatalf("askForSeed() = %q, %v (calls %d)", reply, err, client.calls)
+		}
+		if usage.contextTokens != 0 || usage.promptTokens != promptService.EstimateTokens(system+full) {
+			t.Errorf("stateless usage = %+v", usage)
+		}
+	})
+}
+
+func TestEngine_ResponseCache(t *testing.T) {
+	promptService, err := prompt.NewPromptService(t.TempDir(), "", prompt.NewBuilder(1, "", nil))
+	if err != nil {
+		t.Fatalf("NewPromptService() failed: %v", err)
+	}
+
+	t.Run("perturbs repeated prompts", func(t *testing.T) {
+		cache, _ := llm.NewResponseCache("")
+		client := &chattyLLM{}
+		engine := NewEngine(Config{LLM: client, PromptService: promptService, ResponseCache: cache})
+
+		for i := 0; i < 3; i++ {
+			if _, _, err := engine.askForSeed(callGenerate, nil, "sys", "same prompt"); err != nil {
+				t.Fatalf("askForSeed() failed: %v", err)
+			}
+		}
+		if client.calls != 3 {
+			t.Fatalf("expected every request to reach the LLM, got %d calls", client.calls)
+		}
+		hits, misses := cache.Stats()
+		if hits != 2 || misses != 1 {
+			t.Errorf("cache stats = %d hits, %d misses, want 2, 1", hits, misses)
+		}
+		// Each perturbed prompt is distinct, so it is cached separately.
+		if _, _, ok := cache.Get("sys", prompt.PerturbPrompt("same prompt", 2)); !ok {
+			t.Error("second perturbed prompt should be cached")
+		}
+	})
+
+	t.Run("reuses cached completion", func(t *testing.T) {
+		cache, _ := llm.NewResponseCache("")
+		client := &chattyLLM{}
+		engine := NewEngine(Config{LLM: client, PromptService: promptService, ResponseCache: cache, CacheOnHit: CacheReuse})
+
+		first, _, _ := engine.askForSeed(callGenerate, nil, "sys", "same prompt")
+		second, usage, err := engine.askForSeed(callGenerate, nil, "sys", "same prompt")
+		if err != nil {
+			t.Fatalf("askForSeed() failed: %v", err)
+		}
+		if client.calls != 1 || second != first {
+			t.Errorf("expected the cached completion without a second call, got %q after %d calls", second, client.calls)
+		}
+		if usage.promptTokens != 0 {
+			t.Errorf("a reused completion costs no prompt tokens, got %d", usage.promptTokens)
+		}
+	})
+}
+
+// scriptedLLM returns a fixed completion.
+type scriptedLLM struct {
+	llm.LLM
+	reply string
+}
+
+func (s *scriptedLLM) GetCompletionWithSystem(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	return s.reply, nil
+}
+
+func TestEngine_GenerateCandidateSeeds(t *testing.T) {
+	builder := prompt.NewBuilder(0, "", nil)
+	builder.CandidatesPerCall = 3
+	baseDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(baseDir, "constraint.md"), []byte("system"), 0644); err != nil {
+		t.Fatalf("Failed to write base prompt: %v", err)
+	}
+	promptService, err := prompt.NewPromptService(baseDir, "", builder)
+	if err != nil {
+		t.Fatalf("NewPromptService() failed: %v", err)
+	}
+	corpusManager := corpus.NewFileManager(t.TempDir())
+	if err := corpusManager.Initialize(); err != nil {
+		t.Fatalf("Initialize() failed: %v", err)
+	}
+
+	// The recorded reply has fewer candidates than asked for.
+	client, err := llm.New(filepath.Join("testdata", "replay", "remixer.yaml"), 0)
+	if err != nil {
+		t.Fatalf("llm.New() failed: %v", err)
+	}
+	engine := NewEngine(Config{LLM: client, PromptService: promptService, Corpus: corpusManager})
+
+	ctx := &prompt.TargetContext{TargetFunction: "expand_used_vars", TargetBBID: 9, TargetLines: []int{200}, BaseSeedID: 7}
+	seeds, err := engine.generateCandidateSeeds(ctx, nil, 3)
+	if err != nil {
+		t.Fatalf("generateCandidateSeeds() failed: %v", err)
+	}
+	if len(seeds) != 2 {
+		t.Fatalf("got %d candidates, want 2", len(seeds))
+	}
+	if seeds[0].Meta.ID == seeds[1].Meta.ID {
+		t.Errorf("candidates should get distinct IDs, both have %d", seeds[0].Meta.ID)
+	}
+	for i, s := range seeds {
+		if s.Meta.ParentID != 7 {
+			t.Errorf("candidate %d parent = %d, want 7", i+1, s.Meta.ParentID)
+		}
+	}
+
+	// Candidates over the cap are dropped before they take an ID.
+	seeds, err = engine.generateCandidateSeeds(ctx, nil, 1)
+	if err != nil {
+		t.Fatalf("generateCandidateSeeds() failed: %v", err)
+	}
+	if len(seeds) != 1 {
+		t.Fatalf("got %d candidates, want 1", len(seeds))
+	}
+	if next := corpusManager.AllocateID(); next != seeds[0].Meta.ID+1 {
+		t.Errorf("next ID = %d after candidate %d, want no ID used by the dropped candidate", next, seeds[0].Meta.ID)
+	}
+}
+
+func TestEngine_DecaysFailedTargets(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfgContent := `;; Function first (_Z5firsti, funcdef_no=1, decl_uid=100, cgraph_uid=1, symbol_order=1)
+;; 2 succs { 3 4 }
+int first (int a)
+{
+  <bb 2> :
+  [/path/to/test.cc:10:3] if (a > 0)
+}
+
+;; Function second (_Z6secondi, funcdef_no=2, decl_uid=101, cgraph_uid=2, symbol_order=2)
+;; 2 succs { 3 }
+int second (int b)
+{
+  <bb 2> :
+  [/path/to/test.cc:20:3] b++;
+}
+`
+	cfgPath := filepath.Join(tmpDir, "test.cc.015t.cfg")
+	if err := os.WriteFile(cfgPath, []byte(cfgContent), 0644); err != nil {
+		t.Fatalf("Failed to write CFG file: %v", err)
+	}
+	analyzer, err := coverage.NewAnalyzer([]string{cfgPath}, []string{"first", "second"}, "", filepath.Join(tmpDir, "mapping.json"), 0.8)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	baseDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(baseDir, "constraint.md"), []byte("system"), 0644); err != nil {
+		t.Fatalf("Failed to write base prompt: %v", err)
+	}
+	promptService, err := prompt.NewPromptService(baseDir, "", prompt.NewBuilder(0, "", nil))
+	if err != nil {
+		t.Fatalf("NewPromptService() failed: %v", err)
+	}
+	_, corpusManager := newInitialPhase(t)
+
+	// Every seed compiles but covers nothing, so each target fails
+	engine := NewEngine(Config{
+		Corpus:        corpusManager,
+		Compiler:      &fixableCompiler{want: "return"},
+		Analyzer:      analyzer,
+		LLM:           &scriptedLLM{reply: "```c\nint main() { return 1; }\n```"},
+		PromptService: promptService,
+		MaxIterations: 1,
+	})
+	if err := engine.Run(context.Background()); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if got := analyzer.GetBBWeight("first", 2); got != 2*0.8 {
+		t.Errorf("first:BB2 weight after a failed target = %v, want %v", got, 2*0.8)
+	}
+
+	// first:BB2 starts with 2 successors to second:BB2's 1; after four
+	// failures (2 * 0.8^4 < 1) the fifth iteration targets second:BB2
+	engine.cfg.MaxIterations = 5
+	if err := engine.Run(context.Background()); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if first, second := analyzer.GetBBAttempts("first", 2), analyzer.GetBBAttempts("second", 2); first != 4 || second != 1 {
+		t.Errorf("attempts = first:BB2 %d, second:BB2 %d; want 4, 1", first, second)
+	}
+}
+
+// recordingDivergence records the sources it is asked to compare, read
+// when Analyze is called.
+type recordingDivergence struct {
+	paths   []string
+	sources []string
+}
+
+func (d *recordingDivergence) Analyze(baseSeedPath, mutatedSeedPath string, compilerPath string) (*coverage.DivergencePoint, error) {
+	for _, path := range []string{baseSeedPath, mutatedSeedPath} {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		d.paths = append(d.paths, path)
+		d.sources = append(d.sources, string(source))
+	}
+	return nil, nil
+}
+
+func (d *recordingDivergence) Cleanup() error { return nil }
+
+func TestEngine_DivergenceAnalysisGetsSeedSources(t *testing.T) {
+	_, corpusManager := newInitialPhase(t, &seed.Seed{Content: "int main() { return 0; }"})
+	baseDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(baseDir, "constraint.md"), []byte("system"), 0644); err != nil {
+		t.Fatalf("Failed to write base prompt: %v", err)
+	}
+	promptService, err := prompt.NewPromptService(baseDir, "", prompt.NewBuilder(0, "", nil))
+	if err != nil {
+		t.Fatalf("NewPromptService() failed: %v", err)
+	}
+	stateDir := t.TempDir()
+	divergence := &recordingDivergence{}
+	engine := NewEngine(Config{
+		Corpus:             corpusManager,
+		Compiler:           &fixableCompiler{want: "return"},
+		Analyzer:           newCheckpointAnalyzer(t),
+		LLM:                &scriptedLLM{reply: "```c\nint main() { return 1; }\n```"},
+		PromptService:      promptService,
+		DivergenceAnalyzer: divergence,
+		CompilerPath:       "gcc",
+		MaxRetries:         1,
+		MappingPath:        filepath.Join(stateDir, "coverage_mapping.json"),
+	})
+	engine.iterationCount = 3
+
+	target := &coverage.TargetInfo{Function: "test_func", BBID: 3, File: "/path/to/test.cc", Lines: []int{11}, BaseSeed: "1"}
+	if hit, _, err := engine.solveConstraint(target, 1); err != nil || hit {
+		t.Fatalf("solveConstraint() = %v, %v; want a missed target", hit, err)
+	}
+
+	want := []string{"int main() { return 0; }", "int main() { return 1; }"}
+	if fmt.Sprint(divergence.sources) != fmt.Sprint(want) {
+		t.Fatalf("divergence analyzer read %q, want the base and mutated seed sources %q", divergence.sources, want)
+	}
+	if base, _ := corpusManager.Get(1); divergence.paths[0] != base.Meta.ContentPath {
+		t.Errorf("base seed path = %s, want its corpus file %s", divergence.paths[0], base.Meta.ContentPath)
+	}
+	if want := filepath.Join(stateDir, "scratch", "iter-0003"); filepath.Dir(divergence.paths[1]) != want {
+		t.Errorf("mutated seed path = %s, want a file in %s", divergence.paths[1], want)
+	}
+	if _, err := os.Stat(filepath.Join(stateDir, "scratch", "iter-0003")); !os.IsNotExist(err) {
+		t.Errorf("scratch directory left behind after the target (stat: %v)", err)
+	}
+}
+
+// fixableCompiler fails to compile seeds that do not contain want.
+type fixableCompiler struct {
+	want  string
+	calls int
+}
+
+func (c *fixableCompiler) Compile(s *seed.Seed) (*compiler.CompileResult, error) {
+	c.calls++
+	if !strings.Contains(s.Content, c.want) {
+		return &compiler.CompileResult{Success: false, Stderr: "seed.c:1: error: 'x' undeclared"}, nil
+	}
+	return &compiler.CompileResult{Success: true, BinaryPath: "/tmp/seed"}, nil
+}
+
+func (c *fixableCompiler) GetWorkDir() string { return "" }
+
+func TestEngine_CompileWithFixes(t *testing.T) {
+	baseDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(baseDir, "compile_error.md"), []byte("system"), 0644); err != nil {
+		t.Fatalf("Failed to write base prompt: %v", err)
+	}
+	promptService, err := prompt.NewPromptService(baseDir, "", prompt.NewBuilder(0, "", nil))
+	if err != nil {
+		t.Fatalf("NewPromptService() failed: %v", err)
+	}
+
+	newSeed := func() *seed.Seed {
+		return &seed.Seed{
+			Content: "int main() { return x; }",
+			Meta:    seed.Metadata{ID: 12, ParentID: 4, Depth: 2},
+		}
+	}
+
+	t.Run("repaired", func(t *testing.T) {
+		comp := &fixableCompiler{want: "int x;"}
+		client := &scriptedLLM{reply: "```c\nint x;\nint main() { return x; }\n```"}
+		engine := NewEngine(Config{Compiler: comp, LLM: client, PromptService: promptService, MaxCompileFixRetries: 2})
+
+		s := newSeed()
+		result, err := engine.compileWithFixes(s)
+		if err != nil {
+			t.Fatalf("compileWithFixes() failed: %v", err)
+		}
+		if !result.Success {
+			t.Fatal("seed should compile after the fix")
+		}
+		if !strings.Contains(s.Content, "int x;") {
+			t.Errorf("fix was not applied, content = %q", s.Content)
+		}
+		if s.Meta.ID != 12 || s.Meta.ParentID != 4 || s.Meta.Depth != 2 {
+			t.Errorf("repaired seed should keep its ID and lineage, got %+v", s.Meta)
+		}
+		if comp.calls != 2 || engine.compileFixRepaired != 1 {
+			t.Errorf("calls = %d, repaired = %d; want 2 and 1", comp.calls, engine.compileFixRepaired)
+		}
+	})
+
+	t.Run("gives up", func(t *testing.T) {
+		comp := &fixableCompiler{want: "int x;"}
+		client := &scriptedLLM{reply: "```c\nint main() { return y; }\n```"}
+		engine := NewEngine(Config{Compiler: comp, LLM: client, PromptService: promptService, MaxCompileFixRetries: 2})
+
+		result, err := engine.compileWithFixes(newSeed())
+		if err != nil {
+			t.Fatalf("compileWithFixes() failed: %v", err)
+		}
+		if result.Success {
+			t.Error("seed should still fail to compile")
+		}
+		if comp.calls != 3 {
+			t.Errorf("compile calls = %d, want 1 + 2 fixes", comp.calls)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		comp := &fixableCompiler{want: "int x;"}
+		engine := NewEngine(Config{Compiler: comp, LLM: &scriptedLLM{}, PromptService: promptService})
+
+		if result, _ := engine.compileWithFixes(newSeed()); result.Success || comp.calls != 1 {
+			t.Errorf("no fix should be attempted when MaxCompileFixRetries is 0 (calls = %d)", comp.calls)
+		}
+	})
+}
+
+// capturingLLM records the system prompt of every request.
+type capturingLLM struct {
+	llm.LLM
+	reply   string
+	systems []string
+}
+
+func (c *capturingLLM) GetCompletionWithSystem(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	c.systems = append(c.systems, systemPrompt)
+	return c.reply, nil
+}
+
+func TestEngine_SystemPromptOverride(t *testing.T) {
+	baseDir := t.TempDir()
+	for _, name := range []string{"constraint.md", "compile_error.md"} {
+		if err := os.WriteFile(filepath.Join(baseDir, name), []byte("base "+name), 0644); err != nil {
+			t.Fatalf("Failed to write base prompt: %v", err)
+		}
+	}
+
+	for _, disabled := range []bool{false, true} {
+		builder := prompt.NewBuilder(0, "", nil)
+		builder.DisableSystemOverrides = disabled
+		promptService, err := prompt.NewPromptService(baseDir, "", builder)
+		if err != nil {
+			t.Fatalf("NewPromptService() failed: %v", err)
+		}
+
+		client := &capturingLLM{reply: "```c\nint x;\nint main() { return x; }\n```"}
+		engine := NewEngine(Config{
+			Compiler:             &fixableCompiler{want: "int x;"},
+			LLM:                  client,
+			PromptService:        promptService,
+			MaxCompileFixRetries: 1,
+		})
+		if _, err := engine.compileWithFixes(&seed.Seed{Content: "int main() { return x; }"}); err != nil {
+			t.Fatalf("compileWithFixes() failed: %v", err)
+		}
+
+		ctx := &prompt.TargetContext{TargetFunction: "expand_used_vars", TargetBBID: 9, TargetLines: []int{200}}
+		system, _, err := promptService.GetRefinedPrompt(ctx, &prompt.DivergenceInfo{
+			DivergentFunction:    "expand_stack_vars",
+			MutatedSeedCode:      "int main() { return 0; }",
+			SystemPromptOverride: promptService.RefinementSystemPrompt(ctx),
+		})
+		if err != nil {
+			t.Fatalf("GetRefinedPrompt() failed: %v", err)
+		}
+		if _, err := engine.completeSeed(callGenerate, system, "user"); err != nil {
+			t.Fatalf("completeSeed() failed: %v", err)
+		}
+
+		if len(client.systems) != 2 {
+			t.Fatalf("got %d LLM calls, want 2", len(client.systems))
+		}
+		repair, refine := client.systems[0], client.systems[1]
+		if disabled {
+			if repair != "base compile_error.md" || refine != "base constraint.md" {
+				t.Errorf("with overrides disabled, got system prompts %q and %q", repair, refine)
+			}
+			continue
+		}
+		if !strings.Contains(repair, "repairing a failed compiler-fuzzing mutation") {
+			t.Errorf("compile fix system prompt = %q", repair)
+		}
+		if !strings.Contains(refine, "expand_used_vars") || strings.Contains(refine, "base constraint.md") {
+			t.Errorf("refinement system prompt = %q", refine)
+		}
+	}
+}
+
+func TestEngine_DryRunPrompts(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfgContent := `;; Function test_func (_Z9test_funcii, funcdef_no=1, decl_uid=100, cgraph_uid=1, symbol_order=1)
+;; 2 succs { 3 4 }
+;; 3 succs { 4 }
+;; 4 succs { 1 }
+int test_func (int a, int b)
+{
+  <bb 2> :
+  [/path/to/test.cc:10:3] if (a > b)
+
+  <bb 3> :
+  [/path/to/test.cc:11:5] result = a;
+
+  <bb 4> :
+  [/path/to/test.cc:13:3] return result;
+}
+`
+	cfgPath := filepath.Join(tmpDir, "test.cc.015t.cfg")
+	if err := os.WriteFile(cfgPath, []byte(cfgContent), 0644); err != nil {
+		t.Fatalf("Failed to write CFG file: %v", err)
+	}
+	analyzer, err := coverage.NewAnalyzer([]string{cfgPath}, []string{"test_func"}, "", filepath.Join(tmpDir, "mapping.json"), 0.8)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+
+	baseDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(baseDir, "constraint.md"), []byte("constraint system"), 0644); err != nil {
+		t.Fatalf("Failed to write base prompt: %v", err)
+	}
+	promptService, err := prompt.NewPromptService(baseDir, "", prompt.NewBuilder(0, "", nil))
+	if err != nil {
+		t.Fatalf("NewPromptService() failed: %v", err)
+	}
+
+	client := &capturingLLM{}
+	dryRunDir := filepath.Join(tmpDir, "dry_run_prompts")
+	engine := NewEngine(Config{
+		Analyzer:      analyzer,
+		LLM:           client,
+		PromptService: promptService,
+		DryRunPrompts: true,
+		DryRunDir:     dryRunDir,
+	})
+	engine.iterationCount = 1
+
+	target := &coverage.TargetInfo{Function: "test_func", BBID: 3, Lines: []int{11}, SuccessorCount: 1}
+	hit, retries, err := engine.solveConstraint(target, engine.cfg.MaxRetries)
+	if err != nil || hit || retries != 0 {
+		t.Fatalf("solveConstraint() = %v, %d, %v; want a skipped attempt", hit, retries, err)
+	}
+	if len(client.systems) != 0 {
+		t.Errorf("dry run should not call the LLM, got %d calls", len(client.systems))
+	}
+
+	system, err := os.ReadFile(filepath.Join(dryRunDir, "iter-0001_test_func_bb3_constraint.system.md"))
+	if err != nil {
+		t.Fatalf("system prompt not written: %v", err)
+	}
+	user, err := os.ReadFile(filepath.Join(dryRunDir, "iter-0001_test_func_bb3_constraint.user.md"))
+	if err != nil {
+		t.Fatalf("user prompt not written: %v", err)
+	}
+
+	// The files hold exactly what a real request would send.
+	ctx, err := prompt.BuildTargetContextFromCFG(target, nil, analyzer)
+	if err != nil {
+		t.Fatalf("BuildTargetContextFromCFG() failed: %v", err)
+	}
+	wantSystem, wantUser, err := promptService.GetConstraintPrompt(ctx)
+	if err != nil {
+		t.Fatalf("GetConstraintPrompt() failed: %v", err)
+	}
+	if string(system) != wantSystem || string(user) != wantUser {
+		t.Error("dry-run prompts differ from the prompts a real request would send")
+	}
+}
+
+// newDryRunAnalyzer returns an analyzer with two targets, the entries of
+// test_func (two successors) and helper (one), selected in that order.
+func newDryRunAnalyzer(t *testing.T) *coverage.Analyzer {
+	t.Helper()
+	tmpDir := t.TempDir()
+	cfgContent := `;; Function test_func (_Z9test_funcii, funcdef_no=1, decl_uid=100, cgraph_uid=1, symbol_order=1)
+;; 2 succs { 3 4 }
+;; 3 succs { 4 }
+;; 4 succs { 1 }
+int test_func (int a, int b)
+{
+  <bb 2> :
+  [/path/to/test.cc:10:3] if (a > b)
+
+  <bb 3> :
+  [/path/to/test.cc:11:5] result = a;
+
+  <bb 4> :
+  [/path/to/test.cc:13:3] return result;
+}
+
+;; Function helper (_Z6helperi, funcdef_no=2, decl_uid=200, cgraph_uid=2, symbol_order=2)
+;; 2 succs { 3 }
+;; 3 succs { 1 }
+int helper (int a)
+{
+  <bb 2> :
+  [/path/to/test.cc:20:3] a = a + 1;
+
+  <bb 3> :
+  [/path/to/test.cc:21:3] return a;
+}
+`
+	cfgPath := filepath.Join(tmpDir, "test.cc.015t.cfg")
+	if err := os.WriteFile(cfgPath, []byte(cfgContent), 0644); err != nil {
+		t.Fatalf("Failed to write CFG file: %v", err)
+	}
+	analyzer, err := coverage.NewAnalyzer([]string{cfgPath}, []string{"test_func", "helper"}, "", filepath.Join(tmpDir, "mapping.json"), 0.8)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	return analyzer
+}
+
+// newDryRunConfig returns a config whose iterations only render prompts
+// for newDryRunAnalyzer's targets; each one is counted as skipped.
+func newDryRunConfig(t *testing.T) Config {
+	t.Helper()
+	baseDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(baseDir, "constraint.md"), []byte("constraint system"), 0644); err != nil {
+		t.Fatalf("Failed to write base prompt: %v", err)
+	}
+	promptService, err := prompt.NewPromptService(baseDir, "", prompt.NewBuilder(0, "", nil))
+	if err != nil {
+		t.Fatalf("NewPromptService() failed: %v", err)
+	}
+	return Config{
+		Analyzer:      newDryRunAnalyzer(t),
+		PromptService: promptService,
+		DryRunPrompts: true,
+		DryRunDir:     filepath.Join(t.TempDir(), "dry_run_prompts"),
+	}
+}
+
+func TestEngine_MaxDuration(t *testing.T) {
+	for _, tt := range []struct {
+		name           string
+		maxIterations  int
+		maxDuration    time.Duration
+		priorElapsed   time.Duration
+		wantIterations int
+		wantReason     string
+	}{
+		{"budget spent", 5, time.Nanosecond, 0, 0, "time budget spent"},
+		{"spent by earlier runs", -1, time.Hour, time.Hour, 0, "time budget spent"},
+		{"iterations first", 2, time.Hour, 0, 2, "max iterations reached"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, corpusManager := newInitialPhase(t)
+			cfg := newDryRunConfig(t)
+			cfg.Corpus = corpusManager
+			cfg.MaxIterations = tt.maxIterations
+			cfg.MaxDuration = tt.maxDuration
+			cfg.CheckpointPath = filepath.Join(t.TempDir(), "engine_checkpoint.json")
+			engine := NewEngine(cfg)
+			engine.priorElapsed = tt.priorElapsed
+			if err := engine.Run(context.Background()); err != nil {
+				t.Fatalf("Run() failed: %v", err)
+			}
+			if engine.iterationCount != tt.wantIterations || engine.stopReason != tt.wantReason {
+				t.Errorf("Run() = %d iterations, stopped for %q; want %d, %q",
+					engine.iterationCount, engine.stopReason, tt.wantIterations, tt.wantReason)
+			}
+
+			data, err := os.ReadFile(cfg.CheckpointPath)
+			if err != nil {
+				t.Fatalf("checkpoint not written: %v", err)
+			}
+			var cp Checkpoint
+			if err := json.Unmarshal(data, &cp); err != nil {
+				t.Fatal(err)
+			}
+			if cp.MaxIterations != tt.maxIterations || cp.MaxDurationSeconds != tt.maxDuration.Seconds() ||
+				cp.ElapsedSeconds < tt.priorElapsed.Seconds() || cp.StopReason != tt.wantReason {
+				t.Errorf("checkpoint limits = %d iterations, %vs budget, %vs elapsed, %q",
+					cp.MaxIterations, cp.MaxDurationSeconds, cp.ElapsedSeconds, cp.StopReason)
+			}
+		})
+	}
+}
+
+func TestEngine_DryRunPromptsUnlimited(t *testing.T) {
+	_, corpusManager := newInitialPhase(t)
+	cfg := newDryRunConfig(t)
+	cfg.Corpus = corpusManager
+	cfg.MaxIterations = -1
+	engine := NewEngine(cfg)
+
+	done := make(chan error, 1)
+	go func() { done <- engine.Run(context.Background()) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run() failed: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run() did not return without an iteration limit")
+	}
+	if engine.stopReason != "all targets rendered" || engine.skippedIterations != 2 {
+		t.Errorf("Run() skipped %d iterations, stopped for %q; want 2, \"all targets rendered\"",
+			engine.skippedIterations, engine.stopReason)
+	}
+
+	// Each target is rendered exactly once.
+	entries, err := os.ReadDir(cfg.DryRunDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendered := make(map[string]int)
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasSuffix(name, ".user.md") {
+			rendered[name[strings.Index(name, "_")+1:strings.LastIndex(name, "_")]]++
+		}
+	}
+	for _, bb := range []string{"test_func_bb2", "helper_bb2"} {
+		if rendered[bb] != 1 {
+			t.Errorf("%s rendered %d times, want once (rendered: %v)", bb, rendered[bb], rendered)
+		}
+	}
+}
+
+// meteredLLM answers every request with reply and reports fixed usage per
+// call, like a provider that returns token counts.
+type meteredLLM struct {
+	llm.LLM
+	reply string
+	usage llm.Usage
+}
+
+func (m *meteredLLM) GetCompletionWithSystem(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	m.usage = m.usage.Add(llm.Usage{Calls: 1, PromptTokens: 100, CompletionTokens: 40, Cost: 0.01})
+	return m.reply, nil
+}
+
+func (m *meteredLLM) Usage() llm.Usage {
+	return m.usage
+}
+
+func TestEngine_LLMUsageByCallType(t *testing.T) {
+	promptService, err := prompt.NewPromptService(t.TempDir(), "", prompt.NewBuilder(0, "", nil))
+	if err != nil {
+		t.Fatalf("NewPromptService() failed: %v", err)
+	}
+	usagePath := filepath.Join(t.TempDir(), "llm_usage.json")
+	newEngine := func() *Engine {
+		return NewEngine(Config{
+			LLM:           &meteredLLM{reply: "```c\nint main() { return 0; }\n```"},
+			PromptService: promptService,
+			UsagePath:     usagePath,
+		})
+	}
+
+	engine := newEngine()
+	for _, callType := range []string{callGenerate, callGenerate, callRefine} {
+		if _, err := engine.completeSeed(callType, "sys", "user"); err != nil {
+			t.Fatalf("completeSeed() failed: %v", err)
+		}
+	}
+	if got := engine.llmUsage[callGenerate]; got.Calls != 2 || got.Tokens() != 280 {
+		t.Errorf("generate usage = %+v, want 2 calls and 280 tokens", got)
+	}
+	if got := engine.llmUsage[callRefine]; got.Calls != 1 || got.PromptTokens != 100 || got.CompletionTokens != 40 {
+		t.Errorf("refine usage = %+v, want 1 call with 100+40 tokens", got)
+	}
+	if err := engine.saveLLMUsage(); err != nil {
+		t.Fatalf("saveLLMUsage() failed: %v", err)
+	}
+
+	// A resumed run keeps accumulating on top of the saved totals.
+	resumed := newEngine()
+	if err := resumed.loadLLMUsage(); err != nil {
+		t.Fatalf("loadLLMUsage() failed: %v", err)
+	}
+	if _, err := resumed.completeSeed(callGenerate, "sys", "user"); err != nil {
+		t.Fatalf("completeSeed() failed: %v", err)
+	}
+	total := resumed.totalLLMUsage()
+	if total.Calls != 4 || total.Tokens() != 560 {
+		t.Errorf("total usage = %+v, want 4 calls and 560 tokens", total)
+	}
+	if total.Cost < 0.0399 || total.Cost > 0.0401 {
+		t.Errorf("total cost = %f, want 0.04", total.Cost)
+	}
+}
+
+// streamingLLM streams its chunks and records how many were read.
+type streamingLLM struct {
+	llm.LLM
+	chunks []string
+	read   int
+}
+
+func (s *streamingLLM) StreamCompletionWithSystem(ctx context.Context, systemPrompt, userPrompt string, format llm.ResponseFormat, onChunk func(string) bool) (string, error) {
+	var text strings.Builder
+	for _, chunk := range s.chunks {
+		s.read++
+		text.WriteString(chunk)
+		if !onChunk(chunk) {
+			break
+		}
+	}
+	return text.String(), nil
+}
+
+func TestEngine_CompleteSeedStreamStopsEarly(t *testing.T) {
+	promptService, err := prompt.NewPromptService(t.TempDir(), "", prompt.NewBuilder(0, "", nil))
+	if err != nil {
+		t.Fatalf("NewPromptService() failed: %v", err)
+	}
+	client := &streamingLLM{chunks: []string{"```c\n", "int main() { return 0; }\n", "```\n"}}
+	for i := 0; i < 40; i++ {
+		client.chunks = append(client.chunks, "Here is why this works. ")
+	}
+	engine := NewEngine(Config{LLM: client, PromptService: promptService, Stream: true})
+
+	completion, err := engine.completeSeed(callGenerate, "sys", "user")
+	if err != nil {
+		t.Fatalf("completeSeed() failed: %v", err)
+	}
+	if client.read == len(client.chunks) {
+		t.Error("stream was read to the end; expected it to stop after the code")
+	}
+	s, err := promptService.ParseLLMResponse(completion)
+	if err != nil {
+		t.Fatalf("ParseLLMResponse() failed: %v", err)
+	}
+	if !strings.Contains(s.Content, "return 0;") {
+		t.Errorf("unexpected seed content %q", s.Content)
+	}
+}
+
+// schemalessLLM rejects every JSON schema request, as a provider without a
+// schema mode does.
+type schemalessLLM struct {
+	llm.LLM
+	formats []llm.ResponseFormat
+}
+
+func (s *schemalessLLM) GetFormattedCompletionWithSystem(ctx context.Context, systemPrompt, userPrompt string, format llm.ResponseFormat) (string, error) {
+	s.formats = append(s.formats, format)
+	return "", fmt.Errorf("test: %w", llm.ErrUnsupportedFormat)
+}
+
+func TestEngine_CompleteSeedFallsBackToDelimiterContract(t *testing.T) {
+	builder := prompt.NewBuilder(1, "", nil)
+	builder.StructuredOutput = true
+	promptService, err := prompt.NewPromptService(t.TempDir(), "", builder)
+	if err != nil {
+		t.Fatalf("NewPromptService() failed: %v", err)
+	}
+	client := &schemalessLLM{}
+	engine := NewEngine(Config{LLM: client, PromptService: promptService})
+
+	if _, err := engine.completeSeed(callGenerate, "sys", "user"); !errors.Is(err, llm.ErrUnsupportedFormat) {
+		t.Fatalf("completeSeed() error = %v, want ErrUnsupportedFormat", err)
+	}
+	if len(client.formats) != 1 || client.formats[0].Type != llm.FormatJSONSchema || client.formats[0].Schema == nil {
+		t.Errorf("structured prompts should ask for the seed schema, got %+v", client.formats)
+	}
+	if promptService.StructuredOutput() {
+		t.Error("structured output should be off after ErrUnsupportedFormat")
+	}
+}
+
+// summarizingLLM answers summary requests with summary and everything else
+// with a seed, recording the system prompts of seed requests.
+type summarizingLLM struct {
+	llm.LLM
+	summary   string
+	summaries int
+	systems   []string
+}
+
+func (s *summarizingLLM) GetCompletionWithSystem(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	if strings.HasPrefix(userPrompt, "Condense") {
+		s.summaries++
+		return s.summary, nil
+	}
+	s.systems = append(s.systems, systemPrompt)
+	return "```c\nint main() { return 0; }\n```", nil
+}
+
+func TestEngine_ContextWindowSummarizesUnderstanding(t *testing.T) {
+	baseDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(baseDir, "mutate.md"), []byte("base"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	seedDir := t.TempDir()
+	understanding := strings.Repeat("long background notes ", 200)
+	if err := seed.SaveUnderstanding(seedDir, understanding); err != nil {
+		t.Fatal(err)
+	}
+
+	newEngine := func(client llm.LLM) *Engine {
+		promptService, err := prompt.NewPromptService(baseDir, seed.GetUnderstandingPath(seedDir), prompt.NewBuilder(0, "", nil))
+		if err != nil {
+			t.Fatalf("NewPromptService() failed: %v", err)
+		}
+		promptService.SetContextLimit(200) // a tiny fake model
+		return NewEngine(Config{LLM: client, PromptService: promptService, UnderstandingDir: seedDir})
+	}
+
+	client := &summarizingLLM{summary: "- attack vector: VLAs"}
+	engine := newEngine(client)
+	for i := 0; i < 2; i++ {
+		system, err := engine.cfg.PromptService.GetSystemPrompt(prompt.PhaseMutate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := engine.completeSeed(callMutate, system, "mutate this seed"); err != nil {
+			t.Fatalf("completeSeed() failed: %v", err)
+		}
+	}
+	if client.summaries != 1 {
+		t.Errorf("understanding summarized %d times, want once", client.summaries)
+	}
+	for i, system := range client.systems {
+		if strings.Contains(system, understanding) || !strings.Contains(system, client.summary) {
+			t.Errorf("request %d should carry the summary instead of the understanding", i+1)
+		}
+	}
+	// A resumed run reuses the saved summary.
+	resumed := &summarizingLLM{summary: "unused"}
+	engine = newEngine(resumed)
+	system, _ := engine.cfg.PromptService.GetSystemPrompt(prompt.PhaseMutate)
+	if _, err := engine.completeSeed(callMutate, system, "mutate this seed"); err != nil {
+		t.Fatalf("completeSeed() failed: %v", err)
+	}
+	if resumed.summaries != 0 || !strings.Contains(resumed.systems[0], client.summary) {
+		t.Errorf("resumed run should reuse the cached summary (summaries = %d)", resumed.summaries)
+	}
+}
+
+func TestEngine_CompressesOversizedUnderstanding(t *testing.T) {
+	baseDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(baseDir, "mutate.md"), []byte("base"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	seedDir := t.TempDir()
+	understanding := "- attack vector: VLAs\n" + strings.Repeat("long background notes ", 200)
+	if err := seed.SaveUnderstanding(seedDir, understanding); err != nil {
+		t.Fatal(err)
+	}
+
+	newEngine := func(client llm.LLM, version UnderstandingVersion) *Engine {
+		promptService, err := prompt.NewPromptService(baseDir, seed.GetUnderstandingPath(seedDir), prompt.NewBuilder(0, "", nil))
+		if err != nil {
+			t.Fatalf("NewPromptService() failed: %v", err)
+		}
+		return NewEngine(Config{
+			LLM:                    client,
+			PromptService:          promptService,
+			UnderstandingMaxTokens: 300,
+			UnderstandingDir:       seedDir,
+			UnderstandingVersion:   version,
+		})
+	}
+	systemPrompt := func(e *Engine) string {
+		system, err := e.cfg.PromptService.GetSystemPrompt(prompt.PhaseMutate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return system
+	}
+
+	client := &summarizingLLM{summary: "- attack vector: VLAs"}
+	engine := newEngine(client, UnderstandingCompressed)
+	engine.compressUnderstanding()
+	if client.summaries != 1 {
+		t.Fatalf("understanding compressed %d times, want once", client.summaries)
+	}
+	if system := systemPrompt(engine); strings.Contains(system, understanding) || !strings.Contains(system, client.summary) {
+		t.Errorf("system prompt should carry the compressed understanding")
+	}
+	if engine.cfg.PromptService.Understanding() != understanding {
+		t.Errorf("the full understanding should stay available")
+	}
+	saved, err := seed.LoadCompressedUnderstanding(seedDir, understanding)
+	if err != nil || strings.TrimSpace(saved) != client.summary {
+		t.Errorf("LoadCompressedUnderstanding() = %q, %v; want the summary", saved, err)
+	}
+
+	// The understanding in use is already the latest in the history; its
+	// version goes into bug bundles.
+	engine.recordUnderstanding()
+	history, err := seed.UnderstandingHistory(seedDir)
+	if err != nil || len(history) != 1 || history[0].Tokens == 0 {
+		t.Fatalf("UnderstandingHistory() = %+v, %v; want one version with its token count", history, err)
+	}
+	if engine.understandingVersion != history[0].Timestamp {
+		t.Errorf("understandingVersion = %q, want %q", engine.understandingVersion, history[0].Timestamp)
+	}
+
+	// The saved version is reused while understanding.md is unchanged.
+	resumed := &summarizingLLM{summary: "unused"}
+	engine = newEngine(resumed, UnderstandingCompressed)
+	engine.compressUnderstanding()
+	if resumed.summaries != 0 || !strings.Contains(systemPrompt(engine), client.summary) {
+		t.Errorf("resumed run should reuse the saved compressed understanding (summaries = %d)", resumed.summaries)
+	}
+
+	// "full" keeps sending the full text.
+	full := &summarizingLLM{summary: "unused"}
+	engine = newEngine(full, UnderstandingFull)
+	engine.compressUnderstanding()
+	if full.summaries != 0 || !strings.Contains(systemPrompt(engine), understanding) {
+		t.Errorf("full version should send the full understanding (summaries = %d)", full.summaries)
+	}
+}
+
+func TestEngine_AddToCorpusCountsDuplicates(t *testing.T) {
+	corpusManager := corpus.NewFileManager(t.TempDir())
+	corpusManager.SetDedup(seed.HashWhitespace)
+	if err := corpusManager.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	engine := NewEngine(Config{Corpus: corpusManager})
+
+	for i, content := range []string{"int main() { return 0; }", "int main(){return 0;}"} {
+		added, err := engine.addToCorpus(&seed.Seed{Content: content})
+		if err != nil {
+			t.Fatalf("addToCorpus() failed: %v", err)
+		}
+		if added != (i == 0) {
+			t.Errorf("seed %d: added = %v", i+1, added)
+		}
+	}
+	if engine.duplicateSeeds != 1 {
+		t.Errorf("duplicateSeeds = %d, want 1", engine.duplicateSeeds)
+	}
+}
+
+// overflowOracle reports a bug for any seed that copies into buf.
+type overflowOracle struct{}
+
+func (overflowOracle) Analyze(s *seed.Seed, ctx *oracle.AnalyzeContext, results []oracle.Result) (*oracle.Bug, error) {
+	if strings.Contains(s.Content, "strcpy(buf") {
+		return &oracle.Bug{Seed: s, Description: "overflow"}, nil
+	}
+	return nil, nil
+}
+
+func TestEngine_MinimizesBugSeeds(t *testing.T) {
+	content := "#include <string.h>\nint unused(void) { return 1; }\nint main(int argc, char **argv) {\n" +
+		"    char buf[8];\n    int x = unused();\n    strcpy(buf, argv[1]);\n    return x;\n}\n"
+	sourcePath := filepath.Join(t.TempDir(), "id-000001", "source.c")
+	if err := os.MkdirAll(filepath.Dir(sourcePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	s := &seed.Seed{Meta: seed.Metadata{ID: 1, ContentPath: sourcePath}, Content: content}
+	comp := &fixableCompiler{want: "char buf"}
+	engine := NewEngine(Config{
+		Compiler:          comp,
+		Oracle:            overflowOracle{},
+		ExecuteSeeds:      ExecuteNever,
+		MinimizeBugs:      true,
+		MinimizeMaxChecks: 500,
+	})
+
+	bug := engine.runOracle(s, &compiler.CompileResult{Success: true, BinaryPath: "/tmp/seed"})
+	if bug == nil {
+		t.Fatal("expected a bug")
+	}
+	if s.Content != content {
+		t.Error("the original seed should be left alone")
+	}
+	if comp.calls == 0 {
+		t.Error("candidates should be compiled")
+	}
+	for _, want := range []string{"char buf", "strcpy(buf"} {
+		if !strings.Contains(bug.Seed.Content, want) {
+			t.Errorf("minimized seed lost %q:\n%s", want, bug.Seed.Content)
+		}
+	}
+	if strings.Contains(bug.Seed.Content, "unused") {
+		t.Errorf("minimized seed should drop unrelated code:\n%s", bug.Seed.Content)
+	}
+
+	engine.saveMinimizedSource(s, bug)
+	saved, err := os.ReadFile(filepath.Join(filepath.Dir(sourcePath), "minimized.c"))
+	if err != nil || string(saved) != bug.Seed.Content {
+		t.Errorf("minimized source not saved: %v", err)
+	}
+}
+
+func TestEngine_ExportsBugBundles(t *testing.T) {
+	bugsDir := t.TempDir()
+	engine := NewEngine(Config{
+		OracleType: "canary",
+		BugsDir:    bugsDir,
+		Bundle:     seed.BundleMeta{QEMUPath: "qemu-aarch64"},
+	})
+	s := &seed.Seed{Meta: seed.Metadata{ID: 12}, Content: "int main() { return 0; }"}
+	bug := &oracle.Bug{Seed: s, Description: "canary missing"}
+	result := &compiler.CompileResult{CompilerPath: "/opt/gcc/bin/gcc", EffectiveFlags: []string{"-fstack-protector-all"}}
+
+	engine.exportBundle(s, bug, result)
+	path := filepath.Join(bugsDir, "12", "bundle.tar.gz")
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("bundle not written: %v", err)
+	}
+	if engine.cfg.Bundle.CompilerPath != "" {
+		t.Error("exportBundle should not modify the configured bundle settings")
+	}
+
+	// No bundle without a bugs directory.
+	engine.cfg.BugsDir = ""
+	s.Meta.ID = 13
+	engine.exportBundle(s, bug, result)
+	if _, err := os.Stat(filepath.Join(bugsDir, "13")); !os.IsNotExist(err) {
+		t.Errorf("bundle written without BugsDir: %v", err)
+	}
+}
+
+func TestEngine_BugBundleLineage(t *testing.T) {
+	corp := corpus.NewFileManager(t.TempDir())
+	if err := corp.Initialize(); err != nil {
+		t.Fatalf("failed to initialize corpus: %v", err)
+	}
+	// 1 <- 2 <- 3, with 3 the bug
+	var s *seed.Seed
+	var parent uint64
+	for i := 0; i < 3; i++ {
+		s = &seed.Seed{
+			Content: fmt.Sprintf("int main() { return %d; }", i),
+			Meta:    seed.Metadata{ParentID: parent, MutationNote: fmt.Sprintf("change %d", i)},
+		}
+		if err := corp.Add(s); err != nil {
+			t.Fatalf("failed to add seed: %v", err)
+		}
+		parent = s.Meta.ID
+	}
+
+	bugsDir := t.TempDir()
+	engine := NewEngine(Config{OracleType: "canary", BugsDir: bugsDir, Corpus: corp})
+	bug := &oracle.Bug{Seed: s, Description: "canary missing"}
+	engine.exportBundle(s, bug, &compiler.CompileResult{CompilerPath: "gcc"})
+
+	meta := readBundleMeta(t, filepath.Join(bugsDir, "3", "bundle.tar.gz"), 3)
+	if len(meta.Lineage) != 2 || meta.Lineage[0].ID != 2 || meta.Lineage[0].MutationNote != "change 1" || meta.Lineage[1].ID != 1 {
+		t.Errorf("bundle lineage = %+v, want seeds 2 then 1", meta.Lineage)
+	}
+}
+
+// readBundleMeta reads bundle.json from the bundle of seed id at path.
+func readBundleMeta(t *testing.T, path string, id uint64) seed.BundleMeta {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("bundle not written: %v", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("bundle is not gzip: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	var meta seed.BundleMeta
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			t.Fatalf("bundle.json not found: %v", err)
+		}
+		if hdr.Name == fmt.Sprintf("seed-%d/bundle.json", id) {
+			if err := json.NewDecoder(tr).Decode(&meta); err != nil {
+				t.Fatalf("failed to decode bundle.json: %v", err)
+			}
+			return meta
+		}
+	}
+}
+
+// crashingOracle runs the binary and reports every run that crashes as a
+// bug, described with the seed ID.
+type crashingOracle struct{}
+
+func (crashingOracle) Analyze(s *seed.Seed, ctx *oracle.AnalyzeContext, results []oracle.Result) (*oracle.Bug, error) {
+	exitCode, _, stderr, err := ctx.Executor.ExecuteWithInput(ctx.BinaryPath, "")
+	if err != nil || !oracle.IsCrashExit(exitCode) {
+		return nil, err
+	}
+	return &oracle.Bug{
+		Seed:        s,
+		Results:     []oracle.Result{{ExitCode: exitCode, Stderr: stderr}},
+		Description: fmt.Sprintf("seed %d crashed", s.Meta.ID),
+	}, nil
+}
+
+func TestEngine_DeduplicatesBugsBySignature(t *testing.T) {
+	analyzer, corpusManager := newInitialPhase(t,
+		&seed.Seed{Content: "int main() { return 1; }"},
+		&seed.Seed{Content: "int main() { return 2; }"})
+	bugsDir := t.TempDir()
+	engine := NewEngine(Config{
+		Corpus:         corpusManager,
+		Compiler:       &fixableCompiler{want: "return"},
+		Analyzer:       analyzer,
+		Oracle:         crashingOracle{},
+		OracleType:     "crash",
+		OracleExecutor: &countingOracleExecutor{exitCode: 128 + 11},
+		BugsDir:        bugsDir,
+	})
+	if err := engine.processInitialSeeds(); err != nil {
+		t.Fatalf("processInitialSeeds() failed: %v", err)
+	}
+
+	bugs := engine.GetBugs()
+	if len(bugs) != 1 || bugs[0].Seed.Meta.ID != 1 || fmt.Sprint(bugs[0].Occurrences) != "[2]" {
+		t.Fatalf("bugs = %+v, want the bug of seed 1, also triggered by seed 2", bugs)
+	}
+	if !engine.knownBug(2) {
+		t.Error("seed 2 is not known to trigger a bug")
+	}
+	meta := readBundleMeta(t, filepath.Join(bugsDir, "1", "bundle.tar.gz"), 1)
+	if meta.Signature != bugs[0].Signature || fmt.Sprint(meta.AlsoTriggeredBy) != "[2]" {
+		t.Errorf("bundle of seed 1 = signature %q, also triggered by %v; want %q, [2]",
+			meta.Signature, meta.AlsoTriggeredBy, bugs[0].Signature)
+	}
+	if _, err := os.Stat(filepath.Join(bugsDir, "2")); !os.IsNotExist(err) {
+		t.Errorf("duplicate bug got a bundle of its own: %v", err)
+	}
+	// Both seeds keep their own verdict
+	if dup, err := corpusManager.Get(2); err != nil || dup.Meta.OracleVerdict != seed.OracleVerdictBug {
+		t.Errorf("seed 2 verdict = %v (%v), want BUG", dup, err)
+	}
+
+	// Resume keeps deduplicating against the checkpointed signatures
+	path := filepath.Join(t.TempDir(), "engine_checkpoint.json")
+	engine.cfg.CheckpointPath = path
+	if err := engine.saveCheckpoint(); err != nil {
+		t.Fatalf("saveCheckpoint() failed: %v", err)
+	}
+	resumed := NewEngine(Config{OracleType: "crash"})
+	if err := resumed.Resume(path); err != nil {
+		t.Fatalf("Resume() failed: %v", err)
+	}
+	third := &seed.Seed{Meta: seed.Metadata{ID: 3}}
+	bug := &oracle.Bug{Seed: third, Results: []oracle.Result{{ExitCode: 128 + 11}}, Description: "seed 3 crashed"}
+	resumed.recordBug(third, bug)
+	if len(resumed.GetBugs()) != 1 || bug.DuplicateOf != 1 || fmt.Sprint(resumed.GetBugs()[0].Occurrences) != "[2 3]" {
+		t.Errorf("after Resume, seed 3's bug = %+v among %+v; want an occurrence of seed 1's", bug, resumed.GetBugs())
+	}
+}
+
+// newInitialPhase returns an analyzer over a one-block CFG and a corpus
+// holding seeds, for processInitialSeeds tests.
+func newInitialPhase(t *testing.T, seeds ...*seed.Seed) (*coverage.Analyzer, *corpus.FileManager) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	cfgPath := filepath.Join(tmpDir, "test.cc.015t.cfg")
+	cfgContent := ";; Function test_func (_Z9test_funcv, funcdef_no=1, decl_uid=100, cgraph_uid=1, symbol_order=1)\n" +
+		"int test_func ()\n{\n  <bb 2> :\n  [/path/to/test.cc:10:3] return 0;\n}\n"
+	if err := os.WriteFile(cfgPath, []byte(cfgContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	analyzer, err := coverage.NewAnalyzer([]string{cfgPath}, []string{"test_func"}, "", filepath.Join(tmpDir, "mapping.json"), 0.8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	corpusManager := corpus.NewFileManager(filepath.Join(tmpDir, "out"))
+	if err := corpusManager.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range seeds {
+		if err := corpusManager.Add(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return analyzer, corpusManager
+}
+
+func TestEngine_ProcessInitialSeedsSkipsInvalid(t *testing.T) {
+	analyzer, corpusManager := newInitialPhase(t,
+		&seed.Seed{Content: "int main() { return 0; }"},
+		&seed.Seed{Content: "int main() { return x; }"})
+
+	comp := &fixableCompiler{want: "return 0"}
+	failures := corpusManager.ValidatePending(func(s *seed.Seed) error {
+		result, err := comp.Compile(s)
+		if err != nil {
+			return err
+		}
+		if !result.Success {
+			return fmt.Errorf("compilation failed: %s", result.Stderr)
+		}
+		return nil
+	}, 2)
+	if len(failures) != 1 || failures[2] == nil {
+		t.Fatalf("failures = %v, want seed 2", failures)
+	}
+
+	comp.calls = 0
+	engine := NewEngine(Config{Corpus: corpusManager, Compiler: comp, Analyzer: analyzer})
+	if err := engine.processInitialSeeds(); err != nil {
+		t.Fatalf("processInitialSeeds() failed: %v", err)
+	}
+	if comp.calls != 1 {
+		t.Errorf("compiled %d seeds, want only the valid one", comp.calls)
+	}
+	invalid, err := corpusManager.Get(2)
+	if err != nil || invalid.Meta.State != seed.SeedStateInvalid {
+		t.Errorf("seed 2 state = %v (%v), want INVALID", invalid, err)
+	}
+}
+
+func TestEngine_ProcessInitialSeedsRecordsOutcome(t *testing.T) {
+	analyzer, corpusManager := newInitialPhase(t,
+		&seed.Seed{Content: "int main() { return 0; }"},
+		&seed.Seed{Content: "int main() { return x; }"})
+
+	engine := NewEngine(Config{
+		Corpus:         corpusManager,
+		Compiler:       &fixableCompiler{want: "return 0"},
+		Analyzer:       analyzer,
+		Oracle:         &executingOracle{},
+		OracleExecutor: &countingOracleExecutor{exitCode: 128 + 11},
+	})
+	if err := engine.processInitialSeeds(); err != nil {
+		t.Fatalf("processInitialSeeds() failed: %v", err)
+	}
+
+	crashed, err := corpusManager.Get(1)
+	if err != nil || !crashed.Meta.CompileOK || fmt.Sprint(crashed.Meta.ExecSignals) != "[SIGSEGV]" {
+		t.Errorf("seed 1 outcome = %+v (%v), want compiled, SIGSEGV", crashed.Meta, err)
+	}
+	broken, err := corpusManager.Get(2)
+	if err != nil || broken.Meta.CompileOK || !strings.Contains(broken.Meta.CompileStderr, "'x' undeclared") {
+		t.Errorf("seed 2 outcome = %+v (%v), want the compile error", broken.Meta, err)
+	}
+	stats, err := corpusManager.Stats()
+	want := map[corpus.RejectionReason]int{corpus.RejectedSignal: 1, corpus.RejectedCompile: 1}
+	if err != nil || fmt.Sprint(stats.Rejections) != fmt.Sprint(want) {
+		t.Errorf("Stats().Rejections = %v (%v), want %v", stats.Rejections, err, want)
+	}
+}
+
+func TestEngine_SyncFavored(t *testing.T) {
+	analyzer, corpusManager := newInitialPhase(t,
+		&seed.Seed{Content: "int main() { return 0; }"},
+		&seed.Seed{Content: "int main() { return 1; }"})
+	engine := NewEngine(Config{Corpus: corpusManager, Analyzer: analyzer})
+
+	analyzer.GetMapping().RecordLine(coverage.LineID{File: "cc.c", Line: 1}, 2)
+	engine.syncFavored()
+	for id, want := range map[uint64]bool{1: false, 2: true} {
+		s, err := corpusManager.Get(id)
+		if err != nil || s.Meta.Favored != want {
+			t.Errorf("seed %d favored = %v (%v), want %v", id, s.Meta.Favored, err, want)
+		}
+	}
+	stats, err := corpusManager.Stats()
+	if err != nil || stats.Favored != 1 {
+		t.Errorf("Stats().Favored = %v (%v), want 1", stats, err)
+	}
+}
+
+func TestEngine_InitialSeedFilter(t *testing.T) {
+	analyzer, corpusManager := newInitialPhase(t,
+		&seed.Seed{Content: "int main() { return 0; }"},
+		&seed.Seed{Content: "int main() { return 0; } // c++", Language: seed.LanguageCPP})
+
+	comp := &fixableCompiler{want: "return 0"}
+	engine := NewEngine(Config{
+		Corpus:            corpusManager,
+		Compiler:          comp,
+		Analyzer:          analyzer,
+		InitialSeedFilter: seed.ByLanguage(seed.LanguageC),
+	})
+	if err := engine.processInitialSeeds(); err != nil {
+		t.Fatalf("processInitialSeeds() failed: %v", err)
+	}
+	if comp.calls != 1 {
+		t.Errorf("compiled %d seeds, want only the C seed", comp.calls)
+	}
+	if corpusManager.Len() != 1 {
+		t.Errorf("Len() = %d, want the C++ seed left queued", corpusManager.Len())
+	}
+}
+
+func TestEngine_PrintLineage(t *testing.T) {
+	_, corpusManager := newInitialPhase(t, &seed.Seed{Content: "int main() { return 0; }"})
+	parent, err := corpusManager.Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := corpusManager.Add(&seed.Seed{Meta: seed.Metadata{ParentID: parent.Meta.ID, Depth: 1}, Content: "int main() { return 1; }"}); err != nil {
+		t.Fatal(err)
+	}
+
+	lineagePath := filepath.Join(t.TempDir(), "lineage.dot")
+	engine := NewEngine(Config{Corpus: corpusManager, LineagePath: lineagePath})
+	engine.printLineage()
+	dot, err := os.ReadFile(lineagePath)
+	if err != nil {
+		t.Fatalf("lineage not written: %v", err)
+	}
+	if !strings.Contains(string(dot), "s1 -> s2;") {
+		t.Errorf("lineage misses the mutation edge:\n%s", dot)
+	}
+}
+
+// recordingMetrics counts the engine's Metrics calls.
+type recordingMetrics struct {
+	iterations, compiles, bugs int
+}
+
+func (m *recordingMetrics) IterationDone(time.Duration) { m.iterations++ }
+func (m *recordingMetrics) Compiled(bool)               { m.compiles++ }
+func (m *recordingMetrics) BugFound()                   { m.bugs++ }
+
+func TestEngine_ReportsMetrics(t *testing.T) {
+	_, corpusManager := newInitialPhase(t, &seed.Seed{Content: "int main() { return 0; }"})
+	cfg := newDryRunConfig(t)
+	cfg.Corpus = corpusManager
+	cfg.Compiler = &fixableCompiler{want: "return"}
+	cfg.MaxIterations = 2
+	metrics := &recordingMetrics{}
+	cfg.Metrics = metrics
+	if err := NewEngine(cfg).Run(context.Background()); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if metrics.iterations != 2 || metrics.compiles != 1 || metrics.bugs != 0 {
+		t.Errorf("metrics = %+v, want 2 iterations and the initial seed's compile", *metrics)
+	}
+}
+
+func TestEngine_ReferenceBuild(t *testing.T) {
+	diff, err := oracle.New("diff", nil, nil, nil, "")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	s := &seed.Seed{Meta: seed.Metadata{ID: 1}, Content: "int main() { return 0; }"}
+	primary := &countingOracleExecutor{}
+	reference := &countingOracleExecutor{exitCode: 1}
+	refCompiler := &fixableCompiler{want: "main"}
+	engine := NewEngine(Config{
+		Compiler:          &fixableCompiler{want: "main"},
+		Oracle:            diff,
+		OracleType:        "diff",
+		OracleExecutor:    primary,
+		ReferenceCompiler: refCompiler,
+		ReferenceExecutor: reference,
+	})
+
+	bug := engine.runOracle(s, &compiler.CompileResult{Success: true, BinaryPath: "/tmp/primary"})
+	if bug == nil || bug.Class != "diff exit" {
+		t.Fatalf("runOracle() = %+v, want a divergent exit code", bug)
+	}
+	if refCompiler.calls != 1 || primary.calls != 1 || reference.calls != 1 {
+		t.Errorf("compiled %d, ran %d/%d times; want one reference build and a run per side",
+			refCompiler.calls, primary.calls, reference.calls)
+	}
+	if len(bug.Binaries) != 2 || bug.Binaries[0] != "/tmp/primary" || bug.Binaries[1] != "/tmp/seed" {
+		t.Errorf("bug binaries = %q, want the primary and reference builds", bug.Binaries)
+	}
+
+	refCompiler.want = "never"
+	if bug := engine.runOracle(s, &compiler.CompileResult{Success: true, BinaryPath: "/tmp/primary"}); bug != nil {
+		t.Errorf("runOracle() = %q, want no verdict when the reference compiler rejects the seed", bug.Description)
+	}
+}
+
+func TestEngine_GcovPrefixRuntimeCoverage(t *testing.T) {
+	tmpDir := t.TempDir()
+	prefixDir := filepath.Join(tmpDir, "gcov-prefix")
+	if err := os.MkdirAll(prefixDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	gcc := coverage.NewGCCCoverage(&gcovrStub{prefixDir: prefixDir}, nil, tmpDir, "gcovr -r ..",
+		filepath.Join(tmpDir, "reports", "total.json"), "")
+	gcc.SetGcovPrefix(prefixDir)
+
+	comp := &fixableCompiler{want: "main"}
+	runner := &gcdaWriter{dir: prefixDir}
+	engine := NewEngine(Config{Compiler: comp, Coverage: gcc, OracleExecutor: runner})
+	s := &seed.Seed{
+		Content:   "int main() { return 0; }",
+		Meta:      seed.Metadata{ID: 3},
+		TestCases: []seed.TestCase{{RunningCommand: "./prog 1"}, {RunningCommand: "./prog", Stdin: "x"}},
+	}
+	report, _, err := engine.measureSeed(s, comp.Compile)
+	if err != nil {
+		t.Fatalf("measureSeed() failed: %v", err)
+	}
+	lines, err := coverage.ExtractCoveredLines(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"libtarget.c:1", "libtarget.c:2"}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("covered lines = %v, want the lines both test cases wrote at run time: %v", lines, want)
+	}
+
+	// Seeds that do not run leave no runtime coverage, and the data of the
+	// earlier seed is gone
+	engine.cfg.ExecuteSeeds = ExecuteNever
+	s.Meta.ID = 4
+	report, _, err = engine.measureSeed(s, comp.Compile)
+	if err != nil {
+		t.Fatalf("measureSeed() failed: %v", err)
+	}
+	if lines, _ := coverage.ExtractCoveredLines(report); len(lines) != 0 || runner.runs != 2 {
+		t.Errorf("covered lines = %v after %d runs, want none and no more runs", lines, runner.runs)
+	}
+}
+
+// gcdaWriter stands for a seed binary built against instrumented
+// libraries: its nth run writes n.gcda under dir.
+type gcdaWriter struct {
+	dir  string
+	runs int
+}
+
+func (w *gcdaWriter) run() (int, string, string, error) {
+	w.runs++
+	return 0, "", "", os.WriteFile(filepath.Join(w.dir, fmt.Sprintf("%d.gcda", w.runs)), nil, 0644)
+}
+
+func (w *gcdaWriter) ExecuteWithInput(string, string) (int, string, string, error) { return w.run() }
+
+func (w *gcdaWriter) ExecuteWithArgs(string, ...string) (int, string, string, error) { return w.run() }
+
+// gcovrStub runs the cleanup commands and stands in for gcovr, reporting
+// line n of libtarget.c covered for each n.gcda under prefixDir.
+type gcovrStub struct {
+	prefixDir string
+}
+
+func (g *gcovrStub) Run(command string, args ...string) (*exec.ExecutionResult, error) {
+	script := args[len(args)-1]
+	if !strings.Contains(script, "gcovr") {
+		return exec.NewCommandExecutor().Run(command, args...)
+	}
+	file := gcovr.File{FilePath: "libtarget.c"}
+	matches, _ := filepath.Glob(filepath.Join(g.prefixDir, "*.gcda"))
+	for _, m := range matches {
+		n, err := strconv.Atoi(strings.TrimSuffix(filepath.Base(m), ".gcda"))
+		if err != nil {
+			return nil, err
+		}
+		file.Lines = append(file.Lines, gcovr.Line{LineNumber: n, Count: 1})
+	}
+	data, err := json.Marshal(&gcovr.GcovrReport{FormatVersion: "0.14", Files: []gcovr.File{file}})
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(script)
+	return &exec.ExecutionResult{}, os.WriteFile(fields[len(fields)-1], data, 0644)
+}
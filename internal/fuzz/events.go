@@ -0,0 +1,184 @@
+package fuzz
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zjy-dev/de-fuzz/internal/logger"
+)
+
+// DefaultEventSinkTimeout is how long the engine waits for an EventSink
+// callback when Config.EventSinkTimeout is not set.
+const DefaultEventSinkTimeout = time.Second
+
+// EventSink observes the engine's loop, e.g. for notifications, custom logs
+// or experiment frameworks. The engine calls it synchronously from its own
+// goroutine, in order, but waits at most EventSinkTimeout for each call: a
+// sink that takes longer misses the events raised until it returns. Every
+// event carries the iteration count when it was raised, which the initial
+// phase has not advanced yet.
+type EventSink interface {
+	OnIterationStart(IterationEvent)
+	OnIterationEnd(IterationEvent)
+	OnTargetSelected(TargetEvent)
+	OnSeedMeasured(SeedEvent)
+	OnNewCoverage(CoverageEvent)
+	OnBug(BugEvent)
+	OnStateSaved(StateEvent)
+}
+
+// Iteration outcomes, in IterationEvent.Outcome.
+const (
+	OutcomeHit         = "hit"         // The target was covered
+	OutcomeMissed      = "missed"      // Retries ran out without covering it
+	OutcomeSkipped     = "skipped"     // Dry run: prompts rendered only
+	OutcomeInterrupted = "interrupted" // The run was canceled
+	OutcomeNoTarget    = "no target"   // Every target BB is covered
+)
+
+// IterationEvent starts and ends a constraint-solving iteration. Outcome,
+// Duration and RetryBudget, the retries the target was given, are set on
+// OnIterationEnd only.
+type IterationEvent struct {
+	Iteration   int           `json:"iteration"`
+	Outcome     string        `json:"outcome,omitempty"`
+	Duration    time.Duration `json:"duration,omitempty"`
+	RetryBudget int           `json:"retry_budget,omitempty"`
+}
+
+// TargetEvent names the BB an iteration targets.
+type TargetEvent struct {
+	Iteration      int     `json:"iteration"`
+	Function       string  `json:"function"`
+	BBID           int     `json:"bb_id"`
+	SuccessorCount int     `json:"successor_count"`
+	Weight         float64 `json:"weight"`
+}
+
+// SeedEvent reports a seed compiled and measured, initial or generated.
+type SeedEvent struct {
+	Iteration int    `json:"iteration"`
+	SeedID    uint64 `json:"seed_id"`
+	ParentID  uint64 `json:"parent_id,omitempty"`
+	Compiled  bool   `json:"compiled"`
+	Measured  bool   `json:"measured"` // A coverage report was taken
+	Error     string `json:"error,omitempty"`
+}
+
+// CoverageEvent reports a seed that raised BB coverage.
+type CoverageEvent struct {
+	Iteration      int    `json:"iteration"`
+	SeedID         uint64 `json:"seed_id"`
+	OldBasisPoints uint64 `json:"old_bp"`
+	NewBasisPoints uint64 `json:"new_bp"`
+}
+
+// BugEvent reports a bug, new or another occurrence of a known one.
+type BugEvent struct {
+	Iteration   int    `json:"iteration"`
+	SeedID      uint64 `json:"seed_id"`
+	Signature   string `json:"signature"`
+	DuplicateOf uint64 `json:"duplicate_of,omitempty"`
+	Description string `json:"description"`
+}
+
+// StateEvent reports the corpus, mapping and checkpoint saved; Final when
+// fuzzing ended.
+type StateEvent struct {
+	Iteration int  `json:"iteration"`
+	Final     bool `json:"final,omitempty"`
+}
+
+// sinkRunner guards calls to one sink: at most one is in flight, and the
+// engine stops waiting for it after the timeout.
+type sinkRunner struct {
+	sink    EventSink
+	busy    atomic.Bool
+	dropped int // Events missed while busy
+}
+
+// emit passes an event to every sink through call. call runs on another
+// goroutine, so it must only use an event built beforehand, never engine
+// state.
+func (e *Engine) emit(call func(EventSink)) {
+	if len(e.sinks) == 0 {
+		return
+	}
+	timeout := e.cfg.EventSinkTimeout
+	if timeout <= 0 {
+		timeout = DefaultEventSinkTimeout
+	}
+	for _, r := range e.sinks {
+		if !r.busy.CompareAndSwap(false, true) {
+			r.dropped++
+			continue
+		}
+		if r.dropped > 0 {
+			logger.Warn("Event sink %T is back after missing %d events", r.sink, r.dropped)
+			r.dropped = 0
+		}
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			defer r.busy.Store(false)
+			call(r.sink)
+		}()
+		timer := time.NewTimer(timeout)
+		select {
+		case <-done:
+		case <-timer.C:
+			logger.Warn("Event sink %T did not return within %v; it misses events until it does", r.sink, timeout)
+		}
+		timer.Stop()
+	}
+}
+
+// EventLog is an EventSink that appends every event to a JSONL file, one
+// {"time", "event", "data"} object per line.
+type EventLog struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// NewEventLog opens the event log at path for appending, creating it and
+// its directory as needed.
+func NewEventLog(path string) (*EventLog, error) {
+	f, err := openJSONL(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event log: %w", err)
+	}
+	return &EventLog{file: f, enc: json.NewEncoder(f)}, nil
+}
+
+// Close closes the log file.
+func (l *EventLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+func (l *EventLog) write(event string, data any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	record := struct {
+		Time  time.Time `json:"time"`
+		Event string    `json:"event"`
+		Data  any       `json:"data"`
+	}{time.Now(), event, data}
+	if err := l.enc.Encode(record); err != nil {
+		logger.Warn("Failed to write event log: %v", err)
+	}
+}
+
+func (l *EventLog) OnIterationStart(ev IterationEvent) { l.write("iteration_start", ev) }
+func (l *EventLog) OnIterationEnd(ev IterationEvent)   { l.write("iteration_end", ev) }
+func (l *EventLog) OnTargetSelected(ev TargetEvent)    { l.write("target_selected", ev) }
+func (l *EventLog) OnSeedMeasured(ev SeedEvent)        { l.write("seed_measured", ev) }
+func (l *EventLog) OnNewCoverage(ev CoverageEvent)     { l.write("new_coverage", ev) }
+func (l *EventLog) OnBug(ev BugEvent)                  { l.write("bug", ev) }
+func (l *EventLog) OnStateSaved(ev StateEvent)         { l.write("state_saved", ev) }
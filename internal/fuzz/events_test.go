@@ -0,0 +1,169 @@
+package fuzz
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// recordingSink records the events it gets as short strings.
+type recordingSink struct {
+	mu     sync.Mutex
+	events []string
+	block  chan struct{} // When set, every call waits on it
+}
+
+func (r *recordingSink) record(format string, args ...any) {
+	if r.block != nil {
+		<-r.block
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingSink) recorded() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events...)
+}
+
+func (r *recordingSink) OnIterationStart(ev IterationEvent) {
+	r.record("iteration_start %d", ev.Iteration)
+}
+
+func (r *recordingSink) OnIterationEnd(ev IterationEvent) {
+	r.record("iteration_end %d %s", ev.Iteration, ev.Outcome)
+}
+
+func (r *recordingSink) OnTargetSelected(ev TargetEvent) {
+	r.record("target_selected %d %s:BB%d", ev.Iteration, ev.Function, ev.BBID)
+}
+
+func (r *recordingSink) OnSeedMeasured(ev SeedEvent) {
+	r.record("seed_measured %d compiled=%v", ev.SeedID, ev.Compiled)
+}
+
+func (r *recordingSink) OnNewCoverage(ev CoverageEvent) {
+	r.record("new_coverage %d", ev.SeedID)
+}
+
+func (r *recordingSink) OnBug(ev BugEvent) {
+	r.record("bug %d", ev.SeedID)
+}
+
+func (r *recordingSink) OnStateSaved(ev StateEvent) {
+	r.record("state_saved %d final=%v", ev.Iteration, ev.Final)
+}
+
+func TestEngine_EventOrder(t *testing.T) {
+	_, corpusManager := newInitialPhase(t, &seed.Seed{Content: "int main() { return 0; }"})
+	cfg := newDryRunConfig(t)
+	cfg.Corpus = corpusManager
+	cfg.Compiler = &fixableCompiler{want: "return"}
+	cfg.MaxIterations = 2
+	sink := &recordingSink{}
+	cfg.EventSinks = []EventSink{sink}
+	if err := NewEngine(cfg).Run(context.Background()); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	// The stub compiler leaves no coverage to measure, so the initial seed
+	// gains none; both dry-run iterations are skipped.
+	want := []string{
+		"seed_measured 1 compiled=true",
+		"state_saved 0 final=false",
+		"iteration_start 1",
+		"target_selected 1 test_func:BB2",
+		"iteration_end 1 skipped",
+		"iteration_start 2",
+		"target_selected 2 helper:BB2",
+		"iteration_end 2 skipped",
+		"state_saved 2 final=true",
+	}
+	if got := sink.recorded(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestEngine_SlowEventSinkMissesEvents(t *testing.T) {
+	_, corpusManager := newInitialPhase(t)
+	cfg := newDryRunConfig(t)
+	cfg.Corpus = corpusManager
+	cfg.MaxIterations = 2
+	cfg.EventSinkTimeout = 10 * time.Millisecond
+	sink := &recordingSink{block: make(chan struct{})}
+	cfg.EventSinks = []EventSink{sink}
+
+	start := time.Now()
+	if err := NewEngine(cfg).Run(context.Background()); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Run() took %v, want the engine not to wait on the sink", elapsed)
+	}
+	if got := sink.recorded(); len(got) != 0 {
+		t.Errorf("events = %q, want none while the sink is stuck", got)
+	}
+
+	close(sink.block)
+	deadline := time.Now().Add(5 * time.Second)
+	for len(sink.recorded()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := sink.recorded(); !reflect.DeepEqual(got, []string{"state_saved 0 final=false"}) {
+		t.Errorf("events = %q, want only the event the sink was stuck on", got)
+	}
+}
+
+func TestEventLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out", "events.jsonl")
+	log, err := NewEventLog(path)
+	if err != nil {
+		t.Fatalf("NewEventLog() failed: %v", err)
+	}
+	log.OnIterationStart(IterationEvent{Iteration: 3})
+	log.OnBug(BugEvent{Iteration: 3, SeedID: 7, Signature: "abc"})
+	if err := log.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	type record struct {
+		Time  time.Time       `json:"time"`
+		Event string          `json:"event"`
+		Data  json.RawMessage `json:"data"`
+	}
+	var records []record
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("bad event log line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, r)
+	}
+	if len(records) != 2 || records[0].Event != "iteration_start" || records[1].Event != "bug" {
+		t.Fatalf("records = %+v, want iteration_start then bug", records)
+	}
+	var bug BugEvent
+	if err := json.Unmarshal(records[1].Data, &bug); err != nil {
+		t.Fatal(err)
+	}
+	if bug.SeedID != 7 || bug.Signature != "abc" || records[1].Time.IsZero() {
+		t.Errorf("bug record = %+v at %v", bug, records[1].Time)
+	}
+}
@@ -0,0 +1,359 @@
+package fuzz
+
+import (
+	"math/rand"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zjy-dev/de-fuzz/internal/coverage"
+	"github.com/zjy-dev/de-fuzz/internal/logger"
+	"github.com/zjy-dev/de-fuzz/internal/prompt"
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// solveWithFallback spends the attempts left for target, from attempt
+// used on up to retries, on LLM-free mutations of the base seed instead
+// of an LLM that just failed. Like solveConstraint it stops at a hit or
+// new coverage.
+func (e *Engine) solveWithFallback(target *coverage.TargetInfo, base *seed.Seed, ctx *prompt.TargetContext, used, retries int) (bool, int, error) {
+	if base == nil {
+		logger.Warn("No base seed to mutate without the LLM")
+		return false, used, nil
+	}
+	mutator := newFallbackMutator(e.rng, e.cfg.PromptService.TemplateFunctions(), target)
+	for attempt := used; attempt <= retries; attempt++ {
+		content, op, ok := mutator.mutate(base.Content)
+		if !ok {
+			logger.Warn("No LLM-free mutation applies to seed %d", base.Meta.ID)
+			return false, attempt, nil
+		}
+		s := &seed.Seed{
+			Content:   content,
+			TestCases: slices.Clone(base.TestCases),
+			CFlags:    slices.Clone(base.CFlags),
+			Language:  base.Language,
+		}
+		s.Meta.ID = e.cfg.Corpus.AllocateID()
+		s.Meta.CreatedAt = time.Now()
+		s.Meta.ParentID = base.Meta.ID
+		s.Meta.Mutator = seed.MutatorFallback
+		s.Meta.MutationNote = "LLM-free " + op
+		s.FlagProfile = clonePromptProfile(ctx)
+		e.fallbackSeeds++
+		logger.Debug("Fallback seed %d: %s of seed %d", s.Meta.ID, op, base.Meta.ID)
+
+		result, err := e.tryMutatedSeed(s, target)
+		if err != nil {
+			return false, attempt, err
+		}
+		if result.HitTarget {
+			return true, attempt, nil
+		}
+		if result.CoveredNew {
+			return false, attempt, nil
+		}
+	}
+	return false, retries, nil
+}
+
+// fallbackMutator makes new seeds without the LLM, for when the provider is
+// down or out of quota: classic textual mutations of a base seed (constant
+// flips, buffer size changes, duplicated statements, swapped VLA sizes,
+// toggled volatile/register qualifiers). Mutations the target's source
+// lines hint at are tried first.
+type fallbackMutator struct {
+	rng *rand.Rand
+	// functions are the only functions it may change (function template
+	// mode); nil lets it change the whole seed.
+	functions []string
+	// hints is the lowercased name and source lines of the target.
+	hints string
+}
+
+// mutationOp is one kind of fallback mutation. apply changes code within
+// [start, end), finding tokens in masked (code with comments, literals and
+// preprocessor lines blanked), and reports false when nothing there fits.
+type mutationOp struct {
+	name  string
+	hints []string // Words in the target's source that favor this op
+	apply func(m *fallbackMutator, code, masked string, start, end int) (string, bool)
+}
+
+var fallbackOps = []mutationOp{
+	{"flip constant", []string{"const", "int_cst", "offset", "align"}, flipConstant},
+	{"resize buffer", []string{"array", "buffer", "size", "char", "protect"}, resizeBuffer},
+	{"duplicate statement", []string{"stmt", "call", "loop", "seq"}, duplicateStatement},
+	{"swap VLA sizes", []string{"vla", "alloca", "dynamic", "variable"}, swapVLASizes},
+	{"toggle qualifier", []string{"volatile", "register", "addressable"}, toggleQualifier},
+}
+
+var (
+	decimalRe   = regexp.MustCompile(`\b\d+\b`)
+	arraySizeRe = regexp.MustCompile(`\[\s*(\d+)\s*\]`)
+	arrayDeclRe = regexp.MustCompile(`\b[A-Za-z_]\w*\s*\[([^\[\]]+)\]`)
+	qualifierRe = regexp.MustCompile(`\b(volatile|register)\s+`)
+	// declRe matches lines starting like a declaration: a type name then
+	// a declarator ("int x", "char *p", "size_t n").
+	declRe = regexp.MustCompile(`^[A-Za-z_]\w*[\s*]+[A-Za-z_]\w*`)
+)
+
+// jumpKeywords start statements that are not worth duplicating, or that
+// declRe would take for declarations.
+var jumpKeywords = []string{"return", "break", "continue", "goto", "case", "default", "else"}
+
+// newFallbackMutator returns a mutator for target, limited to functions
+// when they are set.
+func newFallbackMutator(rng *rand.Rand, functions []string, target *coverage.TargetInfo) *fallbackMutator {
+	hints := target.Function
+	if target.File != "" && len(target.Lines) > 0 {
+		if code, err := coverage.ReadSourceLines(target.File, slices.Min(target.Lines), slices.Max(target.Lines)); err == nil {
+			hints += "\n" + code
+		}
+	}
+	return &fallbackMutator{rng: rng, functions: functions, hints: strings.ToLower(hints)}
+}
+
+// mutate returns a mutation of code and the name of the op that made it,
+// or ok false when no op applies.
+func (m *fallbackMutator) mutate(code string) (string, string, bool) {
+	start, end, ok := m.region(code)
+	if !ok {
+		return "", "", false
+	}
+	masked := seed.MaskCode(code)
+	for _, op := range m.order() {
+		if out, ok := op.apply(m, code, masked, start, end); ok && out != code {
+			return out, op.name, true
+		}
+	}
+	return "", "", false
+}
+
+// region returns the part of code the mutator may change: the body of one
+// of its functions, or all of code without any.
+func (m *fallbackMutator) region(code string) (int, int, bool) {
+	if len(m.functions) == 0 {
+		return 0, len(code), true
+	}
+	for _, i := range m.rng.Perm(len(m.functions)) {
+		if start, end, ok := seed.FunctionBody(code, m.functions[i]); ok {
+			return start, end, true
+		}
+	}
+	return 0, 0, false
+}
+
+// order returns fallbackOps in random order, each op hinted at by the
+// target three times as likely to come early as the others.
+func (m *fallbackMutator) order() []mutationOp {
+	ops := slices.Clone(fallbackOps)
+	weights := make([]int, len(ops))
+	for i, op := range ops {
+		weights[i] = 1
+		for _, hint := range op.hints {
+			if strings.Contains(m.hints, hint) {
+				weights[i] = 3
+				break
+			}
+		}
+	}
+	ordered := make([]mutationOp, 0, len(ops))
+	for len(ops) > 0 {
+		total := 0
+		for _, w := range weights {
+			total += w
+		}
+		pick := m.rng.Intn(total)
+		i := 0
+		for pick >= weights[i] {
+			pick -= weights[i]
+			i++
+		}
+		ordered = append(ordered, ops[i])
+		ops = slices.Delete(ops, i, i+1)
+		weights = slices.Delete(weights, i, i+1)
+	}
+	return ordered
+}
+
+// pickValue returns a value other than n from candidates, or n when every
+// candidate equals it.
+func (m *fallbackMutator) pickValue(n int, candidates []int) int {
+	candidates = slices.DeleteFunc(slices.Clone(candidates), func(c int) bool { return c == n })
+	if len(candidates) == 0 {
+		return n
+	}
+	return candidates[m.rng.Intn(len(candidates))]
+}
+
+// matchesIn returns the submatch indexes of re in masked[start:end], as
+// offsets into masked.
+func matchesIn(re *regexp.Regexp, masked string, start, end int) [][]int {
+	matches := re.FindAllStringSubmatchIndex(masked[start:end], -1)
+	for _, match := range matches {
+		for i := range match {
+			if match[i] >= 0 {
+				match[i] += start
+			}
+		}
+	}
+	return matches
+}
+
+// replace returns code with code[start:end] replaced by s.
+func replace(code string, start, end int, s string) string {
+	return code[:start] + s + code[end:]
+}
+
+// flipConstant replaces a decimal constant with a boundary value.
+func flipConstant(m *fallbackMutator, code, masked string, start, end int) (string, bool) {
+	matches := matchesIn(decimalRe, masked, start, end)
+	if len(matches) == 0 {
+		return "", false
+	}
+	match := matches[m.rng.Intn(len(matches))]
+	n, err := strconv.Atoi(code[match[0]:match[1]])
+	if err != nil {
+		return "", false
+	}
+	v := m.pickValue(n, []int{0, 1, max(n-1, 0), n + 1, 2 * n, n / 2, 255, 256, 4096, 65536})
+	return replace(code, match[0], match[1], strconv.Itoa(v)), true
+}
+
+// resizeBuffer changes a constant array size or index.
+func resizeBuffer(m *fallbackMutator, code, masked string, start, end int) (string, bool) {
+	matches := matchesIn(arraySizeRe, masked, start, end)
+	if len(matches) == 0 {
+		return "", false
+	}
+	match := matches[m.rng.Intn(len(matches))]
+	n, err := strconv.Atoi(code[match[2]:match[3]])
+	if err != nil {
+		return "", false
+	}
+	v := m.pickValue(n, []int{1, max(n/2, 1), n + 1, 2 * n, 4096})
+	return replace(code, match[2], match[3], strconv.Itoa(v)), true
+}
+
+// duplicateStatement repeats a simple statement line.
+func duplicateStatement(m *fallbackMutator, code, masked string, start, end int) (string, bool) {
+	type line struct{ start, end int }
+	var candidates []line
+	for lineStart := start; lineStart < end; {
+		lineEnd := strings.IndexByte(masked[lineStart:end], '\n')
+		if lineEnd < 0 {
+			lineEnd = end
+		} else {
+			lineEnd += lineStart
+		}
+		if isStatementLine(strings.TrimSpace(masked[lineStart:lineEnd])) {
+			candidates = append(candidates, line{lineStart, lineEnd})
+		}
+		lineStart = lineEnd + 1
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+	l := candidates[m.rng.Intn(len(candidates))]
+	text := code[l.start:l.end]
+	return code[:l.end] + "\n" + text + code[l.end:], true
+}
+
+// isStatementLine reports whether a masked, trimmed line is one simple
+// statement that can run twice, not a declaration or jump.
+func isStatementLine(line string) bool {
+	if !strings.HasSuffix(line, ";") || strings.Count(line, ";") != 1 {
+		return false
+	}
+	if strings.ContainsAny(line, "{}") || declRe.MatchString(line) {
+		return false
+	}
+	first := strings.FieldsFunc(line, func(r rune) bool { return r != '_' && !isAlnum(r) })
+	return len(first) == 0 || !slices.Contains(jumpKeywords, first[0])
+}
+
+// isLocalDeclLine reports whether a masked, trimmed line is one plain
+// variable declaration a qualifier can go in front of.
+func isLocalDeclLine(line string) bool {
+	if !strings.HasSuffix(line, ";") || strings.Count(line, ";") != 1 || strings.ContainsAny(line, "{}") {
+		return false
+	}
+	if !declRe.MatchString(line) {
+		return false
+	}
+	first := strings.Fields(line)[0]
+	return !slices.Contains(jumpKeywords, first) && !slices.Contains([]string{"static", "extern", "typedef"}, first)
+}
+
+func isAlnum(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
+
+// swapVLASizes swaps the size of a variable-length array with that of
+// another array declared in the region.
+func swapVLASizes(m *fallbackMutator, code, masked string, start, end int) (string, bool) {
+	type size struct {
+		start, end int
+		text       string
+	}
+	var sizes []size
+	hasVLA := false
+	for _, match := range matchesIn(arrayDeclRe, masked, start, end) {
+		lineStart := strings.LastIndexByte(masked[:match[0]], '\n') + 1
+		if !declRe.MatchString(strings.TrimSpace(masked[lineStart:match[1]])) {
+			continue // An index, not a declarator
+		}
+		text := strings.TrimSpace(code[match[2]:match[3]])
+		if _, err := strconv.Atoi(text); err != nil {
+			hasVLA = true
+		}
+		sizes = append(sizes, size{match[2], match[3], text})
+	}
+	if !hasVLA || len(sizes) < 2 {
+		return "", false
+	}
+	for _, i := range m.rng.Perm(len(sizes)) {
+		for _, j := range m.rng.Perm(len(sizes)) {
+			a, b := sizes[i], sizes[j]
+			if a.start >= b.start || a.text == b.text {
+				continue
+			}
+			// Replace the later one first so a's offsets still hold
+			code = replace(code, b.start, b.end, a.text)
+			return replace(code, a.start, a.end, b.text), true
+		}
+	}
+	return "", false
+}
+
+// toggleQualifier drops a volatile or register qualifier, or adds one to a
+// local declaration when there is none.
+func toggleQualifier(m *fallbackMutator, code, masked string, start, end int) (string, bool) {
+	if matches := matchesIn(qualifierRe, masked, start, end); len(matches) > 0 {
+		match := matches[m.rng.Intn(len(matches))]
+		return replace(code, match[0], match[1], ""), true
+	}
+	var decls []int
+	for lineStart := start; lineStart < end; {
+		lineEnd := strings.IndexByte(masked[lineStart:end], '\n')
+		if lineEnd < 0 {
+			lineEnd = end
+		} else {
+			lineEnd += lineStart
+		}
+		line := masked[lineStart:lineEnd]
+		if isLocalDeclLine(strings.TrimSpace(line)) {
+			decls = append(decls, lineStart+len(line)-len(strings.TrimLeft(line, " \t")))
+		}
+		lineStart = lineEnd + 1
+	}
+	if len(decls) == 0 {
+		return "", false
+	}
+	at := decls[m.rng.Intn(len(decls))]
+	qualifier := []string{"volatile ", "register "}[m.rng.Intn(2)]
+	return code[:at] + qualifier + code[at:], true
+}
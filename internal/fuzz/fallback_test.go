@@ -0,0 +1,181 @@
+package fuzz
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zjy-dev/de-fuzz/internal/coverage"
+	"github.com/zjy-dev/de-fuzz/internal/llm"
+	"github.com/zjy-dev/de-fuzz/internal/prompt"
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+func TestFallbackOps(t *testing.T) {
+	tests := []struct {
+		name  string
+		apply func(m *fallbackMutator, code, masked string, start, end int) (string, bool)
+		code  string
+		check func(out string) bool
+	}{
+		{
+			name:  "flip constant",
+			apply: flipConstant,
+			code:  "int main() { return 7; }",
+			check: func(out string) bool {
+				return !strings.Contains(out, "return 7;") && strings.HasPrefix(out, "int main() { return ")
+			},
+		},
+		{
+			name:  "resize buffer",
+			apply: resizeBuffer,
+			code:  "void f() {\n  char buf[64];\n}",
+			check: func(out string) bool { return !strings.Contains(out, "buf[64]") && strings.Contains(out, "char buf[") },
+		},
+		{
+			name:  "duplicate statement",
+			apply: duplicateStatement,
+			code:  "void f(int x) {\n  int y = 1;\n  x++;\n  return;\n}",
+			check: func(out string) bool { return strings.Count(out, "x++;") == 2 && strings.Count(out, "int y") == 1 },
+		},
+		{
+			name:  "swap VLA sizes",
+			apply: swapVLASizes,
+			code:  "void f(int n) {\n  char a[n];\n  char b[16];\n  a[0] = b[1];\n}",
+			check: func(out string) bool {
+				return strings.Contains(out, "char a[16];") && strings.Contains(out, "char b[n];")
+			},
+		},
+		{
+			name:  "add qualifier",
+			apply: toggleQualifier,
+			code:  "void f() {\n  int x = 1;\n}",
+			check: func(out string) bool {
+				return strings.Contains(out, "volatile int x") || strings.Contains(out, "register int x")
+			},
+		},
+		{
+			name:  "drop qualifier",
+			apply: toggleQualifier,
+			code:  "void f() {\n  volatile int x = 1;\n}",
+			check: func(out string) bool { return strings.Contains(out, "\n  int x = 1;") },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &fallbackMutator{rng: rand.New(rand.NewSource(1))}
+			out, ok := tt.apply(m, tt.code, seed.MaskCode(tt.code), 0, len(tt.code))
+			if !ok || !tt.check(out) {
+				t.Errorf("%s(%q) = %q, %v", tt.name, tt.code, out, ok)
+			}
+		})
+	}
+
+	t.Run("nothing to change", func(t *testing.T) {
+		m := &fallbackMutator{rng: rand.New(rand.NewSource(1))}
+		code := "void f() { /* buf[64] = 1; */ }"
+		for _, op := range fallbackOps {
+			if out, ok := op.apply(m, code, seed.MaskCode(code), 0, len(code)); ok {
+				t.Errorf("%s changed a seed with only a comment: %q", op.name, out)
+			}
+		}
+	})
+}
+
+func TestFallbackMutator_FunctionTemplateMode(t *testing.T) {
+	code := "static int helper(int v) {\n  return v + 1;\n}\n\nvoid seed(int n) {\n  char buf[8];\n  helper(n);\n}\n\nint main() {\n  seed(3);\n  return 0;\n}\n"
+	start, end, _ := seed.FunctionBody(code, "seed")
+	m := &fallbackMutator{rng: rand.New(rand.NewSource(1)), functions: []string{"seed"}}
+	for i := 0; i < 50; i++ {
+		out, op, ok := m.mutate(code)
+		if !ok {
+			t.Fatal("mutate() found nothing to change in seed()")
+		}
+		grown := len(out) - len(code)
+		if out[:start] != code[:start] || out[end+grown:] != code[end:] {
+			t.Fatalf("%s changed code outside seed():\n%s", op, out)
+		}
+	}
+
+	m.functions = []string{"missing"}
+	if _, _, ok := m.mutate(code); ok {
+		t.Error("mutate() changed a seed without the template's function")
+	}
+}
+
+// unavailableLLM fails every request, like a provider that is down.
+type unavailableLLM struct {
+	llm.LLM
+	calls int
+}
+
+func (u *unavailableLLM) GetCompletionWithSystem(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	u.calls++
+	return "", errors.New("429 quota exhausted")
+}
+
+// seedRecordingCoverage records the seeds it measures.
+type seedRecordingCoverage struct {
+	coverage.Coverage
+	measured []*seed.Seed
+}
+
+func (c *seedRecordingCoverage) MeasureCompiled(s *seed.Seed) (coverage.Report, error) {
+	c.measured = append(c.measured, s)
+	return &stubReport{}, nil
+}
+
+func TestEngine_FallsBackWithoutLLM(t *testing.T) {
+	baseDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(baseDir, "constraint.md"), []byte("system"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	templatePath := filepath.Join(t.TempDir(), "function_template.c")
+	template := "int main() {\n  seed(4);\n  return 0;\n}\n\n// FUNCTION_PLACEHOLDER: seed\n"
+	if err := os.WriteFile(templatePath, []byte(template), 0644); err != nil {
+		t.Fatal(err)
+	}
+	promptService, err := prompt.NewPromptService(baseDir, "", prompt.NewBuilder(0, templatePath, nil))
+	if err != nil {
+		t.Fatalf("NewPromptService() failed: %v", err)
+	}
+
+	base := &seed.Seed{Content: "int main() {\n  seed(4);\n  return 0;\n}\n\nvoid seed(int n) {\n  char buf[16];\n  buf[0] = n;\n}\n"}
+	analyzer, corpusManager := newInitialPhase(t, base)
+	client := &unavailableLLM{}
+	cov := &seedRecordingCoverage{}
+	engine := NewEngine(Config{
+		Corpus:        corpusManager,
+		Compiler:      &fixableCompiler{want: "seed"},
+		Coverage:      cov,
+		Analyzer:      analyzer,
+		LLM:           client,
+		PromptService: promptService,
+		MaxRetries:    2,
+	})
+
+	target := &coverage.TargetInfo{Function: "test_func", BBID: 2, File: "/path/to/test.cc", Lines: []int{10}, BaseSeed: "1"}
+	hit, retries, err := engine.solveConstraint(target, 2)
+	if err != nil || hit || retries != 2 {
+		t.Fatalf("solveConstraint() = %v, %d, %v; want a miss after 2 retries", hit, retries, err)
+	}
+	if client.calls != 1 {
+		t.Errorf("LLM called %d times, want the engine to stop asking once it failed", client.calls)
+	}
+	if len(cov.measured) != 3 || engine.fallbackSeeds != 3 {
+		t.Fatalf("measured %d seeds, %d fallback; want all 3 attempts measured", len(cov.measured), engine.fallbackSeeds)
+	}
+	for _, s := range cov.measured {
+		if s.Meta.Mutator != seed.MutatorFallback || s.Meta.ParentID != base.Meta.ID || s.Content == base.Content {
+			t.Errorf("seed %d: mutator %q, parent %d; want a fallback mutation of seed %d", s.Meta.ID, s.Meta.Mutator, s.Meta.ParentID, base.Meta.ID)
+		}
+		if !strings.HasPrefix(s.Content, "int main() {\n  seed(4);\n  return 0;\n}\n") {
+			t.Errorf("seed %d changed main() outside the template's function:\n%s", s.Meta.ID, s.Content)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package fuzz
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/zjy-dev/de-fuzz/internal/compiler"
+	"github.com/zjy-dev/de-fuzz/internal/logger"
+	"github.com/zjy-dev/de-fuzz/internal/oracle"
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// iceLocation is the compiler source position an ICE message ends with,
+// ", at expr.cc:10788"; it moves between compiler builds.
+var iceLocation = regexp.MustCompile(`,? at [^\s,]+:\d+\s*$`)
+
+// iceStderrName is the file a preserved ICE's compiler stderr is saved to.
+const iceStderrName = "compile_stderr.txt"
+
+// recordICE records the internal compiler error compiling s hit as a bug
+// of type ICE. The first seed hitting an ICE is kept under BugsDir with the
+// compiler's stderr, its bug report artifacts and a reproduction bundle.
+func (e *Engine) recordICE(s *seed.Seed, result *compiler.CompileResult) {
+	message := result.InternalError()
+	bug := &oracle.Bug{
+		Seed:        s,
+		Results:     []oracle.Result{{Stderr: result.Stderr}},
+		Description: fmt.Sprintf("Internal compiler error on seed %d: %s", s.Meta.ID, message),
+		Type:        oracle.BugTypeICE,
+		Class:       iceClass(message),
+	}
+	e.ices++
+	e.recordBug(s, bug)
+	if bug.DuplicateOf != 0 {
+		return
+	}
+	e.preserveICE(s, result)
+	e.exportBundle(s, bug, result)
+}
+
+// uniqueICEs counts the bugs found that are ICEs.
+func (e *Engine) uniqueICEs() int {
+	n := 0
+	for _, bug := range e.bugsFound {
+		if bug.Type == oracle.BugTypeICE {
+			n++
+		}
+	}
+	return n
+}
+
+// iceClass is the Bug.Class of an ICE: its message without the prefix and
+// the compiler source position.
+func iceClass(message string) string {
+	message = strings.TrimPrefix(message, "internal compiler error: ")
+	return iceLocation.ReplaceAllString(message, "")
+}
+
+// preserveICE writes the seed's source, the compiler's stderr and copies of
+// the compiler's bug report artifacts to <BugsDir>/<seedID>/, since a seed
+// that fails to compile never reaches the corpus.
+func (e *Engine) preserveICE(s *seed.Seed, result *compiler.CompileResult) {
+	if e.cfg.BugsDir == "" {
+		return
+	}
+	dir := filepath.Join(e.cfg.BugsDir, strconv.FormatUint(s.Meta.ID, 10))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		logger.Warn("Failed to preserve ICE of seed %d: %v", s.Meta.ID, err)
+		return
+	}
+	files := map[string]string{
+		s.Language.SourceFileName(): s.Content,
+		iceStderrName:               result.Stderr,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			logger.Warn("Failed to preserve ICE of seed %d: %v", s.Meta.ID, err)
+		}
+	}
+	for _, artifact := range result.ReportArtifacts() {
+		data, err := os.ReadFile(artifact)
+		if err == nil {
+			err = os.WriteFile(filepath.Join(dir, filepath.Base(artifact)), data, 0644)
+		}
+		if err != nil {
+			logger.Warn("Failed to preserve ICE artifact %s of seed %d: %v", artifact, s.Meta.ID, err)
+		}
+	}
+	logger.Info("ICE of seed %d preserved in %s", s.Meta.ID, dir)
+}
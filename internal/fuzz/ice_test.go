@@ -0,0 +1,81 @@
+package fuzz
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zjy-dev/de-fuzz/internal/compiler"
+	"github.com/zjy-dev/de-fuzz/internal/oracle"
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// iceCompiler crashes on every seed, leaving a -freport-bug file behind.
+type iceCompiler struct {
+	reportFile string
+	calls      int
+}
+
+func (c *iceCompiler) Compile(s *seed.Seed) (*compiler.CompileResult, error) {
+	c.calls++
+	return &compiler.CompileResult{
+		Success:      false,
+		CompilerPath: "xgcc",
+		Stderr: "seed.c: In function 'seed':\n" +
+			"seed.c:4:1: internal compiler error: in expand_expr_real_1, at expr.cc:10788\n" +
+			"Please submit a full bug report, with preprocessed source.\n" +
+			"Preprocessed source stored into " + c.reportFile + " file, please attach this to your bugreport.\n",
+	}, nil
+}
+
+func (c *iceCompiler) GetWorkDir() string { return "" }
+
+func TestEngine_RecordsICE(t *testing.T) {
+	reportFile := filepath.Join(t.TempDir(), "ccXq1.out")
+	if err := os.WriteFile(reportFile, []byte("// preprocessed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	bugsDir := t.TempDir()
+	comp := &iceCompiler{reportFile: reportFile}
+	cov := &seedRecordingCoverage{}
+	client := &unavailableLLM{}
+	engine := NewEngine(Config{
+		Compiler:             comp,
+		Coverage:             cov,
+		LLM:                  client,
+		MaxCompileFixRetries: 2,
+		BugsDir:              bugsDir,
+		OracleType:           "canary",
+	})
+
+	for id := uint64(1); id <= 2; id++ {
+		s := &seed.Seed{Meta: seed.Metadata{ID: id}, Content: "void seed(int n) { }"}
+		report, result, err := engine.measureSeed(s, engine.compileWithFixes)
+		if err != nil || report != nil || result.Success {
+			t.Fatalf("measureSeed() = %v, %+v, %v; want a failed compile without a report", report, result, err)
+		}
+	}
+	if len(cov.measured) != 0 {
+		t.Errorf("measured coverage of %d seeds, want none after an ICE", len(cov.measured))
+	}
+	if client.calls != 0 || comp.calls != 2 {
+		t.Errorf("%d compile-fix calls, %d compiles; want an ICE seed left unrepaired", client.calls, comp.calls)
+	}
+
+	bugs := engine.GetBugs()
+	if len(bugs) != 1 || engine.ices != 2 || engine.uniqueICEs() != 1 {
+		t.Fatalf("%d bugs, %d ICEs; want both seeds counted as one ICE", len(bugs), engine.ices)
+	}
+	if bugs[0].Type != oracle.BugTypeICE || bugs[0].Class != "in expand_expr_real_1" || len(bugs[0].Occurrences) != 1 {
+		t.Errorf("bug = %+v, want an ICE in expand_expr_real_1 hit twice", bugs[0])
+	}
+
+	for _, name := range []string{"source.c", iceStderrName, "ccXq1.out", "bundle.tar.gz"} {
+		if _, err := os.Stat(filepath.Join(bugsDir, "1", name)); err != nil {
+			t.Errorf("ICE of seed 1 not preserved: %v", err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(bugsDir, "2")); !os.IsNotExist(err) {
+		t.Errorf("the repeat ICE of seed 2 was preserved too: %v", err)
+	}
+}
@@ -0,0 +1,223 @@
+package fuzz
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zjy-dev/de-fuzz/internal/compiler"
+	"github.com/zjy-dev/de-fuzz/internal/llm"
+	"github.com/zjy-dev/de-fuzz/internal/logger"
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// Compile result classes, in SeedRecord.Compile.
+const (
+	CompileClassOK       = "ok"
+	CompileClassError    = "error"    // The seed did not compile
+	CompileClassICE      = "ice"      // The compiler crashed on the seed
+	CompileClassRejected = "rejected" // Not compiled: it disables the defense
+)
+
+// IterationRecord is what one constraint-solving iteration targeted, tried
+// and spent: a line of the iteration log, which ReadIterationLog reads back
+// for offline analysis. StageMs sums the time spent on "llm" calls,
+// "compile" and coverage measurement (compile fixes included) and the
+// "oracle".
+type IterationRecord struct {
+	Iteration      int                `json:"iteration"`
+	Time           time.Time          `json:"time"` // When the iteration ended
+	Outcome        string             `json:"outcome"`
+	DurationMs     float64            `json:"duration_ms"`
+	Target         *TargetEvent       `json:"target,omitempty"`
+	RetryBudget    int                `json:"retry_budget"`
+	Retries        int                `json:"retries"` // Retries used
+	Seeds          []SeedRecord       `json:"seeds,omitempty"`
+	CoverageBefore uint64             `json:"coverage_before_bp"`
+	CoverageAfter  uint64             `json:"coverage_after_bp"`
+	StageMs        map[string]float64 `json:"stage_ms,omitempty"`
+	Usage          llm.Usage          `json:"usage"`
+}
+
+// SeedRecord is a seed an iteration tried and what became of it.
+type SeedRecord struct {
+	SeedID      uint64             `json:"seed_id"`
+	ParentID    uint64             `json:"parent_id,omitempty"`
+	Mutator     string             `json:"mutator,omitempty"` // "" = the LLM
+	Compile     string             `json:"compile"`
+	HitTarget   bool               `json:"hit_target,omitempty"`
+	NewCoverage bool               `json:"new_coverage,omitempty"`
+	Verdict     seed.OracleVerdict `json:"verdict,omitempty"`
+	Reason      string             `json:"reason,omitempty"` // First line of the compile error
+}
+
+// IterationSink is an EventSink that also takes the record of every
+// finished iteration, after its OnIterationEnd.
+type IterationSink interface {
+	EventSink
+	OnIterationRecord(IterationRecord)
+}
+
+// beginIteration starts the record of the current iteration.
+func (e *Engine) beginIteration() {
+	e.iteration = &IterationRecord{
+		Iteration:      e.iterationCount,
+		CoverageBefore: e.coverageBasisPoints(),
+		StageMs:        make(map[string]float64),
+	}
+	e.iterationUsage = e.totalLLMUsage()
+}
+
+// finishIteration completes the record of the current iteration and passes
+// it to the IterationSinks.
+func (e *Engine) finishIteration(start time.Time, outcome string) {
+	rec := e.iteration
+	if rec == nil {
+		return
+	}
+	e.iteration = nil
+	rec.Time = time.Now()
+	rec.Outcome = outcome
+	rec.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+	rec.RetryBudget = e.retries
+	rec.CoverageAfter = e.coverageBasisPoints()
+	rec.Usage = e.totalLLMUsage().Sub(e.iterationUsage)
+	ev := *rec
+	e.emit(func(sink EventSink) {
+		if s, ok := sink.(IterationSink); ok {
+			s.OnIterationRecord(ev)
+		}
+	})
+}
+
+// timeStage adds the time since start to stage of the current iteration.
+func (e *Engine) timeStage(stage string, start time.Time) {
+	if e.iteration != nil {
+		e.iteration.StageMs[stage] += float64(time.Since(start).Microseconds()) / 1000
+	}
+}
+
+// noteSeedTried adds s, tried by tryMutatedSeed with result, to the current
+// iteration.
+func (e *Engine) noteSeedTried(s *seed.Seed, result *seedTryResult) {
+	if e.iteration == nil {
+		return
+	}
+	rec := SeedRecord{
+		SeedID:      s.Meta.ID,
+		ParentID:    s.Meta.ParentID,
+		Mutator:     s.Meta.Mutator,
+		Compile:     result.Compile,
+		HitTarget:   result.HitTarget,
+		NewCoverage: result.CoveredNew,
+		Verdict:     result.OracleVerdict,
+	}
+	if result.CompileFailed {
+		rec.Reason, _, _ = strings.Cut(strings.TrimSpace(result.CompileError), "\n")
+	}
+	e.iteration.Seeds = append(e.iteration.Seeds, rec)
+}
+
+// compileClass classifies a compilation measureSeed returned.
+func compileClass(result *compiler.CompileResult, err error) string {
+	switch {
+	case errors.Is(err, errCompile) || result == nil:
+		return CompileClassError
+	case result.InternalError() != "":
+		return CompileClassICE
+	case !result.Success:
+		return CompileClassError
+	default:
+		return CompileClassOK
+	}
+}
+
+// IterationLog is an IterationSink that appends every IterationRecord to a
+// JSONL file, one per line. The other events are ignored.
+type IterationLog struct {
+	nopSink
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// NewIterationLog opens the iteration log at path for appending, creating
+// it and its directory as needed.
+func NewIterationLog(path string) (*IterationLog, error) {
+	f, err := openJSONL(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open iteration log: %w", err)
+	}
+	return &IterationLog{file: f, enc: json.NewEncoder(f)}, nil
+}
+
+// Close closes the log file.
+func (l *IterationLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+func (l *IterationLog) OnIterationRecord(rec IterationRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.enc.Encode(rec); err != nil {
+		logger.Warn("Failed to write iteration log: %v", err)
+	}
+}
+
+// ReadIterationLog reads the records of the iteration log at path. A last
+// line cut short, by a campaign killed while writing it, is left out.
+func ReadIterationLog(path string) ([]IterationRecord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read iteration log: %w", err)
+	}
+	if i := bytes.LastIndexByte(data, '\n'); i != len(data)-1 {
+		data = data[:i+1]
+	}
+	var records []IterationRecord
+	reader := bufio.NewReader(bytes.NewReader(data))
+	for lineNum := 1; ; lineNum++ {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return records, nil
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var rec IterationRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid iteration record: %w", path, lineNum, err)
+		}
+		records = append(records, rec)
+	}
+}
+
+// openJSONL opens the JSONL file at path for appending, creating it and its
+// directory as needed.
+func openJSONL(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+}
+
+// nopSink ignores every event, for sinks that observe only some.
+type nopSink struct{}
+
+func (nopSink) OnIterationStart(IterationEvent) {}
+func (nopSink) OnIterationEnd(IterationEvent)   {}
+func (nopSink) OnTargetSelected(TargetEvent)    {}
+func (nopSink) OnSeedMeasured(SeedEvent)        {}
+func (nopSink) OnNewCoverage(CoverageEvent)     {}
+func (nopSink) OnBug(BugEvent)                  {}
+func (nopSink) OnStateSaved(StateEvent)         {}
@@ -0,0 +1,114 @@
+package fuzz
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/zjy-dev/de-fuzz/internal/prompt"
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+func TestReadIterationLog(t *testing.T) {
+	records, err := ReadIterationLog(filepath.Join("testdata", "iterations", "events.jsonl"))
+	if err != nil {
+		t.Fatalf("ReadIterationLog() failed: %v", err)
+	}
+	// The third record was cut short and is left out
+	if len(records) != 2 {
+		t.Fatalf("read %d records, want 2", len(records))
+	}
+
+	hit := records[0]
+	if hit.Iteration != 1 || hit.Outcome != OutcomeHit || hit.Target == nil ||
+		hit.Target.Function != "stack_protect_prologue" || hit.Target.BBID != 7 {
+		t.Errorf("record 1 = %+v", hit)
+	}
+	if hit.RetryBudget != 4 || hit.Retries != 1 || hit.CoverageAfter-hit.CoverageBefore != 60 {
+		t.Errorf("record 1 retries %d/%d, coverage %d -> %d", hit.Retries, hit.RetryBudget, hit.CoverageBefore, hit.CoverageAfter)
+	}
+	var classes []string
+	for _, s := range append(hit.Seeds, records[1].Seeds...) {
+		classes = append(classes, s.Compile)
+	}
+	if want := []string{CompileClassError, CompileClassOK, CompileClassICE, CompileClassRejected}; !reflect.DeepEqual(classes, want) {
+		t.Errorf("compile classes = %v, want %v", classes, want)
+	}
+	if s := hit.Seeds[1]; !s.HitTarget || !s.NewCoverage || s.Verdict != seed.OracleVerdictNormal {
+		t.Errorf("seed record = %+v, want the seed that hit the target", s)
+	}
+	if hit.StageMs["llm"] != 4102.2 || hit.Usage.Calls != 2 || hit.Usage.Tokens() != 2220 {
+		t.Errorf("record 1 spent %v with %+v", hit.StageMs, hit.Usage)
+	}
+
+	if _, err := ReadIterationLog(filepath.Join(t.TempDir(), "missing.jsonl")); err == nil {
+		t.Error("ReadIterationLog() of a missing file passed")
+	}
+}
+
+func TestEngine_IterationLog(t *testing.T) {
+	baseDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(baseDir, "constraint.md"), []byte("system"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	promptService, err := prompt.NewPromptService(baseDir, "", prompt.NewBuilder(0, "", nil))
+	if err != nil {
+		t.Fatalf("NewPromptService() failed: %v", err)
+	}
+	_, corpusManager := newInitialPhase(t)
+	path := filepath.Join(t.TempDir(), "out", "events.jsonl")
+	log, err := NewIterationLog(path)
+	if err != nil {
+		t.Fatalf("NewIterationLog() failed: %v", err)
+	}
+	engine := NewEngine(Config{
+		Corpus:        corpusManager,
+		Compiler:      &fixableCompiler{want: "main"},
+		Analyzer:      newTwoTargetAnalyzer(t),
+		LLM:           &meteredLLM{reply: "```c\nint main() { return 0; }\n```"},
+		PromptService: promptService,
+		MaxIterations: 2,
+		MaxRetries:    2,
+		EventSinks:    []EventSink{log},
+	})
+	if err := engine.Run(context.Background()); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if err := log.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	records, err := ReadIterationLog(path)
+	if err != nil {
+		t.Fatalf("ReadIterationLog() failed: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("logged %d iterations, want 2", len(records))
+	}
+	for i, rec := range records {
+		// No coverage is measured, so every seed compiles but misses
+		if rec.Iteration != i+1 || rec.Outcome != OutcomeMissed || rec.Target == nil || rec.Target.Iteration != i+1 {
+			t.Errorf("record %d = %+v", i, rec)
+			continue
+		}
+		if rec.RetryBudget != 2 || rec.Retries != 2 || len(rec.Seeds) != 3 {
+			t.Errorf("record %d: %d seeds in %d/%d retries, want 3 in 2/2", i, len(rec.Seeds), rec.Retries, rec.RetryBudget)
+		}
+		for _, s := range rec.Seeds {
+			if s.Compile != CompileClassOK || s.HitTarget || s.SeedID == 0 {
+				t.Errorf("record %d seed = %+v, want a compiled miss", i, s)
+			}
+		}
+		if rec.Usage.Calls != len(rec.Seeds) || rec.Usage.PromptTokens != 100*len(rec.Seeds) {
+			t.Errorf("record %d usage = %+v, want one call per seed", i, rec.Usage)
+		}
+		if _, ok := rec.StageMs["llm"]; !ok {
+			t.Errorf("record %d stages = %v, want the LLM timed", i, rec.StageMs)
+		}
+		if _, ok := rec.StageMs["compile"]; !ok {
+			t.Errorf("record %d stages = %v, want compilation timed", i, rec.StageMs)
+		}
+	}
+}
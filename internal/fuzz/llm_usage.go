@@ -0,0 +1,124 @@
+package fuzz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/zjy-dev/de-fuzz/internal/llm"
+	"github.com/zjy-dev/de-fuzz/internal/logger"
+)
+
+// LLM call types, used to break token usage down in the summary.
+const (
+	callGenerate = llm.CallGenerate   // constraint-solving prompts
+	callRefine   = llm.CallRefine     // divergence-refined retries
+	callRepair   = llm.CallRepair     // compile-fix prompts
+	callMutate   = llm.CallMutate     // random-phase mutations
+	callSummary  = llm.CallUnderstand // understanding summaries that fit the context window
+)
+
+// trackLLM runs call with the run's context, tagged with callType and the
+// current llmScope for the audit log, and charges what the LLM client used
+// meanwhile to callType. Clients that do not implement llm.UsageReporter
+// are not tracked.
+func (e *Engine) trackLLM(callType string, call func(ctx context.Context) error) error {
+	scope := e.llmScope
+	scope.Type = callType
+	ctx := llm.WithCallInfo(e.ctx, scope)
+	defer e.timeStage("llm", time.Now())
+
+	reporter, ok := e.cfg.LLM.(llm.UsageReporter)
+	if !ok {
+		return call(ctx)
+	}
+	before := reporter.Usage()
+	err := call(ctx)
+	e.llmUsage[callType] = e.llmUsage[callType].Add(reporter.Usage().Sub(before))
+	return err
+}
+
+// totalLLMUsage sums the usage of all call types.
+func (e *Engine) totalLLMUsage() llm.Usage {
+	var total llm.Usage
+	for _, u := range e.llmUsage {
+		total = total.Add(u)
+	}
+	return total
+}
+
+// loadLLMUsage restores the usage totals of an earlier run from UsagePath so
+// a resumed campaign keeps accumulating.
+func (e *Engine) loadLLMUsage() error {
+	if e.cfg.UsagePath == "" {
+		return nil
+	}
+	data, err := os.ReadFile(e.cfg.UsagePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read LLM usage: %w", err)
+	}
+	usage := make(map[string]llm.Usage)
+	if err := json.Unmarshal(data, &usage); err != nil {
+		return fmt.Errorf("failed to parse LLM usage %s: %w", e.cfg.UsagePath, err)
+	}
+	e.llmUsage = usage
+	return nil
+}
+
+// saveLLMUsage writes the usage totals to UsagePath.
+func (e *Engine) saveLLMUsage() error {
+	if e.cfg.UsagePath == "" || len(e.llmUsage) == 0 {
+		return nil
+	}
+	data, err := json.MarshalIndent(e.llmUsage, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode LLM usage: %w", err)
+	}
+	if err := os.WriteFile(e.cfg.UsagePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write LLM usage: %w", err)
+	}
+	return nil
+}
+
+// printLLMUsage adds the LLM totals and the per-call-type breakdown to the
+// run summary.
+func (e *Engine) printLLMUsage() {
+	total := e.totalLLMUsage()
+	if total.Calls == 0 && total.CacheHits == 0 {
+		return
+	}
+	logger.Info("LLM calls:      %d (%d retries, %d failed, %d disk cache hits)",
+		total.Calls, total.Retries, total.Failures, total.CacheHits)
+	logger.Info("LLM tokens:     %d prompt + %d completion, est. cost %.4f",
+		total.PromptTokens, total.CompletionTokens, total.Cost)
+
+	callTypes := make([]string, 0, len(e.llmUsage))
+	for callType := range e.llmUsage {
+		callTypes = append(callTypes, callType)
+	}
+	sort.Strings(callTypes)
+	for _, callType := range callTypes {
+		u := e.llmUsage[callType]
+		logger.Info("  %-8s => %d calls, %d tokens, est. cost %.4f", callType, u.Calls, u.Tokens(), u.Cost)
+	}
+
+	// Provider counts cover this process only; they are not carried over
+	// on resume.
+	if reporter, ok := e.cfg.LLM.(llm.ProviderCallReporter); ok {
+		served := reporter.ProviderCalls()
+		providers := make([]string, 0, len(served))
+		for provider := range served {
+			providers = append(providers, provider)
+		}
+		sort.Strings(providers)
+		for _, provider := range providers {
+			logger.Info("  served by %s => %d calls", provider, served[provider])
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package fuzz
+
+import "time"
+
+// Metrics receives the engine's measurements as it makes them. The engine
+// calls it from its own goroutine; implementations must be cheap.
+// internal/metrics exports them to Prometheus.
+type Metrics interface {
+	// IterationDone is called after every completed constraint-solving
+	// iteration with how long it took.
+	IterationDone(d time.Duration)
+	// Compiled is called after every seed compilation the engine measures.
+	Compiled(ok bool)
+	// BugFound is called for every new bug.
+	BugFound()
+}
+
+// nopMetrics is the Metrics of an engine configured without one.
+type nopMetrics struct{}
+
+func (nopMetrics) IterationDone(time.Duration) {}
+func (nopMetrics) Compiled(bool)               {}
+func (nopMetrics) BugFound()                   {}
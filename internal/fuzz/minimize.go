@@ -0,0 +1,57 @@
+package fuzz
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zjy-dev/de-fuzz/internal/logger"
+	"github.com/zjy-dev/de-fuzz/internal/oracle"
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// minimizedSourceName is the file, next to a corpus seed's source, that
+// holds the minimized source of the bug it triggered.
+const minimizedSourceName = "minimized"
+
+// minimizeBug replaces bug.Seed with a minimized copy of s that still
+// triggers a bug. The bug keeps s when minimization fails.
+func (e *Engine) minimizeBug(s *seed.Seed, bug *oracle.Bug) {
+	minimized, err := seed.Minimize(s, e.reproducesBug,
+		seed.MinimizeMaxChecks(e.cfg.MinimizeMaxChecks),
+		seed.MinimizeTimeout(e.cfg.MinimizeTimeout))
+	if err != nil {
+		logger.Warn("Could not minimize bug seed %d: %v", s.Meta.ID, err)
+		return
+	}
+	logger.Info("Minimized bug seed %d from %d to %d lines", s.Meta.ID,
+		strings.Count(s.Content, "\n")+1, strings.Count(minimized.Content, "\n")+1)
+	bug.Seed = minimized
+}
+
+// reproducesBug is the minimization check: the candidate must compile and
+// the oracle must still report a bug. Coverage is measured before the
+// oracle runs, so these extra compilations do not disturb it.
+func (e *Engine) reproducesBug(candidate *seed.Seed) bool {
+	if e.ctx != nil && e.ctx.Err() != nil {
+		return false
+	}
+	result, err := e.cfg.Compiler.Compile(candidate)
+	if err != nil || result == nil || !result.Success || result.BinaryPath == "" {
+		return false
+	}
+	bug, err := e.analyze(candidate, result.BinaryPath)
+	return err == nil && bug != nil
+}
+
+// saveMinimizedSource writes the minimized source of bug next to the corpus
+// copy of s, when bug carries one.
+func (e *Engine) saveMinimizedSource(s *seed.Seed, bug *oracle.Bug) {
+	if bug == nil || bug.Seed == nil || bug.Seed == s || bug.Seed.Content == s.Content || s.Meta.ContentPath == "" {
+		return
+	}
+	path := filepath.Join(filepath.Dir(s.Meta.ContentPath), minimizedSourceName+s.Language.Extension())
+	if err := os.WriteFile(path, []byte(bug.Seed.Content), 0644); err != nil {
+		logger.Warn("Failed to save minimized source of seed %d: %v", s.Meta.ID, err)
+	}
+}
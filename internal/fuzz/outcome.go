@@ -0,0 +1,89 @@
+package fuzz
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/zjy-dev/de-fuzz/internal/oracle"
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// timeoutExitCode is the exit code executors report for a run they
+// stopped, like timeout(1).
+const timeoutExitCode = 124
+
+// signalNames names the signals seeds commonly die of.
+var signalNames = map[int]string{
+	4:  "SIGILL",
+	5:  "SIGTRAP",
+	6:  "SIGABRT",
+	7:  "SIGBUS",
+	8:  "SIGFPE",
+	9:  "SIGKILL",
+	11: "SIGSEGV",
+	13: "SIGPIPE",
+	15: "SIGTERM",
+}
+
+// exitSignal returns the seed.Metadata.ExecSignals entry for a run that
+// ended with exitCode, or "" for a plain exit. Executors report a run
+// killed by a signal as 128 + the signal number, like the shell.
+func exitSignal(exitCode int) string {
+	switch {
+	case exitCode == timeoutExitCode:
+		return seed.ExecSignalTimeout
+	case exitCode > 128 && exitCode <= 128+64:
+		if name, ok := signalNames[exitCode-128]; ok {
+			return name
+		}
+		return fmt.Sprintf("SIG%d", exitCode-128)
+	}
+	return ""
+}
+
+// signalRecorder passes runs on to an oracle.Executor and notes in meta
+// how each ended, when not by exiting; each signal is noted once.
+type signalRecorder struct {
+	oracle.Executor
+	meta *seed.Metadata
+}
+
+func (r *signalRecorder) ExecuteWithInput(binaryPath string, stdin string) (int, string, string, error) {
+	exitCode, stdout, stderr, err := r.Executor.ExecuteWithInput(binaryPath, stdin)
+	r.note(exitCode, err)
+	return exitCode, stdout, stderr, err
+}
+
+func (r *signalRecorder) ExecuteWithArgs(binaryPath string, args ...string) (int, string, string, error) {
+	exitCode, stdout, stderr, err := r.Executor.ExecuteWithArgs(binaryPath, args...)
+	r.note(exitCode, err)
+	return exitCode, stdout, stderr, err
+}
+
+// ExecuteTestCase forwards to the wrapped executor's ExecuteTestCase, or
+// runs the test case's stdin or arguments when it has none; it cannot run
+// both then (oracle.ErrStdinWithArgs).
+func (r *signalRecorder) ExecuteTestCase(binaryPath string, tc seed.TestCase, args ...string) (int, string, string, error) {
+	te, ok := r.Executor.(oracle.TestCaseExecutor)
+	if !ok {
+		if tc.Stdin != "" && len(args) > 0 {
+			return 0, "", "", fmt.Errorf("%s: %T: %w", binaryPath, r.Executor, oracle.ErrStdinWithArgs)
+		}
+		if tc.Stdin != "" {
+			return r.ExecuteWithInput(binaryPath, tc.Stdin)
+		}
+		return r.ExecuteWithArgs(binaryPath, args...)
+	}
+	exitCode, stdout, stderr, err := te.ExecuteTestCase(binaryPath, tc, args...)
+	r.note(exitCode, err)
+	return exitCode, stdout, stderr, err
+}
+
+func (r *signalRecorder) note(exitCode int, err error) {
+	if err != nil {
+		return
+	}
+	if name := exitSignal(exitCode); name != "" && !slices.Contains(r.meta.ExecSignals, name) {
+		r.meta.ExecSignals = append(r.meta.ExecSignals, name)
+	}
+}
@@ -0,0 +1,52 @@
+package fuzz
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/zjy-dev/de-fuzz/internal/oracle"
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+func TestExitSignal(t *testing.T) {
+	for exitCode, want := range map[int]string{
+		0:        "",
+		1:        "",
+		124:      seed.ExecSignalTimeout,
+		128:      "",
+		128 + 6:  "SIGABRT",
+		128 + 11: "SIGSEGV",
+		128 + 31: "SIG31",
+		255:      "",
+	} {
+		if got := exitSignal(exitCode); got != want {
+			t.Errorf("exitSignal(%d) = %q, want %q", exitCode, got, want)
+		}
+	}
+}
+
+func TestSignalRecorder(t *testing.T) {
+	var meta seed.Metadata
+	exec := &countingOracleExecutor{exitCode: 128 + 11}
+	recorder := &signalRecorder{Executor: exec, meta: &meta}
+
+	recorder.ExecuteWithInput("/tmp/seed", "")
+	recorder.ExecuteWithArgs("/tmp/seed", "64")
+	exec.exitCode = 0
+	recorder.ExecuteWithArgs("/tmp/seed", "8")
+	exec.exitCode = 124
+	recorder.ExecuteWithArgs("/tmp/seed", "1024")
+
+	if exec.calls != 4 {
+		t.Errorf("executor called %d times, want 4", exec.calls)
+	}
+	if len(meta.ExecSignals) != 2 || meta.ExecSignals[0] != "SIGSEGV" || meta.ExecSignals[1] != seed.ExecSignalTimeout {
+		t.Errorf("ExecSignals = %v, want [SIGSEGV timeout]", meta.ExecSignals)
+	}
+
+	// Without ExecuteTestCase, the wrapped executor cannot run both.
+	_, _, _, err := recorder.ExecuteTestCase("/tmp/seed", seed.TestCase{Stdin: "in"}, "64")
+	if !errors.Is(err, oracle.ErrStdinWithArgs) || exec.calls != 4 {
+		t.Errorf("ExecuteTestCase() with stdin and arguments = %v after %d calls, want oracle.ErrStdinWithArgs and no run", err, exec.calls)
+	}
+}
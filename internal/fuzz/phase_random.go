@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/zjy-dev/de-fuzz/internal/corpus"
+	"github.com/zjy-dev/de-fuzz/internal/llm"
 	"github.com/zjy-dev/de-fuzz/internal/logger"
 	"github.com/zjy-dev/de-fuzz/internal/oracle"
 	"github.com/zjy-dev/de-fuzz/internal/prompt"
@@ -28,7 +29,7 @@ func NewRandomMutationPhase(engine *Engine, maxIterations int) *RandomMutationPh
 	return &RandomMutationPhase{
 		engine:        engine,
 		maxIterations: maxIterations,
-		rng:           rand.New(rand.NewSource(time.Now().UnixNano())),
+		rng:           engine.rng,
 	}
 }
 
@@ -51,6 +52,11 @@ func (p *RandomMutationPhase) Run() error {
 			logger.Info("Random phase: reached max iterations (%d)", p.maxIterations)
 			break
 		}
+		if p.engine.budgetSpent() {
+			logger.Info("Random phase: time budget (%v) spent", p.engine.cfg.MaxDuration)
+			p.engine.stopReason = "time budget spent"
+			break
+		}
 
 		p.iterationCount++
 
@@ -114,10 +120,13 @@ func (p *RandomMutationPhase) getProcessedSeeds() []*seed.Seed {
 func (p *RandomMutationPhase) mutateAndCheck(baseSeed *seed.Seed) (*oracle.Bug, error) {
 	// Build mutation prompt using the standard mutation prompt
 	mutationCtx := &prompt.MutationContext{
-		TotalCoveragePercentage: float64(p.engine.cfg.Analyzer.GetBBCoverageBasisPoints()) / 100.0,
+		TotalCoveragePercentage: float64(p.engine.coverageBasisPoints()) / 100.0,
+	}
+	if depth := p.engine.cfg.PromptService.LineageDepth(); depth > 0 {
+		mutationCtx.Ancestors = p.engine.cfg.Corpus.AncestorSeeds(baseSeed.Meta.ID, depth)
 	}
 
-	systemPrompt, userPrompt, err := p.engine.cfg.PromptService.GetMutatePrompt("", mutationCtx)
+	systemPrompt, userPrompt, err := p.engine.cfg.PromptService.GetMutatePrompt(baseSeed, mutationCtx)
 	if err != nil {
 		return nil, err
 	}
@@ -129,7 +138,9 @@ func (p *RandomMutationPhase) mutateAndCheck(baseSeed *seed.Seed) (*oracle.Bug,
 	// logger.Debug("=== End Prompts ===")
 
 	// Call LLM
-	completion, err := p.engine.cfg.LLM.GetCompletionWithSystem(systemPrompt, userPrompt)
+	p.engine.llmScope = llm.CallInfo{SeedID: baseSeed.Meta.ID}
+	defer func() { p.engine.llmScope = llm.CallInfo{} }()
+	completion, err := p.engine.completeSeed(callMutate, systemPrompt, userPrompt)
 	if err != nil {
 		return nil, err
 	}
@@ -147,27 +158,31 @@ func (p *RandomMutationPhase) mutateAndCheck(baseSeed *seed.Seed) (*oracle.Bug,
 	mutatedSeed.Meta.CreatedAt = time.Now()
 	p.engine.assignDefaultProfile(mutatedSeed)
 
-	// Compile the seed
-	compileResult, err := p.engine.cfg.Compiler.Compile(mutatedSeed)
+	// Compile the seed, repairing compile errors if enabled
+	compileResult, err := p.engine.compileWithFixes(mutatedSeed)
 	if err != nil || !compileResult.Success {
 		logger.Debug("Random phase: seed %d failed to compile", mutatedSeed.Meta.ID)
 		return nil, nil
 	}
+	mutatedSeed.Meta.MutationNote = seed.SummarizeDiff(baseSeed.Content, mutatedSeed.Content, mutatedSeed.Language)
 
 	// Run oracle
 	if p.engine.cfg.Oracle == nil {
 		return nil, nil
 	}
 
-	bug := p.engine.runOracle(mutatedSeed, compileResult.BinaryPath)
+	bug := p.engine.runOracle(mutatedSeed, compileResult)
 	if bug != nil {
 		// Persist the seed that found a bug
 		mutatedSeed.Meta.OracleVerdict = seed.OracleVerdictBug
 		mutatedSeed.Meta.BugDescription = bug.Description
-		if err := p.engine.cfg.Corpus.Add(mutatedSeed); err != nil {
+		mutatedSeed.Meta.CompileOK = true
+		if added, err := p.engine.addToCorpus(mutatedSeed); err != nil {
 			logger.Warn("Failed to persist bug-triggering seed: %v", err)
-		} else {
+		} else if added {
 			p.engine.persistCompilationRecord(mutatedSeed, compileResult)
+			p.engine.saveMinimizedSource(mutatedSeed, bug)
+			p.engine.exportBundle(mutatedSeed, bug, compileResult)
 		}
 	}
 
@@ -0,0 +1,215 @@
+package fuzz
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/zjy-dev/de-fuzz/internal/logger"
+)
+
+// Status is a snapshot of a running campaign. The progress reporter logs it
+// every ProgressInterval and writes it to StatusPath, where dashboards can
+// poll it.
+type Status struct {
+	UpdatedAt time.Time `json:"updated_at"`
+	Phase     string    `json:"phase"` // "initial", "constraint" or "done"
+
+	ElapsedSeconds    float64 `json:"elapsed_seconds"`
+	Iterations        int     `json:"iterations"`
+	IterationsPerHour float64 `json:"iterations_per_hour"` // This run's rate
+	ETASeconds        float64 `json:"eta_seconds,omitempty"`
+
+	LLMCalls           int     `json:"llm_calls"`
+	Compiles           int     `json:"compiles"`
+	CompileFailures    int     `json:"compile_failures"`
+	CompileFailureRate float64 `json:"compile_failure_rate"`
+
+	CoverageBP               uint64    `json:"coverage_bp"`
+	TargetHits               int       `json:"target_hits"`
+	Bugs                     int       `json:"bugs"`
+	LastCoverageGain         time.Time `json:"last_coverage_gain,omitempty"`
+	SecondsSinceCoverageGain float64   `json:"seconds_since_coverage_gain,omitempty"`
+	CurrentTarget            string    `json:"current_target,omitempty"`
+
+	// Cooldowns maps the targets passed over after exhausting their
+	// retries to the iterations they are still passed over in.
+	Cooldowns map[string]int `json:"cooldowns,omitempty"`
+}
+
+// progressReporter reports the engine's progress from its own goroutine.
+// The engine hands it counter snapshots with update, so the goroutine never
+// reads engine state.
+type progressReporter struct {
+	interval time.Duration
+	path     string
+	stop     chan struct{}
+	done     chan struct{}
+
+	mu     sync.Mutex
+	status Status
+
+	// Set by startProgress: this run's start, with the campaign time and
+	// iterations before it, for the elapsed time and rate; the time the
+	// limits leave, for the ETA.
+	started        time.Time
+	elapsedBase    time.Duration
+	iterationsBase int
+	limits         func(Status) time.Duration
+}
+
+// publishProgress passes the counters the summary reports to the progress
+// reporter. It is cheap enough to call every iteration.
+func (e *Engine) publishProgress(phase string) {
+	if e.progress == nil {
+		return
+	}
+	status := Status{
+		Phase:           phase,
+		Iterations:      e.iterationCount,
+		LLMCalls:        e.totalLLMUsage().Calls,
+		Compiles:        e.compiles,
+		CompileFailures: e.compileFailures,
+		TargetHits:      e.targetHits,
+		Bugs:            len(e.bugsFound),
+	}
+	status.CoverageBP = e.coverageBasisPoints()
+	status.LastCoverageGain = e.lastCoverageGain
+	if e.inFlight != nil {
+		status.CurrentTarget = fmt.Sprintf("%s:BB%d", e.inFlight.Function, e.inFlight.BBID)
+	}
+	status.Cooldowns = e.cooldownsLeft()
+	e.progress.update(status)
+}
+
+// startProgress starts the progress reporter when ProgressInterval is set.
+// The returned function stops it after a last report.
+func (e *Engine) startProgress() func() {
+	if e.cfg.ProgressInterval <= 0 {
+		return func() {}
+	}
+	e.progress = &progressReporter{
+		interval:       e.cfg.ProgressInterval,
+		path:           e.cfg.StatusPath,
+		stop:           make(chan struct{}),
+		done:           make(chan struct{}),
+		started:        e.startTime,
+		elapsedBase:    e.priorElapsed,
+		iterationsBase: e.iterationCount,
+		limits:         e.remaining,
+	}
+	e.publishProgress("initial")
+	go e.progress.run()
+	return func() {
+		e.publishProgress("done")
+		close(e.progress.stop)
+		<-e.progress.done
+		e.progress = nil
+	}
+}
+
+// remaining returns how long the limits leave the campaign at the rate in
+// status: the sooner of MaxIterations and MaxDuration, 0 without either.
+func (e *Engine) remaining(status Status) time.Duration {
+	var eta time.Duration
+	if e.cfg.MaxIterations > 0 && status.IterationsPerHour > 0 {
+		left := float64(max(e.cfg.MaxIterations-status.Iterations, 0))
+		eta = time.Duration(left / status.IterationsPerHour * float64(time.Hour))
+	}
+	if e.cfg.MaxDuration > 0 {
+		budget := max(e.cfg.MaxDuration-time.Duration(status.ElapsedSeconds*float64(time.Second)), 0)
+		if eta == 0 || budget < eta {
+			eta = budget
+		}
+	}
+	return eta
+}
+
+func (p *progressReporter) update(status Status) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status = status
+}
+
+// snapshot returns the last status with the rates filled in.
+func (p *progressReporter) snapshot() Status {
+	p.mu.Lock()
+	status := p.status
+	p.mu.Unlock()
+
+	status.UpdatedAt = time.Now()
+	status.ElapsedSeconds = (p.elapsedBase + time.Since(p.started)).Seconds()
+	if hours := time.Since(p.started).Hours(); hours > 0 {
+		status.IterationsPerHour = float64(status.Iterations-p.iterationsBase) / hours
+	}
+	if !status.LastCoverageGain.IsZero() {
+		status.SecondsSinceCoverageGain = time.Since(status.LastCoverageGain).Seconds()
+	}
+	if status.Compiles > 0 {
+		status.CompileFailureRate = float64(status.CompileFailures) / float64(status.Compiles)
+	}
+	if status.Phase != "done" {
+		status.ETASeconds = p.limits(status).Seconds()
+	}
+	return status
+}
+
+func (p *progressReporter) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.report(p.snapshot())
+		case <-p.stop:
+			p.report(p.snapshot())
+			return
+		}
+	}
+}
+
+// report logs status and writes it to the status file.
+func (p *progressReporter) report(status Status) {
+	target := status.CurrentTarget
+	if target == "" {
+		target = "-"
+	}
+	logger.Info("Progress: %d iterations (%.1f/h), %d LLM calls, %.0f%% compile failures, coverage %d bp, %d targets hit, %d bugs, last gain %v ago, target %s",
+		status.Iterations, status.IterationsPerHour, status.LLMCalls, status.CompileFailureRate*100,
+		status.CoverageBP, status.TargetHits, status.Bugs,
+		time.Duration(status.SecondsSinceCoverageGain*float64(time.Second)).Round(time.Second), target)
+	if len(status.Cooldowns) > 0 {
+		logger.Info("Progress: cooling down %s", formatCooldowns(status.Cooldowns))
+	}
+
+	if p.path == "" {
+		return
+	}
+	if err := writeStatus(p.path, status); err != nil {
+		logger.Warn("%v", err)
+	}
+}
+
+// writeStatus writes status to path through a temporary file, so pollers
+// never read half of it.
+func writeStatus(path string, status Status) error {
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode status: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to write status: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write status: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write status: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package fuzz
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEngine_ProgressStatusFile(t *testing.T) {
+	_, corpusManager := newInitialPhase(t)
+	cfg := newDryRunConfig(t)
+	cfg.Corpus = corpusManager
+	cfg.MaxIterations = 2
+	cfg.ProgressInterval = time.Millisecond
+	cfg.StatusPath = filepath.Join(t.TempDir(), "out", "status.json")
+	engine := NewEngine(cfg)
+	if err := engine.Run(context.Background()); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if engine.progress != nil {
+		t.Error("progress reporter still set after Run()")
+	}
+
+	data, err := os.ReadFile(cfg.StatusPath)
+	if err != nil {
+		t.Fatalf("status file not written: %v", err)
+	}
+	var status Status
+	if err := json.Unmarshal(data, &status); err != nil {
+		t.Fatal(err)
+	}
+	if status.Phase != "done" || status.Iterations != 2 || status.ETASeconds != 0 || status.ElapsedSeconds <= 0 {
+		t.Errorf("final status = %+v, want phase done after 2 iterations, no ETA", status)
+	}
+	if status.IterationsPerHour <= 0 {
+		t.Errorf("IterationsPerHour = %v, want a positive rate", status.IterationsPerHour)
+	}
+}
+
+func TestEngine_ProgressRemaining(t *testing.T) {
+	for _, tt := range []struct {
+		name          string
+		maxIterations int
+		maxDuration   time.Duration
+		want          time.Duration
+	}{
+		{"no limits", -1, 0, 0},
+		{"iterations", 10, 0, 2 * time.Hour},
+		{"duration sooner", 10, 90 * time.Minute, time.Hour},
+		{"iterations sooner", 10, 5 * time.Hour, 2 * time.Hour},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewEngine(Config{MaxIterations: tt.maxIterations, MaxDuration: tt.maxDuration})
+			status := Status{Iterations: 6, IterationsPerHour: 2, ElapsedSeconds: 1800}
+			if got := engine.remaining(status); got != tt.want {
+				t.Errorf("remaining() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package fuzz
+
+import (
+	"math"
+
+	"github.com/zjy-dev/de-fuzz/internal/coverage"
+)
+
+const (
+	// hitRateWindow is how many recent targets the rolling hit rate of
+	// adaptive retries covers.
+	hitRateWindow = 20
+	// maxRetriesFactor caps adaptive retry budgets at this many times
+	// MaxRetries.
+	maxRetriesFactor = 2
+)
+
+// retriesFor returns the retry budget of target: MaxRetries, or with
+// AdaptiveRetries, MaxRetries scaled by how much of its weight target kept
+// (a BB starts out weighing its successor count, and loses weight with
+// every miss) and by the rolling hit rate, from 0.5 when no recent target
+// was hit to 1.5 when all were. The budget is clamped to
+// [1, maxRetriesFactor*MaxRetries].
+func (e *Engine) retriesFor(target *coverage.TargetInfo) int {
+	if !e.cfg.AdaptiveRetries {
+		return e.cfg.MaxRetries
+	}
+	kept := 0.0
+	if target.SuccessorCount > 0 {
+		weight := e.cfg.Analyzer.GetBBWeight(target.Function, target.BBID)
+		kept = weight / float64(target.SuccessorCount)
+	}
+	budget := int(math.Ceil(float64(e.cfg.MaxRetries) * kept * (0.5 + e.hitRate())))
+	return max(1, min(budget, maxRetriesFactor*e.cfg.MaxRetries))
+}
+
+// hitRate is the share of the last hitRateWindow targets that were hit,
+// 0.5 before any target was tried.
+func (e *Engine) hitRate() float64 {
+	if len(e.recentHits) == 0 {
+		return 0.5
+	}
+	hits := 0
+	for _, hit := range e.recentHits {
+		if hit {
+			hits++
+		}
+	}
+	return float64(hits) / float64(len(e.recentHits))
+}
+
+// noteTargetOutcome adds whether the target of an iteration was hit to the
+// rolling hit rate.
+func (e *Engine) noteTargetOutcome(hit bool) {
+	e.recentHits = append(e.recentHits, hit)
+	if len(e.recentHits) > hitRateWindow {
+		e.recentHits = e.recentHits[1:]
+	}
+}
@@ -0,0 +1,92 @@
+package fuzz
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/zjy-dev/de-fuzz/internal/prompt"
+)
+
+func TestEngine_RetriesFor(t *testing.T) {
+	analyzer := newTwoTargetAnalyzer(t)
+	hot := analyzer.TargetFor("hot", 2)
+	engine := NewEngine(Config{Analyzer: analyzer, MaxRetries: 4, AdaptiveRetries: true})
+
+	fresh := engine.retriesFor(hot)
+	if fresh != 4 {
+		t.Errorf("retriesFor(fresh target) = %d, want MaxRetries", fresh)
+	}
+	analyzer.DecayBBWeight("hot", 2)
+	analyzer.DecayBBWeight("hot", 2)
+	analyzer.DecayBBWeight("hot", 2)
+	analyzer.DecayBBWeight("hot", 2)
+	if decayed := engine.retriesFor(hot); decayed != 2 {
+		t.Errorf("retriesFor(decayed target) = %d, want 2, fewer than a fresh one's %d", decayed, fresh)
+	}
+
+	for _, tt := range []struct {
+		hits bool
+		want int
+	}{{true, 3}, {false, 1}} {
+		engine.recentHits = nil
+		for range hitRateWindow + 5 {
+			engine.noteTargetOutcome(tt.hits)
+		}
+		if got := engine.retriesFor(hot); got != tt.want {
+			t.Errorf("retriesFor() with hits=%v = %d, want %d", tt.hits, got, tt.want)
+		}
+	}
+	if len(engine.recentHits) != hitRateWindow {
+		t.Errorf("%d outcomes kept, want %d", len(engine.recentHits), hitRateWindow)
+	}
+
+	engine.cfg.AdaptiveRetries = false
+	if got := engine.retriesFor(hot); got != 4 {
+		t.Errorf("retriesFor() = %d without adaptive retries, want MaxRetries", got)
+	}
+}
+
+func TestEngine_RetryBudgetEvent(t *testing.T) {
+	baseDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(baseDir, "constraint.md"), []byte("system"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	promptService, err := prompt.NewPromptService(baseDir, "", prompt.NewBuilder(0, "", nil))
+	if err != nil {
+		t.Fatalf("NewPromptService() failed: %v", err)
+	}
+	_, corpusManager := newInitialPhase(t)
+	sink := &budgetSink{}
+	engine := NewEngine(Config{
+		Corpus:          corpusManager,
+		Compiler:        &fixableCompiler{want: "main"},
+		Analyzer:        newTwoTargetAnalyzer(t),
+		LLM:             &unavailableLLM{},
+		PromptService:   promptService,
+		MaxIterations:   3,
+		MaxRetries:      4,
+		AdaptiveRetries: true,
+		EventSinks:      []EventSink{sink},
+	})
+	if err := engine.Run(context.Background()); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	// Every target misses: hot, still the heaviest, decays and the hit rate
+	// drops to 0
+	if want := []int{4, 2, 2}; !reflect.DeepEqual(sink.budgets, want) {
+		t.Errorf("retry budgets = %v, want %v", sink.budgets, want)
+	}
+}
+
+// budgetSink records the retry budgets of the iterations.
+type budgetSink struct {
+	recordingSink
+	budgets []int
+}
+
+func (s *budgetSink) OnIterationEnd(ev IterationEvent) {
+	s.budgets = append(s.budgets, ev.RetryBudget)
+}
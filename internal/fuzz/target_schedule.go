@@ -0,0 +1,95 @@
+package fuzz
+
+import (
+	"github.com/zjy-dev/de-fuzz/internal/coverage"
+	"github.com/zjy-dev/de-fuzz/internal/logger"
+)
+
+// TargetSchedule selects how iterations are shared between target
+// functions.
+type TargetSchedule string
+
+const (
+	// ScheduleWeighted selects the best BB among all target functions (the
+	// default), which may starve functions with less branchy BBs.
+	ScheduleWeighted TargetSchedule = "weighted"
+	// ScheduleRoundRobin cycles through the target functions, one per
+	// iteration.
+	ScheduleRoundRobin TargetSchedule = "round_robin"
+	// ScheduleStride gives each target function iterations in proportion
+	// to how many of its BBs are uncovered.
+	ScheduleStride TargetSchedule = "stride"
+)
+
+// strideUnit is the pass a function with a single uncovered BB advances by
+// when it is scheduled under ScheduleStride.
+const strideUnit = 1 << 20
+
+// targetSelector is the part of the Analyzer target schedules select with.
+type targetSelector interface {
+	TargetFunctions() []string
+	GetFunctionCoverage() map[string]struct{ Covered, Total int }
+	SelectTargetExcluding(exclude func(funcName string, bbID int) bool) *coverage.TargetInfo
+	SelectTargetIn(funcName string, exclude func(funcName string, bbID int) bool) *coverage.TargetInfo
+}
+
+// targetScheduler picks the function each iteration targets a BB in. When
+// that function has no BB left to target, the BB is selected among all
+// functions instead.
+type targetScheduler struct {
+	schedule TargetSchedule
+	next     int            // Round robin: index of the next function
+	pass     map[string]int // Stride: pass of each function
+}
+
+// selectTarget selects the next target with a, passing over the BBs exclude
+// reports.
+func (s *targetScheduler) selectTarget(a targetSelector, exclude func(string, int) bool) *coverage.TargetInfo {
+	var funcName string
+	switch s.schedule {
+	case ScheduleRoundRobin:
+		funcName = s.nextRoundRobin(a.TargetFunctions())
+	case ScheduleStride:
+		funcName = s.nextStride(a.TargetFunctions(), a.GetFunctionCoverage())
+	}
+	if funcName != "" {
+		if target := a.SelectTargetIn(funcName, exclude); target != nil {
+			return target
+		}
+		logger.Debug("No target left in %s, selecting among all target functions", funcName)
+	}
+	return a.SelectTargetExcluding(exclude)
+}
+
+// nextRoundRobin returns the function after the one it returned last.
+func (s *targetScheduler) nextRoundRobin(functions []string) string {
+	if len(functions) == 0 {
+		return ""
+	}
+	funcName := functions[s.next%len(functions)]
+	s.next = (s.next + 1) % len(functions)
+	return funcName
+}
+
+// nextStride returns the function with uncovered BBs whose pass is lowest,
+// the first of them on a tie, and advances its pass by strideUnit over its
+// uncovered BB count. It returns "" when every function is covered.
+func (s *targetScheduler) nextStride(functions []string, funcCoverage map[string]struct{ Covered, Total int }) string {
+	if s.pass == nil {
+		s.pass = make(map[string]int)
+	}
+	funcName, uncovered := "", 0
+	for _, fn := range functions {
+		left := funcCoverage[fn].Total - funcCoverage[fn].Covered
+		if left <= 0 {
+			continue
+		}
+		if funcName == "" || s.pass[fn] < s.pass[funcName] {
+			funcName, uncovered = fn, left
+		}
+	}
+	if funcName != "" {
+		s.pass[funcName] += strideUnit / uncovered
+	}
+	return funcName
+}
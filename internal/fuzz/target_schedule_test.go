@@ -0,0 +1,88 @@
+package fuzz
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zjy-dev/de-fuzz/internal/coverage"
+)
+
+// mockSelector has target functions with the given uncovered BB counts;
+// "*" marks a target selected among all functions.
+type mockSelector struct {
+	functions []string
+	uncovered map[string]int
+}
+
+func (m *mockSelector) TargetFunctions() []string { return m.functions }
+
+func (m *mockSelector) GetFunctionCoverage() map[string]struct{ Covered, Total int } {
+	cov := make(map[string]struct{ Covered, Total int })
+	for _, fn := range m.functions {
+		cov[fn] = struct{ Covered, Total int }{Covered: 1, Total: 1 + m.uncovered[fn]}
+	}
+	return cov
+}
+
+func (m *mockSelector) SelectTargetExcluding(func(string, int) bool) *coverage.TargetInfo {
+	return &coverage.TargetInfo{Function: "*"}
+}
+
+func (m *mockSelector) SelectTargetIn(funcName string, _ func(string, int) bool) *coverage.TargetInfo {
+	if m.uncovered[funcName] == 0 {
+		return nil
+	}
+	return &coverage.TargetInfo{Function: funcName}
+}
+
+func scheduleTargets(s *targetScheduler, a targetSelector, n int) []string {
+	var functions []string
+	for range n {
+		functions = append(functions, s.selectTarget(a, nil).Function)
+	}
+	return functions
+}
+
+func TestTargetScheduler(t *testing.T) {
+	selector := &mockSelector{
+		functions: []string{"a", "b", "c"},
+		uncovered: map[string]int{"a": 3, "b": 1},
+	}
+	for _, tt := range []struct {
+		schedule TargetSchedule
+		want     []string
+	}{
+		{ScheduleWeighted, []string{"*", "*", "*", "*", "*", "*", "*", "*"}},
+		// c has nothing uncovered: its turn falls back to global selection
+		{ScheduleRoundRobin, []string{"a", "b", "*", "a", "b", "*", "a", "b"}},
+		// a has three times as many uncovered BBs as b; c gets no turn
+		{ScheduleStride, []string{"a", "b", "a", "a", "a", "b", "a", "a"}},
+	} {
+		t.Run(string(tt.schedule), func(t *testing.T) {
+			scheduler := &targetScheduler{schedule: tt.schedule}
+			if got := scheduleTargets(scheduler, selector, len(tt.want)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("targets = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTargetScheduler_StrideAllCovered(t *testing.T) {
+	selector := &mockSelector{functions: []string{"a", "b"}}
+	scheduler := &targetScheduler{schedule: ScheduleStride}
+	if got := scheduleTargets(scheduler, selector, 2); !reflect.DeepEqual(got, []string{"*", "*"}) {
+		t.Errorf("targets = %q, want global selection once every function is covered", got)
+	}
+}
+
+func TestEngine_RoundRobinTargets(t *testing.T) {
+	engine := NewEngine(Config{Analyzer: newTwoTargetAnalyzer(t), TargetSchedule: ScheduleRoundRobin})
+	var got []string
+	for range 4 {
+		target := engine.selectTarget()
+		got = append(got, cooldownKey(target.Function, target.BBID))
+	}
+	if want := []string{"hot:BB2", "cold:BB2", "hot:BB2", "cold:BB2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("targets = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,278 @@
+package llm
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/zjy-dev/de-fuzz/internal/logger"
+)
+
+// CallInfo says what an LLM request is for. The engine attaches it to the
+// request context with WithCallInfo; the audit log records it.
+type CallInfo struct {
+	Type   string // Call type, e.g. "generate" or "repair"
+	Target string // Target basic block, e.g. "expand_used_vars:BB9"
+	SeedID uint64 // Base seed of the request, 0 if none
+}
+
+// Call types the engine tags its requests with (CallInfo.Type). They key
+// the usage report and the per-call-type sampling overrides.
+const (
+	CallUnderstand = "understand" // understanding summaries
+	CallGenerate   = "generate"   // new seeds, including constraint solving
+	CallRefine     = "refine"     // divergence-refined retries
+	CallRepair     = "repair"     // compile-fix prompts
+	CallMutate     = "mutate"     // random-phase mutations
+)
+
+type callInfoKey struct{}
+
+// WithCallInfo returns a copy of ctx carrying info.
+func WithCallInfo(ctx context.Context, info CallInfo) context.Context {
+	return context.WithValue(ctx, callInfoKey{}, info)
+}
+
+// CallInfoFrom returns the CallInfo attached to ctx, or the zero value.
+func CallInfoFrom(ctx context.Context) CallInfo {
+	info, _ := ctx.Value(callInfoKey{}).(CallInfo)
+	return info
+}
+
+// withDefaultCallType tags ctx with callType unless it already carries one.
+func withDefaultCallType(ctx context.Context, callType string) context.Context {
+	info := CallInfoFrom(ctx)
+	if info.Type != "" {
+		return ctx
+	}
+	info.Type = callType
+	return WithCallInfo(ctx, info)
+}
+
+// auditPromptChars clips each message in the audit log unless full prompts
+// are enabled. Completions are always logged in full.
+const auditPromptChars = 2000
+
+// auditQueueSize is how many records may wait for the writer before new
+// ones are dropped.
+const auditQueueSize = 256
+
+// AuditRecord is one line of the audit log: a request, its outcome and what
+// it was for.
+type AuditRecord struct {
+	Time       time.Time      `json:"time"`
+	CallType   string         `json:"call_type,omitempty"`
+	Target     string         `json:"target,omitempty"`
+	SeedID     uint64         `json:"seed_id,omitempty"`
+	Model      string         `json:"model"`
+	PromptHash string         `json:"prompt_hash"`
+	Messages   []AuditMessage `json:"messages"`
+	Completion string         `json:"completion,omitempty"`
+	Cached     bool           `json:"cached,omitempty"` // Answered from the on-disk cache
+
+	PromptTokens     int    `json:"prompt_tokens,omitempty"`
+	CompletionTokens int    `json:"completion_tokens,omitempty"`
+	LatencyMS        int64  `json:"latency_ms"`
+	Error            string `json:"error,omitempty"`
+}
+
+// AuditMessage is one request message. Truncated is set when Content was
+// clipped.
+type AuditMessage struct {
+	Role      string `json:"role"`
+	Content   string `json:"content"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// auditLog appends AuditRecords to a JSONL file from a background goroutine
+// so logging never blocks an LLM call: when the queue is full the record is
+// dropped and counted. When the file would grow past maxBytes it is rotated
+// to path.1 (the previous path.1 becomes path.2, and so on up to
+// auditKeepFiles).
+type auditLog struct {
+	path        string
+	maxBytes    int64 // 0 = never rotate
+	fullPrompts bool
+
+	records chan AuditRecord
+	done    chan struct{}
+
+	mu      sync.Mutex // guards dropped
+	dropped int
+}
+
+const auditKeepFiles = 5
+
+func newAuditLog(path string, maxBytes int64, fullPrompts bool) (*auditLog, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open LLM audit log %s: %w", path, err)
+	}
+	a := &auditLog{
+		path:        path,
+		maxBytes:    maxBytes,
+		fullPrompts: fullPrompts,
+		records:     make(chan AuditRecord, auditQueueSize),
+		done:        make(chan struct{}),
+	}
+	go a.run(f)
+	return a, nil
+}
+
+// record queues rec without waiting. A nil log records nothing.
+func (a *auditLog) record(rec AuditRecord) {
+	if a == nil {
+		return
+	}
+	select {
+	case a.records <- rec:
+	default:
+		a.mu.Lock()
+		a.dropped++
+		a.mu.Unlock()
+	}
+}
+
+// messages converts request messages for a record, clipping them unless
+// full prompts are enabled.
+func (a *auditLog) messages(messages []remixerMessage) []AuditMessage {
+	out := make([]AuditMessage, len(messages))
+	for i, m := range messages {
+		out[i] = AuditMessage{Role: m.Role, Content: m.Content}
+		if !a.fullPrompts && len(m.Content) > auditPromptChars {
+			out[i].Content = m.Content[:auditPromptChars]
+			out[i].Truncated = true
+		}
+	}
+	return out
+}
+
+// run writes queued records until the queue is closed, flushing whenever it
+// runs empty.
+func (a *auditLog) run(f *os.File) {
+	defer close(a.done)
+
+	var size int64
+	if info, err := f.Stat(); err == nil {
+		size = info.Size()
+	}
+	w := bufio.NewWriter(f)
+	for rec := range a.records {
+		line, err := json.Marshal(rec)
+		if err != nil {
+			logger.Warn("[LLM] audit log: %v", err)
+			continue
+		}
+		line = append(line, '\n')
+
+		if a.maxBytes > 0 && size > 0 && size+int64(len(line)) > a.maxBytes {
+			if f, err = a.rotate(w, f); err != nil {
+				logger.Warn("[LLM] audit log: %v", err)
+				return
+			}
+			w.Reset(f)
+			size = 0
+		}
+		if _, err := w.Write(line); err != nil {
+			logger.Warn("[LLM] audit log: %v", err)
+		}
+		size += int64(len(line))
+
+		if len(a.records) == 0 {
+			w.Flush()
+		}
+	}
+	w.Flush()
+	f.Close()
+}
+
+// rotate closes the current file, shifts path.N to path.N+1 and reopens an
+// empty path.
+func (a *auditLog) rotate(w *bufio.Writer, f *os.File) (*os.File, error) {
+	w.Flush()
+	f.Close()
+	for i := auditKeepFiles - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", a.path, i), fmt.Sprintf("%s.%d", a.path, i+1))
+	}
+	if err := os.Rename(a.path, a.path+".1"); err != nil {
+		return nil, fmt.Errorf("failed to rotate %s: %w", a.path, err)
+	}
+	f, err := os.OpenFile(a.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reopen %s: %w", a.path, err)
+	}
+	return f, nil
+}
+
+// close writes the queued records and closes the file.
+func (a *auditLog) close() error {
+	if a == nil {
+		return nil
+	}
+	close(a.records)
+	<-a.done
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.dropped > 0 {
+		logger.Warn("[LLM] audit log dropped %d records while the writer was busy", a.dropped)
+	}
+	return nil
+}
+
+// promptHash hashes the roles and contents of a request's messages, so
+// records of identical prompts can be grouped.
+func promptHash(messages []remixerMessage) string {
+	h := sha256.New()
+	for _, m := range messages {
+		h.Write([]byte(m.Role))
+		h.Write([]byte{0})
+		h.Write([]byte(m.Content))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ReadAuditLog returns the records of the audit log at path, oldest first,
+// including its rotated files (path.N ... path.1). Torn lines from an
+// interrupted run are skipped.
+func ReadAuditLog(path string) ([]AuditRecord, error) {
+	files := []string{path}
+	for i := 1; ; i++ {
+		rotated := fmt.Sprintf("%s.%d", path, i)
+		if _, err := os.Stat(rotated); err != nil {
+			break
+		}
+		files = append([]string{rotated}, files...)
+	}
+
+	var records []AuditRecord
+	for _, file := range files {
+		f, err := os.Open(file)
+		if errors.Is(err, os.ErrNotExist) && file == path {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log: %w", err)
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+		for scanner.Scan() {
+			var rec AuditRecord
+			if json.Unmarshal(scanner.Bytes(), &rec) == nil {
+				records = append(records, rec)
+			}
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read audit log %s: %w", file, err)
+		}
+	}
+	return records, nil
+}
@@ -0,0 +1,117 @@
+package llm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAuditLogRecordsRequests(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	audit, err := newAuditLog(path, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := &countingProvider{reply: "int main(void) { return 0; }"}
+	engine := &remixerEngine{
+		selector: &weightedSelector{
+			entries:     []selectorEntry{{name: "m", providers: []remixerProvider{provider}, upper: 1}},
+			totalWeight: 1,
+		},
+		retrier: newRetrier(remixerRetryConfig{MaxAttempts: 1}),
+		audit:   audit,
+	}
+
+	long := strings.Repeat("x", auditPromptChars+10)
+	ctx := WithCallInfo(context.Background(), CallInfo{Type: "generate", Target: "f:BB3", SeedID: 7})
+	req := remixerChatRequest{Messages: []remixerMessage{{Role: "system", Content: "sys"}, {Role: "user", Content: long}}}
+	if _, err := engine.Chat(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	provider.fail = true
+	if _, err := engine.Chat(context.Background(), req); err == nil {
+		t.Fatal("expected the failing provider to return an error")
+	}
+	if err := audit.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := ReadAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2", len(records))
+	}
+	ok := records[0]
+	if ok.CallType != "generate" || ok.Target != "f:BB3" || ok.SeedID != 7 || ok.Model != "m" {
+		t.Errorf("record scope = %+v", ok)
+	}
+	if ok.Completion != provider.reply || ok.Error != "" {
+		t.Errorf("completion = %q, error = %q", ok.Completion, ok.Error)
+	}
+	if ok.PromptHash != promptHash(req.Messages) || ok.PromptHash != records[1].PromptHash {
+		t.Errorf("prompt hash %q does not match the request", ok.PromptHash)
+	}
+	if user := ok.Messages[1]; !user.Truncated || len(user.Content) != auditPromptChars {
+		t.Errorf("user message not clipped: truncated=%v, %d chars", user.Truncated, len(user.Content))
+	}
+	if failed := records[1]; failed.Error == "" || failed.Completion != "" || failed.CallType != "" {
+		t.Errorf("failed record = %+v", failed)
+	}
+}
+
+func TestAuditLogRotatesBySize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	audit, err := newAuditLog(path, 500, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 6; i++ {
+		audit.record(AuditRecord{Model: "m", Completion: strings.Repeat("y", 100), SeedID: uint64(i + 1)})
+	}
+	if err := audit.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(path + ".1"); err != nil {
+		t.Fatalf("log was not rotated: %v", err)
+	}
+	for _, file := range []string{path, path + ".1"} {
+		if info, err := os.Stat(file); err != nil || info.Size() > 500 {
+			t.Errorf("%s: size over the limit (%v)", file, err)
+		}
+	}
+	records, err := ReadAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 6 {
+		t.Fatalf("got %d records across rotated files, want 6", len(records))
+	}
+	for i, rec := range records {
+		if rec.SeedID != uint64(i+1) {
+			t.Fatalf("records out of order: %d at position %d", rec.SeedID, i)
+		}
+	}
+}
+
+func TestAuditLogDropsWhenFull(t *testing.T) {
+	// A log whose writer never runs: record must not block once the queue
+	// is full.
+	audit := &auditLog{records: make(chan AuditRecord, 1), done: make(chan struct{})}
+	for i := 0; i < 3; i++ {
+		audit.record(AuditRecord{})
+	}
+	if audit.dropped != 2 {
+		t.Errorf("dropped = %d, want 2", audit.dropped)
+	}
+
+	var nilLog *auditLog
+	nilLog.record(AuditRecord{})
+	if err := nilLog.close(); err != nil {
+		t.Errorf("nil log close: %v", err)
+	}
+}
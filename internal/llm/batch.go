@@ -0,0 +1,87 @@
+package llm
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultBatchConcurrency bounds how many requests of a batch are in flight
+// at once when no concurrency is configured.
+const DefaultBatchConcurrency = 4
+
+// Request is one completion of a batch.
+type Request struct {
+	SystemPrompt string
+	UserPrompt   string
+	Format       ResponseFormat // See CompleteSeed
+}
+
+// Response is the outcome of one batch Request. Err is set when that
+// request failed; the rest of the batch is unaffected.
+type Response struct {
+	Text string
+	Err  error
+}
+
+// BatchCompleter is implemented by clients that complete several requests
+// at once, through a native batch endpoint or concurrent calls.
+// GetCompletions returns one Response per request, in request order. The
+// error is set only when ctx ended before every request finished; the
+// Responses still hold what did.
+type BatchCompleter interface {
+	GetCompletions(ctx context.Context, requests []Request) ([]Response, error)
+}
+
+// GetCompletions completes requests with client's BatchCompleter, or with
+// up to concurrency concurrent calls (0 = DefaultBatchConcurrency) when it
+// has none. See BatchCompleter for the results.
+func GetCompletions(ctx context.Context, client LLM, requests []Request, concurrency int) ([]Response, error) {
+	if batcher, ok := client.(BatchCompleter); ok {
+		return batcher.GetCompletions(ctx, requests)
+	}
+	return completeConcurrently(ctx, requests, concurrency, func(ctx context.Context, req Request) (string, error) {
+		return CompleteSeed(ctx, client, req.Format, req.SystemPrompt, req.UserPrompt)
+	})
+}
+
+// GetCompletions completes requests concurrently, at most as many at once
+// as WithBatchConcurrency allows. Every call goes through the remixer's
+// rate limiter, so a batch never outpaces rate_limit.
+func (c *RemixerClient) GetCompletions(ctx context.Context, requests []Request) ([]Response, error) {
+	return completeConcurrently(ctx, requests, c.batchConcurrency, func(ctx context.Context, req Request) (string, error) {
+		return c.complete(ctx, req.SystemPrompt, req.UserPrompt, req.Format)
+	})
+}
+
+// completeConcurrently runs complete for every request with at most
+// concurrency calls in flight. Requests not started before ctx ends fail
+// with ctx's error.
+func completeConcurrently(ctx context.Context, requests []Request, concurrency int, complete func(context.Context, Request) (string, error)) ([]Response, error) {
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+	responses := make([]Response, len(requests))
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, req := range requests {
+		if err := ctx.Err(); err != nil {
+			responses[i].Err = err
+			continue
+		}
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			responses[i].Err = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			text, err := complete(ctx, req)
+			responses[i] = Response{Text: text, Err: err}
+		}()
+	}
+	wg.Wait()
+	return responses, ctx.Err()
+}
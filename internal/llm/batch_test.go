@@ -0,0 +1,122 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echoProvider replies with the user prompt after a short delay, failing
+// prompts listed in fail, and records the most calls it saw at once.
+type echoProvider struct {
+	fail map[string]bool
+
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+}
+
+func (p *echoProvider) Chat(ctx context.Context, req remixerChatRequest) (remixerChatResponse, error) {
+	n := p.inFlight.Add(1)
+	defer p.inFlight.Add(-1)
+	for {
+		seen := p.maxInFlight.Load()
+		if n <= seen || p.maxInFlight.CompareAndSwap(seen, n) {
+			break
+		}
+	}
+	select {
+	case <-time.After(10 * time.Millisecond):
+	case <-ctx.Done():
+		return remixerChatResponse{}, ctx.Err()
+	}
+	prompt := req.Messages[len(req.Messages)-1].Content
+	if p.fail[prompt] {
+		return remixerChatResponse{}, errors.New("bad prompt")
+	}
+	return remixerChatResponse{Content: prompt}, nil
+}
+
+func newEchoClient(provider remixerProvider, concurrency int) *RemixerClient {
+	return &RemixerClient{
+		remixer: &remixerEngine{
+			selector: &weightedSelector{
+				entries:     []selectorEntry{{name: "m", providers: []remixerProvider{provider}, upper: 1}},
+				totalWeight: 1,
+			},
+			retrier: newRetrier(remixerRetryConfig{MaxAttempts: 1}),
+		},
+		batchConcurrency: concurrency,
+	}
+}
+
+func batchRequests(n int) []Request {
+	requests := make([]Request, n)
+	for i := range requests {
+		requests[i] = Request{SystemPrompt: "sys", UserPrompt: fmt.Sprintf("seed %d", i)}
+	}
+	return requests
+}
+
+func TestGetCompletions_OrderAndConcurrency(t *testing.T) {
+	var _ BatchCompleter = &RemixerClient{}
+
+	provider := &echoProvider{fail: map[string]bool{"seed 3": true}}
+	client := newEchoClient(provider, 3)
+
+	responses, err := GetCompletions(context.Background(), client, batchRequests(10), 0)
+	require.NoError(t, err)
+	require.Len(t, responses, 10)
+	for i, resp := range responses {
+		if i == 3 {
+			assert.Error(t, resp.Err, "a failed request is reported on its own response")
+			continue
+		}
+		require.NoError(t, resp.Err)
+		assert.Equal(t, fmt.Sprintf("seed %d", i), resp.Text)
+	}
+	assert.LessOrEqual(t, provider.maxInFlight.Load(), int32(3))
+	assert.Greater(t, provider.maxInFlight.Load(), int32(1), "requests should overlap")
+}
+
+func TestGetCompletions_Canceled(t *testing.T) {
+	client := newEchoClient(&echoProvider{}, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	responses, err := GetCompletions(ctx, client, batchRequests(3), 0)
+	assert.ErrorIs(t, err, context.Canceled)
+	require.Len(t, responses, 3)
+	for _, resp := range responses {
+		assert.ErrorIs(t, resp.Err, context.Canceled)
+	}
+}
+
+// lockedLLM is a plain LLM safe for concurrent use.
+type lockedLLM struct {
+	plainLLM
+	mu sync.Mutex
+}
+
+func (m *lockedLLM) GetCompletionWithSystem(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.plainLLM.GetCompletionWithSystem(ctx, systemPrompt, userPrompt)
+}
+
+func TestGetCompletions_FallsBackToCompleteSeed(t *testing.T) {
+	client := &lockedLLM{}
+	responses, err := GetCompletions(context.Background(), client, batchRequests(5), 2)
+	require.NoError(t, err)
+	for _, resp := range responses {
+		require.NoError(t, resp.Err)
+		assert.Equal(t, "plain", resp.Text)
+	}
+	assert.Equal(t, 5, client.calls)
+}
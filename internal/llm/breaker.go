@@ -0,0 +1,96 @@
+package llm
+
+import (
+	"sync"
+	"time"
+
+	"github.com/zjy-dev/de-fuzz/internal/logger"
+)
+
+// remixerBreakerConfig is the circuit_breaker: block of remixer.yaml. After
+// FailureThreshold consecutive failed calls a provider is skipped for
+// Cooldown, so a model's remaining providers serve its calls meanwhile.
+type remixerBreakerConfig struct {
+	FailureThreshold int           `yaml:"failure_threshold,omitempty"`
+	Cooldown         time.Duration `yaml:"cooldown,omitempty"`
+}
+
+const (
+	defaultBreakerFailureThreshold = 3
+	defaultBreakerCooldown         = 5 * time.Minute
+)
+
+// circuitBreaker tracks consecutive failures per provider. A nil breaker
+// lets every provider through.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu     sync.Mutex
+	states map[string]*breakerState
+}
+
+type breakerState struct {
+	failures  int       // Consecutive failed calls
+	openUntil time.Time // Skipped until then
+}
+
+func newCircuitBreaker(cfg remixerBreakerConfig) *circuitBreaker {
+	b := &circuitBreaker{
+		threshold: cfg.FailureThreshold,
+		cooldown:  cfg.Cooldown,
+		now:       time.Now,
+		states:    make(map[string]*breakerState),
+	}
+	if b.threshold == 0 {
+		b.threshold = defaultBreakerFailureThreshold
+	}
+	if b.cooldown == 0 {
+		b.cooldown = defaultBreakerCooldown
+	}
+	return b
+}
+
+// allow reports whether provider may be called: its circuit is closed, or
+// its cooldown has passed and it gets another try.
+func (b *circuitBreaker) allow(provider string) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state := b.states[provider]
+	return state == nil || !b.now().Before(state.openUntil)
+}
+
+// success closes provider's circuit.
+func (b *circuitBreaker) success(provider string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.states, provider)
+}
+
+// failure counts a failed call. Reaching the threshold opens the circuit;
+// since the count is only reset by a success, the first failure after a
+// cooldown opens it again.
+func (b *circuitBreaker) failure(provider string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state := b.states[provider]
+	if state == nil {
+		state = &breakerState{}
+		b.states[provider] = state
+	}
+	state.failures++
+	if state.failures >= b.threshold {
+		state.openUntil = b.now().Add(b.cooldown)
+		logger.Warn("[LLM] %s failed %d times in a row, skipping it for %s", provider, state.failures, b.cooldown)
+	}
+}
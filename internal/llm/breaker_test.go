@@ -0,0 +1,146 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// countingProvider fails while fail is set and counts its calls.
+type countingProvider struct {
+	reply string
+	fail  bool
+	calls int
+}
+
+func (p *countingProvider) Chat(ctx context.Context, req remixerChatRequest) (remixerChatResponse, error) {
+	p.calls++
+	if p.fail {
+		return remixerChatResponse{}, &StatusError{Provider: "test", StatusCode: http.StatusServiceUnavailable, Message: "outage"}
+	}
+	return remixerChatResponse{Content: p.reply}, nil
+}
+
+func TestRemixerEngineFallbackAndCircuitBreaker(t *testing.T) {
+	primary := &countingProvider{reply: "primary", fail: true}
+	secondary := &countingProvider{reply: "secondary"}
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	breaker := newCircuitBreaker(remixerBreakerConfig{FailureThreshold: 2, Cooldown: time.Minute})
+	breaker.now = clock.Now
+	engine := &remixerEngine{
+		selector: &weightedSelector{
+			entries: []selectorEntry{{
+				name:      "chain",
+				providers: []remixerProvider{primary, secondary},
+				labels:    []string{"openai/deepseek-chat", "openai/gpt-4o-mini"},
+				upper:     1,
+			}},
+			totalWeight: 1,
+		},
+		retrier: newRetrier(remixerRetryConfig{MaxAttempts: 1}),
+		breaker: breaker,
+	}
+	chat := func() string {
+		t.Helper()
+		resp, err := engine.Chat(context.Background(), remixerChatRequest{
+			Messages: []remixerMessage{{Role: "user", Content: "Hello"}},
+		})
+		if err != nil {
+			t.Fatalf("chat error: %v", err)
+		}
+		return resp.Content
+	}
+
+	// The primary fails and the secondary answers until the breaker opens.
+	for i := 0; i < 2; i++ {
+		if got := chat(); got != "secondary" {
+			t.Fatalf("call %d answered by %q, want the secondary", i+1, got)
+		}
+	}
+	if primary.calls != 2 {
+		t.Fatalf("primary called %d times, want 2", primary.calls)
+	}
+
+	// Open: the primary is skipped entirely.
+	chat()
+	if primary.calls != 2 {
+		t.Errorf("primary called while its circuit is open (%d calls)", primary.calls)
+	}
+
+	// After the cooldown the primary gets another try and, once it
+	// recovers, serves calls again.
+	clock.now = clock.now.Add(time.Minute)
+	primary.fail = false
+	if got := chat(); got != "primary" {
+		t.Errorf("after cooldown answered by %q, want the primary", got)
+	}
+	if got := chat(); got != "primary" {
+		t.Errorf("closed circuit answered by %q, want the primary", got)
+	}
+
+	served := engine.ProviderCalls()
+	if served["openai/gpt-4o-mini"] != 3 || served["openai/deepseek-chat"] != 2 {
+		t.Errorf("served = %v, want 3 by the secondary and 2 by the primary", served)
+	}
+	if usage := engine.Usage(); usage.Calls != 5 || usage.Failures != 0 {
+		t.Errorf("usage = %+v, want 5 successful calls", usage)
+	}
+}
+
+func TestCircuitBreakerReopensOnFailureAfterCooldown(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	b := newCircuitBreaker(remixerBreakerConfig{FailureThreshold: 3, Cooldown: time.Minute})
+	b.now = clock.Now
+
+	for i := 0; i < 3; i++ {
+		b.failure("p")
+	}
+	if b.allow("p") {
+		t.Fatal("circuit should be open after 3 failures")
+	}
+	clock.now = clock.now.Add(time.Minute)
+	if !b.allow("p") {
+		t.Fatal("circuit should let a call through after the cooldown")
+	}
+	b.failure("p")
+	if b.allow("p") {
+		t.Error("one failure after the cooldown should reopen the circuit")
+	}
+
+	b.success("p")
+	if !b.allow("p") {
+		t.Error("a success should close the circuit")
+	}
+	if (*circuitBreaker)(nil).allow("p") != true {
+		t.Error("a nil breaker should allow every provider")
+	}
+}
+
+func TestRemixerEngineAllProvidersFail(t *testing.T) {
+	primary := &countingProvider{fail: true}
+	secondary := &countingProvider{fail: true}
+	engine := &remixerEngine{
+		selector: &weightedSelector{
+			entries:     []selectorEntry{{name: "chain", providers: []remixerProvider{primary, secondary}, upper: 1}},
+			totalWeight: 1,
+		},
+		retrier: newRetrier(remixerRetryConfig{MaxAttempts: 1}),
+		breaker: newCircuitBreaker(remixerBreakerConfig{FailureThreshold: 1, Cooldown: time.Hour}),
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := engine.Chat(context.Background(), remixerChatRequest{
+			Messages: []remixerMessage{{Role: "user", Content: "Hello"}},
+		}); err == nil {
+			t.Fatal("expected an error when every provider fails")
+		}
+	}
+	// With every circuit open the chain is still tried rather than failing
+	// without a request.
+	if primary.calls != 2 || secondary.calls != 2 {
+		t.Errorf("calls = %d/%d, want both providers tried on each call", primary.calls, secondary.calls)
+	}
+	if usage := engine.Usage(); usage.Calls != 2 || usage.Failures != 2 {
+		t.Errorf("usage = %+v, want 2 failed calls", usage)
+	}
+}
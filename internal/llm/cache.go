@@ -0,0 +1,132 @@
+package llm
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// ResponseCache remembers completions by a hash of (system prompt, user
+// prompt) so the engine can tell when it is about to pay for a byte-identical
+// request. Entries live in memory and, when a path is given, are appended to
+// a JSONL file that is reloaded on the next run.
+type ResponseCache struct {
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	file    *os.File
+	hits    int
+	misses  int
+}
+
+type cacheEntry struct {
+	Key        string `json:"key"`
+	Completion string `json:"completion"`
+	hits       int
+}
+
+// NewResponseCache creates a cache. An empty path keeps it in memory only.
+func NewResponseCache(path string) (*ResponseCache, error) {
+	c := &ResponseCache{entries: make(map[string]*cacheEntry)}
+	if path == "" {
+		return c, nil
+	}
+
+	if existing, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(existing)
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			var entry cacheEntry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.Key == "" {
+				continue // skip a torn last line from an interrupted run
+			}
+			c.entries[entry.Key] = &entry
+		}
+		existing.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read response cache %s: %w", path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to open response cache %s: %w", path, err)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open response cache %s: %w", path, err)
+	}
+	c.file = f
+	return c, nil
+}
+
+// CacheKey hashes a request into its cache key.
+func CacheKey(systemPrompt, userPrompt string) string {
+	h := sha256.New()
+	h.Write([]byte(systemPrompt))
+	h.Write([]byte{0})
+	h.Write([]byte(userPrompt))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Get returns the cached completion for a request and how many times it has
+// been hit before, counting this lookup as a hit or miss.
+func (c *ResponseCache) Get(systemPrompt, userPrompt string) (completion string, previousHits int, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[CacheKey(systemPrompt, userPrompt)]
+	if !ok {
+		c.misses++
+		return "", 0, false
+	}
+	c.hits++
+	previousHits = entry.hits
+	entry.hits++
+	return entry.Completion, previousHits, true
+}
+
+// Put stores a completion, appending it to the cache file if there is one.
+func (c *ResponseCache) Put(systemPrompt, userPrompt, completion string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := CacheKey(systemPrompt, userPrompt)
+	if _, exists := c.entries[key]; exists {
+		return nil
+	}
+	entry := &cacheEntry{Key: key, Completion: completion}
+	c.entries[key] = entry
+
+	if c.file == nil {
+		return nil
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode response cache entry: %w", err)
+	}
+	if _, err := c.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write response cache entry: %w", err)
+	}
+	return nil
+}
+
+// Stats returns the hit and miss counts since the cache was created.
+func (c *ResponseCache) Stats() (hits, misses int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// Close closes the cache file, if any.
+func (c *ResponseCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.file == nil {
+		return nil
+	}
+	err := c.file.Close()
+	c.file = nil
+	return err
+}
@@ -0,0 +1,60 @@
+package llm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseCache_InMemory(t *testing.T) {
+	cache, err := NewResponseCache("")
+	require.NoError(t, err)
+
+	_, _, ok := cache.Get("sys", "user")
+	assert.False(t, ok)
+
+	require.NoError(t, cache.Put("sys", "user", "reply"))
+	completion, previous, ok := cache.Get("sys", "user")
+	assert.True(t, ok)
+	assert.Equal(t, "reply", completion)
+	assert.Equal(t, 0, previous)
+
+	_, previous, _ = cache.Get("sys", "user")
+	assert.Equal(t, 1, previous)
+
+	// The key covers both prompts.
+	_, _, ok = cache.Get("other sys", "user")
+	assert.False(t, ok)
+
+	hits, misses := cache.Stats()
+	assert.Equal(t, 2, hits)
+	assert.Equal(t, 2, misses)
+}
+
+func TestResponseCache_JSONLPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "llm_cache.jsonl")
+
+	cache, err := NewResponseCache(path)
+	require.NoError(t, err)
+	require.NoError(t, cache.Put("sys", "user", "multi\nline \"reply\""))
+	require.NoError(t, cache.Put("sys", "user", "duplicate is ignored"))
+	require.NoError(t, cache.Close())
+
+	// A torn line from an interrupted run is skipped.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"key": "abc", "compl`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	reloaded, err := NewResponseCache(path)
+	require.NoError(t, err)
+	defer reloaded.Close()
+
+	completion, _, ok := reloaded.Get("sys", "user")
+	assert.True(t, ok)
+	assert.Equal(t, "multi\nline \"reply\"", completion)
+}
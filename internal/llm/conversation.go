@@ -0,0 +1,103 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+)
+
+// Message roles used in a conversation.
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Message is one turn of a chat conversation.
+type Message struct {
+	Role    string
+	Content string
+}
+
+// ChatLLM is implemented by clients that accept a full message history,
+// which lets callers keep a multi-turn session with the model.
+type ChatLLM interface {
+	Chat(ctx context.Context, messages []Message) (string, error)
+}
+
+// JSONChatLLM is the JSON response mode counterpart of ChatLLM.
+type JSONChatLLM interface {
+	ChatJSON(ctx context.Context, messages []Message) (string, error)
+}
+
+// Conversation is a multi-turn chat session. Each Send replays the history
+// so follow-up prompts only need to carry what changed since the last turn.
+type Conversation struct {
+	client     ChatLLM
+	structured bool
+	messages   []Message
+}
+
+// NewConversation starts a session on client, or returns nil when the client
+// cannot chat so callers can fall back to stateless prompts. With structured
+// set, replies use the provider's JSON response mode where available.
+func NewConversation(client LLM, structured bool) *Conversation {
+	chat, ok := client.(ChatLLM)
+	if !ok {
+		return nil
+	}
+	return &Conversation{client: chat, structured: structured}
+}
+
+// SetSystemPrompt sets or replaces the system message.
+func (c *Conversation) SetSystemPrompt(systemPrompt string) {
+	if len(c.messages) > 0 && c.messages[0].Role == RoleSystem {
+		c.messages[0].Content = systemPrompt
+		return
+	}
+	c.messages = append([]Message{{Role: RoleSystem, Content: systemPrompt}}, c.messages...)
+}
+
+// Send appends userPrompt, requests the next reply and records it. On error
+// the user message is dropped again so the history stays well-formed.
+func (c *Conversation) Send(ctx context.Context, userPrompt string) (string, error) {
+	c.messages = append(c.messages, Message{Role: RoleUser, Content: userPrompt})
+
+	var reply string
+	var err error
+	if jc, ok := c.client.(JSONChatLLM); ok && c.structured {
+		reply, err = jc.ChatJSON(ctx, c.messages)
+	} else {
+		reply, err = c.client.Chat(ctx, c.messages)
+	}
+	if err != nil {
+		c.messages = c.messages[:len(c.messages)-1]
+		return "", fmt.Errorf("conversation turn %d: %w", c.Turns()+1, err)
+	}
+
+	c.messages = append(c.messages, Message{Role: RoleAssistant, Content: reply})
+	return reply, nil
+}
+
+// Record appends an exchange that was answered without calling the model,
+// e.g. a reply reused from a ResponseCache.
+func (c *Conversation) Record(userPrompt, reply string) {
+	c.messages = append(c.messages,
+		Message{Role: RoleUser, Content: userPrompt},
+		Message{Role: RoleAssistant, Content: reply})
+}
+
+// Messages returns the history so far, including the system message.
+func (c *Conversation) Messages() []Message {
+	return c.messages
+}
+
+// Turns returns the number of completed user/assistant exchanges.
+func (c *Conversation) Turns() int {
+	n := 0
+	for _, m := range c.messages {
+		if m.Role == RoleAssistant {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,119 @@
+package llm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/zjy-dev/de-fuzz/internal/fsutil"
+)
+
+// diskCache stores completions as one JSON file per request under dir,
+// keyed by a hash of everything that shapes the reply. Unlike
+// ResponseCache, which only flags repeated prompts for the engine, a hit
+// here skips the provider call entirely: re-running a campaign replays the
+// earlier completions.
+type diskCache struct {
+	dir string
+	ttl time.Duration // 0 = entries never expire
+	now func() time.Time
+}
+
+type diskCacheEntry struct {
+	Model   string `json:"model"`
+	Content string `json:"content"`
+}
+
+func newDiskCache(dir string, ttl time.Duration) (*diskCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create LLM cache dir %s: %w", dir, err)
+	}
+	return &diskCache{dir: dir, ttl: ttl, now: time.Now}, nil
+}
+
+// diskCacheKey hashes the provider type, model, effective temperature,
+// response format and every message of a request.
+func diskCacheKey(provider remixerProviderConfig, req remixerChatRequest) string {
+	temperature := "default"
+	if provider.Temperature != nil {
+		temperature = strconv.FormatFloat(*provider.Temperature, 'g', -1, 64)
+	} else if req.Temperature != nil {
+		temperature = strconv.FormatFloat(*req.Temperature, 'g', -1, 64)
+	}
+
+	format := req.ResponseFormat.normalized()
+	parts := []string{provider.Type, provider.Model, temperature, strconv.FormatBool(format.IsJSON())}
+	// Appended only when set so keys written before top_p existed stay valid.
+	if format.Type == FormatJSONSchema {
+		parts = append(parts, "schema="+format.Name+":"+string(format.Schema))
+	}
+	if req.TopP != nil {
+		parts = append(parts, "top_p="+strconv.FormatFloat(*req.TopP, 'g', -1, 64))
+	}
+	if req.MaxTokens != nil {
+		parts = append(parts, "max_tokens="+strconv.Itoa(*req.MaxTokens))
+	}
+
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	for _, m := range req.Messages {
+		h.Write([]byte(m.Role))
+		h.Write([]byte{0})
+		h.Write([]byte(m.Content))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *diskCache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".json")
+}
+
+// get returns the cached reply for key, treating entries older than the TTL
+// and unreadable files as misses.
+func (c *diskCache) get(key string) (remixerChatResponse, bool) {
+	path := c.path(key)
+	info, err := os.Stat(path)
+	if err != nil {
+		return remixerChatResponse{}, false
+	}
+	if c.ttl > 0 && c.now().Sub(info.ModTime()) > c.ttl {
+		return remixerChatResponse{}, false
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return remixerChatResponse{}, false
+	}
+	var entry diskCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return remixerChatResponse{}, false
+	}
+	return remixerChatResponse{Content: entry.Content, Model: entry.Model}, true
+}
+
+// put writes the reply for key. The file is written with
+// fsutil.WriteFileAtomic, so concurrent writers never leave a torn entry.
+func (c *diskCache) put(key string, resp remixerChatResponse) error {
+	data, err := json.Marshal(diskCacheEntry{Model: resp.Model, Content: resp.Content})
+	if err != nil {
+		return fmt.Errorf("failed to encode LLM cache entry: %w", err)
+	}
+
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create LLM cache dir: %w", err)
+	}
+	if err := fsutil.WriteFileAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to write LLM cache entry: %w", err)
+	}
+	return nil
+}
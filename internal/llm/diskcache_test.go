@@ -0,0 +1,142 @@
+package llm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newCachedTestEngine returns a remixer engine with one openai provider
+// pointed at srv and a disk cache in a temp dir.
+func newCachedTestEngine(t *testing.T, srv *httptest.Server, ttl time.Duration) *remixerEngine {
+	t.Helper()
+
+	cfg := remixerProviderConfig{Type: "openai", Endpoint: srv.URL, Model: "test-model", APIKey: "test-key"}
+	provider, err := newOpenAIProvider(cfg)
+	if err != nil {
+		t.Fatalf("creating provider: %v", err)
+	}
+	cache, err := newDiskCache(t.TempDir(), ttl)
+	if err != nil {
+		t.Fatalf("creating cache: %v", err)
+	}
+	return &remixerEngine{
+		selector: &weightedSelector{
+			entries:     []selectorEntry{{name: "test-model", providers: []remixerProvider{provider}, upper: 1, config: cfg}},
+			totalWeight: 1,
+		},
+		retrier: newRetrier(remixerRetryConfig{MaxAttempts: 1}),
+		cache:   cache,
+	}
+}
+
+func countingServer(t *testing.T) (*httptest.Server, *int) {
+	t.Helper()
+
+	var mu sync.Mutex
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"model":"test-model","choices":[{"index":0,"message":{"role":"assistant","content":"int main(void) { return 0; }"}}]}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestDiskCacheSecondCallSkipsHTTP(t *testing.T) {
+	srv, requests := countingServer(t)
+	engine := newCachedTestEngine(t, srv, 0)
+
+	temperature := 0.7
+	req := remixerChatRequest{
+		Messages:    []remixerMessage{{Role: "system", Content: "You write C."}, {Role: "user", Content: "Write main."}},
+		Temperature: &temperature,
+	}
+	first, err := engine.Chat(context.Background(), req)
+	if err != nil {
+		t.Fatalf("first chat: %v", err)
+	}
+	second, err := engine.Chat(context.Background(), req)
+	if err != nil {
+		t.Fatalf("second chat: %v", err)
+	}
+
+	if *requests != 1 {
+		t.Errorf("expected 1 HTTP request, got %d", *requests)
+	}
+	if second.Content != first.Content || second.Model != "test-model" {
+		t.Errorf("cached reply %+v differs from %+v", second, first)
+	}
+	if usage := engine.Usage(); usage.Calls != 1 || usage.CacheHits != 1 {
+		t.Errorf("usage = %+v, want 1 call and 1 cache hit", usage)
+	}
+
+	// A different temperature is a different request.
+	other := 0.2
+	req.Temperature = &other
+	if _, err := engine.Chat(context.Background(), req); err != nil {
+		t.Fatalf("third chat: %v", err)
+	}
+	if *requests != 2 {
+		t.Errorf("changing the temperature should miss the cache, got %d requests", *requests)
+	}
+}
+
+func TestDiskCacheTTL(t *testing.T) {
+	srv, requests := countingServer(t)
+	engine := newCachedTestEngine(t, srv, time.Hour)
+
+	req := remixerChatRequest{Messages: []remixerMessage{{Role: "user", Content: "Write main."}}}
+	if _, err := engine.Chat(context.Background(), req); err != nil {
+		t.Fatalf("first chat: %v", err)
+	}
+
+	engine.cache.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if _, err := engine.Chat(context.Background(), req); err != nil {
+		t.Fatalf("second chat: %v", err)
+	}
+	if *requests != 2 {
+		t.Errorf("expired entry should be fetched again, got %d requests", *requests)
+	}
+}
+
+func TestDiskCachePutIsAtomic(t *testing.T) {
+	cache, err := newDiskCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("creating cache: %v", err)
+	}
+	key := diskCacheKey(remixerProviderConfig{Type: "openai", Model: "m"}, remixerChatRequest{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := cache.put(key, remixerChatResponse{Model: "m", Content: "same reply"}); err != nil {
+				t.Errorf("put: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	resp, ok := cache.get(key)
+	if !ok || resp.Content != "same reply" {
+		t.Errorf("get() = %+v, %v", resp, ok)
+	}
+	leftovers, _ := filepath.Glob(filepath.Join(filepath.Dir(cache.path(key)), ".tmp-*"))
+	if len(leftovers) != 0 {
+		t.Errorf("temporary files left behind: %v", leftovers)
+	}
+	if _, err := os.Stat(cache.path(key)); err != nil {
+		t.Errorf("entry file missing: %v", err)
+	}
+}
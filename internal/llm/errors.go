@@ -0,0 +1,109 @@
+package llm
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StatusError is a non-2xx HTTP reply from a provider.
+type StatusError struct {
+	Provider   string
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s: status %d: %s", e.Provider, e.StatusCode, e.Message)
+}
+
+// streamInterruptedError is a stream that failed after part of the reply
+// was already passed on. It is never retried: the consumer has seen the
+// partial reply and a second attempt would repeat it.
+type streamInterruptedError struct {
+	Err error
+}
+
+func (e *streamInterruptedError) Error() string {
+	return "stream interrupted: " + e.Err.Error()
+}
+
+func (e *streamInterruptedError) Unwrap() error {
+	return e.Err
+}
+
+// RateLimitError is returned when a provider answers 429 Too Many Requests.
+// RetryAfter is the wait the server asked for (0 when it did not say), so
+// callers that retry can honour it.
+type RateLimitError struct {
+	Provider   string
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%s: rate limited (retry after %s): %v", e.Provider, e.RetryAfter, e.Err)
+	}
+	return fmt.Sprintf("%s: rate limited: %v", e.Provider, e.Err)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+// newRateLimitError builds a RateLimitError from the 429 response headers.
+func newRateLimitError(provider string, header http.Header, err error) *RateLimitError {
+	return &RateLimitError{
+		Provider:   provider,
+		RetryAfter: parseRetryAfter(header.Get("Retry-After"), time.Now()),
+		Err:        err,
+	}
+}
+
+// parseRetryAfter reads a Retry-After value, either delay-seconds or an
+// HTTP date. It returns 0 for a missing, malformed or past value.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now).Round(time.Second)
+	}
+	return 0
+}
+
+// ContentFilterError is a prompt or reply blocked by the provider's content
+// filter: Azure's content_filter error, or a reply cut off with
+// finish_reason content_filter. Categories lists what was flagged, e.g.
+// "violence (medium)", when the provider says. It is never retried: the
+// same prompt would be blocked again.
+type ContentFilterError struct {
+	Provider   string
+	Categories []string
+	Err        error // The API error, nil for a filtered reply
+}
+
+func (e *ContentFilterError) Error() string {
+	msg := e.Provider + ": blocked by content filter"
+	if len(e.Categories) > 0 {
+		msg += " (" + strings.Join(e.Categories, ", ") + ")"
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *ContentFilterError) Unwrap() error {
+	return e.Err
+}
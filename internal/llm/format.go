@@ -0,0 +1,52 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Response format types.
+const (
+	FormatText       = "text"
+	FormatJSONObject = "json_object"
+	FormatJSONSchema = "json_schema"
+)
+
+// ErrUnsupportedFormat is returned, wrapped, when a provider cannot enforce
+// the requested response format. Callers can then fall back to a prompt
+// that does not depend on it.
+var ErrUnsupportedFormat = errors.New("response format not supported")
+
+// ResponseFormat constrains the shape of a reply. The zero value is plain
+// text.
+type ResponseFormat struct {
+	Type   string          // FormatText (or ""), FormatJSONObject or FormatJSONSchema
+	Name   string          // Schema name, sent with FormatJSONSchema
+	Schema json.RawMessage // JSON Schema of the reply; without one FormatJSONSchema is FormatJSONObject
+}
+
+// jsonObjectFormat asks for any single JSON object.
+var jsonObjectFormat = ResponseFormat{Type: FormatJSONObject}
+
+// IsJSON reports whether f asks for a JSON reply.
+func (f ResponseFormat) IsJSON() bool {
+	return f.Type == FormatJSONObject || f.Type == FormatJSONSchema
+}
+
+// normalized maps "" to FormatText and a schema-less FormatJSONSchema to
+// FormatJSONObject.
+func (f ResponseFormat) normalized() ResponseFormat {
+	switch {
+	case f.Type == "":
+		f.Type = FormatText
+	case f.Type == FormatJSONSchema && len(f.Schema) == 0:
+		f = ResponseFormat{Type: FormatJSONObject}
+	}
+	return f
+}
+
+// unsupportedFormat is the error of a provider that cannot enforce f.
+func unsupportedFormat(provider string, f ResponseFormat) error {
+	return fmt.Errorf("%s: %s: %w", provider, f.Type, ErrUnsupportedFormat)
+}
@@ -0,0 +1,91 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// schemalessProvider cannot enforce a JSON schema.
+type schemalessProvider struct {
+	calls int
+}
+
+func (p *schemalessProvider) Chat(ctx context.Context, req remixerChatRequest) (remixerChatResponse, error) {
+	p.calls++
+	if format := req.ResponseFormat.normalized(); format.Type == FormatJSONSchema {
+		return remixerChatResponse{}, unsupportedFormat("schemaless", format)
+	}
+	return remixerChatResponse{Content: "schemaless"}, nil
+}
+
+func TestRemixerEngineUnsupportedFormatFallsBack(t *testing.T) {
+	primary := &schemalessProvider{}
+	secondary := &countingProvider{reply: "secondary"}
+	engine := &remixerEngine{
+		selector: &weightedSelector{
+			entries: []selectorEntry{{
+				name:      "m",
+				providers: []remixerProvider{primary, secondary},
+				labels:    []string{"a", "b"},
+				upper:     1,
+			}},
+			totalWeight: 1,
+		},
+		retrier: newRetrier(remixerRetryConfig{MaxAttempts: 3}),
+		breaker: newCircuitBreaker(remixerBreakerConfig{FailureThreshold: 1, Cooldown: time.Minute}),
+	}
+	schema := ResponseFormat{Type: FormatJSONSchema, Name: "seed", Schema: json.RawMessage(`{"type":"object"}`)}
+
+	resp, err := engine.Chat(context.Background(), remixerChatRequest{ResponseFormat: schema})
+	require.NoError(t, err)
+	assert.Equal(t, "secondary", resp.Content, "a provider without the format hands over to the next one")
+	assert.Equal(t, 1, primary.calls, "an unsupported format is not retried")
+
+	// The primary is still healthy for requests it can serve.
+	resp, err = engine.Chat(context.Background(), remixerChatRequest{ResponseFormat: jsonObjectFormat})
+	require.NoError(t, err)
+	assert.Equal(t, "schemaless", resp.Content)
+
+	engine.selector.entries[0].providers = []remixerProvider{primary}
+	_, err = engine.Chat(context.Background(), remixerChatRequest{ResponseFormat: schema})
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
+
+// formatLLM records the formats it is asked for.
+type formatLLM struct {
+	jsonLLM
+	formats []ResponseFormat
+}
+
+func (f *formatLLM) GetFormattedCompletionWithSystem(ctx context.Context, systemPrompt, userPrompt string, format ResponseFormat) (string, error) {
+	f.formats = append(f.formats, format)
+	return `{"source": "x"}`, nil
+}
+
+func TestCompleteSeedFormats(t *testing.T) {
+	var _ FormatCompleter = &RemixerClient{}
+	schema := ResponseFormat{Type: FormatJSONSchema, Name: "seed", Schema: json.RawMessage(`{"type":"object"}`)}
+
+	client := &formatLLM{}
+	_, err := CompleteSeed(context.Background(), client, schema, "sys", "user")
+	require.NoError(t, err)
+	require.Len(t, client.formats, 1)
+	assert.Equal(t, "seed", client.formats[0].Name)
+
+	_, err = CompleteSeed(context.Background(), client, ResponseFormat{}, "sys", "user")
+	require.NoError(t, err)
+	assert.Len(t, client.formats, 1, "plain text does not go through the format")
+	assert.Equal(t, 1, client.calls)
+
+	// Without FormatCompleter a schema falls back to the JSON object mode.
+	jc := &jsonLLM{}
+	out, err := CompleteSeed(context.Background(), jc, schema, "sys", "user")
+	require.NoError(t, err)
+	assert.Equal(t, "{}", out)
+	assert.Equal(t, 1, jc.jsonCalls)
+}
@@ -1,33 +1,179 @@
 package llm
 
 import (
+	"context"
+	"time"
+
 	"github.com/zjy-dev/de-fuzz/internal/seed"
 )
 
 // LLM defines the interface for interacting with a Large Language Model.
+// Every call takes a context; canceling it abandons the request.
 type LLM interface {
 	// GetCompletion sends a raw prompt to the LLM and gets a direct response.
-	GetCompletion(prompt string) (string, error)
+	GetCompletion(ctx context.Context, prompt string) (string, error)
 
 	// GetCompletionWithSystem sends a prompt with system context to the LLM.
-	GetCompletionWithSystem(systemPrompt, userPrompt string) (string, error)
+	GetCompletionWithSystem(ctx context.Context, systemPrompt, userPrompt string) (string, error)
 
 	// Understand processes the initial prompt and returns the LLM's summary.
-	Understand(prompt string) (string, error)
+	Understand(ctx context.Context, prompt string) (string, error)
 
 	// Generate creates a new seed based on the provided context (understanding as system prompt).
-	Generate(understanding, prompt string) (*seed.Seed, error)
+	Generate(ctx context.Context, understanding, prompt string) (*seed.Seed, error)
 
 	// Analyze interprets the feedback from a seed execution (understanding as system prompt).
-	Analyze(understanding, prompt string, s *seed.Seed, feedback string) (string, error)
+	Analyze(ctx context.Context, understanding, prompt string, s *seed.Seed, feedback string) (string, error)
 
 	// Mutate modifies an existing seed to create a new variant (understanding as system prompt).
-	Mutate(understanding, prompt string, s *seed.Seed) (*seed.Seed, error)
+	Mutate(ctx context.Context, understanding, prompt string, s *seed.Seed) (*seed.Seed, error)
 }
 
 // New creates a new LLM client backed by the internal remixer.
 // configPath is the path to the remixer YAML config file.
 // temperature is the default sampling temperature for all requests.
-func New(configPath string, temperature float64) (LLM, error) {
-	return NewRemixerClient(configPath, temperature)
+func New(configPath string, temperature float64, opts ...Option) (LLM, error) {
+	return NewRemixerClient(configPath, temperature, opts...)
+}
+
+// Option configures a client created by New.
+type Option func(*RemixerClient) error
+
+// WithDiskCache answers requests identical to an earlier one (same provider,
+// model, temperature and messages) from files under dir without calling the
+// provider. Entries older than ttl are fetched again; 0 keeps them forever.
+func WithDiskCache(dir string, ttl time.Duration) Option {
+	return func(c *RemixerClient) error {
+		cache, err := newDiskCache(dir, ttl)
+		if err != nil {
+			return err
+		}
+		c.remixer.cache = cache
+		return nil
+	}
+}
+
+// WithRequestTimeout bounds each call, including its retries, to d. 0
+// leaves calls bounded only by the caller's context.
+func WithRequestTimeout(d time.Duration) Option {
+	return func(c *RemixerClient) error {
+		c.requestTimeout = d
+		return nil
+	}
+}
+
+// Sampling overrides the sampling parameters of one call type. Nil and zero
+// fields keep the client's default temperature and the providers' own
+// settings. A temperature pinned on a provider in remixer.yaml still wins,
+// since some models accept only their fixed value.
+type Sampling struct {
+	Temperature *float64
+	TopP        *float64
+	MaxTokens   int
+}
+
+// WithSampling applies s to requests whose context carries callType (see
+// WithCallInfo and the Call* constants).
+func WithSampling(callType string, s Sampling) Option {
+	return func(c *RemixerClient) error {
+		if c.sampling == nil {
+			c.sampling = make(map[string]Sampling)
+		}
+		c.sampling[callType] = s
+		return nil
+	}
+}
+
+// WithBatchConcurrency bounds how many requests of a GetCompletions batch
+// are in flight at once; 0 uses DefaultBatchConcurrency.
+func WithBatchConcurrency(n int) Option {
+	return func(c *RemixerClient) error {
+		c.batchConcurrency = n
+		return nil
+	}
+}
+
+// WithAuditLog appends a record of every request (what it was for, the
+// prompt, the completion, usage, latency and error) to the JSONL file at
+// path. Prompt messages are clipped unless fullPrompts is set. The file is
+// rotated when it would grow past maxBytes; 0 never rotates. Close the
+// client to flush the log.
+func WithAuditLog(path string, maxBytes int64, fullPrompts bool) Option {
+	return func(c *RemixerClient) error {
+		audit, err := newAuditLog(path, maxBytes, fullPrompts)
+		if err != nil {
+			return err
+		}
+		c.remixer.audit = audit
+		return nil
+	}
+}
+
+// WithCallObserver calls observe after every request, cache hits included,
+// e.g. to export metrics. It runs on the requesting goroutine, so it must
+// be cheap and safe for concurrent use.
+func WithCallObserver(observe func(CallStats)) Option {
+	return func(c *RemixerClient) error {
+		c.remixer.observe = observe
+		return nil
+	}
+}
+
+// ContextLimiter is implemented by clients that know their models' context
+// windows. PromptTokenLimit returns the largest prompt, in estimated
+// tokens, that every model accepts, or 0 when unknown.
+type ContextLimiter interface {
+	PromptTokenLimit() int
+}
+
+// JSONCompleter is implemented by clients that can ask the model for a reply
+// constrained to a single JSON object.
+type JSONCompleter interface {
+	GetJSONCompletionWithSystem(ctx context.Context, systemPrompt, userPrompt string) (string, error)
+}
+
+// FormatCompleter is implemented by clients that can ask for a reply in a
+// given ResponseFormat. GetFormattedCompletionWithSystem fails with
+// ErrUnsupportedFormat when the provider cannot enforce it.
+type FormatCompleter interface {
+	GetFormattedCompletionWithSystem(ctx context.Context, systemPrompt, userPrompt string, format ResponseFormat) (string, error)
+}
+
+// CompleteSeed requests a seed-generation completion in format. Clients
+// that implement FormatCompleter get the format as is; for the others a
+// JSON format uses the JSONCompleter mode when there is one, and otherwise
+// the request is sent as plain text and the prompt alone carries the output
+// contract. Only a FormatCompleter can fail with ErrUnsupportedFormat.
+func CompleteSeed(ctx context.Context, client LLM, format ResponseFormat, systemPrompt, userPrompt string) (string, error) {
+	if format.IsJSON() {
+		if fc, ok := client.(FormatCompleter); ok {
+			return fc.GetFormattedCompletionWithSystem(ctx, systemPrompt, userPrompt, format)
+		}
+		if jc, ok := client.(JSONCompleter); ok {
+			return jc.GetJSONCompletionWithSystem(ctx, systemPrompt, userPrompt)
+		}
+	}
+	return client.GetCompletionWithSystem(ctx, systemPrompt, userPrompt)
+}
+
+// StreamCompleter is implemented by clients that can stream a reply. onChunk
+// receives the reply piece by piece; when it returns false the request is
+// abandoned and the text received so far is returned.
+type StreamCompleter interface {
+	StreamCompletionWithSystem(ctx context.Context, systemPrompt, userPrompt string, format ResponseFormat, onChunk func(chunk string) bool) (string, error)
+}
+
+// StreamSeed is CompleteSeed with the reply passed to onChunk as it arrives,
+// so the caller can stop reading once it has what it needs. Clients that do
+// not implement StreamCompleter are asked with CompleteSeed and their whole
+// reply is passed to onChunk at once.
+func StreamSeed(ctx context.Context, client LLM, format ResponseFormat, systemPrompt, userPrompt string, onChunk func(chunk string) bool) (string, error) {
+	if sc, ok := client.(StreamCompleter); ok {
+		return sc.StreamCompletionWithSystem(ctx, systemPrompt, userPrompt, format, onChunk)
+	}
+	completion, err := CompleteSeed(ctx, client, format, systemPrompt, userPrompt)
+	if err == nil {
+		onChunk(completion)
+	}
+	return completion, err
 }
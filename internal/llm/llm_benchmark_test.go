@@ -1,6 +1,7 @@
 package llm
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -48,7 +49,7 @@ func BenchmarkRemixerClient_GetCompletion(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := client.GetCompletion("benchmark prompt")
+		_, err := client.GetCompletion(context.Background(), "benchmark prompt")
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -61,7 +62,7 @@ func BenchmarkRemixerClient_Generate(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := client.Generate("system understanding", "generate code")
+		_, err := client.Generate(context.Background(), "system understanding", "generate code")
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -82,7 +83,7 @@ func BenchmarkRemixerClient_Analyze(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := client.Analyze("system understanding", "analyze this", testSeed, "feedback")
+		_, err := client.Analyze(context.Background(), "system understanding", "analyze this", testSeed, "feedback")
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -103,7 +104,7 @@ func BenchmarkRemixerClient_Mutate(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := client.Mutate("system understanding", "mutate this", testSeed)
+		_, err := client.Mutate(context.Background(), "system understanding", "mutate this", testSeed)
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -3,6 +3,7 @@
 package llm
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -62,7 +63,7 @@ func TestRemixerRealAPIIntegration(t *testing.T) {
 	}
 
 	t.Run("GetCompletion_RealAPI", func(t *testing.T) {
-		response, err := client.GetCompletion("Write a simple 'Hello, World!' program in C. Keep it very short.")
+		response, err := client.GetCompletion(context.Background(), "Write a simple 'Hello, World!' program in C. Keep it very short.")
 		if err != nil {
 			t.Logf("API call failed (this might be expected): %v", err)
 			return
@@ -72,7 +73,7 @@ func TestRemixerRealAPIIntegration(t *testing.T) {
 	})
 
 	t.Run("Understand_RealAPI", func(t *testing.T) {
-		understanding, err := client.Understand("I want to fuzz a simple C program. Explain the approach in one sentence.")
+		understanding, err := client.Understand(context.Background(), "I want to fuzz a simple C program. Explain the approach in one sentence.")
 		if err != nil {
 			t.Logf("API call failed (this might be expected): %v", err)
 			return
@@ -82,7 +83,7 @@ func TestRemixerRealAPIIntegration(t *testing.T) {
 	})
 
 	t.Run("Generate_RealAPI", func(t *testing.T) {
-		newSeed, err := client.Generate("system understanding", "Generate a minimal C program with potential integer overflow. One line of code only.")
+		newSeed, err := client.Generate(context.Background(), "system understanding", "Generate a minimal C program with potential integer overflow. One line of code only.")
 		if err != nil {
 			t.Logf("API call failed (this might be expected): %v", err)
 			return
@@ -100,7 +101,7 @@ func TestRemixerRealAPIIntegration(t *testing.T) {
 				{RunningCommand: "./test", ExpectedResult: "success"},
 			},
 		}
-		analysis, err := client.Analyze("system understanding", "Briefly analyze this overflow", testSeed, "Program returned: -294967296")
+		analysis, err := client.Analyze(context.Background(), "system understanding", "Briefly analyze this overflow", testSeed, "Program returned: -294967296")
 		if err != nil {
 			t.Logf("API call failed (this might be expected): %v", err)
 			return
@@ -114,7 +115,7 @@ func TestRemixerRealAPIIntegration(t *testing.T) {
 			Meta:    seed.Metadata{ID: 2},
 			Content: "int main() { return 42; }",
 		}
-		mutatedSeed, err := client.Mutate("system understanding", "Change the return value only", originalSeed)
+		mutatedSeed, err := client.Mutate(context.Background(), "system understanding", "Change the return value only", originalSeed)
 		if err != nil {
 			t.Logf("API call failed (this might be expected): %v", err)
 			return
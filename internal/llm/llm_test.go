@@ -1,9 +1,15 @@
 package llm
 
 import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -44,14 +50,223 @@ func TestNewRemixerClient_DefaultTemperatureFallback(t *testing.T) {
 
 func TestRemixerClient_Analyze_NilSeed(t *testing.T) {
 	client := &RemixerClient{}
-	_, err := client.Analyze("sys", "prompt", nil, "feedback")
+	_, err := client.Analyze(context.Background(), "sys", "prompt", nil, "feedback")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "seed cannot be nil")
 }
 
 func TestRemixerClient_Mutate_NilSeed(t *testing.T) {
 	client := &RemixerClient{}
-	_, err := client.Mutate("sys", "prompt", nil)
+	_, err := client.Mutate(context.Background(), "sys", "prompt", nil)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "seed cannot be nil")
 }
+
+// hangingClient is a RemixerClient whose only provider never answers.
+func hangingClient(t *testing.T) *RemixerClient {
+	t.Helper()
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(release) }) // runs first, unblocking srv.Close
+
+	provider, err := newOpenAIProvider(remixerProviderConfig{Type: "openai", Endpoint: srv.URL, Model: "m", APIKey: "k"})
+	require.NoError(t, err)
+	return &RemixerClient{
+		remixer: &remixerEngine{
+			selector: &weightedSelector{
+				entries:     []selectorEntry{{name: "m", providers: []remixerProvider{provider}, upper: 1}},
+				totalWeight: 1,
+			},
+			retrier: newRetrier(remixerRetryConfig{}),
+		},
+		temperature: 0.1,
+	}
+}
+
+func TestRemixerClient_ContextCancel(t *testing.T) {
+	client := hangingClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := client.GetCompletionWithSystem(ctx, "sys", "user")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ctx.Err())
+	assert.Less(t, time.Since(start), 2*time.Second, "a canceled call must return promptly")
+}
+
+func TestRemixerClient_RequestTimeout(t *testing.T) {
+	client := hangingClient(t)
+	client.requestTimeout = 50 * time.Millisecond
+
+	start := time.Now()
+	_, err := client.GetCompletionWithSystem(context.Background(), "sys", "user")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 2*time.Second, "retries must not outlive the request timeout")
+}
+
+type plainLLM struct {
+	LLM
+	calls int
+}
+
+func (p *plainLLM) GetCompletionWithSystem(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	p.calls++
+	return "plain", nil
+}
+
+type jsonLLM struct {
+	plainLLM
+	jsonCalls int
+}
+
+func (j *jsonLLM) GetJSONCompletionWithSystem(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	j.jsonCalls++
+	return "{}", nil
+}
+
+func TestCompleteSeed(t *testing.T) {
+	var _ JSONCompleter = &RemixerClient{}
+
+	client := &jsonLLM{}
+	out, err := CompleteSeed(context.Background(), client, jsonObjectFormat, "sys", "user")
+	require.NoError(t, err)
+	assert.Equal(t, "{}", out)
+	assert.Equal(t, 1, client.jsonCalls)
+
+	out, err = CompleteSeed(context.Background(), client, ResponseFormat{}, "sys", "user")
+	require.NoError(t, err)
+	assert.Equal(t, "plain", out)
+	assert.Equal(t, 1, client.calls)
+
+	// Clients without a JSON mode fall back to a plain completion.
+	plain := &plainLLM{}
+	out, err = CompleteSeed(context.Background(), plain, jsonObjectFormat, "sys", "user")
+	require.NoError(t, err)
+	assert.Equal(t, "plain", out)
+	assert.Equal(t, 1, plain.calls)
+}
+
+func TestStreamSeedFallsBackToCompleteSeed(t *testing.T) {
+	var _ StreamCompleter = &RemixerClient{}
+
+	client := &jsonLLM{}
+	var chunks []string
+	out, err := StreamSeed(context.Background(), client, jsonObjectFormat, "sys", "user", func(chunk string) bool {
+		chunks = append(chunks, chunk)
+		return true
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "{}", out)
+	assert.Equal(t, []string{"{}"}, chunks, "the whole reply arrives as one chunk")
+	assert.Equal(t, 1, client.jsonCalls)
+}
+
+type chatLLM struct {
+	plainLLM
+	seen    [][]Message
+	replies []string
+	fail    bool
+}
+
+func (c *chatLLM) Chat(ctx context.Context, messages []Message) (string, error) {
+	c.seen = append(c.seen, append([]Message(nil), messages...))
+	if c.fail {
+		return "", assert.AnError
+	}
+	reply := c.replies[0]
+	c.replies = c.replies[1:]
+	return reply, nil
+}
+
+func TestConversation(t *testing.T) {
+	var _ ChatLLM = &RemixerClient{}
+	var _ JSONChatLLM = &RemixerClient{}
+
+	assert.Nil(t, NewConversation(&plainLLM{}, false), "clients without Chat fall back to stateless mode")
+
+	client := &chatLLM{replies: []string{"first", "second"}}
+	conv := NewConversation(client, false)
+	require.NotNil(t, conv)
+	conv.SetSystemPrompt("sys")
+
+	reply, err := conv.Send(context.Background(), "full prompt")
+	require.NoError(t, err)
+	assert.Equal(t, "first", reply)
+
+	reply, err = conv.Send(context.Background(), "attempt failed")
+	require.NoError(t, err)
+	assert.Equal(t, "second", reply)
+	assert.Equal(t, 2, conv.Turns())
+
+	// The second request replays the history and adds only the follow-up.
+	assert.Equal(t, []Message{
+		{Role: RoleSystem, Content: "sys"},
+		{Role: RoleUser, Content: "full prompt"},
+		{Role: RoleAssistant, Content: "first"},
+		{Role: RoleUser, Content: "attempt failed"},
+	}, client.seen[1])
+
+	client.fail = true
+	_, err = conv.Send(context.Background(), "again")
+	assert.Error(t, err)
+	assert.Len(t, conv.Messages(), 5, "failed turn is not kept in the history")
+}
+
+func TestRemixerClient_SamplingByCallType(t *testing.T) {
+	var bodies []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		bodies = append(bodies, body)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":"ok"}}]}`)
+	}))
+	defer srv.Close()
+
+	provider, err := newOpenAIProvider(remixerProviderConfig{Type: "openai", Endpoint: srv.URL, Model: "m", APIKey: "k", MaxTokensField: openAIMaxTokensFieldLegacy})
+	require.NoError(t, err)
+	client := &RemixerClient{
+		remixer: &remixerEngine{
+			selector: &weightedSelector{
+				entries:     []selectorEntry{{name: "m", providers: []remixerProvider{provider}, upper: 1}},
+				totalWeight: 1,
+			},
+			retrier: newRetrier(remixerRetryConfig{MaxAttempts: 1}),
+		},
+		temperature: 0.5,
+	}
+	low, high, topP := 0.2, 1.1, 0.9
+	for _, opt := range []Option{
+		WithSampling(CallGenerate, Sampling{Temperature: &low, TopP: &topP, MaxTokens: 300}),
+		WithSampling(CallMutate, Sampling{Temperature: &high}),
+	} {
+		require.NoError(t, opt(client))
+	}
+
+	for _, callType := range []string{CallGenerate, CallMutate, CallRepair} {
+		ctx := WithCallInfo(context.Background(), CallInfo{Type: callType})
+		_, err := client.GetCompletionWithSystem(ctx, "sys", "user")
+		require.NoError(t, err)
+	}
+	require.Len(t, bodies, 3)
+
+	assert.InDelta(t, 0.2, bodies[0]["temperature"], 1e-6)
+	assert.InDelta(t, 0.9, bodies[0]["top_p"], 1e-6)
+	assert.EqualValues(t, 300, bodies[0]["max_tokens"])
+
+	assert.InDelta(t, 1.1, bodies[1]["temperature"], 1e-6)
+	assert.NotContains(t, bodies[1], "top_p")
+	assert.NotContains(t, bodies[1], "max_tokens")
+
+	// No override for repair: the client default applies.
+	assert.InDelta(t, 0.5, bodies[2]["temperature"], 1e-6)
+	assert.NotContains(t, bodies[2], "top_p")
+}
@@ -0,0 +1,38 @@
+//go:build integration
+
+package llm
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOllamaProviderIntegration talks to a real Ollama server. Set
+// OLLAMA_ENDPOINT (e.g. http://localhost:11434) and OLLAMA_MODEL to run it.
+func TestOllamaProviderIntegration(t *testing.T) {
+	endpoint := os.Getenv("OLLAMA_ENDPOINT")
+	model := os.Getenv("OLLAMA_MODEL")
+	if endpoint == "" || model == "" {
+		t.Skip("OLLAMA_ENDPOINT and OLLAMA_MODEL not set")
+	}
+
+	p, err := newOllamaProvider(remixerProviderConfig{
+		Type:     "ollama",
+		Endpoint: endpoint,
+		Model:    model,
+	})
+	require.NoError(t, err)
+
+	resp, err := p.Chat(context.Background(), remixerChatRequest{
+		Messages: []remixerMessage{
+			{Role: "system", Content: "Answer with a single word."},
+			{Role: "user", Content: "What language is the Linux kernel written in?"},
+		},
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, resp.Content)
+}
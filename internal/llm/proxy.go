@@ -0,0 +1,40 @@
+package llm
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// proxySchemes are the proxy_url schemes net/http can dial through.
+var proxySchemes = map[string]bool{"http": true, "https": true, "socks5": true, "socks5h": true}
+
+// parseProxyURL checks a provider's proxy_url.
+func parseProxyURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("proxy_url: %w", err)
+	}
+	if !proxySchemes[u.Scheme] || u.Host == "" {
+		return nil, fmt.Errorf("proxy_url %q: want http://, https://, socks5:// or socks5h:// with a host", raw)
+	}
+	return u, nil
+}
+
+// newHTTPClient returns the HTTP client of one provider. Requests go
+// through cfg.ProxyURL when set, and otherwise through the proxy named by
+// the standard HTTPS_PROXY, HTTP_PROXY and NO_PROXY variables. A zero
+// timeout leaves requests bounded by their context only.
+func newHTTPClient(cfg remixerProviderConfig, timeout time.Duration) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	if cfg.ProxyURL != "" {
+		proxyURL, err := parseProxyURL(cfg.ProxyURL)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}, nil
+}
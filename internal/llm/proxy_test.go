@@ -0,0 +1,93 @@
+package llm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// newTestProxy is a forward proxy that answers every request itself, as
+// the upstream would, and records the hosts it was asked for.
+func newTestProxy(t *testing.T, reply string) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var hosts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hosts = append(hosts, r.URL.Host)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, reply)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), hosts...)
+	}
+}
+
+func TestProvidersUseProxyURL(t *testing.T) {
+	for _, tc := range []struct {
+		cfg   remixerProviderConfig
+		reply string
+	}{
+		{
+			cfg:   remixerProviderConfig{Type: "openai", Endpoint: "http://openai.example.invalid/v1", Model: "m", APIKey: "k", MaxTokensField: openAIMaxTokensFieldLegacy},
+			reply: `{"choices":[{"index":0,"message":{"role":"assistant","content":"ok"}}]}`,
+		},
+		{
+			cfg:   remixerProviderConfig{Type: "ollama", Endpoint: "http://ollama.example.invalid", Model: "m"},
+			reply: `{"model":"m","message":{"role":"assistant","content":"ok"},"done":true}`,
+		},
+		{
+			cfg:   remixerProviderConfig{Type: "anthropic", Endpoint: "http://anthropic.example.invalid", Model: "m", APIKey: "k"},
+			reply: `{"id":"msg","type":"message","role":"assistant","model":"m","content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn","usage":{"input_tokens":1,"output_tokens":1}}`,
+		},
+	} {
+		t.Run(tc.cfg.Type, func(t *testing.T) {
+			proxy, hosts := newTestProxy(t, tc.reply)
+			tc.cfg.ProxyURL = proxy.URL
+			p, err := newRemixerProvider(tc.cfg)
+			if err != nil {
+				t.Fatalf("creating provider: %v", err)
+			}
+
+			resp, err := p.Chat(context.Background(), remixerChatRequest{Messages: []remixerMessage{{Role: "user", Content: "Hello"}}})
+			if err != nil {
+				t.Fatalf("chat through proxy: %v", err)
+			}
+			if resp.Content != "ok" {
+				t.Errorf("content = %q", resp.Content)
+			}
+			if got := hosts(); len(got) != 1 || got[0] != tc.cfg.Type+".example.invalid" {
+				t.Errorf("proxy saw hosts %v, want the provider endpoint", got)
+			}
+		})
+	}
+}
+
+func TestNewHTTPClientProxy(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "https://api.example.com/v1/chat/completions", nil)
+
+	client, err := newHTTPClient(remixerProviderConfig{ProxyURL: "socks5://127.0.0.1:1080"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := client.Transport.(*http.Transport)
+	if proxy, err := transport.Proxy(req); err != nil || proxy.String() != "socks5://127.0.0.1:1080" {
+		t.Errorf("socks5 proxy = %v, %v", proxy, err)
+	}
+	if transport.TLSClientConfig != nil && transport.TLSClientConfig.InsecureSkipVerify {
+		t.Error("TLS verification must stay on")
+	}
+
+	for _, bad := range []string{"ftp://proxy:21", "proxy:3128", "http://"} {
+		if _, err := newHTTPClient(remixerProviderConfig{ProxyURL: bad}, 0); err == nil {
+			t.Errorf("proxy_url %q: expected an error", bad)
+		}
+	}
+}
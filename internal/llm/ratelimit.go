@@ -0,0 +1,128 @@
+package llm
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/zjy-dev/de-fuzz/internal/logger"
+)
+
+// remixerRateLimitConfig is the rate_limit: block of remixer.yaml. RPM caps
+// requests per minute and TPM estimated tokens per minute; 0 means no cap.
+type remixerRateLimitConfig struct {
+	RPM int `yaml:"rpm,omitempty"`
+	TPM int `yaml:"tpm,omitempty"`
+}
+
+// tokenBucket holds up to capacity units and refills at capacity per minute.
+type tokenBucket struct {
+	capacity  float64
+	available float64
+	last      time.Time
+}
+
+func newTokenBucket(perMinute int, now time.Time) *tokenBucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &tokenBucket{capacity: float64(perMinute), available: float64(perMinute), last: now}
+}
+
+// refill credits the units accrued since the last call.
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.available = min(b.capacity, b.available+elapsed.Minutes()*b.capacity)
+		b.last = now
+	}
+}
+
+// delay returns how long until cost units are available. A cost above the
+// capacity is clamped so an oversized request waits for a full bucket
+// instead of forever.
+func (b *tokenBucket) delay(cost float64) time.Duration {
+	if b == nil {
+		return 0
+	}
+	cost = min(cost, b.capacity)
+	if b.available >= cost {
+		return 0
+	}
+	return time.Duration((cost - b.available) / b.capacity * float64(time.Minute))
+}
+
+func (b *tokenBucket) take(cost float64) {
+	if b != nil {
+		b.available -= min(cost, b.capacity)
+	}
+}
+
+// rateLimiter throttles requests client-side so the fuzz loop stays under
+// the account's RPM and TPM limits. One limiter is shared by every call
+// through a remixer engine and is safe for concurrent use.
+type rateLimiter struct {
+	mu       sync.Mutex
+	requests *tokenBucket
+	tokens   *tokenBucket
+	now      func() time.Time
+	sleep    func(ctx context.Context, d time.Duration) error
+}
+
+// newRateLimiter returns nil when cfg sets no limit.
+func newRateLimiter(cfg remixerRateLimitConfig) *rateLimiter {
+	if cfg.RPM <= 0 && cfg.TPM <= 0 {
+		return nil
+	}
+	l := &rateLimiter{now: time.Now, sleep: sleepContext}
+	l.reset(cfg)
+	return l
+}
+
+// reset (re)fills both buckets as of l.now().
+func (l *rateLimiter) reset(cfg remixerRateLimitConfig) {
+	now := l.now()
+	l.requests = newTokenBucket(cfg.RPM, now)
+	l.tokens = newTokenBucket(cfg.TPM, now)
+}
+
+// wait blocks until one request costing tokens estimated tokens fits in
+// both buckets, then takes it. It returns early with ctx's error.
+func (l *rateLimiter) wait(ctx context.Context, tokens int) error {
+	if l == nil {
+		return nil
+	}
+	for {
+		l.mu.Lock()
+		now := l.now()
+		if l.requests != nil {
+			l.requests.refill(now)
+		}
+		if l.tokens != nil {
+			l.tokens.refill(now)
+		}
+		d := max(l.requests.delay(1), l.tokens.delay(float64(tokens)))
+		if d == 0 {
+			l.requests.take(1)
+			l.tokens.take(float64(tokens))
+			l.mu.Unlock()
+			return nil
+		}
+		l.mu.Unlock()
+
+		logger.Debug("[LLM] rate limit: waiting %s for capacity (%d estimated tokens)", d.Round(time.Millisecond), tokens)
+		if err := l.sleep(ctx, d); err != nil {
+			return err
+		}
+	}
+}
+
+// estimateRequestTokens estimates what a request counts against TPM: the
+// prompt at about four characters per token (as prompt.EstimateTokens) plus
+// the reply cap.
+func estimateRequestTokens(messages []remixerMessage, maxOutputTokens int) int {
+	chars := 0
+	for _, m := range messages {
+		chars += len(m.Content)
+	}
+	return (chars+3)/4 + maxOutputTokens
+}
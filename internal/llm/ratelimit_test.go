@@ -0,0 +1,136 @@
+package llm
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a manual clock whose sleep advances time instantly.
+type fakeClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	slept time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.slept += d
+	return nil
+}
+
+func fakeRateLimiter(cfg remixerRateLimitConfig) (*rateLimiter, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	l := &rateLimiter{now: clock.Now, sleep: clock.Sleep}
+	l.reset(cfg)
+	return l, clock
+}
+
+func TestRateLimiterRPM(t *testing.T) {
+	l, clock := fakeRateLimiter(remixerRateLimitConfig{RPM: 60})
+
+	// A full bucket lets a minute's worth of requests through at once.
+	for i := 0; i < 60; i++ {
+		if err := l.wait(context.Background(), 0); err != nil {
+			t.Fatalf("wait failed: %v", err)
+		}
+	}
+	if clock.slept != 0 {
+		t.Fatalf("burst within capacity should not wait, slept %s", clock.slept)
+	}
+
+	// The next one waits for one request's worth of refill.
+	if err := l.wait(context.Background(), 0); err != nil {
+		t.Fatalf("wait failed: %v", err)
+	}
+	if clock.slept != time.Second {
+		t.Errorf("expected a 1s wait at 60 rpm, slept %s", clock.slept)
+	}
+}
+
+func TestRateLimiterTPM(t *testing.T) {
+	l, clock := fakeRateLimiter(remixerRateLimitConfig{TPM: 6000})
+
+	if err := l.wait(context.Background(), 4000); err != nil {
+		t.Fatalf("wait failed: %v", err)
+	}
+	if err := l.wait(context.Background(), 4000); err != nil {
+		t.Fatalf("wait failed: %v", err)
+	}
+	// 2000 tokens short at 100 tokens/s.
+	if clock.slept != 20*time.Second {
+		t.Errorf("expected a 20s wait, slept %s", clock.slept)
+	}
+
+	// A request larger than the bucket waits for a full bucket, not forever.
+	clock.slept = 0
+	if err := l.wait(context.Background(), 50000); err != nil {
+		t.Fatalf("wait failed: %v", err)
+	}
+	if clock.slept != time.Minute {
+		t.Errorf("expected a full-bucket wait of 1m, slept %s", clock.slept)
+	}
+}
+
+func TestRateLimiterCancelled(t *testing.T) {
+	l, _ := fakeRateLimiter(remixerRateLimitConfig{RPM: 1})
+	if err := l.wait(context.Background(), 0); err != nil {
+		t.Fatalf("wait failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.wait(ctx, 0); err == nil {
+		t.Error("expected the context error while waiting for capacity")
+	}
+}
+
+func TestRateLimiterConcurrent(t *testing.T) {
+	l, clock := fakeRateLimiter(remixerRateLimitConfig{RPM: 10})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := l.wait(context.Background(), 0); err != nil {
+				t.Errorf("wait failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Ten requests fit in the bucket; the other ten need a minute of refill.
+	if got := clock.Now().Sub(time.Unix(0, 0)); got < time.Minute-time.Millisecond {
+		t.Errorf("20 requests at 10 rpm finished after %s of fake time, want >= 1m", got)
+	}
+}
+
+func TestNewRateLimiterDisabled(t *testing.T) {
+	if l := newRateLimiter(remixerRateLimitConfig{}); l != nil {
+		t.Error("expected no limiter without rpm or tpm")
+	}
+	var l *rateLimiter
+	if err := l.wait(context.Background(), 100); err != nil {
+		t.Errorf("nil limiter should not block: %v", err)
+	}
+}
+
+func TestEstimateRequestTokens(t *testing.T) {
+	messages := []remixerMessage{{Role: "system", Content: "12345678"}, {Role: "user", Content: "1234"}}
+	if got := estimateRequestTokens(messages, 100); got != 103 {
+		t.Errorf("estimateRequestTokens() = %d, want 103", got)
+	}
+}
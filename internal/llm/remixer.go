@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/zjy-dev/de-fuzz/internal/seed"
 )
@@ -11,12 +12,15 @@ import (
 // RemixerClient implements the LLM interface using the internal remixer
 // for weighted-random multi-model LLM selection.
 type RemixerClient struct {
-	remixer     *remixerEngine
-	temperature float64
+	remixer          *remixerEngine
+	temperature      float64
+	requestTimeout   time.Duration // 0 = bounded only by the caller's ctx
+	sampling         map[string]Sampling
+	batchConcurrency int // GetCompletions calls in flight, 0 = DefaultBatchConcurrency
 }
 
 // NewRemixerClient creates a new RemixerClient from a config file path and default temperature.
-func NewRemixerClient(configPath string, temperature float64) (*RemixerClient, error) {
+func NewRemixerClient(configPath string, temperature float64, opts ...Option) (*RemixerClient, error) {
 	r, err := newRemixerEngine(configPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create remixer: %w", err)
@@ -24,38 +28,141 @@ func NewRemixerClient(configPath string, temperature float64) (*RemixerClient, e
 	if temperature <= 0 {
 		temperature = 0.1
 	}
-	return &RemixerClient{
+	c := &RemixerClient{
 		remixer:     r,
 		temperature: temperature,
-	}, nil
+	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Usage returns the request and retry counts since the client was created.
+func (c *RemixerClient) Usage() Usage {
+	return c.remixer.Usage()
+}
+
+// ProviderCalls returns how many calls each provider served since the
+// client was created, keyed by "type/model".
+func (c *RemixerClient) ProviderCalls() map[string]int {
+	return c.remixer.ProviderCalls()
+}
+
+// PromptTokenLimit returns how many prompt tokens every model in the
+// rotation accepts, leaving room for its max_tokens reply; 0 when no model
+// sets context_window.
+func (c *RemixerClient) PromptTokenLimit() int {
+	return c.remixer.promptLimit
+}
+
+// Close flushes and closes the audit log, if any.
+func (c *RemixerClient) Close() error {
+	return c.remixer.audit.close()
 }
 
 // GetCompletion sends a raw prompt to the LLM and gets a direct response.
-func (c *RemixerClient) GetCompletion(prompt string) (string, error) {
-	return c.GetCompletionWithSystem("", prompt)
+func (c *RemixerClient) GetCompletion(ctx context.Context, prompt string) (string, error) {
+	return c.GetCompletionWithSystem(ctx, "", prompt)
 }
 
 // GetCompletionWithSystem sends a prompt with system context to the LLM.
-func (c *RemixerClient) GetCompletionWithSystem(systemPrompt, userPrompt string) (string, error) {
+func (c *RemixerClient) GetCompletionWithSystem(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	return c.complete(ctx, systemPrompt, userPrompt, ResponseFormat{})
+}
+
+// GetJSONCompletionWithSystem is like GetCompletionWithSystem but asks the
+// selected provider for a JSON object reply where it supports one.
+func (c *RemixerClient) GetJSONCompletionWithSystem(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	return c.complete(ctx, systemPrompt, userPrompt, jsonObjectFormat)
+}
+
+// GetFormattedCompletionWithSystem is like GetCompletionWithSystem but asks
+// for a reply in format. It fails with ErrUnsupportedFormat when no provider
+// of the selected model can enforce format.
+func (c *RemixerClient) GetFormattedCompletionWithSystem(ctx context.Context, systemPrompt, userPrompt string, format ResponseFormat) (string, error) {
+	return c.complete(ctx, systemPrompt, userPrompt, format)
+}
+
+// StreamCompletionWithSystem streams the reply to onChunk as the provider
+// produces it (providers that cannot stream deliver it in one piece). When
+// onChunk returns false the stream is closed and the text so far returned.
+func (c *RemixerClient) StreamCompletionWithSystem(ctx context.Context, systemPrompt, userPrompt string, format ResponseFormat, onChunk func(chunk string) bool) (string, error) {
 	var messages []remixerMessage
+	if systemPrompt != "" {
+		messages = append(messages, remixerMessage{Role: RoleSystem, Content: systemPrompt})
+	}
+	messages = append(messages, remixerMessage{Role: RoleUser, Content: userPrompt})
 
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+	result, err := c.remixer.ChatStream(ctx, c.request(ctx, messages, format), onChunk)
+	if err != nil {
+		return "", fmt.Errorf("remixer chat failed: %w", err)
+	}
+	return strings.TrimSpace(result.Content), nil
+}
+
+// Chat sends a whole conversation (system, user and assistant turns) and
+// returns the next assistant message.
+func (c *RemixerClient) Chat(ctx context.Context, messages []Message) (string, error) {
+	return c.chat(ctx, messages, ResponseFormat{})
+}
+
+// ChatJSON is like Chat but asks for a JSON object reply where supported.
+func (c *RemixerClient) ChatJSON(ctx context.Context, messages []Message) (string, error) {
+	return c.chat(ctx, messages, jsonObjectFormat)
+}
+
+func (c *RemixerClient) complete(ctx context.Context, systemPrompt, userPrompt string, format ResponseFormat) (string, error) {
+	var messages []Message
 	if systemPrompt != "" {
-		messages = append(messages, remixerMessage{
-			Role:    "system",
-			Content: systemPrompt,
-		})
+		messages = append(messages, Message{Role: RoleSystem, Content: systemPrompt})
 	}
+	messages = append(messages, Message{Role: RoleUser, Content: userPrompt})
+	return c.chat(ctx, messages, format)
+}
 
-	messages = append(messages, remixerMessage{
-		Role:    "user",
-		Content: userPrompt,
-	})
+// callContext bounds one call by the client's request timeout.
+func (c *RemixerClient) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.requestTimeout > 0 {
+		return context.WithTimeout(ctx, c.requestTimeout)
+	}
+	return context.WithCancel(ctx)
+}
 
+// request builds a request with the client's default temperature and the
+// sampling overrides for the call type carried by ctx.
+func (c *RemixerClient) request(ctx context.Context, messages []remixerMessage, format ResponseFormat) remixerChatRequest {
 	temp := c.temperature
-	result, err := c.remixer.Chat(context.Background(), remixerChatRequest{
-		Messages:    messages,
-		Temperature: &temp,
-	})
+	req := remixerChatRequest{
+		Messages:       messages,
+		Temperature:    &temp,
+		ResponseFormat: format,
+	}
+	s := c.sampling[CallInfoFrom(ctx).Type]
+	if s.Temperature != nil {
+		req.Temperature = s.Temperature
+	}
+	req.TopP = s.TopP
+	if s.MaxTokens > 0 {
+		maxTokens := s.MaxTokens
+		req.MaxTokens = &maxTokens
+	}
+	return req
+}
+
+func (c *RemixerClient) chat(ctx context.Context, history []Message, format ResponseFormat) (string, error) {
+	messages := make([]remixerMessage, 0, len(history))
+	for _, m := range history {
+		messages = append(messages, remixerMessage{Role: m.Role, Content: m.Content})
+	}
+
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+	result, err := c.remixer.Chat(ctx, c.request(ctx, messages, format))
 	if err != nil {
 		return "", fmt.Errorf("remixer chat failed: %w", err)
 	}
@@ -64,13 +171,13 @@ func (c *RemixerClient) GetCompletionWithSystem(systemPrompt, userPrompt string)
 }
 
 // Understand processes the initial prompt and returns the LLM's summary.
-func (c *RemixerClient) Understand(prompt string) (string, error) {
-	return c.GetCompletion(prompt)
+func (c *RemixerClient) Understand(ctx context.Context, prompt string) (string, error) {
+	return c.GetCompletion(withDefaultCallType(ctx, CallUnderstand), prompt)
 }
 
 // Generate creates a new seed based on the provided context.
-func (c *RemixerClient) Generate(understanding, prompt string) (*seed.Seed, error) {
-	completion, err := c.GetCompletionWithSystem(understanding, prompt)
+func (c *RemixerClient) Generate(ctx context.Context, understanding, prompt string) (*seed.Seed, error) {
+	completion, err := c.GetCompletionWithSystem(withDefaultCallType(ctx, CallGenerate), understanding, prompt)
 	if err != nil {
 		return nil, err
 	}
@@ -87,7 +194,7 @@ func (c *RemixerClient) Generate(understanding, prompt string) (*seed.Seed, erro
 }
 
 // Analyze interprets the feedback from a seed execution.
-func (c *RemixerClient) Analyze(understanding, prompt string, s *seed.Seed, feedback string) (string, error) {
+func (c *RemixerClient) Analyze(ctx context.Context, understanding, prompt string, s *seed.Seed, feedback string) (string, error) {
 	if s == nil {
 		return "", fmt.Errorf("seed cannot be nil")
 	}
@@ -95,16 +202,16 @@ func (c *RemixerClient) Analyze(understanding, prompt string, s *seed.Seed, feed
 	analysisPrompt := fmt.Sprintf("%s\n\nSeed Content:\n%s\n\nExecution Feedback:\n%s",
 		prompt, s.Content, feedback)
 
-	return c.GetCompletionWithSystem(understanding, analysisPrompt)
+	return c.GetCompletionWithSystem(withDefaultCallType(ctx, CallRefine), understanding, analysisPrompt)
 }
 
 // Mutate modifies an existing seed to create a new variant.
-func (c *RemixerClient) Mutate(understanding, prompt string, s *seed.Seed) (*seed.Seed, error) {
+func (c *RemixerClient) Mutate(ctx context.Context, understanding, prompt string, s *seed.Seed) (*seed.Seed, error) {
 	if s == nil {
 		return nil, fmt.Errorf("seed cannot be nil")
 	}
 
-	completion, err := c.GetCompletionWithSystem(understanding, prompt)
+	completion, err := c.GetCompletionWithSystem(withDefaultCallType(ctx, CallMutate), understanding, prompt)
 	if err != nil {
 		return nil, err
 	}
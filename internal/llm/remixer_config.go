@@ -3,7 +3,9 @@ package llm
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	appconfig "github.com/zjy-dev/de-fuzz/internal/config"
 	"gopkg.in/yaml.v3"
@@ -15,14 +17,45 @@ const (
 	openAIProtocolResponses       = "responses"
 )
 
+// Values of max_tokens_field: which chat-completions field carries the
+// output token cap. "auto" uses max_completion_tokens for reasoning model
+// families (o1, o3, o4, gpt-5) and max_tokens otherwise.
+const (
+	openAIMaxTokensFieldAuto       = "auto"
+	openAIMaxTokensFieldLegacy     = "max_tokens"
+	openAIMaxTokensFieldCompletion = "max_completion_tokens"
+)
+
 type remixerConfig struct {
-	Models []remixerModelConfig `yaml:"models"`
+	Models         []remixerModelConfig   `yaml:"models"`
+	Retry          remixerRetryConfig     `yaml:"retry,omitempty"`
+	RateLimit      remixerRateLimitConfig `yaml:"rate_limit,omitempty"`
+	CircuitBreaker remixerBreakerConfig   `yaml:"circuit_breaker,omitempty"`
 }
 
 type remixerModelConfig struct {
-	Name      string                  `yaml:"name"`
-	Weight    int                     `yaml:"weight"`
+	Name   string `yaml:"name"`
+	Weight int    `yaml:"weight"`
+	// Providers is a fallback chain: each call goes to the first provider
+	// whose circuit is closed and moves down the list when it fails.
 	Providers []remixerProviderConfig `yaml:"providers"`
+	Pricing   remixerPricing          `yaml:"pricing,omitempty"`
+	// ContextWindow is the model's context size in tokens, prompt and reply
+	// together (0 = unknown). Prompts that would not fit are compressed
+	// before they are sent.
+	ContextWindow int `yaml:"context_window,omitempty"`
+}
+
+// remixerPricing is a model's price in currency units (e.g. USD) per
+// million tokens, used to estimate campaign cost. Zero prices count as free.
+type remixerPricing struct {
+	PromptPerMillion     float64 `yaml:"prompt_per_million,omitempty"`
+	CompletionPerMillion float64 `yaml:"completion_per_million,omitempty"`
+}
+
+// cost returns the price of one request.
+func (p remixerPricing) cost(promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens)*p.PromptPerMillion + float64(completionTokens)*p.CompletionPerMillion) / 1e6
 }
 
 type remixerProviderConfig struct {
@@ -31,6 +64,38 @@ type remixerProviderConfig struct {
 	Model    string `yaml:"model"`
 	APIKey   string `yaml:"api_key"`
 	Protocol string `yaml:"protocol,omitempty"`
+
+	// Optional request settings. Temperature overrides the client default
+	// for this provider; MaxTokens caps the reply (0 = no cap). Organization
+	// applies to openai providers only, MaxTokensField to openai and
+	// azure-openai ones.
+	Temperature    *float64 `yaml:"temperature,omitempty"`
+	MaxTokens      int      `yaml:"max_tokens,omitempty"`
+	Organization   string   `yaml:"organization,omitempty"`
+	MaxTokensField string   `yaml:"max_tokens_field,omitempty"`
+
+	// Timeout bounds one request, e.g. "10m" (0 = provider default). Local
+	// models can take minutes on large prompts.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+
+	// ProxyURL routes this provider's requests through an http(s):// or
+	// socks5:// proxy. Without it the HTTPS_PROXY, HTTP_PROXY and NO_PROXY
+	// environment variables apply.
+	ProxyURL string `yaml:"proxy_url,omitempty"`
+
+	// Azure OpenAI providers only. Deployment names the deployment in the
+	// request path (model defaults to it); APIVersion is the api-version
+	// query parameter (default defaultAzureAPIVersion).
+	Deployment string `yaml:"deployment,omitempty"`
+	APIVersion string `yaml:"api_version,omitempty"`
+
+	// Replay providers only. Cassette is the JSONL file of recorded replies,
+	// relative to this config file. Mode is "replay" (default: serve from
+	// the cassette) or "record" (ask the first provider of the Upstream
+	// model and append its replies to the cassette).
+	Cassette string `yaml:"cassette,omitempty"`
+	Mode     string `yaml:"mode,omitempty"`
+	Upstream string `yaml:"upstream,omitempty"`
 }
 
 func loadRemixerConfig(path string) (*remixerConfig, error) {
@@ -60,6 +125,13 @@ func loadRemixerConfig(path string) (*remixerConfig, error) {
 	if err := validateRemixerConfig(&cfg); err != nil {
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
+	for _, model := range cfg.Models {
+		for j, provider := range model.Providers {
+			if provider.Type == "replay" && !filepath.IsAbs(provider.Cassette) {
+				model.Providers[j].Cassette = filepath.Join(filepath.Dir(path), provider.Cassette)
+			}
+		}
+	}
 
 	return &cfg, nil
 }
@@ -68,8 +140,18 @@ func validateRemixerConfig(cfg *remixerConfig) error {
 	if len(cfg.Models) == 0 {
 		return fmt.Errorf("at least one model must be configured")
 	}
+	if cfg.Retry.MaxAttempts < 0 || cfg.Retry.InitialBackoff < 0 || cfg.Retry.MaxBackoff < 0 || cfg.Retry.MaxElapsed < 0 {
+		return fmt.Errorf("retry: values must be >= 0")
+	}
+	if cfg.RateLimit.RPM < 0 || cfg.RateLimit.TPM < 0 {
+		return fmt.Errorf("rate_limit: rpm and tpm must be >= 0")
+	}
+	if cfg.CircuitBreaker.FailureThreshold < 0 || cfg.CircuitBreaker.Cooldown < 0 {
+		return fmt.Errorf("circuit_breaker: failure_threshold and cooldown must be >= 0")
+	}
 
 	names := make(map[string]bool)
+	totalWeight := 0
 	for i, model := range cfg.Models {
 		if model.Name == "" {
 			return fmt.Errorf("model[%d]: name is required", i)
@@ -79,8 +161,17 @@ func validateRemixerConfig(cfg *remixerConfig) error {
 		}
 		names[model.Name] = true
 
-		if model.Weight <= 0 {
-			return fmt.Errorf("model %q: weight must be positive", model.Name)
+		// A zero weight keeps a model out of the rotation, e.g. when it only
+		// serves as a replay upstream.
+		if model.Weight < 0 {
+			return fmt.Errorf("model %q: weight must be >= 0", model.Name)
+		}
+		totalWeight += model.Weight
+		if model.Pricing.PromptPerMillion < 0 || model.Pricing.CompletionPerMillion < 0 {
+			return fmt.Errorf("model %q: pricing must be >= 0", model.Name)
+		}
+		if model.ContextWindow < 0 {
+			return fmt.Errorf("model %q: context_window must be >= 0", model.Name)
 		}
 		if len(model.Providers) == 0 {
 			return fmt.Errorf("model %q: at least one provider is required", model.Name)
@@ -90,38 +181,132 @@ func validateRemixerConfig(cfg *remixerConfig) error {
 			if err := validateProviderType(provider.Type); err != nil {
 				return fmt.Errorf("model %q provider[%d]: %w", model.Name, j, err)
 			}
+			if provider.Type == "replay" {
+				if err := validateReplayProvider(cfg, &cfg.Models[i].Providers[j]); err != nil {
+					return fmt.Errorf("model %q provider[%d]: %w", model.Name, j, err)
+				}
+				continue
+			}
+			if provider.Cassette != "" || provider.Mode != "" || provider.Upstream != "" {
+				return fmt.Errorf("model %q provider[%d]: cassette, mode and upstream are only supported for replay providers", model.Name, j)
+			}
 			if provider.Endpoint == "" {
 				return fmt.Errorf("model %q provider[%d]: endpoint is required", model.Name, j)
 			}
+			if provider.Type == "azure-openai" {
+				if provider.Deployment == "" {
+					return fmt.Errorf("model %q provider[%d]: deployment is required for azure-openai providers", model.Name, j)
+				}
+				if provider.Model == "" {
+					cfg.Models[i].Providers[j].Model = provider.Deployment
+					provider.Model = provider.Deployment
+				}
+				if provider.APIVersion == "" {
+					cfg.Models[i].Providers[j].APIVersion = defaultAzureAPIVersion
+				}
+			} else if provider.Deployment != "" || provider.APIVersion != "" {
+				return fmt.Errorf("model %q provider[%d]: deployment and api_version are only supported for azure-openai providers", model.Name, j)
+			}
 			if provider.Model == "" {
 				return fmt.Errorf("model %q provider[%d]: model is required", model.Name, j)
 			}
-			if provider.APIKey == "" || strings.HasPrefix(provider.APIKey, "${") {
+			// Local ollama servers need no key.
+			if provider.Type == "ollama" && strings.HasPrefix(provider.APIKey, "${") {
+				cfg.Models[i].Providers[j].APIKey = ""
+			} else if provider.Type != "ollama" && (provider.APIKey == "" || strings.HasPrefix(provider.APIKey, "${")) {
 				return fmt.Errorf("model %q provider[%d]: api_key is required (check your .env)", model.Name, j)
 			}
 
+			if provider.Temperature != nil && (*provider.Temperature < 0 || *provider.Temperature > 2) {
+				return fmt.Errorf("model %q provider[%d]: temperature must be in [0, 2]", model.Name, j)
+			}
+			if provider.MaxTokens < 0 {
+				return fmt.Errorf("model %q provider[%d]: max_tokens must be >= 0", model.Name, j)
+			}
+			if provider.Timeout < 0 {
+				return fmt.Errorf("model %q provider[%d]: timeout must be >= 0", model.Name, j)
+			}
+			if provider.ProxyURL != "" {
+				if _, err := parseProxyURL(provider.ProxyURL); err != nil {
+					return fmt.Errorf("model %q provider[%d]: %w", model.Name, j, err)
+				}
+			}
+
 			if provider.Type == "openai" {
 				if provider.Protocol == "" {
 					cfg.Models[i].Providers[j].Protocol = openAIProtocolAuto
 				} else if err := validateOpenAIProtocol(provider.Protocol); err != nil {
 					return fmt.Errorf("model %q provider[%d]: %w", model.Name, j, err)
 				}
-			} else if provider.Protocol != "" {
-				return fmt.Errorf("model %q provider[%d]: protocol is only supported for openai providers", model.Name, j)
+			} else {
+				if provider.Protocol != "" {
+					return fmt.Errorf("model %q provider[%d]: protocol is only supported for openai providers", model.Name, j)
+				}
+				if provider.Organization != "" {
+					return fmt.Errorf("model %q provider[%d]: organization is only supported for openai providers", model.Name, j)
+				}
+			}
+			if provider.Type == "openai" || provider.Type == "azure-openai" {
+				if provider.MaxTokensField == "" {
+					cfg.Models[i].Providers[j].MaxTokensField = openAIMaxTokensFieldAuto
+				} else if err := validateOpenAIMaxTokensField(provider.MaxTokensField); err != nil {
+					return fmt.Errorf("model %q provider[%d]: %w", model.Name, j, err)
+				}
+			} else if provider.MaxTokensField != "" {
+				return fmt.Errorf("model %q provider[%d]: max_tokens_field is only supported for openai and azure-openai providers", model.Name, j)
 			}
 		}
 	}
+	if totalWeight == 0 {
+		return fmt.Errorf("at least one model must have a positive weight")
+	}
 
 	return nil
 }
 
 func validateProviderType(providerType string) error {
 	switch providerType {
-	case "openai", "anthropic":
+	case "openai", "azure-openai", "anthropic", "ollama", "gemini", "replay":
 		return nil
 	default:
-		return fmt.Errorf("unsupported provider type %q (supported: openai, anthropic)", providerType)
+		return fmt.Errorf("unsupported provider type %q (supported: openai, azure-openai, anthropic, ollama, gemini, replay)", providerType)
+	}
+}
+
+// validateReplayProvider checks a replay provider and fills in its
+// defaults. Endpoint and api_key are not used; the model name only labels
+// the provider in usage reports.
+func validateReplayProvider(cfg *remixerConfig, provider *remixerProviderConfig) error {
+	if provider.Cassette == "" {
+		return fmt.Errorf("cassette is required for replay providers")
+	}
+	if provider.Model == "" {
+		provider.Model = "cassette"
+	}
+	switch provider.Mode {
+	case "":
+		provider.Mode = replayModeReplay
+	case replayModeReplay:
+	case replayModeRecord:
+		if provider.Upstream == "" {
+			return fmt.Errorf("upstream is required in record mode")
+		}
+	default:
+		return fmt.Errorf("unsupported replay mode %q (supported: replay, record)", provider.Mode)
 	}
+
+	if provider.Upstream == "" {
+		return nil
+	}
+	for _, model := range cfg.Models {
+		if model.Name == provider.Upstream {
+			if len(model.Providers) == 0 || model.Providers[0].Type == "replay" {
+				return fmt.Errorf("upstream %q must start with a non-replay provider", provider.Upstream)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("upstream %q is not a configured model", provider.Upstream)
 }
 
 func validateOpenAIProtocol(protocol string) error {
@@ -132,3 +317,12 @@ func validateOpenAIProtocol(protocol string) error {
 		return fmt.Errorf("unsupported openai protocol %q (supported: auto, chat_completions, responses)", protocol)
 	}
 }
+
+func validateOpenAIMaxTokensField(field string) error {
+	switch field {
+	case openAIMaxTokensFieldAuto, openAIMaxTokensFieldLegacy, openAIMaxTokensFieldCompletion:
+		return nil
+	default:
+		return fmt.Errorf("unsupported openai max_tokens_field %q (supported: auto, max_tokens, max_completion_tokens)", field)
+	}
+}
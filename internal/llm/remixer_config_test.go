@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestLoadRemixerConfigDefaultProtocol(t *testing.T) {
@@ -49,6 +50,111 @@ models:
 	}
 }
 
+func TestLoadRemixerConfigRequestSettings(t *testing.T) {
+	configPath := writeTempRemixerConfig(t, `
+models:
+  - name: "test-model"
+    weight: 1
+    providers:
+      - type: "openai"
+        endpoint: "https://api.example.com"
+        model: "gpt-4"
+        api_key: "test-key"
+        temperature: 0.3
+        max_tokens: 1024
+        organization: "org-test"
+`)
+
+	cfg, err := loadRemixerConfig(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	provider := cfg.Models[0].Providers[0]
+	if provider.Temperature == nil || *provider.Temperature != 0.3 {
+		t.Errorf("expected temperature 0.3, got %v", provider.Temperature)
+	}
+	if provider.MaxTokens != 1024 {
+		t.Errorf("expected max_tokens 1024, got %d", provider.MaxTokens)
+	}
+	if provider.Organization != "org-test" {
+		t.Errorf("expected organization 'org-test', got %q", provider.Organization)
+	}
+	if provider.MaxTokensField != openAIMaxTokensFieldAuto {
+		t.Errorf("expected default max_tokens_field %q, got %q", openAIMaxTokensFieldAuto, provider.MaxTokensField)
+	}
+}
+
+func TestLoadRemixerConfigInvalidRequestSettings(t *testing.T) {
+	tests := map[string]string{
+		"negative max_tokens": `
+      - type: "openai"
+        endpoint: "https://api.example.com"
+        model: "gpt-4"
+        api_key: "test-key"
+        max_tokens: -1`,
+		"temperature out of range": `
+      - type: "openai"
+        endpoint: "https://api.example.com"
+        model: "gpt-4"
+        api_key: "test-key"
+        temperature: 3`,
+		"invalid max_tokens_field": `
+      - type: "openai"
+        endpoint: "https://api.example.com"
+        model: "gpt-4"
+        api_key: "test-key"
+        max_tokens_field: "max_output_tokens"`,
+		"organization on anthropic": `
+      - type: "anthropic"
+        endpoint: "https://api.example.com"
+        model: "claude"
+        api_key: "test-key"
+        organization: "org-test"`,
+		"unsupported proxy scheme": `
+      - type: "openai"
+        endpoint: "https://api.example.com"
+        model: "gpt-4"
+        api_key: "test-key"
+        proxy_url: "ftp://proxy.example.com:21"`,
+	}
+
+	for name, provider := range tests {
+		t.Run(name, func(t *testing.T) {
+			configPath := writeTempRemixerConfig(t, `
+models:
+  - name: "test-model"
+    weight: 1
+    providers:`+provider+"\n")
+
+			if _, err := loadRemixerConfig(configPath); err == nil {
+				t.Fatal("expected a validation error")
+			}
+		})
+	}
+}
+
+func TestLoadRemixerConfigOllamaWithoutAPIKey(t *testing.T) {
+	configPath := writeTempRemixerConfig(t, `
+models:
+  - name: "local"
+    weight: 1
+    providers:
+      - type: "ollama"
+        endpoint: "http://localhost:11434"
+        model: "qwen2.5-coder:7b"
+        timeout: "15m"
+`)
+
+	cfg, err := loadRemixerConfig(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := cfg.Models[0].Providers[0].Timeout; got != 15*time.Minute {
+		t.Errorf("expected timeout 15m, got %s", got)
+	}
+}
+
 func writeTempRemixerConfig(t *testing.T, content string) string {
 	t.Helper()
 
@@ -60,3 +166,84 @@ func writeTempRemixerConfig(t *testing.T, content string) string {
 
 	return path
 }
+
+func TestLoadRemixerConfigCircuitBreaker(t *testing.T) {
+	models := `
+models:
+  - name: "test-model"
+    weight: 1
+    providers:
+      - type: "openai"
+        endpoint: "https://api.example.com"
+        model: "gpt-4"
+        api_key: "test-key"
+`
+	cfg, err := loadRemixerConfig(writeTempRemixerConfig(t, models+`
+circuit_breaker:
+  failure_threshold: 5
+  cooldown: "10m"
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.CircuitBreaker.FailureThreshold != 5 || cfg.CircuitBreaker.Cooldown != 10*time.Minute {
+		t.Errorf("circuit_breaker = %+v, want 5 failures and 10m", cfg.CircuitBreaker)
+	}
+
+	if _, err := loadRemixerConfig(writeTempRemixerConfig(t, models+"circuit_breaker:\n  failure_threshold: -1\n")); err == nil {
+		t.Error("expected an error for a negative failure_threshold")
+	}
+}
+
+func TestPromptTokenLimit(t *testing.T) {
+	cfg, err := loadRemixerConfig(writeTempRemixerConfig(t, `
+models:
+  - name: "small"
+    weight: 1
+    context_window: 8000
+    providers:
+      - type: "openai"
+        endpoint: "https://api.example.com"
+        model: "small"
+        api_key: "test-key"
+        max_tokens: 2000
+  - name: "large"
+    weight: 1
+    context_window: 128000
+    providers:
+      - type: "openai"
+        endpoint: "https://api.example.com"
+        model: "large"
+        api_key: "test-key"
+  - name: "unknown"
+    weight: 1
+    providers:
+      - type: "openai"
+        endpoint: "https://api.example.com"
+        model: "unknown"
+        api_key: "test-key"
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := promptTokenLimit(cfg.Models); got != 6000 {
+		t.Errorf("prompt limit = %d, want the small model's 8000 - 2000", got)
+	}
+	if got := promptTokenLimit(cfg.Models[2:]); got != 0 {
+		t.Errorf("prompt limit without context windows = %d, want 0", got)
+	}
+
+	if _, err := loadRemixerConfig(writeTempRemixerConfig(t, `
+models:
+  - name: "m"
+    weight: 1
+    context_window: -1
+    providers:
+      - type: "openai"
+        endpoint: "https://api.example.com"
+        model: "m"
+        api_key: "test-key"
+`)); err == nil {
+		t.Error("expected an error for a negative context_window")
+	}
+}
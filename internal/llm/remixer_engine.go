@@ -2,8 +2,13 @@ package llm
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/zjy-dev/de-fuzz/internal/logger"
 )
 
 type remixerMessage struct {
@@ -14,12 +19,20 @@ type remixerMessage struct {
 type remixerChatRequest struct {
 	Messages    []remixerMessage `json:"messages"`
 	Temperature *float64         `json:"temperature,omitempty"`
+	TopP        *float64         `json:"top_p,omitempty"`
 	MaxTokens   *int             `json:"max_tokens,omitempty"`
+	// ResponseFormat constrains the reply. Providers without a JSON object
+	// mode ignore FormatJSONObject, since the prompt carries the contract;
+	// those that cannot enforce a JSON schema fail with ErrUnsupportedFormat.
+	ResponseFormat ResponseFormat `json:"response_format,omitzero"`
 }
 
 type remixerChatResponse struct {
 	Content string `json:"content"`
 	Model   string `json:"model"`
+	// Token counts reported by the provider; 0 when it reports none.
+	PromptTokens     int `json:"prompt_tokens,omitempty"`
+	CompletionTokens int `json:"completion_tokens,omitempty"`
 }
 
 type remixerChatResult struct {
@@ -31,6 +44,13 @@ type remixerProvider interface {
 	Chat(ctx context.Context, req remixerChatRequest) (remixerChatResponse, error)
 }
 
+// remixerStreamer is implemented by providers that can stream a reply.
+// onDelta receives each piece of content as it arrives; returning false
+// stops the stream, and ChatStream then returns what was received.
+type remixerStreamer interface {
+	ChatStream(ctx context.Context, req remixerChatRequest, onDelta func(string) bool) (remixerChatResponse, error)
+}
+
 type weightedSelector struct {
 	entries     []selectorEntry
 	totalWeight int
@@ -38,17 +58,44 @@ type weightedSelector struct {
 
 type selectorEntry struct {
 	name      string
-	providers []remixerProvider
+	providers []remixerProvider // Fallback chain, primary first
+	labels    []string          // "type/model" of each provider
 	upper     int
+	config    remixerProviderConfig // config of providers[0]
+	pricing   remixerPricing
 }
 
 type selectorResult struct {
 	ModelName string
-	Provider  remixerProvider
+	Providers []remixerProvider
+	Labels    []string
+	Config    remixerProviderConfig
+	Pricing   remixerPricing
+}
+
+// label names provider i of the result in logs, the circuit breaker and
+// the per-provider call counts.
+func (s selectorResult) label(i int) string {
+	if i < len(s.Labels) {
+		return s.Labels[i]
+	}
+	return fmt.Sprintf("%s#%d", s.ModelName, i+1)
 }
 
 type remixerEngine struct {
 	selector *weightedSelector
+	retrier  *retrier
+	limiter  *rateLimiter    // nil when no rate_limit is configured
+	cache    *diskCache      // nil unless a cache dir is set
+	breaker  *circuitBreaker // nil lets every provider through
+	audit    *auditLog       // nil unless an audit log is set
+	observe  func(CallStats) // nil unless a call observer is set
+
+	promptLimit int // Prompt tokens every model accepts, 0 = unknown
+
+	mu     sync.Mutex
+	usage  Usage
+	served map[string]int // Successful calls per provider label
 }
 
 func newRemixerEngine(configPath string) (*remixerEngine, error) {
@@ -62,15 +109,174 @@ func newRemixerEngine(configPath string) (*remixerEngine, error) {
 		return nil, fmt.Errorf("creating selector: %w", err)
 	}
 
-	return &remixerEngine{selector: selector}, nil
+	return &remixerEngine{
+		selector:    selector,
+		retrier:     newRetrier(cfg.Retry),
+		limiter:     newRateLimiter(cfg.RateLimit),
+		breaker:     newCircuitBreaker(cfg.CircuitBreaker),
+		promptLimit: promptTokenLimit(cfg.Models),
+	}, nil
+}
+
+// promptTokenLimit returns the smallest prompt size any model with a known
+// context window accepts: its window minus the reply cap of its first
+// provider. 0 means no model declares a window.
+func promptTokenLimit(models []remixerModelConfig) int {
+	limit := 0
+	for _, model := range models {
+		if model.ContextWindow <= 0 || model.Weight == 0 {
+			continue
+		}
+		modelLimit := max(model.ContextWindow-model.Providers[0].MaxTokens, 1)
+		if limit == 0 || modelLimit < limit {
+			limit = modelLimit
+		}
+	}
+	return limit
 }
 
 func (r *remixerEngine) Chat(ctx context.Context, req remixerChatRequest) (remixerChatResult, error) {
+	return r.chat(ctx, req, nil)
+}
+
+// ChatStream is Chat with the reply passed to onDelta as it arrives.
+// Providers without streaming support, and cache hits, pass the whole reply
+// in one piece. A failed attempt is retried only while nothing has been
+// passed to onDelta yet. Replies cut short by onDelta are not cached.
+func (r *remixerEngine) ChatStream(ctx context.Context, req remixerChatRequest, onDelta func(string) bool) (remixerChatResult, error) {
+	return r.chat(ctx, req, onDelta)
+}
+
+// chat serves Chat (onDelta nil) and ChatStream.
+func (r *remixerEngine) chat(ctx context.Context, req remixerChatRequest, onDelta func(string) bool) (remixerChatResult, error) {
 	selected := r.selector.Select()
+	start := time.Now()
+
+	var cacheKey string
+	if r.cache != nil {
+		cacheKey = diskCacheKey(selected.Config, req)
+		if resp, ok := r.cache.get(cacheKey); ok {
+			r.mu.Lock()
+			r.usage.CacheHits++
+			r.mu.Unlock()
+			if onDelta != nil {
+				onDelta(resp.Content)
+			}
+			r.recordAudit(ctx, req, selected.ModelName, start, resp, true, nil)
+			r.observeCall(ctx, selected.ModelName, start, resp, true, nil)
+			return remixerChatResult{remixerChatResponse: resp, SelectedModel: selected.ModelName}, nil
+		}
+	}
+
+	maxTokens := selected.Config.MaxTokens
+	if req.MaxTokens != nil {
+		maxTokens = *req.MaxTokens
+	}
+	cost := estimateRequestTokens(req.Messages, maxTokens)
+
+	var delivered, stopped bool
+	deliver := func(delta string) bool {
+		delivered = true
+		if !onDelta(delta) {
+			stopped = true
+		}
+		return !stopped
+	}
+
+	// Walk the fallback chain, skipping providers whose circuit is open.
+	// When every circuit is open the whole chain is tried anyway rather
+	// than failing without a request.
+	chain := make([]int, 0, len(selected.Providers))
+	for i := range selected.Providers {
+		if r.breaker.allow(selected.label(i)) {
+			chain = append(chain, i)
+		}
+	}
+	if len(chain) == 0 {
+		for i := range selected.Providers {
+			chain = append(chain, i)
+		}
+	}
+
+	var resp remixerChatResponse
+	var retries int
+	var err error
+	servedBy := ""
+	for n, i := range chain {
+		provider, label := selected.Providers[i], selected.label(i)
+		var attemptRetries int
+		attemptRetries, err = r.retrier.do(ctx, label, func() error {
+			if err := r.limiter.wait(ctx, cost); err != nil {
+				return err
+			}
+			var err error
+			streamer, streams := provider.(remixerStreamer)
+			switch {
+			case onDelta == nil:
+				resp, err = provider.Chat(ctx, req)
+			case streams:
+				resp, err = streamer.ChatStream(ctx, req, deliver)
+			default:
+				if resp, err = provider.Chat(ctx, req); err == nil {
+					deliver(resp.Content)
+				}
+			}
+			if err != nil && delivered {
+				return &streamInterruptedError{Err: err}
+			}
+			return err
+		})
+		retries += attemptRetries
+		if err == nil {
+			r.breaker.success(label)
+			servedBy = label
+			break
+		}
+		if ctx.Err() != nil || delivered {
+			break
+		}
+		if !errors.Is(err, ErrUnsupportedFormat) {
+			r.breaker.failure(label)
+		}
+		if n+1 < len(chain) {
+			logger.Warn("[LLM] %s failed, falling back to %s: %v", label, selected.label(chain[n+1]), err)
+		}
+	}
+
+	if err == nil && stopped && resp.CompletionTokens == 0 {
+		// An aborted stream ends before the provider reports usage.
+		resp.PromptTokens = estimateRequestTokens(req.Messages, 0)
+		resp.CompletionTokens = estimateRequestTokens([]remixerMessage{{Content: resp.Content}}, 0)
+	}
 
-	resp, err := selected.Provider.Chat(ctx, req)
+	r.mu.Lock()
+	r.usage.Calls++
+	r.usage.Retries += retries
 	if err != nil {
-		return remixerChatResult{}, fmt.Errorf("model %q: %w", selected.ModelName, err)
+		r.usage.Failures++
+	} else {
+		r.usage.PromptTokens += resp.PromptTokens
+		r.usage.CompletionTokens += resp.CompletionTokens
+		r.usage.Cost += selected.Pricing.cost(resp.PromptTokens, resp.CompletionTokens)
+		if r.served == nil {
+			r.served = make(map[string]int)
+		}
+		r.served[servedBy]++
+	}
+	r.mu.Unlock()
+
+	if err != nil {
+		err = fmt.Errorf("model %q: %w", selected.ModelName, err)
+		r.recordAudit(ctx, req, selected.ModelName, start, resp, false, err)
+		r.observeCall(ctx, selected.ModelName, start, resp, false, err)
+		return remixerChatResult{}, err
+	}
+	r.recordAudit(ctx, req, selected.ModelName, start, resp, false, nil)
+	r.observeCall(ctx, selected.ModelName, start, resp, false, nil)
+	if r.cache != nil && !stopped {
+		if err := r.cache.put(cacheKey, resp); err != nil {
+			logger.Warn("[LLM] %v", err)
+		}
 	}
 
 	return remixerChatResult{
@@ -79,25 +285,101 @@ func (r *remixerEngine) Chat(ctx context.Context, req remixerChatRequest) (remix
 	}, nil
 }
 
+// recordAudit queues an audit record of one request, if an audit log is set.
+func (r *remixerEngine) recordAudit(ctx context.Context, req remixerChatRequest, model string, start time.Time, resp remixerChatResponse, cached bool, err error) {
+	if r.audit == nil {
+		return
+	}
+	info := CallInfoFrom(ctx)
+	rec := AuditRecord{
+		Time:       start,
+		CallType:   info.Type,
+		Target:     info.Target,
+		SeedID:     info.SeedID,
+		Model:      model,
+		PromptHash: promptHash(req.Messages),
+		Messages:   r.audit.messages(req.Messages),
+		Cached:     cached,
+		LatencyMS:  time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	} else {
+		rec.Completion = resp.Content
+		rec.PromptTokens = resp.PromptTokens
+		rec.CompletionTokens = resp.CompletionTokens
+	}
+	r.audit.record(rec)
+}
+
+// observeCall passes the outcome of one request to the call observer, if
+// one is set.
+func (r *remixerEngine) observeCall(ctx context.Context, model string, start time.Time, resp remixerChatResponse, cached bool, err error) {
+	if r.observe == nil {
+		return
+	}
+	stats := CallStats{
+		Type:    CallInfoFrom(ctx).Type,
+		Model:   model,
+		Cached:  cached,
+		Failed:  err != nil,
+		Latency: time.Since(start),
+	}
+	if err == nil {
+		stats.PromptTokens = resp.PromptTokens
+		stats.CompletionTokens = resp.CompletionTokens
+	}
+	r.observe(stats)
+}
+
+// Usage returns the request counts since the engine was created.
+func (r *remixerEngine) Usage() Usage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.usage
+}
+
+// ProviderCalls returns how many calls each provider served.
+func (r *remixerEngine) ProviderCalls() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	served := make(map[string]int, len(r.served))
+	for label, n := range r.served {
+		served[label] = n
+	}
+	return served
+}
+
 func newWeightedSelector(models []remixerModelConfig) (*weightedSelector, error) {
 	entries := make([]selectorEntry, 0, len(models))
 	cumulative := 0
 
 	for _, model := range models {
 		providers := make([]remixerProvider, 0, len(model.Providers))
+		labels := make([]string, 0, len(model.Providers))
 		for _, providerCfg := range model.Providers {
-			provider, err := newRemixerProvider(providerCfg)
+			var provider remixerProvider
+			var err error
+			if providerCfg.Type == "replay" {
+				provider, err = newReplayProvider(providerCfg, models)
+			} else {
+				provider, err = newRemixerProvider(providerCfg)
+			}
 			if err != nil {
 				return nil, err
 			}
 			providers = append(providers, provider)
+			labels = append(labels, providerCfg.Type+"/"+providerCfg.Model)
 		}
 
 		cumulative += model.Weight
 		entries = append(entries, selectorEntry{
 			name:      model.Name,
 			providers: providers,
+			labels:    labels,
 			upper:     cumulative,
+			config:    model.Providers[0],
+			pricing:   model.Pricing,
 		})
 	}
 
@@ -108,19 +390,19 @@ func newWeightedSelector(models []remixerModelConfig) (*weightedSelector, error)
 }
 
 func (ws *weightedSelector) Select() selectorResult {
+	entry := ws.entries[len(ws.entries)-1]
 	r := rand.IntN(ws.totalWeight)
-	for _, entry := range ws.entries {
-		if r < entry.upper {
-			return selectorResult{
-				ModelName: entry.name,
-				Provider:  entry.providers[0],
-			}
+	for _, e := range ws.entries {
+		if r < e.upper {
+			entry = e
+			break
 		}
 	}
-
-	last := ws.entries[len(ws.entries)-1]
 	return selectorResult{
-		ModelName: last.name,
-		Provider:  last.providers[0],
+		ModelName: entry.name,
+		Providers: entry.providers,
+		Labels:    entry.labels,
+		Config:    entry.config,
+		Pricing:   entry.pricing,
 	}
 }
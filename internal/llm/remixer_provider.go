@@ -2,43 +2,73 @@ package llm
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 
 	anthropic "github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
 )
 
 type anthropicProvider struct {
-	client anthropic.Client
-	model  string
+	client      anthropic.Client
+	model       string
+	temperature *float64
+	maxTokens   int
 }
 
 func newRemixerProvider(cfg remixerProviderConfig) (remixerProvider, error) {
 	switch cfg.Type {
 	case "openai":
 		return newOpenAIProvider(cfg)
+	case "azure-openai":
+		return newAzureOpenAIProvider(cfg)
 	case "anthropic":
-		return newAnthropicProvider(cfg), nil
+		return newAnthropicProvider(cfg)
+	case "ollama":
+		return newOllamaProvider(cfg)
+	case "gemini":
+		return newGeminiProvider(cfg)
 	default:
 		return nil, fmt.Errorf("unsupported provider type: %s", cfg.Type)
 	}
 }
 
-func newAnthropicProvider(cfg remixerProviderConfig) *anthropicProvider {
+func newAnthropicProvider(cfg remixerProviderConfig) (*anthropicProvider, error) {
+	httpClient, err := newHTTPClient(cfg, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// The remixer's retry layer handles transient failures, so the SDK's
+	// own retries are turned off to avoid multiplying attempts.
 	opts := []option.RequestOption{
 		option.WithAPIKey(cfg.APIKey),
+		option.WithMaxRetries(0),
+		option.WithHTTPClient(httpClient),
 	}
 	if cfg.Endpoint != "" {
 		opts = append(opts, option.WithBaseURL(cfg.Endpoint))
 	}
+	if cfg.Timeout > 0 {
+		opts = append(opts, option.WithRequestTimeout(cfg.Timeout))
+	}
 
 	return &anthropicProvider{
-		client: anthropic.NewClient(opts...),
-		model:  cfg.Model,
-	}
+		client:      anthropic.NewClient(opts...),
+		model:       cfg.Model,
+		temperature: cfg.Temperature,
+		maxTokens:   cfg.MaxTokens,
+	}, nil
 }
 
 func (p *anthropicProvider) Chat(ctx context.Context, req remixerChatRequest) (remixerChatResponse, error) {
+	// There is no JSON mode: a JSON object is left to the prompt, but a
+	// schema cannot be enforced.
+	if format := req.ResponseFormat.normalized(); format.Type == FormatJSONSchema {
+		return remixerChatResponse{}, unsupportedFormat("anthropic", format)
+	}
+
 	var systemBlocks []anthropic.TextBlockParam
 	var msgParams []anthropic.MessageParam
 
@@ -65,6 +95,9 @@ func (p *anthropicProvider) Chat(ctx context.Context, req remixerChatRequest) (r
 	}
 
 	maxTokens := 4096
+	if p.maxTokens > 0 {
+		maxTokens = p.maxTokens
+	}
 	if req.MaxTokens != nil {
 		maxTokens = *req.MaxTokens
 	}
@@ -73,12 +106,21 @@ func (p *anthropicProvider) Chat(ctx context.Context, req remixerChatRequest) (r
 	if len(systemBlocks) > 0 {
 		params.System = systemBlocks
 	}
-	if req.Temperature != nil {
+	if p.temperature != nil {
+		params.Temperature = anthropic.Float(*p.temperature)
+	} else if req.Temperature != nil {
 		params.Temperature = anthropic.Float(*req.Temperature)
 	}
+	if req.TopP != nil {
+		params.TopP = anthropic.Float(*req.TopP)
+	}
 
 	resp, err := p.client.Messages.New(ctx, params)
 	if err != nil {
+		var apiErr *anthropic.Error
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests && apiErr.Response != nil {
+			return remixerChatResponse{}, newRateLimitError("anthropic messages", apiErr.Response.Header, err)
+		}
 		return remixerChatResponse{}, fmt.Errorf("anthropic messages: %w", err)
 	}
 
@@ -90,7 +132,9 @@ func (p *anthropicProvider) Chat(ctx context.Context, req remixerChatRequest) (r
 	}
 
 	return remixerChatResponse{
-		Content: content,
-		Model:   string(resp.Model),
+		Content:          content,
+		Model:            string(resp.Model),
+		PromptTokens:     int(resp.Usage.InputTokens),
+		CompletionTokens: int(resp.Usage.OutputTokens),
 	}, nil
 }
@@ -0,0 +1,51 @@
+package llm
+
+import (
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// defaultAzureAPIVersion is the api-version sent when a provider sets none.
+const defaultAzureAPIVersion = "2024-10-21"
+
+// newAzureOpenAIProvider creates an openai provider for an Azure OpenAI
+// deployment. Requests go to <endpoint>/openai/deployments/<deployment>/
+// chat/completions?api-version=<api_version> with the key in the api-key
+// header; the request and response bodies are OpenAI's.
+func newAzureOpenAIProvider(cfg remixerProviderConfig) (*openAIProvider, error) {
+	// Accept the resource URL with or without the /openai suffix the
+	// client adds itself.
+	baseURL := strings.TrimSuffix(strings.TrimRight(strings.TrimSpace(cfg.Endpoint), "/"), "/openai")
+
+	maxTokensField := cfg.MaxTokensField
+	if maxTokensField == "" || maxTokensField == openAIMaxTokensFieldAuto {
+		maxTokensField = defaultOpenAIMaxTokensField(cfg.Model)
+	}
+	apiVersion := cfg.APIVersion
+	if apiVersion == "" {
+		apiVersion = defaultAzureAPIVersion
+	}
+
+	httpClient, err := newHTTPClient(cfg, cfg.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	azureConfig := openai.DefaultAzureConfig(cfg.APIKey, baseURL)
+	azureConfig.APIVersion = apiVersion
+	azureConfig.AzureModelMapperFunc = func(string) string { return cfg.Deployment }
+	azureConfig.HTTPClient = httpClient
+
+	return &openAIProvider{
+		client:         openai.NewClientWithConfig(azureConfig),
+		httpClient:     httpClient,
+		apiKey:         cfg.APIKey,
+		baseURL:        baseURL,
+		model:          cfg.Model,
+		protocol:       openAIProtocolChatCompletions,
+		temperature:    cfg.Temperature,
+		maxTokens:      cfg.MaxTokens,
+		maxTokensField: maxTokensField,
+	}, nil
+}
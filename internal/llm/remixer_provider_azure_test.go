@@ -0,0 +1,151 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAzureOpenAIProviderChat(t *testing.T) {
+	var got *http.Request
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":1}}`)
+	}))
+	defer srv.Close()
+
+	p, err := newRemixerProvider(remixerProviderConfig{
+		Type:       "azure-openai",
+		Endpoint:   srv.URL + "/openai/",
+		Model:      "gpt-4o",
+		APIKey:     "azure-key",
+		Deployment: "fuzz-gpt4o",
+		APIVersion: "2024-06-01",
+		MaxTokens:  512,
+	})
+	if err != nil {
+		t.Fatalf("creating provider: %v", err)
+	}
+
+	resp, err := p.Chat(context.Background(), remixerChatRequest{Messages: []remixerMessage{{Role: "user", Content: "Hello"}}})
+	if err != nil {
+		t.Fatalf("chat error: %v", err)
+	}
+	if resp.Content != "ok" || resp.PromptTokens != 3 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	if got.URL.Path != "/openai/deployments/fuzz-gpt4o/chat/completions" {
+		t.Errorf("path = %q", got.URL.Path)
+	}
+	if v := got.URL.Query().Get("api-version"); v != "2024-06-01" {
+		t.Errorf("api-version = %q", v)
+	}
+	if key := got.Header.Get("api-key"); key != "azure-key" {
+		t.Errorf("api-key header = %q", key)
+	}
+	if auth := got.Header.Get("Authorization"); auth != "" {
+		t.Errorf("Azure takes no bearer token, got Authorization %q", auth)
+	}
+	if messages, _ := body["messages"].([]any); len(messages) != 1 {
+		t.Errorf("messages = %#v", body["messages"])
+	}
+}
+
+func TestAzureOpenAIProviderContentFilter(t *testing.T) {
+	cfg := remixerProviderConfig{Type: "azure-openai", Model: "gpt-4o", APIKey: "azure-key", Deployment: "d"}
+	for _, tc := range []struct {
+		name, reply string
+		wantAPIErr  bool
+	}{
+		{
+			name: "prompt rejected",
+			reply: `{"error":{"message":"The response was filtered due to the prompt triggering Azure OpenAI's content management policy.",` +
+				`"type":null,"param":"prompt","code":"content_filter","status":400,"innererror":{"code":"ResponsibleAIPolicyViolation",` +
+				`"content_filter_result":{"hate":{"filtered":false,"severity":"safe"},"violence":{"filtered":true,"severity":"medium"},` +
+				`"jailbreak":{"filtered":true,"detected":true}}}}}`,
+			wantAPIErr: true,
+		},
+		{
+			name: "reply filtered",
+			reply: `{"choices":[{"index":0,"message":{"role":"assistant","content":""},"finish_reason":"content_filter",` +
+				`"content_filter_results":{"violence":{"filtered":true,"severity":"high"},"jailbreak":{"filtered":true,"detected":true}}}]}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if tc.wantAPIErr {
+					w.WriteHeader(http.StatusBadRequest)
+				}
+				io.WriteString(w, tc.reply)
+			}))
+			defer srv.Close()
+
+			cfg.Endpoint = srv.URL
+			p, err := newRemixerProvider(cfg)
+			if err != nil {
+				t.Fatalf("creating provider: %v", err)
+			}
+			_, err = p.Chat(context.Background(), remixerChatRequest{Messages: []remixerMessage{{Role: "user", Content: "Hello"}}})
+
+			var filterErr *ContentFilterError
+			if !errors.As(err, &filterErr) {
+				t.Fatalf("expected a *ContentFilterError, got %v", err)
+			}
+			want := "violence (medium), jailbreak"
+			if !tc.wantAPIErr {
+				want = "violence (high), jailbreak"
+			}
+			if strings.Join(filterErr.Categories, ", ") != want {
+				t.Errorf("categories = %v, want %s", filterErr.Categories, want)
+			}
+			if (filterErr.Err != nil) != tc.wantAPIErr {
+				t.Errorf("wrapped API error = %v", filterErr.Err)
+			}
+			if isTransient(err) {
+				t.Error("a filtered prompt should not be retried")
+			}
+		})
+	}
+}
+
+func TestLoadRemixerConfigAzureOpenAI(t *testing.T) {
+	cfg, err := loadRemixerConfig(writeTempRemixerConfig(t, `
+models:
+  - name: "azure"
+    weight: 1
+    providers:
+      - type: "azure-openai"
+        endpoint: "https://fuzz.openai.azure.com"
+        deployment: "fuzz-gpt4o"
+        api_key: "azure-key"
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	provider := cfg.Models[0].Providers[0]
+	if provider.Model != "fuzz-gpt4o" || provider.APIVersion != defaultAzureAPIVersion {
+		t.Errorf("model = %q, api_version = %q; want the deployment and the default version", provider.Model, provider.APIVersion)
+	}
+
+	for name, content := range map[string]string{
+		"missing deployment":   "      - type: azure-openai\n        endpoint: https://e\n        model: m\n        api_key: k\n",
+		"deployment on openai": "      - type: openai\n        endpoint: https://e\n        model: m\n        api_key: k\n        deployment: d\n",
+		"protocol on azure":    "      - type: azure-openai\n        endpoint: https://e\n        deployment: d\n        api_key: k\n        protocol: responses\n",
+	} {
+		if _, err := loadRemixerConfig(writeTempRemixerConfig(t, "models:\n  - name: m\n    weight: 1\n    providers:\n"+content)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const geminiAPIKeyHeader = "x-goog-api-key"
+
+type geminiProvider struct {
+	httpClient  *http.Client
+	apiKey      string
+	baseURL     string
+	model       string
+	temperature *float64
+	maxTokens   int
+}
+
+type geminiGenerateRequest struct {
+	SystemInstruction *geminiContent         `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent        `json:"contents"`
+	GenerationConfig  geminiGenerationConfig `json:"generationConfig"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"topP,omitempty"`
+	MaxOutputTokens  int      `json:"maxOutputTokens,omitempty"`
+	ResponseMIMEType string   `json:"responseMimeType,omitempty"`
+}
+
+type geminiGenerateResponse struct {
+	Candidates []struct {
+		Content       geminiContent `json:"content"`
+		FinishReason  string        `json:"finishReason"`
+		FinishMessage string        `json:"finishMessage"`
+	} `json:"candidates"`
+	PromptFeedback *struct {
+		BlockReason        string `json:"blockReason"`
+		BlockReasonMessage string `json:"blockReasonMessage"`
+	} `json:"promptFeedback"`
+	UsageMetadata struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+	} `json:"usageMetadata"`
+	ModelVersion string `json:"modelVersion"`
+}
+
+type geminiErrorEnvelope struct {
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error"`
+}
+
+func newGeminiProvider(cfg remixerProviderConfig) (*geminiProvider, error) {
+	baseURL, err := normalizeGeminiBaseURL(cfg.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient, err := newHTTPClient(cfg, cfg.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &geminiProvider{
+		httpClient:  httpClient,
+		apiKey:      cfg.APIKey,
+		baseURL:     baseURL,
+		model:       cfg.Model,
+		temperature: cfg.Temperature,
+		maxTokens:   cfg.MaxTokens,
+	}, nil
+}
+
+func (p *geminiProvider) Chat(ctx context.Context, req remixerChatRequest) (remixerChatResponse, error) {
+	genReq := geminiGenerateRequest{
+		GenerationConfig: geminiGenerationConfig{
+			Temperature:     req.Temperature,
+			TopP:            req.TopP,
+			MaxOutputTokens: p.maxTokens,
+		},
+	}
+	if p.temperature != nil {
+		genReq.GenerationConfig.Temperature = p.temperature
+	}
+	if req.MaxTokens != nil {
+		genReq.GenerationConfig.MaxOutputTokens = *req.MaxTokens
+	}
+	switch format := req.ResponseFormat.normalized(); format.Type {
+	case FormatJSONObject:
+		genReq.GenerationConfig.ResponseMIMEType = "application/json"
+	case FormatJSONSchema:
+		return remixerChatResponse{}, unsupportedFormat("gemini", format)
+	}
+
+	var system []geminiPart
+	for _, m := range req.Messages {
+		switch m.Role {
+		case "system":
+			system = append(system, geminiPart{Text: m.Content})
+		case "user":
+			genReq.Contents = append(genReq.Contents, geminiContent{Role: "user", Parts: []geminiPart{{Text: m.Content}}})
+		case "assistant":
+			genReq.Contents = append(genReq.Contents, geminiContent{Role: "model", Parts: []geminiPart{{Text: m.Content}}})
+		default:
+			return remixerChatResponse{}, fmt.Errorf("unsupported message role: %s", m.Role)
+		}
+	}
+	if len(genReq.Contents) == 0 {
+		return remixerChatResponse{}, fmt.Errorf("at least one non-system message is required")
+	}
+	if len(system) > 0 {
+		genReq.SystemInstruction = &geminiContent{Parts: system}
+	}
+
+	body, err := json.Marshal(genReq)
+	if err != nil {
+		return remixerChatResponse{}, fmt.Errorf("gemini generateContent: marshal request: %w", err)
+	}
+
+	endpoint := p.baseURL + "/models/" + url.PathEscape(p.model) + ":generateContent"
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return remixerChatResponse{}, fmt.Errorf("gemini generateContent: build request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set(geminiAPIKeyHeader, p.apiKey)
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return remixerChatResponse{}, fmt.Errorf("gemini generateContent: %w", err)
+	}
+	defer resp.Body.Close()
+
+	payload, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return remixerChatResponse{}, fmt.Errorf("gemini generateContent: read response: %w", err)
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		message := geminiErrorMessage(payload)
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return remixerChatResponse{}, newRateLimitError("gemini generateContent", resp.Header, errors.New(message))
+		}
+		return remixerChatResponse{}, &StatusError{Provider: "gemini generateContent", StatusCode: resp.StatusCode, Message: message}
+	}
+
+	var genResp geminiGenerateResponse
+	if err := json.Unmarshal(payload, &genResp); err != nil {
+		return remixerChatResponse{}, fmt.Errorf("gemini generateContent: decode response: %w", err)
+	}
+
+	content, err := extractGeminiText(genResp)
+	if err != nil {
+		return remixerChatResponse{}, fmt.Errorf("gemini generateContent: %w", err)
+	}
+
+	model := genResp.ModelVersion
+	if model == "" {
+		model = p.model
+	}
+
+	return remixerChatResponse{
+		Content:          content,
+		Model:            model,
+		PromptTokens:     genResp.UsageMetadata.PromptTokenCount,
+		CompletionTokens: genResp.UsageMetadata.CandidatesTokenCount,
+	}, nil
+}
+
+// extractGeminiText joins the text parts of the first candidate. A blocked
+// prompt, or a candidate that stopped for a reason other than STOP or
+// MAX_TOKENS (SAFETY, RECITATION, OTHER, ...), is an error naming the
+// reason so the caller can log why the generation was dropped.
+func extractGeminiText(resp geminiGenerateResponse) (string, error) {
+	if len(resp.Candidates) == 0 {
+		if fb := resp.PromptFeedback; fb != nil && fb.BlockReason != "" {
+			return "", fmt.Errorf("prompt blocked (%s)%s", fb.BlockReason, geminiReasonDetail(fb.BlockReasonMessage))
+		}
+		return "", fmt.Errorf("no candidates returned")
+	}
+
+	candidate := resp.Candidates[0]
+	switch candidate.FinishReason {
+	case "", "STOP", "MAX_TOKENS", "FINISH_REASON_UNSPECIFIED":
+	default:
+		return "", fmt.Errorf("generation stopped (finishReason %s)%s", candidate.FinishReason, geminiReasonDetail(candidate.FinishMessage))
+	}
+
+	var parts []string
+	for _, part := range candidate.Content.Parts {
+		parts = append(parts, part.Text)
+	}
+	content := strings.Join(parts, "")
+	if strings.TrimSpace(content) == "" {
+		return "", fmt.Errorf("no text content returned (finishReason %s)", candidate.FinishReason)
+	}
+	return content, nil
+}
+
+func geminiReasonDetail(message string) string {
+	if message == "" {
+		return ""
+	}
+	return ": " + message
+}
+
+// geminiErrorMessage extracts "STATUS: message" from an error body, falling
+// back to the raw body.
+func geminiErrorMessage(payload []byte) string {
+	var errResp geminiErrorEnvelope
+	if err := json.Unmarshal(payload, &errResp); err != nil || errResp.Error == nil || errResp.Error.Message == "" {
+		return strings.TrimSpace(string(payload))
+	}
+	if errResp.Error.Status != "" {
+		return errResp.Error.Status + ": " + errResp.Error.Message
+	}
+	return errResp.Error.Message
+}
+
+// normalizeGeminiBaseURL accepts the API host ("https://generativelanguage.googleapis.com")
+// or a versioned base URL, and returns the versioned base (v1beta by default).
+func normalizeGeminiBaseURL(endpoint string) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(endpoint))
+	if err != nil {
+		return "", fmt.Errorf("gemini provider: parse endpoint: %w", err)
+	}
+	if parsed.Scheme == "" || parsed.Host == "" {
+		return "", fmt.Errorf("gemini provider: invalid endpoint %q", endpoint)
+	}
+
+	path := strings.TrimRight(parsed.Path, "/")
+	if i := strings.Index(path, "/models/"); i >= 0 {
+		path = path[:i]
+	}
+	if !strings.HasSuffix(path, "/v1beta") && !strings.HasSuffix(path, "/v1") {
+		path += "/v1beta"
+	}
+
+	parsed.Path = path
+	parsed.RawPath = ""
+	parsed.RawQuery = ""
+	return strings.TrimRight(parsed.String(), "/"), nil
+}
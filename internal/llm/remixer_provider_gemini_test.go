@@ -0,0 +1,164 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newGeminiFixtureServer replies to every request with the recorded
+// response testdata/gemini/<fixture>.json and the given status.
+func newGeminiFixtureServer(t *testing.T, fixture string, status int, onRequest func(*http.Request)) *httptest.Server {
+	t.Helper()
+
+	payload, err := os.ReadFile(filepath.Join("testdata", "gemini", fixture+".json"))
+	if err != nil {
+		t.Fatalf("reading fixture: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if onRequest != nil {
+			onRequest(r)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "7")
+		}
+		w.WriteHeader(status)
+		w.Write(payload)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func testGeminiProvider(t *testing.T, endpoint string) *geminiProvider {
+	t.Helper()
+
+	temperature := 0.6
+	p, err := newGeminiProvider(remixerProviderConfig{
+		Type:        "gemini",
+		Endpoint:    endpoint,
+		Model:       "gemini-2.0-flash",
+		APIKey:      "test-gemini-key",
+		Temperature: &temperature,
+		MaxTokens:   4096,
+	})
+	if err != nil {
+		t.Fatalf("creating provider: %v", err)
+	}
+	return p
+}
+
+func TestGeminiProviderChat(t *testing.T) {
+	var got geminiGenerateRequest
+	srv := newGeminiFixtureServer(t, "ok", http.StatusOK, func(r *http.Request) {
+		if r.URL.Path != "/v1beta/models/gemini-2.0-flash:generateContent" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if key := r.Header.Get("x-goog-api-key"); key != "test-gemini-key" {
+			t.Errorf("unexpected API key header: %q", key)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding request body: %v", err)
+		}
+	})
+
+	resp, err := testGeminiProvider(t, srv.URL).Chat(context.Background(), remixerChatRequest{
+		Messages: []remixerMessage{
+			{Role: "system", Content: "You write C programs."},
+			{Role: "user", Content: "Write a program."},
+		},
+	})
+	if err != nil {
+		t.Fatalf("chat error: %v", err)
+	}
+	if resp.Content != "```c\nint main(void) {\n  return 0;\n}\n```" {
+		t.Errorf("unexpected content: %q", resp.Content)
+	}
+	if resp.Model != "gemini-2.0-flash" {
+		t.Errorf("unexpected model: %q", resp.Model)
+	}
+
+	if got.SystemInstruction == nil || got.SystemInstruction.Parts[0].Text != "You write C programs." {
+		t.Errorf("expected the system prompt in systemInstruction, got %+v", got.SystemInstruction)
+	}
+	if len(got.Contents) != 1 || got.Contents[0].Role != "user" || got.Contents[0].Parts[0].Text != "Write a program." {
+		t.Errorf("unexpected contents: %+v", got.Contents)
+	}
+	cfg := got.GenerationConfig
+	if cfg.Temperature == nil || *cfg.Temperature != 0.6 || cfg.MaxOutputTokens != 4096 {
+		t.Errorf("unexpected generationConfig: %+v", cfg)
+	}
+}
+
+func TestGeminiProviderBlockedResponses(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    []string
+	}{
+		{"safety", []string{"finishReason SAFETY"}},
+		{"other", []string{"finishReason OTHER", "Malformed function call."}},
+		{"prompt_blocked", []string{"prompt blocked", "PROHIBITED_CONTENT"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			srv := newGeminiFixtureServer(t, tt.fixture, http.StatusOK, nil)
+			_, err := testGeminiProvider(t, srv.URL).Chat(context.Background(), remixerChatRequest{
+				Messages: []remixerMessage{{Role: "user", Content: "Hello"}},
+			})
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q should contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGeminiProviderErrors(t *testing.T) {
+	srv := newGeminiFixtureServer(t, "error_400", http.StatusBadRequest, nil)
+	_, err := testGeminiProvider(t, srv.URL).Chat(context.Background(), remixerChatRequest{
+		Messages: []remixerMessage{{Role: "user", Content: "Hello"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "INVALID_ARGUMENT") || !strings.Contains(err.Error(), "API key not valid") {
+		t.Errorf("expected the API error in the message, got %v", err)
+	}
+
+	srv = newGeminiFixtureServer(t, "error_400", http.StatusTooManyRequests, nil)
+	_, err = testGeminiProvider(t, srv.URL).Chat(context.Background(), remixerChatRequest{
+		Messages: []remixerMessage{{Role: "user", Content: "Hello"}},
+	})
+	var rateErr *RateLimitError
+	if !errors.As(err, &rateErr) || rateErr.RetryAfter != 7*time.Second {
+		t.Errorf("expected a RateLimitError with RetryAfter 7s, got %v", err)
+	}
+}
+
+func TestNormalizeGeminiBaseURL(t *testing.T) {
+	tests := map[string]string{
+		"https://generativelanguage.googleapis.com":                                          "https://generativelanguage.googleapis.com/v1beta",
+		"https://generativelanguage.googleapis.com/v1":                                       "https://generativelanguage.googleapis.com/v1",
+		"https://generativelanguage.googleapis.com/v1beta/":                                  "https://generativelanguage.googleapis.com/v1beta",
+		"https://generativelanguage.googleapis.com/v1beta/models/gemini-pro:generateContent": "https://generativelanguage.googleapis.com/v1beta",
+	}
+	for endpoint, want := range tests {
+		got, err := normalizeGeminiBaseURL(endpoint)
+		if err != nil {
+			t.Fatalf("normalizeGeminiBaseURL(%q) failed: %v", endpoint, err)
+		}
+		if got != want {
+			t.Errorf("normalizeGeminiBaseURL(%q) = %q, want %q", endpoint, got, want)
+		}
+	}
+}
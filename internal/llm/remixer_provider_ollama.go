@@ -0,0 +1,192 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	ollamaChatPath = "/api/chat"
+
+	// defaultOllamaTimeout bounds a request when the config sets none. Local
+	// models on CPU can need several minutes for a long prompt.
+	defaultOllamaTimeout = 10 * time.Minute
+)
+
+type ollamaProvider struct {
+	httpClient  *http.Client
+	apiKey      string
+	baseURL     string
+	model       string
+	temperature *float64
+	maxTokens   int
+}
+
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Format   json.RawMessage `json:"format,omitempty"`
+	Options  *ollamaOptions  `json:"options,omitempty"`
+}
+
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ollamaOptions struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	NumPredict  int      `json:"num_predict,omitempty"`
+}
+
+type ollamaChatResponse struct {
+	Model           string        `json:"model"`
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	Error           string        `json:"error"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+}
+
+func newOllamaProvider(cfg remixerProviderConfig) (*ollamaProvider, error) {
+	baseURL, err := normalizeOllamaBaseURL(cfg.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = defaultOllamaTimeout
+	}
+
+	httpClient, err := newHTTPClient(cfg, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ollamaProvider{
+		httpClient:  httpClient,
+		apiKey:      cfg.APIKey,
+		baseURL:     baseURL,
+		model:       cfg.Model,
+		temperature: cfg.Temperature,
+		maxTokens:   cfg.MaxTokens,
+	}, nil
+}
+
+func (p *ollamaProvider) Chat(ctx context.Context, req remixerChatRequest) (remixerChatResponse, error) {
+	chatReq := ollamaChatRequest{
+		Model:    p.model,
+		Messages: make([]ollamaMessage, 0, len(req.Messages)),
+	}
+	for _, message := range req.Messages {
+		chatReq.Messages = append(chatReq.Messages, ollamaMessage{Role: message.Role, Content: message.Content})
+	}
+
+	options := ollamaOptions{Temperature: req.Temperature, TopP: req.TopP, NumPredict: p.maxTokens}
+	if p.temperature != nil {
+		options.Temperature = p.temperature
+	}
+	if req.MaxTokens != nil {
+		options.NumPredict = *req.MaxTokens
+	}
+	if options.Temperature != nil || options.TopP != nil || options.NumPredict > 0 {
+		chatReq.Options = &options
+	}
+	// Ollama takes "json" or a JSON schema.
+	switch format := req.ResponseFormat.normalized(); format.Type {
+	case FormatJSONObject:
+		chatReq.Format = json.RawMessage(`"json"`)
+	case FormatJSONSchema:
+		chatReq.Format = format.Schema
+	}
+
+	body, err := json.Marshal(chatReq)
+	if err != nil {
+		return remixerChatResponse{}, fmt.Errorf("ollama chat: marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+ollamaChatPath, bytes.NewReader(body))
+	if err != nil {
+		return remixerChatResponse{}, fmt.Errorf("ollama chat: build request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return remixerChatResponse{}, fmt.Errorf("ollama chat: %w", err)
+	}
+	defer resp.Body.Close()
+
+	payload, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return remixerChatResponse{}, fmt.Errorf("ollama chat: read response: %w", err)
+	}
+
+	var chatResp ollamaChatResponse
+	decodeErr := json.Unmarshal(payload, &chatResp)
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		message := strings.TrimSpace(string(payload))
+		if decodeErr == nil && chatResp.Error != "" {
+			message = chatResp.Error
+		}
+		if resp.StatusCode == http.StatusNotFound && strings.Contains(message, "not found") {
+			return remixerChatResponse{}, fmt.Errorf("ollama chat: model %q not found on %s (run `ollama pull %s`): %s", p.model, p.baseURL, p.model, message)
+		}
+		return remixerChatResponse{}, &StatusError{Provider: "ollama chat", StatusCode: resp.StatusCode, Message: message}
+	}
+	if decodeErr != nil {
+		return remixerChatResponse{}, fmt.Errorf("ollama chat: decode response: %w", decodeErr)
+	}
+	if chatResp.Error != "" {
+		return remixerChatResponse{}, fmt.Errorf("ollama chat: %s", chatResp.Error)
+	}
+	if strings.TrimSpace(chatResp.Message.Content) == "" {
+		return remixerChatResponse{}, fmt.Errorf("ollama chat: no content returned")
+	}
+
+	model := chatResp.Model
+	if model == "" {
+		model = p.model
+	}
+
+	return remixerChatResponse{
+		Content:          chatResp.Message.Content,
+		Model:            model,
+		PromptTokens:     chatResp.PromptEvalCount,
+		CompletionTokens: chatResp.EvalCount,
+	}, nil
+}
+
+// normalizeOllamaBaseURL accepts the server root ("http://localhost:11434")
+// or a URL ending in /api or /api/chat, and returns the server root.
+func normalizeOllamaBaseURL(endpoint string) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(endpoint))
+	if err != nil {
+		return "", fmt.Errorf("ollama provider: parse endpoint: %w", err)
+	}
+	if parsed.Scheme == "" || parsed.Host == "" {
+		return "", fmt.Errorf("ollama provider: invalid endpoint %q", endpoint)
+	}
+
+	path := strings.TrimRight(parsed.Path, "/")
+	path = strings.TrimSuffix(path, ollamaChatPath)
+	path = strings.TrimSuffix(path, "/api")
+
+	parsed.Path = strings.TrimRight(path, "/")
+	parsed.RawPath = ""
+	return strings.TrimRight(parsed.String(), "/"), nil
+}
@@ -0,0 +1,136 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOllamaProviderChat(t *testing.T) {
+	var got ollamaChatRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/chat" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"model":"qwen2.5-coder:7b","message":{"role":"assistant","content":"Hello from ollama!"},"done":true}`)
+	}))
+	defer srv.Close()
+
+	temperature := 0.3
+	p, err := newOllamaProvider(remixerProviderConfig{
+		Type:        "ollama",
+		Endpoint:    srv.URL + "/api/chat",
+		Model:       "qwen2.5-coder:7b",
+		Temperature: &temperature,
+		MaxTokens:   1024,
+	})
+	if err != nil {
+		t.Fatalf("creating provider: %v", err)
+	}
+
+	resp, err := p.Chat(context.Background(), remixerChatRequest{
+		Messages: []remixerMessage{
+			{Role: "system", Content: "Be helpful"},
+			{Role: "user", Content: "Hello"},
+		},
+		ResponseFormat: jsonObjectFormat,
+	})
+	if err != nil {
+		t.Fatalf("chat error: %v", err)
+	}
+	if resp.Content != "Hello from ollama!" || resp.Model != "qwen2.5-coder:7b" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	if got.Stream {
+		t.Error("expected a non-streaming request")
+	}
+	if len(got.Messages) != 2 || got.Messages[0].Role != "system" || got.Messages[1].Content != "Hello" {
+		t.Errorf("unexpected messages: %+v", got.Messages)
+	}
+	if got.Options == nil || got.Options.Temperature == nil || *got.Options.Temperature != 0.3 || got.Options.NumPredict != 1024 {
+		t.Errorf("unexpected options: %+v", got.Options)
+	}
+	if string(got.Format) != `"json"` {
+		t.Errorf("expected format json, got %s", got.Format)
+	}
+}
+
+func TestOllamaProviderModelNotFound(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, `{"error":"model \"llama9\" not found, try pulling it first"}`)
+	}))
+	defer srv.Close()
+
+	p, err := newOllamaProvider(remixerProviderConfig{Type: "ollama", Endpoint: srv.URL, Model: "llama9"})
+	if err != nil {
+		t.Fatalf("creating provider: %v", err)
+	}
+
+	_, err = p.Chat(context.Background(), remixerChatRequest{
+		Messages: []remixerMessage{{Role: "user", Content: "Hello"}},
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !strings.Contains(err.Error(), `model "llama9" not found`) || !strings.Contains(err.Error(), "ollama pull llama9") {
+		t.Errorf("error should name the missing model and how to fetch it: %v", err)
+	}
+}
+
+func TestOllamaProviderTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	p, err := newOllamaProvider(remixerProviderConfig{
+		Type:     "ollama",
+		Endpoint: srv.URL,
+		Model:    "slow-model",
+		Timeout:  50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("creating provider: %v", err)
+	}
+
+	if _, err := p.Chat(context.Background(), remixerChatRequest{
+		Messages: []remixerMessage{{Role: "user", Content: "Hello"}},
+	}); err == nil {
+		t.Fatal("expected a timeout error")
+	}
+}
+
+func TestNormalizeOllamaBaseURL(t *testing.T) {
+	for _, endpoint := range []string{
+		"http://localhost:11434",
+		"http://localhost:11434/",
+		"http://localhost:11434/api",
+		"http://localhost:11434/api/chat",
+	} {
+		got, err := normalizeOllamaBaseURL(endpoint)
+		if err != nil {
+			t.Fatalf("normalizeOllamaBaseURL(%q) failed: %v", endpoint, err)
+		}
+		if got != "http://localhost:11434" {
+			t.Errorf("normalizeOllamaBaseURL(%q) = %q", endpoint, got)
+		}
+	}
+
+	if _, err := normalizeOllamaBaseURL("localhost:11434"); err == nil {
+		t.Error("expected an error for an endpoint without a scheme")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -18,6 +19,7 @@ const (
 	openAIChatCompletionsPath   = "/chat/completions"
 	openAIResponsesPath         = "/responses"
 	defaultResponsesInstruction = "You are a helpful assistant."
+	openAIOrganizationHeader    = "OpenAI-Organization"
 )
 
 type openAIProvider struct {
@@ -27,6 +29,11 @@ type openAIProvider struct {
 	baseURL    string
 	model      string
 	protocol   string
+
+	temperature    *float64
+	maxTokens      int
+	organization   string
+	maxTokensField string
 }
 
 type openAIResponsesRequest struct {
@@ -34,6 +41,19 @@ type openAIResponsesRequest struct {
 	Instructions string                        `json:"instructions,omitempty"`
 	Input        []openAIResponsesInputMessage `json:"input,omitempty"`
 	Temperature  *float64                      `json:"temperature,omitempty"`
+	TopP         *float64                      `json:"top_p,omitempty"`
+	Text         *openAIResponsesText          `json:"text,omitempty"`
+}
+
+type openAIResponsesText struct {
+	Format openAIResponsesFormat `json:"format"`
+}
+
+type openAIResponsesFormat struct {
+	Type   string          `json:"type"`
+	Name   string          `json:"name,omitempty"`
+	Schema json.RawMessage `json:"schema,omitempty"`
+	Strict *bool           `json:"strict,omitempty"`
 }
 
 type openAIResponsesInputMessage struct {
@@ -50,6 +70,10 @@ type openAIResponsesResponse struct {
 	Model      string                  `json:"model"`
 	Output     []openAIResponsesOutput `json:"output"`
 	OutputText json.RawMessage         `json:"output_text"`
+	Usage      struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
 }
 
 type openAIResponsesOutput struct {
@@ -79,16 +103,32 @@ func newOpenAIProvider(cfg remixerProviderConfig) (*openAIProvider, error) {
 		protocol = openAIProtocolAuto
 	}
 
+	maxTokensField := cfg.MaxTokensField
+	if maxTokensField == "" || maxTokensField == openAIMaxTokensFieldAuto {
+		maxTokensField = defaultOpenAIMaxTokensField(cfg.Model)
+	}
+
+	httpClient, err := newHTTPClient(cfg, cfg.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
 	openAIConfig := openai.DefaultConfig(cfg.APIKey)
 	openAIConfig.BaseURL = baseURL
+	openAIConfig.OrgID = cfg.Organization
+	openAIConfig.HTTPClient = httpClient
 
 	return &openAIProvider{
-		client:     openai.NewClientWithConfig(openAIConfig),
-		httpClient: http.DefaultClient,
-		apiKey:     cfg.APIKey,
-		baseURL:    baseURL,
-		model:      cfg.Model,
-		protocol:   protocol,
+		client:         openai.NewClientWithConfig(openAIConfig),
+		httpClient:     httpClient,
+		apiKey:         cfg.APIKey,
+		baseURL:        baseURL,
+		model:          cfg.Model,
+		protocol:       protocol,
+		temperature:    cfg.Temperature,
+		maxTokens:      cfg.MaxTokens,
+		organization:   cfg.Organization,
+		maxTokensField: maxTokensField,
 	}, nil
 }
 
@@ -104,6 +144,95 @@ func (p *openAIProvider) Chat(ctx context.Context, req remixerChatRequest) (remi
 }
 
 func (p *openAIProvider) chatCompletions(ctx context.Context, req remixerChatRequest) (remixerChatResponse, error) {
+	openAIRequest, maxTokens := p.chatCompletionRequest(req)
+	resp, err := p.client.CreateChatCompletion(ctx, openAIRequest)
+	if err != nil && maxTokens > 0 && p.maxTokensField == openAIMaxTokensFieldLegacy && wantsMaxCompletionTokens(err) {
+		// Newer models reject max_tokens outright; retry once with the
+		// field the server asked for.
+		p.setMaxTokens(&openAIRequest, openAIMaxTokensFieldCompletion, maxTokens)
+		resp, err = p.client.CreateChatCompletion(ctx, openAIRequest)
+	}
+	if err != nil {
+		return remixerChatResponse{}, wrapOpenAIClientError("openai chat completion", err)
+	}
+	if len(resp.Choices) == 0 {
+		return remixerChatResponse{}, fmt.Errorf("openai chat completion: no choices returned")
+	}
+	if choice := resp.Choices[0]; choice.FinishReason == openai.FinishReasonContentFilter && choice.Message.Content == "" {
+		return remixerChatResponse{}, &ContentFilterError{
+			Provider:   "openai chat completion",
+			Categories: filteredCategories(choice.ContentFilterResults),
+		}
+	}
+
+	return remixerChatResponse{
+		Content:          resp.Choices[0].Message.Content,
+		Model:            resp.Model,
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+	}, nil
+}
+
+// ChatStream streams a chat_completions reply over SSE, passing each content
+// delta to onDelta. When onDelta returns false the stream is closed and the
+// text received so far is returned. The responses protocol is not streamed:
+// its whole reply is passed to onDelta at once.
+func (p *openAIProvider) ChatStream(ctx context.Context, req remixerChatRequest, onDelta func(string) bool) (remixerChatResponse, error) {
+	if p.protocol == openAIProtocolResponses {
+		resp, err := p.responses(ctx, req)
+		if err == nil {
+			onDelta(resp.Content)
+		}
+		return resp, err
+	}
+
+	openAIRequest, maxTokens := p.chatCompletionRequest(req)
+	openAIRequest.Stream = true
+	openAIRequest.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+	stream, err := p.client.CreateChatCompletionStream(ctx, openAIRequest)
+	if err != nil && maxTokens > 0 && p.maxTokensField == openAIMaxTokensFieldLegacy && wantsMaxCompletionTokens(err) {
+		p.setMaxTokens(&openAIRequest, openAIMaxTokensFieldCompletion, maxTokens)
+		stream, err = p.client.CreateChatCompletionStream(ctx, openAIRequest)
+	}
+	if err != nil {
+		return remixerChatResponse{}, wrapOpenAIClientError("openai chat completion stream", err)
+	}
+	defer stream.Close()
+
+	var resp remixerChatResponse
+	var content strings.Builder
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			resp.Content = content.String()
+			return resp, wrapOpenAIClientError("openai chat completion stream", err)
+		}
+		if chunk.Model != "" {
+			resp.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			resp.PromptTokens = chunk.Usage.PromptTokens
+			resp.CompletionTokens = chunk.Usage.CompletionTokens
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+		delta := chunk.Choices[0].Delta.Content
+		content.WriteString(delta)
+		if !onDelta(delta) {
+			break
+		}
+	}
+	resp.Content = content.String()
+	return resp, nil
+}
+
+// chatCompletionRequest builds the chat_completions request for req and
+// returns the max token count it carries.
+func (p *openAIProvider) chatCompletionRequest(req remixerChatRequest) (openai.ChatCompletionRequest, int) {
 	messages := make([]openai.ChatCompletionMessage, 0, len(req.Messages))
 	for _, message := range req.Messages {
 		messages = append(messages, openai.ChatCompletionMessage{
@@ -116,25 +245,30 @@ func (p *openAIProvider) chatCompletions(ctx context.Context, req remixerChatReq
 		Model:    p.model,
 		Messages: messages,
 	}
-	if req.Temperature != nil {
-		openAIRequest.Temperature = float32(*req.Temperature)
+	if temperature := p.requestTemperature(req); temperature != nil {
+		openAIRequest.Temperature = float32(*temperature)
 	}
-	if req.MaxTokens != nil {
-		openAIRequest.MaxTokens = *req.MaxTokens
-	}
-
-	resp, err := p.client.CreateChatCompletion(ctx, openAIRequest)
-	if err != nil {
-		return remixerChatResponse{}, fmt.Errorf("openai chat completion: %w", err)
+	if req.TopP != nil {
+		openAIRequest.TopP = float32(*req.TopP)
 	}
-	if len(resp.Choices) == 0 {
-		return remixerChatResponse{}, fmt.Errorf("openai chat completion: no choices returned")
+	maxTokens := p.requestMaxTokens(req)
+	p.setMaxTokens(&openAIRequest, p.maxTokensField, maxTokens)
+	switch format := p.responseFormat(req); format.Type {
+	case FormatJSONObject:
+		openAIRequest.ResponseFormat = &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+		}
+	case FormatJSONSchema:
+		openAIRequest.ResponseFormat = &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+			JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+				Name:   format.Name,
+				Schema: format.Schema,
+				Strict: true,
+			},
+		}
 	}
-
-	return remixerChatResponse{
-		Content: resp.Choices[0].Message.Content,
-		Model:   resp.Model,
-	}, nil
+	return openAIRequest, maxTokens
 }
 
 func (p *openAIProvider) responses(ctx context.Context, req remixerChatRequest) (remixerChatResponse, error) {
@@ -152,10 +286,21 @@ func (p *openAIProvider) responses(ctx context.Context, req remixerChatRequest)
 	// The current OpenAI-compatible gateway streams responses and rejects
 	// max_output_tokens, while DeFuzz does not depend on hard output caps here.
 
-	// GPT-5 style Responses backends commonly reject temperature overrides, so
-	// we omit them on that family to keep the compatibility path stable.
-	if req.Temperature != nil && allowsResponsesTemperature(p.model) {
-		responseReq.Temperature = req.Temperature
+	// GPT-5 style Responses backends commonly reject temperature and top_p
+	// overrides, so we omit them on that family to keep the compatibility
+	// path stable.
+	if allowsResponsesTemperature(p.model) {
+		responseReq.Temperature = p.requestTemperature(req)
+		responseReq.TopP = req.TopP
+	}
+	if format := p.responseFormat(req); format.IsJSON() {
+		responseReq.Text = &openAIResponsesText{Format: openAIResponsesFormat{Type: format.Type}}
+		if format.Type == FormatJSONSchema {
+			strict := true
+			responseReq.Text.Format.Name = format.Name
+			responseReq.Text.Format.Schema = format.Schema
+			responseReq.Text.Format.Strict = &strict
+		}
 	}
 
 	body, err := json.Marshal(responseReq)
@@ -174,6 +319,9 @@ func (p *openAIProvider) responses(ctx context.Context, req remixerChatRequest)
 	}
 	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
 	httpReq.Header.Set("Content-Type", "application/json")
+	if p.organization != "" {
+		httpReq.Header.Set(openAIOrganizationHeader, p.organization)
+	}
 
 	resp, err := p.httpClient.Do(httpReq)
 	if err != nil {
@@ -206,11 +354,135 @@ func (p *openAIProvider) responses(ctx context.Context, req remixerChatRequest)
 	}
 
 	return remixerChatResponse{
-		Content: content,
-		Model:   model,
+		Content:          content,
+		Model:            model,
+		PromptTokens:     response.Usage.InputTokens,
+		CompletionTokens: response.Usage.OutputTokens,
 	}, nil
 }
 
+// requestTemperature returns the provider's configured temperature, falling
+// back to the one the client asked for.
+func (p *openAIProvider) requestTemperature(req remixerChatRequest) *float64 {
+	if p.temperature != nil {
+		return p.temperature
+	}
+	return req.Temperature
+}
+
+// requestMaxTokens returns the reply cap for req, 0 meaning no cap. A cap set
+// on the request wins over the provider's max_tokens.
+func (p *openAIProvider) requestMaxTokens(req remixerChatRequest) int {
+	if req.MaxTokens != nil {
+		return *req.MaxTokens
+	}
+	return p.maxTokens
+}
+
+// setMaxTokens puts maxTokens in the chat-completions field named by field
+// and clears the other one.
+func (p *openAIProvider) setMaxTokens(req *openai.ChatCompletionRequest, field string, maxTokens int) {
+	req.MaxTokens, req.MaxCompletionTokens = 0, 0
+	if field == openAIMaxTokensFieldCompletion {
+		req.MaxCompletionTokens = maxTokens
+	} else {
+		req.MaxTokens = maxTokens
+	}
+}
+
+// defaultOpenAIMaxTokensField picks the token-cap field for max_tokens_field
+// "auto". Reasoning model families only accept max_completion_tokens.
+func defaultOpenAIMaxTokensField(model string) string {
+	model = strings.ToLower(strings.TrimSpace(model))
+	for _, prefix := range []string{"o1", "o3", "o4", "gpt-5"} {
+		if strings.HasPrefix(model, prefix) {
+			return openAIMaxTokensFieldCompletion
+		}
+	}
+	return openAIMaxTokensFieldLegacy
+}
+
+// wantsMaxCompletionTokens reports whether err is a 400 telling the client to
+// send max_completion_tokens instead of max_tokens.
+func wantsMaxCompletionTokens(err error) bool {
+	var apiErr *openai.APIError
+	return errors.As(err, &apiErr) &&
+		apiErr.HTTPStatusCode == http.StatusBadRequest &&
+		strings.Contains(apiErr.Message, openAIMaxTokensFieldCompletion)
+}
+
+// wrapOpenAIClientError formats a go-openai error like decodeOpenAIError so
+// the HTTP status and error body reach the caller.
+func wrapOpenAIClientError(prefix string, err error) error {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) && isContentFilterError(apiErr) {
+		var categories []string
+		if apiErr.InnerError != nil {
+			categories = filteredCategories(apiErr.InnerError.ContentFilterResults)
+		}
+		return &ContentFilterError{Provider: prefix, Categories: categories, Err: err}
+	}
+	if errors.As(err, &apiErr) {
+		detail := apiErr.Message
+		if apiErr.Type != "" {
+			detail += " (type: " + apiErr.Type + ")"
+		}
+		if apiErr.Param != nil && *apiErr.Param != "" {
+			detail += " (param: " + *apiErr.Param + ")"
+		}
+		return fmt.Errorf("%s: status %d: %s: %w", prefix, apiErr.HTTPStatusCode, detail, err)
+	}
+
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		body := strings.TrimSpace(string(reqErr.Body))
+		if body == "" {
+			body = reqErr.HTTPStatus
+		}
+		return fmt.Errorf("%s: status %d: %s: %w", prefix, reqErr.HTTPStatusCode, body, err)
+	}
+
+	return fmt.Errorf("%s: %w", prefix, err)
+}
+
+// isContentFilterError reports whether apiErr is a prompt rejected by the
+// content filter. Azure answers 400 with code "content_filter" and an
+// innererror naming the policy.
+func isContentFilterError(apiErr *openai.APIError) bool {
+	if code, ok := apiErr.Code.(string); ok && code == "content_filter" {
+		return true
+	}
+	return apiErr.InnerError != nil && apiErr.InnerError.Code == "ResponsibleAIPolicyViolation"
+}
+
+// filteredCategories lists the categories the content filter flagged, with
+// their severity where it has one.
+func filteredCategories(r openai.ContentFilterResults) []string {
+	var categories []string
+	for _, c := range []struct {
+		name     string
+		filtered bool
+		severity string
+	}{
+		{"hate", r.Hate.Filtered, r.Hate.Severity},
+		{"self_harm", r.SelfHarm.Filtered, r.SelfHarm.Severity},
+		{"sexual", r.Sexual.Filtered, r.Sexual.Severity},
+		{"violence", r.Violence.Filtered, r.Violence.Severity},
+		{"jailbreak", r.JailBreak.Filtered, ""},
+		{"profanity", r.Profanity.Filtered, ""},
+	} {
+		if !c.filtered {
+			continue
+		}
+		if c.severity != "" {
+			categories = append(categories, c.name+" ("+c.severity+")")
+		} else {
+			categories = append(categories, c.name)
+		}
+	}
+	return categories
+}
+
 func buildResponsesInput(messages []remixerMessage) (string, []openAIResponsesInputMessage) {
 	systemMessages := make([]string, 0, len(messages))
 	input := make([]openAIResponsesInputMessage, 0, len(messages))
@@ -356,15 +628,15 @@ func decodeResponsesEventStream(payload []byte) (openAIResponsesResponse, error)
 func decodeOpenAIError(prefix string, body io.Reader, statusCode int) error {
 	payload, err := io.ReadAll(body)
 	if err != nil {
-		return fmt.Errorf("%s: status %d", prefix, statusCode)
+		return &StatusError{Provider: prefix, StatusCode: statusCode, Message: http.StatusText(statusCode)}
 	}
 
 	var errResp openAIErrorEnvelope
 	if err := json.Unmarshal(payload, &errResp); err == nil && errResp.Error != nil && errResp.Error.Message != "" {
-		return fmt.Errorf("%s: status %d: %s", prefix, statusCode, errResp.Error.Message)
+		return &StatusError{Provider: prefix, StatusCode: statusCode, Message: errResp.Error.Message}
 	}
 
-	return fmt.Errorf("%s: status %d: %s", prefix, statusCode, strings.TrimSpace(string(payload)))
+	return &StatusError{Provider: prefix, StatusCode: statusCode, Message: strings.TrimSpace(string(payload))}
 }
 
 func normalizeOpenAIBaseURL(endpoint string) (string, error) {
@@ -398,6 +670,16 @@ func normalizeOpenAIBaseURL(endpoint string) (string, error) {
 	return strings.TrimRight(parsed.String(), "/"), nil
 }
 
+// responseFormat is the format to send for req. DeepSeek models have a JSON
+// object mode but no JSON schema one, so a schema is left to the prompt.
+func (p *openAIProvider) responseFormat(req remixerChatRequest) ResponseFormat {
+	format := req.ResponseFormat.normalized()
+	if format.Type == FormatJSONSchema && strings.HasPrefix(strings.ToLower(strings.TrimSpace(p.model)), "deepseek") {
+		return jsonObjectFormat
+	}
+	return format
+}
+
 func allowsResponsesTemperature(model string) bool {
 	return !strings.HasPrefix(strings.ToLower(strings.TrimSpace(model)), "gpt-5")
 }
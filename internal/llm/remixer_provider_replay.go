@@ -0,0 +1,146 @@
+package llm
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+const (
+	replayModeReplay = "replay"
+	replayModeRecord = "record"
+)
+
+// cassetteEntry is one line of a cassette: a completion keyed by the hash
+// of the request messages (the prompt_hash of the audit log).
+type cassetteEntry struct {
+	PromptHash       string `json:"prompt_hash"`
+	Completion       string `json:"completion"`
+	PromptTokens     int    `json:"prompt_tokens,omitempty"`
+	CompletionTokens int    `json:"completion_tokens,omitempty"`
+}
+
+// replayProvider serves completions from a JSONL cassette so tests and
+// offline runs need no API key. In record mode it forwards each request to
+// the upstream provider and appends the reply to the cassette instead.
+//
+// A prompt recorded several times is replayed in recording order, the last
+// reply repeating once they run out. A prompt missing from the cassette is
+// an error, never a silent fallback.
+type replayProvider struct {
+	path     string
+	upstream remixerProvider // nil in replay mode
+
+	mu      sync.Mutex
+	entries map[string][]cassetteEntry
+	served  map[string]int
+}
+
+// newReplayProvider creates a replay provider. In record mode the upstream
+// is the first provider of the model named by cfg.Upstream.
+func newReplayProvider(cfg remixerProviderConfig, models []remixerModelConfig) (*replayProvider, error) {
+	p := &replayProvider{path: cfg.Cassette}
+	if cfg.Mode == replayModeRecord {
+		for _, model := range models {
+			if model.Name == cfg.Upstream {
+				upstream, err := newRemixerProvider(model.Providers[0])
+				if err != nil {
+					return nil, fmt.Errorf("replay upstream %q: %w", cfg.Upstream, err)
+				}
+				p.upstream = upstream
+				return p, nil
+			}
+		}
+		return nil, fmt.Errorf("replay upstream %q: no such model", cfg.Upstream)
+	}
+
+	entries, err := loadCassette(cfg.Cassette)
+	if err != nil {
+		return nil, err
+	}
+	p.entries = entries
+	p.served = make(map[string]int)
+	return p, nil
+}
+
+// loadCassette reads a cassette into entries by prompt hash.
+func loadCassette(path string) (map[string][]cassetteEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cassette: %w", err)
+	}
+	defer f.Close()
+
+	entries := make(map[string][]cassetteEntry)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry cassetteEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.PromptHash == "" {
+			return nil, fmt.Errorf("cassette %s line %d: not a cassette entry", path, line)
+		}
+		entries[entry.PromptHash] = append(entries[entry.PromptHash], entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read cassette %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+func (p *replayProvider) Chat(ctx context.Context, req remixerChatRequest) (remixerChatResponse, error) {
+	hash := promptHash(req.Messages)
+	if p.upstream != nil {
+		return p.record(ctx, hash, req)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	recorded := p.entries[hash]
+	if len(recorded) == 0 {
+		return remixerChatResponse{}, fmt.Errorf("cassette %s has no reply for prompt %s; record it again with mode: record", p.path, hash)
+	}
+	entry := recorded[min(p.served[hash], len(recorded)-1)]
+	p.served[hash]++
+	return remixerChatResponse{
+		Content:          entry.Completion,
+		Model:            "replay",
+		PromptTokens:     entry.PromptTokens,
+		CompletionTokens: entry.CompletionTokens,
+	}, nil
+}
+
+// record forwards req upstream and appends a successful reply to the
+// cassette.
+func (p *replayProvider) record(ctx context.Context, hash string, req remixerChatRequest) (remixerChatResponse, error) {
+	resp, err := p.upstream.Chat(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+	line, err := json.Marshal(cassetteEntry{
+		PromptHash:       hash,
+		Completion:       resp.Content,
+		PromptTokens:     resp.PromptTokens,
+		CompletionTokens: resp.CompletionTokens,
+	})
+	if err != nil {
+		return resp, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	f, err := os.OpenFile(p.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return resp, fmt.Errorf("failed to open cassette: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return resp, fmt.Errorf("failed to write cassette %s: %w", p.path, err)
+	}
+	return resp, nil
+}
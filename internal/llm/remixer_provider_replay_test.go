@@ -0,0 +1,126 @@
+package llm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReplayProviderRecordThenReplay(t *testing.T) {
+	cassette := filepath.Join(t.TempDir(), "cassette.jsonl")
+	upstream := &countingProvider{reply: "first"}
+	recorder := &replayProvider{path: cassette, upstream: upstream}
+
+	hello := remixerChatRequest{Messages: []remixerMessage{{Role: "user", Content: "Hello"}}}
+	bye := remixerChatRequest{Messages: []remixerMessage{{Role: "user", Content: "Bye"}}}
+	for _, step := range []struct {
+		req   remixerChatRequest
+		reply string
+	}{{hello, "first"}, {hello, "second"}, {bye, "later"}} {
+		upstream.reply = step.reply
+		if _, err := recorder.Chat(context.Background(), step.req); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+
+	entries, err := loadCassette(cassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	player := &replayProvider{path: cassette, entries: entries, served: make(map[string]int)}
+	for i, want := range []string{"first", "second", "second"} {
+		resp, err := player.Chat(context.Background(), hello)
+		if err != nil {
+			t.Fatalf("replay %d: %v", i+1, err)
+		}
+		if resp.Content != want {
+			t.Errorf("replay %d = %q, want %q", i+1, resp.Content, want)
+		}
+	}
+	if resp, err := player.Chat(context.Background(), bye); err != nil || resp.Content != "later" {
+		t.Errorf("replay of a second prompt = %q, %v", resp.Content, err)
+	}
+	if upstream.calls != 3 {
+		t.Errorf("upstream called %d times, want 3 (replays must not reach it)", upstream.calls)
+	}
+
+	_, err = player.Chat(context.Background(), remixerChatRequest{Messages: []remixerMessage{{Role: "user", Content: "unseen"}}})
+	if err == nil || !strings.Contains(err.Error(), "no reply for prompt") {
+		t.Errorf("a miss should fail loudly, got %v", err)
+	}
+	if isTransient(err) {
+		t.Error("a miss should not be retried")
+	}
+}
+
+func TestLoadRemixerConfigReplay(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "cassette.jsonl"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	configPath := filepath.Join(dir, "remixer.yaml")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`
+models:
+  - name: "replay"
+    weight: 1
+    providers:
+      - type: "replay"
+        cassette: "cassette.jsonl"
+`)
+	cfg, err := loadRemixerConfig(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	provider := cfg.Models[0].Providers[0]
+	if provider.Cassette != filepath.Join(dir, "cassette.jsonl") || provider.Mode != replayModeReplay {
+		t.Errorf("cassette = %q, mode = %q", provider.Cassette, provider.Mode)
+	}
+	if _, err := newWeightedSelector(cfg.Models); err != nil {
+		t.Errorf("replay provider without an API key should build: %v", err)
+	}
+
+	// Record mode needs an upstream model; weight 0 keeps it out of the
+	// rotation.
+	write(`
+models:
+  - name: "replay"
+    weight: 1
+    providers:
+      - type: "replay"
+        cassette: "cassette.jsonl"
+        mode: "record"
+        upstream: "deepseek"
+  - name: "deepseek"
+    weight: 0
+    providers:
+      - type: "openai"
+        endpoint: "https://api.example.com"
+        model: "deepseek-chat"
+        api_key: "test-key"
+`)
+	if _, err := loadRemixerConfig(configPath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for name, content := range map[string]string{
+		"missing cassette":        "models:\n  - name: r\n    weight: 1\n    providers:\n      - type: replay\n",
+		"record without upstream": "models:\n  - name: r\n    weight: 1\n    providers:\n      - type: replay\n        cassette: c.jsonl\n        mode: record\n",
+		"unknown upstream":        "models:\n  - name: r\n    weight: 1\n    providers:\n      - type: replay\n        cassette: c.jsonl\n        mode: record\n        upstream: nope\n",
+		"all weights zero":        "models:\n  - name: r\n    weight: 0\n    providers:\n      - type: replay\n        cassette: c.jsonl\n",
+		"cassette on openai":      "models:\n  - name: r\n    weight: 1\n    providers:\n      - type: openai\n        endpoint: e\n        model: m\n        api_key: k\n        cassette: c.jsonl\n",
+	} {
+		write(content)
+		if _, err := loadRemixerConfig(configPath); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -4,10 +4,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	anthropic "github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
@@ -64,12 +67,15 @@ func testOpenAIProvider(t *testing.T, endpoint, model, apiKey, protocol string,
 func testAnthropicProvider(t *testing.T, endpoint, model, apiKey string, transport roundTripFunc) *anthropicProvider {
 	t.Helper()
 
-	p := newAnthropicProvider(remixerProviderConfig{
+	p, err := newAnthropicProvider(remixerProviderConfig{
 		Type:     "anthropic",
 		Endpoint: endpoint,
 		Model:    model,
 		APIKey:   apiKey,
 	})
+	if err != nil {
+		t.Fatalf("creating provider: %v", err)
+	}
 	p.client = anthropic.NewClient(
 		option.WithAPIKey(apiKey),
 		option.WithBaseURL(endpoint),
@@ -310,6 +316,173 @@ func TestOpenAIProviderResponsesDefaultInstructions(t *testing.T) {
 	}
 }
 
+func TestOpenAIProviderChatJSONOutput(t *testing.T) {
+	p := testOpenAIProvider(
+		t,
+		"https://openai.example",
+		"test-model",
+		"test-key",
+		"",
+		func(r *http.Request) (*http.Response, error) {
+			var body map[string]any
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Fatalf("decoding request body: %v", err)
+			}
+			format, ok := body["response_format"].(map[string]any)
+			if !ok || format["type"] != "json_object" {
+				t.Errorf("expected json_object response_format, got %#v", body["response_format"])
+			}
+
+			return newJSONResponse(t, http.StatusOK, map[string]any{
+				"model": "test-model",
+				"choices": []map[string]any{
+					{
+						"index":         0,
+						"message":       map[string]any{"role": "assistant", "content": `{"source": "int main() {}"}`},
+						"finish_reason": "stop",
+					},
+				},
+			}), nil
+		},
+	)
+
+	resp, err := p.Chat(context.Background(), remixerChatRequest{
+		Messages:       []remixerMessage{{Role: "user", Content: "Hello"}},
+		ResponseFormat: jsonObjectFormat,
+	})
+	if err != nil {
+		t.Fatalf("chat error: %v", err)
+	}
+	if resp.Content != `{"source": "int main() {}"}` {
+		t.Errorf("unexpected content: %q", resp.Content)
+	}
+}
+
+func TestOpenAIProviderResponsesJSONOutput(t *testing.T) {
+	p := testOpenAIProvider(
+		t,
+		"https://openai.example",
+		"gpt-5.4",
+		"test-key",
+		openAIProtocolResponses,
+		func(r *http.Request) (*http.Response, error) {
+			var body map[string]any
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Fatalf("decoding request body: %v", err)
+			}
+			text, _ := body["text"].(map[string]any)
+			format, _ := text["format"].(map[string]any)
+			if format["type"] != "json_object" {
+				t.Errorf("expected text.format json_object, got %#v", body["text"])
+			}
+
+			return newJSONResponse(t, http.StatusOK, map[string]any{
+				"model": "gpt-5.4",
+				"output": []map[string]any{
+					{
+						"type":    "message",
+						"content": []map[string]any{{"type": "output_text", "text": "{}"}},
+					},
+				},
+			}), nil
+		},
+	)
+
+	_, err := p.Chat(context.Background(), remixerChatRequest{
+		Messages:       []remixerMessage{{Role: "user", Content: "Hello"}},
+		ResponseFormat: jsonObjectFormat,
+	})
+	if err != nil {
+		t.Fatalf("chat error: %v", err)
+	}
+}
+
+func TestOpenAIProviderJSONSchema(t *testing.T) {
+	schema := json.RawMessage(`{"type":"object","properties":{"source":{"type":"string"}},"required":["source"],"additionalProperties":false}`)
+	req := remixerChatRequest{
+		Messages:       []remixerMessage{{Role: "user", Content: "Hello"}},
+		ResponseFormat: ResponseFormat{Type: FormatJSONSchema, Name: "seed", Schema: schema},
+	}
+	reply := func(t *testing.T, model string) *http.Response {
+		return newJSONResponse(t, http.StatusOK, map[string]any{
+			"model":   model,
+			"choices": []map[string]any{{"index": 0, "message": map[string]any{"role": "assistant", "content": "{}"}}},
+		})
+	}
+
+	for _, tc := range []struct {
+		model, want string
+	}{
+		{"gpt-4o-mini", "json_schema"},
+		{"deepseek-chat", "json_object"}, // DeepSeek has no schema mode
+	} {
+		var format map[string]any
+		p := testOpenAIProvider(t, "https://openai.example", tc.model, "test-key", "", func(r *http.Request) (*http.Response, error) {
+			var body map[string]any
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Fatalf("decoding request body: %v", err)
+			}
+			format, _ = body["response_format"].(map[string]any)
+			return reply(t, tc.model), nil
+		})
+		if _, err := p.Chat(context.Background(), req); err != nil {
+			t.Fatalf("%s: chat error: %v", tc.model, err)
+		}
+		if format["type"] != tc.want {
+			t.Errorf("%s: response_format = %#v, want type %s", tc.model, format, tc.want)
+		}
+		if tc.want == "json_schema" {
+			spec, _ := format["json_schema"].(map[string]any)
+			if spec["name"] != "seed" || spec["strict"] != true || spec["schema"] == nil {
+				t.Errorf("json_schema = %#v", spec)
+			}
+		}
+	}
+
+	var text map[string]any
+	p := testOpenAIProvider(t, "https://openai.example", "gpt-5.4", "test-key", openAIProtocolResponses, func(r *http.Request) (*http.Response, error) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("decoding request body: %v", err)
+		}
+		text, _ = body["text"].(map[string]any)
+		return newJSONResponse(t, http.StatusOK, map[string]any{
+			"model":  "gpt-5.4",
+			"output": []map[string]any{{"type": "message", "content": []map[string]any{{"type": "output_text", "text": "{}"}}}},
+		}), nil
+	})
+	if _, err := p.Chat(context.Background(), req); err != nil {
+		t.Fatalf("responses chat error: %v", err)
+	}
+	format, _ := text["format"].(map[string]any)
+	if format["type"] != "json_schema" || format["name"] != "seed" || format["schema"] == nil || format["strict"] != true {
+		t.Errorf("text.format = %#v", format)
+	}
+}
+
+func TestProvidersRejectUnsupportedFormat(t *testing.T) {
+	req := remixerChatRequest{
+		Messages:       []remixerMessage{{Role: "user", Content: "Hello"}},
+		ResponseFormat: ResponseFormat{Type: FormatJSONSchema, Name: "seed", Schema: json.RawMessage(`{"type":"object"}`)},
+	}
+	providers := map[string]remixerProvider{
+		"anthropic": testAnthropicProvider(t, "https://anthropic.example", "claude", "test-key", func(r *http.Request) (*http.Response, error) {
+			t.Error("anthropic: unexpected request")
+			return nil, errors.New("unexpected request")
+		}),
+		"gemini": testGeminiProvider(t, "https://gemini.example"),
+	}
+	for name, p := range providers {
+		_, err := p.Chat(context.Background(), req)
+		if !errors.Is(err, ErrUnsupportedFormat) {
+			t.Errorf("%s: err = %v, want ErrUnsupportedFormat", name, err)
+		}
+		if isTransient(err) {
+			t.Errorf("%s: an unsupported format should not be retried", name)
+		}
+	}
+}
+
 func TestAnthropicProviderChat(t *testing.T) {
 	p := testAnthropicProvider(
 		t,
@@ -350,3 +523,418 @@ func TestAnthropicProviderChat(t *testing.T) {
 		t.Errorf("expected anthropic content, got %q", resp.Content)
 	}
 }
+
+func TestOpenAIProviderChatRequestSettings(t *testing.T) {
+	var got map[string]any
+	var org string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		org = r.Header.Get("OpenAI-Organization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"ok"}}]}`)
+	}))
+	defer srv.Close()
+
+	temperature := 0.2
+	p, err := newOpenAIProvider(remixerProviderConfig{
+		Type:           "openai",
+		Endpoint:       srv.URL,
+		Model:          "gpt-4o",
+		APIKey:         "test-key",
+		Temperature:    &temperature,
+		MaxTokens:      512,
+		Organization:   "org-test",
+		MaxTokensField: openAIMaxTokensFieldCompletion,
+	})
+	if err != nil {
+		t.Fatalf("creating provider: %v", err)
+	}
+
+	clientTemperature := 0.9
+	resp, err := p.Chat(context.Background(), remixerChatRequest{
+		Messages:    []remixerMessage{{Role: "user", Content: "Hello"}},
+		Temperature: &clientTemperature,
+	})
+	if err != nil {
+		t.Fatalf("chat error: %v", err)
+	}
+	if resp.Content != "ok" {
+		t.Errorf("expected 'ok', got %q", resp.Content)
+	}
+	if org != "org-test" {
+		t.Errorf("expected organization header 'org-test', got %q", org)
+	}
+	if got["max_completion_tokens"] != float64(512) {
+		t.Errorf("expected max_completion_tokens 512, got %v", got["max_completion_tokens"])
+	}
+	if _, ok := got["max_tokens"]; ok {
+		t.Errorf("did not expect max_tokens, got %v", got["max_tokens"])
+	}
+	if temp, _ := got["temperature"].(float64); temp < 0.19 || temp > 0.21 {
+		t.Errorf("expected provider temperature 0.2, got %v", got["temperature"])
+	}
+	if _, ok := got["stream"]; ok {
+		t.Errorf("expected streaming off, got stream=%v", got["stream"])
+	}
+}
+
+func TestOpenAIProviderChatRetriesWithMaxCompletionTokens(t *testing.T) {
+	var fields []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, ok := body["max_tokens"]; ok {
+			fields = append(fields, "max_tokens")
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"error":{"message":"Unsupported parameter: 'max_tokens' is not supported with this model. Use 'max_completion_tokens' instead.","type":"invalid_request_error","param":"max_tokens"}}`)
+			return
+		}
+		fields = append(fields, "max_completion_tokens")
+		io.WriteString(w, `{"model":"gpt-4.1","choices":[{"index":0,"message":{"role":"assistant","content":"ok"}}]}`)
+	}))
+	defer srv.Close()
+
+	p, err := newOpenAIProvider(remixerProviderConfig{
+		Type:      "openai",
+		Endpoint:  srv.URL,
+		Model:     "gpt-4.1",
+		APIKey:    "test-key",
+		MaxTokens: 256,
+	})
+	if err != nil {
+		t.Fatalf("creating provider: %v", err)
+	}
+
+	if _, err := p.Chat(context.Background(), remixerChatRequest{
+		Messages: []remixerMessage{{Role: "user", Content: "Hello"}},
+	}); err != nil {
+		t.Fatalf("chat error: %v", err)
+	}
+	if strings.Join(fields, ",") != "max_tokens,max_completion_tokens" {
+		t.Errorf("unexpected request sequence: %v", fields)
+	}
+}
+
+func TestOpenAIProviderChatErrorBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        []string
+	}{
+		{
+			name:        "json error",
+			contentType: "application/json",
+			body:        `{"error":{"message":"model not found","type":"invalid_request_error","param":"model"}}`,
+			want:        []string{"status 404", "model not found", "invalid_request_error", "param: model"},
+		},
+		{
+			name:        "plain text error",
+			contentType: "text/plain",
+			body:        "upstream gateway unavailable",
+			want:        []string{"status 404", "upstream gateway unavailable"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.WriteHeader(http.StatusNotFound)
+				io.WriteString(w, tt.body)
+			}))
+			defer srv.Close()
+
+			p, err := newOpenAIProvider(remixerProviderConfig{
+				Type:     "openai",
+				Endpoint: srv.URL,
+				Model:    "missing-model",
+				APIKey:   "test-key",
+			})
+			if err != nil {
+				t.Fatalf("creating provider: %v", err)
+			}
+
+			_, err = p.Chat(context.Background(), remixerChatRequest{
+				Messages: []remixerMessage{{Role: "user", Content: "Hello"}},
+			})
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q should contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenAIProviderResponsesOrganization(t *testing.T) {
+	var org string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		org = r.Header.Get("OpenAI-Organization")
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"model":"gpt-5","output_text":"ok"}`)
+	}))
+	defer srv.Close()
+
+	p, err := newOpenAIProvider(remixerProviderConfig{
+		Type:         "openai",
+		Endpoint:     srv.URL,
+		Model:        "gpt-5",
+		APIKey:       "test-key",
+		Protocol:     openAIProtocolResponses,
+		Organization: "org-test",
+	})
+	if err != nil {
+		t.Fatalf("creating provider: %v", err)
+	}
+
+	if _, err := p.Chat(context.Background(), remixerChatRequest{
+		Messages: []remixerMessage{{Role: "user", Content: "Hello"}},
+	}); err != nil {
+		t.Fatalf("chat error: %v", err)
+	}
+	if org != "org-test" {
+		t.Errorf("expected organization header 'org-test', got %q", org)
+	}
+}
+
+func TestAnthropicProviderRequestConstruction(t *testing.T) {
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"id":"msg_test","type":"message","role":"assistant","model":"claude-test",`+
+			`"content":[{"type":"text","text":"int main"},{"type":"text","text":"() {}"}],"stop_reason":"end_turn"}`)
+	}))
+	defer srv.Close()
+
+	temperature := 0.4
+	p, err := newAnthropicProvider(remixerProviderConfig{
+		Type:        "anthropic",
+		Endpoint:    srv.URL,
+		Model:       "claude-test",
+		APIKey:      "test-ant-key",
+		Temperature: &temperature,
+		MaxTokens:   2048,
+	})
+	if err != nil {
+		t.Fatalf("creating provider: %v", err)
+	}
+
+	resp, err := p.Chat(context.Background(), remixerChatRequest{
+		Messages: []remixerMessage{
+			{Role: "system", Content: "You write C programs."},
+			{Role: "user", Content: "Write a program."},
+		},
+	})
+	if err != nil {
+		t.Fatalf("chat error: %v", err)
+	}
+	if resp.Content != "int main() {}" {
+		t.Errorf("expected joined text blocks, got %q", resp.Content)
+	}
+
+	system, _ := got["system"].([]any)
+	if len(system) != 1 || system[0].(map[string]any)["text"] != "You write C programs." {
+		t.Errorf("expected the system prompt in the top-level system field, got %v", got["system"])
+	}
+	messages, _ := got["messages"].([]any)
+	if len(messages) != 1 || messages[0].(map[string]any)["role"] != "user" {
+		t.Errorf("expected a single user message, got %v", got["messages"])
+	}
+	if got["max_tokens"] != float64(2048) {
+		t.Errorf("expected max_tokens 2048, got %v", got["max_tokens"])
+	}
+	if got["temperature"] != 0.4 {
+		t.Errorf("expected temperature 0.4, got %v", got["temperature"])
+	}
+}
+
+func TestAnthropicProviderRateLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+		io.WriteString(w, `{"type":"error","error":{"type":"rate_limit_error","message":"Number of requests has exceeded your rate limit"}}`)
+	}))
+	defer srv.Close()
+
+	p, err := newAnthropicProvider(remixerProviderConfig{Type: "anthropic", Model: "claude-test", APIKey: "test-ant-key"})
+	if err != nil {
+		t.Fatalf("creating provider: %v", err)
+	}
+	p.client = anthropic.NewClient(
+		option.WithAPIKey("test-ant-key"),
+		option.WithBaseURL(srv.URL),
+		option.WithMaxRetries(0),
+	)
+
+	_, err = p.Chat(context.Background(), remixerChatRequest{
+		Messages: []remixerMessage{{Role: "user", Content: "Hello"}},
+	})
+	var rateErr *RateLimitError
+	if !errors.As(err, &rateErr) {
+		t.Fatalf("expected a *RateLimitError, got %v", err)
+	}
+	if rateErr.RetryAfter != 30*time.Second {
+		t.Errorf("expected RetryAfter 30s, got %s", rateErr.RetryAfter)
+	}
+	if !strings.Contains(err.Error(), "retry after 30s") || !strings.Contains(err.Error(), "rate_limit_error") {
+		t.Errorf("error should mention the retry delay and the error body: %v", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := map[string]time.Duration{
+		"":                              0,
+		"12":                            12 * time.Second,
+		"1.5":                           1500 * time.Millisecond,
+		"-3":                            0,
+		"soon":                          0,
+		"Wed, 01 Jan 2025 12:01:00 GMT": time.Minute,
+		"Wed, 01 Jan 2025 11:59:00 GMT": 0,
+	}
+	for value, want := range tests {
+		if got := parseRetryAfter(value, now); got != want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", value, got, want)
+		}
+	}
+}
+
+// sseServer streams one chat_completions chunk per delta, then a usage chunk
+// and [DONE]. sent counts the deltas written before the client went away.
+func sseServer(t *testing.T, deltas []string, sent *int) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding request body: %v", err)
+		}
+		if body["stream"] != true {
+			t.Errorf("expected stream=true, got %v", body["stream"])
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		for _, delta := range deltas {
+			chunk, _ := json.Marshal(map[string]any{
+				"model":   "test-model",
+				"choices": []map[string]any{{"index": 0, "delta": map[string]any{"content": delta}}},
+			})
+			if _, err := io.WriteString(w, "data: "+string(chunk)+"\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+			*sent++
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(5 * time.Millisecond):
+			}
+		}
+		io.WriteString(w, `data: {"model":"test-model","choices":[],"usage":{"prompt_tokens":12,"completion_tokens":7}}`+"\n\n")
+		io.WriteString(w, "data: [DONE]\n\n")
+	}))
+}
+
+func TestOpenAIProviderChatStream(t *testing.T) {
+	deltas := []string{"```c\n", "int main(void) {", " return 0; }\n", "```"}
+	sent := 0
+	srv := sseServer(t, deltas, &sent)
+	defer srv.Close()
+
+	p, err := newOpenAIProvider(remixerProviderConfig{Type: "openai", Endpoint: srv.URL, Model: "test-model", APIKey: "test-key"})
+	if err != nil {
+		t.Fatalf("creating provider: %v", err)
+	}
+
+	var got []string
+	resp, err := p.ChatStream(context.Background(), remixerChatRequest{
+		Messages: []remixerMessage{{Role: "user", Content: "Hello"}},
+	}, func(delta string) bool {
+		got = append(got, delta)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+	if strings.Join(got, "|") != strings.Join(deltas, "|") {
+		t.Errorf("deltas = %q, want %q", got, deltas)
+	}
+	if resp.Content != strings.Join(deltas, "") || resp.Model != "test-model" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if resp.PromptTokens != 12 || resp.CompletionTokens != 7 {
+		t.Errorf("usage = %d+%d, want 12+7", resp.PromptTokens, resp.CompletionTokens)
+	}
+}
+
+func TestOpenAIProviderChatStreamEarlyAbort(t *testing.T) {
+	deltas := make([]string, 50)
+	for i := range deltas {
+		deltas[i] = "word "
+	}
+	sent := 0
+	srv := sseServer(t, deltas, &sent)
+	defer srv.Close()
+
+	p, err := newOpenAIProvider(remixerProviderConfig{Type: "openai", Endpoint: srv.URL, Model: "test-model", APIKey: "test-key"})
+	if err != nil {
+		t.Fatalf("creating provider: %v", err)
+	}
+
+	received := 0
+	resp, err := p.ChatStream(context.Background(), remixerChatRequest{
+		Messages: []remixerMessage{{Role: "user", Content: "Hello"}},
+	}, func(string) bool {
+		received++
+		return received < 3
+	})
+	if err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+	if resp.Content != "word word word " {
+		t.Errorf("content = %q, want the first three deltas", resp.Content)
+	}
+	srv.Close() // waits for the handler to notice the closed stream
+	if sent >= len(deltas) {
+		t.Errorf("server sent all %d deltas; the stream was not abandoned", sent)
+	}
+}
+
+func TestRemixerEngineChatStreamFallback(t *testing.T) {
+	engine := &remixerEngine{
+		selector: &weightedSelector{
+			entries:     []selectorEntry{{name: "plain", providers: []remixerProvider{fixedUsageProvider{}}, upper: 1}},
+			totalWeight: 1,
+		},
+		retrier: newRetrier(remixerRetryConfig{MaxAttempts: 1}),
+	}
+
+	var chunks []string
+	resp, err := engine.ChatStream(context.Background(), remixerChatRequest{
+		Messages: []remixerMessage{{Role: "user", Content: "Hello"}},
+	}, func(chunk string) bool {
+		chunks = append(chunks, chunk)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+	if len(chunks) != 1 || chunks[0] != "ok" || resp.Content != "ok" {
+		t.Errorf("chunks = %q, content = %q; want the whole reply in one chunk", chunks, resp.Content)
+	}
+}
@@ -0,0 +1,162 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+
+	anthropic "github.com/anthropics/anthropic-sdk-go"
+	openai "github.com/sashabaranov/go-openai"
+
+	"github.com/zjy-dev/de-fuzz/internal/logger"
+)
+
+// Retry defaults, used for fields left at 0 in remixer.yaml.
+const (
+	defaultRetryMaxAttempts    = 4
+	defaultRetryInitialBackoff = time.Second
+	defaultRetryMaxBackoff     = 30 * time.Second
+	defaultRetryMaxElapsed     = 2 * time.Minute
+)
+
+// remixerRetryConfig is the retry: block of remixer.yaml. MaxAttempts
+// counts the first try, so 1 disables retries.
+type remixerRetryConfig struct {
+	MaxAttempts    int           `yaml:"max_attempts,omitempty"`
+	InitialBackoff time.Duration `yaml:"initial_backoff,omitempty"`
+	MaxBackoff     time.Duration `yaml:"max_backoff,omitempty"`
+	MaxElapsed     time.Duration `yaml:"max_elapsed,omitempty"`
+}
+
+// withDefaults fills unset fields.
+func (c remixerRetryConfig) withDefaults() remixerRetryConfig {
+	if c.MaxAttempts == 0 {
+		c.MaxAttempts = defaultRetryMaxAttempts
+	}
+	if c.InitialBackoff == 0 {
+		c.InitialBackoff = defaultRetryInitialBackoff
+	}
+	if c.MaxBackoff == 0 {
+		c.MaxBackoff = defaultRetryMaxBackoff
+	}
+	if c.MaxElapsed == 0 {
+		c.MaxElapsed = defaultRetryMaxElapsed
+	}
+	return c
+}
+
+// retrier re-runs a provider call on transient failures with exponential
+// backoff and full jitter. The clock, sleep and jitter source are fields so
+// tests can replace them.
+type retrier struct {
+	cfg    remixerRetryConfig
+	now    func() time.Time
+	sleep  func(ctx context.Context, d time.Duration) error
+	jitter func() float64
+}
+
+func newRetrier(cfg remixerRetryConfig) *retrier {
+	return &retrier{
+		cfg:    cfg.withDefaults(),
+		now:    time.Now,
+		sleep:  sleepContext,
+		jitter: rand.Float64,
+	}
+}
+
+// do calls fn until it succeeds, fails with a non-transient error, or the
+// attempt or elapsed-time budget runs out. It returns the number of retries
+// made (attempts after the first) and fn's last error.
+func (r *retrier) do(ctx context.Context, label string, fn func() error) (retries int, err error) {
+	start := r.now()
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || attempt >= r.cfg.MaxAttempts || !isTransient(err) {
+			return retries, err
+		}
+
+		wait := r.backoff(attempt)
+		var rateErr *RateLimitError
+		if errors.As(err, &rateErr) && rateErr.RetryAfter > wait {
+			wait = rateErr.RetryAfter
+		}
+		if r.now().Sub(start)+wait > r.cfg.MaxElapsed {
+			logger.Debug("[LLM] %s: giving up after %d attempt(s), next wait %s exceeds the %s budget: %v",
+				label, attempt, wait, r.cfg.MaxElapsed, err)
+			return retries, err
+		}
+
+		logger.Debug("[LLM] %s: attempt %d/%d failed, retrying in %s: %v", label, attempt, r.cfg.MaxAttempts, wait, err)
+		if sleepErr := r.sleep(ctx, wait); sleepErr != nil {
+			return retries, err
+		}
+		retries++
+	}
+}
+
+// backoff returns the full-jitter delay before retry number attempt:
+// uniform in [0, min(MaxBackoff, InitialBackoff*2^(attempt-1))).
+func (r *retrier) backoff(attempt int) time.Duration {
+	ceiling := r.cfg.InitialBackoff
+	for i := 1; i < attempt && ceiling < r.cfg.MaxBackoff; i++ {
+		ceiling *= 2
+	}
+	ceiling = min(ceiling, r.cfg.MaxBackoff)
+	return time.Duration(r.jitter() * float64(ceiling))
+}
+
+// isTransient reports whether a provider error is worth retrying: rate
+// limits, 408, 5xx and network failures. Other 4xx replies and decoding
+// errors are not, and neither is a cancelled context.
+func isTransient(err error) bool {
+	var interrupted *streamInterruptedError
+	if errors.Is(err, context.Canceled) || errors.As(err, &interrupted) {
+		return false
+	}
+	var rateErr *RateLimitError
+	if errors.As(err, &rateErr) {
+		return true
+	}
+	if code := errorStatusCode(err); code != 0 {
+		return code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
+
+// errorStatusCode digs the HTTP status out of the error types the providers
+// return, or 0 when err carries none.
+func errorStatusCode(err error) int {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode
+	}
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.HTTPStatusCode
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return reqErr.HTTPStatusCode
+	}
+	var anthropicErr *anthropic.Error
+	if errors.As(err, &anthropicErr) {
+		return anthropicErr.StatusCode
+	}
+	return 0
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
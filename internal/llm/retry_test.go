@@ -0,0 +1,146 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeRetrier returns a retrier that records its waits instead of sleeping
+// and always jitters to the full backoff ceiling.
+func fakeRetrier(cfg remixerRetryConfig) (*retrier, *[]time.Duration) {
+	var waits []time.Duration
+	now := time.Unix(0, 0)
+	r := newRetrier(cfg)
+	r.now = func() time.Time { return now }
+	r.sleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		now = now.Add(d)
+		return nil
+	}
+	r.jitter = func() float64 { return 1 }
+	return r, &waits
+}
+
+func TestRetrierBackoff(t *testing.T) {
+	r, waits := fakeRetrier(remixerRetryConfig{MaxAttempts: 5, InitialBackoff: time.Second, MaxBackoff: 3 * time.Second})
+
+	calls := 0
+	retries, err := r.do(context.Background(), "test", func() error {
+		calls++
+		return &StatusError{Provider: "test", StatusCode: http.StatusBadGateway, Message: "bad gateway"}
+	})
+	if err == nil || calls != 5 || retries != 4 {
+		t.Fatalf("got calls=%d retries=%d err=%v, want 5 calls and 4 retries", calls, retries, err)
+	}
+	want := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}
+	if fmt.Sprint(*waits) != fmt.Sprint(want) {
+		t.Errorf("waits = %v, want %v", *waits, want)
+	}
+}
+
+func TestRetrierStopsOnPermanentErrors(t *testing.T) {
+	tests := map[string]error{
+		"400":       &StatusError{Provider: "test", StatusCode: http.StatusBadRequest, Message: "bad request"},
+		"404":       &StatusError{Provider: "test", StatusCode: http.StatusNotFound, Message: "no such model"},
+		"decode":    errors.New("decode response: unexpected end of JSON input"),
+		"cancelled": fmt.Errorf("request: %w", context.Canceled),
+	}
+	for name, failure := range tests {
+		t.Run(name, func(t *testing.T) {
+			r, _ := fakeRetrier(remixerRetryConfig{})
+			calls := 0
+			if _, err := r.do(context.Background(), "test", func() error { calls++; return failure }); err != failure {
+				t.Errorf("expected the original error, got %v", err)
+			}
+			if calls != 1 {
+				t.Errorf("permanent error was retried: %d calls", calls)
+			}
+		})
+	}
+}
+
+func TestRetrierTransientErrors(t *testing.T) {
+	for _, failure := range []error{
+		&StatusError{Provider: "test", StatusCode: http.StatusRequestTimeout},
+		&StatusError{Provider: "test", StatusCode: http.StatusTooManyRequests},
+		&StatusError{Provider: "test", StatusCode: http.StatusServiceUnavailable},
+		&RateLimitError{Provider: "test", Err: errors.New("slow down")},
+		fmt.Errorf("read: %w", io.ErrUnexpectedEOF),
+	} {
+		if !isTransient(failure) {
+			t.Errorf("isTransient(%v) = false, want true", failure)
+		}
+	}
+}
+
+func TestRetrierRespectsRetryAfterAndElapsedBudget(t *testing.T) {
+	r, waits := fakeRetrier(remixerRetryConfig{MaxAttempts: 10, InitialBackoff: time.Second, MaxElapsed: time.Minute})
+
+	calls := 0
+	_, err := r.do(context.Background(), "test", func() error {
+		calls++
+		return &RateLimitError{Provider: "test", RetryAfter: 25 * time.Second, Err: errors.New("slow down")}
+	})
+	if err == nil {
+		t.Fatal("expected the rate-limit error once the budget is spent")
+	}
+	// 25s + 25s fit in the minute; a third 25s wait would not.
+	want := []time.Duration{25 * time.Second, 25 * time.Second}
+	if fmt.Sprint(*waits) != fmt.Sprint(want) || calls != 3 {
+		t.Errorf("waits = %v after %d calls, want %v after 3", *waits, calls, want)
+	}
+}
+
+func TestRemixerEngineRetriesTransientFailures(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		if requests == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			io.WriteString(w, `{"error":{"message":"upstream unavailable"}}`)
+			return
+		}
+		io.WriteString(w, `{"model":"test-model","choices":[{"index":0,"message":{"role":"assistant","content":"ok"}}]}`)
+	}))
+	defer srv.Close()
+
+	provider, err := newOpenAIProvider(remixerProviderConfig{Type: "openai", Endpoint: srv.URL, Model: "test-model", APIKey: "test-key"})
+	if err != nil {
+		t.Fatalf("creating provider: %v", err)
+	}
+	retrier, _ := fakeRetrier(remixerRetryConfig{})
+	engine := &remixerEngine{
+		selector: &weightedSelector{
+			entries:     []selectorEntry{{name: "test-model", providers: []remixerProvider{provider}, upper: 1}},
+			totalWeight: 1,
+		},
+		retrier: retrier,
+	}
+
+	resp, err := engine.Chat(context.Background(), remixerChatRequest{
+		Messages: []remixerMessage{{Role: "user", Content: "Hello"}},
+	})
+	if err != nil {
+		t.Fatalf("chat error: %v", err)
+	}
+	if resp.Content != "ok" {
+		t.Errorf("expected 'ok', got %q", resp.Content)
+	}
+	if usage := engine.Usage(); usage != (Usage{Calls: 1, Retries: 1}) {
+		t.Errorf("usage = %+v, want 1 call and 1 retry", usage)
+	}
+}
+
+func TestRetrierDoesNotRetryInterruptedStreams(t *testing.T) {
+	err := &streamInterruptedError{Err: &StatusError{Provider: "openai", StatusCode: http.StatusBadGateway}}
+	if isTransient(err) {
+		t.Error("a stream that already delivered text must not be retried")
+	}
+}
@@ -0,0 +1,72 @@
+package llm
+
+import "time"
+
+// Usage counts the LLM requests a client has made and what they consumed.
+type Usage struct {
+	Calls     int `json:"calls"`      // Requests sent to a provider
+	Retries   int `json:"retries"`    // Extra attempts after transient provider failures
+	Failures  int `json:"failures"`   // Requests that still failed after all attempts
+	CacheHits int `json:"cache_hits"` // Requests answered from the on-disk cache, not counted in Calls
+
+	PromptTokens     int     `json:"prompt_tokens"`     // As reported by the providers
+	CompletionTokens int     `json:"completion_tokens"` // As reported by the providers
+	Cost             float64 `json:"cost"`              // Estimated from the remixer.yaml pricing
+}
+
+// Add returns the field-wise sum of u and o.
+func (u Usage) Add(o Usage) Usage {
+	return Usage{
+		Calls:            u.Calls + o.Calls,
+		Retries:          u.Retries + o.Retries,
+		Failures:         u.Failures + o.Failures,
+		CacheHits:        u.CacheHits + o.CacheHits,
+		PromptTokens:     u.PromptTokens + o.PromptTokens,
+		CompletionTokens: u.CompletionTokens + o.CompletionTokens,
+		Cost:             u.Cost + o.Cost,
+	}
+}
+
+// Sub returns the field-wise difference u - o, e.g. what was used between
+// two snapshots.
+func (u Usage) Sub(o Usage) Usage {
+	return Usage{
+		Calls:            u.Calls - o.Calls,
+		Retries:          u.Retries - o.Retries,
+		Failures:         u.Failures - o.Failures,
+		CacheHits:        u.CacheHits - o.CacheHits,
+		PromptTokens:     u.PromptTokens - o.PromptTokens,
+		CompletionTokens: u.CompletionTokens - o.CompletionTokens,
+		Cost:             u.Cost - o.Cost,
+	}
+}
+
+// Tokens returns the prompt plus completion tokens.
+func (u Usage) Tokens() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// UsageReporter is implemented by clients that track Usage.
+type UsageReporter interface {
+	Usage() Usage
+}
+
+// CallStats describes one finished request, for a call observer (see
+// WithCallObserver).
+type CallStats struct {
+	Type   string // CallInfo.Type of the request, "" if untagged
+	Model  string
+	Cached bool // Answered from the on-disk cache
+	Failed bool // Still failed after all attempts and fallbacks
+
+	PromptTokens     int // As reported by the provider, 0 on failure
+	CompletionTokens int
+	Latency          time.Duration
+}
+
+// ProviderCallReporter is implemented by clients that spread calls over
+// several providers. ProviderCalls returns how many calls each provider
+// served, keyed by "type/model".
+type ProviderCallReporter interface {
+	ProviderCalls() map[string]int
+}
@@ -0,0 +1,87 @@
+package llm
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+// fixedUsageProvider answers every request with the same token counts.
+type fixedUsageProvider struct{}
+
+func (fixedUsageProvider) Chat(ctx context.Context, req remixerChatRequest) (remixerChatResponse, error) {
+	return remixerChatResponse{Content: "ok", Model: "m", PromptTokens: 2000, CompletionTokens: 500}, nil
+}
+
+func TestRemixerEngineUsageAndCost(t *testing.T) {
+	engine := &remixerEngine{
+		selector: &weightedSelector{
+			entries: []selectorEntry{{
+				name:      "priced",
+				providers: []remixerProvider{fixedUsageProvider{}},
+				upper:     1,
+				pricing:   remixerPricing{PromptPerMillion: 0.5, CompletionPerMillion: 2},
+			}},
+			totalWeight: 1,
+		},
+		retrier: newRetrier(remixerRetryConfig{MaxAttempts: 1}),
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := engine.Chat(context.Background(), remixerChatRequest{
+			Messages: []remixerMessage{{Role: "user", Content: "Hello"}},
+		}); err != nil {
+			t.Fatalf("chat error: %v", err)
+		}
+	}
+
+	usage := engine.Usage()
+	if usage.Calls != 2 || usage.PromptTokens != 4000 || usage.CompletionTokens != 1000 {
+		t.Errorf("usage = %+v, want 2 calls with 4000+1000 tokens", usage)
+	}
+	// 4000 * 0.5/1M + 1000 * 2/1M
+	if want := 0.004; math.Abs(usage.Cost-want) > 1e-12 {
+		t.Errorf("cost = %g, want %g", usage.Cost, want)
+	}
+}
+
+func TestUsageAddSub(t *testing.T) {
+	a := Usage{Calls: 3, Retries: 1, PromptTokens: 30, CompletionTokens: 10, Cost: 0.75}
+	b := Usage{Calls: 1, PromptTokens: 10, CompletionTokens: 5, Cost: 0.25}
+	if got := a.Add(b).Sub(b); got != a {
+		t.Errorf("a+b-b = %+v, want %+v", got, a)
+	}
+}
+
+func TestRemixerEngineCallObserver(t *testing.T) {
+	provider := &countingProvider{reply: "ok"}
+	var observed []CallStats
+	engine := &remixerEngine{
+		selector: &weightedSelector{
+			entries:     []selectorEntry{{name: "m", providers: []remixerProvider{provider}, upper: 1}},
+			totalWeight: 1,
+		},
+		retrier: newRetrier(remixerRetryConfig{MaxAttempts: 1}),
+		observe: func(stats CallStats) { observed = append(observed, stats) },
+	}
+
+	req := remixerChatRequest{Messages: []remixerMessage{{Role: "user", Content: "Hello"}}}
+	ctx := WithCallInfo(context.Background(), CallInfo{Type: CallRepair})
+	if _, err := engine.Chat(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	provider.fail = true
+	if _, err := engine.Chat(context.Background(), req); err == nil {
+		t.Fatal("expected the failing provider to return an error")
+	}
+
+	if len(observed) != 2 {
+		t.Fatalf("observed %d calls, want 2", len(observed))
+	}
+	if ok := observed[0]; ok.Type != CallRepair || ok.Model != "m" || ok.Failed || ok.Cached {
+		t.Errorf("successful call = %+v", ok)
+	}
+	if failed := observed[1]; failed.Type != "" || !failed.Failed {
+		t.Errorf("failed call = %+v", failed)
+	}
+}
@@ -0,0 +1,200 @@
+// Package metrics exports a fuzzing campaign's measurements to Prometheus.
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/zjy-dev/de-fuzz/internal/llm"
+	"github.com/zjy-dev/de-fuzz/internal/logger"
+)
+
+// Metrics holds the campaign's collectors in a registry of their own. The
+// engine, LLM client, analyzer and corpus feed it through the hooks below:
+// IterationDone, Compiled and BugFound implement fuzz.Metrics; ObserveLLMCall
+// is an llm.WithCallObserver observer; WatchCoverage and WatchCorpus read
+// their sources when scraped.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	iterations        prometheus.Counter
+	iterationDuration prometheus.Histogram
+	compiles          *prometheus.CounterVec
+	bugs              prometheus.Counter
+	llmCalls          *prometheus.CounterVec
+	llmTokens         *prometheus.CounterVec
+}
+
+// New creates the collectors and registers them.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		iterations: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "defuzz_iterations_total",
+			Help: "Constraint-solving iterations completed.",
+		}),
+		iterationDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name: "defuzz_iteration_duration_seconds",
+			Help: "Duration of constraint-solving iterations.",
+			// 1s to about 68min: an iteration is a few LLM calls and compiles
+			Buckets: prometheus.ExponentialBuckets(1, 2, 13),
+		}),
+		compiles: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "defuzz_compiles_total",
+			Help: "Seed compilations, by result (success or failure).",
+		}, []string{"result"}),
+		bugs: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "defuzz_bugs_found_total",
+			Help: "Bugs found by the oracle.",
+		}),
+		llmCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "defuzz_llm_calls_total",
+			Help: "LLM requests, by call type and outcome (ok, failed or cached).",
+		}, []string{"type", "outcome"}),
+		llmTokens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "defuzz_llm_tokens_total",
+			Help: "LLM tokens reported by the providers, by call type and kind (prompt or completion).",
+		}, []string{"type", "kind"}),
+	}
+	m.registry.MustRegister(m.iterations, m.iterationDuration, m.compiles, m.bugs, m.llmCalls, m.llmTokens)
+	return m
+}
+
+// IterationDone counts a completed iteration and records its duration.
+func (m *Metrics) IterationDone(d time.Duration) {
+	m.iterations.Inc()
+	m.iterationDuration.Observe(d.Seconds())
+}
+
+// Compiled counts a seed compilation.
+func (m *Metrics) Compiled(ok bool) {
+	result := "failure"
+	if ok {
+		result = "success"
+	}
+	m.compiles.WithLabelValues(result).Inc()
+}
+
+// BugFound counts a new bug.
+func (m *Metrics) BugFound() {
+	m.bugs.Inc()
+}
+
+// ObserveLLMCall counts an LLM request and the tokens it used.
+func (m *Metrics) ObserveLLMCall(stats llm.CallStats) {
+	callType := stats.Type
+	if callType == "" {
+		callType = "other"
+	}
+	outcome := "ok"
+	switch {
+	case stats.Cached:
+		outcome = "cached"
+	case stats.Failed:
+		outcome = "failed"
+	}
+	m.llmCalls.WithLabelValues(callType, outcome).Inc()
+	if stats.Cached {
+		return
+	}
+	m.llmTokens.WithLabelValues(callType, "prompt").Add(float64(stats.PromptTokens))
+	m.llmTokens.WithLabelValues(callType, "completion").Add(float64(stats.CompletionTokens))
+}
+
+// FunctionCoverage is the part of coverage.Analyzer WatchCoverage reads.
+type FunctionCoverage interface {
+	GetFunctionCoverage() map[string]struct{ Covered, Total int }
+}
+
+// WatchCoverage exports the BB coverage of every target function of
+// source, in basis points, read at each scrape.
+func (m *Metrics) WatchCoverage(source FunctionCoverage) {
+	m.registry.MustRegister(&coverageCollector{
+		source: source,
+		desc: prometheus.NewDesc("defuzz_coverage_basis_points",
+			"BB coverage of a target function in basis points (1/100 of a percent).",
+			[]string{"function"}, nil),
+	})
+}
+
+// CorpusSize is the part of corpus.Manager WatchCorpus reads.
+type CorpusSize interface {
+	Len() int
+}
+
+// WatchCorpus exports the number of seeds in source, read at each scrape.
+func (m *Metrics) WatchCorpus(source CorpusSize) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "defuzz_corpus_seeds",
+		Help: "Seeds in the corpus.",
+	}, func() float64 { return float64(source.Len()) }))
+}
+
+// Handler returns the /metrics handler of the registry.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Server serves /metrics until closed.
+type Server struct {
+	listener net.Listener
+	server   *http.Server
+}
+
+// Serve listens on addr (e.g. ":9090") and serves m's /metrics from a
+// goroutine of its own.
+func Serve(addr string, m *Metrics) (*Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for metrics on %s: %w", addr, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+	s := &Server{
+		listener: listener,
+		server:   &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second},
+	}
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Warn("Metrics server stopped: %v", err)
+		}
+	}()
+	return s, nil
+}
+
+// Addr returns the address the server listens on, with the port chosen
+// when addr asked for port 0.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close stops the server.
+func (s *Server) Close() error {
+	return s.server.Close()
+}
+
+// coverageCollector reads per-function coverage when scraped.
+type coverageCollector struct {
+	source FunctionCoverage
+	desc   *prometheus.Desc
+}
+
+func (c *coverageCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *coverageCollector) Collect(ch chan<- prometheus.Metric) {
+	for name, cov := range c.source.GetFunctionCoverage() {
+		var bp float64
+		if cov.Total > 0 {
+			bp = float64(cov.Covered * 10000 / cov.Total)
+		}
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, bp, name)
+	}
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zjy-dev/de-fuzz/internal/llm"
+)
+
+type fakeCoverage map[string]struct{ Covered, Total int }
+
+func (f fakeCoverage) GetFunctionCoverage() map[string]struct{ Covered, Total int } {
+	return f
+}
+
+type fakeCorpus int
+
+func (f fakeCorpus) Len() int { return int(f) }
+
+func TestServe_ScrapesMetrics(t *testing.T) {
+	m := New()
+	m.WatchCoverage(fakeCoverage{"expand_used_vars": {Covered: 3, Total: 8}})
+	m.WatchCorpus(fakeCorpus(42))
+	m.IterationDone(3 * time.Second)
+	m.Compiled(true)
+	m.Compiled(false)
+	m.BugFound()
+	m.ObserveLLMCall(llm.CallStats{Type: llm.CallGenerate, PromptTokens: 100, CompletionTokens: 20})
+	m.ObserveLLMCall(llm.CallStats{Type: llm.CallRepair, Failed: true})
+
+	server, err := Serve("127.0.0.1:0", m)
+	if err != nil {
+		t.Fatalf("Serve() failed: %v", err)
+	}
+	defer server.Close()
+
+	resp, err := http.Get("http://" + server.Addr() + "/metrics")
+	if err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"defuzz_iterations_total 1",
+		"defuzz_iteration_duration_seconds_count 1",
+		`defuzz_compiles_total{result="success"} 1`,
+		`defuzz_compiles_total{result="failure"} 1`,
+		"defuzz_bugs_found_total 1",
+		`defuzz_llm_calls_total{outcome="ok",type="generate"} 1`,
+		`defuzz_llm_calls_total{outcome="failed",type="repair"} 1`,
+		`defuzz_llm_tokens_total{kind="prompt",type="generate"} 100`,
+		`defuzz_llm_tokens_total{kind="completion",type="generate"} 20`,
+		`defuzz_coverage_basis_points{function="expand_used_vars"} 3750`,
+		"defuzz_corpus_seeds 42",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("/metrics is missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package oracle
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/zjy-dev/de-fuzz/internal/llm"
+	"github.com/zjy-dev/de-fuzz/internal/prompt"
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+func init() {
+	Register("diff", NewDiffOracle)
+}
+
+// TestCaseExecutor is implemented by executors that can run a seed test case
+// with its stdin, environment and timeout. Oracles fall back to
+// ExecuteWithInput or ExecuteWithArgs for executors that do not, which
+// cannot run a test case with both stdin and arguments.
+type TestCaseExecutor interface {
+	ExecuteTestCase(binaryPath string, tc seed.TestCase, args ...string) (exitCode int, stdout string, stderr string, err error)
+}
+
+// ErrStdinWithArgs is returned for a test case with both stdin and
+// arguments when the executor is not a TestCaseExecutor: ExecuteWithInput
+// would drop the arguments.
+var ErrStdinWithArgs = errors.New("executor cannot pass both stdin and arguments")
+
+// NewDiffOracle creates the differential oracle. Options:
+//   - compare_stderr (bool): also report seeds whose stderr differs. Off by
+//     default since stderr tends to name paths and addresses.
+func NewDiffOracle(options map[string]interface{}, l llm.LLM, prompter *prompt.Builder, context string) (Oracle, error) {
+	o := &DiffOracle{}
+	if v, ok := options["compare_stderr"].(bool); ok {
+		o.compareStderr = v
+	}
+	return o, nil
+}
+
+// DiffOracle runs every test case of a seed on its primary and reference
+// builds and reports a bug when they disagree on the exit code or stdout.
+// It needs AnalyzeContext.Reference; a seed the reference compiler rejected
+// gets no verdict.
+type DiffOracle struct {
+	compareStderr bool
+}
+
+// Analyze runs the seed's test cases, or the binary once without arguments
+// when it has none, on both builds and stops at the first divergence.
+func (o *DiffOracle) Analyze(s *seed.Seed, ctx *AnalyzeContext, results []Result) (*Bug, error) {
+	if ctx == nil || ctx.Reference == nil {
+		return nil, errors.New("diff oracle requires a reference build (compiler.reference)")
+	}
+	if ctx.Executor == nil {
+		return nil, errors.New("diff oracle requires an executor")
+	}
+	ref := ctx.Reference
+	if ref.BinaryPath == "" {
+		return nil, nil
+	}
+	refExecutor := ref.Executor
+	if refExecutor == nil {
+		refExecutor = ctx.Executor
+	}
+
+	testCases := s.TestCases
+	if len(testCases) == 0 {
+		testCases = []seed.TestCase{{}}
+	}
+	var primary, reference []Result
+	for i, tc := range testCases {
+		p, err := RunTestCase(ctx.Executor, ctx.BinaryPath, tc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to run test case %d on the primary build: %w", i+1, err)
+		}
+		r, err := RunTestCase(refExecutor, ref.BinaryPath, tc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to run test case %d on the reference build: %w", i+1, err)
+		}
+		primary = append(primary, p)
+		reference = append(reference, r)
+
+		class, detail := o.divergence(p, r)
+		if class == "" {
+			continue
+		}
+		return &Bug{
+			Seed:    s,
+			Results: primary,
+			Description: fmt.Sprintf("Test case %d diverges between builds: %s (primary %s, reference %s)",
+				i+1, detail, ctx.BinaryPath, ref.BinaryPath),
+			Class:            class,
+			Binaries:         []string{ctx.BinaryPath, ref.BinaryPath},
+			ReferenceResults: reference,
+		}, nil
+	}
+	return nil, nil
+}
+
+// divergence compares a primary and a reference run and returns the Bug
+// class and a description of the first difference, or "" when they agree.
+func (o *DiffOracle) divergence(p, r Result) (class, detail string) {
+	switch {
+	case p.ExitCode != r.ExitCode:
+		return "diff exit", fmt.Sprintf("exit code %d vs %d", p.ExitCode, r.ExitCode)
+	case p.Stdout != r.Stdout:
+		return "diff stdout", fmt.Sprintf("stdout %q vs %q", truncateForDetail(p.Stdout), truncateForDetail(r.Stdout))
+	case o.compareStderr && p.Stderr != r.Stderr:
+		return "diff stderr", fmt.Sprintf("stderr %q vs %q", truncateForDetail(p.Stderr), truncateForDetail(r.Stderr))
+	}
+	return "", ""
+}
+
+// RunTestCase runs tc on binaryPath, passing the arguments of its running
+// command (the fields after the binary). It fails with ErrStdinWithArgs for
+// a test case with both when executor is not a TestCaseExecutor.
+func RunTestCase(executor Executor, binaryPath string, tc seed.TestCase) (Result, error) {
+	var args []string
+	if fields := strings.Fields(tc.RunningCommand); len(fields) > 1 {
+		args = fields[1:]
+	}
+	var (
+		exitCode       int
+		stdout, stderr string
+		err            error
+	)
+	switch e := executor.(type) {
+	case TestCaseExecutor:
+		exitCode, stdout, stderr, err = e.ExecuteTestCase(binaryPath, tc, args...)
+	default:
+		if tc.Stdin != "" && len(args) > 0 {
+			return Result{}, fmt.Errorf("%s: %T: %w", binaryPath, executor, ErrStdinWithArgs)
+		}
+		if tc.Stdin != "" {
+			exitCode, stdout, stderr, err = executor.ExecuteWithInput(binaryPath, tc.Stdin)
+		} else {
+			exitCode, stdout, stderr, err = executor.ExecuteWithArgs(binaryPath, args...)
+		}
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{Stdout: stdout, Stderr: stderr, ExitCode: exitCode, StdinSupplied: tc.Stdin != ""}, nil
+}
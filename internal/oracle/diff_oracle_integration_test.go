@@ -0,0 +1,78 @@
+//go:build integration
+
+package oracle
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+	executor "github.com/zjy-dev/de-fuzz/internal/seed_executor"
+)
+
+// twoLocalGCCs returns two different gcc versions: DEFUZZ_GCC_PRIMARY and
+// DEFUZZ_GCC_REFERENCE when set, else the first two gcc-N drivers in PATH.
+func twoLocalGCCs(t *testing.T) (string, string) {
+	if primary, reference := os.Getenv("DEFUZZ_GCC_PRIMARY"), os.Getenv("DEFUZZ_GCC_REFERENCE"); primary != "" && reference != "" {
+		return primary, reference
+	}
+	var found []string
+	for version := 7; version <= 20 && len(found) < 2; version++ {
+		if path, err := exec.LookPath(fmt.Sprintf("gcc-%d", version)); err == nil {
+			found = append(found, path)
+		}
+	}
+	if len(found) < 2 {
+		t.Skip("two gcc versions not found; set DEFUZZ_GCC_PRIMARY and DEFUZZ_GCC_REFERENCE")
+	}
+	return found[0], found[1]
+}
+
+func TestDiffOracle_Integration_TwoGCCVersions(t *testing.T) {
+	primaryGCC, referenceGCC := twoLocalGCCs(t)
+	tempDir := t.TempDir()
+
+	build := func(gcc, name, source string) string {
+		sourcePath := filepath.Join(tempDir, name+".c")
+		binaryPath := filepath.Join(tempDir, name)
+		require.NoError(t, os.WriteFile(sourcePath, []byte(source), 0644))
+		output, err := exec.Command(gcc, "-O0", "-o", binaryPath, sourcePath).CombinedOutput()
+		require.NoError(t, err, "%s failed: %s", gcc, output)
+		return binaryPath
+	}
+
+	o, err := New("diff", nil, nil, nil, "")
+	require.NoError(t, err)
+	local := executor.NewOracleExecutorAdapter(10)
+
+	// The same output from both compilers: no bug.
+	stable := "#include <stdio.h>\nint main(int argc, char **argv) { printf(\"%d\\n\", argc); return 0; }\n"
+	s := &seed.Seed{Content: stable, TestCases: []seed.TestCase{{RunningCommand: "./prog a b"}}}
+	bug, err := o.Analyze(s, &AnalyzeContext{
+		BinaryPath: build(primaryGCC, "stable_primary", stable),
+		Executor:   local,
+		Reference:  &ReferenceBuild{BinaryPath: build(referenceGCC, "stable_reference", stable), Executor: local},
+	}, nil)
+	require.NoError(t, err)
+	require.Nil(t, bug)
+
+	// The compiler's major version shows in stdout: a divergence.
+	versioned := "#include <stdio.h>\nint main(void) { printf(\"%d\\n\", __GNUC__); return 0; }\n"
+	primaryBinary := build(primaryGCC, "versioned_primary", versioned)
+	referenceBinary := build(referenceGCC, "versioned_reference", versioned)
+	bug, err = o.Analyze(&seed.Seed{Content: versioned}, &AnalyzeContext{
+		BinaryPath: primaryBinary,
+		Executor:   local,
+		Reference:  &ReferenceBuild{BinaryPath: referenceBinary, Executor: local},
+	}, nil)
+	require.NoError(t, err)
+	if bug == nil {
+		t.Skipf("%s and %s share a major version", primaryGCC, referenceGCC)
+	}
+	require.Equal(t, "diff stdout", bug.Class)
+	require.Equal(t, []string{primaryBinary, referenceBinary}, bug.Binaries)
+}
@@ -0,0 +1,127 @@
+package oracle
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// binaryExecutor returns a fixed run per binary and records the arguments
+// and stdin it was given.
+type binaryExecutor struct {
+	runs  map[string]Result
+	calls []string
+}
+
+func (b *binaryExecutor) ExecuteWithInput(binaryPath string, stdin string) (int, string, string, error) {
+	b.calls = append(b.calls, binaryPath+" <"+stdin)
+	r := b.runs[binaryPath]
+	return r.ExitCode, r.Stdout, r.Stderr, nil
+}
+
+func (b *binaryExecutor) ExecuteWithArgs(binaryPath string, args ...string) (int, string, string, error) {
+	b.calls = append(b.calls, strings.Join(append([]string{binaryPath}, args...), " "))
+	r := b.runs[binaryPath]
+	return r.ExitCode, r.Stdout, r.Stderr, nil
+}
+
+func TestDiffOracle(t *testing.T) {
+	s := &seed.Seed{TestCases: []seed.TestCase{
+		{RunningCommand: "./prog 1"},
+		{RunningCommand: "./prog", Stdin: "AAAA"},
+	}}
+	tests := []struct {
+		name      string
+		primary   Result
+		reference Result
+		options   map[string]interface{}
+		wantClass string
+	}{
+		{name: "same", primary: Result{Stdout: "42\n"}, reference: Result{Stdout: "42\n"}},
+		{name: "exit code", primary: Result{ExitCode: 139}, reference: Result{ExitCode: 0}, wantClass: "diff exit"},
+		{name: "stdout", primary: Result{Stdout: "1\n"}, reference: Result{Stdout: "2\n"}, wantClass: "diff stdout"},
+		{name: "stderr ignored", primary: Result{Stderr: "a"}, reference: Result{Stderr: "b"}},
+		{
+			name:      "stderr compared",
+			primary:   Result{Stderr: "a"},
+			reference: Result{Stderr: "b"},
+			options:   map[string]interface{}{"compare_stderr": true},
+			wantClass: "diff stderr",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := New("diff", tt.options, nil, nil, "")
+			if err != nil {
+				t.Fatalf("New() failed: %v", err)
+			}
+			primary := &binaryExecutor{runs: map[string]Result{"/p": tt.primary}}
+			reference := &binaryExecutor{runs: map[string]Result{"/r": tt.reference}}
+			bug, err := o.Analyze(s, &AnalyzeContext{
+				BinaryPath: "/p",
+				Executor:   primary,
+				Reference:  &ReferenceBuild{BinaryPath: "/r", Executor: reference},
+			}, nil)
+			if err != nil {
+				t.Fatalf("Analyze() failed: %v", err)
+			}
+			if tt.wantClass == "" {
+				if bug != nil {
+					t.Fatalf("Analyze() = %q, want no bug", bug.Description)
+				}
+				if got := strings.Join(reference.calls, ";"); got != "/r 1;/r <AAAA" {
+					t.Errorf("reference runs = %q, want both test cases", got)
+				}
+				return
+			}
+			if bug == nil {
+				t.Fatal("Analyze() found no bug, want a divergence")
+			}
+			if bug.Class != tt.wantClass || len(bug.Binaries) != 2 || bug.Binaries[1] != "/r" {
+				t.Errorf("bug class %q, binaries %q; want %q naming both builds", bug.Class, bug.Binaries, tt.wantClass)
+			}
+			if !strings.Contains(bug.Description, "/p") || !strings.Contains(bug.Description, "/r") {
+				t.Errorf("description %q does not name both binaries", bug.Description)
+			}
+			if len(bug.Results) != 1 || len(bug.ReferenceResults) != 1 {
+				t.Errorf("got %d/%d results, want the first test case's runs", len(bug.Results), len(bug.ReferenceResults))
+			}
+		})
+	}
+}
+
+func TestDiffOracle_NoReferenceBuild(t *testing.T) {
+	o := &DiffOracle{}
+	s := &seed.Seed{}
+	executor := &binaryExecutor{}
+	if _, err := o.Analyze(s, &AnalyzeContext{BinaryPath: "/p", Executor: executor}, nil); err == nil {
+		t.Error("Analyze() without a reference build succeeded, want an error")
+	}
+	bug, err := o.Analyze(s, &AnalyzeContext{BinaryPath: "/p", Executor: executor, Reference: &ReferenceBuild{}}, nil)
+	if err != nil || bug != nil || len(executor.calls) != 0 {
+		t.Errorf("Analyze() = %v, %v after %d runs; want no verdict when the reference compiler rejected the seed", bug, err, len(executor.calls))
+	}
+}
+
+func TestRunTestCase_StdinAndArgs(t *testing.T) {
+	executor := &binaryExecutor{runs: map[string]Result{"/p": {Stdout: "ok"}}}
+	for _, tc := range []seed.TestCase{
+		{RunningCommand: "./prog a"},
+		{RunningCommand: "./prog", Stdin: "in"},
+	} {
+		if r, err := RunTestCase(executor, "/p", tc); err != nil || r.Stdout != "ok" {
+			t.Errorf("RunTestCase(%+v) = %+v, %v", tc, r, err)
+		}
+	}
+	// ExecuteWithInput would drop the arguments.
+	executor.calls = nil
+	if _, err := RunTestCase(executor, "/p", seed.TestCase{RunningCommand: "./prog a", Stdin: "in"}); !errors.Is(err, ErrStdinWithArgs) {
+		t.Errorf("RunTestCase() with stdin and arguments = %v, want ErrStdinWithArgs", err)
+	}
+	if len(executor.calls) != 0 {
+		t.Errorf("calls = %q, want no run", executor.calls)
+	}
+}
@@ -40,6 +40,11 @@ func (o *IBTOracle) Analyze(s *seed.Seed, ctx *AnalyzeContext, results []Result)
 	return o.mechanism().Analyze(s, ctx, results)
 }
 
+// Passive implements PassiveOracle; every IBT checker works on the ELF alone.
+func (o *IBTOracle) Passive() bool {
+	return true
+}
+
 // mechanism builds the MechanismOracle that backs Analyze.
 func (o *IBTOracle) mechanism() *MechanismOracle {
 	return &MechanismOracle{
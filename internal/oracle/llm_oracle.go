@@ -1,6 +1,7 @@
 package oracle
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -78,7 +79,9 @@ func (o *LLMOracle) Analyze(s *seed.Seed, ctx *AnalyzeContext, results []Result)
 		return nil, fmt.Errorf("failed to build analysis prompt: %w", err)
 	}
 
-	description, err := o.llm.Analyze(o.llmContext, analysisPrompt, s, feedback)
+	// Oracle.Analyze carries no context; the client's request timeout still
+	// bounds the call.
+	description, err := o.llm.Analyze(context.Background(), o.llmContext, analysisPrompt, s, feedback)
 	if err != nil {
 		// If LLM analysis fails, fall back to basic description
 		description = fmt.Sprintf("Execution anomalies detected:\n%s", feedback)
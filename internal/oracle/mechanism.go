@@ -2,6 +2,7 @@ package oracle
 
 import (
 	"fmt"
+	"slices"
 	"sort"
 	"strings"
 
@@ -82,6 +83,7 @@ func (m *MechanismOracle) Analyze(s *seed.Seed, ctx *AnalyzeContext, results []R
 		Seed:        s,
 		Results:     results,
 		Description: m.formatDescription(all, violations),
+		Class:       violationClass(violations),
 	}, nil
 }
 
@@ -107,6 +109,17 @@ func (m *MechanismOracle) runPhase(ctx *CheckContext, category InvariantCategory
 	return out
 }
 
+// violationClass returns the sorted IDs of the violated invariants, the
+// Bug.Class of a mechanism bug.
+func violationClass(violations []InvariantResult) string {
+	ids := make([]string, 0, len(violations))
+	for _, v := range violations {
+		ids = append(ids, v.ID)
+	}
+	sort.Strings(ids)
+	return strings.Join(slices.Compact(ids), ",")
+}
+
 // filterByVerdict returns only those results whose Verdict matches.
 func filterByVerdict(rs []InvariantResult, want InvariantVerdict) []InvariantResult {
 	var out []InvariantResult
@@ -102,6 +102,9 @@ func TestMechanism_OneFailReportsBug(t *testing.T) {
 	if !strings.Contains(bug.Description, "INV-A") {
 		t.Errorf("description should list passed invariants too, got: %s", bug.Description)
 	}
+	if bug.Class != "INV-B" {
+		t.Errorf("class should name the violated invariant, got: %q", bug.Class)
+	}
 }
 
 // TestMechanism_ResultsForwardedToBug asserts the engine-supplied Result
@@ -9,13 +9,44 @@ type Result struct {
 	Stdout   string
 	Stderr   string
 	ExitCode int
+	// StdinSupplied reports whether the run was fed standard input, so an
+	// oracle can tell a program blocked on input from one that hung.
+	StdinSupplied bool
 }
 
+// BugTypeICE is the Bug.Type of internal compiler errors: the compiler
+// crashed building the seed, and no oracle was involved.
+const BugTypeICE = "ICE"
+
 // Bug represents a discovered vulnerability.
 type Bug struct {
 	Seed        *seed.Seed
 	Results     []Result
 	Description string
+
+	// Type tells bugs the engine finds itself (BugTypeICE) from the oracle's
+	// verdicts, which leave it empty.
+	Type string
+
+	// Class names the kind of failure when the oracle can tell, e.g. the
+	// invariants a mechanism oracle found violated. It is part of the
+	// Signature.
+	Class string
+
+	// Signature identifies the weakness the bug shows (see Signature). The
+	// engine sets it and records a bug whose signature it has seen as an
+	// occurrence of the first: Occurrences lists the IDs of the other seeds
+	// that triggered it, and DuplicateOf, on the later bugs, the seed ID of
+	// the first.
+	Signature   string
+	Occurrences []uint64
+	DuplicateOf uint64
+
+	// Binaries lists the binaries that show the bug when the oracle compares
+	// several builds of the seed, e.g. the primary and reference builds the
+	// diff oracle ran; ReferenceResults holds the reference build's runs.
+	Binaries         []string
+	ReferenceResults []Result
 }
 
 // AnalyzeContext provides context for Oracle analysis.
@@ -25,6 +56,20 @@ type AnalyzeContext struct {
 	BinaryPath string
 	// Executor is an interface to run the binary (optional, can be nil for passive oracles)
 	Executor Executor
+	// Reference is the seed's build by the reference compiler in differential
+	// mode, nil otherwise
+	Reference *ReferenceBuild
+}
+
+// ReferenceBuild is a seed built by the reference compiler, for oracles that
+// compare it against the primary build.
+type ReferenceBuild struct {
+	// BinaryPath is the reference binary, empty when the reference compiler
+	// rejected the seed
+	BinaryPath string
+	// Executor runs the reference binary; its QEMU settings may differ from
+	// the primary Executor's
+	Executor Executor
 }
 
 // Executor is a minimal interface for running binaries.
@@ -44,6 +89,24 @@ type Oracle interface {
 	Analyze(s *seed.Seed, ctx *AnalyzeContext, results []Result) (*Bug, error)
 }
 
+// PassiveOracle is an optional interface for oracles that only inspect the
+// compiled binary and never run it. Oracles that do not implement it are
+// assumed to need an Executor.
+type PassiveOracle interface {
+	Passive() bool
+}
+
+// NeedsExecution reports whether o has to run seed binaries to reach a verdict.
+func NeedsExecution(o Oracle) bool {
+	if o == nil {
+		return false
+	}
+	if p, ok := o.(PassiveOracle); ok && p.Passive() {
+		return false
+	}
+	return true
+}
+
 // IsCrashExit determines if an exit code indicates a crash.
 // Common crash signals: SIGSEGV (11), SIGBUS (7), SIGABRT (6), SIGFPE (8), SIGILL (4)
 // On Unix, signal exits are typically 128 + signal number.
@@ -0,0 +1,70 @@
+package oracle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// signatureLen is how many hex digits of the hash a signature keeps.
+const signatureLen = 16
+
+// topFramePatterns find the innermost function of a stack trace in stderr:
+// sanitizer and gdb frames ("#0 0x4011d6 in main ..."), then glibc
+// backtraces ("./prog(main+0x1d)[0x4011d6]").
+var topFramePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`#0 +0x[0-9a-fA-F]+ in ([A-Za-z_][\w:.]*)`),
+	regexp.MustCompile(`\(([A-Za-z_][\w.]*)\+0x[0-9a-fA-F]+\)\s*\[0x`),
+}
+
+// Signature returns a short hash identifying the weakness bug shows, so
+// bugs of different seeds with the same signature can be counted as one.
+// It covers the oracle type, bug.Class, how the runs ended (the first
+// crash signal or non-zero exit, from bug.Results or else the signals the
+// seed's runs were noted ending with) and the top stack frame of the first
+// stack trace in stderr. Descriptions are left out: they name sizes,
+// offsets and paths that differ from seed to seed. bug.Type, when set,
+// stands in for the oracle type.
+func Signature(oracleType string, bug *Bug) string {
+	if bug.Type != "" {
+		oracleType = bug.Type
+	}
+	parts := []string{oracleType, bug.Class, exitClass(bug), topFrame(bug.Results)}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])[:signatureLen]
+}
+
+// exitClass describes how the bug's runs ended: "signal 11" for the first
+// run killed by a signal, else "exit 1" for the first non-zero exit, else
+// the first signal noted in the seed's metadata, else "".
+func exitClass(bug *Bug) string {
+	for _, r := range bug.Results {
+		if r.ExitCode > 128 && r.ExitCode <= 128+64 {
+			return fmt.Sprintf("signal %d", r.ExitCode-128)
+		}
+	}
+	for _, r := range bug.Results {
+		if r.ExitCode != 0 {
+			return fmt.Sprintf("exit %d", r.ExitCode)
+		}
+	}
+	if bug.Seed != nil && len(bug.Seed.Meta.ExecSignals) > 0 {
+		return bug.Seed.Meta.ExecSignals[0]
+	}
+	return ""
+}
+
+// topFrame returns the function of the top frame of the first stack trace
+// in the results' stderr, or "".
+func topFrame(results []Result) string {
+	for _, r := range results {
+		for _, pattern := range topFramePatterns {
+			if m := pattern.FindStringSubmatch(r.Stderr); m != nil {
+				return m[1]
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,58 @@
+package oracle
+
+import (
+	"testing"
+
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+func TestSignature(t *testing.T) {
+	asan := "==1==ERROR: AddressSanitizer: stack-buffer-overflow\n    #0 0x4011d6 in copy_input /tmp/a.c:5\n    #1 0x401200 in main"
+	bug := func(class string, exitCode int, stderr, description string) *Bug {
+		return &Bug{
+			Seed:        &seed.Seed{},
+			Results:     []Result{{ExitCode: 0}, {ExitCode: exitCode, Stderr: stderr}},
+			Description: description,
+			Class:       class,
+		}
+	}
+	base := Signature("crash", bug("", 139, asan, "overflow of 24 bytes"))
+
+	for _, tt := range []struct {
+		name       string
+		oracleType string
+		bug        *Bug
+		same       bool
+	}{
+		{"other description", "crash", bug("", 139, asan, "overflow of 40 bytes"), true},
+		{"other oracle", "canary", bug("", 139, asan, "overflow of 24 bytes"), false},
+		{"other class", "crash", bug("INV-SP-L01", 139, asan, "overflow of 24 bytes"), false},
+		{"other signal", "crash", bug("", 134, asan, "overflow of 24 bytes"), false},
+		{"other top frame", "crash", bug("", 139, "    #0 0x4011d6 in parse_args /tmp/a.c:9", "overflow of 24 bytes"), false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Signature(tt.oracleType, tt.bug); (got == base) != tt.same {
+				t.Errorf("Signature() = %s, base %s; want same = %v", got, base, tt.same)
+			}
+		})
+	}
+	ice := &Bug{Type: BugTypeICE, Class: "in expand_expr_real_1"}
+	if Signature("crash", ice) != Signature("canary", ice) {
+		t.Error("Signature() of an ICE depends on the oracle type")
+	}
+
+	if len(base) != signatureLen {
+		t.Errorf("Signature() = %q, want %d hex digits", base, signatureLen)
+	}
+
+	// Active oracles run the binary themselves; the signals noted on the
+	// seed stand in for results
+	noted := &Bug{Seed: &seed.Seed{Meta: seed.Metadata{ExecSignals: []string{"SIGABRT"}}}}
+	if exitClass(noted) != "SIGABRT" {
+		t.Errorf("exitClass() = %q, want the noted SIGABRT", exitClass(noted))
+	}
+	glibc := []Result{{Stderr: "./prog(vuln+0x1d)[0x4011d6]\n./prog(main+0x10)[0x401200]"}}
+	if frame := topFrame(glibc); frame != "vuln" {
+		t.Errorf("topFrame() = %q, want vuln", frame)
+	}
+}
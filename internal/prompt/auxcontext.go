@@ -0,0 +1,87 @@
+package prompt
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/zjy-dev/de-fuzz/internal/logger"
+)
+
+// notAvailable is the placeholder content of a missing auxiliary context file.
+const notAvailable = "Not available for now"
+
+// DefaultAuxContextFiles are read from the strategy base directory when
+// Builder.AuxContextFiles is empty.
+var DefaultAuxContextFiles = []string{"stack_layout.md"}
+
+// DefaultAuxContextMaxBytes caps each auxiliary context file.
+const DefaultAuxContextMaxBytes = 16 * 1024
+
+// AuxContext is one auxiliary context file rendered into a prompt.
+type AuxContext struct {
+	Name    string // File name as configured, e.g. "stack_layout.md"
+	Title   string // Heading derived from the file name, e.g. "Stack Layout"
+	Content string // File content, clipped to the size cap
+}
+
+// auxContextFiles returns the configured file list or the default.
+func (b *Builder) auxContextFiles() []string {
+	if len(b.AuxContextFiles) == 0 {
+		return DefaultAuxContextFiles
+	}
+	return b.AuxContextFiles
+}
+
+// readAuxContext reads the auxiliary context files relative to basePath, in
+// order. Missing files read as "Not available for now"; with skipMissing
+// they are left out instead.
+func (b *Builder) readAuxContext(basePath string, skipMissing bool) ([]AuxContext, error) {
+	var result []AuxContext
+	for _, name := range b.auxContextFiles() {
+		path := name
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(basePath, name)
+		}
+		content, err := readFileOrDefault(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read auxiliary context %s: %w", name, err)
+		}
+		if content == notAvailable && skipMissing {
+			continue
+		}
+		result = append(result, AuxContext{
+			Name:    name,
+			Title:   auxContextTitle(name),
+			Content: b.clipAuxContext(name, content),
+		})
+	}
+	return result, nil
+}
+
+// clipAuxContext cuts content to AuxContextMaxBytes at a line boundary.
+func (b *Builder) clipAuxContext(name, content string) string {
+	limit := b.AuxContextMaxBytes
+	if limit <= 0 || len(content) <= limit {
+		return content
+	}
+
+	cut := content[:limit]
+	if nl := strings.LastIndexByte(cut, '\n'); nl > 0 {
+		cut = cut[:nl+1]
+	}
+	omitted := len(content) - len(cut)
+	logger.Warn("[Prompt] auxiliary context %s truncated: %d of %d bytes omitted", name, omitted, len(content))
+	return strings.TrimRight(cut, "\n") + fmt.Sprintf("\n... [%d bytes omitted]\n", omitted)
+}
+
+// auxContextTitle turns "calling_convention.md" into "Calling Convention".
+func auxContextTitle(name string) string {
+	base := filepath.Base(name)
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	words := strings.FieldsFunc(base, func(r rune) bool { return r == '_' || r == '-' || r == ' ' })
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(words, " ")
+}
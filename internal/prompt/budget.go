@@ -0,0 +1,203 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zjy-dev/de-fuzz/internal/logger"
+)
+
+// TokenCounter estimates how many tokens a model will see for text.
+type TokenCounter func(text string) int
+
+// EstimateTokens is the default TokenCounter: roughly four characters per token.
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// EstimateTokens estimates the token count of text with the builder's
+// Tokenizer, falling back to the chars/4 heuristic.
+func (b *Builder) EstimateTokens(text string) int {
+	if b.Tokenizer != nil {
+		return b.Tokenizer(text)
+	}
+	return EstimateTokens(text)
+}
+
+// tokenBudget returns the effective prompt budget: the smaller of
+// TokenBudget and the context-window cap, 0 meaning unlimited.
+func (b *Builder) tokenBudget() int {
+	if b.contextBudget > 0 && (b.TokenBudget <= 0 || b.contextBudget < b.TokenBudget) {
+		return b.contextBudget
+	}
+	return b.TokenBudget
+}
+
+// fitTargetToBudget renders a target prompt and, when it exceeds TokenBudget,
+// shrinks the variable-size inputs until it fits. The function context window
+// around the target lines is trimmed first; for a refined prompt (div not
+// nil) the oldest prior attempts are dropped next and the middle of the
+// failed mutation is elided; last the middle of the base seed is elided.
+// Everything else (task, critical rules, output format) comes from the
+// template and is never cut. The caller's ctx and div are not modified.
+func (b *Builder) fitTargetToBudget(ctx *TargetContext, div *DivergenceInfo, render func(*TargetContext, *DivergenceInfo) (string, error)) (string, error) {
+	budget := b.tokenBudget()
+	prompt, err := render(ctx, div)
+	if err != nil || budget <= 0 {
+		return prompt, err
+	}
+	original := b.EstimateTokens(prompt)
+	if original <= budget {
+		return prompt, nil
+	}
+
+	trimmed := *ctx
+	var trimmedDiv *DivergenceInfo
+	if div != nil {
+		copied := *div
+		trimmedDiv = &copied
+	}
+	fits := func() (bool, error) {
+		prompt, err = render(&trimmed, trimmedDiv)
+		if err != nil {
+			return false, err
+		}
+		return b.EstimateTokens(prompt) <= budget, nil
+	}
+	// elide shrinks the middles of codes by the same amount until the
+	// prompt fits or only the marker is left.
+	elide := func(codes ...*string) (bool, error) {
+		originals := make([][]string, len(codes))
+		longest := 0
+		for i, code := range codes {
+			originals[i] = strings.Split(*code, "\n")
+			longest = max(longest, len(originals[i]))
+		}
+		for keep := longest / 4; keep >= 0; keep /= 2 {
+			for i, code := range codes {
+				if *code != "" {
+					*code = elideMiddle(originals[i], keep)
+				}
+			}
+			ok, err := fits()
+			if err != nil || ok || keep == 0 {
+				return ok, err
+			}
+		}
+		return false, nil
+	}
+
+	// 1. Narrow the annotated function window around the target lines.
+	if ctx.FunctionCode != "" {
+		lines := strings.Split(strings.TrimRight(ctx.FunctionCode, "\n"), "\n")
+		for radius := len(lines) / 2; radius >= 0; radius /= 2 {
+			trimmed.FunctionCode = targetWindow(lines, radius)
+			ok, err := fits()
+			if err != nil {
+				return "", err
+			}
+			if ok {
+				b.logTruncation(ctx, budget, original, prompt)
+				return prompt, nil
+			}
+			if radius == 0 {
+				break
+			}
+		}
+	}
+
+	if trimmedDiv != nil {
+		// 2. Drop the oldest prior attempts.
+		for len(trimmedDiv.PriorAttempts) > 0 {
+			trimmedDiv.PriorAttempts = trimmedDiv.PriorAttempts[1:]
+			ok, err := fits()
+			if err != nil {
+				return "", err
+			}
+			if ok {
+				b.logTruncation(ctx, budget, original, prompt)
+				return prompt, nil
+			}
+		}
+
+		// 3. Elide the middle of the failed mutation.
+		if trimmedDiv.MutatedSeedCode != "" {
+			ok, err := elide(&trimmedDiv.MutatedSeedCode)
+			if err != nil {
+				return "", err
+			}
+			if ok {
+				b.logTruncation(ctx, budget, original, prompt)
+				return prompt, nil
+			}
+		}
+	}
+
+	// 4. Elide the middle of the base seed, keeping its head and tail. A
+	// refined prompt shows the divergence's copy when it has one.
+	codes := []*string{&trimmed.BaseSeedCode}
+	if trimmedDiv != nil {
+		codes = append(codes, &trimmedDiv.BaseSeedCode)
+	}
+	if _, err := elide(codes...); err != nil {
+		return "", err
+	}
+
+	b.logTruncation(ctx, budget, original, prompt)
+	return prompt, nil
+}
+
+func (b *Builder) logTruncation(ctx *TargetContext, budget, original int, prompt string) {
+	final := b.EstimateTokens(prompt)
+	if final > budget {
+		logger.Warn("[Prompt] %s BB%d: prompt still ~%d tokens after truncation (budget %d, was ~%d)",
+			ctx.TargetFunction, ctx.TargetBBID, final, budget, original)
+		return
+	}
+	logger.Info("[Prompt] %s BB%d: truncated prompt from ~%d to ~%d tokens (budget %d)",
+		ctx.TargetFunction, ctx.TargetBBID, original, final, budget)
+}
+
+// targetWindow keeps the annotated target lines ([→]) plus radius lines of
+// context on either side of the first and last of them. The trailing BB
+// legend ("// ..." lines) is always kept.
+func targetWindow(lines []string, radius int) string {
+	legend := len(lines)
+	for legend > 0 && strings.HasPrefix(lines[legend-1], "//") {
+		legend--
+	}
+	lines, trailer := lines[:legend], lines[legend:]
+
+	first, last := -1, -1
+	for i, line := range lines {
+		if strings.HasPrefix(line, "[→]") {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first < 0 {
+		// No marked target; keep the middle of the window.
+		first, last = len(lines)/2, len(lines)/2
+	}
+
+	start := max(first-radius, 0)
+	end := min(last+radius+1, len(lines))
+	return strings.Join(append(lines[start:end:end], trailer...), "\n") + "\n"
+}
+
+// elideMiddle keeps the first and last keep lines and replaces the rest with a marker.
+func elideMiddle(lines []string, keep int) string {
+	if 2*keep >= len(lines) {
+		return strings.Join(lines, "\n")
+	}
+	elided := len(lines) - 2*keep
+	marker := fmt.Sprintf("/* ... %d lines elided to fit the prompt token budget ... */", elided)
+
+	out := make([]string, 0, 2*keep+1)
+	out = append(out, lines[:keep]...)
+	out = append(out, marker)
+	out = append(out, lines[len(lines)-keep:]...)
+	return strings.Join(out, "\n")
+}
@@ -0,0 +1,232 @@
+package prompt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func budgetTestContext() *TargetContext {
+	var fn strings.Builder
+	for i := 1; i <= 400; i++ {
+		prefix := "[✗]"
+		if i == 200 || i == 201 {
+			prefix = "[→]"
+		}
+		fmt.Fprintf(&fn, "%s %4d: statement_%d ();\n", prefix, i, i)
+	}
+
+	var seedCode strings.Builder
+	seedCode.WriteString("int main() {\n")
+	for i := 0; i < 300; i++ {
+		fmt.Fprintf(&seedCode, "  volatile int v%d = %d;\n", i, i)
+	}
+	seedCode.WriteString("  return 0;\n}")
+
+	return &TargetContext{
+		TargetFunction: "expand_used_vars",
+		TargetBBID:     9,
+		TargetLines:    []int{200, 201},
+		SuccessorCount: 2,
+		BaseSeedCode:   seedCode.String(),
+		FunctionCode:   fn.String(),
+	}
+}
+
+func TestBuildConstraintSolvingPrompt_TokenBudget(t *testing.T) {
+	ctx := budgetTestContext()
+	origFunctionCode := ctx.FunctionCode
+
+	unlimited := NewBuilder(0, "", nil)
+	full, err := unlimited.BuildConstraintSolvingPrompt(ctx)
+	if err != nil {
+		t.Fatalf("BuildConstraintSolvingPrompt() failed: %v", err)
+	}
+
+	t.Run("trims function window first", func(t *testing.T) {
+		b := NewBuilder(0, "", nil)
+		// Enough room for the whole seed but not the whole function window
+		b.TokenBudget = b.EstimateTokens(full) - EstimateTokens(origFunctionCode)/2
+
+		prompt, err := b.BuildConstraintSolvingPrompt(ctx)
+		if err != nil {
+			t.Fatalf("BuildConstraintSolvingPrompt() failed: %v", err)
+		}
+		if got := b.EstimateTokens(prompt); got > b.TokenBudget {
+			t.Errorf("prompt is ~%d tokens, budget %d", got, b.TokenBudget)
+		}
+		for _, want := range []string{"[→]  200:", "[→]  201:", "volatile int v150", "## Output Format"} {
+			if !strings.Contains(prompt, want) {
+				t.Errorf("prompt should still contain %q", want)
+			}
+		}
+		if strings.Contains(prompt, "[✗]    1:") {
+			t.Error("function window should have been narrowed")
+		}
+		if strings.Contains(prompt, "lines elided") {
+			t.Error("base seed should not be elided while trimming the window suffices")
+		}
+	})
+
+	t.Run("elides base seed middle", func(t *testing.T) {
+		b := NewBuilder(0, "", nil)
+		b.TokenBudget = b.EstimateTokens(full) - EstimateTokens(origFunctionCode) - EstimateTokens(ctx.BaseSeedCode)/2
+
+		prompt, err := b.BuildConstraintSolvingPrompt(ctx)
+		if err != nil {
+			t.Fatalf("BuildConstraintSolvingPrompt() failed: %v", err)
+		}
+		if got := b.EstimateTokens(prompt); got > b.TokenBudget {
+			t.Errorf("prompt is ~%d tokens, budget %d", got, b.TokenBudget)
+		}
+		for _, want := range []string{"lines elided to fit the prompt token budget", "int main() {", "return 0;", "## Output Format", "LANGUAGE CONSTRAINTS"} {
+			if !strings.Contains(prompt, want) {
+				t.Errorf("prompt should contain %q", want)
+			}
+		}
+	})
+
+	if ctx.FunctionCode != origFunctionCode {
+		t.Error("BuildConstraintSolvingPrompt() must not modify the caller's context")
+	}
+}
+
+func TestBuildRefinedPrompt_TokenBudget(t *testing.T) {
+	ctx := budgetTestContext()
+	var mutated strings.Builder
+	mutated.WriteString("int main() {\n")
+	for i := 0; i < 300; i++ {
+		fmt.Fprintf(&mutated, "  volatile long m%d = %d;\n", i, i)
+	}
+	mutated.WriteString("  return 1;\n}")
+	// The engine passes the base seed in the divergence too, and the
+	// template shows that copy
+	div := &DivergenceInfo{
+		DivergentFunction: "expand_stack_vars",
+		MutatedSeedCode:   mutated.String(),
+		BaseSeedCode:      ctx.BaseSeedCode,
+	}
+	for i := 1; i <= 3; i++ {
+		div.PriorAttempts = append(div.PriorAttempts, FailedAttempt{
+			Attempt: i,
+			Code:    strings.Repeat(fmt.Sprintf("int attempt%d;\n", i), 80),
+			Reason:  "diverged at expand_stack_vars",
+		})
+	}
+	origMutated := div.MutatedSeedCode
+
+	full, err := NewBuilder(0, "", nil).BuildRefinedPrompt(ctx, div)
+	if err != nil {
+		t.Fatalf("BuildRefinedPrompt() failed: %v", err)
+	}
+	fullTokens := EstimateTokens(full)
+
+	t.Run("drops oldest prior attempts before eliding seeds", func(t *testing.T) {
+		b := NewBuilder(0, "", nil)
+		b.TokenBudget = fullTokens - EstimateTokens(ctx.FunctionCode) - 100
+
+		prompt, err := b.BuildRefinedPrompt(ctx, div)
+		if err != nil {
+			t.Fatalf("BuildRefinedPrompt() failed: %v", err)
+		}
+		if got := b.EstimateTokens(prompt); got > b.TokenBudget {
+			t.Errorf("prompt is ~%d tokens, budget %d", got, b.TokenBudget)
+		}
+		if strings.Contains(prompt, "int attempt1;") || !strings.Contains(prompt, "int attempt3;") {
+			t.Error("the oldest prior attempt should be dropped, the newest kept")
+		}
+		if !strings.Contains(prompt, "older attempt(s) not shown") {
+			t.Error("dropped attempts should be counted as omitted")
+		}
+		if strings.Contains(prompt, "lines elided") {
+			t.Error("seeds should not be elided while dropping attempts suffices")
+		}
+	})
+
+	t.Run("elides the mutated and base seed shown", func(t *testing.T) {
+		b := NewBuilder(0, "", nil)
+		b.TokenBudget = fullTokens - EstimateTokens(ctx.FunctionCode) - EstimateTokens(div.MutatedSeedCode) -
+			EstimateTokens(div.BaseSeedCode)/2
+
+		prompt, err := b.BuildRefinedPrompt(ctx, div)
+		if err != nil {
+			t.Fatalf("BuildRefinedPrompt() failed: %v", err)
+		}
+		if got := b.EstimateTokens(prompt); got > b.TokenBudget {
+			t.Errorf("prompt is ~%d tokens, budget %d", got, b.TokenBudget)
+		}
+		if strings.Count(prompt, "lines elided to fit the prompt token budget") != 2 {
+			t.Error("both the failed mutation and the base seed should be elided")
+		}
+		// The failed mutation goes first; the base seed keeps its head
+		for _, want := range []string{"volatile int v0 = 0;", "expand_stack_vars", "## Output Format"} {
+			if !strings.Contains(prompt, want) {
+				t.Errorf("prompt should still contain %q", want)
+			}
+		}
+	})
+
+	if div.MutatedSeedCode != origMutated || len(div.PriorAttempts) != 3 {
+		t.Error("BuildRefinedPrompt() must not modify the caller's divergence info")
+	}
+}
+
+func TestBuilder_EstimateTokens_CustomTokenizer(t *testing.T) {
+	b := NewBuilder(0, "", nil)
+	if got := b.EstimateTokens("abcdefgh"); got != 2 {
+		t.Errorf("default estimate = %d, want 2", got)
+	}
+
+	b.Tokenizer = func(text string) int { return len(strings.Fields(text)) }
+	if got := b.EstimateTokens("one two three"); got != 3 {
+		t.Errorf("custom estimate = %d, want 3", got)
+	}
+}
+
+func TestPromptService_ContextLimit(t *testing.T) {
+	baseDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(baseDir, "constraint.md"), []byte("base"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	understandingPath := filepath.Join(t.TempDir(), "understanding.md")
+	understanding := strings.Repeat("background ", 400)
+	if err := os.WriteFile(understandingPath, []byte(understanding), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ps, err := NewPromptService(baseDir, understandingPath, NewBuilder(0, "", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, full, err := ps.GetConstraintPrompt(budgetTestContext())
+	if err != nil {
+		t.Fatal(err)
+	}
+	limit := EstimateTokens(full) / 2
+	ps.SetContextLimit(limit)
+
+	system, user, err := ps.GetConstraintPrompt(budgetTestContext())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ps.EstimateTokens(system) + ps.EstimateTokens(user); got > limit {
+		t.Errorf("system + user is ~%d tokens, limit %d", got, limit)
+	}
+	if !strings.Contains(user, "[→]  200:") {
+		t.Error("trimmed prompt should keep the target lines")
+	}
+	if ps.builder.contextBudget != 0 {
+		t.Error("the context cap should be lifted after the prompt is built")
+	}
+
+	ps.UseUnderstandingSummary("short summary")
+	system, _, err = ps.GetConstraintPrompt(budgetTestContext())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(system, "short summary") || strings.Contains(system, understanding) {
+		t.Error("system prompt should carry the summary instead of the full understanding")
+	}
+}
@@ -0,0 +1,126 @@
+package prompt
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/zjy-dev/de-fuzz/internal/logger"
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// CandidateDelimiter starts each candidate in a multi-candidate response,
+// followed by the candidate number: "### CANDIDATE 1".
+const CandidateDelimiter = "### CANDIDATE"
+
+// reCandidateHeader matches a candidate header line. The leading #'s and a
+// trailing colon are optional since models do not always copy them.
+var reCandidateHeader = regexp.MustCompile(`(?m)^[ \t]*#{0,4}[ \t]*CANDIDATE[ \t]+\d+[ \t]*:?[ \t]*$`)
+
+// candidatesPerCall returns how many candidates one constraint-solving
+// request asks for (at least 1).
+func (b *Builder) candidatesPerCall() int {
+	return max(b.CandidatesPerCall, 1)
+}
+
+// candidatesFormat is appended to the constraint-solving output format when
+// more than one candidate is requested.
+func (b *Builder) candidatesFormat() string {
+	n := b.candidatesPerCall()
+	if n == 1 {
+		return ""
+	}
+	if b.StructuredOutput {
+		return fmt.Sprintf(`
+
+## Multiple Candidates
+
+Give up to %d DIFFERENT candidates, each trying a different way to reach the target.
+Wrap them in one JSON object: {"candidates": [<candidate>, <candidate>, ...]}, where each
+<candidate> is an object in the format above.`, n)
+	}
+	return fmt.Sprintf(`
+
+## Multiple Candidates
+
+Give up to %d DIFFERENT candidates, each trying a different way to reach the target.
+Start each candidate with a numbered header line, then give it in the format above:
+
+%s 1
+...
+%s 2
+...`, n, CandidateDelimiter, CandidateDelimiter)
+}
+
+// ParseMultiCandidateResponse parses a constraint-solving response into one
+// seed per candidate. Fewer candidates than requested are fine, and a
+// response without candidate headers is parsed as a single candidate.
+// Candidates that fail to parse are skipped; an error is returned only when
+// none of them parse. Extra candidates beyond CandidatesPerCall are dropped.
+func (b *Builder) ParseMultiCandidateResponse(response string) ([]*seed.Seed, error) {
+	n := b.candidatesPerCall()
+	if n == 1 {
+		s, err := b.ParseLLMResponse(response)
+		if err != nil {
+			return nil, err
+		}
+		return []*seed.Seed{s}, nil
+	}
+
+	chunks := splitCandidates(response, b.StructuredOutput)
+	var seeds []*seed.Seed
+	var firstErr error
+	for i, chunk := range chunks {
+		s, err := b.ParseLLMResponse(chunk)
+		if err != nil {
+			logger.Warn("[Prompt] candidate %d/%d could not be parsed: %v", i+1, len(chunks), err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		seeds = append(seeds, s)
+	}
+	if len(seeds) == 0 {
+		return nil, fmt.Errorf("no parsable candidate in response: %w", firstErr)
+	}
+	if len(seeds) > n {
+		logger.Warn("[Prompt] response has %d candidates, keeping the first %d", len(seeds), n)
+		seeds = seeds[:n]
+	}
+	return seeds, nil
+}
+
+// splitCandidates cuts a response into candidate chunks. Structured responses
+// carry them in a "candidates" array; plain ones are split on header lines.
+func splitCandidates(response string, structured bool) []string {
+	if structured {
+		var wrapper struct {
+			Candidates []json.RawMessage `json:"candidates"`
+		}
+		err := json.NewDecoder(strings.NewReader(seed.ExtractJSONObject(response))).Decode(&wrapper)
+		if err == nil && len(wrapper.Candidates) > 0 {
+			chunks := make([]string, len(wrapper.Candidates))
+			for i, raw := range wrapper.Candidates {
+				chunks[i] = string(raw)
+			}
+			return chunks
+		}
+		return []string{response}
+	}
+
+	headers := reCandidateHeader.FindAllStringIndex(response, -1)
+	if len(headers) == 0 {
+		return []string{response}
+	}
+	chunks := make([]string, 0, len(headers))
+	for i, h := range headers {
+		end := len(response)
+		if i+1 < len(headers) {
+			end = headers[i+1][0]
+		}
+		chunks = append(chunks, strings.TrimSpace(response[h[1]:end]))
+	}
+	return chunks
+}
@@ -0,0 +1,127 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuilder_CandidatesFormat(t *testing.T) {
+	ctx := &TargetContext{TargetFunction: "expand_used_vars", TargetBBID: 9, TargetLines: []int{200}}
+
+	single, err := NewBuilder(0, "", nil).BuildConstraintSolvingPrompt(ctx)
+	if err != nil {
+		t.Fatalf("BuildConstraintSolvingPrompt() failed: %v", err)
+	}
+	if strings.Contains(single, CandidateDelimiter) {
+		t.Error("single-candidate prompt should not mention candidate headers")
+	}
+
+	b := NewBuilder(0, "", nil)
+	b.CandidatesPerCall = 3
+	multi, err := b.BuildConstraintSolvingPrompt(ctx)
+	if err != nil {
+		t.Fatalf("BuildConstraintSolvingPrompt() failed: %v", err)
+	}
+	for _, want := range []string{"up to 3 DIFFERENT candidates", CandidateDelimiter + " 1", CandidateDelimiter + " 2"} {
+		if !strings.Contains(multi, want) {
+			t.Errorf("multi-candidate prompt should contain %q", want)
+		}
+	}
+
+	b.StructuredOutput = true
+	structured, err := b.BuildConstraintSolvingPrompt(ctx)
+	if err != nil {
+		t.Fatalf("BuildConstraintSolvingPrompt() failed: %v", err)
+	}
+	if !strings.Contains(structured, `{"candidates": [`) {
+		t.Error("structured multi-candidate prompt should describe the candidates array")
+	}
+}
+
+func TestBuilder_ParseMultiCandidateResponse(t *testing.T) {
+	b := NewBuilder(0, "", nil)
+	b.CandidatesPerCall = 3
+
+	tests := []struct {
+		name     string
+		response string
+		want     []string
+	}{
+		{
+			name: "all candidates",
+			response: "### CANDIDATE 1\n```c\nint a;\n```\n" +
+				"### CANDIDATE 2\n```c\nint b;\n```\n" +
+				"### CANDIDATE 3\n```c\nint c;\n```\n",
+			want: []string{"int a;", "int b;", "int c;"},
+		},
+		{
+			name:     "fewer than asked",
+			response: "### CANDIDATE 1\n```c\nint a;\n```\n\n### CANDIDATE 2\n```c\nint b;\n```",
+			want:     []string{"int a;", "int b;"},
+		},
+		{
+			name:     "loose headers",
+			response: "CANDIDATE 1:\n```c\nint a;\n```\n## CANDIDATE 2\n```c\nint b;\n```",
+			want:     []string{"int a;", "int b;"},
+		},
+		{
+			name:     "no headers is a single candidate",
+			response: "```c\nint only;\n```",
+			want:     []string{"int only;"},
+		},
+		{
+			name: "extra candidates are dropped",
+			response: "### CANDIDATE 1\n```c\nint a;\n```\n### CANDIDATE 2\n```c\nint b;\n```\n" +
+				"### CANDIDATE 3\n```c\nint c;\n```\n### CANDIDATE 4\n```c\nint d;\n```\n",
+			want: []string{"int a;", "int b;", "int c;"},
+		},
+		{
+			name: "unparsable candidate is skipped",
+			response: "### CANDIDATE 1\n```c\nint a;\n```\n### CANDIDATE 2\n\n" +
+				"### CANDIDATE 3\n```c\nint c;\n```\n",
+			want: []string{"int a;", "int c;"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seeds, err := b.ParseMultiCandidateResponse(tt.response)
+			if err != nil {
+				t.Fatalf("ParseMultiCandidateResponse() failed: %v", err)
+			}
+			if len(seeds) != len(tt.want) {
+				t.Fatalf("got %d candidates, want %d", len(seeds), len(tt.want))
+			}
+			for i, s := range seeds {
+				if strings.TrimSpace(s.Content) != tt.want[i] {
+					t.Errorf("candidate %d = %q, want %q", i+1, s.Content, tt.want[i])
+				}
+			}
+		})
+	}
+
+	t.Run("structured", func(t *testing.T) {
+		sb := NewBuilder(0, "", nil)
+		sb.CandidatesPerCall = 2
+		sb.StructuredOutput = true
+		seeds, err := sb.ParseMultiCandidateResponse(`{"candidates": [{"source": "int a;"}, {"source": "int b;", "cflags": ["-O2"]}]}`)
+		if err != nil {
+			t.Fatalf("ParseMultiCandidateResponse() failed: %v", err)
+		}
+		if len(seeds) != 2 || seeds[1].Content != "int b;" || len(seeds[1].CFlags) != 1 {
+			t.Errorf("unexpected candidates: %+v", seeds)
+		}
+
+		// A bare object is a single candidate.
+		seeds, err = sb.ParseMultiCandidateResponse(`{"source": "int only;"}`)
+		if err != nil || len(seeds) != 1 {
+			t.Fatalf("ParseMultiCandidateResponse() = %d seeds, %v", len(seeds), err)
+		}
+	})
+
+	t.Run("no parsable candidate", func(t *testing.T) {
+		if _, err := b.ParseMultiCandidateResponse("### CANDIDATE 1\n\n### CANDIDATE 2\n"); err == nil {
+			t.Error("expected an error when no candidate parses")
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CompilerContext identifies the compiler every seed is built with, so the
+// LLM does not have to guess the version or target from code alone.
+type CompilerContext struct {
+	Name    string   // Compiler name, e.g. "gcc"
+	Version string   // Compiler version, e.g. "12.2.0"
+	Target  string   // Target triple, e.g. "aarch64-linux-gnu"
+	CFlags  []string // Baseline compile flags from the config
+}
+
+// RelevantFlags returns the CFlags that affect code generation (-f*, -m*,
+// -O*, -std=*, -D*). Paths such as -I, -L, -B and --sysroot say nothing
+// useful to the LLM and are dropped.
+func (c *CompilerContext) RelevantFlags() []string {
+	var flags []string
+	for _, flag := range c.CFlags {
+		switch {
+		case strings.HasPrefix(flag, "-f"),
+			strings.HasPrefix(flag, "-m"),
+			strings.HasPrefix(flag, "-O"),
+			strings.HasPrefix(flag, "-std="),
+			strings.HasPrefix(flag, "-D"):
+			flags = append(flags, flag)
+		}
+	}
+	return flags
+}
+
+// compilationEnvSection renders the compiler context as a short prompt
+// section, or "" when no context is set.
+func (b *Builder) compilationEnvSection() string {
+	c := b.Compiler
+	if c == nil || (c.Name == "" && c.Target == "" && len(c.CFlags) == 0) {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## Compilation Environment\n\n")
+	if identity := strings.TrimSpace(c.Name + " " + c.Version); identity != "" {
+		sb.WriteString(fmt.Sprintf("- Compiler: %s\n", identity))
+	}
+	if c.Target != "" {
+		sb.WriteString(fmt.Sprintf("- Target: %s\n", c.Target))
+	}
+	if flags := c.RelevantFlags(); len(flags) > 0 {
+		sb.WriteString(fmt.Sprintf("- Flags: %s\n", strings.Join(flags, " ")))
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
@@ -4,7 +4,7 @@ package prompt
 import (
 	"fmt"
 	"os"
-	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/zjy-dev/de-fuzz/internal/coverage"
@@ -51,6 +51,21 @@ type DivergenceInfo struct {
 	// Context
 	MutatedSeedCode string // Code of the seed that failed
 	BaseSeedCode    string // Code of the covered predecessor seed (for comparison)
+
+	// PriorAttempts are the earlier failed seeds for the same target, oldest
+	// first, excluding MutatedSeedCode. Rendered as negative examples.
+	PriorAttempts []FailedAttempt
+
+	// SystemPromptOverride replaces the phase system prompt for this call
+	// (optional), e.g. Builder.RefinementSystemPrompt.
+	SystemPromptOverride string
+}
+
+// FailedAttempt is a seed that was tried for a target and missed it.
+type FailedAttempt struct {
+	Attempt int    // 1-based attempt number within the target
+	Code    string // Seed source that was tried
+	Reason  string // One-line reason, e.g. "diverged at expand_stack_vars"
 }
 
 // CompileErrorInfo holds information about a compilation failure.
@@ -61,6 +76,10 @@ type CompileErrorInfo struct {
 	ExitCode       int    // Compiler exit code
 	RetryAttempt   int    // Current retry attempt number (1-based)
 	MaxRetries     int    // Maximum retry attempts
+
+	// SystemPromptOverride replaces the phase system prompt for this call
+	// (optional), e.g. Builder.CompileRepairSystemPrompt.
+	SystemPromptOverride string
 }
 
 // BuildConstraintSolvingPrompt creates a prompt to guide LLM to cover a specific basic block.
@@ -70,148 +89,14 @@ func (b *Builder) BuildConstraintSolvingPrompt(ctx *TargetContext) (string, erro
 		return "", fmt.Errorf("target context must be provided")
 	}
 
-	// Build the target description
-	targetDesc := fmt.Sprintf(`## Target Basic Block
-
-**Function:** %s
-**Basic Block ID:** BB%d
-**Branching Factor:** %d successors
-**Target Lines:** %v
-**Source File:** %s
-
-`, ctx.TargetFunction, ctx.TargetBBID, ctx.SuccessorCount, ctx.TargetLines, filepath.Base(ctx.SourceFile))
-
-	// Build the annotated function code section
-	functionCodeSection := ""
-	if ctx.FunctionCode != "" {
-		functionCodeSection = fmt.Sprintf(`## Function Context
-
-The following is the function code with coverage annotations:
-- Lines prefixed with [✓] are already covered
-- Lines prefixed with [✗] are NOT covered  
-- Lines prefixed with [→] are the TARGET lines you need to reach
-
-%s
-%s
-%s
-
-`, "```cpp", ctx.FunctionCode, "```")
-	}
-
-	// Build the base seed section
-	baseSeedSection := ""
-	if ctx.BaseSeedCode != "" {
-		baseSeedSection = fmt.Sprintf(`## Base Seed (MUST MODIFY)
-
-This is your starting point. This seed covers line %d, which is close to your target (lines %v).
-**You MUST modify this seed to reach the target lines. Do NOT write a completely new program.**
-**Keep the same program structure and main() function. Only modify what's necessary to reach the target.**
-
-%s
-%s
-%s
-
-`, ctx.BaseSeedLine, ctx.TargetLines, "```c", ctx.BaseSeedCode, "```")
-	}
-
-	compilerProfileSection := buildCompilerProfileSection(ctx)
-
-	// Build output format based on configuration
-	outputFormat := b.getOutputFormat()
-
-	// Build CRITICAL RULES section based on mode
-	criticalRules := ""
-	if b.FunctionTemplate != "" {
-		criticalRules = `**CRITICAL RULES (Function Template Mode):**
-- **You are generating the COMPLETE seed() function** including its declaration and attributes.
-- **DO NOT generate main() function.** The template already provides main().
-- **DO NOT generate #include statements.** The template already has them.
-- **DO NOT generate a complete program.** Only the seed() function.
-- Focus on modifying the function to trigger different compiler paths.`
-		if b.Mechanism != nil {
-			if addendum := b.Mechanism.CriticalRulesAddendum(); addendum != "" {
-				criticalRules += "\n" + addendum
-			}
-		}
-	} else {
-		criticalRules = `**CRITICAL RULES:**
-- **DO NOT create a new program from scratch.** You must modify the provided base seed.
-- **DO NOT add a new main() function.** The base seed already has one.
-- **Keep the same overall structure.** Only change what's necessary to reach the target.
-- Focus on modifying variables, conditions, or adding small code snippets.`
-	}
-
-	// Add language constraints to critical rules
-	languageConstraints := `
-
-**LANGUAGE CONSTRAINTS (VERY IMPORTANT):**
-- Use ONLY C99/C11 standard C code.
-- DO NOT use C++ features (references, auto, lambda, classes, templates, new/delete, etc.).
-- Use standard C types and functions: int, char, void, malloc, free, memset, memcpy, etc.
-- Example of WRONG code: int& ref = x; or auto func = [](int x) { return x; };
-- Example of CORRECT code: int* ref = &x; or void* func(int x) { return (void*)(intptr_t)x; }`
-	criticalRules += languageConstraints
-
-	// Build output example based on mode
-	outputExample := ""
-	if b.FunctionTemplate != "" {
-		if b.Mechanism != nil {
-			outputExample = b.Mechanism.FuzzTimePromptExample()
-		} else {
-			outputExample = `## CRITICAL OUTPUT REQUIREMENTS
-
-**DO NOT include ANY explanations, analysis, or natural language text in your response.**
-**Output ONLY the complete function inside a markdown code block.**
-**NO text before or after the code block.**
-**NO main() function. NO #include statements.**
-`
-		}
-	} else {
-		outputExample = `## CRITICAL OUTPUT REQUIREMENTS
-
-**DO NOT include ANY explanations, analysis, or natural language text in your response.**
-**Output ONLY the code inside a markdown code block.**
-**NO text before or after the code block.**
-`
-	}
-
-	prompt := fmt.Sprintf(`You are an expert at generating test cases for compiler fuzzing. Your task is to MODIFY an existing C program to trigger specific code paths in the compiler.
-
-%s
-%s
-%s
-%s
-## Your Task
-
-1. Analyze the target basic block and understand what conditions would cause the compiler to take that code path.
-2. Study the base seed that reaches nearby code.
-3. **MODIFY the base seed** to cause the compiler to execute the target lines (%v).
-
-%s
-
-**Key Insights:**
-- The target is in function %s at BB%d with %d possible branches.
-- Focus on the conditions that lead to the target branch.
-- Small, focused changes often work better than major rewrites.
-
-%s
-
-%s
-`,
-		targetDesc,
-		functionCodeSection,
-		baseSeedSection,
-		compilerProfileSection,
-		ctx.TargetLines,
-		criticalRules,
-		ctx.TargetFunction,
-		ctx.TargetBBID,
-		ctx.SuccessorCount,
-		outputFormat,
-		outputExample,
-	)
-
-	return prompt, nil
+	return b.fitTargetToBudget(ctx, nil, func(ctx *TargetContext, _ *DivergenceInfo) (string, error) {
+		data := b.newTemplateData()
+		data.Target = ctx
+		data.CompilerProfile = buildCompilerProfileSection(ctx)
+		data.OutputFormat = b.getOutputFormat() + b.candidatesFormat()
+		data.Candidates = b.candidatesPerCall()
+		return b.renderTemplate(ConstraintTemplate, data)
+	})
 }
 
 // BuildRefinedPrompt creates a prompt with divergence information for retry.
@@ -221,152 +106,99 @@ func (b *Builder) BuildRefinedPrompt(ctx *TargetContext, div *DivergenceInfo) (s
 		return "", fmt.Errorf("target context and divergence info must be provided")
 	}
 
-	// Section 1: Target Function with highlighted target lines
-	targetFunctionSection := ""
-	if ctx.FunctionCode != "" {
-		targetFunctionSection = fmt.Sprintf(`## 1. Target: Function %s (BB%d)
-
-The compiler function you need to trigger. Lines marked with [→] are your TARGET.
-
-%s
-%s
-%s
-
-**Target Lines:** %v (marked with [→] above)
-**Branching Factor:** %d possible paths from this basic block
-
-`, ctx.TargetFunction, ctx.TargetBBID, "```cpp", ctx.FunctionCode, "```", ctx.TargetLines, ctx.SuccessorCount)
-	} else {
-		targetFunctionSection = fmt.Sprintf(`## 1. Target
-
-**Function:** %s
-**Basic Block:** BB%d
-**Target Lines:** %v
-**Branching Factor:** %d possible paths
+	// The budget drops prior attempts from the limited list; they count as
+	// omitted too
+	attempts, omitted := b.limitPriorAttempts(div.PriorAttempts)
+	limited := *div
+	limited.PriorAttempts = attempts
+	return b.fitTargetToBudget(ctx, &limited, func(ctx *TargetContext, div *DivergenceInfo) (string, error) {
+		data := b.newTemplateData()
+		data.Target = ctx
+		data.Divergence = div
+		data.PriorAttempts = div.PriorAttempts
+		data.OmittedPriorAttempts = omitted + len(attempts) - len(div.PriorAttempts)
+		data.CompilerProfile = buildCompilerProfileSection(ctx)
+		data.OutputFormat = b.getOutputFormat()
+		return b.renderTemplate(RefinedTemplate, data)
+	})
+}
 
-`, ctx.TargetFunction, ctx.TargetBBID, ctx.TargetLines, ctx.SuccessorCount)
+// BuildFollowUpPrompt creates the next user message of a conversation that
+// started with BuildConstraintSolvingPrompt. The target, base seed and the
+// model's earlier answers are already in the chat history, so it carries only
+// why the last answer failed: errInfo for a compile failure, otherwise div.
+func (b *Builder) BuildFollowUpPrompt(ctx *TargetContext, div *DivergenceInfo, errInfo *CompileErrorInfo) (string, error) {
+	if ctx == nil || (div == nil && errInfo == nil) {
+		return "", fmt.Errorf("target context and divergence or compile error info must be provided")
 	}
 
-	// Section 2: Divergence Analysis with function source code
-	divergenceSection := ""
-	if div.DivergentFunction != "" {
-		divergenceSection = fmt.Sprintf(`## 2. Why Previous Attempt Failed
-
-The compiler took a different code path at function: **%s**
-
-`, div.DivergentFunction)
-
-		if div.DivergentFunctionCode != "" {
-			divergenceSection += fmt.Sprintf(`**Divergent Function Source Code** (study this to understand the branching condition):
-
-%s
-%s
-%s
-
-`, "```cpp", div.DivergentFunctionCode, "```")
-		}
+	data := b.newTemplateData()
+	data.Target = ctx
+	data.Divergence = div
+	data.CompileError = errInfo
+	return b.renderTemplate(FollowUpTemplate, data)
+}
 
-		divergenceSection += `**Analysis:** Your seed caused the compiler to branch differently than expected in this function.
-Study the conditions in the divergent function to understand what code patterns trigger each branch.
+// maxDiagnosticLines caps the compiler output shown in a compile-fix prompt;
+// the first errors are the ones worth fixing, the rest usually cascade.
+const maxDiagnosticLines = 40
 
-`
+// BuildCompileFixPrompt asks for a minimal repair of a seed that failed to
+// compile, showing its code and the first maxDiagnosticLines lines of
+// compilerStderr. The response parses with ParseLLMResponse.
+func (b *Builder) BuildCompileFixPrompt(s *seed.Seed, compilerStderr string) (string, error) {
+	if s == nil {
+		return "", fmt.Errorf("seed must be provided")
 	}
 
-	// Section 3: Failed mutation (what didn't work)
-	failedSection := ""
-	if div.MutatedSeedCode != "" {
-		failedSection = fmt.Sprintf(`## 3. Failed Mutation (DO NOT repeat this)
-
-This seed was tried but took the WRONG compiler path:
-
-%s
-%s
-%s
-
-`, "```c", div.MutatedSeedCode, "```")
+	data := b.newTemplateData()
+	data.Seed = s
+	data.CompileError = &CompileErrorInfo{
+		FailedSeedCode: s.Content,
+		CompilerOutput: clipDiagnostics(compilerStderr, maxDiagnosticLines),
 	}
+	data.OutputFormat = b.getOutputFormat()
+	return b.renderTemplate(CompileFixTemplate, data)
+}
 
-	// Section 4: Base seed (what works as starting point)
-	baseSeedSection := ""
-	if div.BaseSeedCode != "" {
-		baseSeedSection = fmt.Sprintf(`## 4. Working Base Seed (USE THIS AS STARTING POINT)
-
-This seed successfully reaches nearby code (line %d). Start from this and make targeted modifications:
-
-%s
-%s
-%s
-
-`, ctx.BaseSeedLine, "```c", div.BaseSeedCode, "```")
-	} else if ctx.BaseSeedCode != "" {
-		baseSeedSection = fmt.Sprintf(`## 4. Working Base Seed (USE THIS AS STARTING POINT)
-
-This seed successfully reaches nearby code (line %d). Start from this and make targeted modifications:
-
-%s
-%s
-%s
-
-`, ctx.BaseSeedLine, "```c", ctx.BaseSeedCode, "```")
+// clipDiagnostics keeps the first limit lines of compiler output.
+func clipDiagnostics(stderr string, limit int) string {
+	lines := strings.Split(strings.TrimRight(stderr, "\n"), "\n")
+	if len(lines) <= limit {
+		return strings.Join(lines, "\n")
 	}
+	return strings.Join(lines[:limit], "\n") + fmt.Sprintf("\n... (%d more lines)", len(lines)-limit)
+}
 
-	// Section 5: Task and strategy
-	compilerProfileSection := buildCompilerProfileSection(ctx)
-	taskSection := fmt.Sprintf(`## 5. Your Task
-
-Create a NEW seed that:
-1. Uses the **base seed** as starting point (Section 4)
-2. Avoids the divergence at **%s** (Section 2)
-3. Reaches the **target lines %v** in function **%s** (Section 1)
-
-**Strategy:**
-- Study the divergent function's conditions to understand what triggers each branch
-- Make small, targeted changes to the base seed
-- Consider: What C code patterns cause the compiler to take the target branch?
+// PerturbPrompt makes a repeated request distinct from the n-1 identical ones
+// already answered, asking the model for a different approach.
+func PerturbPrompt(userPrompt string, n int) string {
+	return userPrompt + fmt.Sprintf("\n\n**Note:** This exact request has already been answered %d time(s) and "+
+		"those answers did not reach the target. Produce a DIFFERENT approach this time.\n", n)
+}
 
-`, div.DivergentFunction, ctx.TargetLines, ctx.TargetFunction)
+// limitPriorAttempts keeps the newest MaxPriorAttempts failures and clips each
+// snippet to PriorAttemptMaxChars. It returns the kept attempts and how many
+// older ones were dropped.
+func (b *Builder) limitPriorAttempts(attempts []FailedAttempt) ([]FailedAttempt, int) {
+	if b.MaxPriorAttempts <= 0 || len(attempts) == 0 {
+		return nil, len(attempts)
+	}
 
-	// Output format and rules
-	outputFormat := b.getOutputFormat()
+	omitted := 0
+	if len(attempts) > b.MaxPriorAttempts {
+		omitted = len(attempts) - b.MaxPriorAttempts
+		attempts = attempts[omitted:]
+	}
 
-	// Build CRITICAL RULES section based on mode
-	criticalRules := ""
-	if b.FunctionTemplate != "" {
-		criticalRules = `**RULES:**
-- Output the COMPLETE seed() function (including declaration and any attributes)
-- NO main() function (template provides it)
-- NO #include statements
-- Use only C99/C11 standard C code (no C++ features)`
-		if b.Mechanism != nil {
-			if addendum := b.Mechanism.CriticalRulesAddendum(); addendum != "" {
-				criticalRules += "\n" + addendum
-			}
+	limited := make([]FailedAttempt, len(attempts))
+	for i, a := range attempts {
+		if b.PriorAttemptMaxChars > 0 && len(a.Code) > b.PriorAttemptMaxChars {
+			a.Code = strings.ToValidUTF8(a.Code[:b.PriorAttemptMaxChars], "") + "\n/* ... truncated ... */"
 		}
-	} else {
-		criticalRules = `**RULES:**
-- Modify the base seed, do NOT create a new program
-- Keep the same main() structure
-- Use only C99/C11 standard C code (no C++ features)`
+		limited[i] = a
 	}
-
-	prompt := fmt.Sprintf(`%s%s%s%s%s%s
-%s
-
-%s
-
-**OUTPUT: Only the code in a markdown code block. No explanations.**
-`,
-		targetFunctionSection,
-		divergenceSection,
-		failedSection,
-		baseSeedSection,
-		compilerProfileSection,
-		taskSection,
-		criticalRules,
-		outputFormat,
-	)
-
-	return prompt, nil
+	return limited, omitted
 }
 
 // BuildCompileErrorRetryPrompt creates a prompt for retrying after compile error.
@@ -424,7 +256,7 @@ Your previous attempt failed to compile. **You MUST fix the compilation error.**
 
 `, errInfo.RetryAttempt, errInfo.MaxRetries, errInfo.ExitCode,
 		"```", errInfo.CompilerOutput, "```",
-		"```c", errInfo.FailedSeedCode, "```")
+		"```"+b.language().FenceTag(), errInfo.FailedSeedCode, "```")
 
 	// Section 3: Working Base Seed
 	baseSeedSection := ""
@@ -437,7 +269,7 @@ This seed compiles and runs successfully. Use it as your starting point:
 %s
 %s
 
-`, "```c", ctx.BaseSeedCode, "```")
+`, "```"+b.language().FenceTag(), ctx.BaseSeedCode, "```")
 	}
 
 	// Section 4: Task
@@ -449,12 +281,9 @@ This seed compiles and runs successfully. Use it as your starting point:
 3. Use the working base seed (Section 3) as reference for correct syntax
 
 **Common fixes:**
-- Check for undefined variables or functions
-- Ensure proper C99/C11 syntax (no C++ features)
-- Verify all includes are available in the template
-- Check for missing semicolons or braces
+%s
 
-`, ctx.TargetLines, ctx.TargetFunction)
+`, ctx.TargetLines, ctx.TargetFunction, b.languageGuide().CompileFixes)
 
 	// Critical rules and output format
 	criticalRules := ""
@@ -463,7 +292,7 @@ This seed compiles and runs successfully. Use it as your starting point:
 - Output the COMPLETE seed() function (including declaration and any attributes)
 - NO main() function (template provides it)
 - NO #include statements
-- Use only C99/C11 standard C code (no C++ features)`
+- ` + b.languageGuide().Rule
 		if b.Mechanism != nil {
 			if addendum := b.Mechanism.CriticalRulesAddendum(); addendum != "" {
 				criticalRules += "\n" + addendum
@@ -471,8 +300,8 @@ This seed compiles and runs successfully. Use it as your starting point:
 		}
 	} else {
 		criticalRules = `**RULES:**
-- Output complete, compilable C code
-- Use only C99/C11 standard C code
+- Output complete, compilable ` + b.language().DisplayName() + ` code
+- ` + b.languageGuide().Rule + `
 - Keep the same main() structure as base seed`
 	}
 
@@ -483,7 +312,7 @@ This seed compiles and runs successfully. Use it as your starting point:
 
 %s
 
-**OUTPUT: Only the fixed code in a markdown code block. No explanations.**
+**OUTPUT: %s**
 `,
 		targetSection,
 		compileErrorSection,
@@ -492,14 +321,27 @@ This seed compiles and runs successfully. Use it as your starting point:
 		taskSection,
 		criticalRules,
 		outputFormat,
+		b.outputReminder("fixed code"),
 	)
 
 	return prompt, nil
 }
 
+// outputReminder is the one-line output requirement closing a prompt.
+func (b *Builder) outputReminder(what string) string {
+	if b.StructuredOutput {
+		return "Only the JSON object. No explanations."
+	}
+	return "Only the " + what + " in a markdown code block. No explanations."
+}
+
 // getOutputFormat returns the appropriate output format instruction.
 // This is a generic format that works for all defense strategies.
 func (b *Builder) getOutputFormat() string {
+	if b.StructuredOutput {
+		return "## Output Format\n\n" + b.structuredOutputFormat(true)
+	}
+
 	cflagsNote := `
 
 ## Optional: Compiler Flags
@@ -534,10 +376,10 @@ Output format:
 // ||||| CFLAGS_START |||||
 [optional flags]
 // ||||| CFLAGS_END |||||
-// ||||| JSON_TESTCASES_START |||||
+%s
 [{"running command": "./prog args", "expected result": "..."}]
 
-Maximum %d test case(s).%s`, b.MaxTestCases, cflagsNote)
+Maximum %d test case(s). %s%s%s`, b.testCaseSeparator(), b.MaxTestCases, testCaseKeysNote, b.multiFunctionNote(), cflagsNote)
 	} else if b.FunctionTemplate != "" {
 		return `## Output Format
 
@@ -546,42 +388,87 @@ Maximum %d test case(s).%s`, b.MaxTestCases, cflagsNote)
 - You CAN include function attributes like __attribute__((stack_protect)) if needed
 - The template already provides main() and #include statements
 - DO NOT generate main() function
-- DO NOT generate #include statements
-` + cflagsNote
+- DO NOT generate #include statements` + b.multiFunctionNote() + "\n" + cflagsNote
 	} else if b.MaxTestCases > 0 {
 		return fmt.Sprintf(`## Output Format
 
 Output:
-1. Complete C source code in a markdown code block
+1. Complete %s source code in a markdown code block
 2. (Optional) CFLAGS section
 3. Test cases in JSON format
 
 // ||||| CFLAGS_START |||||
 [optional flags]
 // ||||| CFLAGS_END |||||
-// ||||| JSON_TESTCASES_START |||||
+%s
 [{"running command": "./prog", "expected result": "..."}]
 
-Maximum %d test case(s).%s`, b.MaxTestCases, cflagsNote)
+Maximum %d test case(s). %s%s`, b.language().DisplayName(), b.testCaseSeparator(), b.MaxTestCases, testCaseKeysNote, cflagsNote)
 	}
 	return `## Output Format
 
-Output complete C source code in a markdown code block.
+Output complete ` + b.language().DisplayName() + ` source code in a markdown code block.
 No test cases needed.` + cflagsNote
 }
 
+// BBLayout describes the basic blocks of the annotated function so the
+// prompt can show where each block begins and what leads to the target.
+type BBLayout struct {
+	LineBBs      map[int][]int // Source line -> IDs of the BBs that contain it
+	TargetBBID   int           // Target basic block
+	Predecessors []int         // Predecessor BB IDs of the target
+}
+
+// firstLines returns the first source line of every BB in the layout.
+func (l *BBLayout) firstLines() map[int]int {
+	first := make(map[int]int)
+	for line, ids := range l.LineBBs {
+		for _, id := range ids {
+			if cur, ok := first[id]; !ok || line < cur {
+				first[id] = line
+			}
+		}
+	}
+	return first
+}
+
+// legend lists the target's predecessors and where they begin, as comment
+// lines appended after the annotated code.
+func (l *BBLayout) legend(first map[int]int) string {
+	if len(l.Predecessors) == 0 {
+		return fmt.Sprintf("// BB%d has no predecessors (function entry)\n", l.TargetBBID)
+	}
+
+	preds := append([]int(nil), l.Predecessors...)
+	sort.Ints(preds)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("// Predecessors of target BB%d:\n", l.TargetBBID))
+	for _, id := range preds {
+		if line, ok := first[id]; ok {
+			sb.WriteString(fmt.Sprintf("//   BB%d begins at line %d\n", id, line))
+		} else {
+			sb.WriteString(fmt.Sprintf("//   BB%d (no source lines)\n", id))
+		}
+	}
+	return sb.String()
+}
+
 // GenerateAnnotatedFunctionCode generates function code with coverage annotations.
-// coveredLines and targetLines are the line numbers to annotate.
-func GenerateAnnotatedFunctionCode(sourceFile string, startLine, endLine int, coveredLines, targetLines []int) (string, error) {
+// coveredLines and targetLines are the line numbers to annotate. When layout
+// is non-nil, the first line of each basic block in the window is marked with
+// "/* BBn begins */" and a legend of the target's predecessors follows the code.
+func GenerateAnnotatedFunctionCode(sourceFile string, startLine, endLine int, coveredLines, targetLines []int, layout *BBLayout) (string, error) {
 	content, err := os.ReadFile(sourceFile)
 	if err != nil {
 		return "", fmt.Errorf("failed to read source file: %w", err)
 	}
 
-	lines := strings.Split(string(content), "\n")
-	if startLine < 1 || endLine > len(lines) {
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	if startLine < 1 || startLine > len(lines) {
 		return "", fmt.Errorf("line range out of bounds")
 	}
+	endLine = min(endLine, len(lines)) // context windows may run past the end of the file
 
 	// Build line sets for quick lookup
 	coveredSet := make(map[int]bool)
@@ -593,6 +480,19 @@ func GenerateAnnotatedFunctionCode(sourceFile string, startLine, endLine int, co
 		targetSet[l] = true
 	}
 
+	// Invert the BB first lines so each source line knows which BBs begin there
+	beginsAt := make(map[int][]int)
+	var first map[int]int
+	if layout != nil {
+		first = layout.firstLines()
+		for id, line := range first {
+			beginsAt[line] = append(beginsAt[line], id)
+		}
+		for _, ids := range beginsAt {
+			sort.Ints(ids)
+		}
+	}
+
 	var sb strings.Builder
 	for i := startLine - 1; i < endLine && i < len(lines); i++ {
 		lineNum := i + 1
@@ -606,7 +506,15 @@ func GenerateAnnotatedFunctionCode(sourceFile string, startLine, endLine int, co
 			prefix = "[✗]" // Uncovered
 		}
 
-		sb.WriteString(fmt.Sprintf("%s %4d: %s\n", prefix, lineNum, lines[i]))
+		sb.WriteString(fmt.Sprintf("%s %4d: %s", prefix, lineNum, lines[i]))
+		for _, id := range beginsAt[lineNum] {
+			sb.WriteString(fmt.Sprintf(" /* BB%d begins */", id))
+		}
+		sb.WriteString("\n")
+	}
+
+	if layout != nil {
+		sb.WriteString(layout.legend(first))
 	}
 
 	return sb.String(), nil
@@ -657,12 +565,23 @@ func BuildTargetContextFromCFG(
 		}
 		maxLine := target.Lines[len(target.Lines)-1] + 20
 
+		layout := &BBLayout{
+			LineBBs:    analyzer.GetFunctionLineBBs(target.Function, target.File),
+			TargetBBID: target.BBID,
+		}
+		if fn, ok := analyzer.GetFunction(target.Function); ok {
+			if bb, ok := fn.Blocks[target.BBID]; ok {
+				layout.Predecessors = bb.Predecessors
+			}
+		}
+
 		code, err := GenerateAnnotatedFunctionCode(
 			target.File,
 			minLine,
 			maxLine,
 			coveredInFile,
 			target.Lines,
+			layout,
 		)
 		if err == nil {
 			ctx.FunctionCode = code
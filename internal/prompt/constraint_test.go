@@ -1,12 +1,19 @@
 package prompt
 
 import (
+	"flag"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/zjy-dev/de-fuzz/internal/coverage"
+	"github.com/zjy-dev/de-fuzz/internal/seed"
 )
 
+var updateGolden = flag.Bool("update", false, "rewrite golden files in testdata/")
+
 func TestBuilder_BuildConstraintSolvingPrompt(t *testing.T) {
 	builder := NewBuilder(3, "", nil)
 
@@ -126,6 +133,76 @@ func TestBuilder_BuildRefinedPrompt(t *testing.T) {
 	t.Logf("Generated refined prompt length: %d chars", len(prompt))
 }
 
+func TestBuilder_BuildRefinedPrompt_PriorAttempts(t *testing.T) {
+	ctx := &TargetContext{
+		TargetFunction: "expand_used_vars",
+		TargetBBID:     5,
+		TargetLines:    []int{100},
+		BaseSeedCode:   "int main() { return 0; }",
+	}
+	newest := "int main() { char buf[64]; /* newest */ " + strings.Repeat("buf[0] = 1; ", 200) + "return 0; }"
+	div := &DivergenceInfo{
+		DivergentFunction: "expand_stack_vars",
+		MutatedSeedCode:   newest,
+		PriorAttempts: []FailedAttempt{
+			{Attempt: 1, Code: "int main() { /* first */ }", Reason: "diverged at expand_stack_vars"},
+			{Attempt: 2, Code: "int main() { /* second */ }", Reason: "did not compile"},
+			{Attempt: 3, Code: "int main() { /* third */ }", Reason: "compiled but did not reach the target lines"},
+		},
+	}
+
+	builder := NewBuilder(0, "", nil)
+	prompt, err := builder.BuildRefinedPrompt(ctx, div)
+	if err != nil {
+		t.Fatalf("BuildRefinedPrompt() failed: %v", err)
+	}
+
+	if !strings.Contains(prompt, "Already Tried — do NOT repeat these") {
+		t.Error("prompt should contain the already-tried section")
+	}
+	for _, a := range div.PriorAttempts {
+		if !strings.Contains(prompt, a.Code) || !strings.Contains(prompt, a.Reason) {
+			t.Errorf("prompt should list attempt %d with its reason", a.Attempt)
+		}
+	}
+	if !strings.Contains(prompt, newest) {
+		t.Error("newest failure should be shown in full")
+	}
+
+	t.Run("limits count and size", func(t *testing.T) {
+		limited := NewBuilder(0, "", nil)
+		limited.MaxPriorAttempts = 2
+		limited.PriorAttemptMaxChars = 10
+
+		prompt, err := limited.BuildRefinedPrompt(ctx, div)
+		if err != nil {
+			t.Fatalf("BuildRefinedPrompt() failed: %v", err)
+		}
+		if strings.Contains(prompt, "diverged at expand_stack_vars") {
+			t.Error("oldest attempt should be dropped")
+		}
+		if !strings.Contains(prompt, "1 older attempt(s) not shown") {
+			t.Error("prompt should mention omitted attempts")
+		}
+		if strings.Contains(prompt, "/* third */") || !strings.Contains(prompt, "/* ... truncated ... */") {
+			t.Error("attempt snippets should be clipped")
+		}
+		if !strings.Contains(prompt, newest) {
+			t.Error("newest failure should not be clipped")
+		}
+	})
+
+	t.Run("omitted without prior attempts", func(t *testing.T) {
+		prompt, err := builder.BuildRefinedPrompt(ctx, &DivergenceInfo{MutatedSeedCode: newest})
+		if err != nil {
+			t.Fatalf("BuildRefinedPrompt() failed: %v", err)
+		}
+		if strings.Contains(prompt, "Already Tried") {
+			t.Error("already-tried section should be omitted")
+		}
+	})
+}
+
 func TestBuilder_BuildRefinedPrompt_NilInputs(t *testing.T) {
 	builder := NewBuilder(0, "", nil)
 
@@ -140,6 +217,105 @@ func TestBuilder_BuildRefinedPrompt_NilInputs(t *testing.T) {
 	}
 }
 
+func TestBuilder_BuildFollowUpPrompt(t *testing.T) {
+	ctx := &TargetContext{
+		TargetFunction: "expand_used_vars",
+		TargetBBID:     5,
+		TargetLines:    []int{100},
+		FunctionCode:   "[→] 100: if (flag_stack_protect)",
+		BaseSeedCode:   "int main() { /* base */ return 0; }",
+	}
+	builder := NewBuilder(0, "", nil)
+
+	prompt, err := builder.BuildFollowUpPrompt(ctx, &DivergenceInfo{
+		DivergentFunction:     "expand_stack_vars",
+		DivergentFunctionCode: "static void expand_stack_vars (void)",
+		MutatedSeedCode:       "int main() { /* failed */ }",
+	}, nil)
+	if err != nil {
+		t.Fatalf("BuildFollowUpPrompt() failed: %v", err)
+	}
+	for _, want := range []string{"expand_stack_vars", "static void expand_stack_vars (void)", "[100]", "expand_used_vars"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("follow-up prompt should contain %q", want)
+		}
+	}
+	// Everything the model already saw stays out of the follow-up.
+	for _, unwanted := range []string{ctx.FunctionCode, ctx.BaseSeedCode, "int main() { /* failed */ }"} {
+		if strings.Contains(prompt, unwanted) {
+			t.Errorf("follow-up prompt should not resend %q", unwanted)
+		}
+	}
+
+	full, err := builder.BuildRefinedPrompt(ctx, &DivergenceInfo{DivergentFunction: "expand_stack_vars", MutatedSeedCode: "int main() { /* failed */ }"})
+	if err != nil {
+		t.Fatalf("BuildRefinedPrompt() failed: %v", err)
+	}
+	if len(prompt) >= len(full) {
+		t.Errorf("follow-up prompt (%d bytes) should be shorter than the stateless refined prompt (%d bytes)", len(prompt), len(full))
+	}
+
+	prompt, err = builder.BuildFollowUpPrompt(ctx, nil, &CompileErrorInfo{
+		CompilerOutput: "error: 'x' undeclared",
+		ExitCode:       1,
+		RetryAttempt:   2,
+		MaxRetries:     3,
+	})
+	if err != nil {
+		t.Fatalf("BuildFollowUpPrompt() failed: %v", err)
+	}
+	for _, want := range []string{"failed to compile", "error: 'x' undeclared", "Attempt 2 of 3", "Common fixes"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("compile-error follow-up should contain %q", want)
+		}
+	}
+
+	if _, err := builder.BuildFollowUpPrompt(ctx, nil, nil); err == nil {
+		t.Error("BuildFollowUpPrompt() should fail without divergence or compile error info")
+	}
+}
+
+func TestBuilder_BuildCompileFixPrompt(t *testing.T) {
+	builder := NewBuilder(0, "", nil)
+	failed := &seed.Seed{
+		Content: "int main() { return x; }",
+		CFlags:  []string{"-O2"},
+	}
+
+	var diag strings.Builder
+	for i := 1; i <= 50; i++ {
+		diag.WriteString(fmt.Sprintf("seed.c:%d: error: 'x' undeclared\n", i))
+	}
+
+	prompt, err := builder.BuildCompileFixPrompt(failed, diag.String())
+	if err != nil {
+		t.Fatalf("BuildCompileFixPrompt() failed: %v", err)
+	}
+	for _, want := range []string{
+		"failed to compile", "SMALLEST change", failed.Content, "-O2",
+		"seed.c:1: error", "seed.c:40: error", "... (10 more lines)", "Common fixes",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("compile-fix prompt should contain %q", want)
+		}
+	}
+	if strings.Contains(prompt, "seed.c:41: error") {
+		t.Error("compile-fix prompt should clip diagnostics to the first 40 lines")
+	}
+
+	fixed, err := builder.ParseLLMResponse("```c\nint x;\nint main() { return x; }\n```")
+	if err != nil {
+		t.Fatalf("ParseLLMResponse() failed on fixed response: %v", err)
+	}
+	if !strings.Contains(fixed.Content, "int x;") {
+		t.Errorf("fixed seed content = %q", fixed.Content)
+	}
+
+	if _, err := builder.BuildCompileFixPrompt(nil, "error"); err == nil {
+		t.Error("BuildCompileFixPrompt() should fail without a seed")
+	}
+}
+
 func TestGenerateAnnotatedFunctionCode(t *testing.T) {
 	// Create a temporary source file
 	tmpDir := t.TempDir()
@@ -163,6 +339,7 @@ func TestGenerateAnnotatedFunctionCode(t *testing.T) {
 		1, 8,
 		[]int{1, 2, 3}, // Covered lines
 		[]int{5},       // Target line
+		nil,
 	)
 	if err != nil {
 		t.Fatalf("GenerateAnnotatedFunctionCode() failed: %v", err)
@@ -187,12 +364,49 @@ func TestGenerateAnnotatedFunctionCode(t *testing.T) {
 	t.Logf("Annotated code:\n%s", annotated)
 }
 
+func TestBuildTargetContextFromCFG_BBAnnotationsGolden(t *testing.T) {
+	analyzer, err := coverage.NewAnalyzer(
+		[]string{filepath.Join("testdata", "sample.c.015t.cfg")},
+		[]string{"classify"}, "", filepath.Join(t.TempDir(), "mapping.json"), 0.8,
+	)
+	if err != nil {
+		t.Fatalf("NewAnalyzer() failed: %v", err)
+	}
+	analyzer.RecordCoverage(1, []string{"testdata/sample.c:3", "testdata/sample.c:4", "testdata/sample.c:6", "testdata/sample.c:7", "testdata/sample.c:13"})
+
+	ctx, err := BuildTargetContextFromCFG(&coverage.TargetInfo{
+		Function:       "classify",
+		BBID:           5,
+		Lines:          []int{9},
+		File:           "testdata/sample.c",
+		SuccessorCount: 1,
+	}, nil, analyzer)
+	if err != nil {
+		t.Fatalf("BuildTargetContextFromCFG() failed: %v", err)
+	}
+
+	golden := filepath.Join("testdata", "sample_annotated.golden")
+	if *updateGolden {
+		if err := os.WriteFile(golden, []byte(ctx.FunctionCode), 0644); err != nil {
+			t.Fatalf("Failed to update golden file: %v", err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("Failed to read golden file: %v", err)
+	}
+	if ctx.FunctionCode != string(want) {
+		t.Errorf("Annotated function code mismatch (run with -update to accept).\nGot:\n%s\nWant:\n%s", ctx.FunctionCode, want)
+	}
+}
+
 func TestGenerateAnnotatedFunctionCode_FileNotFound(t *testing.T) {
 	_, err := GenerateAnnotatedFunctionCode(
 		"/nonexistent/file.c",
 		1, 10,
 		nil,
 		nil,
+		nil,
 	)
 	if err == nil {
 		t.Error("Should return error for nonexistent file")
@@ -209,6 +423,7 @@ func TestGenerateAnnotatedFunctionCode_OutOfBounds(t *testing.T) {
 		1, 100, // End line way out of bounds
 		nil,
 		nil,
+		nil,
 	)
 	// Should not error, just include available lines
 	if err != nil {
@@ -0,0 +1,63 @@
+package prompt
+
+import "fmt"
+
+// SetContextLimit sets how many prompt tokens (system plus user) the model
+// accepts; 0 means unknown. Constraint-solving and refined prompts are then
+// trimmed to what the system prompt leaves of the limit, on top of the
+// builder's TokenBudget.
+func (s *PromptService) SetContextLimit(tokens int) {
+	s.contextLimit = tokens
+}
+
+// ContextLimit returns the limit set by SetContextLimit.
+func (s *PromptService) ContextLimit() int {
+	return s.contextLimit
+}
+
+// Understanding returns the full understanding text.
+func (s *PromptService) Understanding() string {
+	return s.understanding
+}
+
+// UseUnderstandingSummary makes system prompts built from now on carry
+// summary instead of the full understanding. An empty summary restores the
+// full text.
+func (s *PromptService) UseUnderstandingSummary(summary string) {
+	s.summary = summary
+}
+
+func (s *PromptService) activeUnderstanding() string {
+	if s.summary != "" {
+		return s.summary
+	}
+	return s.understanding
+}
+
+// capBuilderBudget caps the builder's budget by what systemPrompt leaves of
+// the context limit and returns the function that lifts the cap again.
+func (s *PromptService) capBuilderBudget(systemPrompt string) func() {
+	if s.contextLimit <= 0 {
+		return func() {}
+	}
+	s.builder.contextBudget = max(s.contextLimit-s.builder.EstimateTokens(systemPrompt), 1)
+	return func() { s.builder.contextBudget = 0 }
+}
+
+// GetUnderstandingSummaryPrompt returns (system, user) prompts asking the
+// LLM to compress the understanding to about maxTokens tokens.
+func (s *PromptService) GetUnderstandingSummaryPrompt(maxTokens int) (string, string) {
+	return s.builder.UnderstandingSummaryPrompt(s.understanding, maxTokens)
+}
+
+// UnderstandingSummaryPrompt returns (system, user) prompts asking the LLM
+// to compress understanding to about maxTokens tokens while keeping the
+// attack vectors and the facts a seed writer needs.
+func (b *Builder) UnderstandingSummaryPrompt(understanding string, maxTokens int) (string, string) {
+	system := "You condense background notes for a compiler fuzzer. " +
+		"Keep every attack vector and bullet point that names a code construct, compiler flag or defense behaviour; " +
+		"drop examples, repetition and prose. Reply with the condensed notes only, in Markdown."
+	user := fmt.Sprintf("Condense the following notes to at most about %d tokens (roughly %d words).\n\n---\n%s",
+		maxTokens, maxTokens*3/4, understanding)
+	return system, user
+}
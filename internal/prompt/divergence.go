@@ -0,0 +1,96 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DivergenceLimits bounds how much of a uftrace divergence is shown to the
+// LLM. A full trace can hold thousands of calls; only the ones next to the
+// divergence point say anything about why the paths split.
+type DivergenceLimits struct {
+	PrefixEntries int // Last common-prefix calls before the divergence point
+	PathEntries   int // First calls of each divergent path
+}
+
+// Defaults for DivergenceLimits.
+const (
+	DefaultDivergencePrefixEntries = 15
+	DefaultDivergencePathEntries   = 10
+)
+
+// divergenceLimits returns the builder's limits; zero values mean defaults.
+func (b *Builder) divergenceLimits() DivergenceLimits {
+	return DivergenceLimits{
+		PrefixEntries: b.DivergencePrefixEntries,
+		PathEntries:   b.DivergencePathEntries,
+	}
+}
+
+// FormatDivergenceForLLM renders a divergence for a prompt, keeping the last
+// limits.PrefixEntries common calls and the first limits.PathEntries calls of
+// each path; omitted calls are replaced by a line with their count. When the
+// context carries no structured trace, its FormattedReport is used, clipped
+// to the same overall size. Non-positive limits fall back to the defaults.
+func FormatDivergenceForLLM(divCtx *DivergenceContext, limits DivergenceLimits) string {
+	if divCtx == nil {
+		return ""
+	}
+	if limits.PrefixEntries <= 0 {
+		limits.PrefixEntries = DefaultDivergencePrefixEntries
+	}
+	if limits.PathEntries <= 0 {
+		limits.PathEntries = DefaultDivergencePathEntries
+	}
+
+	if divCtx.BaseFunction == "" && divCtx.MutatedFunction == "" &&
+		len(divCtx.CommonPrefix) == 0 && len(divCtx.BasePath) == 0 && len(divCtx.MutatedPath) == 0 {
+		if divCtx.FormattedReport == "" {
+			return ""
+		}
+		return clipDiagnostics(divCtx.FormattedReport, limits.PrefixEntries+2*limits.PathEntries+8)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## Divergence Analysis (Function-Level)\n\n")
+	sb.WriteString(fmt.Sprintf("The execution paths diverged at function call #%d.\n\n", divCtx.DivergenceIndex))
+	sb.WriteString("### Divergence Point\n")
+	sb.WriteString(fmt.Sprintf("- Base seed executed: `%s`\n", divCtx.BaseFunction))
+	sb.WriteString(fmt.Sprintf("- Mutated seed executed: `%s`\n\n", divCtx.MutatedFunction))
+
+	if prefix := divCtx.CommonPrefix; len(prefix) > 0 {
+		sb.WriteString("### Context Before Divergence\n")
+		sb.WriteString("Calls made by both executions right before they diverged:\n")
+		if omitted := len(prefix) - limits.PrefixEntries; omitted > 0 {
+			sb.WriteString(fmt.Sprintf("- ... (%d earlier common calls omitted)\n", omitted))
+			prefix = prefix[omitted:]
+		}
+		for _, fn := range prefix {
+			sb.WriteString(fmt.Sprintf("- `%s`\n", fn))
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(divCtx.BasePath) > 0 || len(divCtx.MutatedPath) > 0 {
+		sb.WriteString("### Execution Path After Divergence\n")
+		if len(divCtx.BasePath) > 0 {
+			sb.WriteString("Base seed path: " + formatDivergentPath(divCtx.BasePath, limits.PathEntries) + "\n")
+		}
+		if len(divCtx.MutatedPath) > 0 {
+			sb.WriteString("Mutated seed path: " + formatDivergentPath(divCtx.MutatedPath, limits.PathEntries) + "\n")
+		}
+	}
+
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// formatDivergentPath joins the first limit calls of path, noting how many
+// more follow.
+func formatDivergentPath(path []string, limit int) string {
+	shown := path[:min(limit, len(path))]
+	out := "`" + strings.Join(shown, "` → `") + "`"
+	if more := len(path) - len(shown); more > 0 {
+		out += fmt.Sprintf(" → ... (%d more calls)", more)
+	}
+	return out
+}
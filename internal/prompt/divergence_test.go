@@ -0,0 +1,93 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// syntheticDivergence builds a divergence over a long uftrace run.
+func syntheticDivergence(prefixLen, pathLen int) *DivergenceContext {
+	divCtx := &DivergenceContext{
+		BaseFunction:    "gen_addsi3",
+		MutatedFunction: "optimize_insn_for_speed_p",
+		DivergenceIndex: prefixLen,
+	}
+	for i := 0; i < prefixLen; i++ {
+		divCtx.CommonPrefix = append(divCtx.CommonPrefix, fmt.Sprintf("common_%d", i))
+	}
+	for i := 0; i < pathLen; i++ {
+		divCtx.BasePath = append(divCtx.BasePath, fmt.Sprintf("base_%d", i))
+		divCtx.MutatedPath = append(divCtx.MutatedPath, fmt.Sprintf("mutated_%d", i))
+	}
+	return divCtx
+}
+
+func TestFormatDivergenceForLLM(t *testing.T) {
+	divCtx := syntheticDivergence(5000, 300)
+
+	report := FormatDivergenceForLLM(divCtx, DivergenceLimits{})
+	for _, want := range []string{
+		"diverged at function call #5000",
+		"`gen_addsi3`", "`optimize_insn_for_speed_p`",
+		"... (4985 earlier common calls omitted)",
+		"`common_4985`", "`common_4999`",
+		"`base_0`", "`base_9` → ... (290 more calls)",
+		"`mutated_9` → ... (290 more calls)",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report should contain %q", want)
+		}
+	}
+	for _, unwanted := range []string{"`common_4984`", "`common_0`", "`base_10`", "`mutated_10`"} {
+		if strings.Contains(report, unwanted) {
+			t.Errorf("report should not contain %q", unwanted)
+		}
+	}
+	if lines := strings.Count(report, "\n") + 1; lines > 40 {
+		t.Errorf("report has %d lines, want a short section", lines)
+	}
+
+	custom := FormatDivergenceForLLM(divCtx, DivergenceLimits{PrefixEntries: 3, PathEntries: 2})
+	for _, want := range []string{"... (4997 earlier common calls omitted)", "`common_4997`", "`base_1` → ... (298 more calls)"} {
+		if !strings.Contains(custom, want) {
+			t.Errorf("custom-limit report should contain %q", want)
+		}
+	}
+
+	short := FormatDivergenceForLLM(syntheticDivergence(4, 3), DivergenceLimits{})
+	if strings.Contains(short, "omitted") || strings.Contains(short, "more calls") {
+		t.Errorf("nothing should be omitted from a short trace:\n%s", short)
+	}
+
+	builder := NewBuilder(0, "", nil)
+	builder.DivergencePrefixEntries = 3
+	prompt, err := builder.BuildDivergenceRefinedPrompt(&seed.Seed{Content: "int a;"}, &seed.Seed{Content: "int b;"}, divCtx)
+	if err != nil {
+		t.Fatalf("BuildDivergenceRefinedPrompt() failed: %v", err)
+	}
+	if !strings.Contains(prompt, "(4997 earlier common calls omitted)") || strings.Contains(prompt, "`common_0`") {
+		t.Error("refined prompt should use the builder's divergence limits")
+	}
+
+	if FormatDivergenceForLLM(nil, DivergenceLimits{}) != "" {
+		t.Error("nil context should render nothing")
+	}
+}
+
+func TestFormatDivergenceForLLM_FormattedReportFallback(t *testing.T) {
+	var report strings.Builder
+	for i := 0; i < 5000; i++ {
+		report.WriteString(fmt.Sprintf("- `fn_%d`\n", i))
+	}
+
+	got := FormatDivergenceForLLM(&DivergenceContext{FormattedReport: report.String()}, DivergenceLimits{PrefixEntries: 5, PathEntries: 5})
+	if !strings.Contains(got, "`fn_0`") || strings.Contains(got, "`fn_100`") {
+		t.Errorf("fallback report should be clipped:\n%s", got)
+	}
+	if !strings.Contains(got, "more lines)") {
+		t.Error("fallback report should note the omitted lines")
+	}
+}
@@ -0,0 +1,56 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zjy-dev/de-fuzz/internal/corpus"
+)
+
+// Limits for the generate prompt's diversity section.
+const (
+	diversityListSize  = 3 // constructs named on each line
+	minCommonConstruct = 2 // seeds a construct needs to count as common
+)
+
+// diversitySection tells the generator which constructs the corpus already
+// has plenty of and which it lacks, so new seeds do not repeat the same
+// program shape. It is empty when no ConstructStats source is set or the
+// corpus is empty.
+func (b *Builder) diversitySection() string {
+	if b.ConstructStats == nil {
+		return ""
+	}
+	stats := b.ConstructStats()
+	if stats.Seeds == 0 {
+		return ""
+	}
+
+	common := stats.MostUsed(diversityListSize, minCommonConstruct)
+	isCommon := make(map[string]bool, len(common))
+	for _, c := range common {
+		isCommon[c.Name] = true
+	}
+	var rare []corpus.ConstructCount
+	for _, c := range stats.LeastUsed(diversityListSize + len(common)) {
+		if !isCommon[c.Name] && len(rare) < diversityListSize {
+			rare = append(rare, c)
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## Corpus Diversity\n\n")
+	if len(common) > 0 {
+		sb.WriteString(fmt.Sprintf("The corpus (%d seeds) already has many: %s.\n", stats.Seeds, formatConstructs(common)))
+	}
+	sb.WriteString(fmt.Sprintf("Prefer exploring: %s.\n\n", formatConstructs(rare)))
+	return sb.String()
+}
+
+func formatConstructs(counts []corpus.ConstructCount) string {
+	parts := make([]string, len(counts))
+	for i, c := range counts {
+		parts[i] = fmt.Sprintf("%s (%d)", c.Name, c.Seeds)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,60 @@
+package prompt
+
+import "github.com/zjy-dev/de-fuzz/internal/seed"
+
+// languageGuide holds the language-specific wording injected into prompts.
+type languageGuide struct {
+	// Rule is a one-line language requirement for compact rule lists.
+	Rule string
+	// Rules is the detailed LANGUAGE CONSTRAINTS block of the constraint prompt.
+	Rules string
+	// CompileFixes lists common fixes for the compile-error retry prompt.
+	CompileFixes string
+}
+
+var languageGuides = map[seed.Language]languageGuide{
+	seed.LanguageC: {
+		Rule: "Use only C99/C11 standard C code (no C++ features)",
+		Rules: `- Use ONLY C99/C11 standard C code.
+- DO NOT use C++ features (references, auto, lambda, classes, templates, new/delete, etc.).
+- Use standard C types and functions: int, char, void, malloc, free, memset, memcpy, etc.
+- Example of WRONG code: int& ref = x; or auto func = [](int x) { return x; };
+- Example of CORRECT code: int* ref = &x; or void* func(int x) { return (void*)(intptr_t)x; }`,
+		CompileFixes: `- Check for undefined variables or functions
+- Ensure proper C99/C11 syntax (no C++ features)
+- Verify all includes are available in the template
+- Check for missing semicolons or braces`,
+	},
+	seed.LanguageCPP: {
+		Rule: "Use only standard C++17 code in a single translation unit",
+		Rules: `- Use ONLY standard C++17 code in a single translation unit.
+- Classes, templates, lambdas, references and the standard library are allowed.
+- Prefer constructs that exercise C++-specific compiler passes (templates, inlining, exceptions, virtual dispatch).
+- Avoid compiler-specific extensions other than function attributes.`,
+		CompileFixes: `- Check for undefined identifiers and missing declarations
+- Ensure template arguments and overloads resolve unambiguously
+- Verify every used standard header is included
+- Check for missing semicolons after class definitions`,
+	},
+	seed.LanguageRust: {
+		Rule: "Use only stable Rust (2021 edition, std only, no external crates)",
+		Rules: `- Use ONLY stable Rust, 2021 edition.
+- DO NOT use external crates; only std and core are available.
+- The program must define fn main().
+- unsafe blocks are allowed when the target needs raw memory access.`,
+		CompileFixes: `- Check for borrow-checker errors (conflicting mutable borrows, moved values)
+- Ensure integer types match exactly (no implicit conversions)
+- Wrap raw pointer operations in unsafe blocks
+- Check for missing semicolons and unused-result warnings treated as errors`,
+	},
+}
+
+// language returns the builder's seed language, defaulting to C.
+func (b *Builder) language() seed.Language {
+	return b.Language.OrDefault()
+}
+
+// languageGuide returns the prompt wording for the builder's language.
+func (b *Builder) languageGuide() languageGuide {
+	return languageGuides[b.language()]
+}
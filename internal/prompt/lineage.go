@@ -0,0 +1,64 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// maxLineageEntries caps the lineage section at about ten lines including
+// its heading and closing hint.
+const maxLineageEntries = 8
+
+// maxLineageNoteChars clips each lineage entry.
+const maxLineageNoteChars = 160
+
+// lineageSection summarizes what s and its ancestors (nearest first, from
+// MutationContext.Ancestors) changed, so the LLM does not undo earlier
+// mutations. It is empty when LineageDepth is 0 or s is an initial seed.
+func (b *Builder) lineageSection(s *seed.Seed, mutationCtx *MutationContext) string {
+	if b.LineageDepth <= 0 || s == nil || s.Meta.ParentID == 0 {
+		return ""
+	}
+
+	chain := []*seed.Seed{s}
+	if mutationCtx != nil {
+		chain = append(chain, mutationCtx.Ancestors...)
+	}
+	chain = chain[:min(len(chain), b.LineageDepth+1, maxLineageEntries)]
+
+	var sb strings.Builder
+	sb.WriteString("**Lineage (most recent change first):**\n")
+	for i, node := range chain {
+		label := fmt.Sprintf("#%d", node.Meta.ID)
+		if i == 0 {
+			label = "this seed (" + label + ")"
+		}
+		sb.WriteString(fmt.Sprintf("- %s: %s\n", label, lineageNote(chain, i)))
+	}
+	sb.WriteString("Build on these changes; do not reintroduce code an ancestor removed.\n\n")
+	return sb.String()
+}
+
+// lineageNote returns the stored mutation note of chain[i], falling back to
+// a diff against its parent when the parent is the next entry in the chain.
+func lineageNote(chain []*seed.Seed, i int) string {
+	node := chain[i]
+	note := node.Meta.MutationNote
+	switch {
+	case note != "":
+	case node.Meta.ParentID == 0:
+		note = "initial seed"
+	case i+1 < len(chain) && chain[i+1].Meta.ID == node.Meta.ParentID:
+		note = seed.SummarizeDiff(chain[i+1].Content, node.Content, node.Language)
+	default:
+		note = "no summary available"
+	}
+
+	note = strings.Join(strings.Fields(note), " ")
+	if runes := []rune(note); len(runes) > maxLineageNoteChars {
+		note = string(runes[:maxLineageNoteChars-3]) + "..."
+	}
+	return note
+}
@@ -1,11 +1,13 @@
 package prompt
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
 
+	"github.com/zjy-dev/de-fuzz/internal/corpus"
+	"github.com/zjy-dev/de-fuzz/internal/logger"
 	"github.com/zjy-dev/de-fuzz/internal/prompt/mechanism"
 	"github.com/zjy-dev/de-fuzz/internal/seed"
 )
@@ -26,6 +28,10 @@ type MutationContext struct {
 
 	// TotalLines is the total number of lines to cover
 	TotalLines int
+
+	// Ancestors are the mutated seed's ancestors from the corpus, nearest
+	// first, for the lineage section (see Builder.LineageDepth)
+	Ancestors []*seed.Seed
 }
 
 // Builder is responsible for constructing prompts for the LLM.
@@ -41,8 +47,99 @@ type Builder struct {
 	// Mechanism is the defense-mechanism contract that drives template validation
 	// and prompt injection. May be nil when not in function-template mode.
 	Mechanism mechanism.Contract
+
+	// TemplateDir optionally overrides the embedded prompt templates.
+	// Files named like the defaults (e.g. constraint.tmpl) replace them;
+	// missing files fall back to the embedded versions.
+	TemplateDir string
+
+	// TokenBudget caps the estimated size of constraint-solving and refined
+	// prompts. When exceeded, the function context and base seed are trimmed
+	// so the instructions at the end of the prompt survive. 0 = unlimited.
+	TokenBudget int
+
+	// Tokenizer overrides the default chars/4 token estimate (optional).
+	Tokenizer TokenCounter
+
+	// contextBudget further caps TokenBudget while a PromptService with a
+	// context limit builds a prompt: what the system prompt leaves of the
+	// model's context window. 0 = no cap.
+	contextBudget int
+
+	// MaxPriorAttempts is how many earlier failed seeds the refined prompt lists
+	// as negative examples; PriorAttemptMaxChars clips each of them (0 = no clip).
+	MaxPriorAttempts     int
+	PriorAttemptMaxChars int
+
+	// TestCaseSeparator is the marker the LLM places between code and JSON
+	// test cases. Defaults to seed.TestCaseSeparator.
+	TestCaseSeparator string
+
+	// Language is the seed source language (c, cpp, rust). It selects prompt
+	// wording and code-fence tags, and is stamped on parsed seeds. Empty means C.
+	Language seed.Language
+
+	// StructuredOutput switches the response contract to a single JSON object
+	// {"source": ..., "test_cases": [...], "cflags": [...]} instead of code
+	// followed by TestCaseSeparator and a JSON array.
+	StructuredOutput bool
+
+	// RequestTags asks the generate and mutate prompts to label the seed
+	// with the vulnerability patterns it exercises: a "tags" field in the
+	// structured contract, a seed.TagsMarker line otherwise. Tags are
+	// parsed into Metadata.Tags whenever a response carries them.
+	RequestTags bool
+
+	// AuxContextFiles are extra notes (stack layout, calling convention, pass
+	// docs, ...) read from the strategy base directory and rendered into the
+	// understand and generate prompts, in order. Defaults to stack_layout.md.
+	// AuxContextMaxBytes caps each file (0 = no cap).
+	AuxContextFiles    []string
+	AuxContextMaxBytes int
+
+	// StrictTestCases rejects responses with more than MaxTestCases test
+	// cases instead of keeping the first MaxTestCases. AllowShellTestCommands
+	// permits pipes and command chaining in running commands.
+	StrictTestCases        bool
+	AllowShellTestCommands bool
+
+	// CandidatesPerCall asks the constraint-solving prompt for up to this many
+	// alternative seeds in one response, each after a numbered
+	// CandidateDelimiter header. 0 or 1 = a single seed.
+	CandidatesPerCall int
+
+	// LineageDepth is how many ancestors of the mutated seed the mutate
+	// prompt summarizes, from MutationContext.Ancestors. 0 = no lineage section.
+	LineageDepth int
+
+	// DisableSystemOverrides ignores SystemPromptOverride on DivergenceInfo
+	// and CompileErrorInfo and the built-in repair system message, so every
+	// call uses its phase system prompt.
+	DisableSystemOverrides bool
+
+	// DivergencePrefixEntries and DivergencePathEntries bound the uftrace
+	// divergence shown by BuildDivergenceRefinedPrompt: the last common-prefix
+	// calls and the first calls of each divergent path. 0 = defaults.
+	DivergencePrefixEntries int
+	DivergencePathEntries   int
+
+	// ConstructStats profiles the current corpus (optional). When set, the
+	// generate prompt names constructs the corpus already has many of and
+	// the least used ones to explore instead.
+	ConstructStats func() corpus.ConstructStats
+
+	// Compiler describes the compiler under test. When set, the generate,
+	// mutate and constraint-solving prompts include a short "Compilation
+	// Environment" section.
+	Compiler *CompilerContext
 }
 
+// Defaults for the refined prompt's "already tried" section.
+const (
+	DefaultMaxPriorAttempts     = 5
+	DefaultPriorAttemptMaxChars = 1200
+)
+
 // NewBuilder creates a new prompt builder.
 // maxTestCases specifies the maximum number of test cases to generate per seed.
 // If maxTestCases is 0, test case generation will be disabled in prompts.
@@ -54,6 +151,12 @@ func NewBuilder(maxTestCases int, functionTemplate string, c mechanism.Contract)
 		MaxTestCases:     maxTestCases,
 		FunctionTemplate: functionTemplate,
 		Mechanism:        c,
+
+		MaxPriorAttempts:     DefaultMaxPriorAttempts,
+		PriorAttemptMaxChars: DefaultPriorAttemptMaxChars,
+		TestCaseSeparator:    seed.TestCaseSeparator,
+		Language:             seed.LanguageC,
+		AuxContextMaxBytes:   DefaultAuxContextMaxBytes,
 	}
 }
 
@@ -64,96 +167,117 @@ func readFileOrDefault(path string) (string, error) {
 	content, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return "Not available for now", nil
+			return notAvailable, nil
 		}
 		return "", err
 	}
 	return string(content), nil
 }
 
+// BuildUnderstandPrompt constructs the prompt asking the LLM to summarize its
+// understanding of the target defense mechanism. The answer is saved as
+// understanding.md and used as the system prompt of later phases.
+// Auxiliary context files (AuxContextFiles) are read from basePath; missing
+// ones are shown as "Not available for now".
+func (b *Builder) BuildUnderstandPrompt(isa, strategy, basePath string) (string, error) {
+	if isa == "" || strategy == "" {
+		return "", fmt.Errorf("isa and strategy must be provided")
+	}
+
+	auxContext, err := b.readAuxContext(basePath, false)
+	if err != nil {
+		return "", err
+	}
+
+	data := b.newTemplateData()
+	data.ISA = isa
+	data.Strategy = strategy
+	data.AuxContext = auxContext
+	return b.renderTemplate(UnderstandTemplate, data)
+}
+
 // BuildGeneratePrompt constructs a prompt to generate a new seed.
 func (b *Builder) BuildGeneratePrompt(basePath string) (string, error) {
-	// Read stack layout if available (optional)
-	stackLayoutSection := ""
-	stackLayoutPath := filepath.Join(basePath, "stack_layout.md")
-	if stackLayout, err := os.ReadFile(stackLayoutPath); err == nil {
-		stackLayoutSection = fmt.Sprintf("\n**Stack Layout Reference:**\n%s\n", string(stackLayout))
+	data := b.newTemplateData()
+
+	// Auxiliary context is optional here; missing files are left out
+	auxContext, err := b.readAuxContext(basePath, true)
+	if err != nil {
+		return "", err
 	}
+	data.AuxContext = auxContext
 
 	// Read template if configured
-	var templateSection string
 	if b.FunctionTemplate != "" {
 		templateContent, err := os.ReadFile(b.FunctionTemplate)
 		if err != nil {
 			return "", fmt.Errorf("failed to read function template: %w", err)
 		}
-		templateSection = fmt.Sprintf(`
-**Code Template:**
-Implement ONLY the function marked with FUNCTION_PLACEHOLDER. Do NOT include the template.
-
-%s
-`, string(templateContent))
+		data.FunctionTemplateCode = string(templateContent)
 	}
 
-	// Build output format
-	outputFormat := b.buildOutputFormat()
-
-	// Build the prompt
-	var prompt strings.Builder
-	prompt.WriteString("Generate C code for compiler fuzzing.\n\n")
+	data.Diversity = b.diversitySection()
+	data.OutputFormat = b.buildOutputFormat()
+	return b.renderTemplate(GenerateTemplate, data)
+}
 
-	if b.FunctionTemplate != "" {
-		prompt.WriteString("**Task:** Implement the function body that tests compiler security features.\n\n")
-	} else {
-		prompt.WriteString("**Task:** Generate complete C source code that tests compiler security features.\n\n")
-	}
+// testCaseKeysNote documents the optional keys of a delimiter-based test case.
+const testCaseKeysNote = `Optional test case keys: "stdin" (input fed to the program), ` +
+	`"timeout seconds" (time limit for this case) and "env" (object of environment variables).`
 
-	prompt.WriteString(`**Requirements:**
-- Complete, compilable C99/C11 code
-- Focus on patterns that may trigger compiler bugs: buffer/integer overflows, format strings, pointer manipulation
-- Output ONLY code, no explanations
-`)
+// exampleTags are the patterns the tag request offers as examples.
+const exampleTags = "vla, alloca, longjmp, fmt-string"
 
-	if b.MaxTestCases > 0 {
-		prompt.WriteString(fmt.Sprintf("- Include 1-%d test cases after the code\n", b.MaxTestCases))
+// buildOutputFormat returns the output format instructions based on configuration.
+func (b *Builder) buildOutputFormat() string {
+	if b.StructuredOutput {
+		return b.structuredOutputFormat(false)
 	}
+	return b.delimiterOutputFormat() + b.tagsNote()
+}
 
-	prompt.WriteString(stackLayoutSection)
-	prompt.WriteString(templateSection)
-	prompt.WriteString("\n")
-	prompt.WriteString(outputFormat)
-
-	return prompt.String(), nil
+// tagsNote asks for the TAGS line of a delimiter-format response; it is
+// empty unless RequestTags is set.
+func (b *Builder) tagsNote() string {
+	if !b.RequestTags {
+		return ""
+	}
+	return "\n\nBegin the response with a line `" + seed.TagsMarker + ` ["tag", ...]` +
+		"` listing the vulnerability patterns the code exercises, lowercase (e.g. " + exampleTags + ")."
 }
 
-// buildOutputFormat returns the output format instructions based on configuration.
-func (b *Builder) buildOutputFormat() string {
+// delimiterOutputFormat describes the code-then-separator response contract.
+func (b *Builder) delimiterOutputFormat() string {
 	if b.FunctionTemplate != "" && b.MaxTestCases > 0 {
 		return fmt.Sprintf(`**Output Format:**
 [function_code]
-// ||||| JSON_TESTCASES_START |||||
+%s
 [{"running command": "./prog", "expected result": "..."}]
 
-Output ONLY function code, then separator, then %d-%d JSON test cases. No markdown.`, 1, b.MaxTestCases)
+Output ONLY function code, then separator, then %d-%d JSON test cases. No markdown.
+`+testCaseKeysNote+`%s`,
+			b.testCaseSeparator(), 1, b.MaxTestCases, b.multiFunctionNote())
 	}
 	if b.FunctionTemplate != "" {
 		return `**Output Format:**
 [function_code]
 
-Output ONLY the function implementation. No markdown, no explanations.`
+Output ONLY the function implementation. No markdown, no explanations.` + b.multiFunctionNote()
 	}
 	if b.MaxTestCases > 0 {
 		return `**Output Format:**
-[C source code]
-// ||||| JSON_TESTCASES_START |||||
+[` + b.language().DisplayName() + ` source code]
+` + b.testCaseSeparator() + `
 [{"running command": "./prog", "expected result": "..."}]
 
-Output code, separator, then JSON test cases. No markdown.`
+Output code, separator, then JSON test cases. No markdown.
+` + testCaseKeysNote
 	}
+	lang := b.language().DisplayName()
 	return `**Output Format:**
-[C source code]
+[` + lang + ` source code]
 
-Output ONLY C source code. No markdown, no explanations.`
+Output ONLY ` + lang + ` source code. No markdown, no explanations.`
 }
 
 // BuildMutatePrompt constructs a prompt to mutate an existing seed.
@@ -163,47 +287,19 @@ func (b *Builder) BuildMutatePrompt(s *seed.Seed, mutationCtx *MutationContext)
 		return "", fmt.Errorf("seed must be provided")
 	}
 
-	var prompt strings.Builder
-
-	// Include the existing seed
-	prompt.WriteString("**Existing Seed to Mutate:**\n```c\n")
-	prompt.WriteString(s.Content)
-	prompt.WriteString("\n```\n\n")
-
-	// Include test cases if any
+	data := b.newTemplateData()
+	data.Seed = s
+	data.Mutation = mutationCtx
+	data.Lineage = b.lineageSection(s, mutationCtx)
 	if len(s.TestCases) > 0 {
-		prompt.WriteString("**Test Cases:**\n")
-		for _, tc := range s.TestCases {
-			prompt.WriteString(fmt.Sprintf("- Command: `%s` → Expected: %s\n", tc.RunningCommand, tc.ExpectedResult))
+		testCasesJSON, err := seed.EncodeTestCases(s.TestCases)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode test cases: %w", err)
 		}
-		prompt.WriteString("\n")
-	}
-
-	// Build coverage context section if available
-	if mutationCtx != nil && mutationCtx.TotalCoveragePercentage > 0 {
-		prompt.WriteString(fmt.Sprintf(`**Coverage Context:**
-- Current coverage: %.1f%% (%d/%d lines)
-%s
-
-Focus mutations on:
-1. Similar patterns that increased coverage
-2. Edge cases around newly covered code
-
-`, mutationCtx.TotalCoveragePercentage, mutationCtx.TotalCoveredLines, mutationCtx.TotalLines, mutationCtx.CoverageIncreaseSummary))
+		data.TestCasesJSON = testCasesJSON
 	}
-
-	prompt.WriteString(`**Task:** Mutate this seed to explore different compiler code paths.
-
-**Requirements:**
-- Make focused, meaningful changes
-- Preserve overall structure and main()
-- Target different compiler optimizations or security checks
-- Output ONLY code, no explanations
-
-`)
-	prompt.WriteString(b.buildOutputFormat())
-
-	return prompt.String(), nil
+	data.OutputFormat = b.buildOutputFormat()
+	return b.renderTemplate(MutateTemplate, data)
 }
 
 // BuildAnalyzePrompt constructs a prompt to analyze execution feedback.
@@ -212,23 +308,15 @@ func (b *Builder) BuildAnalyzePrompt(s *seed.Seed, feedback string) (string, err
 		return "", fmt.Errorf("seed and feedback must be provided")
 	}
 
-	// Convert test cases to JSON for display
-	testCasesJSON := "[\n"
-	for i, tc := range s.TestCases {
-		if i > 0 {
-			testCasesJSON += ",\n"
-		}
-		testCasesJSON += fmt.Sprintf(`  {
-    "running command": "%s",
-    "expected result": "%s"
-  }`, tc.RunningCommand, tc.ExpectedResult)
+	testCasesJSON, err := seed.EncodeTestCases(s.TestCases)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode test cases: %w", err)
 	}
-	testCasesJSON += "\n]"
 
 	prompt := fmt.Sprintf(`
 [SEED]
 %s
-// ||||| JSON_TESTCASES_START |||||
+%s
 %s
 [/SEED]
 
@@ -243,7 +331,7 @@ Provide insights about:
 3. Suggestions for further exploration
 
 Please provide a concise but informative analysis.
-`, s.Content, testCasesJSON, feedback)
+`, s.Content, b.testCaseSeparator(), testCasesJSON, feedback)
 	return prompt, nil
 }
 
@@ -263,7 +351,8 @@ type DivergenceContext struct {
 	BasePath    []string
 	MutatedPath []string
 
-	// Formatted string for LLM (from DivergencePoint.ForLLM())
+	// Formatted string for LLM (from DivergencePoint.ForLLM()). Only used
+	// when the fields above are empty; see FormatDivergenceForLLM.
 	FormattedReport string
 }
 
@@ -281,23 +370,27 @@ func (b *Builder) BuildDivergenceRefinedPrompt(
 
 	// Build output format based on MaxTestCases and FunctionTemplate
 	var outputFormat string
-	if b.FunctionTemplate != "" && b.MaxTestCases > 0 {
+	outputKind := "code"
+	if b.StructuredOutput {
+		outputFormat = b.structuredOutputFormat(false)
+		outputKind = "JSON object"
+	} else if b.FunctionTemplate != "" && b.MaxTestCases > 0 {
 		outputFormat = fmt.Sprintf(`**Output Format:**
-Output ONLY the function code, then "// ||||| JSON_TESTCASES_START |||||", then %d-%d test cases in JSON format.`, 1, b.MaxTestCases)
+Output ONLY the function code, then "%s", then %d-%d test cases in JSON format.`, b.testCaseSeparator(), 1, b.MaxTestCases)
 	} else if b.FunctionTemplate != "" {
 		outputFormat = `**Output Format:**
 Output ONLY the function implementation code.`
 	} else if b.MaxTestCases > 0 {
 		outputFormat = `**Output Format:**
-Output C source code, then "// ||||| JSON_TESTCASES_START |||||", then JSON test cases.`
+Output ` + b.language().DisplayName() + ` source code, then "` + b.testCaseSeparator() + `", then JSON test cases.`
 	} else {
 		outputFormat = `**Output Format:**
-Output ONLY the mutated C source code.`
+Output ONLY the mutated ` + b.language().DisplayName() + ` source code.`
 	}
 
 	// Build divergence section
 	divergenceSection := ""
-	if divCtx != nil && divCtx.FormattedReport != "" {
+	if report := FormatDivergenceForLLM(divCtx, b.divergenceLimits()); report != "" {
 		divergenceSection = fmt.Sprintf(`
 [DIVERGENCE ANALYSIS]
 The previous mutation did not achieve the target coverage. Here's where the execution paths diverged:
@@ -312,7 +405,7 @@ The previous mutation did not achieve the target coverage. Here's where the exec
 **Hint:** Look at the common prefix functions - these represent the shared execution path. 
 The divergence function names often indicate what kind of code pattern is being compiled differently.
 [/DIVERGENCE ANALYSIS]
-`, divCtx.FormattedReport, divCtx.BaseFunction, divCtx.MutatedFunction)
+`, report, divCtx.BaseFunction, divCtx.MutatedFunction)
 	}
 
 	prompt := fmt.Sprintf(`
@@ -336,8 +429,8 @@ Your mutation should preserve those patterns while still introducing variation.
 
 %s
 
-**IMPORTANT:** Do NOT include markdown code blocks or explanations. Output only the code.
-`, baseSeed.Content, mutatedSeed.Content, divergenceSection, outputFormat)
+**IMPORTANT:** Do NOT include markdown code blocks or explanations. Output only the %s.
+`, baseSeed.Content, mutatedSeed.Content, divergenceSection, outputFormat, outputKind)
 
 	// Debug: Log prompts (disabled for performance profiling)
 	// logger.Debug("\n%s", strings.Repeat("=", 80))
@@ -357,8 +450,56 @@ Your mutation should preserve those patterns while still introducing variation.
 // 4. Standard mode: Extracts code with test cases using ParseSeedFromLLMResponse
 //
 // In all modes, it also extracts CFlags if present in the response.
+// With StructuredOutput set, the response is instead decoded as a single JSON
+// object; see parseStructuredResponse.
+// Test cases are then checked against MaxTestCases and the running-command rules.
 // Returns a Seed with Content, TestCases, and CFlags populated appropriately.
 func (b *Builder) ParseLLMResponse(response string) (*seed.Seed, error) {
+	var tags []string
+	if !b.StructuredOutput {
+		tags = seed.ParseTagsFromResponse(response)
+		response = seed.ExtractCodeWithoutTags(response)
+	}
+	s, err := b.parseResponse(response)
+	if err != nil {
+		return nil, err
+	}
+	if tags != nil {
+		s.Meta.Tags = tags
+	}
+	if b.MaxTestCases > 0 {
+		if s.TestCases, err = b.enforceTestCases(s.TestCases); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// enforceTestCases applies the MaxTestCases limit and validates each running
+// command. Excess test cases are dropped with a warning, or rejected when
+// StrictTestCases is set so the caller can retry.
+func (b *Builder) enforceTestCases(testCases []seed.TestCase) ([]seed.TestCase, error) {
+	if len(testCases) > b.MaxTestCases {
+		if b.StrictTestCases {
+			return nil, fmt.Errorf("response has %d test cases, at most %d allowed", len(testCases), b.MaxTestCases)
+		}
+		logger.Warn("[Prompt] response has %d test cases, keeping the first %d", len(testCases), b.MaxTestCases)
+		testCases = testCases[:b.MaxTestCases]
+	}
+	for i, tc := range testCases {
+		if err := seed.ValidateRunningCommand(tc.RunningCommand, b.AllowShellTestCommands); err != nil {
+			return nil, fmt.Errorf("test case %d: %w", i+1, err)
+		}
+	}
+	return testCases, nil
+}
+
+// parseResponse extracts the seed for the configured mode.
+func (b *Builder) parseResponse(response string) (*seed.Seed, error) {
+	if b.StructuredOutput {
+		return b.parseStructuredResponse(response)
+	}
+
 	// Extract CFlags first (before removing the section from response)
 	cflags := seed.ParseCFlagsFromResponse(response)
 
@@ -374,13 +515,13 @@ func (b *Builder) ParseLLMResponse(response string) (*seed.Seed, error) {
 		}
 
 		// Parse function code and test cases from response
-		functionCode, testCases, err := seed.ParseFunctionWithTestCasesFromLLMResponse(cleanResponse)
+		functionCode, testCases, err := seed.ParseFunctionWithTestCasesWithSeparator(cleanResponse, b.testCaseSeparator())
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse function with test cases from response: %w", err)
 		}
 
 		// Merge function into template
-		mergedCode, err := seed.MergeTemplate(string(templateContent), functionCode)
+		mergedCode, err := mergeFunctionTemplate(string(templateContent), functionCode)
 		if err != nil {
 			return nil, fmt.Errorf("failed to merge function into template: %w", err)
 		}
@@ -396,6 +537,7 @@ func (b *Builder) ParseLLMResponse(response string) (*seed.Seed, error) {
 			Content:   mergedCode,
 			TestCases: testCases,
 			CFlags:    cflags,
+			Language:  b.language(),
 		}, nil
 	}
 
@@ -414,7 +556,7 @@ func (b *Builder) ParseLLMResponse(response string) (*seed.Seed, error) {
 		}
 
 		// Merge function into template
-		mergedCode, err := seed.MergeTemplate(string(templateContent), functionCode)
+		mergedCode, err := mergeFunctionTemplate(string(templateContent), functionCode)
 		if err != nil {
 			return nil, fmt.Errorf("failed to merge function into template: %w", err)
 		}
@@ -430,6 +572,7 @@ func (b *Builder) ParseLLMResponse(response string) (*seed.Seed, error) {
 			Content:   mergedCode,
 			TestCases: []seed.TestCase{},
 			CFlags:    cflags,
+			Language:  b.language(),
 		}, nil
 	}
 
@@ -444,11 +587,12 @@ func (b *Builder) ParseLLMResponse(response string) (*seed.Seed, error) {
 			Content:   sourceCode,
 			TestCases: []seed.TestCase{},
 			CFlags:    cflags,
+			Language:  b.language(),
 		}, nil
 	}
 
 	// Mode 4: Standard mode with test cases
-	sourceCode, testCases, err := seed.ParseSeedFromLLMResponse(cleanResponse)
+	sourceCode, testCases, err := seed.ParseSeedWithSeparator(cleanResponse, b.testCaseSeparator())
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse seed from response: %w", err)
 	}
@@ -457,9 +601,190 @@ func (b *Builder) ParseLLMResponse(response string) (*seed.Seed, error) {
 		Content:   sourceCode,
 		TestCases: testCases,
 		CFlags:    cflags,
+		Language:  b.language(),
 	}, nil
 }
 
+// parseStructuredResponse decodes a structured-output response. In function
+// template mode "source" holds the function code and is merged into the template.
+func (b *Builder) parseStructuredResponse(response string) (*seed.Seed, error) {
+	resp, err := seed.ParseStructuredResponse(response, b.MaxTestCases > 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse structured response: %w", err)
+	}
+
+	content := resp.Source
+	if b.FunctionTemplate != "" {
+		templateContent, err := os.ReadFile(b.FunctionTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read function template: %w", err)
+		}
+		content, err = mergeFunctionTemplate(string(templateContent), resp.Source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to merge function into template: %w", err)
+		}
+		if b.Mechanism != nil {
+			if err := seed.EnsureMarkers(content, b.Mechanism.RequiredMarkers()); err != nil {
+				return nil, fmt.Errorf("template merge validation failed: %w", err)
+			}
+		}
+	}
+
+	testCases := resp.TestCases
+	if b.MaxTestCases == 0 {
+		testCases = []seed.TestCase{}
+	}
+	return &seed.Seed{
+		Meta:      seed.Metadata{Tags: resp.Tags},
+		Content:   content,
+		TestCases: testCases,
+		CFlags:    resp.CFlags,
+		Language:  b.language(),
+	}, nil
+}
+
+// structuredOutputFormat describes the JSON response contract. withCFlags adds
+// the optional "cflags" field (constraint-solving prompts).
+func (b *Builder) structuredOutputFormat(withCFlags bool) string {
+	source := "complete " + b.language().DisplayName() + " source code"
+	if b.FunctionTemplate != "" {
+		source = "the function implementation only (no main(), no #include)"
+	}
+
+	fields := []string{`"source": "..."`}
+	var sb strings.Builder
+	sb.WriteString("**Output Format:** Respond with a single JSON object and nothing else. No markdown, no explanations.\n\n")
+	sb.WriteString("- `source`: " + source + ", as a JSON string with newlines escaped as \\n\n")
+	if b.MaxTestCases > 0 {
+		fields = append(fields, `"test_cases": [{"running command": "./prog", "expected result": "..."}]`)
+		sb.WriteString(fmt.Sprintf("- `test_cases`: 1-%d objects with \"running command\", \"expected result\", \"stdin\" "+
+			"(the program's standard input, \"\" if it reads none) and \"timeout seconds\" (time limit for the case, null for the default)\n", b.MaxTestCases))
+	}
+	if withCFlags {
+		fields = append(fields, `"cflags": ["-flag1"]`)
+		sb.WriteString("- `cflags` (optional): extra compiler flags, appended after the default config/profile flags. " +
+			"Keep the defense mechanism enabled; flags that disable it are rejected.\n")
+	}
+	if b.RequestTags {
+		fields = append(fields, `"tags": ["vla"]`)
+		sb.WriteString("- `tags`: the vulnerability patterns the code exercises, lowercase (e.g. " + exampleTags + ")\n")
+	}
+
+	sb.WriteString("\nExample:\n{" + strings.Join(fields, ", ") + "}")
+	sb.WriteString(b.multiFunctionNote())
+	return sb.String()
+}
+
+// ResponseSchema returns the JSON Schema of a structured-output response,
+// for providers that can enforce one, or nil when prompts do not use the
+// structured contract. It is also nil when constraint-solving prompts ask
+// for several candidates, since those arrive wrapped in another object.
+// The schema is strict: every field is required and "cflags" is null when
+// the prompt does not ask for flags; with RequestTags, "tags" is added the
+// same way. Test case "env" is left out, since a strict schema cannot
+// describe a free-form object.
+func (b *Builder) ResponseSchema() json.RawMessage {
+	if !b.StructuredOutput || b.candidatesPerCall() > 1 {
+		return nil
+	}
+
+	type object = map[string]any
+	str := object{"type": "string"}
+	properties := object{
+		"source": str,
+		"cflags": object{"type": []string{"array", "null"}, "items": str},
+	}
+	required := []string{"source", "cflags"}
+	if b.RequestTags {
+		properties["tags"] = object{"type": []string{"array", "null"}, "items": str}
+		required = append(required, "tags")
+	}
+	if b.MaxTestCases > 0 {
+		properties["test_cases"] = object{
+			"type": "array",
+			"items": object{
+				"type": "object",
+				"properties": object{
+					"running command": str,
+					"expected result": str,
+					"stdin":           str,
+					"timeout seconds": object{"type": []string{"integer", "null"}},
+				},
+				"required":             []string{"running command", "expected result", "stdin", "timeout seconds"},
+				"additionalProperties": false,
+			},
+		}
+		required = append(required, "test_cases")
+	}
+	schema, err := json.Marshal(object{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	})
+	if err != nil {
+		return nil
+	}
+	return schema
+}
+
+// mergeFunctionTemplate merges the LLM's function code into the template.
+// Templates with several placeholders get each function by declarator name.
+func mergeFunctionTemplate(template, functionCode string) (string, error) {
+	if len(seed.ParseTemplateSlots(template)) < 2 {
+		return seed.MergeTemplate(template, functionCode)
+	}
+
+	functions, err := seed.SplitFunctions(functionCode)
+	if err != nil {
+		return "", fmt.Errorf("failed to split response into functions: %w", err)
+	}
+	return seed.MergeTemplateFunctions(template, functions)
+}
+
+// functionSlots returns the placeholders of the function template, or nil
+// when there is no template or it cannot be read.
+func (b *Builder) functionSlots() []seed.TemplateSlot {
+	if b.FunctionTemplate == "" {
+		return nil
+	}
+	content, err := os.ReadFile(b.FunctionTemplate)
+	if err != nil {
+		return nil
+	}
+	return seed.ParseTemplateSlots(string(content))
+}
+
+// multiFunctionNote lists the functions the LLM must emit when the template
+// has more than one placeholder. It is empty for single-placeholder templates.
+func (b *Builder) multiFunctionNote() string {
+	slots := b.functionSlots()
+	if len(slots) < 2 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\n\n**Multiple Functions Required:** The template has one slot per function below.\n")
+	sb.WriteString("Output ALL of them as complete definitions, each preceded by a `// FUNCTION: <name>` label line:\n")
+	for _, slot := range slots {
+		signature := slot.Signature
+		if signature == "" {
+			signature = slot.Name + "(...)"
+		}
+		sb.WriteString("- `" + signature + "`\n")
+	}
+	sb.WriteString("Use exactly these names and do NOT output any other functions.")
+	return sb.String()
+}
+
+// testCaseSeparator returns the configured separator or the default.
+func (b *Builder) testCaseSeparator() string {
+	if b.TestCaseSeparator == "" {
+		return seed.TestCaseSeparator
+	}
+	return b.TestCaseSeparator
+}
+
 // IsFunctionTemplateMode returns true if the builder is configured for function template mode
 func (b *Builder) IsFunctionTemplateMode() bool {
 	return b.FunctionTemplate != ""
@@ -1,10 +1,14 @@
 package prompt
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/zjy-dev/de-fuzz/internal/corpus"
 	"github.com/zjy-dev/de-fuzz/internal/seed"
 
 	"github.com/stretchr/testify/assert"
@@ -49,6 +53,57 @@ func TestBuilder_BuildGeneratePrompt(t *testing.T) {
 	})
 }
 
+func TestBuilder_AuxContextFiles(t *testing.T) {
+	basePath := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(basePath, "stack_layout.md"), []byte("STACK NOTES"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(basePath, "docs"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(basePath, "docs", "calling_convention.md"), []byte("CC NOTES"), 0644))
+
+	builder := NewBuilder(0, "", nil)
+	builder.AuxContextFiles = []string{"docs/calling_convention.md", "stack_layout.md", "known-cves.md"}
+
+	t.Run("understand prompt renders every file in order", func(t *testing.T) {
+		prompt, err := builder.BuildUnderstandPrompt("x64", "cfi", basePath)
+		require.NoError(t, err)
+
+		cc := strings.Index(prompt, "[CALLING CONVENTION]\nCC NOTES\n[/CALLING CONVENTION]")
+		stack := strings.Index(prompt, "[STACK LAYOUT]\nSTACK NOTES\n[/STACK LAYOUT]")
+		cves := strings.Index(prompt, "[KNOWN CVES]\nNot available for now\n[/KNOWN CVES]")
+		require.True(t, cc >= 0 && stack >= 0 && cves >= 0, prompt)
+		assert.Less(t, cc, stack)
+		assert.Less(t, stack, cves)
+	})
+
+	t.Run("generate prompt skips missing files", func(t *testing.T) {
+		prompt, err := builder.BuildGeneratePrompt(basePath)
+		require.NoError(t, err)
+
+		assert.Less(t, strings.Index(prompt, "**Calling Convention Reference:**"), strings.Index(prompt, "**Stack Layout Reference:**"))
+		assert.Contains(t, prompt, "CC NOTES")
+		assert.NotContains(t, prompt, "Known Cves")
+	})
+
+	t.Run("files are clipped to the size cap", func(t *testing.T) {
+		long := strings.Repeat("line of notes\n", 100)
+		require.NoError(t, os.WriteFile(filepath.Join(basePath, "long.md"), []byte(long), 0644))
+		capped := NewBuilder(0, "", nil)
+		capped.AuxContextFiles = []string{"long.md"}
+		capped.AuxContextMaxBytes = 100
+
+		prompt, err := capped.BuildGeneratePrompt(basePath)
+		require.NoError(t, err)
+		assert.Contains(t, prompt, "bytes omitted]")
+		assert.Equal(t, 100/len("line of notes\n"), strings.Count(prompt, "line of notes"))
+	})
+
+	t.Run("defaults to stack_layout.md", func(t *testing.T) {
+		prompt, err := NewBuilder(0, "", nil).BuildGeneratePrompt(basePath)
+		require.NoError(t, err)
+		assert.Contains(t, prompt, "STACK NOTES")
+		assert.NotContains(t, prompt, "CC NOTES")
+	})
+}
+
 func TestBuilder_BuildMutatePrompt(t *testing.T) {
 	builder := NewBuilder(3, "", nil)
 	testCases := []seed.TestCase{
@@ -87,12 +142,106 @@ func TestBuilder_BuildMutatePrompt(t *testing.T) {
 		assert.Contains(t, prompt, "Covered 10 new lines in function foo")
 	})
 
+	t.Run("should escape test cases as JSON", func(t *testing.T) {
+		quoted := &seed.Seed{
+			Content:   "int main() { return 0; }",
+			TestCases: []seed.TestCase{{RunningCommand: `./prog "x\y"`, ExpectedResult: "ok"}},
+		}
+		prompt, err := builder.BuildMutatePrompt(quoted, nil)
+		require.NoError(t, err)
+		assert.Contains(t, prompt, `"running command": "./prog \"x\\y\""`)
+	})
+
 	t.Run("should return error if seed is nil", func(t *testing.T) {
 		_, err := builder.BuildMutatePrompt(nil, nil)
 		assert.Error(t, err)
 	})
 }
 
+func TestBuilder_MutatePromptLineage(t *testing.T) {
+	root := &seed.Seed{Meta: seed.Metadata{ID: 1}, Content: "int main() {\n  char buf[16];\n  return 0;\n}"}
+	mid := &seed.Seed{Meta: seed.Metadata{ID: 4, ParentID: 1}, Content: "int main() {\n  char buf[64];\n  return 0;\n}"}
+	leaf := &seed.Seed{
+		Meta:    seed.Metadata{ID: 9, ParentID: 4, MutationNote: "removed the alloca call"},
+		Content: "int main() {\n  char buf[64];\n  buf[0] = 1;\n  return 0;\n}",
+	}
+	mutationCtx := &MutationContext{Ancestors: []*seed.Seed{mid, root}}
+
+	t.Run("off by default", func(t *testing.T) {
+		prompt, err := NewBuilder(0, "", nil).BuildMutatePrompt(leaf, mutationCtx)
+		require.NoError(t, err)
+		assert.NotContains(t, prompt, "Lineage")
+	})
+
+	t.Run("summarizes ancestors", func(t *testing.T) {
+		builder := NewBuilder(0, "", nil)
+		builder.LineageDepth = 3
+		prompt, err := builder.BuildMutatePrompt(leaf, mutationCtx)
+		require.NoError(t, err)
+		assert.Contains(t, prompt, "**Lineage (most recent change first):**\n"+
+			"- this seed (#9): removed the alloca call\n"+
+			"- #4: +1/-1 lines; removed \"char buf[16];\"; added \"char buf[64];\"\n"+
+			"- #1: initial seed\n")
+	})
+
+	t.Run("capped by depth and length", func(t *testing.T) {
+		builder := NewBuilder(0, "", nil)
+		builder.LineageDepth = 1
+		prompt, err := builder.BuildMutatePrompt(leaf, mutationCtx)
+		require.NoError(t, err)
+		assert.Contains(t, prompt, "- #4:")
+		assert.NotContains(t, prompt, "- #1:")
+
+		var ancestors []*seed.Seed
+		for id := uint64(100); id > 80; id-- {
+			ancestors = append(ancestors, &seed.Seed{Meta: seed.Metadata{ID: id, ParentID: id - 1, MutationNote: "tweak"}})
+		}
+		builder.LineageDepth = 50
+		prompt, err = builder.BuildMutatePrompt(leaf, &MutationContext{Ancestors: ancestors})
+		require.NoError(t, err)
+		assert.Equal(t, maxLineageEntries, strings.Count(prompt, ": tweak\n")+1)
+	})
+}
+
+func TestBuilder_CompilationEnvironment(t *testing.T) {
+	const want = "## Compilation Environment\n\n" +
+		"- Compiler: gcc 12.2.0\n" +
+		"- Target: aarch64-linux-gnu\n" +
+		"- Flags: -fstack-protector-strong -O0 -march=armv8-a\n\n"
+
+	builder := NewBuilder(0, "", nil)
+	builder.Compiler = &CompilerContext{
+		Name:    "gcc",
+		Version: "12.2.0",
+		Target:  "aarch64-linux-gnu",
+		CFlags:  []string{"-fstack-protector-strong", "-O0", "-B/opt/gcc/lib", "--sysroot=/opt/sysroot", "-march=armv8-a"},
+	}
+	s := &seed.Seed{Content: "int main() { return 0; }"}
+
+	generate, err := builder.BuildGeneratePrompt(t.TempDir())
+	require.NoError(t, err)
+	mutate, err := builder.BuildMutatePrompt(s, nil)
+	require.NoError(t, err)
+	constraint, err := builder.BuildConstraintSolvingPrompt(&TargetContext{
+		TargetFunction: "expand_used_vars",
+		TargetBBID:     9,
+		TargetLines:    []int{200},
+		BaseSeedCode:   s.Content,
+	})
+	require.NoError(t, err)
+
+	for name, prompt := range map[string]string{"generate": generate, "mutate": mutate, "constraint": constraint} {
+		assert.Contains(t, prompt, want, name)
+		assert.NotContains(t, prompt, "/opt/", name)
+	}
+
+	t.Run("omitted without compiler context", func(t *testing.T) {
+		prompt, err := NewBuilder(0, "", nil).BuildMutatePrompt(s, nil)
+		require.NoError(t, err)
+		assert.NotContains(t, prompt, "Compilation Environment")
+	})
+}
+
 func TestBuilder_BuildAnalyzePrompt(t *testing.T) {
 	builder := NewBuilder(3, "", nil)
 	testCases := []seed.TestCase{
@@ -115,6 +264,24 @@ func TestBuilder_BuildAnalyzePrompt(t *testing.T) {
 		assert.Contains(t, prompt, "system context")
 	})
 
+	t.Run("should embed test cases as valid JSON", func(t *testing.T) {
+		special := &seed.Seed{
+			Content: "int main() { return 0; }",
+			TestCases: []seed.TestCase{
+				{RunningCommand: "./prog \"a b\" C:\\tmp", ExpectedResult: "line1\nline2 ✓"},
+			},
+		}
+		prompt, err := builder.BuildAnalyzePrompt(special, feedback)
+		require.NoError(t, err)
+
+		start := strings.Index(prompt, "// ||||| JSON_TESTCASES_START |||||")
+		end := strings.Index(prompt, "[/SEED]")
+		require.True(t, start >= 0 && end > start)
+		decoded, err := seed.DecodeTestCases(prompt[start+len("// ||||| JSON_TESTCASES_START |||||") : end])
+		require.NoError(t, err)
+		assert.Equal(t, special.TestCases, decoded)
+	})
+
 	t.Run("should return error if seed is nil", func(t *testing.T) {
 		_, err := builder.BuildAnalyzePrompt(nil, feedback)
 		assert.Error(t, err)
@@ -196,6 +363,292 @@ int main() {
 	})
 }
 
+func TestBuilder_MultipleFunctionPlaceholders(t *testing.T) {
+	templatePath := filepath.Join(t.TempDir(), "template.c")
+	templateContent := `#include <string.h>
+
+// FUNCTION_PLACEHOLDER: seed(int fill_size)
+
+// FUNCTION_PLACEHOLDER: helper(char *dst, const char *src)
+
+int main(int argc, char *argv[]) {
+    seed(argc);
+    return 0;
+}`
+	require.NoError(t, os.WriteFile(templatePath, []byte(templateContent), 0644))
+	builder := NewBuilder(0, templatePath, nil)
+
+	t.Run("output format lists every slot", func(t *testing.T) {
+		for _, format := range []string{builder.buildOutputFormat(), builder.getOutputFormat()} {
+			assert.Contains(t, format, "Multiple Functions Required")
+			assert.Contains(t, format, "`seed(int fill_size)`")
+			assert.Contains(t, format, "`helper(char *dst, const char *src)`")
+		}
+	})
+
+	t.Run("parses labeled functions into their slots", func(t *testing.T) {
+		response := "```c\n// FUNCTION: helper\nvoid helper(char *dst, const char *src) {\n    strcpy(dst, src);\n}\n\n// FUNCTION: seed\nvoid seed(int fill_size) {\n    char buf[16];\n    helper(buf, \"x\");\n}\n```"
+		s, err := builder.ParseLLMResponse(response)
+		require.NoError(t, err)
+		assert.NotContains(t, s.Content, "FUNCTION_PLACEHOLDER")
+		assert.Less(t, strings.Index(s.Content, "void seed("), strings.Index(s.Content, "void helper("))
+	})
+
+	t.Run("reports missing function", func(t *testing.T) {
+		_, err := builder.ParseLLMResponse("void seed(int fill_size) {\n}")
+		assert.ErrorContains(t, err, "missing function(s) helper")
+	})
+
+	t.Run("single placeholder templates keep accepting any code", func(t *testing.T) {
+		singlePath := filepath.Join(t.TempDir(), "single.c")
+		require.NoError(t, os.WriteFile(singlePath, []byte("// FUNCTION_PLACEHOLDER: seed\nint main() { return 0; }"), 0644))
+		single := NewBuilder(0, singlePath, nil)
+		assert.NotContains(t, single.buildOutputFormat(), "Multiple Functions Required")
+
+		s, err := single.ParseLLMResponse("static int g;\nvoid seed(void) { g++; }")
+		require.NoError(t, err)
+		assert.Contains(t, s.Content, "static int g;")
+	})
+}
+
+func TestBuilder_CustomTestCaseSeparator(t *testing.T) {
+	builder := NewBuilder(2, "", nil)
+	builder.TestCaseSeparator = "/* @@ TESTS @@ */"
+
+	assert.Contains(t, builder.buildOutputFormat(), "/* @@ TESTS @@ */")
+	assert.Contains(t, builder.getOutputFormat(), "/* @@ TESTS @@ */")
+	assert.NotContains(t, builder.getOutputFormat(), "JSON_TESTCASES_START")
+
+	s, err := builder.ParseLLMResponse("int main() { return 0; }\n/*@@ TESTS @@*/\n[{\"running command\": \"./prog\", \"expected result\": \"ok\"}]")
+	require.NoError(t, err)
+	assert.Equal(t, "int main() { return 0; }", s.Content)
+	assert.Len(t, s.TestCases, 1)
+}
+
+func TestBuilder_StructuredOutput(t *testing.T) {
+	legacy := NewBuilder(2, "", nil)
+	structured := NewBuilder(2, "", nil)
+	structured.StructuredOutput = true
+
+	t.Run("prompts describe the JSON contract", func(t *testing.T) {
+		for _, format := range []string{structured.buildOutputFormat(), structured.getOutputFormat()} {
+			assert.Contains(t, format, `"source"`)
+			assert.Contains(t, format, `"test_cases"`)
+			assert.NotContains(t, format, "JSON_TESTCASES_START")
+		}
+		assert.Contains(t, structured.getOutputFormat(), `"cflags"`)
+
+		prompt, err := structured.BuildGeneratePrompt(t.TempDir())
+		require.NoError(t, err)
+		assert.Contains(t, prompt, "Output ONLY the JSON object")
+		assert.Contains(t, legacy.buildOutputFormat(), "JSON_TESTCASES_START")
+	})
+
+	t.Run("parses canned structured response", func(t *testing.T) {
+		response := `{"source": "#include <stdio.h>\nint main() {\n    puts(\"a\\nb\");\n    return 0;\n}", ` +
+			`"test_cases": [{"running command": "./prog", "expected result": "a"}], "cflags": ["-O2"]}`
+
+		s, err := structured.ParseLLMResponse(response)
+		require.NoError(t, err)
+		assert.Equal(t, "#include <stdio.h>\nint main() {\n    puts(\"a\\nb\");\n    return 0;\n}", s.Content)
+		require.Len(t, s.TestCases, 1)
+		assert.Equal(t, "./prog", s.TestCases[0].RunningCommand)
+		assert.Equal(t, []string{"-O2"}, s.CFlags)
+
+		// The legacy parser does not understand the JSON object.
+		_, err = legacy.ParseLLMResponse(response)
+		assert.Error(t, err)
+	})
+
+	t.Run("parses canned legacy response", func(t *testing.T) {
+		response := "int main() { return 0; }\n// ||||| JSON_TESTCASES_START |||||\n[{\"running command\": \"./prog\", \"expected result\": \"ok\"}]"
+
+		s, err := legacy.ParseLLMResponse(response)
+		require.NoError(t, err)
+		assert.Equal(t, "int main() { return 0; }", s.Content)
+
+		_, err = structured.ParseLLMResponse(response)
+		assert.Error(t, err)
+	})
+
+	t.Run("merges function template source", func(t *testing.T) {
+		templatePath := filepath.Join(t.TempDir(), "template.c")
+		require.NoError(t, os.WriteFile(templatePath, []byte("// FUNCTION_PLACEHOLDER: seed\nint main() { seed(); return 0; }"), 0644))
+		builder := NewBuilder(0, templatePath, nil)
+		builder.StructuredOutput = true
+
+		s, err := builder.ParseLLMResponse(`{"source": "void seed(void) {\n}"}`)
+		require.NoError(t, err)
+		assert.Contains(t, s.Content, "void seed(void) {\n}")
+		assert.NotContains(t, s.Content, "FUNCTION_PLACEHOLDER")
+		assert.Empty(t, s.TestCases)
+	})
+}
+
+func TestBuilder_ResponseSchema(t *testing.T) {
+	b := NewBuilder(2, "", nil)
+	assert.Nil(t, b.ResponseSchema(), "no schema without structured output")
+
+	b.StructuredOutput = true
+	var schema struct {
+		Type       string                     `json:"type"`
+		Properties map[string]json.RawMessage `json:"properties"`
+		Required   []string                   `json:"required"`
+	}
+	require.NoError(t, json.Unmarshal(b.ResponseSchema(), &schema))
+	assert.Equal(t, "object", schema.Type)
+	assert.ElementsMatch(t, []string{"source", "cflags", "test_cases"}, schema.Required)
+	assert.Len(t, schema.Properties, len(schema.Required), "a strict schema requires every property")
+
+	assert.Contains(t, string(schema.Properties["test_cases"]), `"stdin"`)
+	assert.Contains(t, string(schema.Properties["test_cases"]), `"timeout seconds"`)
+
+	// A canned reply that follows the schema parses.
+	s, err := b.ParseLLMResponse(`{"source": "int main() { return 0; }", "cflags": null, ` +
+		`"test_cases": [{"running command": "./prog", "expected result": "ok", "stdin": "3 4\n", "timeout seconds": null}]}`)
+	require.NoError(t, err)
+	assert.Empty(t, s.CFlags)
+	require.Len(t, s.TestCases, 1)
+	assert.Equal(t, "3 4\n", s.TestCases[0].Stdin)
+
+	b.MaxTestCases = 0
+	schema.Properties = nil
+	require.NoError(t, json.Unmarshal(b.ResponseSchema(), &schema))
+	assert.NotContains(t, schema.Properties, "test_cases")
+
+	b.CandidatesPerCall = 3
+	assert.Nil(t, b.ResponseSchema(), "candidate wrappers do not match the seed schema")
+}
+func TestBuilder_RequestTags(t *testing.T) {
+	legacy := NewBuilder(2, "", nil)
+	structured := NewBuilder(2, "", nil)
+	structured.StructuredOutput = true
+
+	assert.NotContains(t, legacy.buildOutputFormat(), seed.TagsMarker)
+	assert.NotContains(t, structured.buildOutputFormat(), `"tags"`)
+	legacy.RequestTags = true
+	structured.RequestTags = true
+	assert.Contains(t, legacy.buildOutputFormat(), seed.TagsMarker)
+	assert.Contains(t, structured.buildOutputFormat(), `"tags"`)
+	assert.Contains(t, string(structured.ResponseSchema()), `"tags"`)
+
+	t.Run("parses the TAGS line", func(t *testing.T) {
+		response := "// TAGS: [\"VLA\", \"fmt string\"]\nint main() { return 0; }\n// ||||| JSON_TESTCASES_START |||||\n[{\"running command\": \"./prog\", \"expected result\": \"ok\"}]"
+
+		s, err := legacy.ParseLLMResponse(response)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"vla", "fmt-string"}, s.Meta.Tags)
+		assert.Equal(t, "int main() { return 0; }", s.Content)
+	})
+
+	t.Run("parses the tags field", func(t *testing.T) {
+		s, err := structured.ParseLLMResponse(`{"source": "int main() { return 0; }", "cflags": null, "tags": ["alloca"], ` +
+			`"test_cases": [{"running command": "./prog", "expected result": "ok"}]}`)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"alloca"}, s.Meta.Tags)
+	})
+
+	t.Run("tags stay optional", func(t *testing.T) {
+		s, err := legacy.ParseLLMResponse("int main() { return 0; }\n// ||||| JSON_TESTCASES_START |||||\n[{\"running command\": \"./prog\", \"expected result\": \"ok\"}]")
+		require.NoError(t, err)
+		assert.Nil(t, s.Meta.Tags)
+	})
+}
+
+func TestBuilder_EnforceTestCases(t *testing.T) {
+	var cases []string
+	for i := 0; i < 5; i++ {
+		cases = append(cases, fmt.Sprintf(`{"running command": "./prog %d", "expected result": "ok"}`, i))
+	}
+	response := "int main() { return 0; }\n// ||||| JSON_TESTCASES_START |||||\n[" + strings.Join(cases, ",") + "]"
+
+	t.Run("truncates to MaxTestCases by default", func(t *testing.T) {
+		s, err := NewBuilder(2, "", nil).ParseLLMResponse(response)
+		require.NoError(t, err)
+		require.Len(t, s.TestCases, 2)
+		assert.Equal(t, "./prog 1", s.TestCases[1].RunningCommand)
+	})
+
+	t.Run("rejects extra test cases in strict mode", func(t *testing.T) {
+		builder := NewBuilder(2, "", nil)
+		builder.StrictTestCases = true
+		_, err := builder.ParseLLMResponse(response)
+		assert.ErrorContains(t, err, "5 test cases, at most 2 allowed")
+
+		builder.MaxTestCases = 5
+		_, err = builder.ParseLLMResponse(response)
+		assert.NoError(t, err)
+	})
+
+	t.Run("rejects unrunnable commands", func(t *testing.T) {
+		builder := NewBuilder(3, "", nil)
+		for _, command := range []string{"gcc prog.c", "./prog | grep x", "./prog; rm -rf /tmp/x"} {
+			resp := "int main() { return 0; }\n// ||||| JSON_TESTCASES_START |||||\n" +
+				fmt.Sprintf(`[{"running command": %q, "expected result": "ok"}]`, command)
+			_, err := builder.ParseLLMResponse(resp)
+			assert.Error(t, err, command)
+		}
+
+		builder.AllowShellTestCommands = true
+		_, err := builder.ParseLLMResponse("int main() { return 0; }\n// ||||| JSON_TESTCASES_START |||||\n" +
+			`[{"running command": "./prog | grep x", "expected result": "ok"}]`)
+		assert.NoError(t, err)
+	})
+}
+
+func TestBuilder_SeedLanguages(t *testing.T) {
+	ctx := &TargetContext{
+		TargetFunction: "expand_used_vars",
+		TargetBBID:     3,
+		TargetLines:    []int{10},
+		BaseSeedCode:   "fn main() {}",
+	}
+	tests := []struct {
+		lang       seed.Language
+		name       string
+		fence      string
+		rule       string
+		notContain string
+	}{
+		{seed.LanguageC, "C", "```c\n", "C99/C11", "Rust"},
+		{seed.LanguageCPP, "C++", "```cpp\nfn main", "C++17", "C99/C11"},
+		{seed.LanguageRust, "Rust", "```rust\n", "2021 edition", "C99/C11"},
+	}
+
+	prompts := map[seed.Language]string{}
+	for _, tt := range tests {
+		t.Run(string(tt.lang), func(t *testing.T) {
+			builder := NewBuilder(0, "", nil)
+			builder.Language = tt.lang
+
+			generate, err := builder.BuildGeneratePrompt(t.TempDir())
+			require.NoError(t, err)
+			assert.Contains(t, generate, "Generate "+tt.name+" code for compiler fuzzing")
+			assert.Contains(t, generate, tt.rule)
+
+			constraint, err := builder.BuildConstraintSolvingPrompt(ctx)
+			require.NoError(t, err)
+			assert.Contains(t, constraint, "MODIFY an existing "+tt.name+" program")
+			assert.Contains(t, constraint, tt.fence)
+			assert.Contains(t, constraint, "Output complete "+tt.name+" source code")
+			assert.NotContains(t, constraint, tt.notContain)
+			prompts[tt.lang] = constraint
+
+			refined, err := builder.BuildRefinedPrompt(ctx, &DivergenceInfo{MutatedSeedCode: "fn main() { }"})
+			require.NoError(t, err)
+			assert.Contains(t, refined, tt.rule)
+
+			parsed, err := builder.ParseLLMResponse("```" + tt.lang.FenceTag() + "\nfn main() {}\n```")
+			require.NoError(t, err)
+			assert.Equal(t, "fn main() {}", parsed.Content)
+			assert.Equal(t, tt.lang, parsed.Language)
+		})
+	}
+
+	assert.NotEqual(t, prompts[seed.LanguageC], prompts[seed.LanguageCPP])
+	assert.NotEqual(t, prompts[seed.LanguageCPP], prompts[seed.LanguageRust])
+}
+
 func TestBuilder_IsFunctionTemplateMode(t *testing.T) {
 	t.Run("returns true when template is set", func(t *testing.T) {
 		builder := NewBuilder(0, "/path/to/template.c", nil)
@@ -313,3 +766,77 @@ func TestBuilder_BuildDivergenceRefinedPrompt(t *testing.T) {
 		assert.Contains(t, prompt, "function")
 	})
 }
+
+func TestBuilder_SystemPromptOverrides(t *testing.T) {
+	builder := NewBuilder(0, "", nil)
+	builder.Language = seed.LanguageRust
+	ctx := &TargetContext{TargetFunction: "expand_used_vars", TargetLines: []int{200, 201}}
+
+	refine := builder.RefinementSystemPrompt(ctx)
+	assert.Contains(t, refine, "Rust")
+	assert.Contains(t, refine, "lines [200 201] of expand_used_vars")
+	assert.NotContains(t, refine, structuredSystemNote)
+
+	builder.StructuredOutput = true
+	repair := builder.CompileRepairSystemPrompt()
+	assert.Contains(t, repair, "repairing a failed compiler-fuzzing mutation")
+	assert.Contains(t, repair, structuredSystemNote)
+}
+
+func TestBuilder_GeneratePromptDiversity(t *testing.T) {
+	builder := NewBuilder(0, "", nil)
+
+	prompt, err := builder.BuildGeneratePrompt(t.TempDir())
+	require.NoError(t, err)
+	assert.NotContains(t, prompt, "Corpus Diversity", "no section without a stats source")
+
+	builder.ConstructStats = func() corpus.ConstructStats {
+		return corpus.ProfileConstructs([]*seed.Seed{
+			{Content: "void f(char *s) { char b[8]; strcpy(b, s); }"},
+			{Content: "void g(char *s) { char b[4]; strcat(b, s); char *p = malloc(4); }"},
+			{Content: "void h(char *s) { char b[2]; sprintf(b, \"%s\", s); char *p = malloc(2); }"},
+		})
+	}
+	prompt, err = builder.BuildGeneratePrompt(t.TempDir())
+	require.NoError(t, err)
+	assert.Contains(t, prompt, "## Corpus Diversity")
+	assert.Contains(t, prompt, "The corpus (3 seeds) already has many: unbounded string copies (strcpy/strcat/sprintf/gets) (3), heap allocation (2).")
+	assert.Contains(t, prompt, "Prefer exploring: memcpy/memmove/memset (0), variable-length arrays (0), alloca (0).")
+
+	builder.ConstructStats = func() corpus.ConstructStats { return corpus.ProfileConstructs(nil) }
+	prompt, err = builder.BuildGeneratePrompt(t.TempDir())
+	require.NoError(t, err)
+	assert.NotContains(t, prompt, "Corpus Diversity", "no section for an empty corpus")
+}
+
+func TestPromptService_RenderOnly(t *testing.T) {
+	baseDir := t.TempDir()
+	for _, name := range []string{"constraint.md", "mutate.md"} {
+		require.NoError(t, os.WriteFile(filepath.Join(baseDir, name), []byte("base "+name), 0644))
+	}
+	service, err := NewPromptService(baseDir, "", NewBuilder(0, "", nil))
+	require.NoError(t, err)
+
+	ctx := &TargetContext{TargetFunction: "expand_used_vars", TargetBBID: 9, TargetLines: []int{200}}
+	baseSeed := &seed.Seed{Content: "int main() { return 0; }"}
+
+	rendered, err := service.RenderOnly(ctx, baseSeed, nil)
+	require.NoError(t, err)
+	require.Len(t, rendered.All(), 2)
+
+	system, user, err := service.GetConstraintPrompt(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, PhaseConstraint, rendered.Constraint.Phase)
+	assert.Equal(t, system, rendered.Constraint.System)
+	assert.Equal(t, user, rendered.Constraint.User)
+
+	system, user, err = service.GetMutatePrompt(baseSeed, nil)
+	require.NoError(t, err)
+	assert.Equal(t, system, rendered.Mutate.System)
+	assert.Equal(t, user, rendered.Mutate.User)
+
+	rendered, err = service.RenderOnly(ctx, nil, nil)
+	require.NoError(t, err)
+	assert.Nil(t, rendered.Mutate)
+	assert.Len(t, rendered.All(), 1)
+}
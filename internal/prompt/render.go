@@ -0,0 +1,55 @@
+package prompt
+
+import "github.com/zjy-dev/de-fuzz/internal/seed"
+
+// RenderedPrompt is a system/user prompt pair exactly as it would be sent.
+type RenderedPrompt struct {
+	Phase  Phase
+	System string
+	User   string
+}
+
+// RenderedPrompts holds the prompts RenderOnly produced. A field is nil when
+// its inputs were not given.
+type RenderedPrompts struct {
+	Constraint *RenderedPrompt // Opening constraint-solving prompt for a target
+	Mutate     *RenderedPrompt // Mutation prompt for a base seed
+}
+
+// All returns the rendered prompts in a fixed order, skipping nil ones.
+func (r *RenderedPrompts) All() []*RenderedPrompt {
+	var all []*RenderedPrompt
+	for _, p := range []*RenderedPrompt{r.Constraint, r.Mutate} {
+		if p != nil {
+			all = append(all, p)
+		}
+	}
+	return all
+}
+
+// RenderOnly builds the prompts the given inputs would produce without
+// calling an LLM. It goes through the same Get*Prompt methods as real
+// requests, so the output is what would be sent. ctx selects the
+// constraint-solving prompt and baseSeed (with an optional mutationCtx) the
+// mutation prompt; either may be nil.
+func (s *PromptService) RenderOnly(ctx *TargetContext, baseSeed *seed.Seed, mutationCtx *MutationContext) (*RenderedPrompts, error) {
+	rendered := &RenderedPrompts{}
+
+	if ctx != nil {
+		system, user, err := s.GetConstraintPrompt(ctx)
+		if err != nil {
+			return nil, err
+		}
+		rendered.Constraint = &RenderedPrompt{Phase: PhaseConstraint, System: system, User: user}
+	}
+
+	if baseSeed != nil {
+		system, user, err := s.GetMutatePrompt(baseSeed, mutationCtx)
+		if err != nil {
+			return nil, err
+		}
+		rendered.Mutate = &RenderedPrompt{Phase: PhaseMutate, System: system, User: user}
+	}
+
+	return rendered, nil
+}
@@ -1,6 +1,7 @@
 package prompt
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -23,6 +24,9 @@ type PromptService struct {
 	baseDir       string // Directory containing base prompts (e.g., "prompts/base")
 	understanding string // Content of understanding.md (background context)
 	builder       *Builder
+
+	contextLimit int    // Prompt tokens the model accepts, 0 = unknown
+	summary      string // Compressed understanding used instead, when set
 }
 
 // NewPromptService creates a new PromptService
@@ -73,8 +77,13 @@ func (s *PromptService) GetSystemPrompt(phase Phase) (string, error) {
 	// Assemble: base + understanding
 	result := string(baseContent)
 
-	if s.understanding != "" {
-		result += "\n\n" + s.understanding
+	if understanding := s.activeUnderstanding(); understanding != "" {
+		result += "\n\n" + understanding
+	}
+
+	// The base prompts ask for raw code; point the model at the JSON contract.
+	if s.builder.StructuredOutput {
+		result += "\n\n" + structuredSystemNote
 	}
 
 	return result, nil
@@ -87,6 +96,7 @@ func (s *PromptService) GetConstraintPrompt(ctx *TargetContext) (string, string,
 		return "", "", err
 	}
 
+	defer s.capBuilderBudget(systemPrompt)()
 	userPrompt, err := s.builder.BuildConstraintSolvingPrompt(ctx)
 	if err != nil {
 		return "", "", err
@@ -95,13 +105,19 @@ func (s *PromptService) GetConstraintPrompt(ctx *TargetContext) (string, string,
 	return systemPrompt, userPrompt, nil
 }
 
-// GetRefinedPrompt returns (system, user) prompts for divergence-based retry
+// GetRefinedPrompt returns (system, user) prompts for divergence-based retry.
+// div.SystemPromptOverride, when set, replaces the system prompt.
 func (s *PromptService) GetRefinedPrompt(ctx *TargetContext, div *DivergenceInfo) (string, string, error) {
-	systemPrompt, err := s.GetSystemPrompt(PhaseConstraint)
+	var override string
+	if div != nil {
+		override = div.SystemPromptOverride
+	}
+	systemPrompt, err := s.systemPromptFor(PhaseConstraint, override)
 	if err != nil {
 		return "", "", err
 	}
 
+	defer s.capBuilderBudget(systemPrompt)()
 	userPrompt, err := s.builder.BuildRefinedPrompt(ctx, div)
 	if err != nil {
 		return "", "", err
@@ -110,9 +126,21 @@ func (s *PromptService) GetRefinedPrompt(ctx *TargetContext, div *DivergenceInfo
 	return systemPrompt, userPrompt, nil
 }
 
-// GetCompileErrorPrompt returns (system, user) prompts for compile error retry
+// GetFollowUpPrompt returns the user message for the next turn of a
+// constraint-solving conversation. The system prompt is the one the
+// conversation started with (GetConstraintPrompt).
+func (s *PromptService) GetFollowUpPrompt(ctx *TargetContext, div *DivergenceInfo, errInfo *CompileErrorInfo) (string, error) {
+	return s.builder.BuildFollowUpPrompt(ctx, div, errInfo)
+}
+
+// GetCompileErrorPrompt returns (system, user) prompts for compile error retry.
+// errInfo.SystemPromptOverride, when set, replaces the system prompt.
 func (s *PromptService) GetCompileErrorPrompt(ctx *TargetContext, errInfo *CompileErrorInfo) (string, string, error) {
-	systemPrompt, err := s.GetSystemPrompt(PhaseCompileError)
+	var override string
+	if errInfo != nil {
+		override = errInfo.SystemPromptOverride
+	}
+	systemPrompt, err := s.systemPromptFor(PhaseCompileError, override)
 	if err != nil {
 		return "", "", err
 	}
@@ -125,14 +153,31 @@ func (s *PromptService) GetCompileErrorPrompt(ctx *TargetContext, errInfo *Compi
 	return systemPrompt, userPrompt, nil
 }
 
-// GetMutatePrompt returns (system, user) prompts for mutation
-func (s *PromptService) GetMutatePrompt(basePath string, mutationCtx *MutationContext) (string, string, error) {
+// GetCompileFixPrompt returns (system, user) prompts for repairing a seed
+// that failed to compile. The system prompt is the builder's repair message
+// unless overrides are disabled.
+func (s *PromptService) GetCompileFixPrompt(failed *seed.Seed, compilerStderr string) (string, string, error) {
+	systemPrompt, err := s.systemPromptFor(PhaseCompileError, s.builder.CompileRepairSystemPrompt())
+	if err != nil {
+		return "", "", err
+	}
+
+	userPrompt, err := s.builder.BuildCompileFixPrompt(failed, compilerStderr)
+	if err != nil {
+		return "", "", err
+	}
+
+	return systemPrompt, userPrompt, nil
+}
+
+// GetMutatePrompt returns (system, user) prompts for mutating baseSeed
+func (s *PromptService) GetMutatePrompt(baseSeed *seed.Seed, mutationCtx *MutationContext) (string, string, error) {
 	systemPrompt, err := s.GetSystemPrompt(PhaseMutate)
 	if err != nil {
 		return "", "", err
 	}
 
-	userPrompt, err := s.builder.BuildMutatePrompt(nil, mutationCtx)
+	userPrompt, err := s.builder.BuildMutatePrompt(baseSeed, mutationCtx)
 	if err != nil {
 		return "", "", err
 	}
@@ -155,8 +200,78 @@ func (s *PromptService) GetGeneratePrompt(basePath string) (string, string, erro
 	return systemPrompt, userPrompt, nil
 }
 
+// RefinementSystemPrompt returns the builder's terse divergence-retry system
+// message, for DivergenceInfo.SystemPromptOverride.
+func (s *PromptService) RefinementSystemPrompt(ctx *TargetContext) string {
+	return s.builder.RefinementSystemPrompt(ctx)
+}
+
+// CompileRepairSystemPrompt returns the builder's compile-repair system
+// message, for CompileErrorInfo.SystemPromptOverride.
+func (s *PromptService) CompileRepairSystemPrompt() string {
+	return s.builder.CompileRepairSystemPrompt()
+}
+
+// EstimateTokens returns the builder's token estimate for a prompt.
+func (s *PromptService) EstimateTokens(text string) int {
+	return s.builder.EstimateTokens(text)
+}
+
+// StructuredOutput reports whether responses use the JSON object contract,
+// so callers can enable the provider's JSON response mode.
+func (s *PromptService) StructuredOutput() bool {
+	return s.builder.StructuredOutput
+}
+
+// ResponseSchema returns the builder's structured-output JSON Schema; see
+// Builder.ResponseSchema.
+func (s *PromptService) ResponseSchema() json.RawMessage {
+	return s.builder.ResponseSchema()
+}
+
+// DisableStructuredOutput switches later prompts to the delimiter-based
+// output contract, for providers that cannot enforce the JSON one.
+func (s *PromptService) DisableStructuredOutput() {
+	s.builder.StructuredOutput = false
+}
+
+// NewStreamWatcher returns a watcher that stops a streamed seed response
+// once it is complete or longer than about maxTokens tokens (0 = no cap).
+func (s *PromptService) NewStreamWatcher(maxTokens int) *StreamWatcher {
+	return s.builder.NewStreamWatcher(maxTokens)
+}
+
 // ParseLLMResponse parses LLM response into a seed
 // This is a convenience wrapper around builder.ParseLLMResponse
 func (s *PromptService) ParseLLMResponse(response string) (*seed.Seed, error) {
 	return s.builder.ParseLLMResponse(response)
 }
+
+// CandidatesPerCall returns how many candidate seeds one constraint-solving
+// response may hold (at least 1).
+func (s *PromptService) CandidatesPerCall() int {
+	return s.builder.candidatesPerCall()
+}
+
+// ParseMultiCandidateResponse parses a constraint-solving response into its
+// candidate seeds. This is a convenience wrapper around
+// builder.ParseMultiCandidateResponse
+func (s *PromptService) ParseMultiCandidateResponse(response string) ([]*seed.Seed, error) {
+	return s.builder.ParseMultiCandidateResponse(response)
+}
+
+// TemplateFunctions returns the names of the functions the function
+// template leaves to the LLM, or nil outside function template mode.
+func (s *PromptService) TemplateFunctions() []string {
+	var names []string
+	for _, slot := range s.builder.functionSlots() {
+		names = append(names, slot.Name)
+	}
+	return names
+}
+
+// LineageDepth returns how many ancestors the mutate prompt summarizes, so
+// callers know how far up the corpus to walk (0 = none).
+func (s *PromptService) LineageDepth() int {
+	return s.builder.LineageDepth
+}
@@ -0,0 +1,94 @@
+package prompt
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// streamTrailingChars is how much text may follow the closed code block of
+// a response without test cases before the rest is taken to be commentary.
+const streamTrailingChars = 200
+
+// StreamWatcher reads a streamed seed response and says when to stop: once
+// everything the output format asks for has arrived, or once the response
+// runs past a token cap.
+type StreamWatcher struct {
+	b         *Builder
+	maxTokens int
+	text      strings.Builder
+	reason    string
+}
+
+// NewStreamWatcher returns a watcher for one response. maxTokens stops
+// responses longer than about that many tokens (0 = no cap).
+func (b *Builder) NewStreamWatcher(maxTokens int) *StreamWatcher {
+	return &StreamWatcher{b: b, maxTokens: maxTokens}
+}
+
+// Feed adds the next chunk of the response and reports whether to keep
+// reading.
+func (w *StreamWatcher) Feed(chunk string) bool {
+	if w.reason != "" {
+		return false
+	}
+	w.text.WriteString(chunk)
+	text := w.text.String()
+	switch {
+	case w.maxTokens > 0 && w.b.EstimateTokens(text) > w.maxTokens:
+		w.reason = fmt.Sprintf("response ran past %d tokens", w.maxTokens)
+	case w.b.responseComplete(text):
+		w.reason = "response complete"
+	default:
+		return true
+	}
+	return false
+}
+
+// StopReason says why Feed stopped the stream, or "" if it did not.
+func (w *StreamWatcher) StopReason() string {
+	return w.reason
+}
+
+// responseComplete reports whether text already holds a whole response in
+// the configured output format. Multi-candidate responses are never cut
+// short since the model decides how many candidates to give.
+func (b *Builder) responseComplete(text string) bool {
+	if b.candidatesPerCall() > 1 {
+		return false
+	}
+	if b.StructuredOutput {
+		var obj json.RawMessage
+		return json.NewDecoder(strings.NewReader(seed.ExtractJSONObject(text))).Decode(&obj) == nil
+	}
+	if b.MaxTestCases > 0 {
+		_, testCasesJSON, ok := seed.SplitTestCases(text, b.testCaseSeparator())
+		if !ok {
+			return false
+		}
+		start := strings.IndexByte(testCasesJSON, '[')
+		if start < 0 {
+			return false
+		}
+		var testCases []json.RawMessage
+		return json.NewDecoder(strings.NewReader(testCasesJSON[start:])).Decode(&testCases) == nil
+	}
+
+	// Code only: the code block is closed and either the optional CFLAGS
+	// section has ended or enough other text has followed.
+	open := strings.Index(text, "```")
+	if open < 0 {
+		return false
+	}
+	closing := strings.Index(text[open+3:], "```")
+	if closing < 0 {
+		return false
+	}
+	tail := text[open+3+closing+3:]
+	if start := strings.Index(tail, seed.CFlagsStartMarker); start >= 0 {
+		return strings.Contains(tail[start:], seed.CFlagsEndMarker)
+	}
+	return len(strings.TrimSpace(tail)) >= streamTrailingChars
+}
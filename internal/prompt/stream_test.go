@@ -0,0 +1,104 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// feedAll streams response to w in small chunks and returns the text read
+// before the watcher stopped.
+func feedAll(w *StreamWatcher, response string) string {
+	var read strings.Builder
+	for len(response) > 0 {
+		n := min(7, len(response))
+		read.WriteString(response[:n])
+		if !w.Feed(response[:n]) {
+			break
+		}
+		response = response[n:]
+	}
+	return read.String()
+}
+
+func TestStreamWatcher(t *testing.T) {
+	code := "```c\nint main(void) { return 0; }\n```\n"
+	essay := strings.Repeat("This program returns zero because it does nothing. ", 20)
+	testCases := seed.TestCaseSeparator + "\n[{\"running command\": \"./prog\", \"expected result\": \"0\"}]\n"
+
+	tests := []struct {
+		name      string
+		setup     func(b *Builder)
+		maxTokens int
+		response  string
+		wantStop  bool
+		wantRead  string // prefix the watcher must have read
+	}{
+		{
+			name:     "code only stops on trailing commentary",
+			response: code + essay,
+			wantStop: true,
+			wantRead: code,
+		},
+		{
+			name:     "code only waits for the cflags section",
+			response: code + seed.CFlagsStartMarker + "\n-O2\n" + seed.CFlagsEndMarker + "\n" + essay,
+			wantStop: true,
+			wantRead: code + seed.CFlagsStartMarker + "\n-O2\n" + seed.CFlagsEndMarker,
+		},
+		{
+			name:     "short code-only reply is read to the end",
+			response: code + "Done.",
+		},
+		{
+			name:     "test cases stop after the JSON array",
+			setup:    func(b *Builder) { b.MaxTestCases = 1 },
+			response: code + testCases + essay,
+			wantStop: true,
+			wantRead: code + testCases,
+		},
+		{
+			name:     "structured stops after the object",
+			setup:    func(b *Builder) { b.StructuredOutput = true },
+			response: `{"source": "int main(void) { return 0; }"}` + "\n" + essay,
+			wantStop: true,
+			wantRead: `{"source": "int main(void) { return 0; }"}`,
+		},
+		{
+			name:      "token cap",
+			maxTokens: 50,
+			response:  essay,
+			wantStop:  true,
+		},
+		{
+			name:     "multiple candidates are never cut short",
+			setup:    func(b *Builder) { b.CandidatesPerCall = 2 },
+			response: code + essay,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBuilder(0, "", nil)
+			if tt.setup != nil {
+				tt.setup(b)
+			}
+			w := b.NewStreamWatcher(tt.maxTokens)
+			read := feedAll(w, tt.response)
+
+			if stopped := w.StopReason() != ""; stopped != tt.wantStop {
+				t.Fatalf("stopped = %v (%q), want %v", stopped, w.StopReason(), tt.wantStop)
+			}
+			if !tt.wantStop && read != tt.response {
+				t.Errorf("read %d of %d bytes without stopping", len(read), len(tt.response))
+			}
+			if tt.wantStop && len(read) == len(tt.response) {
+				t.Errorf("watcher read the whole response")
+			}
+			if !strings.HasPrefix(read, tt.wantRead) {
+				t.Errorf("stopped before %q arrived; read %q", tt.wantRead, read)
+			}
+		})
+	}
+}
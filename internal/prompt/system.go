@@ -0,0 +1,52 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// structuredSystemNote is appended to system prompts when responses use the
+// JSON object contract, since the base prompts ask for raw code.
+const structuredSystemNote = "Respond with the single JSON object described in the user message instead of raw code."
+
+// RefinementSystemPrompt returns a terse system message for divergence
+// retries. Unlike the phase prompt it leaves out the understanding text, so
+// the model stays on the one target instead of drifting back to the task
+// overview.
+func (b *Builder) RefinementSystemPrompt(ctx *TargetContext) string {
+	target := "its target basic block"
+	if ctx != nil && ctx.TargetFunction != "" {
+		target = fmt.Sprintf("lines %v of %s", ctx.TargetLines, ctx.TargetFunction)
+	}
+	return b.withStructuredNote(fmt.Sprintf(
+		"You are refining a %s compiler-fuzzing seed that compiled but did not reach %s. "+
+			"Make small, targeted changes to the program so compilation takes the path to the target. "+
+			"Reply only in the output format the user message asks for.",
+		b.language().DisplayName(), target))
+}
+
+// CompileRepairSystemPrompt returns a terse system message for requests that
+// fix a seed which failed to compile.
+func (b *Builder) CompileRepairSystemPrompt() string {
+	return b.withStructuredNote(fmt.Sprintf(
+		"You are repairing a failed compiler-fuzzing mutation: a %s program that does not compile. "+
+			"Fix the reported errors with the smallest change and keep what the program is trying to exercise. "+
+			"Reply only in the output format the user message asks for.",
+		b.language().DisplayName()))
+}
+
+func (b *Builder) withStructuredNote(system string) string {
+	if b.StructuredOutput {
+		return system + "\n\n" + structuredSystemNote
+	}
+	return system
+}
+
+// systemPromptFor returns override when it is set and overrides are enabled,
+// and the phase system prompt otherwise.
+func (s *PromptService) systemPromptFor(phase Phase, override string) (string, error) {
+	if override != "" && !s.builder.DisableSystemOverrides {
+		return strings.TrimSpace(override), nil
+	}
+	return s.GetSystemPrompt(phase)
+}
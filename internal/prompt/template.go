@@ -0,0 +1,146 @@
+package prompt
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+// Template file names. A file with the same name in Builder.TemplateDir
+// overrides the embedded default; prompts without an override keep using
+// the defaults in templates/.
+const (
+	UnderstandTemplate = "understand.tmpl"
+	GenerateTemplate   = "generate.tmpl"
+	MutateTemplate     = "mutate.tmpl"
+	ConstraintTemplate = "constraint.tmpl"
+	RefinedTemplate    = "refined.tmpl"
+	FollowUpTemplate   = "followup.tmpl"
+	CompileFixTemplate = "compilefix.tmpl"
+)
+
+//go:embed templates/*.tmpl
+var defaultTemplates embed.FS
+
+// TemplateData is the data model passed to every prompt template.
+// Fields that do not apply to a given prompt are left zero/nil; for example
+// Target is only set for constraint.tmpl and refined.tmpl.
+type TemplateData struct {
+	// Builder settings
+	Language             string // Seed language display name (C, C++, Rust)
+	FenceTag             string // Markdown fence tag for seed code (c, cpp, rust)
+	LanguageRule         string // One-line language requirement
+	LanguageRules        string // Detailed language constraints (constraint.tmpl)
+	CompileFixes         string // Common compile-error fixes (followup.tmpl)
+	MaxTestCases         int    // Maximum test cases per seed (0 = none)
+	FunctionTemplateMode bool   // LLM generates only the seed() function
+	FunctionTemplateCode string // Content of the function template file (generate.tmpl)
+	StructuredOutput     bool   // Responses are a single JSON object, not fenced code
+	Candidates           int    // Seeds requested per response (constraint.tmpl; 1 = single)
+
+	// Target identification (understand.tmpl)
+	ISA      string
+	Strategy string
+
+	// Prompt inputs
+	Target       *TargetContext    // constraint.tmpl, refined.tmpl, followup.tmpl
+	Divergence   *DivergenceInfo   // refined.tmpl, followup.tmpl
+	CompileError *CompileErrorInfo // followup.tmpl, compilefix.tmpl
+	Mutation     *MutationContext  // mutate.tmpl (may be nil)
+	Seed         *seed.Seed        // mutate.tmpl, compilefix.tmpl
+
+	// Earlier failed attempts for the target, already limited by the builder (refined.tmpl)
+	PriorAttempts        []FailedAttempt
+	OmittedPriorAttempts int
+
+	// TestCasesJSON is Seed.TestCases encoded with seed.EncodeTestCases (mutate.tmpl)
+	TestCasesJSON string
+
+	// Auxiliary context files read from the strategy base directory, in
+	// configured order (understand.tmpl, generate.tmpl)
+	AuxContext []AuxContext
+
+	// Pre-rendered sections shared across prompts
+	OutputFormat     string // Output format instructions for the current mode
+	CompilerProfile  string // Active compiler profile section (may be empty)
+	CompilationEnv   string // Compiler identity and baseline flags (may be empty)
+	Lineage          string // Mutated seed's lineage summary (mutate.tmpl, may be empty)
+	Diversity        string // Common and rare corpus constructs (generate.tmpl, may be empty)
+	MechanismRules   string // Mechanism-specific critical rules addendum (may be empty)
+	MechanismExample string // Mechanism-specific output example (may be empty)
+}
+
+// templateFuncs are the helper functions available inside prompt templates.
+var templateFuncs = template.FuncMap{
+	"base":  filepath.Base,
+	"join":  strings.Join,
+	"trim":  strings.TrimSpace,
+	"upper": strings.ToUpper,
+}
+
+// newTemplateData fills in the Builder settings shared by all prompts.
+func (b *Builder) newTemplateData() *TemplateData {
+	guide := b.languageGuide()
+	data := &TemplateData{
+		Language:             b.language().DisplayName(),
+		FenceTag:             b.language().FenceTag(),
+		LanguageRule:         guide.Rule,
+		LanguageRules:        guide.Rules,
+		CompileFixes:         guide.CompileFixes,
+		MaxTestCases:         b.MaxTestCases,
+		FunctionTemplateMode: b.FunctionTemplate != "",
+		StructuredOutput:     b.StructuredOutput,
+		CompilationEnv:       b.compilationEnvSection(),
+	}
+	if b.Mechanism != nil && b.FunctionTemplate != "" {
+		data.MechanismRules = b.Mechanism.CriticalRulesAddendum()
+		// Mechanism examples show fenced code, which contradicts the JSON contract.
+		if !b.StructuredOutput {
+			data.MechanismExample = b.Mechanism.FuzzTimePromptExample()
+		}
+	}
+	return data
+}
+
+// loadTemplate returns the template text for name, preferring TemplateDir.
+func (b *Builder) loadTemplate(name string) (string, error) {
+	if b.TemplateDir != "" {
+		content, err := os.ReadFile(filepath.Join(b.TemplateDir, name))
+		if err == nil {
+			return string(content), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to read prompt template %s: %w", name, err)
+		}
+	}
+
+	content, err := defaultTemplates.ReadFile("templates/" + name)
+	if err != nil {
+		return "", fmt.Errorf("no default prompt template %s: %w", name, err)
+	}
+	return string(content), nil
+}
+
+// renderTemplate executes the named prompt template with data.
+func (b *Builder) renderTemplate(name string, data *TemplateData) (string, error) {
+	text, err := b.loadTemplate(name)
+	if err != nil {
+		return "", err
+	}
+
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse prompt template %s: %w", name, err)
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt template %s: %w", name, err)
+	}
+	return sb.String(), nil
+}
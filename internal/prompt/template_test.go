@@ -0,0 +1,90 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+func TestBuilder_TemplateDir_OverridesOnlyProvidedTemplates(t *testing.T) {
+	templateDir := t.TempDir()
+	override := "CUSTOM CONSTRAINT for {{.Target.TargetFunction}} BB{{.Target.TargetBBID}} lines {{.Target.TargetLines}}\n{{.OutputFormat}}"
+	if err := os.WriteFile(filepath.Join(templateDir, ConstraintTemplate), []byte(override), 0644); err != nil {
+		t.Fatalf("Failed to write template override: %v", err)
+	}
+
+	builder := NewBuilder(0, "", nil)
+	builder.TemplateDir = templateDir
+
+	ctx := &TargetContext{
+		TargetFunction: "expand_used_vars",
+		TargetBBID:     7,
+		TargetLines:    []int{2460, 2461},
+		SuccessorCount: 2,
+		BaseSeedCode:   "int main() { return 0; }",
+	}
+
+	constraintPrompt, err := builder.BuildConstraintSolvingPrompt(ctx)
+	if err != nil {
+		t.Fatalf("BuildConstraintSolvingPrompt() failed: %v", err)
+	}
+	if !strings.HasPrefix(constraintPrompt, "CUSTOM CONSTRAINT for expand_used_vars BB7 lines [2460 2461]") {
+		t.Errorf("constraint prompt did not use override:\n%s", constraintPrompt)
+	}
+	if !strings.Contains(constraintPrompt, "## Output Format") {
+		t.Error("override should have access to pre-rendered OutputFormat")
+	}
+
+	// Refined and mutate prompts have no override and use the embedded defaults
+	refinedPrompt, err := builder.BuildRefinedPrompt(ctx, &DivergenceInfo{DivergentFunction: "gimplify_expr"})
+	if err != nil {
+		t.Fatalf("BuildRefinedPrompt() failed: %v", err)
+	}
+	if strings.Contains(refinedPrompt, "CUSTOM CONSTRAINT") || !strings.Contains(refinedPrompt, "## 2. Why Previous Attempt Failed") {
+		t.Errorf("refined prompt should use the default template:\n%s", refinedPrompt)
+	}
+
+	mutatePrompt, err := builder.BuildMutatePrompt(&seed.Seed{Content: "int main() { return 0; }"}, nil)
+	if err != nil {
+		t.Fatalf("BuildMutatePrompt() failed: %v", err)
+	}
+	if !strings.Contains(mutatePrompt, "**Existing Seed to Mutate:**") {
+		t.Errorf("mutate prompt should use the default template:\n%s", mutatePrompt)
+	}
+}
+
+func TestBuilder_TemplateDir_InvalidTemplate(t *testing.T) {
+	templateDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(templateDir, GenerateTemplate), []byte("{{.Unclosed"), 0644); err != nil {
+		t.Fatalf("Failed to write template override: %v", err)
+	}
+
+	builder := NewBuilder(0, "", nil)
+	builder.TemplateDir = templateDir
+
+	if _, err := builder.BuildGeneratePrompt(t.TempDir()); err == nil {
+		t.Error("BuildGeneratePrompt() should fail on an unparsable template")
+	}
+}
+
+func TestBuilder_BuildUnderstandPrompt(t *testing.T) {
+	basePath := t.TempDir()
+	builder := NewBuilder(0, "", nil)
+
+	prompt, err := builder.BuildUnderstandPrompt("x64", "canary", basePath)
+	if err != nil {
+		t.Fatalf("BuildUnderstandPrompt() failed: %v", err)
+	}
+	for _, want := range []string{"x64", "canary", "Not available for now", "## Goal", "## Attack Vectors", "## Seed Generation"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("understand prompt should contain %q", want)
+		}
+	}
+
+	if _, err := builder.BuildUnderstandPrompt("", "canary", basePath); err == nil {
+		t.Error("BuildUnderstandPrompt() should fail without isa")
+	}
+}
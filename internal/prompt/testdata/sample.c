@@ -0,0 +1,14 @@
+int classify(int x, int y)
+{
+  int r = 0;
+  if (x > 10)
+    {
+      if (y < 0)
+        r = 1;
+      else
+        r = 2;
+    }
+  else
+    r = 3;
+  return r;
+}
@@ -39,6 +39,9 @@ func (r *MarkdownReporter) Save(bug *oracle.Bug) error {
 	for i, result := range bug.Results {
 		content += fmt.Sprintf("### Test Case %d\n\n", i+1)
 		content += fmt.Sprintf("**Exit Code:** %d\n\n", result.ExitCode)
+		if result.StdinSupplied {
+			content += "**Stdin:** supplied\n\n"
+		}
 		content += fmt.Sprintf("**Stdout:**\n\n```\n%s\n```\n\n", result.Stdout)
 		content += fmt.Sprintf("**Stderr:**\n\n```\n%s\n```\n\n", result.Stderr)
 	}
@@ -51,6 +54,9 @@ func (r *MarkdownReporter) Save(bug *oracle.Bug) error {
 	for i, tc := range bug.Seed.TestCases {
 		content += fmt.Sprintf("**Test Case %d:**\n", i+1)
 		content += fmt.Sprintf("- Command: `%s`\n", tc.RunningCommand)
+		if tc.Stdin != "" {
+			content += fmt.Sprintf("- Stdin: `%s`\n", tc.Stdin)
+		}
 		content += fmt.Sprintf("- Expected: %s\n\n", tc.ExpectedResult)
 	}
 
@@ -0,0 +1,217 @@
+package seed
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/zjy-dev/de-fuzz/internal/exec"
+)
+
+// BundleMeta describes how a bug-triggering seed was built and run, for
+// ExportBundle.
+type BundleMeta struct {
+	CompilerPath   string   `json:"compiler_path"`
+	CFlags         []string `json:"cflags"`                    // Effective flags, without source and output paths
+	CompileCommand string   `json:"compile_command,omitempty"` // Invocation as recorded during fuzzing
+	Env            []string `json:"env,omitempty"`             // KEY=VALUE pairs for running the binary
+	QEMUPath       string   `json:"qemu_path,omitempty"`       // Empty for native execution
+	QEMUSysroot    string   `json:"qemu_sysroot,omitempty"`
+	Oracle         string   `json:"oracle,omitempty"`
+	Verdict        string   `json:"verdict"`
+	// UnderstandingVersion is the understanding history timestamp of the
+	// understanding active when the bug was found.
+	UnderstandingVersion string `json:"understanding_version,omitempty"`
+	// Lineage lists the seed's ancestors in the corpus, nearest first.
+	Lineage []BundleAncestor `json:"lineage,omitempty"`
+	// Signature is the bug's oracle.Signature; AlsoTriggeredBy lists the
+	// other seeds whose bugs had the same one.
+	Signature       string   `json:"signature,omitempty"`
+	AlsoTriggeredBy []uint64 `json:"also_triggered_by,omitempty"`
+}
+
+// BundleAncestor is an ancestor of a bundled seed, in BundleMeta.Lineage.
+type BundleAncestor struct {
+	ID           uint64 `json:"id"`
+	Depth        int    `json:"depth"`
+	CovIncrease  uint64 `json:"cov_incr,omitempty"`
+	MutationNote string `json:"mutation_note,omitempty"`
+}
+
+// bundleBinary is the name reproduce.sh gives the compiled seed.
+const bundleBinary = "prog"
+
+// ExportBundle writes a self-contained reproduction bundle for s to outPath
+// as a tar.gz: the source, test cases (and their stdin), bundle.json with
+// meta, and a reproduce.sh that recompiles the source with the recorded
+// compiler and flags and runs the test cases, under QEMU when meta names it.
+// Everything sits in a seed-<id>/ directory inside the archive.
+func ExportBundle(s *Seed, meta BundleMeta, outPath string) error {
+	if s == nil {
+		return fmt.Errorf("seed cannot be nil")
+	}
+
+	metaJSON, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal bundle meta: %w", err)
+	}
+	files := []bundleFile{
+		{name: s.Language.SourceFileName(), data: []byte(s.Content), mode: 0644},
+		{name: "bundle.json", data: metaJSON, mode: 0644},
+	}
+	if len(s.TestCases) > 0 {
+		data, err := EncodeTestCases(s.TestCases)
+		if err != nil {
+			return fmt.Errorf("failed to marshal test cases: %w", err)
+		}
+		files = append(files, bundleFile{name: "testcases.json", data: []byte(data), mode: 0644})
+	}
+	for i, tc := range s.TestCases {
+		if tc.Stdin != "" {
+			files = append(files, bundleFile{name: bundleStdinFile(i), data: []byte(tc.Stdin), mode: 0644})
+		}
+	}
+	files = append(files, bundleFile{name: "reproduce.sh", data: []byte(reproduceScript(s, meta)), mode: 0755})
+
+	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+		return fmt.Errorf("failed to create bundle directory: %w", err)
+	}
+	f, err := os.Create(outPath)
+	if err != nil {
+		return fmt.Errorf("failed to create bundle %s: %w", outPath, err)
+	}
+	if err := writeBundle(f, fmt.Sprintf("seed-%d", s.Meta.ID), files); err != nil {
+		f.Close()
+		os.Remove(outPath)
+		return fmt.Errorf("failed to write bundle %s: %w", outPath, err)
+	}
+	return f.Close()
+}
+
+type bundleFile struct {
+	name string
+	data []byte
+	mode int64
+}
+
+func writeBundle(f *os.File, dir string, files []bundleFile) error {
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	for _, file := range files {
+		hdr := &tar.Header{
+			Name:    dir + "/" + file.name,
+			Mode:    file.mode,
+			Size:    int64(len(file.data)),
+			ModTime: now,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(file.data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func bundleStdinFile(i int) string {
+	return fmt.Sprintf("stdin-%d.txt", i+1)
+}
+
+// reproduceScript renders reproduce.sh. CC and QEMU may be overridden from
+// the environment, for reporters whose toolchain lives elsewhere.
+func reproduceScript(s *Seed, meta BundleMeta) string {
+	var sb strings.Builder
+	sb.WriteString("#!/bin/sh\n")
+	sb.WriteString(fmt.Sprintf("# Reproduces seed %d", s.Meta.ID))
+	if meta.Oracle != "" {
+		sb.WriteString(" (" + meta.Oracle + " oracle)")
+	}
+	sb.WriteString("\n")
+	for _, line := range strings.Split(strings.TrimSpace(meta.Verdict), "\n") {
+		if line != "" {
+			sb.WriteString("# " + line + "\n")
+		}
+	}
+	if meta.CompileCommand != "" {
+		sb.WriteString("# Recorded compile command: " + strings.ReplaceAll(meta.CompileCommand, "\n", " ") + "\n")
+	}
+	sb.WriteString("cd \"$(dirname \"$0\")\" || exit 1\n\n")
+
+	sb.WriteString("CC=${CC:-" + exec.ShellQuote(meta.CompilerPath) + "}\n")
+	run := "./" + bundleBinary
+	if meta.QEMUPath != "" {
+		sb.WriteString("QEMU=${QEMU:-" + exec.ShellQuote(meta.QEMUPath) + "}\n")
+		run = `"$QEMU"`
+		if meta.QEMUSysroot != "" {
+			run += " -L " + exec.ShellQuote(meta.QEMUSysroot)
+		}
+		for _, kv := range meta.Env {
+			run += " -E " + exec.ShellQuote(kv)
+		}
+		run += " ./" + bundleBinary
+	} else if len(meta.Env) > 0 {
+		quoted := make([]string, len(meta.Env))
+		for i, kv := range meta.Env {
+			quoted[i] = exec.ShellQuote(kv)
+		}
+		run = "env " + strings.Join(quoted, " ") + " " + run
+	}
+
+	sb.WriteString("\n\"$CC\"")
+	for _, flag := range meta.CFlags {
+		sb.WriteString(" " + exec.ShellQuote(flag))
+	}
+	sb.WriteString(" " + s.Language.SourceFileName() + " -o " + bundleBinary + " || exit 1\n")
+
+	if len(s.TestCases) == 0 {
+		sb.WriteString("\n" + run + "\necho \"exit code: $?\"\n")
+		return sb.String()
+	}
+	for i, tc := range s.TestCases {
+		// The running command starts with the seed binary; keep its
+		// arguments and redirections.
+		command := strings.TrimSpace(tc.RunningCommand)
+		args := ""
+		if fields := strings.SplitN(command, " ", 2); len(fields) == 2 {
+			args = " " + fields[1]
+		}
+		sb.WriteString(fmt.Sprintf("\n# Expected: %s\n", strings.ReplaceAll(tc.ExpectedResult, "\n", " ")))
+		sb.WriteString(fmt.Sprintf("echo %s\n", exec.ShellQuote(fmt.Sprintf("== test case %d: %s", i+1, command))))
+		line := run + args
+		if env := sortedEnv(tc.Env); len(env) > 0 {
+			for j, kv := range env {
+				env[j] = exec.ShellQuote(kv)
+			}
+			line = "env " + strings.Join(env, " ") + " " + line
+		}
+		if tc.TimeoutSeconds > 0 {
+			line = fmt.Sprintf("timeout %d %s", tc.TimeoutSeconds, line)
+		}
+		if tc.Stdin != "" {
+			line += " < " + bundleStdinFile(i)
+		}
+		sb.WriteString(line + "\necho \"exit code: $?\"\n")
+	}
+	return sb.String()
+}
+
+// sortedEnv returns env as KEY=VALUE pairs in key order.
+func sortedEnv(env map[string]string) []string {
+	pairs := make([]string, 0, len(env))
+	for k, v := range env {
+		pairs = append(pairs, k+"="+v)
+	}
+	slices.Sort(pairs)
+	return pairs
+}
@@ -0,0 +1,108 @@
+package seed
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readBundle unpacks a bundle into a map from archive path to content.
+func readBundle(t *testing.T, path string) map[string]string {
+	t.Helper()
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+
+	files := map[string]string{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		files[hdr.Name] = string(data)
+		if hdr.Name == "seed-7/reproduce.sh" {
+			assert.Equal(t, int64(0755), hdr.Mode, "reproduce.sh should be executable")
+		}
+	}
+	return files
+}
+
+func TestExportBundle(t *testing.T) {
+	s := &Seed{
+		Meta:    Metadata{ID: 7},
+		Content: "#include <stdio.h>\nint main(void) { int n; scanf(\"%d\", &n); return n; }\n",
+		TestCases: []TestCase{
+			{RunningCommand: "./prog AAAA", ExpectedResult: "crash", Stdin: "42\n", TimeoutSeconds: 3},
+			{RunningCommand: "./prog", ExpectedResult: "exit 0", Env: map[string]string{"LANG": "C"}},
+		},
+	}
+	meta := BundleMeta{
+		CompilerPath:   "/opt/cross/bin/aarch64-linux-gnu-gcc",
+		CFlags:         []string{"-fstack-protector-all", "-O2", "--sysroot=/usr/aarch64-linux-gnu"},
+		CompileCommand: "/opt/cross/bin/aarch64-linux-gnu-gcc -fstack-protector-all -O2 build/source.c -o build/prog",
+		QEMUPath:       "qemu-aarch64",
+		QEMUSysroot:    "/usr/aarch64-linux-gnu",
+		Oracle:         "canary",
+		Verdict:        "stack smashing not detected",
+		Lineage: []BundleAncestor{
+			{ID: 5, Depth: 1, CovIncrease: 12, MutationNote: "added a VLA"},
+			{ID: 2, Depth: 0, CovIncrease: 40},
+		},
+	}
+	out := filepath.Join(t.TempDir(), "bugs", "7", "bundle.tar.gz")
+	require.NoError(t, ExportBundle(s, meta, out))
+
+	files := readBundle(t, out)
+	assert.Equal(t, s.Content, files["seed-7/source.c"])
+	assert.Equal(t, "42\n", files["seed-7/stdin-1.txt"])
+	assert.NotContains(t, files, "seed-7/stdin-2.txt")
+
+	var gotMeta BundleMeta
+	require.NoError(t, json.Unmarshal([]byte(files["seed-7/bundle.json"]), &gotMeta))
+	assert.Equal(t, meta, gotMeta)
+	testCases, err := DecodeTestCases(files["seed-7/testcases.json"])
+	require.NoError(t, err)
+	assert.Equal(t, s.TestCases, testCases)
+
+	script := files["seed-7/reproduce.sh"]
+	assert.Contains(t, script, "CC=${CC:-/opt/cross/bin/aarch64-linux-gnu-gcc}")
+	assert.Contains(t, script, `"$CC" -fstack-protector-all -O2 --sysroot=/usr/aarch64-linux-gnu source.c -o prog`)
+	assert.Contains(t, script, "# stack smashing not detected")
+	assert.Contains(t, script, `timeout 3 "$QEMU" -L /usr/aarch64-linux-gnu ./prog AAAA < stdin-1.txt`)
+	assert.Contains(t, script, `env LANG=C "$QEMU" -L /usr/aarch64-linux-gnu ./prog`)
+
+	if sh, err := exec.LookPath("sh"); err == nil {
+		path := filepath.Join(t.TempDir(), "reproduce.sh")
+		require.NoError(t, os.WriteFile(path, []byte(script), 0755))
+		output, err := exec.Command(sh, "-n", path).CombinedOutput()
+		assert.NoError(t, err, "reproduce.sh should parse: %s", output)
+	}
+}
+
+func TestExportBundle_NativeWithoutTestCases(t *testing.T) {
+	s := &Seed{Meta: Metadata{ID: 3}, Content: "int main() { return 0; }", Language: LanguageCPP}
+	out := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	require.NoError(t, ExportBundle(s, BundleMeta{CompilerPath: "g++", CFlags: []string{"-DMSG=a b"}}, out))
+
+	files := readBundle(t, out)
+	assert.Contains(t, files, "seed-3/source.cpp")
+	assert.NotContains(t, files, "seed-3/testcases.json")
+	script := files["seed-3/reproduce.sh"]
+	assert.Contains(t, script, `"$CC" '-DMSG=a b' source.cpp -o prog`)
+	assert.Contains(t, script, "\n./prog\n")
+	assert.NotContains(t, script, "QEMU")
+}
@@ -0,0 +1,177 @@
+package seed
+
+import (
+	"fmt"
+	"strings"
+)
+
+// canonicalIndent indents one brace level of canonical source.
+const canonicalIndent = "    "
+
+// Canonicalize returns content in a canonical layout for lang (a Language
+// name; empty means C): comments stripped, runs of blanks inside a line
+// collapsed to one space, lines re-indented by brace depth, and runs of
+// blank lines collapsed to one. Lines holding only a comment are dropped.
+// String and character literals are kept as written.
+//
+// The canonical form is only for comparing seeds (hashes, diffs); it is
+// never what gets compiled. An unterminated block comment is an error.
+func Canonicalize(content string, lang string) (string, error) {
+	language, err := ParseLanguage(lang)
+	if err != nil {
+		return "", err
+	}
+
+	type line struct {
+		text    strings.Builder
+		depth   int  // Brace depth at the start of the line
+		comment bool // A comment was stripped from the line
+	}
+	lines := []*line{{}}
+	depth := 0
+	pendingSpace := false
+	cur := func() *line { return lines[len(lines)-1] }
+	newLine := func() {
+		lines = append(lines, &line{depth: depth})
+		pendingSpace = false
+	}
+	write := func(text string) {
+		l := cur()
+		if pendingSpace && l.text.Len() > 0 {
+			l.text.WriteByte(' ')
+		}
+		pendingSpace = false
+		l.text.WriteString(text)
+	}
+
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == '\\' && strings.HasPrefix(content[i+1:], "\n"):
+			pendingSpace = true
+			i += 2
+		case c == '\n':
+			newLine()
+			i++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			pendingSpace = true
+			i++
+		case strings.HasPrefix(content[i:], "//"):
+			end := strings.IndexByte(content[i:], '\n')
+			if end < 0 {
+				end = len(content) - i
+			}
+			cur().comment = true
+			pendingSpace = true
+			i += end
+		case strings.HasPrefix(content[i:], "/*"):
+			n, ok := blockCommentLen(content[i:], language == LanguageRust)
+			if !ok {
+				return "", fmt.Errorf("unterminated comment at line %d", strings.Count(content[:i], "\n")+1)
+			}
+			cur().comment = true
+			pendingSpace = true
+			// A comment spanning lines still ends the line it starts on.
+			if strings.Contains(content[i:i+n], "\n") {
+				newLine()
+				cur().comment = true
+			}
+			i += n
+		case c == '"' || c == '\'':
+			n := literalLen(content[i:])
+			write(content[i : i+n])
+			i += n
+		default:
+			if language == LanguageRust && (i == 0 || !isWordByte(content[i-1])) {
+				if n := rawStringLen(content[i:]); n > 0 {
+					write(content[i : i+n])
+					i += n
+					continue
+				}
+			}
+			switch c {
+			case '{':
+				depth++
+			case '}':
+				depth = max(depth-1, 0)
+			}
+			write(content[i : i+1])
+			i++
+		}
+	}
+
+	var b strings.Builder
+	blank := false
+	for _, l := range lines {
+		text := l.text.String()
+		if text == "" {
+			// Lines that held only a comment vanish; blank lines
+			// collapse, and none lead or trail.
+			blank = blank || !l.comment && b.Len() > 0
+			continue
+		}
+		if blank {
+			b.WriteByte('\n')
+			blank = false
+		}
+		indent := l.depth
+		if text[0] == '}' {
+			indent = max(indent-1, 0)
+		}
+		if text[0] == '#' {
+			indent = 0
+		}
+		b.WriteString(strings.Repeat(canonicalIndent, indent))
+		b.WriteString(text)
+		b.WriteByte('\n')
+	}
+	return b.String(), nil
+}
+
+// blockCommentLen returns the length of the block comment at the start of
+// s, with Rust's nested comments when nested is set, and whether it is
+// terminated.
+func blockCommentLen(s string, nested bool) (int, bool) {
+	level := 0
+	for i := 0; i+1 < len(s); i++ {
+		switch {
+		case s[i] == '/' && s[i+1] == '*' && (level == 0 || nested):
+			level++
+			i++
+		case s[i] == '*' && s[i+1] == '/':
+			level--
+			i++
+			if level == 0 {
+				return i + 1, true
+			}
+		}
+	}
+	return len(s), false
+}
+
+// rawStringLen returns the length of the Rust raw string literal (r"..",
+// r#".."#, br"..") at the start of s, or 0 when there is none.
+func rawStringLen(s string) int {
+	i := 0
+	if strings.HasPrefix(s, "br") {
+		i = 2
+	} else if strings.HasPrefix(s, "r") {
+		i = 1
+	} else {
+		return 0
+	}
+	hashes := 0
+	for i < len(s) && s[i] == '#' {
+		hashes++
+		i++
+	}
+	if i >= len(s) || s[i] != '"' {
+		return 0
+	}
+	closing := "\"" + strings.Repeat("#", hashes)
+	end := strings.Index(s[i+1:], closing)
+	if end < 0 {
+		return len(s)
+	}
+	return i + 1 + end + len(closing)
+}
@@ -0,0 +1,54 @@
+package seed
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalize(t *testing.T) {
+	t.Run("layout and comments", func(t *testing.T) {
+		src := "/* header */\n#include <stdio.h>\n\n\n\nint main()   {\n\t// fill the buffer\n  char buf[16];   /* too small */\nif (1) {\n gets(buf);\n      }\n\n\n\treturn 0;\n}\n\n"
+		got, err := Canonicalize(src, "c")
+		require.NoError(t, err)
+		assert.Equal(t, "#include <stdio.h>\n\nint main() {\n    char buf[16];\n    if (1) {\n        gets(buf);\n    }\n\n    return 0;\n}\n", got)
+	})
+
+	t.Run("literals are kept", func(t *testing.T) {
+		got, err := Canonicalize(`puts("a  // b /* c */");  char c = '{';`, "")
+		require.NoError(t, err)
+		assert.Equal(t, "puts(\"a  // b /* c */\"); char c = '{';\n", got)
+	})
+
+	t.Run("rust", func(t *testing.T) {
+		got, err := Canonicalize("fn main() {\n/* outer /* inner */ still */\n    let s = r#\"a \" // b\"#;\n}", "rust")
+		require.NoError(t, err)
+		assert.Equal(t, "fn main() {\n    let s = r#\"a \" // b\"#;\n}\n", got)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := Canonicalize("int x; /* open", "c")
+		assert.ErrorContains(t, err, "unterminated comment at line 1")
+		_, err = Canonicalize("int x;", "fortran")
+		assert.Error(t, err)
+	})
+}
+
+func TestCanonicalize_Hashes(t *testing.T) {
+	original := &Seed{Content: "#include <string.h>\nint main(void) {\n    char buf[8];\n    strcpy(buf, \"AAAAAAAAAAAA\");\n    return 0;\n}\n"}
+	for name, content := range map[string]string{
+		"reindented":     "#include <string.h>\nint main(void) {\n\tchar buf[8];\n\tstrcpy(buf, \"AAAAAAAAAAAA\");\n\treturn 0;\n}",
+		"blank lines":    "#include <string.h>\n\n\nint main(void) {\n\n    char buf[8];\n\n\n    strcpy(buf, \"AAAAAAAAAAAA\");\n    return 0;\n}\n\n",
+		"commented":      "// overflow probe\n#include <string.h>\nint main(void) { /* entry */\n    char buf[8]; // small\n    strcpy(buf, \"AAAAAAAAAAAA\");\n    return 0;\n}\n",
+		"one line, crlf": "#include <string.h>\r\nint main(void) { char buf[8]; strcpy(buf, \"AAAAAAAAAAAA\"); return 0; }\r\n",
+	} {
+		reformatted := &Seed{Content: content}
+		assert.Equal(t, original.Hash(HashComments), reformatted.Hash(HashComments), name)
+		assert.Equal(t, content, reformatted.Content, "hashing must not touch the compiled source")
+	}
+
+	changed := &Seed{Content: "#include <string.h>\nint main(void) {\n    char buf[64];\n    strcpy(buf, \"AAAAAAAAAAAA\");\n    return 0;\n}\n"}
+	assert.NotEqual(t, original.Hash(HashComments), changed.Hash(HashComments))
+	assert.Equal(t, "no source changes", SummarizeDiff(original.Content, "// probe\n"+original.Content, LanguageC))
+}
@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/zjy-dev/de-fuzz/internal/fsutil"
 )
 
 const compilationRecordFile = "compile_command.json"
@@ -56,7 +58,7 @@ func SaveCompilationRecord(seedDir string, record *CompilationRecord) error {
 	}
 
 	recordPath := GetCompilationRecordPath(seedDir)
-	if err := os.WriteFile(recordPath, data, 0644); err != nil {
+	if err := fsutil.WriteFileAtomic(recordPath, data); err != nil {
 		return fmt.Errorf("failed to write compilation record %s: %w", recordPath, err)
 	}
 
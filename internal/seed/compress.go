@@ -0,0 +1,89 @@
+package seed
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CompressedSuffix is added to the source file name of a seed saved with
+// Metadata.Compressed set (source.c.gz).
+const CompressedSuffix = ".gz"
+
+// IsCompressedSource reports whether path names a gzip-compressed seed
+// source file.
+func IsCompressedSource(path string) bool {
+	return strings.HasSuffix(path, CompressedSuffix)
+}
+
+// compressSource gzips a seed source.
+func compressSource(content string) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(content)); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeSource returns the source held in data, read from path:
+// decompressed for a compressed source file, data itself otherwise.
+func decodeSource(path string, data []byte) ([]byte, error) {
+	if !IsCompressedSource(path) {
+		return data, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s: %w", path, err)
+	}
+	defer zr.Close()
+	source, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s: %w", path, err)
+	}
+	return source, nil
+}
+
+// ReadSource reads the seed source file at path, such as Metadata.ContentPath,
+// decompressing it when it is compressed.
+func ReadSource(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	source, err := decodeSource(path, data)
+	if err != nil {
+		return "", err
+	}
+	return string(source), nil
+}
+
+// PlainSourcePath returns a path holding the uncompressed source at path,
+// for tools that read a source file themselves: path itself unless it is
+// compressed, else a copy written into dir and named after the seed
+// directory (id-...-<hash>.c).
+func PlainSourcePath(path, dir string) (string, error) {
+	if !IsCompressedSource(path) {
+		return path, nil
+	}
+	source, err := ReadSource(path)
+	if err != nil {
+		return "", err
+	}
+	ext := filepath.Ext(strings.TrimSuffix(path, CompressedSuffix))
+	plain := filepath.Join(dir, filepath.Base(filepath.Dir(path))+ext)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(plain, []byte(source), 0644); err != nil {
+		return "", err
+	}
+	return plain, nil
+}
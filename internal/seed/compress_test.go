@@ -0,0 +1,109 @@
+package seed
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveSeedWithMetadata_Compression(t *testing.T) {
+	namer := NewDefaultNamingStrategy()
+	content := "int main() {\n" + strings.Repeat("    puts(\"padding\");\n", 200) + "    return 0;\n}\n"
+
+	for _, compressed := range []bool{false, true} {
+		t.Run(map[bool]string{false: "plain", true: "compressed"}[compressed], func(t *testing.T) {
+			dir := t.TempDir()
+			s := &Seed{
+				Meta:      Metadata{ID: 1, Compressed: compressed},
+				Content:   content,
+				TestCases: []TestCase{{RunningCommand: "./prog", ExpectedResult: "padding"}},
+			}
+			dirName, err := SaveSeedWithMetadata(dir, s, namer)
+			require.NoError(t, err)
+
+			assert.Equal(t, compressed, strings.HasSuffix(s.Meta.ContentPath, "source.c.gz"))
+			raw, err := os.ReadFile(s.Meta.ContentPath)
+			require.NoError(t, err)
+			if compressed {
+				assert.Less(t, len(raw), len(content)/4, "the source should be compressed on disk")
+			} else {
+				assert.Equal(t, content, string(raw))
+			}
+			source, err := ReadSource(s.Meta.ContentPath)
+			require.NoError(t, err)
+			assert.Equal(t, content, source)
+
+			loaded, err := LoadSeedWithMetadata(filepath.Join(dir, dirName), namer)
+			require.NoError(t, err)
+			assert.Equal(t, content, loaded.Content)
+			assert.Equal(t, compressed, loaded.Meta.Compressed)
+			assert.Equal(t, s.Meta.ContentPath, loaded.Meta.ContentPath)
+			assert.Equal(t, int64(len(content)), loaded.Meta.FileSize)
+			assert.Equal(t, s.TestCases, loaded.TestCases)
+		})
+	}
+}
+
+func TestLoadSeedsWithMetadata_MixedCompression(t *testing.T) {
+	dir := t.TempDir()
+	namer := NewDefaultNamingStrategy()
+	for i, compressed := range []bool{true, false, true} {
+		s := &Seed{Meta: Metadata{ID: uint64(i + 1), Compressed: compressed}, Content: strings.Repeat("x", i+1)}
+		_, err := SaveSeedWithMetadata(dir, s, namer)
+		require.NoError(t, err)
+	}
+
+	seeds, err := LoadSeedsWithMetadata(dir, namer)
+	require.NoError(t, err)
+	require.Len(t, seeds, 3)
+	for _, s := range seeds {
+		assert.Equal(t, strings.Repeat("x", int(s.Meta.ID)), s.Content)
+		assert.Equal(t, s.Meta.ID != 2, s.Meta.Compressed, "seed %d", s.Meta.ID)
+	}
+
+	// A truncated archive is quarantined like any other corrupt seed.
+	compressed := seeds[0].Meta.ContentPath
+	require.NoError(t, os.WriteFile(compressed, []byte{0x1f, 0x8b, 0x08}, 0644))
+	seeds, err = LoadSeedsWithMetadata(dir, namer)
+	require.NoError(t, err)
+	assert.Len(t, seeds, 2)
+	assert.DirExists(t, filepath.Join(dir, QuarantineDir, filepath.Base(filepath.Dir(compressed))))
+}
+
+func TestSaveSeedWithMetadata_SwitchesForm(t *testing.T) {
+	dir := t.TempDir()
+	namer := NewDefaultNamingStrategy()
+	s := &Seed{Meta: Metadata{ID: 1}, Content: "int main() { return 0; }"}
+	_, err := SaveSeedWithMetadata(dir, s, namer)
+	require.NoError(t, err)
+	plain := s.Meta.ContentPath
+
+	s.Meta.Compressed = true
+	_, err = SaveSeedWithMetadata(dir, s, namer)
+	require.NoError(t, err)
+	assert.NoFileExists(t, plain, "the plain source must not shadow the compressed one")
+	assert.FileExists(t, plain+CompressedSuffix)
+}
+
+func TestPlainSourcePath(t *testing.T) {
+	dir := t.TempDir()
+	s := &Seed{Meta: Metadata{ID: 7, Compressed: true}, Content: "int main() { return 7; }", Language: LanguageCPP}
+	dirName, err := SaveSeedWithMetadata(dir, s, NewDefaultNamingStrategy())
+	require.NoError(t, err)
+
+	outDir := filepath.Join(t.TempDir(), "state")
+	plain, err := PlainSourcePath(s.Meta.ContentPath, outDir)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(outDir, dirName+".cpp"), plain)
+	data, err := os.ReadFile(plain)
+	require.NoError(t, err)
+	assert.Equal(t, s.Content, string(data))
+
+	same, err := PlainSourcePath("/corpus/id-000001/source.c", outDir)
+	require.NoError(t, err)
+	assert.Equal(t, "/corpus/id-000001/source.c", same)
+}
@@ -0,0 +1,70 @@
+package seed
+
+import "slices"
+
+// Filter selects seeds, e.g. for corpus.Manager.NextWhere or an Iterator.
+type Filter func(*Seed) bool
+
+// ByLanguage selects seeds written in one of langs. An empty seed language
+// counts as C.
+func ByLanguage(langs ...Language) Filter {
+	return func(s *Seed) bool {
+		for _, lang := range langs {
+			if s.Language.OrDefault() == lang.OrDefault() {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// ByState selects seeds in one of states.
+func ByState(states ...SeedState) Filter {
+	return func(s *Seed) bool {
+		for _, state := range states {
+			if s.Meta.State == state {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// ByTag selects seeds labeled with tag (compared after NormalizeTags).
+func ByTag(tag string) Filter {
+	want := NormalizeTags([]string{tag})
+	return func(s *Seed) bool {
+		return len(want) == 1 && slices.Contains(s.Meta.Tags, want[0])
+	}
+}
+
+// Iterator walks a fixed list of seeds, yielding those its filter selects.
+// Reset starts it over.
+type Iterator struct {
+	seeds  []*Seed
+	filter Filter
+	pos    int
+}
+
+// NewIterator returns an Iterator over seeds in order. A nil filter selects
+// every seed.
+func NewIterator(seeds []*Seed, filter Filter) *Iterator {
+	return &Iterator{seeds: seeds, filter: filter}
+}
+
+// Next returns the next selected seed, or false when there is none left.
+func (it *Iterator) Next() (*Seed, bool) {
+	for it.pos < len(it.seeds) {
+		s := it.seeds[it.pos]
+		it.pos++
+		if it.filter == nil || it.filter(s) {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// Reset rewinds the iterator to the first seed.
+func (it *Iterator) Reset() {
+	it.pos = 0
+}
@@ -0,0 +1,58 @@
+package seed
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilters(t *testing.T) {
+	c := &Seed{Meta: Metadata{State: SeedStatePending}}
+	cpp := &Seed{Meta: Metadata{State: SeedStateProcessed}, Language: LanguageCPP}
+	rust := &Seed{Meta: Metadata{State: SeedStateInvalid}, Language: LanguageRust}
+
+	isC := ByLanguage(LanguageC)
+	assert.True(t, isC(c), "an empty language counts as C")
+	assert.False(t, isC(cpp))
+	assert.True(t, ByLanguage(LanguageCPP, LanguageRust)(rust))
+
+	assert.True(t, ByState(SeedStatePending)(c))
+	assert.False(t, ByState(SeedStatePending)(cpp))
+	assert.True(t, ByState(SeedStateProcessed, SeedStateInvalid)(rust))
+	assert.False(t, ByState()(c))
+
+	tagged := &Seed{Meta: Metadata{Tags: []string{"vla", "fmt-string"}}}
+	assert.True(t, ByTag("vla")(tagged))
+	assert.True(t, ByTag("Fmt String")(tagged), "the tag is normalized")
+	assert.False(t, ByTag("alloca")(tagged))
+	assert.False(t, ByTag("")(tagged))
+	assert.False(t, ByTag("vla")(c))
+}
+
+func TestIterator(t *testing.T) {
+	var seeds []*Seed
+	for i, lang := range []Language{LanguageC, LanguageCPP, LanguageC, LanguageRust, LanguageC} {
+		seeds = append(seeds, &Seed{Meta: Metadata{ID: uint64(i + 1)}, Language: lang})
+	}
+	ids := func(it *Iterator) []uint64 {
+		var got []uint64
+		for s, ok := it.Next(); ok; s, ok = it.Next() {
+			got = append(got, s.Meta.ID)
+		}
+		return got
+	}
+
+	it := NewIterator(seeds, ByLanguage(LanguageC))
+	first, ok := it.Next()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1), first.Meta.ID)
+	assert.Equal(t, []uint64{3, 5}, ids(it))
+	_, ok = it.Next()
+	assert.False(t, ok, "an exhausted iterator stays exhausted")
+
+	it.Reset()
+	assert.Equal(t, []uint64{1, 3, 5}, ids(it))
+
+	assert.Equal(t, []uint64{1, 2, 3, 4, 5}, ids(NewIterator(seeds, nil)))
+	assert.Empty(t, ids(NewIterator(nil, nil)))
+}
@@ -0,0 +1,253 @@
+package seed
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SplitFunctions splits C code containing several top-level function
+// definitions into a map keyed by declarator name. Anything between two
+// definitions (label comments, globals, struct types) stays with the function
+// that follows it; trailing text stays with the last function.
+func SplitFunctions(code string) (map[string]string, error) {
+	masked := MaskCode(code)
+
+	functions := make(map[string]string)
+	var order []string
+	chunkStart := 0  // start of the current function's chunk
+	headerStart := 0 // start of the current top-level declaration
+	depth := 0
+	current := "" // name of the function whose body is open, if any
+
+	for i := 0; i < len(masked); i++ {
+		switch masked[i] {
+		case '{':
+			if depth == 0 {
+				current = declaratorName(masked[headerStart:i])
+			}
+			depth++
+		case '}':
+			if depth == 0 {
+				return nil, fmt.Errorf("unbalanced '}' at offset %d", i)
+			}
+			depth--
+			if depth == 0 && current != "" {
+				if _, dup := functions[current]; dup {
+					return nil, fmt.Errorf("function %q is defined more than once", current)
+				}
+				functions[current] = strings.TrimSpace(code[chunkStart : i+1])
+				order = append(order, current)
+				chunkStart, headerStart = i+1, i+1
+				current = ""
+			}
+		case ';':
+			if depth == 0 {
+				headerStart = i + 1
+			}
+		}
+	}
+
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced braces: %d block(s) not closed", depth)
+	}
+	if len(order) == 0 {
+		return nil, fmt.Errorf("no function definitions found")
+	}
+	if trailing := strings.TrimSpace(code[chunkStart:]); trailing != "" {
+		last := order[len(order)-1]
+		functions[last] += "\n" + trailing
+	}
+	return functions, nil
+}
+
+// definitionHeader returns the header of the definition of name in code
+// (everything before its body, comments removed and whitespace collapsed), or
+// "" when code does not define name.
+func definitionHeader(code, name string) string {
+	masked := MaskCode(code)
+	headerStart, depth := 0, 0
+	for i := 0; i < len(masked); i++ {
+		switch masked[i] {
+		case '{':
+			if depth == 0 && declaratorName(masked[headerStart:i]) == name {
+				return strings.Join(strings.Fields(masked[headerStart:i]), " ")
+			}
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				headerStart = i + 1
+			}
+		case ';':
+			if depth == 0 {
+				headerStart = i + 1
+			}
+		}
+	}
+	return ""
+}
+
+// FunctionBody returns the byte offsets of the body of the top-level
+// definition of name in code, from just after its opening brace to its
+// closing brace, or ok false when code does not define name.
+func FunctionBody(code, name string) (start, end int, ok bool) {
+	masked := MaskCode(code)
+	headerStart, depth := 0, 0
+	for i := 0; i < len(masked); i++ {
+		switch masked[i] {
+		case '{':
+			if depth == 0 && declaratorName(masked[headerStart:i]) == name {
+				start, ok = i+1, true
+			}
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				if ok {
+					return start, i, true
+				}
+				headerStart = i + 1
+			}
+		case ';':
+			if depth == 0 {
+				headerStart = i + 1
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// isStaticHelper reports whether code defines name with internal linkage,
+// the way models write small helpers next to the functions they were asked
+// for.
+func isStaticHelper(code, name string) bool {
+	header := definitionHeader(code, name)
+	return header != "" && slices.Contains(strings.Fields(stripAttributes(header)), "static")
+}
+
+// declaratorName returns the function name declared by a top-level header
+// such as "static __attribute__((noinline)) int helper(char *p)", or "" when
+// the header is not a function definition (struct, initializer, ...).
+func declaratorName(header string) string {
+	header = stripAttributes(header)
+	if strings.Contains(header, "=") {
+		return ""
+	}
+	paren := strings.IndexByte(header, '(')
+	if paren < 0 {
+		return ""
+	}
+
+	end := paren
+	for end > 0 && (header[end-1] == ' ' || header[end-1] == '\t' || header[end-1] == '\n') {
+		end--
+	}
+	start := end
+	for start > 0 && isIdentByte(header[start-1]) {
+		start--
+	}
+	if start == end || (header[start] >= '0' && header[start] <= '9') {
+		return ""
+	}
+	return header[start:end]
+}
+
+// stripAttributes blanks out GNU __attribute__((...)) and __declspec(...)
+// groups so their parentheses are not mistaken for a parameter list.
+func stripAttributes(header string) string {
+	b := []byte(header)
+	for _, kw := range []string{"__attribute__", "__declspec"} {
+		for {
+			idx := strings.Index(string(b), kw)
+			if idx < 0 {
+				break
+			}
+			end := idx + len(kw)
+			for end < len(b) && (b[end] == ' ' || b[end] == '\t' || b[end] == '\n') {
+				end++
+			}
+			if end < len(b) && b[end] == '(' {
+				depth := 0
+				for ; end < len(b); end++ {
+					if b[end] == '(' {
+						depth++
+					} else if b[end] == ')' {
+						depth--
+						if depth == 0 {
+							end++
+							break
+						}
+					}
+				}
+			}
+			for k := idx; k < end; k++ {
+				b[k] = ' '
+			}
+		}
+	}
+	return string(b)
+}
+
+// MaskCode returns code with comments, string/char literals and
+// preprocessor lines replaced by spaces, preserving byte offsets, so the
+// result can be searched for code tokens that apply to code itself.
+func MaskCode(code string) string {
+	b := []byte(code)
+	lineStart := true
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		switch {
+		case lineStart && c == '#':
+			for i < len(b) && b[i] != '\n' {
+				b[i] = ' '
+				i++
+			}
+			i-- // let the newline be seen below
+		case c == '/' && i+1 < len(b) && b[i+1] == '/':
+			for i < len(b) && b[i] != '\n' {
+				b[i] = ' '
+				i++
+			}
+			i--
+		case c == '/' && i+1 < len(b) && b[i+1] == '*':
+			end := strings.Index(code[i+2:], "*/")
+			stop := len(b)
+			if end >= 0 {
+				stop = i + 2 + end + 2
+			}
+			for ; i < stop; i++ {
+				if b[i] != '\n' {
+					b[i] = ' '
+				}
+			}
+			i--
+		case c == '"' || c == '\'':
+			quote := c
+			b[i] = ' '
+			for i++; i < len(b) && b[i] != quote && b[i] != '\n'; i++ {
+				if b[i] == '\\' && i+1 < len(b) {
+					b[i] = ' '
+					i++
+				}
+				b[i] = ' '
+			}
+			if i < len(b) && b[i] == quote {
+				b[i] = ' '
+			}
+		}
+
+		if i >= 0 && i < len(b) {
+			if b[i] == '\n' {
+				lineStart = true
+			} else if b[i] != ' ' && b[i] != '\t' {
+				lineStart = false
+			}
+		}
+	}
+	return string(b)
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,159 @@
+package seed
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// HashStrictness selects which differences between two sources Hash
+// ignores.
+type HashStrictness string
+
+const (
+	// HashExact hashes the source byte for byte.
+	HashExact HashStrictness = "exact"
+	// HashWhitespace ignores whitespace between tokens (the default).
+	HashWhitespace HashStrictness = "whitespace"
+	// HashComments ignores comments as well as whitespace, by hashing the
+	// Canonicalize form.
+	HashComments HashStrictness = "comments"
+)
+
+// Hash returns the hex sha256 of the seed's language, source and CFlags,
+// with the source normalized per strictness ("" means HashWhitespace).
+// Seeds with equal hashes compile to the same program under the same flags.
+// String and character literals are never normalized.
+func (s *Seed) Hash(strictness HashStrictness) string {
+	source := s.Content
+	switch strictness {
+	case HashExact:
+	case HashComments:
+		if canonical, err := Canonicalize(source, string(s.Language)); err == nil {
+			source = canonical
+		}
+		source = normalizeSource(source)
+	default:
+		source = normalizeSource(source)
+	}
+
+	h := sha256.New()
+	h.Write([]byte(s.Language.OrDefault()))
+	h.Write([]byte{0})
+	h.Write([]byte(source))
+	for _, flag := range s.CFlags {
+		h.Write([]byte{0})
+		h.Write([]byte(flag))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// normalizeSource drops whitespace between tokens, keeping one space where
+// removing it would merge two tokens and the line breaks that end
+// preprocessor directives.
+func normalizeSource(source string) string {
+	var b strings.Builder
+	b.Grow(len(source))
+	var prev byte         // Last byte written, 0 at the start
+	pendingSpace := false // Whitespace seen since prev
+	pendingNewline := false
+	directive := false // Inside a preprocessor line
+
+	flush := func(next byte) {
+		switch {
+		case pendingNewline:
+			b.WriteByte('\n')
+		case pendingSpace && prev != 0 && mergesWith(prev, next):
+			b.WriteByte(' ')
+		}
+		pendingSpace, pendingNewline = false, false
+	}
+	write := func(text string) {
+		flush(text[0])
+		b.WriteString(text)
+		prev = text[len(text)-1]
+	}
+
+	for i := 0; i < len(source); {
+		c := source[i]
+		switch {
+		case c == '\\' && i+1 < len(source) && source[i+1] == '\n':
+			// A line continuation keeps a directive going.
+			pendingSpace = true
+			i += 2
+		case c == '\n':
+			if directive {
+				pendingNewline = true
+				directive = false
+			}
+			pendingSpace = true
+			i++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			pendingSpace = true
+			i++
+		case c == '"' || c == '\'':
+			n := literalLen(source[i:])
+			write(source[i : i+n])
+			i += n
+		default:
+			if c == '#' && atLineStart(source, i) {
+				directive = true
+			}
+			write(source[i : i+1])
+			i++
+		}
+	}
+	return b.String()
+}
+
+// atLineStart reports whether only blanks precede source[i] on its line.
+func atLineStart(source string, i int) bool {
+	for j := i - 1; j >= 0; j-- {
+		switch source[j] {
+		case '\n':
+			return true
+		case ' ', '\t':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// literalLen returns the length of the string or character literal at the
+// start of s. A quote that does not open a one-character literal (a Rust
+// lifetime such as 'a) is a literal of length 1.
+func literalLen(s string) int {
+	quote := s[0]
+	if quote == '\'' {
+		switch {
+		case len(s) >= 3 && s[1] != '\\' && s[2] == '\'':
+			return 3
+		case len(s) >= 2 && s[1] == '\\':
+		default:
+			return 1
+		}
+	}
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1
+		case '\n':
+			return i
+		}
+	}
+	return len(s)
+}
+
+// mergesWith reports whether a and b would read as one token if the
+// whitespace between them were removed.
+func mergesWith(a, b byte) bool {
+	return isWordByte(a) && isWordByte(b) ||
+		strings.IndexByte("+-*/%&|^<>=!:.", a) >= 0 && strings.IndexByte("+-*/%&|^<>=!:.", b) >= 0
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
@@ -0,0 +1,55 @@
+package seed
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeed_Hash(t *testing.T) {
+	base := &Seed{Content: "#include <stdio.h>\nint main() {\n    char buf[16];\n    return 0; // done\n}\n"}
+
+	t.Run("exact duplicates", func(t *testing.T) {
+		dup := &Seed{Content: base.Content}
+		for _, strictness := range []HashStrictness{HashExact, HashWhitespace, HashComments} {
+			assert.Equal(t, base.Hash(strictness), dup.Hash(strictness), strictness)
+		}
+		assert.Len(t, base.Hash(""), 64)
+		assert.Equal(t, base.Hash(HashWhitespace), base.Hash(""))
+	})
+
+	t.Run("whitespace-only differences", func(t *testing.T) {
+		reformatted := &Seed{Content: "#include <stdio.h>\r\n\nint main(){\n\tchar buf [16];\n\treturn 0;   // done\n}"}
+		assert.NotEqual(t, base.Hash(HashExact), reformatted.Hash(HashExact))
+		assert.Equal(t, base.Hash(HashWhitespace), reformatted.Hash(HashWhitespace))
+		assert.Equal(t, base.Hash(HashComments), reformatted.Hash(HashComments))
+	})
+
+	t.Run("comment-only differences", func(t *testing.T) {
+		commented := &Seed{Content: "/* overflow probe */\n#include <stdio.h>\nint main() {\n    char buf[16];\n    return 0;\n}\n"}
+		assert.NotEqual(t, base.Hash(HashWhitespace), commented.Hash(HashWhitespace))
+		assert.Equal(t, base.Hash(HashComments), commented.Hash(HashComments))
+	})
+
+	t.Run("different seeds", func(t *testing.T) {
+		for name, other := range map[string]*Seed{
+			"code":             {Content: "#include <stdio.h>\nint main() {\n    char buf[32];\n    return 0;\n}\n"},
+			"merged tokens":    {Content: "#include <stdio.h>\nintmain() {\n    char buf[16];\n    return 0;\n}\n"},
+			"string literal":   {Content: `int main() { puts("a  b"); }`},
+			"cflags":           {Content: base.Content, CFlags: []string{"-O2"}},
+			"language":         {Content: base.Content, Language: LanguageCPP},
+			"directive layout": {Content: "#include <stdio.h> int main() {\n    char buf[16];\n    return 0;\n}\n"},
+		} {
+			assert.NotEqual(t, base.Hash(HashComments), other.Hash(HashComments), name)
+		}
+		assert.NotEqual(t,
+			(&Seed{Content: `int main() { puts("a b"); }`}).Hash(HashComments),
+			(&Seed{Content: `int main() { puts("a  b"); }`}).Hash(HashComments))
+		assert.NotEqual(t,
+			(&Seed{Content: "int x = a - -b;"}).Hash(HashWhitespace),
+			(&Seed{Content: "int x = a --b;"}).Hash(HashWhitespace))
+		assert.NotEqual(t,
+			(&Seed{Content: `char *s = "// a";`}).Hash(HashComments),
+			(&Seed{Content: `char *s = "// b";`}).Hash(HashComments))
+	})
+}
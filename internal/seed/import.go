@@ -0,0 +1,170 @@
+package seed
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultImportMaxBytes is the size limit of an imported source file unless
+// ImportOptions.MaxBytes says otherwise.
+const DefaultImportMaxBytes = 64 << 10
+
+// importExtensions maps the source file extensions ImportDirectory picks up
+// to their language.
+var importExtensions = map[string]Language{
+	".c":   LanguageC,
+	".cpp": LanguageCPP,
+	".cc":  LanguageCPP,
+	".cxx": LanguageCPP,
+}
+
+// ImportOptions configures ImportDirectory.
+type ImportOptions struct {
+	// MaxBytes skips files larger than this; 0 means DefaultImportMaxBytes.
+	MaxBytes int64
+	// DefaultTestCases gives every imported seed a "./prog" test case
+	// expecting exit code 0.
+	DefaultTestCases bool
+	// NextID allocates seed IDs; nil numbers the seeds from 1.
+	NextID func() uint64
+	// Source names the corpus the files come from (e.g. "csmith") in the
+	// provenance note; empty uses the directory name.
+	Source string
+	// OnSkip, when set, is told about every source file left out and why.
+	OnSkip func(path, reason string)
+}
+
+// ImportDirectory walks dir for .c/.cpp/.cc/.cxx files and returns them as
+// initial seeds, in lexical path order. Files over the size limit or failing
+// a quick syntax sanity check are skipped. Each seed records where it came
+// from in Meta.Provenance.
+func ImportDirectory(dir string, opts ImportOptions) ([]*Seed, error) {
+	maxBytes := opts.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultImportMaxBytes
+	}
+	source := opts.Source
+	if source == "" {
+		source = filepath.Base(filepath.Clean(dir))
+	}
+	nextID := opts.NextID
+	if nextID == nil {
+		var id uint64
+		nextID = func() uint64 {
+			id++
+			return id
+		}
+	}
+	skip := func(path, reason string) {
+		if opts.OnSkip != nil {
+			opts.OnSkip(path, reason)
+		}
+	}
+
+	var seeds []*Seed
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		lang, ok := importExtensions[strings.ToLower(filepath.Ext(path))]
+		if d.IsDir() || !ok {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.Size() > maxBytes {
+			skip(path, fmt.Sprintf("larger than %d bytes", maxBytes))
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if reason := checkImportSource(content); reason != "" {
+			skip(path, reason)
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			rel = path
+		}
+		s := &Seed{
+			Meta: Metadata{
+				ID:         nextID(),
+				FileSize:   info.Size(),
+				CreatedAt:  time.Now(),
+				State:      SeedStatePending,
+				Provenance: "import:" + source + "/" + filepath.ToSlash(rel),
+			},
+			Content:  string(content),
+			Language: lang,
+		}
+		if opts.DefaultTestCases {
+			s.TestCases = []TestCase{{RunningCommand: "./prog", ExpectedResult: "exit 0"}}
+		}
+		seeds = append(seeds, s)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to import %s: %w", dir, err)
+	}
+	return seeds, nil
+}
+
+// checkImportSource is a quick syntax sanity check of an imported file. It
+// returns why the file is rejected, or "" when it looks like source code:
+// non-empty UTF-8 text whose brackets balance outside comments and literals.
+func checkImportSource(content []byte) string {
+	if len(bytes.TrimSpace(content)) == 0 {
+		return "empty"
+	}
+	if bytes.IndexByte(content, 0) >= 0 || !utf8.Valid(content) {
+		return "not text"
+	}
+
+	source := string(content)
+	var stack []byte
+	closing := map[byte]byte{')': '(', ']': '[', '}': '{'}
+	for i := 0; i < len(source); {
+		switch c := source[i]; {
+		case strings.HasPrefix(source[i:], "//"):
+			end := strings.IndexByte(source[i:], '\n')
+			if end < 0 {
+				end = len(source) - i
+			}
+			i += end
+		case strings.HasPrefix(source[i:], "/*"):
+			end := strings.Index(source[i+2:], "*/")
+			if end < 0 {
+				return "unterminated comment"
+			}
+			i += end + 4
+		case c == '"' || c == '\'':
+			i += literalLen(source[i:])
+		case c == '(' || c == '[' || c == '{':
+			stack = append(stack, c)
+			i++
+		case c == ')' || c == ']' || c == '}':
+			if len(stack) == 0 || stack[len(stack)-1] != closing[c] {
+				return fmt.Sprintf("unbalanced %q", c)
+			}
+			stack = stack[:len(stack)-1]
+			i++
+		default:
+			i++
+		}
+	}
+	if len(stack) > 0 {
+		return fmt.Sprintf("unclosed %q", stack[len(stack)-1])
+	}
+	return ""
+}
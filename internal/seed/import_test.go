@@ -0,0 +1,81 @@
+package seed
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportDirectory(t *testing.T) {
+	dir := filepath.Join("testdata", "import")
+
+	skipped := map[string]string{}
+	nextID := uint64(100)
+	seeds, err := ImportDirectory(dir, ImportOptions{
+		MaxBytes:         256,
+		DefaultTestCases: true,
+		NextID: func() uint64 {
+			nextID++
+			return nextID
+		},
+		Source: "fixtures",
+		OnSkip: func(path, reason string) { skipped[filepath.ToSlash(path)] = reason },
+	})
+	require.NoError(t, err)
+
+	require.Len(t, seeds, 2)
+	assert.Equal(t, "import:fixtures/csmith/random1.c", seeds[0].Meta.Provenance)
+	assert.Equal(t, LanguageC, seeds[0].Language)
+	assert.Equal(t, uint64(101), seeds[0].Meta.ID)
+	assert.Equal(t, SeedStatePending, seeds[0].Meta.State)
+	assert.Equal(t, []TestCase{{RunningCommand: "./prog", ExpectedResult: "exit 0"}}, seeds[0].TestCases)
+	assert.Contains(t, seeds[0].Content, "checksum")
+
+	assert.Equal(t, "import:fixtures/gcc/execute/pr12345.cpp", seeds[1].Meta.Provenance)
+	assert.Equal(t, LanguageCPP, seeds[1].Language)
+	assert.Equal(t, uint64(102), seeds[1].Meta.ID)
+
+	assert.Equal(t, map[string]string{
+		"testdata/import/gcc/execute/large.c": "larger than 256 bytes",
+		"testdata/import/junk/binary.c":       "not text",
+		"testdata/import/junk/empty.c":        "empty",
+		"testdata/import/junk/unbalanced.c":   `unclosed '('`,
+	}, skipped, "README.txt is not a source file and is not reported")
+}
+
+func TestImportDirectory_Defaults(t *testing.T) {
+	seeds, err := ImportDirectory(filepath.Join("testdata", "import"), ImportOptions{})
+	require.NoError(t, err)
+
+	// The default size limit admits large.c; IDs count from 1.
+	require.Len(t, seeds, 3)
+	for i, s := range seeds {
+		assert.Equal(t, uint64(i+1), s.Meta.ID)
+		assert.Empty(t, s.TestCases)
+	}
+	assert.Equal(t, "import:import/csmith/random1.c", seeds[0].Meta.Provenance)
+
+	_, err = ImportDirectory(filepath.Join("testdata", "missing"), ImportOptions{})
+	assert.Error(t, err)
+}
+
+func TestCheckImportSource(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"int main() { return 0; }", ""},
+		{"int main() { puts(\"(\"); return ')'; } // {", ""},
+		{"int main() { /* } */ return 0; }", ""},
+		{"int main() { return 0; ", `unclosed '{'`},
+		{"int main() ] ", `unbalanced ']'`},
+		{"int main() { /* return 0; }", "unterminated comment"},
+		{"   \n\t", "empty"},
+		{"\xff\xfe", "not text"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, checkImportSource([]byte(tt.source)), tt.source)
+	}
+}
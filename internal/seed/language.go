@@ -0,0 +1,91 @@
+package seed
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Language is the source language of a seed.
+type Language string
+
+const (
+	LanguageC    Language = "c"
+	LanguageCPP  Language = "cpp"
+	LanguageRust Language = "rust"
+)
+
+// Languages lists the supported seed languages, C first.
+var Languages = []Language{LanguageC, LanguageCPP, LanguageRust}
+
+// ParseLanguage validates a language name. An empty name means C.
+func ParseLanguage(name string) (Language, error) {
+	switch Language(name) {
+	case "", LanguageC:
+		return LanguageC, nil
+	case LanguageCPP, LanguageRust:
+		return Language(name), nil
+	}
+	return "", fmt.Errorf("unsupported seed language %q: must be one of c, cpp, rust", name)
+}
+
+// OrDefault returns l, or LanguageC when l is empty.
+func (l Language) OrDefault() Language {
+	if l == "" {
+		return LanguageC
+	}
+	return l
+}
+
+// Extension returns the source file extension including the dot.
+func (l Language) Extension() string {
+	switch l.OrDefault() {
+	case LanguageCPP:
+		return ".cpp"
+	case LanguageRust:
+		return ".rs"
+	}
+	return ".c"
+}
+
+// SourceFileName is the name of the seed source file in a seed directory.
+func (l Language) SourceFileName() string {
+	return "source" + l.Extension()
+}
+
+// FenceTag is the markdown code-fence language tag.
+func (l Language) FenceTag() string {
+	switch l.OrDefault() {
+	case LanguageCPP:
+		return "cpp"
+	case LanguageRust:
+		return "rust"
+	}
+	return "c"
+}
+
+// DisplayName is the human-readable language name used in prompts.
+func (l Language) DisplayName() string {
+	switch l.OrDefault() {
+	case LanguageCPP:
+		return "C++"
+	case LanguageRust:
+		return "Rust"
+	}
+	return "C"
+}
+
+// findSourceFile returns the source file in seedDir and its language,
+// checking source.c first. A compressed source file (source.c.gz) is
+// found too.
+func findSourceFile(seedDir string) (string, Language, bool) {
+	for _, lang := range Languages {
+		path := filepath.Join(seedDir, lang.SourceFileName())
+		for _, candidate := range []string{path, path + CompressedSuffix} {
+			if _, err := os.Stat(candidate); err == nil {
+				return candidate, lang, true
+			}
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,188 @@
+package seed
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zjy-dev/de-fuzz/internal/fsutil"
+)
+
+// LayoutFile marks a seed directory, such as a corpus, whose new seeds go
+// into shards; see ShardedNamingStrategy. A directory without it is flat.
+const LayoutFile = "layout.json"
+
+// Layout is how a directory stores its seed directories.
+type Layout string
+
+const (
+	// LayoutFlat keeps every seed directory directly in the directory.
+	LayoutFlat Layout = "flat"
+	// LayoutSharded keeps each seed directory in a shard named after the
+	// first two hex digits of its content hash, e.g. 3f/id-000042-...-3fa1b2c4,
+	// so no directory grows past a few thousand entries.
+	LayoutSharded Layout = "sharded"
+)
+
+// layoutMarker is the on-disk form of LayoutFile.
+type layoutMarker struct {
+	Layout Layout `json:"layout"`
+}
+
+// DetectLayout returns the layout LayoutFile in dir records, or LayoutFlat
+// when there is none.
+func DetectLayout(dir string) (Layout, error) {
+	data, err := os.ReadFile(filepath.Join(dir, LayoutFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return LayoutFlat, nil
+		}
+		return "", fmt.Errorf("failed to read %s: %w", LayoutFile, err)
+	}
+	var marker layoutMarker
+	if err := json.Unmarshal(data, &marker); err != nil {
+		return "", fmt.Errorf("failed to parse %s in %s: %w", LayoutFile, dir, err)
+	}
+	switch marker.Layout {
+	case LayoutFlat, LayoutSharded:
+		return marker.Layout, nil
+	default:
+		return "", fmt.Errorf("unknown seed layout %q in %s", marker.Layout, dir)
+	}
+}
+
+// WriteLayout records layout in dir's LayoutFile.
+func WriteLayout(dir string, layout Layout) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+	data, err := json.Marshal(layoutMarker{Layout: layout})
+	if err != nil {
+		return err
+	}
+	if err := fsutil.WriteFileAtomic(filepath.Join(dir, LayoutFile), data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", LayoutFile, err)
+	}
+	return nil
+}
+
+// ShardedNamingStrategy wraps a NamingStrategy to place each seed directory
+// in its LayoutSharded shard: GenerateFilename returns "<shard>/<name>".
+type ShardedNamingStrategy struct {
+	NamingStrategy
+}
+
+// NewShardedNamingStrategy creates a ShardedNamingStrategy around namer.
+func NewShardedNamingStrategy(namer NamingStrategy) *ShardedNamingStrategy {
+	return &ShardedNamingStrategy{NamingStrategy: namer}
+}
+
+// GenerateFilename creates the filename namer would, inside its shard.
+func (s *ShardedNamingStrategy) GenerateFilename(meta *Metadata, content string) string {
+	return filepath.Join(shardName(GenerateContentHash(content)), s.NamingStrategy.GenerateFilename(meta, content))
+}
+
+// ParseFilename parses the filename with or without its shard.
+func (s *ShardedNamingStrategy) ParseFilename(filename string) (*Metadata, error) {
+	return s.NamingStrategy.ParseFilename(filepath.Base(filename))
+}
+
+// shardName returns the shard of a seed whose content hash is hash.
+func shardName(hash string) string {
+	if len(hash) < 2 {
+		return "00"
+	}
+	return hash[:2]
+}
+
+// isShardName reports whether name can be a shard: two lowercase hex digits.
+func isShardName(name string) bool {
+	if len(name) != 2 {
+		return false
+	}
+	for _, c := range name {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// walkSeedDirs calls fn for every candidate seed directory in dir, as its
+// parent directory and name, whatever the layout: seed directories in dir
+// itself, then those of each shard. Shards are read one at a time, so
+// memory does not grow with the corpus. The quarantine is skipped.
+func walkSeedDirs(dir string, fn func(parent, name string)) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read directory %s: %w", dir, err)
+	}
+
+	var shards []string
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == QuarantineDir {
+			continue
+		}
+		if isShardName(entry.Name()) {
+			shards = append(shards, entry.Name())
+			continue
+		}
+		fn(dir, entry.Name())
+	}
+	for _, shard := range shards {
+		shardDir := filepath.Join(dir, shard)
+		entries, err := os.ReadDir(shardDir)
+		if err != nil {
+			return fmt.Errorf("failed to read shard %s: %w", shardDir, err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() && entry.Name() != QuarantineDir {
+				fn(shardDir, entry.Name())
+			}
+		}
+	}
+	return nil
+}
+
+// MigrateToSharded moves the seed directories kept flat in dir into their
+// shards and records LayoutSharded. namer parses the directory names for
+// their content hashes; directories it cannot parse stay where they are.
+// The layout is recorded first, and seeds not moved yet are still found
+// where they are, so an interrupted migration leaves a usable directory
+// and running it again finishes it. It returns the new path, relative to
+// dir, of every seed directory moved, keyed by the old one.
+func MigrateToSharded(dir string, namer NamingStrategy) (map[string]string, error) {
+	if err := WriteLayout(dir, LayoutSharded); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory %s: %w", dir, err)
+	}
+
+	moved := make(map[string]string)
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || name == QuarantineDir || isShardName(name) {
+			continue
+		}
+		meta, err := namer.ParseFilename(name + ".seed")
+		if err != nil {
+			continue
+		}
+		target := filepath.Join(shardName(strings.ToLower(meta.ContentHash)), name)
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(target)), 0755); err != nil {
+			return moved, fmt.Errorf("failed to create shard: %w", err)
+		}
+		if err := os.Rename(filepath.Join(dir, name), filepath.Join(dir, target)); err != nil {
+			return moved, fmt.Errorf("failed to move seed %s into its shard: %w", name, err)
+		}
+		moved[name] = target
+	}
+	return moved, nil
+}
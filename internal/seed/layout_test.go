@@ -0,0 +1,110 @@
+package seed
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectLayout(t *testing.T) {
+	dir := t.TempDir()
+	layout, err := DetectLayout(dir)
+	require.NoError(t, err)
+	assert.Equal(t, LayoutFlat, layout, "no marker means flat")
+
+	require.NoError(t, WriteLayout(dir, LayoutSharded))
+	layout, err = DetectLayout(dir)
+	require.NoError(t, err)
+	assert.Equal(t, LayoutSharded, layout)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, LayoutFile), []byte(`{"layout":"nested"}`), 0644))
+	_, err = DetectLayout(dir)
+	assert.Error(t, err)
+}
+
+func TestShardedNamingStrategy(t *testing.T) {
+	namer := NewShardedNamingStrategy(NewDefaultNamingStrategy())
+	content := "int main() { return 0; }"
+	meta := &Metadata{ID: 42, ParentID: 7, CovIncrease: 12}
+
+	filename := namer.GenerateFilename(meta, content)
+	hash := GenerateContentHash(content)
+	assert.Equal(t, filepath.Join(hash[:2], fmt.Sprintf("id-000042-src-000007-cov-00012-%s.seed", hash)), filename)
+
+	parsed, err := namer.ParseFilename(filename)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), parsed.ID)
+	assert.Equal(t, uint64(7), parsed.ParentID)
+}
+
+func TestMigrateToSharded(t *testing.T) {
+	dir := t.TempDir()
+	flat := NewDefaultNamingStrategy()
+	var names []string
+	for i := 1; i <= 4; i++ {
+		s := &Seed{Meta: Metadata{ID: uint64(i)}, Content: fmt.Sprintf("int main() { return %d; }", i)}
+		name, err := SaveSeedWithMetadata(dir, s, flat)
+		require.NoError(t, err)
+		names = append(names, name)
+	}
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "notes"), 0755))
+
+	moved, err := MigrateToSharded(dir, flat)
+	require.NoError(t, err)
+	assert.Len(t, moved, 4, "only seed directories move")
+	assert.DirExists(t, filepath.Join(dir, "notes"))
+	for _, name := range names {
+		assert.NoDirExists(t, filepath.Join(dir, name))
+		meta, err := flat.ParseFilename(name + ".seed")
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(meta.ContentHash[:2], name), moved[name])
+		assert.FileExists(t, filepath.Join(dir, moved[name], "source.c"))
+	}
+	layout, err := DetectLayout(dir)
+	require.NoError(t, err)
+	assert.Equal(t, LayoutSharded, layout)
+
+	// Both loaders find the sharded seeds, with FilePath relative to dir
+	seeds, err := LoadSeedsWithMetadata(dir, flat)
+	require.NoError(t, err)
+	require.Len(t, seeds, 4)
+	pool, err := OpenPool(dir, flat)
+	require.NoError(t, err)
+	require.Equal(t, 4, pool.Len())
+	for _, s := range append(seeds, pool.Seeds()...) {
+		assert.Equal(t, moved[filepath.Base(s.Meta.FilePath)], s.Meta.FilePath)
+		assert.Equal(t, filepath.Join(dir, s.Meta.FilePath, "source.c"), s.Meta.ContentPath)
+	}
+}
+
+func TestLoadSeedsWithMetadata_MixedLayout(t *testing.T) {
+	// An interrupted migration leaves some seeds flat and some sharded
+	dir := t.TempDir()
+	flat := NewDefaultNamingStrategy()
+	sharded := NewShardedNamingStrategy(flat)
+	for i := 1; i <= 4; i++ {
+		namer := NamingStrategy(flat)
+		if i%2 == 0 {
+			namer = sharded
+		}
+		s := &Seed{Meta: Metadata{ID: uint64(i)}, Content: fmt.Sprintf("int main() { return %d; }", i)}
+		_, err := SaveSeedWithMetadata(dir, s, namer)
+		require.NoError(t, err)
+	}
+
+	seeds, err := LoadSeedsWithMetadata(dir, flat)
+	require.NoError(t, err)
+	ids := make(map[uint64]bool)
+	for _, s := range seeds {
+		ids[s.Meta.ID] = true
+	}
+	assert.Equal(t, map[uint64]bool{1: true, 2: true, 3: true, 4: true}, ids)
+
+	moved, err := MigrateToSharded(dir, flat)
+	require.NoError(t, err)
+	assert.Len(t, moved, 2, "a second run moves only what is left")
+}
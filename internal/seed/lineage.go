@@ -0,0 +1,95 @@
+package seed
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxNoteLineChars clips the example lines quoted in a mutation note.
+const maxNoteLineChars = 60
+
+// SummarizeDiff describes in one line how child differs from parent: the
+// number of lines added and removed, plus the first removed and first added
+// line. Both sides are compared in their Canonicalize form for lang, so
+// layout and comment changes do not count. It is the computed fallback for
+// Metadata.MutationNote.
+func SummarizeDiff(parent, child string, lang Language) string {
+	removed, added := diffLines(splitCodeLines(canonicalOrRaw(parent, lang)), splitCodeLines(canonicalOrRaw(child, lang)))
+	if len(removed) == 0 && len(added) == 0 {
+		return "no source changes"
+	}
+
+	parts := []string{fmt.Sprintf("+%d/-%d lines", len(added), len(removed))}
+	if len(removed) > 0 {
+		parts = append(parts, fmt.Sprintf("removed %q", clipNoteLine(removed[0])))
+	}
+	if len(added) > 0 {
+		parts = append(parts, fmt.Sprintf("added %q", clipNoteLine(added[0])))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// canonicalOrRaw returns the canonical form of code, or code itself when it
+// cannot be canonicalized.
+func canonicalOrRaw(code string, lang Language) string {
+	if canonical, err := Canonicalize(code, string(lang)); err == nil {
+		return canonical
+	}
+	return code
+}
+
+// splitCodeLines returns the trimmed, non-blank lines of code.
+func splitCodeLines(code string) []string {
+	var lines []string
+	for _, line := range strings.Split(code, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// diffLines returns the lines of a missing from b and of b missing from a,
+// in order, using a longest-common-subsequence alignment.
+func diffLines(a, b []string) (removed, added []string) {
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			removed = append(removed, a[i])
+			i++
+		default:
+			added = append(added, b[j])
+			j++
+		}
+	}
+	removed = append(removed, a[i:]...)
+	added = append(added, b[j:]...)
+	return removed, added
+}
+
+func clipNoteLine(line string) string {
+	runes := []rune(line)
+	if len(runes) <= maxNoteLineChars {
+		return line
+	}
+	return string(runes[:maxNoteLineChars-3]) + "..."
+}
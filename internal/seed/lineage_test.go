@@ -0,0 +1,33 @@
+package seed
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarizeDiff(t *testing.T) {
+	parent := "int main() {\n  char buf[16];\n  gets(buf);\n  return 0;\n}"
+
+	t.Run("added and removed lines", func(t *testing.T) {
+		child := "int main() {\n  char buf[64];\n  gets(buf);\n  puts(buf);\n  return 0;\n}"
+		assert.Equal(t, `+2/-1 lines; removed "char buf[16];"; added "char buf[64];"`, SummarizeDiff(parent, child, LanguageC))
+	})
+
+	t.Run("only additions", func(t *testing.T) {
+		child := "int main() {\n  char buf[16];\n  gets(buf);\n\n  puts(buf);\n  return 0;\n}"
+		assert.Equal(t, `+1/-0 lines; added "puts(buf);"`, SummarizeDiff(parent, child, LanguageC))
+	})
+
+	t.Run("whitespace-only changes", func(t *testing.T) {
+		child := "int main() {\n    char buf[16];\n    gets(buf);\n\n    return 0;\n}\n"
+		assert.Equal(t, "no source changes", SummarizeDiff(parent, child, LanguageC))
+	})
+
+	t.Run("long lines are clipped", func(t *testing.T) {
+		long := "volatile int a_really_long_identifier_name_that_goes_on_and_on_and_on = 1;"
+		note := SummarizeDiff("", long, LanguageC)
+		assert.Contains(t, note, "...")
+		assert.Less(t, len(note), len(long)+20)
+	})
+}
@@ -1,6 +1,9 @@
 package seed
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // SeedState represents the processing status of a seed.
 type SeedState string
@@ -14,6 +17,12 @@ const (
 	SeedStateCrash SeedState = "CRASH"
 	// SeedStateTimeout indicates the seed caused a timeout.
 	SeedStateTimeout SeedState = "TIMEOUT"
+	// SeedStateInvalid indicates the seed failed validation when the corpus
+	// was loaded and is not fuzzed; CompileStderr says why.
+	SeedStateInvalid SeedState = "INVALID"
+	// SeedStateArchived indicates the seed was trimmed from the corpus into
+	// its archive because other seeds cover everything it covers.
+	SeedStateArchived SeedState = "ARCHIVED"
 )
 
 // OracleVerdict represents the verdict from oracle analysis.
@@ -38,10 +47,31 @@ type Metadata struct {
 	FileSize    int64     `json:"file_size"`    // File size in bytes
 	CreatedAt   time.Time `json:"created_at"`   // Creation timestamp
 
+	// Compressed marks a gzip-compressed source file (source.c.gz at
+	// ContentPath); read it with ReadSource.
+	Compressed bool `json:"compressed,omitempty"`
+
 	// Lineage
 	ParentID uint64 `json:"parent_id"` // Parent seed ID (0 for initial seeds)
 	Depth    int    `json:"depth"`     // Mutation depth (0 for initial seeds)
 
+	// MutationNote is a one-line summary of what this seed changed relative
+	// to its parent, shown to the LLM when a descendant is mutated.
+	MutationNote string `json:"mutation_note,omitempty"`
+
+	// Mutator names what made the seed when it was not the LLM:
+	// MutatorFallback for the textual mutations the engine falls back on
+	// while the LLM is unavailable. Empty for LLM-generated seeds.
+	Mutator string `json:"mutator,omitempty"`
+
+	// Tags name the vulnerability patterns the seed exercises ("vla",
+	// "alloca", "longjmp", ...), as the LLM labeled it; see NormalizeTags.
+	Tags []string `json:"tags,omitempty"`
+
+	// Provenance records where an imported seed came from, as
+	// "import:<source>/<relative path>" (empty for generated seeds).
+	Provenance string `json:"provenance,omitempty"`
+
 	// State
 	State SeedState `json:"state"` // Current processing state
 
@@ -51,13 +81,48 @@ type Metadata struct {
 	NewCoverage uint64 `json:"new_cov"`  // BB coverage after this seed (basis points)
 	CovIncrease uint64 `json:"cov_incr"` // Coverage increase (new - old, basis points)
 
+	// ExecTimeUs is how long the seed's test cases ran, in microseconds
+	// (0 if they were not run).
+	ExecTimeUs int64 `json:"exec_time_us,omitempty"`
+	// Energy is the seed's scheduling score as of its last result; see
+	// corpus.Energy.
+	Energy float64 `json:"energy,omitempty"`
+	// Favored marks a seed in the coverage mapping's favored set, the small
+	// set of seeds that together cover every covered line; the corpus picks
+	// favored seeds more often. It is recomputed from the mapping.
+	Favored bool `json:"favored,omitempty"`
+
 	// Oracle Results
 	OracleVerdict  OracleVerdict `json:"oracle_verdict"`     // Verdict from oracle analysis
 	BugType        string        `json:"bug_type,omitempty"` // Type of bug if detected
 	BugDescription string        `json:"bug_desc,omitempty"` // Description of bug
 
+	// Outcome of the last run, so postmortems can tell why a seed did not
+	// pay off: whether it compiled (with the head of the compiler's stderr
+	// when not; see StderrSnippet), how its test runs ended when that was
+	// not an exit ("SIGSEGV", "timeout", ...), and how long it took to
+	// compile, measure and run. CompileOK is false for seeds not run yet.
+	CompileOK     bool     `json:"compile_ok"`
+	CompileStderr string   `json:"compile_stderr,omitempty"`
+	ExecSignals   []string `json:"exec_signals,omitempty"`
+	DurationMs    int64    `json:"duration_ms,omitempty"`
+
+	// PromptTokens is the estimated size of the prompt that produced this seed (0 if unknown).
+	PromptTokens int `json:"prompt_tokens,omitempty"`
+	// ContextTokens is the estimated size of earlier conversation turns resent
+	// with the prompt in conversation mode (0 for stateless prompts).
+	ContextTokens int `json:"context_tokens,omitempty"`
+
 	// ContentHash is an optional short hash (e.g., CRC32 or SHA1 prefix) for deduplication.
 	ContentHash string `json:"content_hash,omitempty"`
+
+	// Hash is the full Seed.Hash the corpus deduplicated this seed by
+	// (empty when deduplication is off).
+	Hash string `json:"dedup_hash,omitempty"`
+
+	// SchemaVersion is the metadata JSON schema the record was written
+	// with; see MetadataSchemaVersion.
+	SchemaVersion int `json:"schema_version"`
 }
 
 // NewMetadata creates a new Metadata with the given ID and parent information.
@@ -70,3 +135,29 @@ func NewMetadata(id, parentID uint64, depth int) *Metadata {
 		CreatedAt: time.Now(),
 	}
 }
+
+// MutatorFallback is the Metadata.Mutator of seeds made by the engine's
+// LLM-free fallback mutations.
+const MutatorFallback = "fallback"
+
+// ExecSignalTimeout is the Metadata.ExecSignals entry for a test run that
+// timed out; the others name signals, e.g. "SIGSEGV".
+const ExecSignalTimeout = "timeout"
+
+// StderrSnippetBytes is how much compiler stderr Metadata.CompileStderr
+// keeps.
+const StderrSnippetBytes = 1024
+
+// StderrSnippet returns the head of stderr, at most StderrSnippetBytes
+// cut at a line break when there is one, marked when truncated.
+func StderrSnippet(stderr string) string {
+	stderr = strings.TrimSpace(stderr)
+	if len(stderr) <= StderrSnippetBytes {
+		return stderr
+	}
+	head := stderr[:StderrSnippetBytes]
+	if i := strings.LastIndexByte(head, '\n'); i > 0 {
+		head = head[:i]
+	}
+	return head + "\n...(truncated)"
+}
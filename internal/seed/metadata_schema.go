@@ -0,0 +1,97 @@
+package seed
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// MetadataSchemaVersion is the version of the metadata JSON this binary
+// writes. History:
+//
+//	0: unversioned; state and cov_incr may be missing.
+//	1: schema_version added; state and cov_incr always present.
+//	2: the deduplication hash is stored as dedup_hash (was hash, easily
+//	   confused with content_hash).
+//	3: compile_ok, compile_stderr, exec_signals and duration_ms record the
+//	   outcome of the last run; compile_ok is inferred for older seeds.
+const MetadataSchemaVersion = 3
+
+// ErrMetadataTooNew is returned for metadata written by a newer binary,
+// whose schema this one cannot read safely.
+var ErrMetadataTooNew = errors.New("metadata schema version is newer than supported")
+
+// metadataMigrations upgrades raw metadata JSON from the version it is
+// keyed by to the next one.
+var metadataMigrations = map[int]func(raw map[string]any){
+	0: func(raw map[string]any) {
+		if _, ok := raw["state"]; !ok {
+			raw["state"] = string(SeedStatePending)
+		}
+		if _, ok := raw["cov_incr"]; !ok {
+			oldCov, _ := raw["old_cov"].(float64)
+			newCov, _ := raw["new_cov"].(float64)
+			if newCov > oldCov {
+				raw["cov_incr"] = newCov - oldCov
+			} else {
+				raw["cov_incr"] = 0
+			}
+		}
+	},
+	1: func(raw map[string]any) {
+		if hash, ok := raw["hash"]; ok {
+			raw["dedup_hash"] = hash
+			delete(raw, "hash")
+		}
+	},
+	2: func(raw map[string]any) {
+		// Results used to be reported only for seeds that compiled, and
+		// validation marks the seeds that do not invalid
+		state, _ := raw["state"].(string)
+		raw["compile_ok"] = state != string(SeedStatePending) && state != string(SeedStateInvalid)
+	},
+}
+
+// decodeMetadata parses metadata JSON of any supported schema version,
+// upgrading it to MetadataSchemaVersion. It reports whether a migration
+// was applied.
+func decodeMetadata(data []byte) (*Metadata, bool, error) {
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, false, err
+	}
+
+	version := 0
+	if v, ok := raw["schema_version"]; ok {
+		f, ok := v.(float64)
+		if !ok || f < 0 || f != float64(int(f)) {
+			return nil, false, fmt.Errorf("invalid schema_version %v", v)
+		}
+		version = int(f)
+	}
+	if version > MetadataSchemaVersion {
+		return nil, false, fmt.Errorf("%w: %d > %d", ErrMetadataTooNew, version, MetadataSchemaVersion)
+	}
+
+	migrated := version < MetadataSchemaVersion
+	if migrated {
+		for ; version < MetadataSchemaVersion; version++ {
+			migrate, ok := metadataMigrations[version]
+			if !ok {
+				return nil, false, fmt.Errorf("no migration from metadata schema version %d", version)
+			}
+			migrate(raw)
+		}
+		raw["schema_version"] = MetadataSchemaVersion
+		var err error
+		if data, err = json.Marshal(raw); err != nil {
+			return nil, false, err
+		}
+	}
+
+	var meta Metadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, false, err
+	}
+	return &meta, migrated, nil
+}
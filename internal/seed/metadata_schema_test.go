@@ -0,0 +1,75 @@
+package seed
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMetadataJSON_MigratesHistoricalVersions(t *testing.T) {
+	// Version 0: no schema_version, state and cov_incr missing.
+	v0, err := LoadMetadataJSON(filepath.Join("testdata", "metadata", "id-000001.json"))
+	require.NoError(t, err)
+	assert.Equal(t, MetadataSchemaVersion, v0.SchemaVersion)
+	assert.Equal(t, uint64(1), v0.ID)
+	assert.Equal(t, SeedStatePending, v0.State)
+	assert.Equal(t, uint64(150), v0.CovIncrease)
+	assert.Equal(t, OracleVerdictNormal, v0.OracleVerdict)
+	assert.False(t, v0.CompileOK, "a pending seed has not been compiled")
+	assert.Equal(t, time.Date(2025, 3, 2, 10, 15, 0, 0, time.UTC), v0.CreatedAt.UTC())
+
+	// Version 1: the dedup hash was stored as "hash".
+	v1, err := LoadMetadataJSON(filepath.Join("testdata", "metadata", "id-000002.json"))
+	require.NoError(t, err)
+	assert.Equal(t, MetadataSchemaVersion, v1.SchemaVersion)
+	assert.Equal(t, SeedStateProcessed, v1.State)
+	assert.Equal(t, uint64(42), v1.CovIncrease)
+	assert.Equal(t, "c0ffee00c0ffee00c0ffee00c0ffee00c0ffee00c0ffee00c0ffee00c0ffee00", v1.Hash)
+	assert.Equal(t, "5e6f7a8b", v1.ContentHash)
+	assert.Equal(t, "add a second buffer", v1.MutationNote)
+	assert.True(t, v1.CompileOK, "a processed seed compiled")
+
+	// Version 2: no outcome; an invalid seed failed to compile.
+	v2, err := LoadMetadataJSON(filepath.Join("testdata", "metadata", "id-000003.json"))
+	require.NoError(t, err)
+	assert.Equal(t, SeedStateInvalid, v2.State)
+	assert.False(t, v2.CompileOK)
+	assert.Empty(t, v2.ExecSignals)
+}
+
+func TestLoadAllMetadataJSON_CountsMigrations(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"id-000001.json", "id-000002.json"} {
+		data, err := os.ReadFile(filepath.Join("testdata", "metadata", name))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0644))
+	}
+	require.NoError(t, SaveMetadataJSON(dir, &Metadata{ID: 3, State: SeedStatePending, Hash: "abc"}))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "id-000004.json"), []byte("{not json"), 0644))
+
+	metas, migrated, err := LoadAllMetadataJSON(dir)
+	require.NoError(t, err)
+	assert.Len(t, metas, 3, "the corrupt file is skipped")
+	assert.Equal(t, 2, migrated)
+
+	saved, err := os.ReadFile(filepath.Join(dir, "id-000003.json"))
+	require.NoError(t, err)
+	assert.Contains(t, string(saved), `"schema_version": 3`)
+	assert.Contains(t, string(saved), `"dedup_hash": "abc"`)
+}
+
+func TestLoadMetadataJSON_RejectsNewerVersion(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "id-000001.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"id": 1, "schema_version": 99}`), 0644))
+
+	_, err := LoadMetadataJSON(path)
+	assert.ErrorIs(t, err, ErrMetadataTooNew)
+
+	_, _, err = LoadAllMetadataJSON(dir)
+	assert.ErrorIs(t, err, ErrMetadataTooNew)
+}
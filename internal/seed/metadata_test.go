@@ -0,0 +1,18 @@
+package seed
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStderrSnippet(t *testing.T) {
+	assert.Equal(t, "error: expected ';'", StderrSnippet("\nerror: expected ';'\n"))
+
+	line := strings.Repeat("x", 99) + "\n"
+	snippet := StderrSnippet(strings.Repeat(line, 20))
+	assert.LessOrEqual(t, len(snippet), StderrSnippetBytes+len("\n...(truncated)"))
+	assert.True(t, strings.HasSuffix(snippet, "x\n...(truncated)"), "cut at a line break: %q", snippet[len(snippet)-20:])
+	assert.Equal(t, 10, strings.Count(snippet, line))
+}
@@ -0,0 +1,160 @@
+package seed
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultMinimizeMaxChecks bounds how many candidates Minimize checks
+// unless MinimizeMaxChecks says otherwise.
+const DefaultMinimizeMaxChecks = 200
+
+// ErrCheckFailed is returned by Minimize when the seed itself does not pass
+// the check.
+var ErrCheckFailed = errors.New("seed does not pass the minimization check")
+
+// MinimizeOption sets a budget of Minimize.
+type MinimizeOption func(*minimizer)
+
+// MinimizeMaxChecks caps the calls to the check, the original seed's
+// included. 0 keeps DefaultMinimizeMaxChecks.
+func MinimizeMaxChecks(n int) MinimizeOption {
+	return func(m *minimizer) {
+		if n > 0 {
+			m.maxChecks = n
+		}
+	}
+}
+
+// MinimizeTimeout stops the reduction after d; 0 means no time limit.
+func MinimizeTimeout(d time.Duration) MinimizeOption {
+	return func(m *minimizer) {
+		m.timeout = d
+	}
+}
+
+// Minimize shrinks the source of s while check keeps accepting it. It
+// removes chunks of lines, then chunks of tokens, ddmin-style: a chunk
+// whose removal still passes the check stays removed, and chunks are
+// halved when none can go. check typically compiles the candidate and
+// confirms the oracle verdict or target coverage still holds. Once the
+// budget is spent the smallest seed found so far is returned. The result
+// is a copy of s with only Content changed.
+func Minimize(s *Seed, check func(*Seed) bool, opts ...MinimizeOption) (*Seed, error) {
+	if s == nil {
+		return nil, errors.New("seed cannot be nil")
+	}
+	m := &minimizer{base: s, check: check, maxChecks: DefaultMinimizeMaxChecks}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.timeout > 0 {
+		m.deadline = time.Now().Add(m.timeout)
+	}
+
+	if !m.test(s.Content) {
+		return nil, ErrCheckFailed
+	}
+	content := m.reduce(splitLines(s.Content))
+	content = m.reduce(splitTokens(content))
+
+	minimized := *s
+	minimized.Content = content
+	return &minimized, nil
+}
+
+// minimizer holds the state of one Minimize call.
+type minimizer struct {
+	base      *Seed
+	check     func(*Seed) bool
+	maxChecks int
+	timeout   time.Duration
+	deadline  time.Time
+	checks    int
+}
+
+// exhausted reports whether the budget is spent.
+func (m *minimizer) exhausted() bool {
+	return m.checks >= m.maxChecks || !m.deadline.IsZero() && time.Now().After(m.deadline)
+}
+
+// test runs the check on the base seed with content as its source.
+func (m *minimizer) test(content string) bool {
+	if m.exhausted() {
+		return false
+	}
+	m.checks++
+	candidate := *m.base
+	candidate.Content = content
+	return m.check(&candidate)
+}
+
+// reduce removes chunks of units while the rest passes the check and
+// returns what is left, joined.
+func (m *minimizer) reduce(units []string) string {
+	n := 2
+	for len(units) >= 2 && !m.exhausted() {
+		size := (len(units) + n - 1) / n
+		reduced := false
+		for start := 0; start < len(units) && !m.exhausted(); start += size {
+			end := min(start+size, len(units))
+			candidate := slices.Concat(units[:start], units[end:])
+			if m.test(strings.Join(candidate, "")) {
+				units = candidate
+				n = max(n-1, 2)
+				reduced = true
+				break
+			}
+		}
+		if !reduced {
+			if n >= len(units) {
+				break
+			}
+			n = min(2*n, len(units))
+		}
+	}
+	return strings.Join(units, "")
+}
+
+// splitLines splits source into lines that keep their line breaks.
+func splitLines(source string) []string {
+	lines := strings.SplitAfter(source, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// splitTokens splits source into tokens (identifiers and numbers, string
+// and character literals, single punctuation bytes), each carrying the
+// whitespace before it, so joining them gives source back.
+func splitTokens(source string) []string {
+	var tokens []string
+	start := 0
+	for i := 0; i < len(source); {
+		for i < len(source) && strings.IndexByte(" \t\r\n\f\v", source[i]) >= 0 {
+			i++
+		}
+		if i == len(source) {
+			break
+		}
+		switch c := source[i]; {
+		case isWordByte(c):
+			for i < len(source) && isWordByte(source[i]) {
+				i++
+			}
+		case c == '"' || c == '\'':
+			i += literalLen(source[i:])
+		default:
+			i++
+		}
+		tokens = append(tokens, source[start:i])
+		start = i
+	}
+	if start < len(source) && len(tokens) > 0 {
+		tokens[len(tokens)-1] += source[start:]
+	}
+	return tokens
+}
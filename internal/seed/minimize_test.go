@@ -0,0 +1,70 @@
+package seed
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bugSeed is a seed whose "bug" needs only the buffer and the strcpy line,
+// padded with unrelated lines.
+func bugSeed() *Seed {
+	var b strings.Builder
+	b.WriteString("#include <string.h>\n")
+	for i := 0; i < 40; i++ {
+		fmt.Fprintf(&b, "int helper%d(int x) { return x * %d; }\n", i, i)
+	}
+	b.WriteString("int main(int argc, char **argv) {\n")
+	b.WriteString("    char buf[8];\n")
+	for i := 0; i < 40; i++ {
+		fmt.Fprintf(&b, "    int v%d = helper%d(argc);\n", i, i)
+	}
+	b.WriteString("    strcpy(buf, argv[1]);\n")
+	b.WriteString("    return 0;\n}\n")
+	return &Seed{Meta: Metadata{ID: 7}, Content: b.String(), CFlags: []string{"-O0"}}
+}
+
+// bugCheck stands in for compiling the candidate and running the oracle.
+func bugCheck(s *Seed) bool {
+	return strings.Contains(s.Content, "char buf[8]") && strings.Contains(s.Content, "strcpy(buf, argv[1])")
+}
+
+func TestMinimize(t *testing.T) {
+	t.Run("reduces lines and tokens", func(t *testing.T) {
+		s := bugSeed()
+		minimized, err := Minimize(s, bugCheck, MinimizeMaxChecks(5000))
+		require.NoError(t, err)
+		assert.True(t, bugCheck(minimized))
+		assert.Equal(t, "char buf[8]\n    strcpy(buf, argv[1])", strings.TrimSpace(minimized.Content))
+		assert.Equal(t, s.Meta, minimized.Meta)
+		assert.Equal(t, s.CFlags, minimized.CFlags)
+		assert.Contains(t, s.Content, "helper39", "the original seed is left alone")
+	})
+
+	t.Run("stops at the check budget", func(t *testing.T) {
+		checks := 0
+		check := func(s *Seed) bool {
+			checks++
+			return bugCheck(s)
+		}
+		minimized, err := Minimize(bugSeed(), check, MinimizeMaxChecks(10))
+		require.NoError(t, err)
+		assert.Equal(t, 10, checks)
+		assert.True(t, bugCheck(minimized))
+	})
+
+	t.Run("seed that fails the check", func(t *testing.T) {
+		_, err := Minimize(&Seed{Content: "int main() { return 0; }"}, bugCheck)
+		assert.ErrorIs(t, err, ErrCheckFailed)
+	})
+}
+
+func TestSplitTokens(t *testing.T) {
+	source := "int main() {\n  puts(\"a b\");\n}\n"
+	tokens := splitTokens(source)
+	assert.Equal(t, source, strings.Join(tokens, ""))
+	assert.Contains(t, tokens, `"a b"`, "literals are single tokens")
+}
@@ -0,0 +1,148 @@
+package seed
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Pool is a corpus opened by OpenPool: the metadata of every seed is in
+// memory, while each seed's source and JSON files are read only when it is
+// loaded.
+type Pool struct {
+	seeds []*Seed
+	pos   int
+}
+
+// OpenPool scans dir like LoadSeedsWithMetadata but leaves the seeds
+// unloaded: only the directory names, tags and file sizes are read. Call
+// Seed.Load before using a seed's content and Seed.Release once done with it.
+// Seeds with an empty source file are quarantined here; other corruption is
+// found, and quarantined, by Load. Both layouts are read; see Layout.
+func OpenPool(dir string, namer NamingStrategy) (*Pool, error) {
+	return openPool(dir, namer, false)
+}
+
+// OpenPoolReadOnly is OpenPool for a corpus another process may be writing:
+// nothing is quarantined. A seed with an empty source file, which may be
+// half written, is skipped, and Load of a corrupt seed only fails.
+func OpenPoolReadOnly(dir string, namer NamingStrategy) (*Pool, error) {
+	return openPool(dir, namer, true)
+}
+
+func openPool(dir string, namer NamingStrategy, readOnly bool) (*Pool, error) {
+	pool := &Pool{}
+	err := walkSeedDirs(dir, func(parent, name string) {
+		seedDir := filepath.Join(parent, name)
+		sourceFile, language, ok := findSourceFile(seedDir)
+		if !ok {
+			return
+		}
+		info, err := os.Stat(sourceFile)
+		if err != nil {
+			return
+		}
+		if info.Size() == 0 {
+			if readOnly {
+				return
+			}
+			quarantineSeedDir(parent, name, fmt.Errorf("seed %s has an empty source file", seedDir))
+			return
+		}
+		meta, err := namer.ParseFilename(name + ".seed")
+		if err != nil {
+			return
+		}
+
+		meta.Tags = loadTags(seedDir)
+		meta.FilePath, _ = filepath.Rel(dir, seedDir)
+		meta.ContentPath = sourceFile
+		meta.FileSize = info.Size()
+		meta.Compressed = IsCompressedSource(sourceFile)
+		if meta.State == "" {
+			meta.State = SeedStatePending
+		}
+		pool.seeds = append(pool.seeds, &Seed{Meta: *meta, Language: language, pooled: true, unloaded: true, readOnly: readOnly})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+// Next returns the next seed of the pool, unloaded, or false at the end.
+func (p *Pool) Next() (*Seed, bool) {
+	if p.pos >= len(p.seeds) {
+		return nil, false
+	}
+	s := p.seeds[p.pos]
+	p.pos++
+	return s, true
+}
+
+// Seeds returns every seed of the pool in directory order.
+func (p *Pool) Seeds() []*Seed {
+	return p.seeds
+}
+
+// Len returns the number of seeds in the pool.
+func (p *Pool) Len() int {
+	return len(p.seeds)
+}
+
+// Load reads the content, test cases, CFlags and flag profile of a seed
+// opened by OpenPool from its directory, unless they are already loaded.
+// A seed directory that fails the integrity check is quarantined, unless
+// the pool was opened read-only. Load does nothing for seeds that did not
+// come from a pool.
+func (s *Seed) Load() error {
+	if !s.unloaded {
+		return nil
+	}
+	seedDir := filepath.Dir(s.Meta.ContentPath)
+	source, err := os.ReadFile(s.Meta.ContentPath)
+	if err != nil {
+		return fmt.Errorf("failed to read source file %s: %w", s.Meta.ContentPath, err)
+	}
+	if source, err = decodeSource(s.Meta.ContentPath, source); err == nil {
+		err = checkSeedDir(seedDir, source)
+	}
+	if err != nil {
+		if !s.readOnly {
+			quarantineSeedDir(filepath.Dir(seedDir), filepath.Base(seedDir), err)
+		}
+		return err
+	}
+
+	testCases, cflags, flagProfile := readSeedFiles(seedDir)
+	s.Content = string(source)
+	s.TestCases = testCases
+	// CFlags and the flag profile survive Release; keep any set since.
+	if s.CFlags == nil {
+		s.CFlags = cflags
+	}
+	if s.FlagProfile == nil {
+		s.FlagProfile = flagProfile
+	}
+	s.Meta.FileSize = int64(len(source))
+	s.unloaded = false
+	return nil
+}
+
+// Release drops the content and test cases of a seed opened by OpenPool,
+// the bulk of what Load read; Load reads them again. Changes made to them
+// since loading are lost. It does nothing for seeds that did not come from a
+// pool.
+func (s *Seed) Release() {
+	if !s.pooled {
+		return
+	}
+	s.Content = ""
+	s.TestCases = nil
+	s.unloaded = true
+}
+
+// Loaded reports whether the seed's content is in memory.
+func (s *Seed) Loaded() bool {
+	return !s.unloaded
+}
@@ -0,0 +1,155 @@
+package seed
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenPool(t *testing.T) {
+	dir := t.TempDir()
+	namer := NewDefaultNamingStrategy()
+	var names []string
+	for i := 1; i <= 3; i++ {
+		s := &Seed{
+			Meta:      Metadata{ID: uint64(i), Tags: []string{"vla"}},
+			Content:   fmt.Sprintf("int main() { return %d; }", i),
+			TestCases: []TestCase{{RunningCommand: "./prog", ExpectedResult: "ok"}},
+			CFlags:    []string{"-O2"},
+		}
+		name, err := SaveSeedWithMetadata(dir, s, namer)
+		require.NoError(t, err)
+		names = append(names, name)
+	}
+	// Corrupt seeds: one with an empty source, found when opening, and one
+	// with a broken test case file, found when loading.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, names[1], "source.c"), nil, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, names[2], "testcases.json"), []byte("[{"), 0644))
+
+	pool, err := OpenPool(dir, namer)
+	require.NoError(t, err)
+	require.Equal(t, 2, pool.Len())
+	assert.NoDirExists(t, filepath.Join(dir, names[1]))
+
+	s, ok := pool.Next()
+	require.True(t, ok)
+	assert.Equal(t, uint64(1), s.Meta.ID)
+	assert.Equal(t, []string{"vla"}, s.Meta.Tags, "metadata is read eagerly")
+	assert.False(t, s.Loaded())
+	assert.Empty(t, s.Content)
+
+	require.NoError(t, s.Load())
+	assert.True(t, s.Loaded())
+	assert.Equal(t, "int main() { return 1; }", s.Content)
+	assert.Len(t, s.TestCases, 1)
+	assert.Equal(t, []string{"-O2"}, s.CFlags)
+
+	s.Release()
+	assert.False(t, s.Loaded())
+	assert.Empty(t, s.Content)
+	assert.Nil(t, s.TestCases)
+	assert.Equal(t, []string{"-O2"}, s.CFlags, "small fields survive Release")
+	require.NoError(t, s.Load())
+	assert.Equal(t, "int main() { return 1; }", s.Content)
+
+	corrupt, ok := pool.Next()
+	require.True(t, ok)
+	assert.Error(t, corrupt.Load())
+	assert.DirExists(t, filepath.Join(dir, QuarantineDir, names[2]))
+
+	_, ok = pool.Next()
+	assert.False(t, ok)
+
+	// Seeds built in memory are always loaded.
+	plain := &Seed{Content: "int main() {}"}
+	require.NoError(t, plain.Load())
+	plain.Release()
+	assert.True(t, plain.Loaded())
+	assert.Equal(t, "int main() {}", plain.Content)
+}
+
+func TestOpenPoolReadOnly(t *testing.T) {
+	dir := t.TempDir()
+	namer := NewDefaultNamingStrategy()
+	var names []string
+	for i := 1; i <= 2; i++ {
+		s := &Seed{Meta: Metadata{ID: uint64(i)}, Content: fmt.Sprintf("int main() { return %d; }", i)}
+		name, err := SaveSeedWithMetadata(dir, s, namer)
+		require.NoError(t, err)
+		names = append(names, name)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, names[0], "source.c"), nil, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, names[1], "testcases.json"), []byte("[{"), 0644))
+
+	pool, err := OpenPoolReadOnly(dir, namer)
+	require.NoError(t, err)
+	require.Equal(t, 1, pool.Len(), "the seed with an empty source is skipped")
+	s, _ := pool.Next()
+	assert.Error(t, s.Load())
+	assert.DirExists(t, filepath.Join(dir, names[0]))
+	assert.DirExists(t, filepath.Join(dir, names[1]))
+	assert.NoDirExists(t, filepath.Join(dir, QuarantineDir))
+}
+
+// benchmarkCorpus writes n seeds of about 16 KB each.
+func benchmarkCorpus(b *testing.B, n int) string {
+	b.Helper()
+	dir := b.TempDir()
+	namer := NewDefaultNamingStrategy()
+	body := strings.Repeat("    buf[0] = 'A';\n", 1000)
+	for i := 1; i <= n; i++ {
+		s := &Seed{Meta: Metadata{ID: uint64(i)}, Content: fmt.Sprintf("int main() {\n    char buf[%d];\n%s}\n", i, body)}
+		if _, err := SaveSeedWithMetadata(dir, s, namer); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// reportRetainedHeap reports the live heap left after open, which returns
+// what it loaded so it stays reachable.
+func reportRetainedHeap(b *testing.B, open func() any) {
+	var before, after runtime.MemStats
+	var kept any
+	for i := 0; i < b.N; i++ {
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		kept = open()
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+	}
+	b.ReportMetric(float64(after.HeapAlloc-min(before.HeapAlloc, after.HeapAlloc))/(1<<20), "MB-retained")
+	runtime.KeepAlive(kept)
+}
+
+func BenchmarkLoadSeedsWithMetadata(b *testing.B) {
+	dir := benchmarkCorpus(b, 500)
+	namer := NewDefaultNamingStrategy()
+	b.ResetTimer()
+	reportRetainedHeap(b, func() any {
+		seeds, err := LoadSeedsWithMetadata(dir, namer)
+		if err != nil {
+			b.Fatal(err)
+		}
+		return seeds
+	})
+}
+
+func BenchmarkOpenPool(b *testing.B) {
+	dir := benchmarkCorpus(b, 500)
+	namer := NewDefaultNamingStrategy()
+	b.ResetTimer()
+	reportRetainedHeap(b, func() any {
+		pool, err := OpenPool(dir, namer)
+		if err != nil {
+			b.Fatal(err)
+		}
+		return pool
+	})
+}
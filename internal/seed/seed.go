@@ -1,20 +1,106 @@
 package seed
 
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
 // TestCase represents a single execution command and its expected outcome.
 type TestCase struct {
 	RunningCommand string `json:"running command"`
 	ExpectedResult string `json:"expected result"`
+	// Stdin is fed to the program's standard input; empty means none.
+	// It is omitted from JSON when empty, so seeds saved before it existed
+	// load unchanged.
+	Stdin string `json:"stdin,omitempty"`
+	// TimeoutSeconds limits this case's run; 0 uses the executor's timeout.
+	TimeoutSeconds int `json:"timeout seconds,omitempty"`
+	// Env holds extra environment variables for this case's run.
+	Env map[string]string `json:"env,omitempty"`
 }
 
 // Seed represents a single test case for the fuzzer.
 // It contains the source code and a set of test cases.
 type Seed struct {
 	Meta             Metadata     // Metadata for lineage tracking and resume
-	Content          string       // C source code (source.c)
+	Content          string       // Source code (source.c, source.cpp or source.rs)
+	Language         Language     // Source language; empty means C
 	TestCases        []TestCase   // Test cases with running commands and expected results
 	CFlags           []string     // Additional compiler flags specified by LLM
 	FlagProfile      *FlagProfile // Selected compiler flag profile for this seed
 	AppliedLLMCFlags []string     // LLM flags that survived conflict filtering for this compile
 	DroppedLLMCFlags []string     // LLM flags removed due to profile conflicts for this compile
 	LLMCFlagsApplied bool         // Whether CFlags were actually applied during compilation
+
+	// pooled marks a seed opened by OpenPool; unloaded says its content
+	// and test cases are on disk only. See Load. readOnly marks one opened
+	// by OpenPoolReadOnly.
+	pooled, unloaded, readOnly bool
+}
+
+// EncodeTestCases renders test cases as indented JSON using the
+// "running command" / "expected result" keys. Quotes, backslashes and
+// newlines are escaped so the output can be embedded in prompts verbatim.
+func EncodeTestCases(testCases []TestCase) (string, error) {
+	if testCases == nil {
+		testCases = []TestCase{}
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false) // keep <, > and & readable for shell redirections
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(testCases); err != nil {
+		return "", err
+	}
+	return strings.TrimRight(buf.String(), "\n"), nil
+}
+
+// DecodeTestCases parses test cases produced by EncodeTestCases or by an LLM
+// following the same format. A surrounding ```json fence, a lead-in label
+// before the opening bracket (which may itself contain brackets, as in
+// "Test cases [2 total]:") and any text after the JSON array are ignored:
+// exactly one JSON value is read.
+func DecodeTestCases(data string) ([]TestCase, error) {
+	data = strings.TrimSpace(data)
+	if strings.HasPrefix(data, "```") {
+		if nl := strings.IndexByte(data, '\n'); nl >= 0 {
+			data = data[nl+1:]
+		} else {
+			data = ""
+		}
+	}
+
+	var firstErr error
+	for _, start := range testCaseArrayStarts(data) {
+		var testCases []TestCase
+		if err := json.NewDecoder(strings.NewReader(data[start:])).Decode(&testCases); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		return testCases, nil
+	}
+	return nil, firstErr
+}
+
+// testCaseArrayStarts returns where the test case array may start in data:
+// the first '[' and every later line that starts with one. Without any '['
+// it returns 0, so decoding reports what is there instead.
+func testCaseArrayStarts(data string) []int {
+	first := strings.IndexByte(data, '[')
+	if first < 0 {
+		return []int{0}
+	}
+	starts := []int{first}
+	offset := 0
+	for _, line := range strings.SplitAfter(data, "\n") {
+		if idx := offset + len(line) - len(strings.TrimLeft(line, " \t")); idx > first && strings.HasPrefix(data[idx:], "[") {
+			starts = append(starts, idx)
+		}
+		offset += len(line)
+	}
+	return starts
 }
@@ -1,6 +1,7 @@
 package seed
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
 	"strings"
@@ -8,6 +9,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/zjy-dev/de-fuzz/internal/logger"
 )
 
 func TestStorage(t *testing.T) {
@@ -25,6 +28,25 @@ func TestStorage(t *testing.T) {
 		assert.Equal(t, content, loadedContent)
 	})
 
+	t.Run("should save and load the compressed understanding", func(t *testing.T) {
+		dir := filepath.Join(basePath, "compressed")
+		_, err := LoadCompressedUnderstanding(dir, "full understanding")
+		assert.Error(t, err)
+
+		require.NoError(t, SaveCompressedUnderstanding(dir, "full understanding", "short"))
+		full, err := LoadUnderstanding(dir)
+		require.NoError(t, err)
+		assert.Equal(t, "full understanding", full)
+		compressed, err := LoadCompressedUnderstanding(dir, "full understanding")
+		require.NoError(t, err)
+		assert.Equal(t, "short", compressed)
+
+		// Stale for any other understanding in use, even with
+		// understanding.md unchanged on disk.
+		_, err = LoadCompressedUnderstanding(dir, "edited understanding")
+		assert.ErrorIs(t, err, ErrStaleUnderstanding)
+	})
+
 	t.Run("should save and load a single seed", func(t *testing.T) {
 		testCases := []TestCase{
 			{RunningCommand: "./prog", ExpectedResult: "success"},
@@ -52,6 +74,34 @@ func TestStorage(t *testing.T) {
 		assert.Contains(t, string(content), "int main() { return 0; }")
 	})
 
+	t.Run("should save and load seeds in other languages", func(t *testing.T) {
+		namer := NewDefaultNamingStrategy()
+		for _, lang := range []Language{LanguageCPP, LanguageRust} {
+			s := &Seed{
+				Meta:     Metadata{ID: 42},
+				Content:  "// " + string(lang) + " seed",
+				Language: lang,
+			}
+			dir := t.TempDir()
+			filename, err := SaveSeedWithMetadata(dir, s, namer)
+			require.NoError(t, err)
+
+			seedDir := filepath.Join(dir, strings.TrimSuffix(filename, ".seed"))
+			assert.FileExists(t, filepath.Join(seedDir, lang.SourceFileName()))
+			assert.NoFileExists(t, filepath.Join(seedDir, "source.c"))
+
+			loaded, err := LoadSeedWithMetadata(seedDir, namer)
+			require.NoError(t, err)
+			assert.Equal(t, lang, loaded.Language)
+			assert.Equal(t, s.Content, loaded.Content)
+
+			all, err := LoadSeedsWithMetadata(dir, namer)
+			require.NoError(t, err)
+			require.Len(t, all, 1)
+			assert.Equal(t, lang, all[0].Language)
+		}
+	})
+
 	t.Run("should save and load different seeds", func(t *testing.T) {
 		// Clear and recreate the directory
 		os.RemoveAll(basePath)
@@ -126,3 +176,50 @@ func TestStorage(t *testing.T) {
 		assert.Equal(t, 0, len(seeds))
 	})
 }
+
+func TestLoadSeedsWithMetadata_QuarantinesCorruptSeeds(t *testing.T) {
+	var logs bytes.Buffer
+	logger.SetOutput(&logs)
+	t.Cleanup(func() { logger.SetOutput(os.Stdout) })
+
+	dir := t.TempDir()
+	namer := NewDefaultNamingStrategy()
+	var names []string
+	for id := uint64(1); id <= 3; id++ {
+		name, err := SaveSeedWithMetadata(dir, &Seed{
+			Meta:      Metadata{ID: id},
+			Content:   "int main() { return 0; }",
+			TestCases: []TestCase{{RunningCommand: "./prog", ExpectedResult: "0"}},
+		}, namer)
+		require.NoError(t, err)
+		names = append(names, name)
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, names[0]))
+	require.NoError(t, err)
+	for _, entry := range entries {
+		assert.NotContains(t, entry.Name(), ".tmp-", "no temp files should be left behind")
+	}
+
+	// Simulate crashes mid-write: a truncated source and truncated JSON.
+	require.NoError(t, os.Truncate(filepath.Join(dir, names[1], "source.c"), 0))
+	require.NoError(t, os.Truncate(filepath.Join(dir, names[2], "testcases.json"), 10))
+
+	seeds, err := LoadSeedsWithMetadata(dir, namer)
+	require.NoError(t, err)
+	require.Len(t, seeds, 1)
+	assert.Equal(t, uint64(1), seeds[0].Meta.ID)
+
+	for _, name := range names[1:] {
+		assert.NoDirExists(t, filepath.Join(dir, name))
+		assert.DirExists(t, filepath.Join(dir, QuarantineDir, name))
+	}
+	assert.Equal(t, 2, strings.Count(logs.String(), "Quarantined corrupt seed"))
+
+	// The quarantine is not scanned again, and a single load reports the
+	// corruption instead of returning garbage.
+	seeds, err = LoadSeedsWithMetadata(dir, namer)
+	require.NoError(t, err)
+	assert.Len(t, seeds, 1)
+	_, err = LoadSeedWithMetadata(filepath.Join(dir, QuarantineDir, names[2]), namer)
+	assert.ErrorContains(t, err, "corrupt testcases.json")
+}
@@ -1,19 +1,33 @@
 package seed
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/zjy-dev/de-fuzz/internal/fsutil"
+	"github.com/zjy-dev/de-fuzz/internal/logger"
 )
 
 const (
-	understandingFile = "understanding.md"
-	flagProfileFile   = "flag_profile.json"
+	understandingFile           = "understanding.md"
+	compressedUnderstandingFile = "understanding.compressed.md"
+	flagProfileFile             = "flag_profile.json"
+	tagsFile                    = "tags.json"
+	// QuarantineDir is where LoadSeedsWithMetadata moves seed directories
+	// that fail the integrity check, inside the directory it scans.
+	QuarantineDir = ".quarantine"
+	// TestCaseSeparator is the default marker between code and JSON test
+	// cases in LLM responses.
+	TestCaseSeparator = "// ||||| JSON_TESTCASES_START |||||"
 	// Separator defines the boundary between C source code and JSON test cases.
 	// Exported for use by other packages.
-	Separator = "\n// ||||| JSON_TESTCASES_START |||||\n"
+	Separator = "\n" + TestCaseSeparator + "\n"
 )
 
 // GetUnderstandingPath returns the full path to the understanding.md file.
@@ -21,13 +35,18 @@ func GetUnderstandingPath(basePath string) string {
 	return filepath.Join(basePath, understandingFile)
 }
 
-// SaveUnderstanding saves the LLM's understanding to a file.
+// SaveUnderstanding saves the LLM's understanding to a file, and a copy of
+// it to the understanding history.
 func SaveUnderstanding(basePath, content string) error {
 	if err := os.MkdirAll(basePath, 0755); err != nil {
 		return fmt.Errorf("failed to create base path %s: %w", basePath, err)
 	}
 	filePath := GetUnderstandingPath(basePath)
-	return os.WriteFile(filePath, []byte(content), 0644)
+	if err := fsutil.WriteFileAtomic(filePath, []byte(content)); err != nil {
+		return err
+	}
+	_, err := RecordUnderstanding(basePath, content, "", 0)
+	return err
 }
 
 // LoadUnderstanding loads the LLM's understanding from a file.
@@ -40,6 +59,50 @@ func LoadUnderstanding(basePath string) (string, error) {
 	return string(content), nil
 }
 
+// ErrStaleUnderstanding is returned by LoadCompressedUnderstanding when the
+// compressed understanding was made from a different understanding.
+var ErrStaleUnderstanding = errors.New("compressed understanding is stale")
+
+// GetCompressedUnderstandingPath returns the full path to the compressed
+// understanding file.
+func GetCompressedUnderstandingPath(basePath string) string {
+	return filepath.Join(basePath, compressedUnderstandingFile)
+}
+
+// compressedUnderstandingHeader ties a compressed understanding to the full
+// text it was made from.
+func compressedUnderstandingHeader(full string) string {
+	sum := sha256.Sum256([]byte(full))
+	return "<!-- compressed from understanding.md " + hex.EncodeToString(sum[:]) + " -->\n"
+}
+
+// SaveCompressedUnderstanding saves full as the understanding and compressed
+// as its compressed version next to it.
+func SaveCompressedUnderstanding(basePath, full, compressed string) error {
+	if err := SaveUnderstanding(basePath, full); err != nil {
+		return err
+	}
+	filePath := GetCompressedUnderstandingPath(basePath)
+	return fsutil.WriteFileAtomic(filePath, []byte(compressedUnderstandingHeader(full)+compressed))
+}
+
+// LoadCompressedUnderstanding loads the compressed version of full, the
+// understanding in use. It fails with ErrStaleUnderstanding when the
+// compressed version saved was made from another text, e.g. before
+// understanding.md was edited.
+func LoadCompressedUnderstanding(basePath, full string) (string, error) {
+	filePath := GetCompressedUnderstandingPath(basePath)
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read compressed understanding file %s: %w", filePath, err)
+	}
+	compressed, ok := strings.CutPrefix(string(content), compressedUnderstandingHeader(full))
+	if !ok {
+		return "", fmt.Errorf("%s: %w", filePath, ErrStaleUnderstanding)
+	}
+	return compressed, nil
+}
+
 // SaveSeedWithMetadata saves a seed using the specified naming strategy.
 // It saves the seed content to a separate source.c file and returns the generated directory name.
 // The metadata's ContentPath field will be updated to point to the source.c file.
@@ -58,9 +121,23 @@ func SaveSeedWithMetadata(dir string, s *Seed, namer NamingStrategy) (string, er
 		return "", fmt.Errorf("failed to create seed directory %s: %w", seedDir, err)
 	}
 
-	// Save source code to source.c
-	sourceFile := filepath.Join(seedDir, "source.c")
-	if err := os.WriteFile(sourceFile, []byte(s.Content), 0644); err != nil {
+	// Save source code to source.c (source.cpp / source.rs for other
+	// languages), gzipped to source.c.gz when Meta.Compressed is set. The
+	// other variant is removed so it cannot shadow this one.
+	sourceFile := filepath.Join(seedDir, s.Language.SourceFileName())
+	data := []byte(s.Content)
+	if s.Meta.Compressed {
+		compressed, err := compressSource(s.Content)
+		if err != nil {
+			return "", fmt.Errorf("failed to compress source of seed %d: %w", s.Meta.ID, err)
+		}
+		os.Remove(sourceFile)
+		sourceFile += CompressedSuffix
+		data = compressed
+	} else {
+		os.Remove(sourceFile + CompressedSuffix)
+	}
+	if err := fsutil.WriteFileAtomic(sourceFile, data); err != nil {
 		return "", fmt.Errorf("failed to write source file %s: %w", sourceFile, err)
 	}
 
@@ -71,7 +148,7 @@ func SaveSeedWithMetadata(dir string, s *Seed, namer NamingStrategy) (string, er
 			return "", fmt.Errorf("failed to marshal test cases: %w", err)
 		}
 		testCasesFile := filepath.Join(seedDir, "testcases.json")
-		if err := os.WriteFile(testCasesFile, jsonData, 0644); err != nil {
+		if err := fsutil.WriteFileAtomic(testCasesFile, jsonData); err != nil {
 			return "", fmt.Errorf("failed to write test cases file %s: %w", testCasesFile, err)
 		}
 	}
@@ -83,11 +160,23 @@ func SaveSeedWithMetadata(dir string, s *Seed, namer NamingStrategy) (string, er
 			return "", fmt.Errorf("failed to marshal cflags: %w", err)
 		}
 		cflagsFile := filepath.Join(seedDir, "cflags.json")
-		if err := os.WriteFile(cflagsFile, jsonData, 0644); err != nil {
+		if err := fsutil.WriteFileAtomic(cflagsFile, jsonData); err != nil {
 			return "", fmt.Errorf("failed to write cflags file %s: %w", cflagsFile, err)
 		}
 	}
 
+	// Save the tags to tags.json if there are any
+	if len(s.Meta.Tags) > 0 {
+		jsonData, err := json.MarshalIndent(s.Meta.Tags, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal tags: %w", err)
+		}
+		tagsPath := filepath.Join(seedDir, tagsFile)
+		if err := fsutil.WriteFileAtomic(tagsPath, jsonData); err != nil {
+			return "", fmt.Errorf("failed to write tags file %s: %w", tagsPath, err)
+		}
+	}
+
 	// Save selected flag profile when present so resumed runs preserve compile semantics.
 	if s.FlagProfile != nil {
 		jsonData, err := json.MarshalIndent(s.FlagProfile, "", "  ")
@@ -95,14 +184,14 @@ func SaveSeedWithMetadata(dir string, s *Seed, namer NamingStrategy) (string, er
 			return "", fmt.Errorf("failed to marshal flag profile: %w", err)
 		}
 		profileFile := filepath.Join(seedDir, flagProfileFile)
-		if err := os.WriteFile(profileFile, jsonData, 0644); err != nil {
+		if err := fsutil.WriteFileAtomic(profileFile, jsonData); err != nil {
 			return "", fmt.Errorf("failed to write flag profile file %s: %w", profileFile, err)
 		}
 	}
 
 	// Update metadata - use directory name (without .seed extension)
 	s.Meta.FilePath = seedDirName
-	s.Meta.ContentPath = sourceFile // Store absolute path to source.c
+	s.Meta.ContentPath = sourceFile // Store absolute path to source.c (or source.c.gz)
 	s.Meta.FileSize = int64(len(s.Content))
 	s.Meta.ContentHash = GenerateContentHash(s.Content)
 
@@ -138,44 +227,29 @@ func loadSeedFromDirectory(seedDir, dirName string, namer NamingStrategy) (*Seed
 	}
 
 	// Read source code
-	sourceFile := filepath.Join(seedDir, "source.c")
+	sourceFile, language, ok := findSourceFile(seedDir)
+	if !ok {
+		return nil, fmt.Errorf("no source file found in %s", seedDir)
+	}
 	sourceBytes, err := os.ReadFile(sourceFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read source file %s: %w", sourceFile, err)
 	}
-
-	// Read test cases if they exist
-	var testCases []TestCase
-	testCasesFile := filepath.Join(seedDir, "testcases.json")
-	if data, err := os.ReadFile(testCasesFile); err == nil {
-		if err := json.Unmarshal(data, &testCases); err != nil {
-			// Log warning but don't fail - testcases are optional
-		}
+	if sourceBytes, err = decodeSource(sourceFile, sourceBytes); err != nil {
+		return nil, err
 	}
-
-	// Read CFlags if they exist
-	var cflags []string
-	cflagsFile := filepath.Join(seedDir, "cflags.json")
-	if data, err := os.ReadFile(cflagsFile); err == nil {
-		if err := json.Unmarshal(data, &cflags); err != nil {
-			// Log warning but don't fail - cflags are optional
-		}
+	if err := checkSeedDir(seedDir, sourceBytes); err != nil {
+		return nil, err
 	}
 
-	// Read selected flag profile if it exists
-	var flagProfile *FlagProfile
-	profileFile := filepath.Join(seedDir, flagProfileFile)
-	if data, err := os.ReadFile(profileFile); err == nil {
-		flagProfile = &FlagProfile{}
-		if err := json.Unmarshal(data, flagProfile); err != nil {
-			flagProfile = nil
-		}
-	}
+	testCases, cflags, flagProfile := readSeedFiles(seedDir)
 
 	// Update metadata
+	meta.Tags = loadTags(seedDir)
 	meta.FilePath = dirName
 	meta.ContentPath = sourceFile
 	meta.FileSize = int64(len(sourceBytes))
+	meta.Compressed = IsCompressedSource(sourceFile)
 
 	if meta.State == "" {
 		meta.State = SeedStatePending
@@ -184,6 +258,7 @@ func loadSeedFromDirectory(seedDir, dirName string, namer NamingStrategy) (*Seed
 	return &Seed{
 		Meta:        *meta,
 		Content:     string(sourceBytes),
+		Language:    language,
 		TestCases:   testCases,
 		CFlags:      cflags,
 		FlagProfile: flagProfile,
@@ -191,70 +266,50 @@ func loadSeedFromDirectory(seedDir, dirName string, namer NamingStrategy) (*Seed
 }
 
 // LoadSeedsWithMetadata scans a directory and loads all seeds with their metadata.
+// Seed directories failing the integrity check (an empty source file or a
+// JSON file that does not parse, as a crash mid-write leaves them) are
+// moved to QuarantineDir with a warning instead of being loaded. Both
+// layouts are read; see Layout.
 func LoadSeedsWithMetadata(dir string, namer NamingStrategy) ([]*Seed, error) {
 	var seeds []*Seed
 
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return seeds, nil
-		}
-		return nil, fmt.Errorf("failed to read directory %s: %w", dir, err)
-	}
-
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-
-		seedDir := filepath.Join(dir, entry.Name())
+	err := walkSeedDirs(dir, func(parent, name string) {
+		seedDir := filepath.Join(parent, name)
 
-		// Check if source.c exists
-		sourceFile := filepath.Join(seedDir, "source.c")
-		if _, err := os.Stat(sourceFile); err != nil {
-			continue // Not a valid seed directory
+		// Check if a source file exists
+		sourceFile, language, ok := findSourceFile(seedDir)
+		if !ok {
+			return // Not a valid seed directory
 		}
 
 		// Read source code
 		sourceBytes, err := os.ReadFile(sourceFile)
 		if err != nil {
-			continue
+			return
 		}
-
-		// Try to parse metadata from directory name
-		meta, err := namer.ParseFilename(entry.Name() + ".seed")
-		if err != nil {
-			continue
+		if sourceBytes, err = decodeSource(sourceFile, sourceBytes); err != nil {
+			quarantineSeedDir(parent, name, err)
+			return
 		}
-
-		// Read test cases if they exist
-		var testCases []TestCase
-		testCasesFile := filepath.Join(seedDir, "testcases.json")
-		if data, err := os.ReadFile(testCasesFile); err == nil {
-			json.Unmarshal(data, &testCases)
+		if err := checkSeedDir(seedDir, sourceBytes); err != nil {
+			quarantineSeedDir(parent, name, err)
+			return
 		}
 
-		// Read CFlags if they exist
-		var cflags []string
-		cflagsFile := filepath.Join(seedDir, "cflags.json")
-		if data, err := os.ReadFile(cflagsFile); err == nil {
-			json.Unmarshal(data, &cflags)
+		// Try to parse metadata from directory name
+		meta, err := namer.ParseFilename(name + ".seed")
+		if err != nil {
+			return
 		}
 
-		// Read selected flag profile if it exists.
-		var flagProfile *FlagProfile
-		profileFile := filepath.Join(seedDir, flagProfileFile)
-		if data, err := os.ReadFile(profileFile); err == nil {
-			flagProfile = &FlagProfile{}
-			if err := json.Unmarshal(data, flagProfile); err != nil {
-				flagProfile = nil
-			}
-		}
+		testCases, cflags, flagProfile := readSeedFiles(seedDir)
 
 		// Update metadata
-		meta.FilePath = entry.Name()
+		meta.Tags = loadTags(seedDir)
+		meta.FilePath, _ = filepath.Rel(dir, seedDir)
 		meta.ContentPath = sourceFile
 		meta.FileSize = int64(len(sourceBytes))
+		meta.Compressed = IsCompressedSource(sourceFile)
 
 		if meta.State == "" {
 			meta.State = SeedStatePending
@@ -263,15 +318,91 @@ func LoadSeedsWithMetadata(dir string, namer NamingStrategy) ([]*Seed, error) {
 		seeds = append(seeds, &Seed{
 			Meta:        *meta,
 			Content:     string(sourceBytes),
+			Language:    language,
 			TestCases:   testCases,
 			CFlags:      cflags,
 			FlagProfile: flagProfile,
 		})
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return seeds, nil
 }
 
+// readSeedFiles reads the optional test cases, CFlags and flag profile of
+// the seed in seedDir. A missing or unreadable file leaves its value empty.
+func readSeedFiles(seedDir string) ([]TestCase, []string, *FlagProfile) {
+	var testCases []TestCase
+	if data, err := os.ReadFile(filepath.Join(seedDir, "testcases.json")); err == nil {
+		json.Unmarshal(data, &testCases)
+	}
+
+	var cflags []string
+	if data, err := os.ReadFile(filepath.Join(seedDir, "cflags.json")); err == nil {
+		json.Unmarshal(data, &cflags)
+	}
+
+	var flagProfile *FlagProfile
+	if data, err := os.ReadFile(filepath.Join(seedDir, flagProfileFile)); err == nil {
+		flagProfile = &FlagProfile{}
+		if err := json.Unmarshal(data, flagProfile); err != nil {
+			flagProfile = nil
+		}
+	}
+	return testCases, cflags, flagProfile
+}
+
+// loadTags reads the tags saved in seedDir, if any.
+func loadTags(seedDir string) []string {
+	var tags []string
+	if data, err := os.ReadFile(filepath.Join(seedDir, tagsFile)); err == nil {
+		json.Unmarshal(data, &tags)
+	}
+	return tags
+}
+
+// seedJSONFiles are the JSON files a seed directory may hold.
+var seedJSONFiles = []string{"testcases.json", "cflags.json", tagsFile, flagProfileFile, compilationRecordFile}
+
+// checkSeedDir is the integrity check of a seed directory whose source file
+// holds source: the source must not be empty and every JSON file present
+// must parse.
+func checkSeedDir(seedDir string, source []byte) error {
+	if len(source) == 0 {
+		return fmt.Errorf("seed %s has an empty source file", seedDir)
+	}
+	for _, name := range seedJSONFiles {
+		data, err := os.ReadFile(filepath.Join(seedDir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s in seed %s: %w", name, seedDir, err)
+		}
+		if !json.Valid(data) {
+			return fmt.Errorf("seed %s has a corrupt %s (%d bytes)", seedDir, name, len(data))
+		}
+	}
+	return nil
+}
+
+// quarantineSeedDir moves the corrupt seed directory name out of dir into
+// QuarantineDir.
+func quarantineSeedDir(dir, name string, cause error) {
+	target := filepath.Join(dir, QuarantineDir, name)
+	err := os.MkdirAll(filepath.Dir(target), 0755)
+	if err == nil {
+		err = os.Rename(filepath.Join(dir, name), target)
+	}
+	if err != nil {
+		logger.Warn("Skipping corrupt seed (%v); could not quarantine it: %v", cause, err)
+		return
+	}
+	logger.Warn("Quarantined corrupt seed in %s: %v", target, cause)
+}
+
 // SaveMetadataJSON saves the metadata as a JSON file.
 // The filename is id-XXXXXX.json (e.g., id-000001.json).
 func SaveMetadataJSON(dir string, meta *Metadata) error {
@@ -283,44 +414,56 @@ func SaveMetadataJSON(dir string, meta *Metadata) error {
 	filename := fmt.Sprintf("id-%06d.json", meta.ID)
 	filePath := filepath.Join(dir, filename)
 
-	// Marshal metadata to JSON
-	jsonData, err := json.MarshalIndent(meta, "", "  ")
+	// Marshal metadata to JSON, stamped with the current schema
+	stamped := *meta
+	stamped.SchemaVersion = MetadataSchemaVersion
+	jsonData, err := json.MarshalIndent(&stamped, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal metadata: %w", err)
 	}
 
-	if err := os.WriteFile(filePath, jsonData, 0644); err != nil {
+	if err := fsutil.WriteFileAtomic(filePath, jsonData); err != nil {
 		return fmt.Errorf("failed to write metadata file %s: %w", filePath, err)
 	}
 
 	return nil
 }
 
-// LoadMetadataJSON loads a metadata JSON file.
+// LoadMetadataJSON loads a metadata JSON file, migrating older schema
+// versions to MetadataSchemaVersion. Files from a newer schema fail with
+// ErrMetadataTooNew.
 func LoadMetadataJSON(filePath string) (*Metadata, error) {
+	meta, _, err := loadMetadataJSON(filePath)
+	return meta, err
+}
+
+func loadMetadataJSON(filePath string) (*Metadata, bool, error) {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read metadata file %s: %w", filePath, err)
+		return nil, false, fmt.Errorf("failed to read metadata file %s: %w", filePath, err)
 	}
 
-	var meta Metadata
-	if err := json.Unmarshal(data, &meta); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+	meta, migrated, err := decodeMetadata(data)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal metadata %s: %w", filePath, err)
 	}
 
-	return &meta, nil
+	return meta, migrated, nil
 }
 
-// LoadAllMetadataJSON loads all metadata JSON files from a directory.
-func LoadAllMetadataJSON(dir string) ([]*Metadata, error) {
+// LoadAllMetadataJSON loads all metadata JSON files from a directory and
+// reports how many of them were migrated from an older schema version.
+// Unreadable files are skipped, but a file from a newer schema is an error.
+func LoadAllMetadataJSON(dir string) ([]*Metadata, int, error) {
 	var metas []*Metadata
+	migrated := 0
 
 	entries, err := os.ReadDir(dir)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return metas, nil
+			return metas, 0, nil
 		}
-		return nil, fmt.Errorf("failed to read directory %s: %w", dir, err)
+		return nil, 0, fmt.Errorf("failed to read directory %s: %w", dir, err)
 	}
 
 	for _, entry := range entries {
@@ -334,14 +477,20 @@ func LoadAllMetadataJSON(dir string) ([]*Metadata, error) {
 		}
 
 		filePath := filepath.Join(dir, filename)
-		meta, err := LoadMetadataJSON(filePath)
+		meta, wasMigrated, err := loadMetadataJSON(filePath)
+		if errors.Is(err, ErrMetadataTooNew) {
+			return nil, 0, err
+		}
 		if err != nil {
 			// Skip invalid files
 			continue
 		}
+		if wasMigrated {
+			migrated++
+		}
 
 		metas = append(metas, meta)
 	}
 
-	return metas, nil
+	return metas, migrated, nil
 }
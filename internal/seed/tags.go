@@ -0,0 +1,70 @@
+package seed
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+// TagsMarker starts the line on which a delimiter-format response lists the
+// vulnerability patterns its seed exercises, as a JSON array:
+//
+//	// TAGS: ["vla", "alloca"]
+//
+// The line is a C comment, so it does no harm when left in the code.
+const TagsMarker = "// TAGS:"
+
+// NormalizeTags lowercases tags and turns inner spaces and underscores into
+// dashes ("Format String" -> "format-string"), dropping empty and repeated
+// tags. The order is kept. It returns nil when no tag is left.
+func NormalizeTags(tags []string) []string {
+	var normalized []string
+	for _, tag := range tags {
+		tag = strings.Join(strings.Fields(strings.ToLower(strings.ReplaceAll(tag, "_", " "))), "-")
+		if tag != "" && !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized
+}
+
+// ParseTagsFromResponse extracts the tags from the first TagsMarker line of
+// an LLM response, normalized. Tags are optional: a missing or malformed
+// line yields nil.
+func ParseTagsFromResponse(response string) []string {
+	line, _, ok := findTagsLine(response)
+	if !ok {
+		return nil
+	}
+	var tags []string
+	if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, TagsMarker))), &tags); err != nil {
+		return nil
+	}
+	return NormalizeTags(tags)
+}
+
+// ExtractCodeWithoutTags removes the TagsMarker line from the response.
+func ExtractCodeWithoutTags(response string) string {
+	_, start, ok := findTagsLine(response)
+	if !ok {
+		return response
+	}
+	end := len(response)
+	if nl := strings.IndexByte(response[start:], '\n'); nl >= 0 {
+		end = start + nl + 1
+	}
+	return response[:start] + response[end:]
+}
+
+// findTagsLine returns the trimmed first line starting with TagsMarker and
+// its offset in response.
+func findTagsLine(response string) (string, int, bool) {
+	offset := 0
+	for _, line := range strings.SplitAfter(response, "\n") {
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, TagsMarker) {
+			return trimmed, offset, true
+		}
+		offset += len(line)
+	}
+	return "", 0, false
+}
@@ -0,0 +1,43 @@
+package seed
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	assert.Equal(t, []string{"vla", "fmt-string", "alloca"},
+		NormalizeTags([]string{" VLA ", "fmt_string", "Fmt String", "", "alloca"}))
+	assert.Nil(t, NormalizeTags([]string{" ", ""}))
+}
+
+func TestParseTagsFromResponse(t *testing.T) {
+	response := "```c\n// TAGS: [\"longjmp\", \"VLA\"]\nvoid seed(int n) {}\n```"
+	assert.Equal(t, []string{"longjmp", "vla"}, ParseTagsFromResponse(response))
+	assert.Equal(t, "```c\nvoid seed(int n) {}\n```", ExtractCodeWithoutTags(response))
+
+	assert.Nil(t, ParseTagsFromResponse("void seed(int n) {}"))
+	assert.Nil(t, ParseTagsFromResponse("// TAGS: vla, alloca\nvoid seed(int n) {}"), "tags must be a JSON array")
+	assert.Equal(t, "void seed(int n) {}", ExtractCodeWithoutTags("void seed(int n) {}"))
+}
+
+func TestSeedTagsPersistence(t *testing.T) {
+	dir := t.TempDir()
+	namer := NewDefaultNamingStrategy()
+	s := &Seed{Meta: Metadata{ID: 1, Tags: []string{"vla", "alloca"}}, Content: "int main() { return 0; }"}
+	name, err := SaveSeedWithMetadata(dir, s, namer)
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, name, tagsFile))
+
+	loaded, err := LoadSeedWithMetadata(filepath.Join(dir, name), namer)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vla", "alloca"}, loaded.Meta.Tags)
+
+	all, err := LoadSeedsWithMetadata(dir, namer)
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	assert.Equal(t, []string{"vla", "alloca"}, all[0].Meta.Tags)
+}
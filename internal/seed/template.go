@@ -2,6 +2,7 @@ package seed
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 )
 
@@ -11,10 +12,37 @@ const (
 	FunctionPlaceholder = "// FUNCTION_PLACEHOLDER:"
 )
 
+// TemplateSlot is one FUNCTION_PLACEHOLDER in a template.
+// A placeholder is written either as "name" or as "name(signature)", e.g.
+//
+//	// FUNCTION_PLACEHOLDER: seed(int fill_size)
+type TemplateSlot struct {
+	Name      string // Function name the LLM must define
+	Signature string // Full "name(params)" text if given, empty otherwise
+}
+
+// placeholderBlock is the line range [start, end] a placeholder occupies,
+// covering its whole block comment when it sits inside one.
+type placeholderBlock struct {
+	start, end int
+	slot       TemplateSlot
+}
+
+// ParseTemplateSlots returns the placeholders of a template in source order.
+func ParseTemplateSlots(template string) []TemplateSlot {
+	blocks := findPlaceholderBlocks(strings.Split(template, "\n"))
+	slots := make([]TemplateSlot, len(blocks))
+	for i, b := range blocks {
+		slots[i] = b.slot
+	}
+	return slots
+}
+
 // MergeTemplate merges a function implementation into a C code template.
 // The template should contain a comment block starting with "// FUNCTION_PLACEHOLDER: function_name"
 // or a block comment containing "FUNCTION_PLACEHOLDER: function_name".
 // The entire comment block (including multi-line comments) will be replaced with the function code.
+// Only the first placeholder is filled; use MergeTemplateFunctions for templates with several.
 //
 // Example template:
 //
@@ -40,14 +68,97 @@ func MergeTemplate(template, functionCode string) (string, error) {
 		return "", fmt.Errorf("functionCode cannot be empty")
 	}
 
-	// Find the placeholder and determine if it's in a block comment
 	lines := strings.Split(template, "\n")
-	placeholderIndex := -1
+	blocks := findPlaceholderBlocks(lines)
+	if len(blocks) == 0 {
+		return "", fmt.Errorf("template does not contain FUNCTION_PLACEHOLDER: marker")
+	}
+
+	return strings.Join(replaceBlock(lines, blocks[0], functionCode), "\n"), nil
+}
+
+// MergeTemplateFunctions fills every placeholder of template with the function
+// of the same name from functions, such as the map SplitFunctions returns.
+// Static helpers without a placeholder go, prototypes first, in front of the
+// first placeholder. It fails if a placeholder has no function or if
+// functions contains any other name the template has no placeholder for.
+func MergeTemplateFunctions(template string, functions map[string]string) (string, error) {
+	if template == "" {
+		return "", fmt.Errorf("template cannot be empty")
+	}
+
+	lines := strings.Split(template, "\n")
+	blocks := findPlaceholderBlocks(lines)
+	if len(blocks) == 0 {
+		return "", fmt.Errorf("template does not contain FUNCTION_PLACEHOLDER: marker")
+	}
+
+	expected := make(map[string]bool, len(blocks))
+	var names, missing []string
+	for _, b := range blocks {
+		expected[b.slot.Name] = true
+		names = append(names, b.slot.Name)
+		if strings.TrimSpace(functions[b.slot.Name]) == "" {
+			missing = append(missing, b.slot.Name)
+		}
+	}
+	var extra, helpers []string
+	for name, code := range functions {
+		switch {
+		case expected[name]:
+		case isStaticHelper(code, name):
+			helpers = append(helpers, name)
+		default:
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	sort.Strings(helpers)
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing function(s) %s for template placeholders (expected: %s)",
+			strings.Join(missing, ", "), strings.Join(names, ", "))
+	}
+	if len(extra) > 0 {
+		return "", fmt.Errorf("unexpected function(s) %s: template only has placeholders for %s",
+			strings.Join(extra, ", "), strings.Join(names, ", "))
+	}
+
+	// Replace from the bottom up so earlier line indices stay valid.
+	for i := len(blocks) - 1; i >= 0; i-- {
+		code := functions[blocks[i].slot.Name]
+		if i == 0 && len(helpers) > 0 {
+			code = helperBlock(functions, helpers) + "\n\n" + code
+		}
+		lines = replaceBlock(lines, blocks[i], code)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// helperBlock declares the named static helpers and then defines them, so
+// they may call each other in any order.
+func helperBlock(functions map[string]string, names []string) string {
+	parts := make([]string, 0, len(names)+1)
+	prototypes := make([]string, len(names))
+	for i, name := range names {
+		prototypes[i] = definitionHeader(functions[name], name) + ";"
+	}
+	parts = append(parts, strings.Join(prototypes, "\n"))
+	for _, name := range names {
+		parts = append(parts, strings.TrimSpace(functions[name]))
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// findPlaceholderBlocks locates every FUNCTION_PLACEHOLDER line. A placeholder
+// inside a block comment claims the whole comment.
+func findPlaceholderBlocks(lines []string) []placeholderBlock {
+	var blocks []placeholderBlock
 	blockCommentStart := -1
-	blockCommentEnd := -1
 	inBlockComment := false
 
-	for i, line := range lines {
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
 		trimmed := strings.TrimSpace(line)
 
 		// Track block comment state
@@ -56,19 +167,24 @@ func MergeTemplate(template, functionCode string) (string, error) {
 			blockCommentStart = i
 		}
 
-		// Check for placeholder
 		if strings.Contains(line, "FUNCTION_PLACEHOLDER:") {
-			placeholderIndex = i
+			block := placeholderBlock{start: i, end: i, slot: parseTemplateSlot(line)}
 			if inBlockComment {
 				// Find the end of this block comment
 				for j := i; j < len(lines); j++ {
 					if strings.Contains(lines[j], "*/") {
-						blockCommentEnd = j
+						block.start, block.end = blockCommentStart, j
 						break
 					}
 				}
 			}
-			break
+			blocks = append(blocks, block)
+			if block.end > i {
+				i = block.end
+				inBlockComment = false
+				blockCommentStart = -1
+				continue
+			}
 		}
 
 		if strings.Contains(trimmed, "*/") {
@@ -76,34 +192,34 @@ func MergeTemplate(template, functionCode string) (string, error) {
 			blockCommentStart = -1
 		}
 	}
+	return blocks
+}
 
-	if placeholderIndex == -1 {
-		return "", fmt.Errorf("template does not contain FUNCTION_PLACEHOLDER: marker")
-	}
-
-	// Determine the range to replace
-	startReplace := placeholderIndex
-	endReplace := placeholderIndex
+// parseTemplateSlot extracts the slot from a placeholder line such as
+// "// FUNCTION_PLACEHOLDER: helper(int *buf, size_t n)".
+func parseTemplateSlot(line string) TemplateSlot {
+	_, rest, _ := strings.Cut(line, "FUNCTION_PLACEHOLDER:")
+	rest = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(rest), "*/"))
 
-	if blockCommentStart != -1 && blockCommentEnd != -1 {
-		// Replace the entire block comment
-		startReplace = blockCommentStart
-		endReplace = blockCommentEnd
+	end := 0
+	for end < len(rest) && isIdentByte(rest[end]) {
+		end++
 	}
+	slot := TemplateSlot{Name: rest[:end]}
+	if strings.HasPrefix(strings.TrimSpace(rest[end:]), "(") {
+		slot.Signature = rest
+	}
+	return slot
+}
 
-	// Get indentation from the start of the block being replaced
-	indent := getIndentation(lines[startReplace])
-
-	// Indent each line of the function code
-	indentedFunction := indentCode(functionCode, indent)
-
-	// Build the result
-	result := make([]string, 0, len(lines)+strings.Count(functionCode, "\n"))
-	result = append(result, lines[:startReplace]...)
-	result = append(result, indentedFunction)
-	result = append(result, lines[endReplace+1:]...)
-
-	return strings.Join(result, "\n"), nil
+// replaceBlock returns lines with block replaced by code, indented to match.
+func replaceBlock(lines []string, block placeholderBlock, code string) []string {
+	indent := getIndentation(lines[block.start])
+	result := make([]string, 0, len(lines)+strings.Count(code, "\n"))
+	result = append(result, lines[:block.start]...)
+	result = append(result, indentCode(code, indent))
+	result = append(result, lines[block.end+1:]...)
+	return result
 }
 
 // getIndentation returns the leading whitespace of a string
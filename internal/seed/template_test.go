@@ -1,6 +1,7 @@
 package seed
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -278,3 +279,127 @@ func TestEnsureMarkers(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+const multiSlotTemplate = `#include <stdio.h>
+#include <string.h>
+
+/**
+ * FUNCTION_PLACEHOLDER: seed(int fill_size)
+ * Fill a stack buffer.
+ */
+
+// FUNCTION_PLACEHOLDER: helper(char *dst, const char *src)
+
+int main(int argc, char *argv[]) {
+    seed(argc);
+    return 0;
+}`
+
+func TestParseTemplateSlots(t *testing.T) {
+	slots := ParseTemplateSlots(multiSlotTemplate)
+	require.Len(t, slots, 2)
+	assert.Equal(t, TemplateSlot{Name: "seed", Signature: "seed(int fill_size)"}, slots[0])
+	assert.Equal(t, TemplateSlot{Name: "helper", Signature: "helper(char *dst, const char *src)"}, slots[1])
+
+	single := ParseTemplateSlots("// FUNCTION_PLACEHOLDER: seed\nint main() {}")
+	require.Len(t, single, 1)
+	assert.Equal(t, TemplateSlot{Name: "seed"}, single[0])
+}
+
+func TestSplitFunctions(t *testing.T) {
+	code := `// FUNCTION: helper
+static __attribute__((noinline)) void helper(char *dst, const char *src) {
+    const char *s = "not { a } brace";
+    char c = '}';
+    strcpy(dst, src); /* } */
+}
+
+// FUNCTION: seed
+__attribute__((stack_protect))
+void seed(int fill_size) {
+    char buf[64];
+    struct { int a; } local = { 1 };
+    if (fill_size > 0) { helper(buf, "x"); }
+}`
+
+	functions, err := SplitFunctions(code)
+	require.NoError(t, err)
+	require.Len(t, functions, 2)
+	assert.True(t, strings.HasPrefix(functions["helper"], "// FUNCTION: helper"))
+	assert.True(t, strings.HasSuffix(functions["helper"], "/* } */\n}"))
+	assert.Contains(t, functions["seed"], "__attribute__((stack_protect))")
+	assert.Contains(t, functions["seed"], "helper(buf, \"x\");")
+
+	t.Run("should reject duplicate definitions", func(t *testing.T) {
+		_, err := SplitFunctions("void a(void) {}\nvoid a(void) {}")
+		assert.ErrorContains(t, err, `"a" is defined more than once`)
+	})
+
+	t.Run("should reject unbalanced braces", func(t *testing.T) {
+		_, err := SplitFunctions("void a(void) { if (1) {")
+		assert.Error(t, err)
+	})
+
+	t.Run("should reject code without functions", func(t *testing.T) {
+		_, err := SplitFunctions("int x = 1;")
+		assert.ErrorContains(t, err, "no function definitions")
+	})
+}
+
+func TestFunctionBody(t *testing.T) {
+	code := `int helper(void) { return '}'; }
+/* void seed(void) { */
+void seed(int n) {
+    if (n) { helper(); }
+}`
+
+	start, end, ok := FunctionBody(code, "seed")
+	require.True(t, ok)
+	assert.Equal(t, "\n    if (n) { helper(); }\n", code[start:end])
+
+	_, _, ok = FunctionBody(code, "missing")
+	assert.False(t, ok)
+}
+
+func TestMergeTemplateFunctions(t *testing.T) {
+	functions := map[string]string{
+		"seed":   "void seed(int fill_size) {\n    char buf[64];\n}",
+		"helper": "void helper(char *dst, const char *src) {\n    strcpy(dst, src);\n}",
+	}
+
+	t.Run("should fill every placeholder", func(t *testing.T) {
+		result, err := MergeTemplateFunctions(multiSlotTemplate, functions)
+		require.NoError(t, err)
+		assert.NotContains(t, result, "FUNCTION_PLACEHOLDER")
+		assert.NotContains(t, result, "Fill a stack buffer.")
+		seedIdx := strings.Index(result, "void seed(int fill_size)")
+		helperIdx := strings.Index(result, "void helper(char *dst")
+		mainIdx := strings.Index(result, "int main(")
+		assert.True(t, seedIdx >= 0 && seedIdx < helperIdx && helperIdx < mainIdx, "functions should land in their slots:\n%s", result)
+	})
+
+	t.Run("should report missing functions", func(t *testing.T) {
+		_, err := MergeTemplateFunctions(multiSlotTemplate, map[string]string{"seed": functions["seed"]})
+		assert.ErrorContains(t, err, "missing function(s) helper")
+	})
+
+	t.Run("should declare static helpers before the first placeholder", func(t *testing.T) {
+		withHelpers := map[string]string{
+			"seed":   functions["seed"],
+			"helper": functions["helper"],
+			"pad":    "static int pad(int n) {\n    return twice(n) + 1;\n}",
+			"twice":  "// scales n\nstatic inline int twice(int n) {\n    return 2 * n;\n}",
+		}
+		result, err := MergeTemplateFunctions(multiSlotTemplate, withHelpers)
+		require.NoError(t, err)
+		assert.Contains(t, result, "static int pad(int n);\nstatic inline int twice(int n);\n\nstatic int pad(int n) {")
+		assert.Contains(t, result, "// scales n\nstatic inline int twice(int n) {")
+		assert.Less(t, strings.Index(result, "static inline int twice(int n) {"), strings.Index(result, "void seed(int fill_size)"))
+	})
+
+	t.Run("should report extra functions", func(t *testing.T) {
+		withExtra := map[string]string{"seed": functions["seed"], "helper": functions["helper"], "bonus": "void bonus(void) {}"}
+		_, err := MergeTemplateFunctions(multiSlotTemplate, withExtra)
+		assert.ErrorContains(t, err, "unexpected function(s) bonus")
+	})
+}
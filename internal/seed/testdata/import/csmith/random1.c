@@ -0,0 +1,9 @@
+#include <stdio.h>
+
+/* csmith-style seed: bracket characters in literals ')' and "}" are ignored */
+int g[4] = {1, 2, 3, 4};
+
+int main(void) {
+    printf("checksum = %d\n", g[0] + g[3]);
+    return 0;
+}
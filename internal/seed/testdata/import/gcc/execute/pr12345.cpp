@@ -0,0 +1,9 @@
+// { dg-do run }
+struct S {
+    int v[2];
+};
+
+int main() {
+    S s{{1, 2}};
+    return s.v[0] + s.v[1] == 3 ? 0 : 1;
+}
@@ -0,0 +1,122 @@
+package seed
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/zjy-dev/de-fuzz/internal/fsutil"
+)
+
+const (
+	// UnderstandingHistoryDir holds a copy of every understanding version,
+	// next to understanding.md.
+	UnderstandingHistoryDir   = "understanding_history"
+	understandingHistoryIndex = "index.json"
+	// understandingTimestampFormat names the versions; it sorts by time.
+	understandingTimestampFormat = "20060102T150405.000000000Z"
+)
+
+// UnderstandingRecord describes one version in the understanding history.
+type UnderstandingRecord struct {
+	Timestamp string `json:"timestamp"`       // UTC; also the version's name
+	Model     string `json:"model,omitempty"` // Model that wrote it, when known
+	Tokens    int    `json:"tokens,omitempty"`
+	SHA256    string `json:"sha256"`
+}
+
+// RecordUnderstanding adds content to the understanding history unless it
+// is already the latest version, and returns the record of the version.
+// model and tokens fill in what the latest record is missing.
+func RecordUnderstanding(basePath, content, model string, tokens int) (UnderstandingRecord, error) {
+	history, err := UnderstandingHistory(basePath)
+	if err != nil {
+		return UnderstandingRecord{}, err
+	}
+	sum := sha256.Sum256([]byte(content))
+	hash := hex.EncodeToString(sum[:])
+
+	if n := len(history); n > 0 && history[n-1].SHA256 == hash {
+		latest := &history[n-1]
+		if (latest.Model != "" || model == "") && (latest.Tokens != 0 || tokens == 0) {
+			return *latest, nil
+		}
+		if latest.Model == "" {
+			latest.Model = model
+		}
+		if latest.Tokens == 0 {
+			latest.Tokens = tokens
+		}
+		return *latest, writeUnderstandingHistory(basePath, history)
+	}
+
+	record := UnderstandingRecord{
+		Timestamp: time.Now().UTC().Format(understandingTimestampFormat),
+		Model:     model,
+		Tokens:    tokens,
+		SHA256:    hash,
+	}
+	dir := filepath.Join(basePath, UnderstandingHistoryDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return UnderstandingRecord{}, fmt.Errorf("failed to create understanding history %s: %w", dir, err)
+	}
+	if err := fsutil.WriteFileAtomic(filepath.Join(dir, record.Timestamp+".md"), []byte(content)); err != nil {
+		return UnderstandingRecord{}, fmt.Errorf("failed to save understanding version: %w", err)
+	}
+	return record, writeUnderstandingHistory(basePath, append(history, record))
+}
+
+// UnderstandingHistory lists the saved understanding versions, oldest first.
+// It is empty when nothing has been recorded.
+func UnderstandingHistory(basePath string) ([]UnderstandingRecord, error) {
+	path := filepath.Join(basePath, UnderstandingHistoryDir, understandingHistoryIndex)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read understanding history %s: %w", path, err)
+	}
+	var history []UnderstandingRecord
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, fmt.Errorf("failed to parse understanding history %s: %w", path, err)
+	}
+	return history, nil
+}
+
+// LoadUnderstandingVersion loads the understanding version saved at
+// timestamp, as listed by UnderstandingHistory.
+func LoadUnderstandingVersion(basePath, timestamp string) (string, error) {
+	history, err := UnderstandingHistory(basePath)
+	if err != nil {
+		return "", err
+	}
+	for _, record := range history {
+		if record.Timestamp != timestamp {
+			continue
+		}
+		path := filepath.Join(basePath, UnderstandingHistoryDir, timestamp+".md")
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read understanding version %s: %w", path, err)
+		}
+		return string(content), nil
+	}
+	return "", fmt.Errorf("no understanding version %q in %s", timestamp, basePath)
+}
+
+func writeUnderstandingHistory(basePath string, history []UnderstandingRecord) error {
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal understanding history: %w", err)
+	}
+	path := filepath.Join(basePath, UnderstandingHistoryDir, understandingHistoryIndex)
+	if err := fsutil.WriteFileAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to write understanding history %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package seed
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnderstandingHistory(t *testing.T) {
+	dir := t.TempDir()
+
+	history, err := UnderstandingHistory(dir)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+
+	require.NoError(t, SaveUnderstanding(dir, "first"))
+	// Saving the same text again, as SaveCompressedUnderstanding does, adds
+	// no version.
+	require.NoError(t, SaveCompressedUnderstanding(dir, "first", "short"))
+	require.NoError(t, SaveUnderstanding(dir, "second"))
+	record, err := RecordUnderstanding(dir, "second", "gpt-test", 42)
+	require.NoError(t, err)
+
+	history, err = UnderstandingHistory(dir)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Less(t, history[0].Timestamp, history[1].Timestamp)
+	assert.Equal(t, record, history[1])
+	assert.Equal(t, "gpt-test", record.Model)
+	assert.Equal(t, 42, record.Tokens)
+	assert.Empty(t, history[0].Model)
+
+	// The latest version stays at the usual path.
+	latest, err := LoadUnderstanding(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "second", latest)
+
+	for i, want := range []string{"first", "second"} {
+		content, err := LoadUnderstandingVersion(dir, history[i].Timestamp)
+		require.NoError(t, err)
+		assert.Equal(t, want, content)
+	}
+	_, err = LoadUnderstandingVersion(dir, "../understanding")
+	assert.Error(t, err)
+}
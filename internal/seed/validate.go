@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"runtime"
 	"strings"
+	"sync"
 )
 
 // ValidationError represents an error during seed validation.
@@ -21,20 +23,17 @@ func (e *ValidationError) Error() string {
 // This is the canonical parsing function used by both generation and mutation.
 // Uses the unified storage format with separator: // ||||| JSON_TESTCASES_START |||||
 func ParseSeedFromLLMResponse(response string) (string, []TestCase, error) {
-	// Use the separator defined in storage.go (without leading newline for split)
-	separatorMarker := "// ||||| JSON_TESTCASES_START |||||"
+	return ParseSeedWithSeparator(response, TestCaseSeparator)
+}
 
-	// Split response by the separator
-	parts := strings.SplitN(response, separatorMarker, 2)
-	if len(parts) < 2 {
-		return "", nil, &ValidationError{
-			Field:   "format",
-			Message: "could not find separator '// ||||| JSON_TESTCASES_START |||||' in response",
-		}
+// ParseSeedWithSeparator is ParseSeedFromLLMResponse with a custom test-case
+// separator. See SplitTestCases for how the separator is located.
+func ParseSeedWithSeparator(response, separator string) (string, []TestCase, error) {
+	code, testCasesJSON, err := splitTestCasesOrError(response, separator)
+	if err != nil {
+		return "", nil, err
 	}
-
-	sourceCode := strings.TrimSpace(parts[0])
-	testCasesJSON := strings.TrimSpace(parts[1])
+	sourceCode := stripMarkdownCodeBlocks(code)
 
 	// Validate source code is not empty
 	if sourceCode == "" {
@@ -45,8 +44,8 @@ func ParseSeedFromLLMResponse(response string) (string, []TestCase, error) {
 	}
 
 	// Parse test cases JSON
-	var testCases []TestCase
-	if err := json.Unmarshal([]byte(testCasesJSON), &testCases); err != nil {
+	testCases, err := DecodeTestCases(testCasesJSON)
+	if err != nil {
 		return "", nil, &ValidationError{
 			Field:   "test_cases",
 			Message: fmt.Sprintf("failed to parse test cases JSON: %v", err),
@@ -74,6 +73,63 @@ func ParseSeedFromLLMResponse(response string) (string, []TestCase, error) {
 	return sourceCode, testCases, nil
 }
 
+// splitTestCasesOrError wraps SplitTestCases with the parsers' ValidationError.
+func splitTestCasesOrError(response, separator string) (string, string, error) {
+	code, testCasesJSON, ok := SplitTestCases(response, separator)
+	if !ok {
+		return "", "", &ValidationError{
+			Field:   "format",
+			Message: fmt.Sprintf("could not find separator '%s' or a trailing JSON test case array in response", separator),
+		}
+	}
+	return code, testCasesJSON, nil
+}
+
+// SplitTestCases splits an LLM response into code and the JSON test cases
+// that follow separator. It tolerates the usual ways models mangle the
+// separator:
+//   - different whitespace between or around its tokens, or different case
+//   - the separator placed inside the code fence or after it
+//   - a missing separator, when a top-level JSON array of test cases follows
+//     the code; prose after it is ignored, but not another code block
+func SplitTestCases(response, separator string) (code, testCasesJSON string, ok bool) {
+	if loc := separatorPattern(separator).FindStringIndex(response); loc != nil {
+		return response[:loc[0]], response[loc[1]:], true
+	}
+
+	// Last resort: a line starting a JSON array of test cases, followed by
+	// nothing but a closing fence and prose.
+	offset := 0
+	for _, line := range strings.SplitAfter(response, "\n") {
+		start := offset
+		offset += len(line)
+		if !strings.HasPrefix(strings.TrimSpace(line), "[") || strings.TrimSpace(response[:start]) == "" {
+			continue
+		}
+		tail := response[start:]
+		dec := json.NewDecoder(strings.NewReader(tail))
+		var testCases []TestCase
+		if dec.Decode(&testCases) != nil || len(testCases) == 0 {
+			continue
+		}
+		rest := strings.TrimSpace(tail[dec.InputOffset():])
+		if !strings.Contains(strings.TrimPrefix(rest, "```"), "```") {
+			return response[:start], tail, true
+		}
+	}
+	return "", "", false
+}
+
+// separatorPattern matches separator with flexible whitespace between and
+// around its tokens, case-insensitively.
+func separatorPattern(separator string) *regexp.Regexp {
+	fields := strings.Fields(separator)
+	for i, f := range fields {
+		fields[i] = regexp.QuoteMeta(f)
+	}
+	return regexp.MustCompile(`(?i)` + strings.Join(fields, `\s*`))
+}
+
 // ParseFunctionFromLLMResponse extracts function code from LLM response (for template mode).
 // It strips markdown code blocks and returns the raw function code.
 func ParseFunctionFromLLMResponse(response string) (string, error) {
@@ -94,21 +150,17 @@ func ParseFunctionFromLLMResponse(response string) (string, error) {
 // This is used when function template mode is combined with test case generation.
 // Format: function code + separator + JSON test cases
 func ParseFunctionWithTestCasesFromLLMResponse(response string) (string, []TestCase, error) {
-	// Use the separator to split function code and test cases
-	separatorMarker := "// ||||| JSON_TESTCASES_START |||||"
+	return ParseFunctionWithTestCasesWithSeparator(response, TestCaseSeparator)
+}
 
-	// Split response by the separator
-	parts := strings.SplitN(response, separatorMarker, 2)
-	if len(parts) < 2 {
-		return "", nil, &ValidationError{
-			Field:   "format",
-			Message: "could not find separator '// ||||| JSON_TESTCASES_START |||||' in response",
-		}
+// ParseFunctionWithTestCasesWithSeparator is ParseFunctionWithTestCasesFromLLMResponse
+// with a custom test-case separator.
+func ParseFunctionWithTestCasesWithSeparator(response, separator string) (string, []TestCase, error) {
+	code, testCasesJSON, err := splitTestCasesOrError(response, separator)
+	if err != nil {
+		return "", nil, err
 	}
-
-	functionCode := strings.TrimSpace(parts[0])
-	functionCode = stripMarkdownCodeBlocks(functionCode)
-	testCasesJSON := strings.TrimSpace(parts[1])
+	functionCode := stripMarkdownCodeBlocks(code)
 
 	// Validate function code is not empty
 	if functionCode == "" {
@@ -119,8 +171,8 @@ func ParseFunctionWithTestCasesFromLLMResponse(response string) (string, []TestC
 	}
 
 	// Parse test cases JSON
-	var testCases []TestCase
-	if err := json.Unmarshal([]byte(testCasesJSON), &testCases); err != nil {
+	testCases, err := DecodeTestCases(testCasesJSON)
+	if err != nil {
 		return "", nil, &ValidationError{
 			Field:   "test_cases",
 			Message: fmt.Sprintf("failed to parse test cases JSON: %v", err),
@@ -148,6 +200,138 @@ func ParseFunctionWithTestCasesFromLLMResponse(response string) (string, []TestC
 	return functionCode, testCases, nil
 }
 
+// ParseFunctionsWithTestCasesFromLLMResponse is ParseFunctionWithTestCasesFromLLMResponse
+// for responses defining several functions: the code is split with
+// SplitFunctions into a map keyed by function name, ready for
+// MergeTemplateFunctions.
+func ParseFunctionsWithTestCasesFromLLMResponse(response string) (map[string]string, []TestCase, error) {
+	return ParseFunctionsWithTestCasesWithSeparator(response, TestCaseSeparator)
+}
+
+// ParseFunctionsWithTestCasesWithSeparator is ParseFunctionsWithTestCasesFromLLMResponse
+// with a custom test-case separator.
+func ParseFunctionsWithTestCasesWithSeparator(response, separator string) (map[string]string, []TestCase, error) {
+	code, testCases, err := ParseFunctionWithTestCasesWithSeparator(response, separator)
+	if err != nil {
+		return nil, nil, err
+	}
+	functions, err := SplitFunctions(code)
+	if err != nil {
+		return nil, nil, &ValidationError{
+			Field:   "function",
+			Message: fmt.Sprintf("failed to split function code: %v", err),
+		}
+	}
+	return functions, testCases, nil
+}
+
+// StructuredResponse is the single JSON object the LLM returns when prompts
+// use the structured output contract:
+//
+//	{"source": "...", "test_cases": [...], "cflags": [...], "tags": [...]}
+type StructuredResponse struct {
+	Source    string     `json:"source"`
+	TestCases []TestCase `json:"test_cases"`
+	CFlags    []string   `json:"cflags,omitempty"`
+	Tags      []string   `json:"tags,omitempty"` // Optional; normalized by ParseStructuredResponse
+}
+
+// ExtractJSONObject strips an opening markdown fence and any prose before
+// the first '{', leaving a JSON object for a streaming decoder (which stops
+// at the end of the object and ignores a trailing fence).
+func ExtractJSONObject(response string) string {
+	data := strings.TrimSpace(response)
+	if strings.HasPrefix(data, "```") {
+		if nl := strings.IndexByte(data, '\n'); nl >= 0 {
+			data = data[nl+1:]
+		} else {
+			data = ""
+		}
+	}
+	if idx := strings.IndexByte(data, '{'); idx > 0 {
+		data = data[idx:]
+	}
+	return data
+}
+
+// ParseStructuredResponse decodes a structured-output response. A surrounding
+// ```json fence, text before the opening brace and text after the object are
+// ignored. When requireTestCases is set, at least one test case with a
+// non-empty running command is required; otherwise test_cases may be absent.
+func ParseStructuredResponse(response string, requireTestCases bool) (*StructuredResponse, error) {
+	var resp StructuredResponse
+	if err := json.NewDecoder(strings.NewReader(ExtractJSONObject(response))).Decode(&resp); err != nil {
+		return nil, &ValidationError{
+			Field:   "response",
+			Message: fmt.Sprintf("failed to parse structured JSON response: %v", err),
+		}
+	}
+
+	// Some models still fence the code inside the JSON string.
+	resp.Source = stripMarkdownCodeBlocks(strings.TrimSpace(resp.Source))
+	resp.Tags = NormalizeTags(resp.Tags)
+	if resp.Source == "" {
+		return nil, &ValidationError{
+			Field:   "source",
+			Message: "source code is empty",
+		}
+	}
+
+	if !requireTestCases {
+		if resp.TestCases == nil {
+			resp.TestCases = []TestCase{}
+		}
+		return &resp, nil
+	}
+	if len(resp.TestCases) == 0 {
+		return nil, &ValidationError{
+			Field:   "test_cases",
+			Message: "at least one test case is required",
+		}
+	}
+	for i, tc := range resp.TestCases {
+		if tc.RunningCommand == "" {
+			return nil, &ValidationError{
+				Field:   "test_cases",
+				Message: fmt.Sprintf("test case %d: running command is empty", i+1),
+			}
+		}
+	}
+	return &resp, nil
+}
+
+// shellControlOperators are the tokens that chain or substitute commands.
+// Redirections (<, >) stay allowed since test cases use them to feed input.
+var shellControlOperators = []string{"|", ";", "&&", "`", "$("}
+
+// ValidateRunningCommand checks that a test case command runs the compiled
+// seed binary: it must start with "./" and, unless allowShell is set, must not
+// chain commands with pipes, ";", "&&", backticks or $(...).
+func ValidateRunningCommand(command string, allowShell bool) error {
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return &ValidationError{Field: "test_cases", Message: "running command is empty"}
+	}
+	if !strings.HasPrefix(command, "./") {
+		return &ValidationError{
+			Field:   "test_cases",
+			Message: fmt.Sprintf("running command %q must start with \"./\" (the seed binary)", command),
+		}
+	}
+	if allowShell {
+		return nil
+	}
+	for _, op := range shellControlOperators {
+		if strings.Contains(command, op) {
+			return &ValidationError{
+				Field:   "test_cases",
+				Message: fmt.Sprintf("running command %q uses shell operator %q", command, op),
+			}
+		}
+	}
+	return nil
+}
+
 // ParseCodeOnlyFromLLMResponse extracts source code without test cases from LLM response.
 // Used when MaxTestCases is 0.
 func ParseCodeOnlyFromLLMResponse(response string) (string, error) {
@@ -170,7 +354,7 @@ func ParseCodeOnlyFromLLMResponse(response string) (string, error) {
 func stripMarkdownCodeBlocks(code string) string {
 	// First, try to extract code from markdown code blocks
 	// Pattern: ```[language]\n...code...\n```
-	codeBlockRegex := regexp.MustCompile("(?s)```(?:c|cpp|C|CPP)?\\s*\\n(.+?)\\n?```")
+	codeBlockRegex := regexp.MustCompile("(?s)```(?:c|cpp|C|CPP|c\\+\\+|cxx|cc|rust|rs)?\\s*\\n(.+?)\\n?```")
 	matches := codeBlockRegex.FindAllStringSubmatch(code, -1)
 
 	if len(matches) > 0 {
@@ -232,6 +416,64 @@ const (
 	CFlagsEndMarker   = "// ||||| CFLAGS_END |||||"
 )
 
+// Validate checks s with ValidateSeed and then with compileFn, typically a
+// compilation with the configured compiler and flags that runs nothing. A
+// nil compileFn skips compilation. An unloaded pool seed is loaded for the
+// check and released after it.
+func Validate(s *Seed, compileFn func(*Seed) error) error {
+	if s != nil && !s.Loaded() {
+		if err := s.Load(); err != nil {
+			return &ValidationError{Field: "source", Message: err.Error()}
+		}
+		defer s.Release()
+	}
+	if err := ValidateSeed(s); err != nil {
+		return err
+	}
+	if compileFn == nil {
+		return nil
+	}
+	if err := compileFn(s); err != nil {
+		return &ValidationError{Field: "compile", Message: err.Error()}
+	}
+	return nil
+}
+
+// ValidateAll runs Validate on every seed, at most workers at once
+// (0 means runtime.NumCPU()), and marks the seeds that fail
+// SeedStateInvalid. It returns the failures by seed ID.
+func ValidateAll(seeds []*Seed, compileFn func(*Seed) error, workers int) map[uint64]error {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	errs := make([]error, len(seeds))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, len(seeds)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				errs[i] = Validate(seeds[i], compileFn)
+			}
+		}()
+	}
+	for i := range seeds {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	failures := make(map[uint64]error)
+	for i, err := range errs {
+		if err != nil && seeds[i] != nil {
+			seeds[i].Meta.State = SeedStateInvalid
+			failures[seeds[i].Meta.ID] = err
+		}
+	}
+	return failures
+}
+
 // ParseCFlagsFromResponse extracts compiler flags from LLM response.
 // The flags are expected between CFLAGS_START and CFLAGS_END markers.
 // Each flag should be on its own line. Empty lines and comment lines (starting with #) are ignored.
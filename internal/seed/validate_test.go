@@ -1,7 +1,13 @@
 package seed
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -152,6 +158,53 @@ func TestValidateSeed(t *testing.T) {
 	})
 }
 
+func TestValidateAll(t *testing.T) {
+	var running, peak atomic.Int32
+	compile := func(s *Seed) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		if strings.Contains(s.Content, "error") {
+			return errors.New("seed.c:1: error: expected ';'\nseed.c:2: note: here")
+		}
+		return nil
+	}
+
+	var seeds []*Seed
+	for i := 1; i <= 10; i++ {
+		content := "int main() { return 0; }"
+		if i%4 == 0 {
+			content = "int main() { error }"
+		}
+		seeds = append(seeds, &Seed{Meta: Metadata{ID: uint64(i), State: SeedStatePending}, Content: content})
+	}
+	seeds = append(seeds, &Seed{Meta: Metadata{ID: 11, State: SeedStatePending}})
+
+	failures := ValidateAll(seeds, compile, 3)
+	assert.Len(t, failures, 3)
+	assert.LessOrEqual(t, peak.Load(), int32(3))
+	for _, s := range seeds {
+		if _, ok := failures[s.Meta.ID]; ok {
+			assert.Equal(t, SeedStateInvalid, s.Meta.State, "seed %d", s.Meta.ID)
+		} else {
+			assert.Equal(t, SeedStatePending, s.Meta.State, "seed %d", s.Meta.ID)
+		}
+	}
+	var verr *ValidationError
+	require.ErrorAs(t, failures[4], &verr)
+	assert.Equal(t, "compile", verr.Field)
+	require.ErrorAs(t, failures[11], &verr)
+	assert.Equal(t, "content", verr.Field, "empty seeds are not compiled")
+
+	assert.NoError(t, Validate(seeds[0], nil))
+}
+
 func TestValidationError(t *testing.T) {
 	err := &ValidationError{
 		Field:   "test_field",
@@ -235,6 +288,56 @@ func TestParseFunctionWithTestCasesFromLLMResponse(t *testing.T) {
 	})
 }
 
+func TestParseFunctionsWithTestCasesFromLLMResponse(t *testing.T) {
+	t.Run("should split seed and a static helper", func(t *testing.T) {
+		response := "```c\n" + `static void fill(char *buf, int n) {
+    memset(buf, 'A', n);
+}
+
+void seed(int fill_size) {
+    char buffer[64];
+    fill(buffer, fill_size);
+}
+` + "```\n// ||||| JSON_TESTCASES_START |||||\n[{\"running command\": \"./prog 100\", \"expected result\": \"crash\"}]"
+
+		functions, testCases, err := ParseFunctionsWithTestCasesFromLLMResponse(response)
+		require.NoError(t, err)
+		require.Len(t, functions, 2)
+		assert.True(t, strings.HasPrefix(functions["fill"], "static void fill("))
+		assert.True(t, strings.HasPrefix(functions["seed"], "void seed(int fill_size) {"))
+		assert.Len(t, testCases, 1)
+	})
+
+	t.Run("should keep a stray forward declaration with the next function", func(t *testing.T) {
+		response := `void seed(int fill_size);
+
+void seed(int fill_size) {
+    char buffer[64];
+    memset(buffer, 'A', fill_size);
+}
+// ||||| JSON_TESTCASES_START |||||
+[{"running command": "./prog 10", "expected result": "success"}]`
+
+		functions, _, err := ParseFunctionsWithTestCasesFromLLMResponse(response)
+		require.NoError(t, err)
+		require.Len(t, functions, 1)
+		assert.True(t, strings.HasPrefix(functions["seed"], "void seed(int fill_size);\n\nvoid seed(int fill_size) {"))
+
+		// A single-placeholder template still takes the code as it came.
+		code, _, err := ParseFunctionWithTestCasesFromLLMResponse(response)
+		require.NoError(t, err)
+		assert.Equal(t, code, functions["seed"])
+	})
+
+	t.Run("should fail on unbalanced braces", func(t *testing.T) {
+		response := "void seed(int n) { if (n) {\n// ||||| JSON_TESTCASES_START |||||\n[{\"running command\": \"./prog\", \"expected result\": \"ok\"}]"
+
+		_, _, err := ParseFunctionsWithTestCasesFromLLMResponse(response)
+		var vErr *ValidationError
+		require.ErrorAs(t, err, &vErr)
+		assert.Equal(t, "function", vErr.Field)
+	})
+}
 func TestStripMarkdownCodeBlocks(t *testing.T) {
 	t.Run("should extract code from markdown code block", func(t *testing.T) {
 		response := "Here is my analysis...\n```c\nint main() { return 0; }\n```\nThis code does..."
@@ -281,3 +384,226 @@ This modification adds...`
 		assert.Equal(t, "int main() { return 0; }", result)
 	})
 }
+
+func TestEncodeDecodeTestCases(t *testing.T) {
+	testCases := []TestCase{
+		{RunningCommand: `./prog "quoted arg" 'single'`, ExpectedResult: `prints "ok"`},
+		{RunningCommand: `./prog C:\path\to\file \n`, ExpectedResult: "line1\nline2\ttab"},
+		{RunningCommand: "./prog --name=café 漢字 🚀", ExpectedResult: "exit <0> && done"},
+	}
+
+	t.Run("should round-trip special characters", func(t *testing.T) {
+		encoded, err := EncodeTestCases(testCases)
+		require.NoError(t, err)
+		assert.Contains(t, encoded, `"running command"`)
+		assert.Contains(t, encoded, `"expected result"`)
+		assert.Contains(t, encoded, `\"quoted arg\"`)
+		assert.Contains(t, encoded, "exit <0> && done", "HTML characters should not be escaped")
+
+		decoded, err := DecodeTestCases(encoded)
+		require.NoError(t, err)
+		assert.Equal(t, testCases, decoded)
+	})
+
+	t.Run("should round-trip through ParseSeedFromLLMResponse", func(t *testing.T) {
+		encoded, err := EncodeTestCases(testCases)
+		require.NoError(t, err)
+
+		response := "int main() { return 0; }\n// ||||| JSON_TESTCASES_START |||||\n" + encoded
+		_, parsed, err := ParseSeedFromLLMResponse(response)
+		require.NoError(t, err)
+		assert.Equal(t, testCases, parsed)
+
+		_, parsed, err = ParseFunctionWithTestCasesFromLLMResponse("void seed(void) {}\n// ||||| JSON_TESTCASES_START |||||\n" + encoded)
+		require.NoError(t, err)
+		assert.Equal(t, testCases, parsed)
+	})
+
+	t.Run("should tolerate code fence and trailing text", func(t *testing.T) {
+		decoded, err := DecodeTestCases("```json\n[{\"running command\": \"./prog\", \"expected result\": \"ok\"}]\n```\nDone.")
+		require.NoError(t, err)
+		require.Len(t, decoded, 1)
+		assert.Equal(t, "./prog", decoded[0].RunningCommand)
+	})
+
+	t.Run("should round-trip optional keys and omit them when empty", func(t *testing.T) {
+		withStdin := []TestCase{
+			{RunningCommand: "./prog", ExpectedResult: "7", Stdin: "3 4\n", TimeoutSeconds: 2, Env: map[string]string{"LANG": "C"}},
+			{RunningCommand: "./prog", ExpectedResult: "0"},
+		}
+		encoded, err := EncodeTestCases(withStdin)
+		require.NoError(t, err)
+		assert.Equal(t, 1, strings.Count(encoded, `"stdin"`))
+		assert.Equal(t, 1, strings.Count(encoded, `"timeout seconds"`))
+		assert.Equal(t, 1, strings.Count(encoded, `"env"`))
+
+		decoded, err := DecodeTestCases(encoded)
+		require.NoError(t, err)
+		assert.Equal(t, withStdin, decoded)
+
+		// Test cases saved before the optional keys existed still load.
+		decoded, err = DecodeTestCases(`[{"running command": "./prog", "expected result": "ok"}]`)
+		require.NoError(t, err)
+		require.Len(t, decoded, 1)
+		assert.Empty(t, decoded[0].Stdin)
+		assert.Zero(t, decoded[0].TimeoutSeconds)
+		assert.Nil(t, decoded[0].Env)
+	})
+
+	t.Run("should encode empty list as array", func(t *testing.T) {
+		encoded, err := EncodeTestCases(nil)
+		require.NoError(t, err)
+		assert.Equal(t, "[]", encoded)
+	})
+}
+
+func TestParseSeedFromLLMResponse_MalformedSeparators(t *testing.T) {
+	const code = "int main() {\n    return 0;\n}"
+	const cases = `[{"running command": "./prog", "expected result": "ok"}]`
+
+	tests := []struct {
+		name     string
+		response string
+	}{
+		{"canonical", code + "\n// ||||| JSON_TESTCASES_START |||||\n" + cases},
+		{"no space after slashes", code + "\n//||||| JSON_TESTCASES_START |||||\n" + cases},
+		{"extra inner spaces", code + "\n//  |||||   JSON_TESTCASES_START  |||||\n" + cases},
+		{"no spaces at all", code + "\n//|||||JSON_TESTCASES_START|||||\n" + cases},
+		{"trailing whitespace and CRLF", code + "\r\n// ||||| JSON_TESTCASES_START |||||   \r\n" + cases},
+		{"indented separator", code + "\n    // ||||| JSON_TESTCASES_START |||||\n" + cases},
+		{"lowercase separator", code + "\n// ||||| json_testcases_start |||||\n" + cases},
+		{"separator on the closing brace line", code + " // ||||| JSON_TESTCASES_START |||||\n" + cases},
+		{"separator inside code fence", "```c\n" + code + "\n// ||||| JSON_TESTCASES_START |||||\n" + cases + "\n```"},
+		{"separator after code fence", "```c\n" + code + "\n```\n// ||||| JSON_TESTCASES_START |||||\n" + cases},
+		{"json in its own fence", "```c\n" + code + "\n```\n// ||||| JSON_TESTCASES_START |||||\n```json\n" + cases + "\n```"},
+		{"lead-in text before json", code + "\n// ||||| JSON_TESTCASES_START |||||\nTest cases:\n" + cases},
+		{"missing separator, trailing array", code + "\n" + cases},
+		{"missing separator, fenced code and array", "```c\n" + code + "\n```\n```json\n" + cases + "\n```"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, testCases, err := ParseSeedFromLLMResponse(tt.response)
+			require.NoError(t, err)
+			assert.Equal(t, code, source)
+			require.Len(t, testCases, 1)
+			assert.Equal(t, "./prog", testCases[0].RunningCommand)
+		})
+	}
+
+	t.Run("array-like C code is not mistaken for test cases", func(t *testing.T) {
+		_, _, err := ParseSeedFromLLMResponse("int a[] = {\n[0] = 1,\n};\nint main() { return a[0]; }")
+		assert.Error(t, err)
+	})
+}
+
+// The responses in testdata/responses were captured from generation logs
+// where parsing used to fail: prose or markdown after the test cases, and
+// labels or fences around them.
+func TestParseSeedFromLLMResponse_CapturedResponses(t *testing.T) {
+	tests := []struct {
+		file      string
+		codeStart string
+		codeEnd   string
+		first     string
+	}{
+		{"trailing_prose.txt", "#include <stdio.h>", "return 0;\n}", "./prog"},
+		{"trailing_explanation.txt", "#include <stdio.h>", "return 0;\n}", "./prog"},
+		{"label_with_brackets.txt", "#include <stdlib.h>", "return buf[0] == 'A' ? 0 : 1;\n}", "./prog 4"},
+		{"missing_separator_trailing_prose.txt", "#include <stdio.h>", "return 0;\n}", "./prog"},
+		{"uppercase_fence_trailing_note.txt", "#include <string.h>", "return dst[0];\n}", "./prog abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			response, err := os.ReadFile(filepath.Join("testdata", "responses", tt.file))
+			require.NoError(t, err)
+
+			source, testCases, err := ParseSeedFromLLMResponse(string(response))
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(source, tt.codeStart), "source starts with %q", source)
+			assert.True(t, strings.HasSuffix(source, tt.codeEnd), "source ends with %q", source)
+			require.Len(t, testCases, 2)
+			assert.Equal(t, tt.first, testCases[0].RunningCommand)
+			assert.Equal(t, "stack smashing detected", testCases[1].ExpectedResult)
+		})
+	}
+
+	t.Run("a second code block after the array is not dropped", func(t *testing.T) {
+		response := "int main() { return 0; }\n[{\"running command\": \"./prog\", \"expected result\": \"ok\"}]\n```c\nint helper(void);\n```"
+		_, _, err := ParseSeedFromLLMResponse(response)
+		assert.Error(t, err)
+	})
+}
+
+func TestParseSeedWithSeparator_Custom(t *testing.T) {
+	response := "int main() { return 0; }\n/* === TESTS === */\n[{\"running command\": \"./prog\", \"expected result\": \"ok\"}]"
+
+	source, testCases, err := ParseSeedWithSeparator(response, "/* === TESTS === */")
+	require.NoError(t, err)
+	assert.Equal(t, "int main() { return 0; }", source)
+	assert.Len(t, testCases, 1)
+
+	_, testCases, err = ParseFunctionWithTestCasesWithSeparator("void seed(void) {}\n/*=== TESTS ===*/\n[{\"running command\": \"./prog\", \"expected result\": \"ok\"}]", "/* === TESTS === */")
+	require.NoError(t, err)
+	assert.Len(t, testCases, 1)
+}
+
+func TestParseStructuredResponse(t *testing.T) {
+	t.Run("decodes escaped newlines and quotes in source", func(t *testing.T) {
+		response := `{"source": "#include <stdio.h>\nint main() {\n    printf(\"hi\\n\");\n    return 0;\n}", ` +
+			`"test_cases": [{"running command": "./prog", "expected result": "hi"}], "cflags": ["-O2"]}`
+
+		resp, err := ParseStructuredResponse(response, true)
+		require.NoError(t, err)
+		assert.Equal(t, "#include <stdio.h>\nint main() {\n    printf(\"hi\\n\");\n    return 0;\n}", resp.Source)
+		require.Len(t, resp.TestCases, 1)
+		assert.Equal(t, "./prog", resp.TestCases[0].RunningCommand)
+		assert.Equal(t, []string{"-O2"}, resp.CFlags)
+	})
+
+	t.Run("tolerates fence, lead-in and fenced source", func(t *testing.T) {
+		response := "```json\nHere it is: {\"source\": \"```c\\nint main() { return 0; }\\n```\", " +
+			"\"test_cases\": [{\"running command\": \"./prog\", \"expected result\": \"ok\"}]}\n```"
+
+		resp, err := ParseStructuredResponse(response, true)
+		require.NoError(t, err)
+		assert.Equal(t, "int main() { return 0; }", resp.Source)
+	})
+
+	t.Run("test cases optional when not required", func(t *testing.T) {
+		resp, err := ParseStructuredResponse(`{"source": "void seed(void) {}"}`, false)
+		require.NoError(t, err)
+		assert.Equal(t, "void seed(void) {}", resp.Source)
+		assert.Empty(t, resp.TestCases)
+		assert.NotNil(t, resp.TestCases)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		cases := map[string]string{
+			"not json":          "int main() { return 0; }",
+			"empty source":      `{"source": "  ", "test_cases": [{"running command": "./prog"}]}`,
+			"no test cases":     `{"source": "int main() {}"}`,
+			"empty run command": `{"source": "int main() {}", "test_cases": [{"running command": ""}]}`,
+		}
+		for name, response := range cases {
+			_, err := ParseStructuredResponse(response, true)
+			assert.Error(t, err, name)
+		}
+	})
+}
+
+func TestValidateRunningCommand(t *testing.T) {
+	valid := []string{"./prog", "./a.out arg1 arg2", "./prog < input.txt", "./prog > /dev/null 2>&1"}
+	for _, command := range valid {
+		assert.NoError(t, ValidateRunningCommand(command, false), command)
+	}
+
+	invalid := []string{"", "   ", "prog", "gcc source.c", "/bin/sh -c ./prog", "./prog | head", "./prog; ls", "./prog && ./prog", "./prog `id`", "./prog $(id)"}
+	for _, command := range invalid {
+		assert.Error(t, ValidateRunningCommand(command, false), command)
+	}
+
+	assert.NoError(t, ValidateRunningCommand("./prog | head -1", true))
+	assert.Error(t, ValidateRunningCommand("cat x | ./prog", true), "must still start with ./")
+}
@@ -6,9 +6,12 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"slices"
 	"strings"
 	"syscall"
 	"time"
+
+	"github.com/zjy-dev/de-fuzz/internal/seed"
 )
 
 // ExecutionResult holds the outcome of a single command execution.
@@ -18,10 +21,27 @@ type ExecutionResult struct {
 	ExitCode int
 }
 
+// GcovEnv returns the environment assignments that redirect .gcda output of
+// instrumented binaries. libgcov strips the first strip components from the
+// absolute object path baked in at compile time and prepends prefix, so data
+// from cross-built target libraries lands under prefix instead of a host path
+// that only existed on the build machine.
+func GcovEnv(prefix string, strip int) []string {
+	if prefix == "" {
+		return nil
+	}
+	env := []string{"GCOV_PREFIX=" + prefix}
+	if strip > 0 {
+		env = append(env, fmt.Sprintf("GCOV_PREFIX_STRIP=%d", strip))
+	}
+	return env
+}
+
 // OracleExecutorAdapter adapts a LocalExecutor to the oracle.Executor interface.
 // This allows oracles to execute binaries with custom stdin input.
 type OracleExecutorAdapter struct {
 	timeoutSec int
+	env        []string // Extra KEY=VALUE pairs appended to the host environment
 }
 
 // NewOracleExecutorAdapter creates a new OracleExecutorAdapter.
@@ -31,6 +51,11 @@ func NewOracleExecutorAdapter(timeoutSec int) *OracleExecutorAdapter {
 	}
 }
 
+// SetEnv sets extra KEY=VALUE pairs for executed binaries, e.g. GcovEnv output.
+func (a *OracleExecutorAdapter) SetEnv(env []string) {
+	a.env = env
+}
+
 // ExecuteWithInput runs the binary with the given stdin input and returns the exit code.
 func (a *OracleExecutorAdapter) ExecuteWithInput(binaryPath string, stdin string) (exitCode int, stdout string, stderr string, err error) {
 	ctx := context.Background()
@@ -41,6 +66,9 @@ func (a *OracleExecutorAdapter) ExecuteWithInput(binaryPath string, stdin string
 	}
 
 	cmd := exec.CommandContext(ctx, binaryPath)
+	if len(a.env) > 0 {
+		cmd.Env = append(os.Environ(), a.env...)
+	}
 
 	// Set up stdin
 	cmd.Stdin = strings.NewReader(stdin)
@@ -85,6 +113,9 @@ func (a *OracleExecutorAdapter) ExecuteWithArgs(binaryPath string, args ...strin
 	}
 
 	cmd := exec.CommandContext(ctx, binaryPath, args...)
+	if len(a.env) > 0 {
+		cmd.Env = append(os.Environ(), a.env...)
+	}
 
 	var stdoutBuf, stderrBuf bytes.Buffer
 	cmd.Stdout = &stdoutBuf
@@ -112,6 +143,71 @@ func (a *OracleExecutorAdapter) ExecuteWithArgs(binaryPath string, args ...strin
 	return exitCode, stdout, stderr, nil
 }
 
+// ExecuteTestCase runs the binary with args for one test case: tc.Stdin is
+// fed to it, tc.Env is added to its environment and tc.TimeoutSeconds, when
+// set, replaces the adapter's timeout.
+func (a *OracleExecutorAdapter) ExecuteTestCase(binaryPath string, tc seed.TestCase, args ...string) (exitCode int, stdout string, stderr string, err error) {
+	env := append(slices.Clone(a.env), testCaseEnv(tc)...)
+	return runTestCase(testCaseTimeout(tc, a.timeoutSec), env, tc.Stdin, "failed to execute", binaryPath, args...)
+}
+
+// testCaseTimeout returns the timeout of tc, falling back to defaultSec.
+func testCaseTimeout(tc seed.TestCase, defaultSec int) int {
+	if tc.TimeoutSeconds > 0 {
+		return tc.TimeoutSeconds
+	}
+	return defaultSec
+}
+
+// testCaseEnv returns tc.Env as sorted KEY=VALUE pairs.
+func testCaseEnv(tc seed.TestCase) []string {
+	env := make([]string, 0, len(tc.Env))
+	for k, v := range tc.Env {
+		env = append(env, k+"="+v)
+	}
+	slices.Sort(env)
+	return env
+}
+
+// runTestCase runs name with args, stdin and env appended to the host
+// environment, stopping it after timeoutSec (0 means no limit). A timed-out
+// run reports exit code 124, like timeout(1).
+func runTestCase(timeoutSec int, env []string, stdin string, errPrefix string, name string, args ...string) (exitCode int, stdout string, stderr string, err error) {
+	ctx := context.Background()
+	if timeoutSec > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeoutSec)*time.Second)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	cmd.Stdin = strings.NewReader(stdin)
+
+	var stdoutBuf, stderrBuf bytes.Buffer
+	cmd.Stdout = &stdoutBuf
+	cmd.Stderr = &stderrBuf
+
+	runErr := cmd.Run()
+
+	stdout = stdoutBuf.String()
+	stderr = stderrBuf.String()
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return 124, stdout, stderr, nil
+	}
+	exitCode = getExitCode(cmd.ProcessState, runErr)
+	if runErr != nil {
+		if _, ok := runErr.(*exec.ExitError); !ok {
+			return exitCode, stdout, stderr, fmt.Errorf("%s: %w", errPrefix, runErr)
+		}
+	}
+
+	return exitCode, stdout, stderr, nil
+}
+
 // getExitCode extracts the exit code from ProcessState, handling both normal
 // exits and signal terminations. For signal terminations, returns 128 + signal.
 func getExitCode(ps *os.ProcessState, runErr error) int {
@@ -148,6 +244,7 @@ type QEMUOracleExecutorAdapter struct {
 	qemuPath   string
 	sysroot    string
 	timeoutSec int
+	env        []string // Guest environment overrides, passed as -E KEY=VALUE
 }
 
 // NewQEMUOracleExecutorAdapter creates a new QEMUOracleExecutorAdapter.
@@ -159,6 +256,25 @@ func NewQEMUOracleExecutorAdapter(qemuPath, sysroot string, timeoutSec int) *QEM
 	}
 }
 
+// SetEnv sets KEY=VALUE pairs for the guest environment. They are passed with
+// QEMU's -E option so that they reach the emulated binary (and its libgcov)
+// rather than only the QEMU process.
+func (a *QEMUOracleExecutorAdapter) SetEnv(env []string) {
+	a.env = env
+}
+
+// qemuArgs builds the QEMU argument prefix preceding the guest binary.
+func (a *QEMUOracleExecutorAdapter) qemuArgs() []string {
+	args := []string{}
+	if a.sysroot != "" {
+		args = append(args, "-L", a.sysroot)
+	}
+	for _, kv := range a.env {
+		args = append(args, "-E", kv)
+	}
+	return args
+}
+
 // ExecuteWithInput runs the binary via QEMU with the given stdin input.
 func (a *QEMUOracleExecutorAdapter) ExecuteWithInput(binaryPath string, stdin string) (exitCode int, stdout string, stderr string, err error) {
 	ctx := context.Background()
@@ -168,11 +284,8 @@ func (a *QEMUOracleExecutorAdapter) ExecuteWithInput(binaryPath string, stdin st
 		defer cancel()
 	}
 
-	// Build QEMU command: qemu-aarch64 -L <sysroot> <binary>
-	args := []string{}
-	if a.sysroot != "" {
-		args = append(args, "-L", a.sysroot)
-	}
+	// Build QEMU command: qemu-aarch64 -L <sysroot> [-E KEY=VALUE...] <binary>
+	args := a.qemuArgs()
 	args = append(args, binaryPath)
 
 	cmd := exec.CommandContext(ctx, a.qemuPath, args...)
@@ -210,11 +323,8 @@ func (a *QEMUOracleExecutorAdapter) ExecuteWithArgs(binaryPath string, args ...s
 		defer cancel()
 	}
 
-	// Build QEMU command: qemu-aarch64 -L <sysroot> <binary> <args...>
-	qemuArgs := []string{}
-	if a.sysroot != "" {
-		qemuArgs = append(qemuArgs, "-L", a.sysroot)
-	}
+	// Build QEMU command: qemu-aarch64 -L <sysroot> [-E KEY=VALUE...] <binary> <args...>
+	qemuArgs := a.qemuArgs()
 	qemuArgs = append(qemuArgs, binaryPath)
 	qemuArgs = append(qemuArgs, args...)
 
@@ -242,3 +352,13 @@ func (a *QEMUOracleExecutorAdapter) ExecuteWithArgs(binaryPath string, args ...s
 
 	return exitCode, stdout, stderr, nil
 }
+
+// ExecuteTestCase runs the binary via QEMU for one test case, like
+// OracleExecutorAdapter.ExecuteTestCase. tc.Env is set on the QEMU process,
+// whose environment qemu-user passes on to the guest.
+func (a *QEMUOracleExecutorAdapter) ExecuteTestCase(binaryPath string, tc seed.TestCase, args ...string) (exitCode int, stdout string, stderr string, err error) {
+	qemuArgs := a.qemuArgs()
+	qemuArgs = append(qemuArgs, binaryPath)
+	qemuArgs = append(qemuArgs, args...)
+	return runTestCase(testCaseTimeout(tc, a.timeoutSec), testCaseEnv(tc), tc.Stdin, "failed to execute via QEMU", a.qemuPath, qemuArgs...)
+}
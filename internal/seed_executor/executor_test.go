@@ -0,0 +1,81 @@
+package executor
+
+import (
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zjy-dev/de-fuzz/internal/seed"
+)
+
+func TestOracleExecutorAdapter_ExecuteTestCase(t *testing.T) {
+	a := NewOracleExecutorAdapter(30)
+
+	t.Run("case timeout shorter than the global one", func(t *testing.T) {
+		start := time.Now()
+		exitCode, _, _, err := a.ExecuteTestCase("sleep", seed.TestCase{TimeoutSeconds: 1}, "10")
+		if err != nil {
+			t.Fatalf("ExecuteTestCase: %v", err)
+		}
+		if exitCode != 124 {
+			t.Errorf("exit code = %d, want 124", exitCode)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("case ran for %v, want about 1s", elapsed)
+		}
+	})
+
+	t.Run("env and stdin", func(t *testing.T) {
+		tc := seed.TestCase{Stdin: "input", Env: map[string]string{"DEFUZZ_CASE": "42"}}
+		exitCode, stdout, _, err := a.ExecuteTestCase("sh", tc, "-c", `printf '%s:' "$DEFUZZ_CASE"; cat`)
+		if err != nil {
+			t.Fatalf("ExecuteTestCase: %v", err)
+		}
+		if exitCode != 0 || stdout != "42:input" {
+			t.Errorf("got exit %d, stdout %q; want 0, %q", exitCode, stdout, "42:input")
+		}
+	})
+}
+
+// TestOracleExecutorAdapter_ScanfStdin runs a scanf-based seed end to end:
+// the test case's stdin reaches the native binary.
+func TestOracleExecutorAdapter_ScanfStdin(t *testing.T) {
+	if _, err := osexec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not available")
+	}
+	dir := t.TempDir()
+	srcPath := filepath.Join(dir, "scanf_sum.c")
+	src := "#include <stdio.h>\nint main(void) { int a, b; if (scanf(\"%d %d\", &a, &b) != 2) return 1; printf(\"sum=%d\\n\", a + b); return 0; }\n"
+	if err := os.WriteFile(srcPath, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	binPath := filepath.Join(dir, "scanf_sum")
+	if out, err := osexec.Command("gcc", "-o", binPath, srcPath).CombinedOutput(); err != nil {
+		t.Fatalf("gcc: %v\n%s", err, out)
+	}
+
+	a := NewOracleExecutorAdapter(5)
+	exitCode, stdout, _, err := a.ExecuteTestCase(binPath, seed.TestCase{Stdin: "20 22\n"})
+	if err != nil {
+		t.Fatalf("ExecuteTestCase: %v", err)
+	}
+	if exitCode != 0 || stdout != "sum=42\n" {
+		t.Errorf("got exit %d, stdout %q; want 0, %q", exitCode, stdout, "sum=42\n")
+	}
+
+	// Without input scanf fails and the program reports it.
+	if exitCode, _, _, err := a.ExecuteTestCase(binPath, seed.TestCase{}); err != nil || exitCode != 1 {
+		t.Errorf("without stdin: exit %d, %v; want 1", exitCode, err)
+	}
+}
+
+func TestTestCaseTimeout(t *testing.T) {
+	if got := testCaseTimeout(seed.TestCase{}, 7); got != 7 {
+		t.Errorf("testCaseTimeout without a case timeout = %d, want 7", got)
+	}
+	if got := testCaseTimeout(seed.TestCase{TimeoutSeconds: 2}, 7); got != 2 {
+		t.Errorf("testCaseTimeout = %d, want 2", got)
+	}
+}
@@ -0,0 +1,58 @@
+//go:build integration
+// +build integration
+
+package executor
+
+import (
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestQEMUOracleExecutor_GcovPrefix_Integration builds an instrumented aarch64
+// binary with the cross toolchain and checks that running it under QEMU with
+// GcovEnv writes its .gcda file below the configured prefix instead of the
+// compile-time object directory.
+func TestQEMUOracleExecutor_GcovPrefix_Integration(t *testing.T) {
+	gccPath, err := osexec.LookPath("aarch64-linux-gnu-gcc")
+	if err != nil {
+		t.Skip("Skipping test: aarch64-linux-gnu-gcc not found in PATH")
+	}
+	qemuPath, err := osexec.LookPath("qemu-aarch64")
+	if err != nil {
+		t.Skip("Skipping test: qemu-aarch64 not found in PATH")
+	}
+
+	buildDir := t.TempDir()
+	prefixDir := t.TempDir()
+
+	srcPath := filepath.Join(buildDir, "prog.c")
+	if err := os.WriteFile(srcPath, []byte("int main(void) { return 0; }\n"), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	binPath := filepath.Join(buildDir, "prog")
+	out, err := osexec.Command(gccPath, "--coverage", "-static", "-o", binPath, srcPath).CombinedOutput()
+	if err != nil {
+		t.Skipf("Skipping test: cross toolchain cannot build instrumented binary: %v\n%s", err, out)
+	}
+
+	// Strip every component of buildDir so the .gcda lands directly under prefixDir.
+	strip := len(strings.Split(strings.Trim(filepath.ToSlash(buildDir), "/"), "/"))
+	adapter := NewQEMUOracleExecutorAdapter(qemuPath, "", 30)
+	adapter.SetEnv(GcovEnv(prefixDir, strip))
+
+	exitCode, _, stderr, err := adapter.ExecuteWithInput(binPath, "")
+	if err != nil || exitCode != 0 {
+		t.Fatalf("ExecuteWithInput() exit=%d err=%v stderr=%s", exitCode, err, stderr)
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(prefixDir, "*.gcda"))
+	if len(matches) == 0 {
+		t.Errorf("no .gcda file written under prefix %s", prefixDir)
+	}
+	if leaked, _ := filepath.Glob(filepath.Join(buildDir, "*.gcda")); len(leaked) > 0 {
+		t.Errorf(".gcda written to build dir despite GCOV_PREFIX: %v", leaked)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+
+	"github.com/zjy-dev/de-fuzz/internal/fsutil"
 )
 
 const (
@@ -36,9 +38,16 @@ type Manager interface {
 	// Save writes the state to disk.
 	Save() error
 
+	// SaveSnapshot writes a state taken earlier with GetState to disk.
+	SaveSnapshot(snapshot GlobalState) error
+
 	// NextID increments and returns the next unique seed ID.
 	NextID() uint64
 
+	// ReserveID records that IDs up to id are taken, for IDs allocated
+	// elsewhere.
+	ReserveID(id uint64)
+
 	// UpdateCurrentID sets the ID currently being fuzzed.
 	UpdateCurrentID(id uint64)
 
@@ -48,9 +57,16 @@ type Manager interface {
 	// IncrementProcessed increments the processed count.
 	IncrementProcessed()
 
+	// RestoreProgress raises the processed count and total coverage to at
+	// least the given values, for progress recorded after the state file.
+	RestoreProgress(processed int, coverage uint64)
+
 	// UpdatePoolSize sets the current pool size.
 	UpdatePoolSize(size int)
 
+	// SetState replaces the whole state.
+	SetState(state GlobalState)
+
 	// GetState returns a copy of the current state.
 	GetState() GlobalState
 }
@@ -58,6 +74,7 @@ type Manager interface {
 // FileManager is a file-backed implementation of the Manager interface.
 type FileManager struct {
 	mu       sync.Mutex
+	writeMu  sync.Mutex // Serializes writes of the state file
 	filePath string
 	state    GlobalState
 }
@@ -102,23 +119,36 @@ func (m *FileManager) Load() error {
 	return nil
 }
 
-// Save writes the state to disk.
+// Save writes the state to disk. The state is copied first, so updates do
+// not wait for the write.
 func (m *FileManager) Save() error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	m.writeMu.Lock()
+	defer m.writeMu.Unlock()
+	return m.write(m.GetState())
+}
+
+// SaveSnapshot writes snapshot to disk. Callers that take snapshots
+// concurrently must order their saves, or an older snapshot may land last.
+func (m *FileManager) SaveSnapshot(snapshot GlobalState) error {
+	m.writeMu.Lock()
+	defer m.writeMu.Unlock()
+	return m.write(snapshot)
+}
 
+// write writes state to the state file. Callers hold m.writeMu.
+func (m *FileManager) write(state GlobalState) error {
 	// Ensure directory exists
 	dir := filepath.Dir(m.filePath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return fmt.Errorf("failed to create state directory %s: %w", dir, err)
 	}
 
-	data, err := json.MarshalIndent(m.state, "", "  ")
+	data, err := json.MarshalIndent(state, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal state: %w", err)
 	}
 
-	if err := os.WriteFile(m.filePath, data, 0644); err != nil {
+	if err := fsutil.WriteFileAtomic(m.filePath, data); err != nil {
 		return fmt.Errorf("failed to write state file %s: %w", m.filePath, err)
 	}
 
@@ -135,6 +165,14 @@ func (m *FileManager) NextID() uint64 {
 	return m.state.LastAllocatedID
 }
 
+// ReserveID raises the last allocated ID to id if it is lower.
+func (m *FileManager) ReserveID(id uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.state.LastAllocatedID = max(m.state.LastAllocatedID, id)
+}
+
 // UpdateCurrentID sets the ID currently being fuzzed.
 func (m *FileManager) UpdateCurrentID(id uint64) {
 	m.mu.Lock()
@@ -159,6 +197,16 @@ func (m *FileManager) IncrementProcessed() {
 	m.state.Stats.ProcessedCount++
 }
 
+// RestoreProgress raises the processed count and total coverage to at
+// least processed and coverage.
+func (m *FileManager) RestoreProgress(processed int, coverage uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.state.Stats.ProcessedCount = max(m.state.Stats.ProcessedCount, processed)
+	m.state.TotalCoverage = max(m.state.TotalCoverage, coverage)
+}
+
 // UpdatePoolSize sets the current pool size.
 func (m *FileManager) UpdatePoolSize(size int) {
 	m.mu.Lock()
@@ -167,6 +215,14 @@ func (m *FileManager) UpdatePoolSize(size int) {
 	m.state.Stats.PoolSize = size
 }
 
+// SetState replaces the whole state, e.g. with one reread from disk.
+func (m *FileManager) SetState(state GlobalState) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.state = state
+}
+
 // GetState returns a copy of the current state.
 func (m *FileManager) GetState() GlobalState {
 	m.mu.Lock()
@@ -108,4 +108,18 @@ func TestFileManager(t *testing.T) {
 			t.Errorf("expected TotalCoverage 2500, got %d", state.TotalCoverage)
 		}
 	})
+
+	t.Run("should only raise restored progress", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		manager := NewFileManager(tmpDir)
+		_ = manager.Load()
+
+		manager.RestoreProgress(5, 3000)
+		manager.RestoreProgress(3, 1000)
+		state := manager.GetState()
+
+		if state.Stats.ProcessedCount != 5 || state.TotalCoverage != 3000 {
+			t.Errorf("expected ProcessedCount 5 and TotalCoverage 3000, got %d and %d", state.Stats.ProcessedCount, state.TotalCoverage)
+		}
+	})
 }
@@ -29,6 +29,7 @@ type QEMUVM struct {
 	qemuPath  string   // Path to qemu-user executable (e.g., "qemu-aarch64")
 	sysroot   string   // Sysroot for library path
 	extraArgs []string // Additional QEMU arguments
+	env       []string // Guest environment overrides (KEY=VALUE)
 }
 
 // QEMUConfig holds the configuration for QEMU.
@@ -36,6 +37,10 @@ type QEMUConfig struct {
 	QEMUPath  string   // Path to QEMU executable
 	Sysroot   string   // Sysroot path for -L argument
 	ExtraArgs []string // Additional QEMU arguments
+	// Env holds KEY=VALUE pairs for the guest environment, passed with -E.
+	// Used e.g. for GCOV_PREFIX so instrumented target libraries write .gcda
+	// files under a known host directory.
+	Env []string
 }
 
 // NewQEMUVM creates a new QEMU VM instance.
@@ -45,6 +50,7 @@ func NewQEMUVM(cfg QEMUConfig) *QEMUVM {
 		qemuPath:  cfg.QEMUPath,
 		sysroot:   cfg.Sysroot,
 		extraArgs: cfg.ExtraArgs,
+		env:       cfg.Env,
 	}
 }
 
@@ -67,6 +73,11 @@ func (q *QEMUVM) run(binaryPath string, timeoutSec int, args ...string) (*Execut
 		qemuArgs = append(qemuArgs, "-L", q.sysroot)
 	}
 
+	// Add guest environment overrides
+	for _, kv := range q.env {
+		qemuArgs = append(qemuArgs, "-E", kv)
+	}
+
 	// Add extra QEMU arguments
 	qemuArgs = append(qemuArgs, q.extraArgs...)
 
@@ -191,6 +191,33 @@ func TestQEMUVM_RunWithExtraArgs(t *testing.T) {
 	assert.Contains(t, capturedArgs, "cortex-a72")
 }
 
+func TestQEMUVM_RunWithEnv(t *testing.T) {
+	cfg := QEMUConfig{
+		QEMUPath: "qemu-aarch64",
+		Sysroot:  "/usr/aarch64-linux-gnu",
+		Env:      []string{"GCOV_PREFIX=/tmp/gcov", "GCOV_PREFIX_STRIP=3"},
+	}
+	vm := NewQEMUVM(cfg)
+
+	var capturedArgs []string
+	vm.executor = &MockExecutor{
+		RunFunc: func(command string, args ...string) (*exec.ExecutionResult, error) {
+			capturedArgs = args
+			return &exec.ExecutionResult{ExitCode: 0}, nil
+		},
+	}
+
+	_, err := vm.Run("/path/to/binary", "arg1")
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"-L", "/usr/aarch64-linux-gnu",
+		"-E", "GCOV_PREFIX=/tmp/gcov",
+		"-E", "GCOV_PREFIX_STRIP=3",
+		"/path/to/binary", "arg1",
+	}, capturedArgs)
+}
+
 // Tests for parseQEMUExitCode function
 
 func TestParseQEMUExitCode_NormalExit(t *testing.T) {